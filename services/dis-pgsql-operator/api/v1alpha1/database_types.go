@@ -1,16 +1,30 @@
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // DatabaseDeletionPolicy controls what happens to the PostgreSQL
 // database when the Database resource is deleted.
-// +kubebuilder:validation:Enum=Retain
+// +kubebuilder:validation:Enum=Retain;Delete
 type DatabaseDeletionPolicy string
 
 const (
+	// DatabaseDeletionPolicyRetain leaves the database, its managed roles and
+	// their memberships on the server when the Database is deleted.
 	DatabaseDeletionPolicyRetain DatabaseDeletionPolicy = "Retain"
+
+	// DatabaseDeletionPolicyDelete drops the database and its managed roles once
+	// the deletion grace period after deleting the Database has expired.
+	DatabaseDeletionPolicyDelete DatabaseDeletionPolicy = "Delete"
 )
 
+// DefaultDatabaseDeletionGracePeriod applies when deletionPolicy is Delete and
+// spec.deletionGracePeriod is not set.
+const DefaultDatabaseDeletionGracePeriod = 24 * time.Hour
+
 // DatabaseServerReference identifies the DatabaseServer that hosts this
 // database.
 type DatabaseServerReference struct {
@@ -19,6 +33,19 @@ type DatabaseServerReference struct {
 	Name string `json:"name"`
 }
 
+// DatabaseServerSelector picks the DatabaseServer for a Database among the
+// same-namespace servers instead of naming one.
+type DatabaseServerSelector struct {
+	// matchLabels selects DatabaseServers that have all of these labels.
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// serverType selects DatabaseServers of this environment, such as "dev"
+	// or "prod", matching their spec.serverType.
+	// +optional
+	ServerType string `json:"serverType,omitempty"`
+}
+
 // +kubebuilder:validation:Enum=Reader;Writer;Owner
 // DatabaseAccessRole is the database role granted to an access principal.
 type DatabaseAccessRole string
@@ -61,16 +88,76 @@ type DatabaseServicePrincipalSpec struct {
 	PrincipalId string `json:"principalId"`
 }
 
+// DatabaseIdentityRef references the ApplicationIdentity of a Database access
+// principal.
+type DatabaseIdentityRef struct {
+	// name is the ApplicationIdentity name.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// namespace is the ApplicationIdentity namespace. It defaults to the
+	// Database's namespace. An identity in another namespace can only get the
+	// Reader role, and only while a DatabaseAccessGrant in its namespace
+	// allows this Database to reference it. Its connection details are
+	// published in its own namespace.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DatabaseTableReference names a table or view in the database.
+type DatabaseTableReference struct {
+	// schema is the schema of the table. It defaults to the database's own
+	// schema.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	Schema string `json:"schema,omitempty"`
+
+	// name is the table or view name.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.tables) || (!has(self.schemas) && !has(self.executeFunctions))",message="tables cannot be combined with schemas or executeFunctions."
+// DatabaseAccessScope widens or narrows the objects a principal's role applies
+// to. Without a scope the role covers the database's own schema.
+type DatabaseAccessScope struct {
+	// schemas are additional schemas the role applies to, besides the
+	// database's own schema. Missing schemas are created.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:items:MinLength=1
+	// +kubebuilder:validation:items:MaxLength=63
+	Schemas []string `json:"schemas,omitempty"`
+
+	// tables limits a Reader to SELECT on the listed tables and views instead
+	// of every table in the database's schema. The tables must exist; the
+	// access Job is retried until they do.
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=64
+	Tables []DatabaseTableReference `json:"tables,omitempty"`
+
+	// executeFunctions also grants EXECUTE on every function in the role's
+	// schemas, including functions created later.
+	// +optional
+	ExecuteFunctions bool `json:"executeFunctions,omitempty"`
+}
+
 // +kubebuilder:validation:XValidation:rule="(has(self.identityRef) ? 1 : 0) + (has(self.group) ? 1 : 0) + (has(self.servicePrincipal) ? 1 : 0) == 1",message="Provide exactly one principal source: identityRef, group, or servicePrincipal."
+// +kubebuilder:validation:XValidation:rule="!has(self.scope) || !has(self.scope.tables) || self.role == 'Reader'",message="scope.tables is only supported with the Reader role."
 // DatabaseAccessPrincipalSpec describes one principal and the role it should get.
 type DatabaseAccessPrincipalSpec struct {
 	// role is the managed database access role granted to the principal.
 	Role DatabaseAccessRole `json:"role"`
 
-	// identityRef points to an ApplicationIdentity in the same namespace.
-	// The operator resolves the managed identity name and principalId from status.
+	// identityRef points to an ApplicationIdentity, by default in the same
+	// namespace. The operator resolves the managed identity name and
+	// principalId from status.
 	// +optional
-	IdentityRef *ApplicationIdentityRef `json:"identityRef,omitempty"`
+	IdentityRef *DatabaseIdentityRef `json:"identityRef,omitempty"`
 
 	// group identifies an existing Entra group.
 	// +optional
@@ -79,6 +166,32 @@ type DatabaseAccessPrincipalSpec struct {
 	// servicePrincipal identifies an existing Entra service principal by object id.
 	// +optional
 	ServicePrincipal *DatabaseServicePrincipalSpec `json:"servicePrincipal,omitempty"`
+
+	// scope grants the role on additional schemas, limits a Reader to listed
+	// tables, or adds EXECUTE on functions.
+	// +optional
+	Scope *DatabaseAccessScope `json:"scope,omitempty"`
+
+	// connectionLimit caps the concurrent connections of the principal's
+	// login role. The limit applies to the role on the whole server, so when
+	// several Databases on a server set one for the principal, the lowest
+	// applies. The limits on a server, one per principal, must fit within
+	// its max_connections. Unset is unlimited unless another Database on the
+	// server sets a limit for the principal.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// statementTimeout aborts the principal's statements in this database
+	// that run longer. Unset uses the server's statement_timeout.
+	// +optional
+	StatementTimeout *metav1.Duration `json:"statementTimeout,omitempty"`
+
+	// idleInTransactionSessionTimeout ends the principal's sessions in this
+	// database that stay idle in an open transaction longer. Unset uses the
+	// server's idle_in_transaction_session_timeout.
+	// +optional
+	IdleInTransactionSessionTimeout *metav1.Duration `json:"idleInTransactionSessionTimeout,omitempty"`
 }
 
 // DatabaseAccessSpec describes role-based access requirements for the database.
@@ -89,9 +202,177 @@ type DatabaseAccessSpec struct {
 	Principals []DatabaseAccessPrincipalSpec `json:"principals"`
 }
 
+// DatabaseExtensionSpec is a PostgreSQL extension installed in the database.
+type DatabaseExtensionSpec struct {
+	// name is the extension. It must also be listed in the DatabaseServer's
+	// spec.enableExtensions, which allow-lists and preloads it on the server.
+	Name DatabaseServerExtension `json:"name"`
+
+	// version pins the extension version. When empty the extension follows
+	// the default version of the server, so it is updated after the server is.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9][A-Za-z0-9._-]*$`
+	Version string `json:"version,omitempty"`
+}
+
+// DatabaseConnectionFormat is a form in which the operator publishes the
+// connection details of each identityRef principal.
+// +kubebuilder:validation:Enum=ConfigMap;ServiceBindingSecret
+type DatabaseConnectionFormat string
+
+const (
+	// DatabaseConnectionFormatConfigMap publishes a ConfigMap with CNPG-style
+	// keys (host, port, dbname, user, sslmode, uri).
+	DatabaseConnectionFormatConfigMap DatabaseConnectionFormat = "ConfigMap"
+
+	// DatabaseConnectionFormatServiceBindingSecret publishes a Secret in the
+	// servicebinding.io layout (type, provider, host, port, database,
+	// username). It holds no password: the workload authenticates with its
+	// managed identity.
+	DatabaseConnectionFormatServiceBindingSecret DatabaseConnectionFormat = "ServiceBindingSecret"
+)
+
+// DatabaseConnectionKey is an additional key published in every connection
+// format.
+// +kubebuilder:validation:Enum=jdbc-url;dotnet-connection-string
+type DatabaseConnectionKey string
+
+const (
+	// DatabaseConnectionKeyJDBCURL publishes a PostgreSQL JDBC URL.
+	DatabaseConnectionKeyJDBCURL DatabaseConnectionKey = "jdbc-url"
+
+	// DatabaseConnectionKeyDotNetConnectionString publishes an Npgsql
+	// connection string.
+	DatabaseConnectionKeyDotNetConnectionString DatabaseConnectionKey = "dotnet-connection-string"
+)
+
+// DatabaseConnectionSpec selects how connection details are published.
+type DatabaseConnectionSpec struct {
+	// formats are the forms the connection details of each identityRef
+	// principal are published in. Every format uses the same deterministic
+	// name, "<database>-<identityRef>-dis-pgsql". Defaults to ConfigMap.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MinItems=1
+	Formats []DatabaseConnectionFormat `json:"formats,omitempty"`
+
+	// extraKeys are additional keys published in every format.
+	// +optional
+	// +listType=set
+	ExtraKeys []DatabaseConnectionKey `json:"extraKeys,omitempty"`
+}
+
+// DatabaseAccessAuditMode selects what the periodic access audit does with
+// the drift it finds.
+// +kubebuilder:validation:Enum=Report;Enforce
+type DatabaseAccessAuditMode string
+
+const (
+	// DatabaseAccessAuditModeReport only records drift in status.accessAudit.
+	DatabaseAccessAuditModeReport DatabaseAccessAuditMode = "Report"
+
+	// DatabaseAccessAuditModeEnforce also revokes role memberships and default
+	// privileges the operator does not manage.
+	DatabaseAccessAuditModeEnforce DatabaseAccessAuditMode = "Enforce"
+)
+
+// DatabaseAccessAuditSpec configures the periodic access audit.
+type DatabaseAccessAuditSpec struct {
+	// mode is Report, which only records drift, or Enforce, which also
+	// revokes unmanaged role memberships and default privileges. Missing
+	// grants and a changed schema owner are only reported; the access
+	// provisioning Job restores them. Defaults to Report.
+	// +optional
+	// +kubebuilder:default=Report
+	Mode DatabaseAccessAuditMode `json:"mode,omitempty"`
+}
+
+// DatabaseBackupScheduleSpec configures scheduled logical backups of the
+// database to a Blob Storage container.
+type DatabaseBackupScheduleSpec struct {
+	// cron is when the backup runs, in cron format and UTC, such as
+	// "0 2 * * *".
+	// +kubebuilder:validation:MinLength=1
+	Cron string `json:"cron"`
+
+	// containerURL is the Blob Storage container the backups are written to,
+	// such as https://account.blob.core.windows.net/backups. Each backup is a
+	// pg_dump custom-format archive named
+	// <namespace>/<database name>/<time>.dump. The DatabaseServer's admin
+	// identity needs the Storage Blob Data Contributor role on the container,
+	// whose account must be one the operator is configured to upload backups
+	// to. http:// is only accepted in local clusters, for Azurite, and
+	// file:// for a directory in the backup Pod when testing.
+	// +kubebuilder:validation:Pattern=`^(https?|file)://`
+	ContainerURL string `json:"containerURL"`
+
+	// retentionDays prunes the database's backups older than this many days
+	// after each successful backup. The latest backup is always kept. Without
+	// it, backups are kept until removed from the container.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RetentionDays *int32 `json:"retentionDays,omitempty"`
+
+	// suspend stops new backups from starting.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// DatabaseConfigMapReference names a same-namespace ConfigMap.
+type DatabaseConfigMapReference struct {
+	// name is the ConfigMap in the Database's namespace.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// DatabaseLocalIdentityRef names an ApplicationIdentity in the Database's
+// namespace.
+type DatabaseLocalIdentityRef struct {
+	// name is the ApplicationIdentity name.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// DatabaseSchemaMigrationsSpec is where the schema migrations of the
+// database come from and who applies them. Each migration is a .sql file;
+// they are applied in the order of their file names, and the file name
+// without .sql is the migration's version.
+// +kubebuilder:validation:XValidation:rule="has(self.image) != has(self.configMap)",message="exactly one of image and configMap must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.path) || has(self.image)",message="path is only used with image"
+type DatabaseSchemaMigrationsSpec struct {
+	// image is an OCI image holding the migrations in path. The image must
+	// provide cp, which copies the migrations out of it. Use a new tag or a
+	// digest for new migrations, since the reference is what the operator
+	// compares.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// path is the directory in image holding the migrations. Defaults to
+	// /migrations.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// configMap holds the migrations in its keys ending in .sql. The
+	// ConfigMap must have the label
+	// pgsql.dis.altinn.cloud/component: schema-migrations.
+	// +optional
+	ConfigMap *DatabaseConfigMapReference `json:"configMap,omitempty"`
+
+	// identityRef is the ApplicationIdentity the migrations run as. It must
+	// be the identityRef of an Owner principal of this Database, and its
+	// login role must not be a member of any role other than the database's
+	// Owner role. The Job runs under the identity's ServiceAccount and
+	// connects as it, so neither the migrations nor the image they come from
+	// get the server admin's rights.
+	IdentityRef DatabaseLocalIdentityRef `json:"identityRef"`
+}
+
 // DatabaseSpec defines the desired state of Database.
 //
 // The PostgreSQL database name is spec.name.
+// +kubebuilder:validation:XValidation:rule="has(self.server) != has(self.serverSelector)",message="exactly one of server and serverSelector must be set"
 type DatabaseSpec struct {
 	// name is the PostgreSQL database name to create inside the selected server.
 	// It must be unique per server.
@@ -99,17 +380,68 @@ type DatabaseSpec struct {
 	// +kubebuilder:validation:MaxLength=63
 	Name string `json:"name"`
 
-	// server identifies the same-namespace DatabaseServer.
-	Server DatabaseServerReference `json:"server"`
+	// server identifies the same-namespace DatabaseServer. Changing it on a
+	// Database that already has a database moves the database to the new
+	// server with a dump and restore; see status.migration.
+	// +optional
+	Server DatabaseServerReference `json:"server,omitzero"`
+
+	// serverSelector lets the operator place the database on the matching
+	// DatabaseServer with the most remaining capacity. The chosen server is
+	// recorded in status.server and kept, even if the selector or the
+	// servers' capacity changes later.
+	// +optional
+	ServerSelector *DatabaseServerSelector `json:"serverSelector,omitempty"`
 
 	// access defines the principals that should get access to this database.
 	Access DatabaseAccessSpec `json:"access"`
 
 	// deletionPolicy controls database cleanup when this resource is deleted.
-	// Only Retain is supported in this API slice.
+	// With Delete, the database and its managed roles are dropped once
+	// deletionGracePeriod has passed after the Database was deleted. Until then
+	// the Database reports PendingDeletion and can be rescued by setting the
+	// policy back to Retain.
 	// +optional
 	// +kubebuilder:default=Retain
 	DeletionPolicy DatabaseDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// deletionGracePeriod is how long a deleted Database with deletionPolicy
+	// Delete waits before the database is dropped. Defaults to 24h.
+	// +optional
+	DeletionGracePeriod *metav1.Duration `json:"deletionGracePeriod,omitempty"`
+
+	// extensions are created in the database's schema by the access
+	// provisioning Job, or in the schema the extension requires, such as cron
+	// for pg_cron. Extensions already installed are updated in place. An
+	// extension removed from this list is dropped, unless other objects
+	// still depend on it.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Extensions []DatabaseExtensionSpec `json:"extensions,omitempty"`
+
+	// connection selects the formats and extra keys of the published
+	// connection details. Without it, a ConfigMap is published.
+	// +optional
+	Connection *DatabaseConnectionSpec `json:"connection,omitempty"`
+
+	// accessAudit configures the periodic audit that compares the role
+	// memberships, schema owners and default privileges in the database with
+	// the access the operator manages. Without it, drift is only reported.
+	// +optional
+	AccessAudit *DatabaseAccessAuditSpec `json:"accessAudit,omitempty"`
+
+	// schedule runs a CronJob that writes a logical backup of the database to
+	// Blob Storage. The backups are independent of the server's point-in-time
+	// backups, so they outlive the server and its retention.
+	// +optional
+	Schedule *DatabaseBackupScheduleSpec `json:"schedule,omitempty"`
+
+	// schemaMigrations are applied to the database as its Owner role once
+	// access is provisioned. The Database is not Ready until every migration
+	// has been applied; see status.schemaMigrations.
+	// +optional
+	SchemaMigrations *DatabaseSchemaMigrationsSpec `json:"schemaMigrations,omitempty"`
 }
 
 // DatabaseValidationError captures a validation failure observed by the
@@ -128,12 +460,222 @@ type DatabaseValidationError struct {
 	Message string `json:"message"`
 }
 
+// DatabaseExtensionStatus is the observed state of one extension listed in
+// spec.extensions, or of one being dropped after it was removed from it.
+type DatabaseExtensionStatus struct {
+	// name is the extension.
+	Name DatabaseServerExtension `json:"name"`
+
+	// ready is true when the extension is installed as requested, or has been
+	// dropped.
+	Ready bool `json:"ready"`
+
+	// version is the installed extension version.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// schema is the schema the extension is installed in.
+	// +optional
+	Schema string `json:"schema,omitempty"`
+
+	// reason is a machine-readable reason for the state: Installed, Pending,
+	// NotEnabled, Dropping or Failed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// message is a human-readable description of the state.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DatabaseAccessDrift is one difference between the access in the database
+// and the access the operator manages.
+type DatabaseAccessDrift struct {
+	// kind is UnmanagedMember, MissingMember, MissingSchema, SchemaOwner,
+	// UnmanagedDefaultPrivilege or MissingDefaultPrivilege.
+	Kind string `json:"kind"`
+
+	// role is the managed role of a membership, the expected owner of a
+	// schema, or the creating role of a default privilege.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// grantee is the member of a role or the grantee of a default privilege.
+	// +optional
+	Grantee string `json:"grantee,omitempty"`
+
+	// schema is the schema of a schema owner or default privilege.
+	// +optional
+	Schema string `json:"schema,omitempty"`
+
+	// objectType is the object type of a default privilege: Tables,
+	// Sequences, Functions or Types.
+	// +optional
+	ObjectType string `json:"objectType,omitempty"`
+
+	// owner is the actual owner of a schema with another owner than role.
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// revoked is true when the Enforce mode revoked the grant.
+	// +optional
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// DatabaseAccessAuditStatus is the result of the last access audit.
+type DatabaseAccessAuditStatus struct {
+	// observedTime is when the audit last ran.
+	// +optional
+	ObservedTime *metav1.Time `json:"observedTime,omitempty"`
+
+	// mode is the mode the audit ran in.
+	// +optional
+	Mode DatabaseAccessAuditMode `json:"mode,omitempty"`
+
+	// truncated is true when the audit found more drift than fit in one
+	// report.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+
+	// drift lists the differences found. It is empty when the access in the
+	// database matches.
+	// +listType=atomic
+	// +optional
+	Drift []DatabaseAccessDrift `json:"drift,omitempty"`
+}
+
+// DatabaseMigrationPhase is the progress of moving a database to another
+// DatabaseServer.
+// +kubebuilder:validation:Enum=Pending;Copying;Failed;Completed
+type DatabaseMigrationPhase string
+
+const (
+	// DatabaseMigrationPhasePending waits for the target server to be able to
+	// take the database.
+	DatabaseMigrationPhasePending DatabaseMigrationPhase = "Pending"
+	// DatabaseMigrationPhaseCopying runs the dump, restore and verification.
+	DatabaseMigrationPhaseCopying DatabaseMigrationPhase = "Copying"
+	// DatabaseMigrationPhaseFailed is a copy that did not verify, or a target
+	// server that cannot be used. A failed copy is retried.
+	DatabaseMigrationPhaseFailed DatabaseMigrationPhase = "Failed"
+	// DatabaseMigrationPhaseCompleted is a verified copy. The database is
+	// served from the target server.
+	DatabaseMigrationPhaseCompleted DatabaseMigrationPhase = "Completed"
+)
+
+// DatabaseMigrationSourceCleanup is what happened to the database on the
+// source server after a migration completed.
+// +kubebuilder:validation:Enum=Pending;Retained;Dropped
+type DatabaseMigrationSourceCleanup string
+
+const (
+	DatabaseMigrationSourceCleanupPending  DatabaseMigrationSourceCleanup = "Pending"
+	DatabaseMigrationSourceCleanupRetained DatabaseMigrationSourceCleanup = "Retained"
+	DatabaseMigrationSourceCleanupDropped  DatabaseMigrationSourceCleanup = "Dropped"
+)
+
+// DatabaseMigrationStatus is the state of the last move of the database to
+// another DatabaseServer.
+type DatabaseMigrationStatus struct {
+	// sourceServer is the DatabaseServer the database is moved from.
+	SourceServer string `json:"sourceServer"`
+
+	// targetServer is the DatabaseServer the database is moved to.
+	TargetServer string `json:"targetServer"`
+
+	// phase is Pending, Copying, Failed or Completed.
+	Phase DatabaseMigrationPhase `json:"phase"`
+
+	// message is a human-readable description of the phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// startTime is when the migration started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// completionTime is when the copy was verified and the database switched
+	// to the target server.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// verifiedTables and verifiedRows are the tables and rows the
+	// verification compared between the servers.
+	// +optional
+	VerifiedTables int32 `json:"verifiedTables,omitempty"`
+	// +optional
+	VerifiedRows int64 `json:"verifiedRows,omitempty"`
+
+	// sourceCleanup is Pending until the database on the source server has
+	// been handled by spec.deletionPolicy: Retained detaches it and leaves it
+	// in place, Dropped drops it after spec.deletionGracePeriod.
+	// +optional
+	SourceCleanup DatabaseMigrationSourceCleanup `json:"sourceCleanup,omitempty"`
+}
+
+// DatabaseBackupStatus is the outcome of the scheduled backups.
+type DatabaseBackupStatus struct {
+	// lastBackupTime is when the last successful backup was written.
+	// +optional
+	LastBackupTime *metav1.Time `json:"lastBackupTime,omitempty"`
+
+	// lastBackupName is the blob name of the last successful backup.
+	// +optional
+	LastBackupName string `json:"lastBackupName,omitempty"`
+
+	// lastBackupSizeBytes is the size of the last successful backup.
+	// +optional
+	LastBackupSizeBytes int64 `json:"lastBackupSizeBytes,omitempty"`
+
+	// prunedBackups is the number of backups the last successful backup
+	// removed under spec.schedule.retentionDays.
+	// +optional
+	PrunedBackups int32 `json:"prunedBackups,omitempty"`
+
+	// lastFailureTime is when a backup last failed.
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+}
+
+// DatabaseSchemaMigrationsStatus is the outcome of the last schema
+// migration run.
+type DatabaseSchemaMigrationsStatus struct {
+	// version is the latest migration applied to the database.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// appliedTime is when the last run that applied migrations finished.
+	// +optional
+	AppliedTime *metav1.Time `json:"appliedTime,omitempty"`
+
+	// failedVersion is the migration the last run stopped at. Its changes
+	// were rolled back; the migrations before it stay applied.
+	// +optional
+	FailedVersion string `json:"failedVersion,omitempty"`
+
+	// message describes why the last run failed: the failure of
+	// failedVersion, or of the Job itself when failedVersion is empty.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// jobName is the Job that ran the migrations for the current source and
+	// server. A new Job runs when either changes.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+}
+
 // DatabaseStatus defines the observed state of Database.
 type DatabaseStatus struct {
 	// databaseName is the PostgreSQL database name managed by the operator.
 	// +optional
 	DatabaseName string `json:"databaseName,omitempty"`
 
+	// server is the DatabaseServer hosting the database: spec.server.name, or
+	// the server chosen with spec.serverSelector. While the database is moved
+	// to another server, it stays the server the database is moved from.
+	// +optional
+	Server string `json:"server,omitempty"`
+
 	// host is the PostgreSQL server host for this database.
 	// It is populated in a later reconciliation slice.
 	// +optional
@@ -159,6 +701,30 @@ type DatabaseStatus struct {
 	// +listMapKey=field
 	// +optional
 	ValidationErrors []DatabaseValidationError `json:"validationErrors,omitempty"`
+
+	// extensions reports each extension in spec.extensions, and each
+	// extension removed from it until it has been dropped.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Extensions []DatabaseExtensionStatus `json:"extensions,omitempty"`
+
+	// accessAudit is the result of the last periodic access audit.
+	// +optional
+	AccessAudit *DatabaseAccessAuditStatus `json:"accessAudit,omitempty"`
+
+	// migration is the state of the last move of the database to another
+	// DatabaseServer, started by changing spec.server.name.
+	// +optional
+	Migration *DatabaseMigrationStatus `json:"migration,omitempty"`
+
+	// backup is the outcome of the backups run by spec.schedule.
+	// +optional
+	Backup *DatabaseBackupStatus `json:"backup,omitempty"`
+
+	// schemaMigrations is the outcome of applying spec.schemaMigrations.
+	// +optional
+	SchemaMigrations *DatabaseSchemaMigrationsStatus `json:"schemaMigrations,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,79 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseAccessGrantFrom identifies the Databases allowed to grant access to
+// ApplicationIdentities in the grant's namespace.
+type DatabaseAccessGrantFrom struct {
+	// namespace is the namespace of the Databases.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace"`
+
+	// name limits the grant to one Database. When empty, every Database in
+	// namespace is allowed.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name,omitempty"`
+}
+
+// DatabaseAccessGrantTo identifies the ApplicationIdentities in the grant's
+// namespace that the Databases in from may grant access to.
+type DatabaseAccessGrantTo struct {
+	// name limits the grant to one ApplicationIdentity. When empty, every
+	// ApplicationIdentity in the grant's namespace may be referenced.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name,omitempty"`
+}
+
+// DatabaseAccessGrantSpec defines which Databases in other namespaces may
+// grant read access to ApplicationIdentities in this namespace.
+type DatabaseAccessGrantSpec struct {
+	// from are the Databases allowed to reference identities in this
+	// namespace.
+	// +listType=atomic
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	From []DatabaseAccessGrantFrom `json:"from"`
+
+	// to are the ApplicationIdentities in this namespace they may reference.
+	// +listType=atomic
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	To []DatabaseAccessGrantTo `json:"to"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseAccessGrant lets Databases in other namespaces grant read access to
+// ApplicationIdentities in its namespace. Like a Gateway API ReferenceGrant,
+// it lives in the namespace of the referenced identities, so the team owning
+// an identity decides which databases may bind it. The operator publishes the
+// connection details for such identities in this namespace.
+type DatabaseAccessGrant struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is standard object metadata.
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the Databases and identities the grant covers.
+	// +required
+	Spec DatabaseAccessGrantSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseAccessGrantList contains a list of DatabaseAccessGrant.
+type DatabaseAccessGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []DatabaseAccessGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseAccessGrant{}, &DatabaseAccessGrantList{})
+}
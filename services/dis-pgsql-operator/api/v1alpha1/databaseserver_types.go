@@ -108,6 +108,8 @@ type DatabaseServerNetworkSpec struct {
 // DatabaseServerSpec defines the desired state of DatabaseServer.
 // +kubebuilder:validation:XValidation:rule="(has(self.mode) && self.mode == 'Shared') ? has(self.network) : !has(self.network)",message="spec.network is required when mode is Shared and must be omitted when mode is Dedicated."
 // +kubebuilder:validation:XValidation:rule="!has(self.debugAccess) || !has(self.mode) || self.mode == 'Dedicated'",message="spec.debugAccess is only supported for dedicated servers (mode: Dedicated)."
+// +kubebuilder:validation:XValidation:rule="!has(self.encryption) || !has(self.geoRedundantBackup) || !self.geoRedundantBackup",message="spec.encryption cannot be combined with spec.geoRedundantBackup."
+// +kubebuilder:validation:XValidation:rule="!has(self.restore) || !has(self.restore.type) || self.restore.type != 'GeoRestore' || (has(self.mode) && self.mode == 'Shared')",message="spec.restore.type GeoRestore requires mode Shared with spec.network in the paired region."
 type DatabaseServerSpec struct {
 	// mode controls whether this DatabaseServer provisions a dedicated server or a shared server.
 	// Defaults to Dedicated.
@@ -117,13 +119,27 @@ type DatabaseServerSpec struct {
 	Mode DatabaseServerMode `json:"mode,omitempty"`
 
 	// version is the major version of PostgreSQL to run (e.g. 17).
+	// Raising it on an existing server starts an in-place major version
+	// upgrade: the operator runs pre-checks first and applies the upgrade in
+	// the maintenance window. Progress is reported in status.upgrade.
+	// Downgrades are rejected.
 	// +kubebuilder:validation:Minimum=9
 	Version int `json:"version"`
 
-	// serverType selects the size/profile of the database server (e.g. "dev", "prod").
+	// serverType selects the size/profile of the database server (e.g. "dev", "prod")
+	// when spec.profile is omitted.
 	// +kubebuilder:validation:MinLength=1
 	ServerType string `json:"serverType"`
 
+	// profile selects a named server profile from the operator configuration.
+	// A profile sets the SKU, default storage, high availability, backup
+	// retention, PgBouncer and the maximum number of databases on the server.
+	// When omitted, the profile named after serverType is used, falling back
+	// to the built-in dev or prod profile.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	Profile string `json:"profile,omitempty"`
+
 	// auth defines the identities used for server administration.
 	Auth DatabaseServerAuth `json:"auth"`
 
@@ -139,6 +155,8 @@ type DatabaseServerSpec struct {
 	EnableExtensions []DatabaseServerExtension `json:"enableExtensions,omitempty"`
 
 	// serverParams configures allowed PostgreSQL server parameters.
+	// Names and values are checked against the operator's server parameter catalog
+	// for spec.version; numeric values are in the parameter's base unit.
 	// azure.extensions and shared_preload_libraries are managed via enableExtensions.
 	// pgbouncer settings and max_connections are managed by the operator.
 	// +optional
@@ -150,21 +168,188 @@ type DatabaseServerSpec struct {
 	Storage *DatabaseServerStorageSpec `json:"storage,omitempty"`
 
 	// highAvailabilityEnabled controls whether PostgreSQL high availability is enabled.
-	// If omitted, it defaults to true for prod/production server types and false otherwise.
+	// If omitted, it defaults to the server profile's setting.
 	// +optional
 	HighAvailabilityEnabled *bool `json:"highAvailabilityEnabled,omitempty"`
 
 	// backupRetentionDays controls backup retention for the server.
-	// If omitted, it defaults to 14 for non-prod server types and 30 for prod/production.
+	// If omitted, it defaults to the server profile's retention.
 	// +optional
 	// +kubebuilder:validation:Minimum=7
 	// +kubebuilder:validation:Maximum=35
 	BackupRetentionDays *int `json:"backupRetentionDays,omitempty"`
 
+	// geoRedundantBackup also stores backups in the paired Azure region, so
+	// the server can be geo-restored after a regional outage. Azure only sets
+	// it when the server is created, so it cannot be changed afterwards. It is
+	// only allowed on profiles with geoRedundantBackupAllowed. Defaults to false.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="geoRedundantBackup is immutable"
+	GeoRedundantBackup *bool `json:"geoRedundantBackup,omitempty"`
+
 	// debugAccess grants read-only debug access to this server.
 	// It is only supported for dedicated servers.
 	// +optional
 	DebugAccess *DatabaseServerDebugAccessSpec `json:"debugAccess,omitempty"`
+
+	// restore creates the server as a point-in-time restore or geo-restore of
+	// another DatabaseServer instead of an empty server. It is only honoured when the
+	// Flexible Server is first created; later changes are ignored.
+	// +optional
+	Restore *DatabaseServerRestoreSpec `json:"restore,omitempty"`
+
+	// replicas declares read replicas of this server. Each entry provisions an
+	// Azure Flexible Server read replica with the same size and network as the
+	// primary. Read replicas are not supported on Burstable server types.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=5
+	Replicas []DatabaseServerReplicaSpec `json:"replicas,omitempty"`
+
+	// maintenanceWindow is the weekly window in which Azure performs planned
+	// maintenance on the server. Changes that restart the server, such as a
+	// new SKU or major version, are held until this window unless the
+	// storage.dis.altinn.cloud/apply-now annotation is set.
+	// Defaults to Sunday 03:00 UTC.
+	// +optional
+	MaintenanceWindow *DatabaseServerMaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// connectionPooling configures the built-in PgBouncer connection pooler,
+	// which listens on port 6432 of the server. When omitted, the server
+	// profile decides whether PgBouncer runs. It is not supported on Burstable
+	// profiles.
+	// +optional
+	ConnectionPooling *DatabaseServerConnectionPoolingSpec `json:"connectionPooling,omitempty"`
+
+	// encryption encrypts the server's data with a customer-managed key in a
+	// dis-vault Vault instead of a key managed by Azure. Azure only accepts it
+	// when the server is created, so it cannot be changed afterwards.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="encryption is immutable"
+	Encryption *DatabaseServerEncryptionSpec `json:"encryption,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Transaction;Session;Statement
+// ConnectionPoolMode is the PgBouncer pool mode.
+type ConnectionPoolMode string
+
+const (
+	ConnectionPoolModeTransaction ConnectionPoolMode = "Transaction"
+	ConnectionPoolModeSession     ConnectionPoolMode = "Session"
+	ConnectionPoolModeStatement   ConnectionPoolMode = "Statement"
+)
+
+// DatabaseServerConnectionPoolingSpec configures the built-in PgBouncer.
+type DatabaseServerConnectionPoolingSpec struct {
+	// enabled turns PgBouncer on or off. Defaults to true.
+	// +optional
+	// +kubebuilder:default=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// poolMode controls when a server connection is returned to the pool.
+	// Defaults to Transaction.
+	// +optional
+	// +kubebuilder:default=Transaction
+	PoolMode ConnectionPoolMode `json:"poolMode,omitempty"`
+}
+
+// Default maintenance window, Sunday 03:00 UTC, used when
+// spec.maintenanceWindow is omitted.
+const (
+	DefaultMaintenanceDayOfWeek   = 0
+	DefaultMaintenanceStartHour   = 3
+	DefaultMaintenanceStartMinute = 0
+)
+
+// DatabaseServerMaintenanceWindow is a weekly one-hour window in UTC.
+type DatabaseServerMaintenanceWindow struct {
+	// dayOfWeek is the day the window starts, from 0 (Sunday) to 6 (Saturday).
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=6
+	DayOfWeek int `json:"dayOfWeek"`
+
+	// startHour is the UTC hour the window starts.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=23
+	StartHour int `json:"startHour"`
+
+	// startMinute is the minute the window starts. Defaults to 0.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=59
+	StartMinute int `json:"startMinute,omitempty"`
+}
+
+// DatabaseServerReplicaSpec declares one read replica.
+type DatabaseServerReplicaSpec struct {
+	// name identifies the replica. It is appended to the primary's Azure
+	// server name to form the replica's Azure server name.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+}
+
+// +kubebuilder:validation:Enum=PointInTime;GeoRestore
+// DatabaseServerRestoreType selects how a server is restored.
+type DatabaseServerRestoreType string
+
+const (
+	// DatabaseServerRestoreTypePointInTime restores the source server to a
+	// timestamp in the same region.
+	DatabaseServerRestoreTypePointInTime DatabaseServerRestoreType = "PointInTime"
+	// DatabaseServerRestoreTypeGeoRestore restores the latest geo-redundant
+	// backup of the source server in the paired region.
+	DatabaseServerRestoreTypeGeoRestore DatabaseServerRestoreType = "GeoRestore"
+)
+
+// DatabaseServerRestoreSpec selects the source server and timestamp for a
+// point-in-time restore, or the source server for a geo-restore.
+// +kubebuilder:validation:XValidation:rule="(has(self.type) && self.type == 'GeoRestore') ? !has(self.pointInTime) : has(self.pointInTime)",message="pointInTime is required for a PointInTime restore and must be omitted for a GeoRestore."
+type DatabaseServerRestoreSpec struct {
+	// type is PointInTime to restore to a timestamp in the same region, or
+	// GeoRestore to create the server in the paired region from the source
+	// server's latest geo-redundant backup. A GeoRestore requires mode Shared,
+	// a spec.network subnet the operator lists as a paired-region subnet, and
+	// a source with geoRedundantBackup enabled. Defaults to PointInTime.
+	// +optional
+	// +kubebuilder:default=PointInTime
+	Type DatabaseServerRestoreType `json:"type,omitempty"`
+
+	// sourceServer is the same-namespace DatabaseServer to restore from.
+	// Its Flexible Server must be provisioned (status.resourceId set).
+	SourceServer DatabaseServerReference `json:"sourceServer"`
+
+	// pointInTime is the UTC timestamp to restore to. It must be in the past
+	// and within the source server's backup retention window. It is required
+	// for a PointInTime restore.
+	// +optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+}
+
+// VaultReference references a dis-vault Vault in the same namespace.
+type VaultReference struct {
+	// name is the Vault (vault.dis.altinn.cloud) in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// DatabaseServerEncryptionSpec selects the customer-managed key that encrypts
+// the server's data.
+type DatabaseServerEncryptionSpec struct {
+	// vaultRef is the Vault that holds the key.
+	VaultRef VaultReference `json:"vaultRef"`
+
+	// keyName is the name of the key in the vault. The operator creates an
+	// RSA key with this name when the vault has none.
+	// +kubebuilder:validation:Pattern=`^[0-9a-zA-Z-]{1,127}$`
+	KeyName string `json:"keyName"`
+
+	// identityRef is the ApplicationIdentity the server uses to read the key.
+	// The operator grants it the Key Vault Crypto Service Encryption User
+	// role on the key.
+	IdentityRef ApplicationIdentityRef `json:"identityRef"`
 }
 
 // DatabaseServerDebugAccessSpec grants read-only debug access to this server.
@@ -181,7 +366,10 @@ type DatabaseServerDebugAccessSpec struct {
 }
 
 // +kubebuilder:validation:XValidation:rule="(has(self.identityRef) ? 1 : 0) + (has(self.group) ? 1 : 0) + (has(self.servicePrincipal) ? 1 : 0) == 1",message="Provide exactly one principal source: identityRef, group, or servicePrincipal."
+// +kubebuilder:validation:XValidation:rule="!(has(self.expiresAt) && has(self.duration))",message="Set at most one of expiresAt and duration."
 // DebugAccessPrincipalSpec identifies one principal granted debug access.
+// Without expiresAt or duration the access stands until the principal is
+// removed.
 type DebugAccessPrincipalSpec struct {
 	// identityRef points to an ApplicationIdentity in the same namespace.
 	// +optional
@@ -194,11 +382,51 @@ type DebugAccessPrincipalSpec struct {
 	// servicePrincipal identifies an existing Entra service principal by object id.
 	// +optional
 	ServicePrincipal *DatabaseServicePrincipalSpec `json:"servicePrincipal,omitempty"`
+
+	// expiresAt is when the principal's debug access is revoked.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// duration is how long the principal's debug access lasts, counted from
+	// when the operator first granted it (status.debugAccessGrants[].grantedAt).
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// justification records why the access is needed. Changing it starts a
+	// new grant, so an expired duration-based grant can be renewed.
+	// +optional
+	// +kubebuilder:validation:MaxLength=512
+	Justification string `json:"justification,omitempty"`
+}
+
+// DatabaseServerDebugAccessGrant is the observed state of one debug access
+// principal.
+type DatabaseServerDebugAccessGrant struct {
+	// principal identifies the spec principal as identityRef/<name>,
+	// group/<principalId> or servicePrincipal/<principalId>.
+	Principal string `json:"principal"`
+
+	// grantedAt is when the operator started granting the current access.
+	GrantedAt metav1.Time `json:"grantedAt"`
+
+	// expiresAt is when the access is revoked. Unset for standing access.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// expired is true once expiresAt has passed. The Azure role assignment
+	// and the PostgreSQL debug role membership of an expired grant are
+	// revoked.
+	// +optional
+	Expired bool `json:"expired,omitempty"`
+
+	// justification is the justification the access was granted with.
+	// +optional
+	Justification string `json:"justification,omitempty"`
 }
 
 type DatabaseServerStorageSpec struct {
 	// sizeGB is the initial storage size in GB.
-	// If omitted, the operator will default it.
+	// If omitted, it defaults to the server profile's storage size.
 	// +optional
 	SizeGB *int32 `json:"sizeGB,omitempty"`
 
@@ -208,6 +436,142 @@ type DatabaseServerStorageSpec struct {
 	Tier *string `json:"tier,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Pending;Restoring;Completed;Failed
+// DatabaseServerRestorePhase is the progress of a restore.
+type DatabaseServerRestorePhase string
+
+const (
+	// DatabaseServerRestorePhasePending means the restore is waiting for its
+	// source server to be provisioned.
+	DatabaseServerRestorePhasePending DatabaseServerRestorePhase = "Pending"
+	// DatabaseServerRestorePhaseRestoring means the Flexible Server has been
+	// requested as a restore and Azure is still provisioning it.
+	DatabaseServerRestorePhaseRestoring DatabaseServerRestorePhase = "Restoring"
+	// DatabaseServerRestorePhaseCompleted means the restored server is ready.
+	DatabaseServerRestorePhaseCompleted DatabaseServerRestorePhase = "Completed"
+	// DatabaseServerRestorePhaseFailed means the restore request is invalid
+	// (for example a timestamp outside the retention window).
+	DatabaseServerRestorePhaseFailed DatabaseServerRestorePhase = "Failed"
+)
+
+// DatabaseServerRestoreStatus reports the restore used to create the server.
+type DatabaseServerRestoreStatus struct {
+	// phase is the current restore progress.
+	// +optional
+	Phase DatabaseServerRestorePhase `json:"phase,omitempty"`
+
+	// type is the kind of restore, PointInTime or GeoRestore.
+	// +optional
+	Type DatabaseServerRestoreType `json:"type,omitempty"`
+
+	// sourceServerName is the DatabaseServer the restore was taken from.
+	// +optional
+	SourceServerName string `json:"sourceServerName,omitempty"`
+
+	// sourceResourceId is the ARM resource id of the source Flexible Server.
+	// +optional
+	SourceResourceID string `json:"sourceResourceId,omitempty"`
+
+	// pointInTime is the UTC timestamp the server was restored to. It is
+	// unset for a geo-restore, which uses the latest geo-redundant backup.
+	// +optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+
+	// location is the Azure region the restored server was created in.
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// message is a human-readable explanation of the current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DatabaseServerEncryptionStatus reports the customer-managed key that
+// encrypts the server.
+type DatabaseServerEncryptionStatus struct {
+	// keyUri is the versionless URI of the key. The server follows new
+	// versions of the key, so rotating the key needs no change here.
+	// +optional
+	KeyURI string `json:"keyUri,omitempty"`
+
+	// keyVersion is the current version of the key.
+	// +optional
+	KeyVersion string `json:"keyVersion,omitempty"`
+
+	// rotationTime is when the operator first saw keyVersion after a
+	// rotation. It is unset until the key is rotated.
+	// +optional
+	RotationTime *metav1.Time `json:"rotationTime,omitempty"`
+
+	// checkedTime is when the operator last checked the key.
+	// +optional
+	CheckedTime *metav1.Time `json:"checkedTime,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=PreChecking;Pending;Upgrading;Completed;Failed
+// DatabaseServerUpgradePhase is the progress of a major version upgrade.
+type DatabaseServerUpgradePhase string
+
+const (
+	// DatabaseServerUpgradePhasePreChecking means the pre-check Job is
+	// inspecting the server's extensions and storage.
+	DatabaseServerUpgradePhasePreChecking DatabaseServerUpgradePhase = "PreChecking"
+	// DatabaseServerUpgradePhasePending means the pre-checks passed and the
+	// upgrade waits for the maintenance window.
+	DatabaseServerUpgradePhasePending DatabaseServerUpgradePhase = "Pending"
+	// DatabaseServerUpgradePhaseUpgrading means the new version has been
+	// requested and Azure is upgrading the server.
+	DatabaseServerUpgradePhaseUpgrading DatabaseServerUpgradePhase = "Upgrading"
+	// DatabaseServerUpgradePhaseCompleted means the server runs the new version.
+	DatabaseServerUpgradePhaseCompleted DatabaseServerUpgradePhase = "Completed"
+	// DatabaseServerUpgradePhaseFailed means the pre-checks found problems.
+	// They are re-run periodically, so fixing the problems resumes the upgrade.
+	DatabaseServerUpgradePhaseFailed DatabaseServerUpgradePhase = "Failed"
+)
+
+// DatabaseServerUpgradeStatus reports the most recent major version upgrade.
+type DatabaseServerUpgradeStatus struct {
+	// phase is the current upgrade progress.
+	// +optional
+	Phase DatabaseServerUpgradePhase `json:"phase,omitempty"`
+
+	// fromVersion is the major version the server is upgraded from.
+	// +optional
+	FromVersion int `json:"fromVersion,omitempty"`
+
+	// toVersion is the major version the server is upgraded to.
+	// +optional
+	ToVersion int `json:"toVersion,omitempty"`
+
+	// preCheckTime is when the pre-checks last reported.
+	// +optional
+	PreCheckTime *metav1.Time `json:"preCheckTime,omitempty"`
+
+	// message is a human-readable explanation of the current phase, including
+	// the problems found by failed pre-checks.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DatabaseServerReplicaStatus reports the observed state of one read replica.
+type DatabaseServerReplicaStatus struct {
+	// name is the replica name from spec.replicas.
+	Name string `json:"name"`
+
+	// serverName is the Azure PostgreSQL Flexible Server name of the replica.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// host is the fully qualified DNS name of the replica. It is populated
+	// once Azure has provisioned the replica.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// ready reports whether the replica's Flexible Server is ready.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+}
+
 // DatabaseServerParameterError captures a failed server parameter reconciliation.
 type DatabaseServerParameterError struct {
 	// name is the PostgreSQL server parameter name that failed.
@@ -223,6 +587,49 @@ type DatabaseServerParameterError struct {
 	Message string `json:"message,omitempty"`
 }
 
+// DatabaseServerOrphanedDatabase is a database found on the server that no
+// Database resource manages.
+type DatabaseServerOrphanedDatabase struct {
+	// name is the PostgreSQL database name.
+	Name string `json:"name"`
+
+	// sizeBytes is the on-disk size of the database.
+	// +optional
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+
+	// sessions is the total number of sessions established to the database as
+	// reported by pg_stat_database (PostgreSQL 14 and later). It is compared
+	// between catalog runs to detect connections.
+	// +optional
+	Sessions int64 `json:"sessions,omitempty"`
+
+	// lastConnectionTime is the most recent catalog run that saw the database
+	// in use, either with open connections or with new sessions since the
+	// previous run. It is empty while the database has not been seen in use.
+	// +optional
+	LastConnectionTime *metav1.Time `json:"lastConnectionTime,omitempty"`
+}
+
+// DatabaseServerDatabaseCatalog reports the databases on a shared server that
+// are not managed by any Database resource, e.g. databases left behind by the
+// Retain deletion policy.
+type DatabaseServerDatabaseCatalog struct {
+	// observedTime is when the catalog was last collected from the server.
+	// +optional
+	ObservedTime *metav1.Time `json:"observedTime,omitempty"`
+
+	// truncated is true when the server had more databases than fit in one
+	// catalog report; the largest databases are kept.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+
+	// orphanedDatabases lists the databases without a matching Database.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	OrphanedDatabases []DatabaseServerOrphanedDatabase `json:"orphanedDatabases,omitempty"`
+}
+
 // DatabaseServerStatus defines the observed state of DatabaseServer.
 type DatabaseServerStatus struct {
 	// subnetCIDR is the /28 network block allocated for this database's subnet.
@@ -253,6 +660,11 @@ type DatabaseServerStatus struct {
 	// +optional
 	ResourceID string `json:"resourceId,omitempty"`
 
+	// version is the PostgreSQL major version Azure reports for the server.
+	// It differs from spec.version while an upgrade is pending or running.
+	// +optional
+	Version int `json:"version,omitempty"`
+
 	// debugAccessProvisionedHash is an opaque marker of the debug-access
 	// principal set most recently provisioned into PostgreSQL (data plane).
 	// A non-empty value means grants may exist in the server, so when
@@ -261,6 +673,38 @@ type DatabaseServerStatus struct {
 	// +optional
 	DebugAccessProvisionedHash string `json:"debugAccessProvisionedHash,omitempty"`
 
+	// debugAccessGrants reports each principal in spec.debugAccess and when
+	// its access expires.
+	// +listType=map
+	// +listMapKey=principal
+	// +optional
+	DebugAccessGrants []DatabaseServerDebugAccessGrant `json:"debugAccessGrants,omitempty"`
+
+	// encryption reports the customer-managed key requested by
+	// spec.encryption.
+	// +optional
+	Encryption *DatabaseServerEncryptionStatus `json:"encryption,omitempty"`
+
+	// restore reports the restore requested by spec.restore.
+	// +optional
+	Restore *DatabaseServerRestoreStatus `json:"restore,omitempty"`
+
+	// upgrade reports the major version upgrade requested by raising
+	// spec.version.
+	// +optional
+	Upgrade *DatabaseServerUpgradeStatus `json:"upgrade,omitempty"`
+
+	// replicas reports the read replicas declared in spec.replicas.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Replicas []DatabaseServerReplicaStatus `json:"replicas,omitempty"`
+
+	// databaseCatalog reports databases on a shared server that have no
+	// matching Database resource. It is refreshed periodically.
+	// +optional
+	DatabaseCatalog *DatabaseServerDatabaseCatalog `json:"databaseCatalog,omitempty"`
+
 	// conditions represent the current state of the DatabaseServer resource.
 	// Each condition has a unique type and reflects the status of a specific aspect of the resource.
 	//
@@ -83,12 +83,179 @@ func (in *Database) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessAuditSpec) DeepCopyInto(out *DatabaseAccessAuditSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessAuditSpec.
+func (in *DatabaseAccessAuditSpec) DeepCopy() *DatabaseAccessAuditSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessAuditSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessAuditStatus) DeepCopyInto(out *DatabaseAccessAuditStatus) {
+	*out = *in
+	if in.ObservedTime != nil {
+		in, out := &in.ObservedTime, &out.ObservedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]DatabaseAccessDrift, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessAuditStatus.
+func (in *DatabaseAccessAuditStatus) DeepCopy() *DatabaseAccessAuditStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessAuditStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessDrift) DeepCopyInto(out *DatabaseAccessDrift) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessDrift.
+func (in *DatabaseAccessDrift) DeepCopy() *DatabaseAccessDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessGrant) DeepCopyInto(out *DatabaseAccessGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessGrant.
+func (in *DatabaseAccessGrant) DeepCopy() *DatabaseAccessGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseAccessGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessGrantFrom) DeepCopyInto(out *DatabaseAccessGrantFrom) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessGrantFrom.
+func (in *DatabaseAccessGrantFrom) DeepCopy() *DatabaseAccessGrantFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessGrantFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessGrantList) DeepCopyInto(out *DatabaseAccessGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseAccessGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessGrantList.
+func (in *DatabaseAccessGrantList) DeepCopy() *DatabaseAccessGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseAccessGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessGrantSpec) DeepCopyInto(out *DatabaseAccessGrantSpec) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]DatabaseAccessGrantFrom, len(*in))
+		copy(*out, *in)
+	}
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]DatabaseAccessGrantTo, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessGrantSpec.
+func (in *DatabaseAccessGrantSpec) DeepCopy() *DatabaseAccessGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessGrantTo) DeepCopyInto(out *DatabaseAccessGrantTo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessGrantTo.
+func (in *DatabaseAccessGrantTo) DeepCopy() *DatabaseAccessGrantTo {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessGrantTo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseAccessPrincipalSpec) DeepCopyInto(out *DatabaseAccessPrincipalSpec) {
 	*out = *in
 	if in.IdentityRef != nil {
 		in, out := &in.IdentityRef, &out.IdentityRef
-		*out = new(ApplicationIdentityRef)
+		*out = new(DatabaseIdentityRef)
 		**out = **in
 	}
 	if in.Group != nil {
@@ -101,6 +268,26 @@ func (in *DatabaseAccessPrincipalSpec) DeepCopyInto(out *DatabaseAccessPrincipal
 		*out = new(DatabaseServicePrincipalSpec)
 		**out = **in
 	}
+	if in.Scope != nil {
+		in, out := &in.Scope, &out.Scope
+		*out = new(DatabaseAccessScope)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StatementTimeout != nil {
+		in, out := &in.StatementTimeout, &out.StatementTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.IdleInTransactionSessionTimeout != nil {
+		in, out := &in.IdleInTransactionSessionTimeout, &out.IdleInTransactionSessionTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessPrincipalSpec.
@@ -113,6 +300,31 @@ func (in *DatabaseAccessPrincipalSpec) DeepCopy() *DatabaseAccessPrincipalSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccessScope) DeepCopyInto(out *DatabaseAccessScope) {
+	*out = *in
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]DatabaseTableReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccessScope.
+func (in *DatabaseAccessScope) DeepCopy() *DatabaseAccessScope {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccessScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseAccessSpec) DeepCopyInto(out *DatabaseAccessSpec) {
 	*out = *in
@@ -135,6 +347,119 @@ func (in *DatabaseAccessSpec) DeepCopy() *DatabaseAccessSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupScheduleSpec) DeepCopyInto(out *DatabaseBackupScheduleSpec) {
+	*out = *in
+	if in.RetentionDays != nil {
+		in, out := &in.RetentionDays, &out.RetentionDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupScheduleSpec.
+func (in *DatabaseBackupScheduleSpec) DeepCopy() *DatabaseBackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupStatus) DeepCopyInto(out *DatabaseBackupStatus) {
+	*out = *in
+	if in.LastBackupTime != nil {
+		in, out := &in.LastBackupTime, &out.LastBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupStatus.
+func (in *DatabaseBackupStatus) DeepCopy() *DatabaseBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseConfigMapReference) DeepCopyInto(out *DatabaseConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseConfigMapReference.
+func (in *DatabaseConfigMapReference) DeepCopy() *DatabaseConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseConnectionSpec) DeepCopyInto(out *DatabaseConnectionSpec) {
+	*out = *in
+	if in.Formats != nil {
+		in, out := &in.Formats, &out.Formats
+		*out = make([]DatabaseConnectionFormat, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraKeys != nil {
+		in, out := &in.ExtraKeys, &out.ExtraKeys
+		*out = make([]DatabaseConnectionKey, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseConnectionSpec.
+func (in *DatabaseConnectionSpec) DeepCopy() *DatabaseConnectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseConnectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseExtensionSpec) DeepCopyInto(out *DatabaseExtensionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseExtensionSpec.
+func (in *DatabaseExtensionSpec) DeepCopy() *DatabaseExtensionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseExtensionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseExtensionStatus) DeepCopyInto(out *DatabaseExtensionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseExtensionStatus.
+func (in *DatabaseExtensionStatus) DeepCopy() *DatabaseExtensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseExtensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseGroupPrincipalSpec) DeepCopyInto(out *DatabaseGroupPrincipalSpec) {
 	*out = *in
@@ -151,37 +476,130 @@ func (in *DatabaseGroupPrincipalSpec) DeepCopy() *DatabaseGroupPrincipalSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
+func (in *DatabaseIdentityRef) DeepCopyInto(out *DatabaseIdentityRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseIdentityRef.
+func (in *DatabaseIdentityRef) DeepCopy() *DatabaseIdentityRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseIdentityRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Database, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseList.
+func (in *DatabaseList) DeepCopy() *DatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseLocalIdentityRef) DeepCopyInto(out *DatabaseLocalIdentityRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseLocalIdentityRef.
+func (in *DatabaseLocalIdentityRef) DeepCopy() *DatabaseLocalIdentityRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseLocalIdentityRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigrationStatus) DeepCopyInto(out *DatabaseMigrationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigrationStatus.
+func (in *DatabaseMigrationStatus) DeepCopy() *DatabaseMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseSchemaMigrationsSpec) DeepCopyInto(out *DatabaseSchemaMigrationsSpec) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(DatabaseConfigMapReference)
+		**out = **in
+	}
+	out.IdentityRef = in.IdentityRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSchemaMigrationsSpec.
+func (in *DatabaseSchemaMigrationsSpec) DeepCopy() *DatabaseSchemaMigrationsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseSchemaMigrationsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseSchemaMigrationsStatus) DeepCopyInto(out *DatabaseSchemaMigrationsStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Database, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.AppliedTime != nil {
+		in, out := &in.AppliedTime, &out.AppliedTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseList.
-func (in *DatabaseList) DeepCopy() *DatabaseList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSchemaMigrationsStatus.
+func (in *DatabaseSchemaMigrationsStatus) DeepCopy() *DatabaseSchemaMigrationsStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DatabaseList)
+	out := new(DatabaseSchemaMigrationsStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DatabaseList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServer) DeepCopyInto(out *DatabaseServer) {
 	*out = *in
@@ -230,6 +648,72 @@ func (in *DatabaseServerAuth) DeepCopy() *DatabaseServerAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerConnectionPoolingSpec) DeepCopyInto(out *DatabaseServerConnectionPoolingSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerConnectionPoolingSpec.
+func (in *DatabaseServerConnectionPoolingSpec) DeepCopy() *DatabaseServerConnectionPoolingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerConnectionPoolingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerDatabaseCatalog) DeepCopyInto(out *DatabaseServerDatabaseCatalog) {
+	*out = *in
+	if in.ObservedTime != nil {
+		in, out := &in.ObservedTime, &out.ObservedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OrphanedDatabases != nil {
+		in, out := &in.OrphanedDatabases, &out.OrphanedDatabases
+		*out = make([]DatabaseServerOrphanedDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerDatabaseCatalog.
+func (in *DatabaseServerDatabaseCatalog) DeepCopy() *DatabaseServerDatabaseCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerDatabaseCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerDebugAccessGrant) DeepCopyInto(out *DatabaseServerDebugAccessGrant) {
+	*out = *in
+	in.GrantedAt.DeepCopyInto(&out.GrantedAt)
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerDebugAccessGrant.
+func (in *DatabaseServerDebugAccessGrant) DeepCopy() *DatabaseServerDebugAccessGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerDebugAccessGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerDebugAccessSpec) DeepCopyInto(out *DatabaseServerDebugAccessSpec) {
 	*out = *in
@@ -252,6 +736,46 @@ func (in *DatabaseServerDebugAccessSpec) DeepCopy() *DatabaseServerDebugAccessSp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerEncryptionSpec) DeepCopyInto(out *DatabaseServerEncryptionSpec) {
+	*out = *in
+	out.VaultRef = in.VaultRef
+	out.IdentityRef = in.IdentityRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerEncryptionSpec.
+func (in *DatabaseServerEncryptionSpec) DeepCopy() *DatabaseServerEncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerEncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerEncryptionStatus) DeepCopyInto(out *DatabaseServerEncryptionStatus) {
+	*out = *in
+	if in.RotationTime != nil {
+		in, out := &in.RotationTime, &out.RotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CheckedTime != nil {
+		in, out := &in.CheckedTime, &out.CheckedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerEncryptionStatus.
+func (in *DatabaseServerEncryptionStatus) DeepCopy() *DatabaseServerEncryptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerEncryptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerList) DeepCopyInto(out *DatabaseServerList) {
 	*out = *in
@@ -284,6 +808,21 @@ func (in *DatabaseServerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerMaintenanceWindow) DeepCopyInto(out *DatabaseServerMaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerMaintenanceWindow.
+func (in *DatabaseServerMaintenanceWindow) DeepCopy() *DatabaseServerMaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerMaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerNetworkSpec) DeepCopyInto(out *DatabaseServerNetworkSpec) {
 	*out = *in
@@ -299,6 +838,25 @@ func (in *DatabaseServerNetworkSpec) DeepCopy() *DatabaseServerNetworkSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerOrphanedDatabase) DeepCopyInto(out *DatabaseServerOrphanedDatabase) {
+	*out = *in
+	if in.LastConnectionTime != nil {
+		in, out := &in.LastConnectionTime, &out.LastConnectionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerOrphanedDatabase.
+func (in *DatabaseServerOrphanedDatabase) DeepCopy() *DatabaseServerOrphanedDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerOrphanedDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerParameter) DeepCopyInto(out *DatabaseServerParameter) {
 	*out = *in
@@ -345,6 +903,97 @@ func (in *DatabaseServerReference) DeepCopy() *DatabaseServerReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerReplicaSpec) DeepCopyInto(out *DatabaseServerReplicaSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerReplicaSpec.
+func (in *DatabaseServerReplicaSpec) DeepCopy() *DatabaseServerReplicaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerReplicaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerReplicaStatus) DeepCopyInto(out *DatabaseServerReplicaStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerReplicaStatus.
+func (in *DatabaseServerReplicaStatus) DeepCopy() *DatabaseServerReplicaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerReplicaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerRestoreSpec) DeepCopyInto(out *DatabaseServerRestoreSpec) {
+	*out = *in
+	out.SourceServer = in.SourceServer
+	if in.PointInTime != nil {
+		in, out := &in.PointInTime, &out.PointInTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerRestoreSpec.
+func (in *DatabaseServerRestoreSpec) DeepCopy() *DatabaseServerRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerRestoreStatus) DeepCopyInto(out *DatabaseServerRestoreStatus) {
+	*out = *in
+	if in.PointInTime != nil {
+		in, out := &in.PointInTime, &out.PointInTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerRestoreStatus.
+func (in *DatabaseServerRestoreStatus) DeepCopy() *DatabaseServerRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerSelector) DeepCopyInto(out *DatabaseServerSelector) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerSelector.
+func (in *DatabaseServerSelector) DeepCopy() *DatabaseServerSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerSpec) DeepCopyInto(out *DatabaseServerSpec) {
 	*out = *in
@@ -379,11 +1028,41 @@ func (in *DatabaseServerSpec) DeepCopyInto(out *DatabaseServerSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.GeoRedundantBackup != nil {
+		in, out := &in.GeoRedundantBackup, &out.GeoRedundantBackup
+		*out = new(bool)
+		**out = **in
+	}
 	if in.DebugAccess != nil {
 		in, out := &in.DebugAccess, &out.DebugAccess
 		*out = new(DatabaseServerDebugAccessSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(DatabaseServerRestoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = make([]DatabaseServerReplicaSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(DatabaseServerMaintenanceWindow)
+		**out = **in
+	}
+	if in.ConnectionPooling != nil {
+		in, out := &in.ConnectionPooling, &out.ConnectionPooling
+		*out = new(DatabaseServerConnectionPoolingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(DatabaseServerEncryptionSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerSpec.
@@ -399,6 +1078,38 @@ func (in *DatabaseServerSpec) DeepCopy() *DatabaseServerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServerStatus) DeepCopyInto(out *DatabaseServerStatus) {
 	*out = *in
+	if in.DebugAccessGrants != nil {
+		in, out := &in.DebugAccessGrants, &out.DebugAccessGrants
+		*out = make([]DatabaseServerDebugAccessGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(DatabaseServerEncryptionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(DatabaseServerRestoreStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(DatabaseServerUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = make([]DatabaseServerReplicaStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatabaseCatalog != nil {
+		in, out := &in.DatabaseCatalog, &out.DatabaseCatalog
+		*out = new(DatabaseServerDatabaseCatalog)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -448,6 +1159,25 @@ func (in *DatabaseServerStorageSpec) DeepCopy() *DatabaseServerStorageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerUpgradeStatus) DeepCopyInto(out *DatabaseServerUpgradeStatus) {
+	*out = *in
+	if in.PreCheckTime != nil {
+		in, out := &in.PreCheckTime, &out.PreCheckTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerUpgradeStatus.
+func (in *DatabaseServerUpgradeStatus) DeepCopy() *DatabaseServerUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseServicePrincipalSpec) DeepCopyInto(out *DatabaseServicePrincipalSpec) {
 	*out = *in
@@ -467,7 +1197,42 @@ func (in *DatabaseServicePrincipalSpec) DeepCopy() *DatabaseServicePrincipalSpec
 func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 	*out = *in
 	out.Server = in.Server
+	if in.ServerSelector != nil {
+		in, out := &in.ServerSelector, &out.ServerSelector
+		*out = new(DatabaseServerSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Access.DeepCopyInto(&out.Access)
+	if in.DeletionGracePeriod != nil {
+		in, out := &in.DeletionGracePeriod, &out.DeletionGracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]DatabaseExtensionSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		*out = new(DatabaseConnectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccessAudit != nil {
+		in, out := &in.AccessAudit, &out.AccessAudit
+		*out = new(DatabaseAccessAuditSpec)
+		**out = **in
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(DatabaseBackupScheduleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchemaMigrations != nil {
+		in, out := &in.SchemaMigrations, &out.SchemaMigrations
+		*out = new(DatabaseSchemaMigrationsSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSpec.
@@ -495,6 +1260,31 @@ func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
 		*out = make([]DatabaseValidationError, len(*in))
 		copy(*out, *in)
 	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]DatabaseExtensionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.AccessAudit != nil {
+		in, out := &in.AccessAudit, &out.AccessAudit
+		*out = new(DatabaseAccessAuditStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(DatabaseMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(DatabaseBackupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchemaMigrations != nil {
+		in, out := &in.SchemaMigrations, &out.SchemaMigrations
+		*out = new(DatabaseSchemaMigrationsStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
@@ -507,6 +1297,21 @@ func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseTableReference) DeepCopyInto(out *DatabaseTableReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseTableReference.
+func (in *DatabaseTableReference) DeepCopy() *DatabaseTableReference {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseTableReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseValidationError) DeepCopyInto(out *DatabaseValidationError) {
 	*out = *in
@@ -540,6 +1345,15 @@ func (in *DebugAccessPrincipalSpec) DeepCopyInto(out *DebugAccessPrincipalSpec)
 		*out = new(DatabaseServicePrincipalSpec)
 		**out = **in
 	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugAccessPrincipalSpec.
@@ -587,3 +1401,18 @@ func (in *UserIdentitySpec) DeepCopy() *UserIdentitySpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultReference) DeepCopyInto(out *VaultReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultReference.
+func (in *VaultReference) DeepCopy() *VaultReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultReference)
+	in.DeepCopyInto(out)
+	return out
+}
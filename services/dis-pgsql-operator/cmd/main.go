@@ -22,6 +22,7 @@ import (
 	"errors"
 	"flag"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -40,6 +41,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -50,7 +52,9 @@ import (
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/controller"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/keyvault"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/network"
+	webhookstoragev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/webhook/v1alpha1"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/test/azfakes"
 	// +kubebuilder:scaffold:imports
 )
@@ -98,6 +102,10 @@ func main() {
 	var userProvisionImage string
 	var clusterID string
 	var rawBaseTags string
+	var serverProfilesFile string
+	var migrationImage string
+	var backupStorageAccounts string
+	var pairedRegionSubnetIDs string
 	var provisionUser bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -174,6 +182,34 @@ func main() {
 		os.Getenv("DISPG_BASE_TAGS"),
 		"JSON object of platform base tags applied to every Azure resource this operator creates (optional)",
 	)
+	flag.StringVar(
+		&serverProfilesFile,
+		"server-profiles-file",
+		os.Getenv("DISPG_SERVER_PROFILES_FILE"),
+		"Path to a YAML or JSON file of named DatabaseServer profiles merged over the built-in "+
+			"dev and prod profiles (optional)",
+	)
+	flag.StringVar(
+		&migrationImage,
+		"migration-image",
+		os.Getenv("DISPG_MIGRATION_IMAGE"),
+		"PostgreSQL client image that runs pg_dump and pg_restore when a Database moves to "+
+			"another server (optional, defaults to "+config.DefaultMigrationImage+")",
+	)
+	flag.StringVar(
+		&backupStorageAccounts,
+		"backup-storage-accounts",
+		os.Getenv("DISPG_BACKUP_STORAGE_ACCOUNTS"),
+		"Comma-separated storage accounts Database backups may be uploaded to (optional, "+
+			"no Blob Storage backups without it)",
+	)
+	flag.StringVar(
+		&pairedRegionSubnetIDs,
+		"paired-region-subnet-ids",
+		os.Getenv("DISPG_PAIRED_REGION_SUBNET_IDS"),
+		"Comma-separated ARM IDs of the delegated subnets in the paired region a geo-restore may use (optional, "+
+			"no geo-restore without it)",
+	)
 
 	opts := zap.Options{
 		Development: true,
@@ -261,6 +297,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  controller.CacheOptions(),
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -324,6 +361,33 @@ func main() {
 		setupLog.Error(err, "ignoring invalid base-tags value; Azure resources will not receive platform tags")
 	}
 	opCfg.BaseTags = baseTags
+	opCfg.MigrationImage = strings.TrimSpace(migrationImage)
+	for account := range strings.SplitSeq(backupStorageAccounts, ",") {
+		if account = strings.TrimSpace(account); account != "" {
+			opCfg.BackupStorageAccounts = append(opCfg.BackupStorageAccounts, account)
+		}
+	}
+	for subnetID := range strings.SplitSeq(pairedRegionSubnetIDs, ",") {
+		if subnetID = strings.TrimSpace(subnetID); subnetID != "" {
+			opCfg.PairedRegionSubnets = append(opCfg.PairedRegionSubnets, subnetID)
+		}
+	}
+
+	var rawServerProfiles []byte
+	if serverProfilesFile != "" {
+		rawServerProfiles, err = os.ReadFile(serverProfilesFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read server profiles", "path", serverProfilesFile)
+			os.Exit(1)
+		}
+	}
+	serverProfiles, err := database.ParseProfiles(rawServerProfiles)
+	if err != nil {
+		setupLog.Error(err, "invalid server profiles", "path", serverProfilesFile)
+		os.Exit(1)
+	}
+	opCfg.Profiles = serverProfiles
+	setupLog.Info("loaded server profiles", "profiles", serverProfiles.Names())
 
 	// Startup context just for fetching the subnet catalog
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -339,23 +403,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Customer-managed keys are not faked; servers asking for one wait.
+	var keyClient keyvault.KeyClient
+	if !useFakes {
+		armKeyClient, err := keyvault.NewARMKeyClient(cred, armOpts)
+		if err != nil {
+			setupLog.Error(err, "failed to create key vault client")
+			os.Exit(1)
+		}
+		keyClient = armKeyClient
+	}
+
 	if err := (&controller.DatabaseServerReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		SubnetCatalog: subnetCatalog,
+		APIReader:     mgr.GetAPIReader(),
+		Recorder:      mgr.GetEventRecorder("databaseserver-controller"),
+		KeyClient:     keyClient,
 		Config:        *opCfg,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DatabaseServer")
 		os.Exit(1)
 	}
 	if err := (&controller.DatabaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Config: *opCfg,
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+		Recorder:  mgr.GetEventRecorder("database-controller"),
+		Config:    *opCfg,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Database")
 		os.Exit(1)
 	}
+	metrics.Registry.MustRegister(controller.NewInventoryCollector(mgr.GetClient(), subnetCatalog))
+	if err := controller.SetupProvisionJobObserver(ctx, mgr, mgr.GetEventRecorder("provision-job-observer")); err != nil {
+		setupLog.Error(err, "unable to set up provisioning Job observer")
+		os.Exit(1)
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err := webhookstoragev1alpha1.SetupDatabaseServerWebhookWithManager(mgr, *opCfg); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DatabaseServer")
+			os.Exit(1)
+		}
+		if err := webhookstoragev1alpha1.SetupDatabaseWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Database")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -3,8 +3,14 @@ package config
 import (
 	"fmt"
 	"strings"
+
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
 )
 
+// DefaultMigrationImage is the migration image when none is configured. Its
+// client tools must not be older than the servers' PostgreSQL version.
+const DefaultMigrationImage = "postgres:17"
+
 type OperatorConfig struct {
 	// ResourceGroup is the Azure resource group that owns the VNet and
 	// the Private DNS zones
@@ -26,13 +32,35 @@ type OperatorConfig struct {
 	// UserProvisionImage is the image used for user provisioning Jobs.
 	UserProvisionImage string
 
+	// MigrationImage is the PostgreSQL client image that runs pg_dump and
+	// pg_restore when a Database is moved to another server. It is optional
+	// and set after construction: empty means DefaultMigrationImage.
+	MigrationImage string
+
 	// UseAzFakes toggles Azure fake servers (used for kind/local).
 	UseAzFakes bool
 
+	// BackupStorageAccounts are the storage accounts Database backups may be
+	// uploaded to. The upload authenticates with the DatabaseServer's admin
+	// identity, so only these accounts receive its token. It is optional and
+	// set after construction: empty rejects every Blob Storage container.
+	BackupStorageAccounts []string
+
+	// PairedRegionSubnets are the ARM IDs of the delegated subnets in the
+	// paired region. A Flexible Server must be in its delegated subnet's
+	// region, so a geo-restore may only use one of these. It is optional and
+	// set after construction: empty blocks every geo-restore.
+	PairedRegionSubnets []string
+
 	// BaseTags are the platform-owned Azure tags (the RFC 0007 finops base
 	// tag set) applied to every Azure resource this operator creates. It is
 	// optional and set after construction: empty disables platform tagging.
 	BaseTags map[string]string
+
+	// Profiles are the named server sizes a DatabaseServer can select. It is
+	// optional and set after construction: nil means the built-in dev and
+	// prod profiles.
+	Profiles dbUtil.Profiles
 }
 
 // NewOperatorConfig builds and validates the OperatorConfig from already-parsed
@@ -1,9 +1,14 @@
-// Package connection renders the non-secret ConfigMaps that the operator
-// publishes so consuming apps can read PostgreSQL connection coordinates.
+// Package connection renders the ConfigMaps and service binding Secrets that
+// the operator publishes so consuming apps can read PostgreSQL connection
+// coordinates. Neither holds a password: apps authenticate with their managed
+// identity.
 package connection
 
 import (
 	"fmt"
+	"maps"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -26,6 +31,21 @@ const (
 	DataKeySSLMode = "sslmode"
 	DataKeyURI     = "uri"
 
+	// DataKeyReadOnlyHost and DataKeyReadOnlyURI are only published when the
+	// server has a ready read replica.
+	DataKeyReadOnlyHost = "ro-host"
+	DataKeyReadOnlyURI  = "ro-uri"
+
+	// DataKeyPoolerPort and DataKeyPoolerURI are only published when the
+	// server runs the built-in PgBouncer. The pooler listens on the same host.
+	DataKeyPoolerPort = "pooler-port"
+	DataKeyPoolerURI  = "pooler-uri"
+
+	// DataKeyJDBCURL and DataKeyDotNetConnectionString are only published when
+	// requested in spec.connection.extraKeys.
+	DataKeyJDBCURL                = string(storagev1alpha1.DatabaseConnectionKeyJDBCURL)
+	DataKeyDotNetConnectionString = string(storagev1alpha1.DatabaseConnectionKeyDotNetConnectionString)
+
 	// SSLModeRequire is the only sslmode the operator publishes; Azure
 	// PostgreSQL Flexible Server enforces TLS.
 	SSLModeRequire = "require"
@@ -36,6 +56,12 @@ const (
 	LabelPrincipal = "pgsql.dis.altinn.cloud/principal"
 	LabelComponent = "pgsql.dis.altinn.cloud/component"
 	ComponentValue = "connection"
+
+	// LabelDatabaseNamespace is only set on objects published in another
+	// namespace than the Database's. Owner references cannot cross
+	// namespaces, so together with LabelDatabase it identifies the Database
+	// the object belongs to.
+	LabelDatabaseNamespace = "pgsql.dis.altinn.cloud/database-namespace"
 )
 
 // Coordinates is the resolved, non-secret connection input for one
@@ -43,8 +69,14 @@ const (
 type Coordinates struct {
 	// Host is the PostgreSQL server FQDN (database.Status.Host).
 	Host string
+	// ReadOnlyHost is the FQDN of a read replica of the server. It is empty
+	// when the server has no ready replicas.
+	ReadOnlyHost string
 	// Port is the PostgreSQL server port (database.Status.Port).
 	Port int32
+	// PoolerPort is the PgBouncer port of the server. It is zero when the
+	// server does not run PgBouncer.
+	PoolerPort int32
 	// DBName is the PostgreSQL database name (database.Status.DatabaseName).
 	DBName string
 	// User is the resolved managed-identity name the app authenticates as
@@ -54,13 +86,17 @@ type Coordinates struct {
 	// IdentityRef is the spec principal identityRef.name. It drives the
 	// ConfigMap name and the principal label, and is known at authoring time.
 	IdentityRef string
+	// Namespace is the identityRef namespace the details are published in. It
+	// is empty for identities in the Database's namespace.
+	Namespace string
 }
 
 // DeterministicConfigMapName returns the ConfigMap name for a database/principal
-// pair. It is a pure function of values known before the database is deployed
-// (database.metadata.name and identityRef.name), so a consumer can derive it up
-// front. The name is "<database>-<identityRef>-dis-pgsql", sanitized to be a
-// valid DNS-1123 name and hash-suffixed when it would exceed 63 characters.
+// pair. The service binding Secret uses the same name. It is a pure function
+// of values known before the database is deployed (database.metadata.name and
+// identityRef.name), so a consumer can derive it up front. The name is
+// "<database>-<identityRef>-dis-pgsql", sanitized to be a valid DNS-1123 name
+// and hash-suffixed when it would exceed 63 characters.
 func DeterministicConfigMapName(databaseName, identityRefName string) string {
 	base := naming.SanitizeLowerHyphen(databaseName + "-" + identityRefName)
 	if base == "" {
@@ -78,7 +114,8 @@ func DeterministicConfigMapName(databaseName, identityRefName string) string {
 
 // BuildConnectionConfigMap renders the desired (un-owned) ConfigMap for one
 // principal. The caller is responsible for setting the controller owner
-// reference before persisting it.
+// reference before persisting it, unless IsForeign reports that it is
+// published in another namespace.
 func BuildConnectionConfigMap(database *storagev1alpha1.Database, coords Coordinates) (*corev1.ConfigMap, error) {
 	if database == nil {
 		return nil, fmt.Errorf("database must not be nil")
@@ -88,28 +125,105 @@ func BuildConnectionConfigMap(database *storagev1alpha1.Database, coords Coordin
 	}
 
 	port := strconv.Itoa(int(coords.Port))
-	uri := fmt.Sprintf(
-		"postgresql://%s@%s:%s/%s?sslmode=%s",
-		coords.User, coords.Host, port, coords.DBName, SSLModeRequire,
-	)
+	data := map[string]string{
+		DataKeyHost:    coords.Host,
+		DataKeyPort:    port,
+		DataKeyDBName:  coords.DBName,
+		DataKeyUser:    coords.User,
+		DataKeySSLMode: SSLModeRequire,
+		DataKeyURI:     connectionURI(coords.User, coords.Host, port, coords.DBName),
+	}
+	if readOnlyHost := strings.TrimSpace(coords.ReadOnlyHost); readOnlyHost != "" {
+		data[DataKeyReadOnlyHost] = readOnlyHost
+		data[DataKeyReadOnlyURI] = connectionURI(coords.User, readOnlyHost, port, coords.DBName)
+	}
+	if coords.PoolerPort > 0 {
+		poolerPort := strconv.Itoa(int(coords.PoolerPort))
+		data[DataKeyPoolerPort] = poolerPort
+		data[DataKeyPoolerURI] = connectionURI(coords.User, coords.Host, poolerPort, coords.DBName)
+	}
+	maps.Copy(data, extraKeys(database, coords, port))
 
 	return &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
+		ObjectMeta: objectMeta(database, coords),
+		Data:       data,
+	}, nil
+}
+
+// objectMeta returns the name, namespace and labels shared by the ConfigMap
+// and the service binding Secret of one principal. Objects for an identity in
+// another namespace are published there, and their name is prefixed with the
+// Database's namespace so Databases with the same name do not collide.
+func objectMeta(database *storagev1alpha1.Database, coords Coordinates) metav1.ObjectMeta {
+	labels := map[string]string{
+		LabelDatabase:  database.Name,
+		LabelPrincipal: coords.IdentityRef,
+		LabelComponent: ComponentValue,
+	}
+	if !IsForeign(database, coords) {
+		return metav1.ObjectMeta{
 			Name:      DeterministicConfigMapName(database.Name, coords.IdentityRef),
 			Namespace: database.Namespace,
-			Labels: map[string]string{
-				LabelDatabase:  database.Name,
-				LabelPrincipal: coords.IdentityRef,
-				LabelComponent: ComponentValue,
-			},
-		},
-		Data: map[string]string{
-			DataKeyHost:    coords.Host,
-			DataKeyPort:    port,
-			DataKeyDBName:  coords.DBName,
-			DataKeyUser:    coords.User,
-			DataKeySSLMode: SSLModeRequire,
-			DataKeyURI:     uri,
-		},
-	}, nil
+			Labels:    labels,
+		}
+	}
+	labels[LabelDatabaseNamespace] = database.Namespace
+	return metav1.ObjectMeta{
+		Name:      DeterministicConfigMapName(database.Namespace+"-"+database.Name, coords.IdentityRef),
+		Namespace: coords.Namespace,
+		Labels:    labels,
+	}
+}
+
+// IsForeign reports whether coords are published outside the Database's
+// namespace.
+func IsForeign(database *storagev1alpha1.Database, coords Coordinates) bool {
+	return coords.Namespace != "" && coords.Namespace != database.Namespace
+}
+
+// PublishesFormat reports whether the connection details of database are
+// published in format. Without spec.connection.formats only the ConfigMap is.
+func PublishesFormat(database *storagev1alpha1.Database, format storagev1alpha1.DatabaseConnectionFormat) bool {
+	if database.Spec.Connection == nil || len(database.Spec.Connection.Formats) == 0 {
+		return format == storagev1alpha1.DatabaseConnectionFormatConfigMap
+	}
+	return slices.Contains(database.Spec.Connection.Formats, format)
+}
+
+// extraKeys renders the keys requested in spec.connection.extraKeys.
+func extraKeys(database *storagev1alpha1.Database, coords Coordinates, port string) map[string]string {
+	if database.Spec.Connection == nil {
+		return nil
+	}
+	data := make(map[string]string, len(database.Spec.Connection.ExtraKeys))
+	for _, key := range database.Spec.Connection.ExtraKeys {
+		switch key {
+		case storagev1alpha1.DatabaseConnectionKeyJDBCURL:
+			data[DataKeyJDBCURL] = jdbcURL(coords.User, coords.Host, port, coords.DBName)
+		case storagev1alpha1.DatabaseConnectionKeyDotNetConnectionString:
+			data[DataKeyDotNetConnectionString] = dotNetConnectionString(coords.User, coords.Host, port, coords.DBName)
+		}
+	}
+	return data
+}
+
+func jdbcURL(user, host, port, dbName string) string {
+	return fmt.Sprintf(
+		"jdbc:postgresql://%s:%s/%s?sslmode=%s&user=%s",
+		host, port, dbName, SSLModeRequire, url.QueryEscape(user),
+	)
+}
+
+func dotNetConnectionString(user, host, port, dbName string) string {
+	return fmt.Sprintf(
+		"Host=%s;Port=%s;Database=%s;Username=%s;SSL Mode=Require",
+		host, port, dbName, user,
+	)
+}
+
+func connectionURI(user, host, port, dbName string) string {
+	return fmt.Sprintf(
+		"postgresql://%s@%s:%s/%s?sslmode=%s",
+		user, host, port, dbName, SSLModeRequire,
+	)
 }
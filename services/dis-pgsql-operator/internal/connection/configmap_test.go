@@ -127,6 +127,137 @@ func TestBuildConnectionConfigMap(t *testing.T) {
 	}
 }
 
+func TestBuildConnectionConfigMapForeignNamespace(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	database.Name = testDB
+	database.Namespace = "team-a"
+
+	coords := Coordinates{
+		Host:        testHost,
+		Port:        5432,
+		DBName:      testDB,
+		User:        "reporting-mi",
+		IdentityRef: testIdentity,
+		Namespace:   "reporting",
+	}
+	if !IsForeign(database, coords) {
+		t.Fatalf("expected coordinates in another namespace to be foreign")
+	}
+
+	cm, err := BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	if want := DeterministicConfigMapName("team-a-"+testDB, testIdentity); cm.Name != want {
+		t.Fatalf("name = %q, want %q", cm.Name, want)
+	}
+	if cm.Namespace != "reporting" {
+		t.Fatalf("namespace = %q, want %q", cm.Namespace, "reporting")
+	}
+	if got := cm.Labels[LabelDatabaseNamespace]; got != "team-a" {
+		t.Fatalf("label %q = %q, want %q", LabelDatabaseNamespace, got, "team-a")
+	}
+
+	coords.Namespace = "team-a"
+	if IsForeign(database, coords) {
+		t.Fatalf("expected coordinates in the Database's namespace not to be foreign")
+	}
+	cm, err = BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	if _, ok := cm.Labels[LabelDatabaseNamespace]; ok || cm.Name != DeterministicConfigMapName(testDB, testIdentity) {
+		t.Fatalf("expected the same-namespace ConfigMap, got %s with labels %v", cm.Name, cm.Labels)
+	}
+}
+
+func TestBuildConnectionConfigMapReadOnlyKeys(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	database.Name = testDB
+	database.Namespace = "team-a"
+
+	coords := Coordinates{
+		Host:        testHost,
+		Port:        5432,
+		DBName:      testDB,
+		User:        "payments-api-mi",
+		IdentityRef: testIdentity,
+	}
+
+	cm, err := BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	for _, key := range []string{DataKeyReadOnlyHost, DataKeyReadOnlyURI} {
+		if _, ok := cm.Data[key]; ok {
+			t.Fatalf("did not expect %q without a read replica", key)
+		}
+	}
+
+	const replicaHost = "payments-db-ro.postgres.database.azure.com"
+	coords.ReadOnlyHost = replicaHost
+	cm, err = BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	if got := cm.Data[DataKeyReadOnlyHost]; got != replicaHost {
+		t.Fatalf("data %q = %q, want %q", DataKeyReadOnlyHost, got, replicaHost)
+	}
+	wantURI := fmt.Sprintf("postgresql://payments-api-mi@%s:5432/%s?sslmode=require", replicaHost, testDB)
+	if got := cm.Data[DataKeyReadOnlyURI]; got != wantURI {
+		t.Fatalf("data %q = %q, want %q", DataKeyReadOnlyURI, got, wantURI)
+	}
+	if got := cm.Data[DataKeyHost]; got != testHost {
+		t.Fatalf("data %q = %q, want primary host %q", DataKeyHost, got, testHost)
+	}
+}
+
+func TestBuildConnectionConfigMapPoolerKeys(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	database.Name = testDB
+	database.Namespace = "team-a"
+
+	coords := Coordinates{
+		Host:        testHost,
+		Port:        5432,
+		DBName:      testDB,
+		User:        "payments-api-mi",
+		IdentityRef: testIdentity,
+	}
+
+	cm, err := BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	for _, key := range []string{DataKeyPoolerPort, DataKeyPoolerURI} {
+		if _, ok := cm.Data[key]; ok {
+			t.Fatalf("did not expect %q without PgBouncer", key)
+		}
+	}
+
+	coords.PoolerPort = 6432
+	cm, err = BuildConnectionConfigMap(database, coords)
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+	if got := cm.Data[DataKeyPoolerPort]; got != "6432" {
+		t.Fatalf("data %q = %q, want %q", DataKeyPoolerPort, got, "6432")
+	}
+	wantURI := fmt.Sprintf("postgresql://payments-api-mi@%s:6432/%s?sslmode=require", testHost, testDB)
+	if got := cm.Data[DataKeyPoolerURI]; got != wantURI {
+		t.Fatalf("data %q = %q, want %q", DataKeyPoolerURI, got, wantURI)
+	}
+	if got := cm.Data[DataKeyPort]; got != "5432" {
+		t.Fatalf("data %q = %q, want direct port %q", DataKeyPort, got, "5432")
+	}
+}
+
 func TestBuildConnectionConfigMapValidation(t *testing.T) {
 	t.Parallel()
 
@@ -140,3 +271,59 @@ func TestBuildConnectionConfigMapValidation(t *testing.T) {
 		t.Fatalf("expected error for empty IdentityRef")
 	}
 }
+
+func TestBuildConnectionConfigMapExtraKeys(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	database.Name = testDB
+	database.Namespace = "team-a"
+	database.Spec.Connection = &storagev1alpha1.DatabaseConnectionSpec{
+		ExtraKeys: []storagev1alpha1.DatabaseConnectionKey{
+			storagev1alpha1.DatabaseConnectionKeyJDBCURL,
+			storagev1alpha1.DatabaseConnectionKeyDotNetConnectionString,
+		},
+	}
+
+	cm, err := BuildConnectionConfigMap(database, Coordinates{
+		Host:        testHost,
+		Port:        5432,
+		DBName:      testDB,
+		User:        "payments-api-mi",
+		IdentityRef: testIdentity,
+	})
+	if err != nil {
+		t.Fatalf("BuildConnectionConfigMap() error: %v", err)
+	}
+
+	wantJDBC := fmt.Sprintf("jdbc:postgresql://%s:5432/%s?sslmode=require&user=payments-api-mi", testHost, testDB)
+	if got := cm.Data[DataKeyJDBCURL]; got != wantJDBC {
+		t.Fatalf("data %q = %q, want %q", DataKeyJDBCURL, got, wantJDBC)
+	}
+	wantDotNet := fmt.Sprintf("Host=%s;Port=5432;Database=%s;Username=payments-api-mi;SSL Mode=Require", testHost, testDB)
+	if got := cm.Data[DataKeyDotNetConnectionString]; got != wantDotNet {
+		t.Fatalf("data %q = %q, want %q", DataKeyDotNetConnectionString, got, wantDotNet)
+	}
+}
+
+func TestPublishesFormat(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	if !PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatConfigMap) {
+		t.Fatalf("expected the ConfigMap to be published by default")
+	}
+	if PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatServiceBindingSecret) {
+		t.Fatalf("did not expect the binding Secret to be published by default")
+	}
+
+	database.Spec.Connection = &storagev1alpha1.DatabaseConnectionSpec{
+		Formats: []storagev1alpha1.DatabaseConnectionFormat{storagev1alpha1.DatabaseConnectionFormatServiceBindingSecret},
+	}
+	if PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatConfigMap) {
+		t.Fatalf("did not expect the ConfigMap when only the binding Secret is selected")
+	}
+	if !PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatServiceBindingSecret) {
+		t.Fatalf("expected the binding Secret to be published when selected")
+	}
+}
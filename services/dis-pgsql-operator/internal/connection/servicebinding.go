@@ -0,0 +1,67 @@
+package connection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// servicebinding.io well-known entries, plus the database and sslmode
+	// entries PostgreSQL binding libraries read.
+	BindingKeyType     = "type"
+	BindingKeyProvider = "provider"
+	BindingKeyHost     = "host"
+	BindingKeyPort     = "port"
+	BindingKeyDatabase = "database"
+	BindingKeyUsername = "username"
+	BindingKeySSLMode  = "sslmode"
+	BindingKeyURI      = "uri"
+
+	// BindingType and BindingProvider identify the bound service to binding
+	// libraries.
+	BindingType     = "postgresql"
+	BindingProvider = "azure"
+
+	// ServiceBindingSecretType is the Secret type servicebinding.io
+	// recommends for a provisioned PostgreSQL service.
+	ServiceBindingSecretType corev1.SecretType = "servicebinding.io/" + BindingType
+)
+
+// BuildServiceBindingSecret renders the desired (un-owned) servicebinding.io
+// Secret for one principal. It has the same name and labels as the principal's
+// ConfigMap. The caller is responsible for setting the controller owner
+// reference before persisting it, unless IsForeign reports that it is
+// published in another namespace.
+func BuildServiceBindingSecret(database *storagev1alpha1.Database, coords Coordinates) (*corev1.Secret, error) {
+	if database == nil {
+		return nil, fmt.Errorf("database must not be nil")
+	}
+	if strings.TrimSpace(coords.IdentityRef) == "" {
+		return nil, fmt.Errorf("coords.IdentityRef must not be empty")
+	}
+
+	port := strconv.Itoa(int(coords.Port))
+	data := map[string][]byte{
+		BindingKeyType:     []byte(BindingType),
+		BindingKeyProvider: []byte(BindingProvider),
+		BindingKeyHost:     []byte(coords.Host),
+		BindingKeyPort:     []byte(port),
+		BindingKeyDatabase: []byte(coords.DBName),
+		BindingKeyUsername: []byte(coords.User),
+		BindingKeySSLMode:  []byte(SSLModeRequire),
+		BindingKeyURI:      []byte(connectionURI(coords.User, coords.Host, port, coords.DBName)),
+	}
+	for key, value := range extraKeys(database, coords, port) {
+		data[key] = []byte(value)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: objectMeta(database, coords),
+		Type:       ServiceBindingSecretType,
+		Data:       data,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package connection
+
+import (
+	"fmt"
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestBuildServiceBindingSecret(t *testing.T) {
+	t.Parallel()
+
+	database := &storagev1alpha1.Database{}
+	database.Name = testDB
+	database.Namespace = "team-a"
+	database.Spec.Connection = &storagev1alpha1.DatabaseConnectionSpec{
+		ExtraKeys: []storagev1alpha1.DatabaseConnectionKey{storagev1alpha1.DatabaseConnectionKeyJDBCURL},
+	}
+
+	const user = "payments-api-mi"
+	secret, err := BuildServiceBindingSecret(database, Coordinates{
+		Host:        testHost,
+		Port:        5432,
+		DBName:      testDB,
+		User:        user,
+		IdentityRef: testIdentity,
+	})
+	if err != nil {
+		t.Fatalf("BuildServiceBindingSecret() error: %v", err)
+	}
+
+	if want := DeterministicConfigMapName(testDB, testIdentity); secret.Name != want {
+		t.Fatalf("name = %q, want %q", secret.Name, want)
+	}
+	if secret.Type != ServiceBindingSecretType {
+		t.Fatalf("type = %q, want %q", secret.Type, ServiceBindingSecretType)
+	}
+	if secret.Labels[LabelComponent] != ComponentValue || secret.Labels[LabelPrincipal] != testIdentity {
+		t.Fatalf("unexpected labels %v", secret.Labels)
+	}
+
+	wantData := map[string]string{
+		BindingKeyType:     BindingType,
+		BindingKeyProvider: BindingProvider,
+		BindingKeyHost:     testHost,
+		BindingKeyPort:     "5432",
+		BindingKeyDatabase: testDB,
+		BindingKeyUsername: user,
+		BindingKeySSLMode:  SSLModeRequire,
+		BindingKeyURI:      fmt.Sprintf("postgresql://%s@%s:5432/%s?sslmode=require", user, testHost, testDB),
+		DataKeyJDBCURL:     fmt.Sprintf("jdbc:postgresql://%s:5432/%s?sslmode=require&user=%s", testHost, testDB, user),
+	}
+	if len(secret.Data) != len(wantData) {
+		t.Fatalf("expected %d keys, got %v", len(wantData), secret.Data)
+	}
+	for k, want := range wantData {
+		if got := string(secret.Data[k]); got != want {
+			t.Fatalf("data %q = %q, want %q", k, got, want)
+		}
+	}
+	if _, ok := secret.Data["password"]; ok {
+		t.Fatalf("did not expect a password in the binding Secret")
+	}
+}
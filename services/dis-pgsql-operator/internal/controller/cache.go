@@ -0,0 +1,35 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+)
+
+// CacheOptions restricts the manager's cache of Secrets and ConfigMaps to
+// the ones the operator publishes, and the ConfigMaps holding schema
+// migrations. The operator may read both in every namespace, and without a
+// selector its informers would hold all of them in memory.
+func CacheOptions() cache.Options {
+	return cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}:    {Label: cachedSecretSelector()},
+			&corev1.ConfigMap{}: {Label: cachedConfigMapSelector()},
+		},
+	}
+}
+
+func cachedSecretSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{connection.LabelComponent: connection.ComponentValue})
+}
+
+func cachedConfigMapSelector() labels.Selector {
+	// Both values are valid label values, so the requirement cannot fail.
+	requirement, _ := labels.NewRequirement(connection.LabelComponent, selection.In,
+		[]string{connection.ComponentValue, databaseSchemaMigrationsComponentLabelValue})
+	return labels.NewSelector().Add(*requirement)
+}
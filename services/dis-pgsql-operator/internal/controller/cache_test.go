@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+)
+
+func TestCachedSecretSelector(t *testing.T) {
+	selector := cachedSecretSelector()
+	if !selector.Matches(labels.Set{connection.LabelComponent: connection.ComponentValue, connection.LabelDatabase: "app"}) {
+		t.Fatalf("expected the connection Secrets to be cached")
+	}
+	if selector.Matches(labels.Set{"app": "web"}) {
+		t.Fatalf("expected other Secrets not to be cached")
+	}
+}
+
+func TestCachedConfigMapSelector(t *testing.T) {
+	selector := cachedConfigMapSelector()
+	for _, value := range []string{connection.ComponentValue, databaseSchemaMigrationsComponentLabelValue} {
+		if !selector.Matches(labels.Set{connection.LabelComponent: value}) {
+			t.Fatalf("expected ConfigMaps labelled %q to be cached", value)
+		}
+	}
+	if selector.Matches(labels.Set{}) || selector.Matches(labels.Set{connection.LabelComponent: "other"}) {
+		t.Fatalf("expected other ConfigMaps not to be cached")
+	}
+}
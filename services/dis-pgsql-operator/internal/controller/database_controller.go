@@ -4,66 +4,84 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	identityv1alpha1 "github.com/Altinn/altinn-platform/services/dis-identity-operator/api/v1alpha1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/validation"
 	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
 )
 
 const (
-	databaseValidationReasonRequired        = "Required"
-	databaseValidationReasonNotFound        = "NotFound"
-	databaseValidationReasonUnsupported     = "Unsupported"
-	databaseValidationReasonInvalid         = "Invalid"
-	databaseValidationReasonConflict        = "Conflict"
-	databaseValidationReasonImmutable       = "Immutable"
-	databaseValidationFieldMetadataName     = "metadata.name"
-	databaseValidationFieldSpecName         = "spec.name"
-	databaseValidationFieldServerName       = "spec.server.name"
-	databaseValidationFieldAccessPrincipals = "spec.access.principals"
-	databaseValidationFieldDeletionPolicy   = "spec.deletionPolicy"
-	databaseValidationFieldDatabaseName     = "status.databaseName"
-	databaseMaxNameLength                   = 63
-	databaseMaxPrincipalNameLength          = 63
+	databaseValidationReasonRequired      = validation.ReasonRequired
+	databaseValidationReasonNotFound      = "NotFound"
+	databaseValidationReasonInvalid       = validation.ReasonInvalid
+	databaseValidationReasonConflict      = validation.ReasonConflict
+	databaseValidationReasonImmutable     = validation.ReasonImmutable
+	databaseValidationReasonLimitExceeded = "LimitExceeded"
+	databaseValidationFieldSpecName       = validation.FieldSpecName
+	databaseValidationFieldServerName     = validation.FieldServerName
+	databaseValidationFieldDatabaseName   = "status.databaseName"
+
+	// databaseLimitRequeueDelay re-checks a Database held back by its server
+	// profile's maxDatabases, so it proceeds once another Database is removed.
+	databaseLimitRequeueDelay = time.Minute
 )
 
-var entraPrincipalIDPattern = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
-
 // DatabaseReconciler reconciles a Database object.
 type DatabaseReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 
+	// APIReader reads objects the manager does not cache, such as the Pods of
+	// the access Job that reports extension results. The cached client is used
+	// when it is unset.
+	APIReader client.Reader
+
+	// Recorder records lifecycle Events on Databases. No Events are recorded
+	// when it is unset.
+	Recorder events.EventRecorder
+
 	Config config.OperatorConfig
 }
 
-// +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databases,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databases,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databases/finalizers,verbs=update
 // +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databaseservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.dis.altinn.cloud,resources=databaseaccessgrants,verbs=get;list;watch
 // +kubebuilder:rbac:groups=application.dis.altinn.cloud,resources=applicationidentities,verbs=get;list;watch
 // +kubebuilder:rbac:groups=dbforpostgresql.azure.com,resources=flexibleservers,verbs=get;list;watch
 // +kubebuilder:rbac:groups=dbforpostgresql.azure.com,resources=flexibleserversdatabases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=dbforpostgresql.azure.com,resources=flexibleserversdatabases/status,verbs=get
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("database", req.NamespacedName)
@@ -77,6 +95,15 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 
 	if !database.DeletionTimestamp.IsZero() {
+		return r.reconcileDatabaseDeletion(ctx, logger, &database)
+	}
+
+	// Only Databases with deletionPolicy Delete carry the finalizer. Returning
+	// after the update lets it re-trigger reconciliation cleanly.
+	if syncDatabaseFinalizer(&database) {
+		if err := r.Update(ctx, &database); err != nil {
+			return ctrl.Result{}, fmt.Errorf("update finalizer on Database %s/%s: %w", database.Namespace, database.Name, err)
+		}
 		return ctrl.Result{}, nil
 	}
 
@@ -97,20 +124,27 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			databaseReasonValidationFailed,
 			"Database validation failed",
 		)
+		if hasDatabaseValidationReason(validationErrors, databaseValidationReasonLimitExceeded) {
+			result = ctrl.Result{RequeueAfter: databaseLimitRequeueDelay}
+		}
 	} else {
-		if err := r.ensureFlexibleServersDatabase(ctx, &database, databaseName); err != nil {
+		if err := r.ensureFlexibleServersDatabase(ctx, &database, databaseServerName(&database), databaseName); err != nil {
 			var conflictErr *databaseASOResourceConflictError
 			if !errors.As(err, &conflictErr) {
 				logger.Error(err, "failed to ensure FlexibleServersDatabase for Database")
 				return ctrl.Result{}, err
 			}
 
-			database.Status.ValidationErrors = appendDatabaseValidationError(
+			database.Status.ValidationErrors = validation.AppendDatabaseError(
 				database.Status.ValidationErrors,
 				databaseValidationFieldSpecName,
 				databaseValidationReasonConflict,
-				fmt.Sprintf("database %q on server %q is already managed by %s; choose another spec.name", databaseName, database.Spec.Server.Name, conflictErr.ownerDescription()),
+				fmt.Sprintf("database %q on server %q is already managed by %s; choose another spec.name", databaseName, databaseServerName(&database), conflictErr.ownerDescription()),
 			)
+			if !hasDatabaseValidationReason(original.Status.ValidationErrors, databaseValidationReasonConflict) {
+				recordWarningEvent(r.Recorder, &database, eventReasonASOResourceConflict, eventActionReconcile,
+					"Database %q on server %q is already managed by %s", databaseName, databaseServerName(&database), conflictErr.ownerDescription())
+			}
 			setDatabaseConditions(
 				&database,
 				metav1.ConditionFalse,
@@ -120,7 +154,7 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		} else {
 			database.Status.DatabaseName = databaseName
 
-			ready, host, err := r.databaseReady(ctx, logger, &database)
+			ready, host, err := r.databaseReady(ctx, logger, &database, databaseServerName(&database))
 			if err != nil {
 				logger.Error(err, "failed to check Database readiness")
 				return ctrl.Result{}, err
@@ -136,7 +170,7 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 					"Database is ready",
 				)
 
-				accessReady, accessReason, accessMessage, err := r.ensureDatabaseAccess(ctx, logger, &database)
+				accessReady, accessReason, accessMessage, auditAfter, err := r.ensureDatabaseAccess(ctx, logger, &database)
 				if err != nil {
 					logger.Error(err, "failed to ensure Database access")
 					return ctrl.Result{}, err
@@ -149,13 +183,45 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 						accessReason,
 						accessMessage,
 					)
-					setDatabaseCondition(
-						&database,
-						databaseConditionReady,
-						metav1.ConditionTrue,
-						databaseReasonReady,
-						"Database and access are ready",
-					)
+					// Schema migrations can depend on the extensions, so they
+					// only run once the extensions are ready.
+					if databaseExtensionsReady(&database) {
+						if err := r.reconcileDatabaseSchemaMigrations(ctx, logger, &database); err != nil {
+							logger.Error(err, "failed to reconcile Database schema migrations")
+							return ctrl.Result{}, err
+						}
+					}
+					switch {
+					case !databaseExtensionsReady(&database):
+						setDatabaseCondition(
+							&database,
+							databaseConditionReady,
+							metav1.ConditionFalse,
+							databaseReasonExtensionsNotReady,
+							"Database extensions are not ready",
+						)
+						result = ctrl.Result{RequeueAfter: databaseRequeueDelay}
+					case !databaseSchemaMigrationsReady(&database):
+						setDatabaseCondition(
+							&database,
+							databaseConditionReady,
+							metav1.ConditionFalse,
+							databaseReasonSchemaMigrationsNotReady,
+							"Database schema migrations are not applied",
+						)
+						result = ctrl.Result{RequeueAfter: databaseRequeueDelay}
+					default:
+						setDatabaseCondition(
+							&database,
+							databaseConditionReady,
+							metav1.ConditionTrue,
+							databaseReasonReady,
+							"Database and access are ready",
+						)
+						if auditAfter > 0 {
+							result = ctrl.Result{RequeueAfter: auditAfter}
+						}
+					}
 				} else {
 					setDatabaseCondition(
 						&database,
@@ -200,6 +266,25 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
+	accessReady := len(database.Status.ValidationErrors) == 0 &&
+		meta.IsStatusConditionTrue(database.Status.Conditions, databaseConditionAccessReady)
+	if accessReady {
+		migrateAfter, err := r.reconcileDatabaseMigration(ctx, logger, &database)
+		if err != nil {
+			logger.Error(err, "failed to reconcile Database migration")
+			return ctrl.Result{}, err
+		}
+		result.RequeueAfter = earliestRequeue(result.RequeueAfter, migrateAfter)
+	}
+	// A removed schedule deletes the backup CronJob whatever the state of the
+	// Database, so backups stop even while access is not ready.
+	if accessReady || database.Spec.Schedule == nil {
+		if err := r.reconcileDatabaseBackup(ctx, logger, &database); err != nil {
+			logger.Error(err, "failed to reconcile Database backup")
+			return ctrl.Result{}, err
+		}
+	}
+
 	if apiequality.Semantic.DeepEqual(original.Status, database.Status) {
 		return result, nil
 	}
@@ -212,81 +297,25 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		logger.Error(err, "failed to update Database status")
 		return ctrl.Result{}, err
 	}
+	observeTimeToReady("Database", original.Status.Conditions, database.Status.Conditions, database.CreationTimestamp, time.Now())
 
 	return result, nil
 }
 
-func appendDatabaseValidationError(
-	validationErrors []storagev1alpha1.DatabaseValidationError,
-	field, reason, message string,
-) []storagev1alpha1.DatabaseValidationError {
-	for i := range validationErrors {
-		if validationErrors[i].Field == field {
-			return validationErrors
-		}
-	}
-
-	return append(validationErrors, storagev1alpha1.DatabaseValidationError{
-		Field:   field,
-		Reason:  reason,
-		Message: message,
-	})
-}
-
 func (r *DatabaseReconciler) validateDatabase(
 	ctx context.Context,
 	database *storagev1alpha1.Database,
 ) ([]storagev1alpha1.DatabaseValidationError, string, error) {
-	var validationErrors []storagev1alpha1.DatabaseValidationError
-	databaseName := database.Spec.Name
-	serverName := strings.TrimSpace(database.Spec.Server.Name)
-
-	addRequiredStringError := func(field, value string) {
-		if strings.TrimSpace(value) != "" {
-			return
-		}
-		validationErrors = appendDatabaseValidationError(
-			validationErrors,
-			field,
-			databaseValidationReasonRequired,
-			fmt.Sprintf("%s must be set", field),
-		)
-	}
-
-	addRequiredStringError(databaseValidationFieldServerName, database.Spec.Server.Name)
-	addRequiredStringError(databaseValidationFieldMetadataName, database.Name)
-	addRequiredStringError(databaseValidationFieldSpecName, database.Spec.Name)
-	validationErrors = validateDatabaseAccess(validationErrors, database)
-
-	if database.Spec.Name != "" && strings.TrimSpace(database.Spec.Name) != database.Spec.Name {
-		validationErrors = appendDatabaseValidationError(
-			validationErrors,
-			databaseValidationFieldSpecName,
-			databaseValidationReasonInvalid,
-			"spec.name must not have leading or trailing whitespace",
-		)
-	}
-
-	if len(databaseName) > databaseMaxNameLength {
-		validationErrors = appendDatabaseValidationError(
-			validationErrors,
-			databaseValidationFieldSpecName,
-			databaseValidationReasonInvalid,
-			fmt.Sprintf("spec.name must be at most %d characters", databaseMaxNameLength),
-		)
-	}
-
-	if database.Spec.Server.Name != "" && serverName != database.Spec.Server.Name {
-		validationErrors = appendDatabaseValidationError(
-			validationErrors,
-			databaseValidationFieldServerName,
-			databaseValidationReasonInvalid,
-			"spec.server.name must not have leading or trailing whitespace",
-		)
+	validationErrors := validation.Database(database)
+	if database.Spec.Schedule != nil {
+		validationErrors = validation.DatabaseScheduleStorage(validationErrors, database.Spec.Schedule,
+			r.Config.BackupStorageAccounts, r.Config.UseAzFakes)
 	}
+	databaseName := database.Spec.Name
+	serverName := databaseServerName(database)
 
 	if database.Status.DatabaseName != "" && databaseName != "" && database.Status.DatabaseName != databaseName {
-		validationErrors = appendDatabaseValidationError(
+		validationErrors = validation.AppendDatabaseError(
 			validationErrors,
 			databaseValidationFieldDatabaseName,
 			databaseValidationReasonImmutable,
@@ -294,19 +323,28 @@ func (r *DatabaseReconciler) validateDatabase(
 		)
 	}
 
-	if database.Spec.DeletionPolicy != "" &&
-		database.Spec.DeletionPolicy != storagev1alpha1.DatabaseDeletionPolicyRetain {
-		validationErrors = appendDatabaseValidationError(
-			validationErrors,
-			databaseValidationFieldDeletionPolicy,
-			databaseValidationReasonUnsupported,
-			"spec.deletionPolicy only supports Retain",
-		)
+	if serverName == "" && database.Spec.ServerSelector != nil && len(validationErrors) == 0 {
+		placed, placementErr, err := r.placeDatabase(ctx, database)
+		if err != nil {
+			return nil, databaseName, err
+		}
+		if placementErr != nil {
+			if placementErr.Reason == databaseValidationReasonLimitExceeded &&
+				!hasDatabaseValidationError(database.Status.ValidationErrors, placementErr.Field, placementErr.Reason) {
+				recordWarningEvent(r.Recorder, database, eventReasonServerPoolFull, eventActionPlaceDatabase, "%s", placementErr.Message)
+			}
+			validationErrors = validation.AppendDatabaseError(validationErrors, placementErr.Field, placementErr.Reason, placementErr.Message)
+			return validationErrors, databaseName, nil
+		}
+		recordNormalEvent(r.Recorder, database, eventReasonDatabasePlaced, eventActionPlaceDatabase,
+			"Database placed on DatabaseServer %q", placed)
+		serverName = placed
 	}
 
 	if serverName == "" {
 		return validationErrors, databaseName, nil
 	}
+	database.Status.Server = serverName
 
 	var db storagev1alpha1.DatabaseServer
 	if err := r.Get(ctx, types.NamespacedName{
@@ -314,7 +352,7 @@ func (r *DatabaseReconciler) validateDatabase(
 		Namespace: database.Namespace,
 	}, &db); err != nil {
 		if apierrors.IsNotFound(err) {
-			validationErrors = appendDatabaseValidationError(
+			validationErrors = validation.AppendDatabaseError(
 				validationErrors,
 				databaseValidationFieldServerName,
 				databaseValidationReasonNotFound,
@@ -325,218 +363,184 @@ func (r *DatabaseReconciler) validateDatabase(
 		return nil, databaseName, fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, serverName, err)
 	}
 
-	return validationErrors, databaseName, nil
-}
+	// An unknown profile is reported on the DatabaseServer's Ready condition.
+	profile, err := resolveServerProfile(r.Config, &db)
+	if err != nil || (profile.MaxDatabases == 0 && databaseConnectionLimit(database) == 0) {
+		return validationErrors, databaseName, nil
+	}
 
-func validateDatabaseAccess(
-	validationErrors []storagev1alpha1.DatabaseValidationError,
-	database *storagev1alpha1.Database,
-) []storagev1alpha1.DatabaseValidationError {
-	if len(database.Spec.Access.Principals) == 0 {
-		return appendDatabaseValidationError(
+	var databases storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &databases, client.InNamespace(database.Namespace)); err != nil {
+		return nil, databaseName, fmt.Errorf("list Databases in namespace %s: %w", database.Namespace, err)
+	}
+	if profile.MaxDatabases > 0 && !databaseWithinServerLimit(database, databases.Items, profile.MaxDatabases) {
+		validationErrors = validation.AppendDatabaseError(
 			validationErrors,
-			databaseValidationFieldAccessPrincipals,
-			databaseValidationReasonRequired,
-			"spec.access.principals must contain at least one principal",
+			databaseValidationFieldServerName,
+			databaseValidationReasonLimitExceeded,
+			fmt.Sprintf("DatabaseServer %q already has the maximum of %d databases allowed by its profile", serverName, profile.MaxDatabases),
 		)
 	}
-
-	seen := map[string]string{}
-	for i, principal := range database.Spec.Access.Principals {
-		field := func(suffix string) string {
-			if suffix == "" {
-				return fmt.Sprintf("spec.access.principals[%d]", i)
-			}
-			return fmt.Sprintf("spec.access.principals[%d].%s", i, suffix)
+	if databaseConnectionLimit(database) > 0 {
+		maxConnections, err := dbUtil.ResolveMaxConnections(profile)
+		if err != nil {
+			return validationErrors, databaseName, nil
 		}
-
-		roleField := field("role")
-		switch principal.Role {
-		case storagev1alpha1.DatabaseAccessRoleReader,
-			storagev1alpha1.DatabaseAccessRoleWriter,
-			storagev1alpha1.DatabaseAccessRoleOwner:
-		case "":
-			validationErrors = appendDatabaseValidationError(
-				validationErrors,
-				roleField,
-				databaseValidationReasonRequired,
-				"role must be set",
-			)
-		default:
-			validationErrors = appendDatabaseValidationError(
+		if used := databaseServerConnectionLimits(database, databases.Items); used > maxConnections {
+			validationErrors = validation.AppendDatabaseError(
 				validationErrors,
-				roleField,
-				databaseValidationReasonInvalid,
-				"role must be one of Reader, Writer, or Owner",
+				validation.FieldAccessPrincipals,
+				databaseValidationReasonLimitExceeded,
+				fmt.Sprintf("the principal connection limits on DatabaseServer %q add up to %d, more than its max_connections of %d", serverName, used, maxConnections),
 			)
 		}
+	}
 
-		hasIdentityRef := principal.IdentityRef != nil
-		hasGroup := principal.Group != nil
-		hasServicePrincipal := principal.ServicePrincipal != nil
-		sourceCount := 0
-		for _, set := range []bool{hasIdentityRef, hasGroup, hasServicePrincipal} {
-			if set {
-				sourceCount++
-			}
+	return validationErrors, databaseName, nil
+}
+
+// databaseConnectionLimit returns the sum of the principal connection limits
+// of database. Principals without a limit count as zero.
+func databaseConnectionLimit(database *storagev1alpha1.Database) int {
+	total := 0
+	for _, principal := range database.Spec.Access.Principals {
+		if principal.ConnectionLimit != nil {
+			total += int(*principal.ConnectionLimit)
 		}
-		if sourceCount != 1 {
-			validationErrors = appendDatabaseValidationError(
-				validationErrors,
-				field(""),
-				databaseValidationReasonInvalid,
-				"exactly one principal source must be set: identityRef, group, or servicePrincipal",
-			)
+	}
+	return total
+}
+
+// databaseServerConnectionLimits returns the connection limits of database
+// and the Databases created before it on its server, oldest first like
+// databaseWithinServerLimit, so a newer Database cannot take connections
+// from the ones already there. A principal with access to several of them
+// has one login role, so it counts once, with its effective limit.
+func databaseServerConnectionLimits(
+	database *storagev1alpha1.Database,
+	databases []storagev1alpha1.Database,
+) int {
+	serverName := databaseServerName(database)
+	counted := []storagev1alpha1.Database{*database}
+	for i := range databases {
+		other := &databases[i]
+		if other.Name == database.Name || databaseServerName(other) != serverName {
 			continue
 		}
-
-		var principalKey string
-		if hasIdentityRef {
-			refName := principal.IdentityRef.Name
-			refField := field("identityRef.name")
-			validationErrors = validateAccessName(validationErrors, refField, refName, databaseMaxPrincipalNameLength)
-			if refName != "" {
-				for _, msg := range validation.IsDNS1123Subdomain(refName) {
-					validationErrors = appendDatabaseValidationError(
-						validationErrors,
-						refField,
-						databaseValidationReasonInvalid,
-						fmt.Sprintf("identityRef.name must be a valid Kubernetes name: %s", msg),
-					)
-				}
-				principalKey = "identityRef:" + refName
-			}
+		if other.CreationTimestamp.Before(&database.CreationTimestamp) ||
+			(other.CreationTimestamp.Equal(&database.CreationTimestamp) && other.Name < database.Name) {
+			counted = append(counted, *other)
 		}
+	}
+	total := 0
+	for _, limit := range principalConnectionLimits(counted) {
+		total += int(limit)
+	}
+	return total
+}
 
-		if hasGroup {
-			groupName := principal.Group.Name
-			groupPrincipalID := principal.Group.PrincipalId
-			groupNameField := field("group.name")
-			groupPrincipalIDField := field("group.principalId")
-
-			validationErrors = validateAccessName(validationErrors, groupNameField, groupName, databaseMaxPrincipalNameLength)
-			if strings.TrimSpace(groupPrincipalID) == "" {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					groupPrincipalIDField,
-					databaseValidationReasonRequired,
-					"group.principalId must be set",
-				)
-			} else if groupPrincipalID != strings.TrimSpace(groupPrincipalID) {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					groupPrincipalIDField,
-					databaseValidationReasonInvalid,
-					"group.principalId must not have leading or trailing whitespace",
-				)
-			} else if !entraPrincipalIDPattern.MatchString(groupPrincipalID) {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					groupPrincipalIDField,
-					databaseValidationReasonInvalid,
-					"group.principalId must be an Entra object ID GUID",
-				)
+// principalConnectionLimits returns the effective connection limit of each
+// principal of databases, keyed by databasePrincipalKey: the lowest limit any
+// of them sets, since the limit belongs to the principal's login role on the
+// whole server. Principals no Database sets a limit for are left out.
+func principalConnectionLimits(databases []storagev1alpha1.Database) map[string]int32 {
+	limits := map[string]int32{}
+	for i := range databases {
+		database := &databases[i]
+		for _, principal := range database.Spec.Access.Principals {
+			if principal.ConnectionLimit == nil {
+				continue
 			}
-			if groupPrincipalID != "" {
-				principalKey = "group:" + strings.ToLower(groupPrincipalID)
-			} else if groupName != "" {
-				principalKey = "group-name:" + groupName
+			key := databasePrincipalKey(database, principal)
+			if limit, ok := limits[key]; !ok || *principal.ConnectionLimit < limit {
+				limits[key] = *principal.ConnectionLimit
 			}
 		}
+	}
+	return limits
+}
 
-		if hasServicePrincipal {
-			servicePrincipalName := principal.ServicePrincipal.Name
-			servicePrincipalID := principal.ServicePrincipal.PrincipalId
-			servicePrincipalNameField := field("servicePrincipal.name")
-			servicePrincipalIDField := field("servicePrincipal.principalId")
-
-			validationErrors = validateAccessName(validationErrors, servicePrincipalNameField, servicePrincipalName, databaseMaxPrincipalNameLength)
-			if strings.TrimSpace(servicePrincipalID) == "" {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					servicePrincipalIDField,
-					databaseValidationReasonRequired,
-					"servicePrincipal.principalId must be set",
-				)
-			} else if servicePrincipalID != strings.TrimSpace(servicePrincipalID) {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					servicePrincipalIDField,
-					databaseValidationReasonInvalid,
-					"servicePrincipal.principalId must not have leading or trailing whitespace",
-				)
-			} else if !entraPrincipalIDPattern.MatchString(servicePrincipalID) {
-				validationErrors = appendDatabaseValidationError(
-					validationErrors,
-					servicePrincipalIDField,
-					databaseValidationReasonInvalid,
-					"servicePrincipal.principalId must be an Entra object ID GUID",
-				)
-			}
-			if servicePrincipalID != "" {
-				principalKey = "servicePrincipal:" + strings.ToLower(servicePrincipalID)
-			} else if servicePrincipalName != "" {
-				principalKey = "servicePrincipal-name:" + servicePrincipalName
-			}
-		}
+// databasePrincipalKey identifies the principal of a spec entry across the
+// Databases on a server: the object ID of a group or service principal, or
+// the ApplicationIdentity an identityRef names.
+func databasePrincipalKey(database *storagev1alpha1.Database, principal storagev1alpha1.DatabaseAccessPrincipalSpec) string {
+	switch {
+	case principal.Group != nil:
+		return string(dbUtil.PrincipalTypeGroup) + ":" + strings.ToLower(strings.TrimSpace(principal.Group.PrincipalId))
+	case principal.ServicePrincipal != nil:
+		return string(dbUtil.PrincipalTypeService) + ":" + strings.ToLower(strings.TrimSpace(principal.ServicePrincipal.PrincipalId))
+	case principal.IdentityRef != nil:
+		return "identity:" + databaseIdentityRefNamespace(database, principal.IdentityRef) + "/" + strings.TrimSpace(principal.IdentityRef.Name)
+	}
+	return ""
+}
 
-		if principalKey == "" {
-			continue
-		}
-		if firstField, ok := seen[principalKey]; ok {
-			validationErrors = appendDatabaseValidationError(
-				validationErrors,
-				field(""),
-				databaseValidationReasonConflict,
-				fmt.Sprintf("principal duplicates %s", firstField),
-			)
-			continue
+// serverPrincipalConnectionLimits returns the effective connection limits of
+// the principals on database's server, counting every Database there.
+func (r *DatabaseReconciler) serverPrincipalConnectionLimits(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+) (map[string]int32, error) {
+	var databases storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &databases, client.InNamespace(database.Namespace)); err != nil {
+		return nil, fmt.Errorf("list Databases in namespace %s: %w", database.Namespace, err)
+	}
+	serverName := databaseServerName(database)
+	onServer := []storagev1alpha1.Database{*database}
+	for i := range databases.Items {
+		other := &databases.Items[i]
+		if other.Name != database.Name && databaseServerName(other) == serverName {
+			onServer = append(onServer, *other)
 		}
-		seen[principalKey] = field("")
 	}
-
-	return validationErrors
+	return principalConnectionLimits(onServer), nil
 }
 
-func validateAccessName(
-	validationErrors []storagev1alpha1.DatabaseValidationError,
-	field, value string,
-	maxLength int,
-) []storagev1alpha1.DatabaseValidationError {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return appendDatabaseValidationError(
-			validationErrors,
-			field,
-			databaseValidationReasonRequired,
-			fmt.Sprintf("%s must be set", field),
-		)
+// databaseWithinServerLimit reports whether database is among the first
+// maxDatabases Databases on its server, oldest first. Databases being deleted
+// still hold their slot until they are gone.
+func databaseWithinServerLimit(
+	database *storagev1alpha1.Database,
+	databases []storagev1alpha1.Database,
+	maxDatabases int,
+) bool {
+	serverName := databaseServerName(database)
+	onServer := make([]storagev1alpha1.Database, 0, len(databases))
+	for i := range databases {
+		if databaseServerName(&databases[i]) == serverName {
+			onServer = append(onServer, databases[i])
+		}
 	}
-	if trimmed != value {
-		return appendDatabaseValidationError(
-			validationErrors,
-			field,
-			databaseValidationReasonInvalid,
-			fmt.Sprintf("%s must not have leading or trailing whitespace", field),
-		)
+	sort.Slice(onServer, func(i, j int) bool {
+		if !onServer[i].CreationTimestamp.Equal(&onServer[j].CreationTimestamp) {
+			return onServer[i].CreationTimestamp.Before(&onServer[j].CreationTimestamp)
+		}
+		return onServer[i].Name < onServer[j].Name
+	})
+	for i := range onServer {
+		if onServer[i].Name == database.Name {
+			return i < maxDatabases
+		}
 	}
-	if strings.ContainsRune(value, 0) {
-		return appendDatabaseValidationError(
-			validationErrors,
-			field,
-			databaseValidationReasonInvalid,
-			fmt.Sprintf("%s must not contain NUL bytes", field),
-		)
+	return len(onServer) < maxDatabases
+}
+
+func hasDatabaseValidationError(validationErrors []storagev1alpha1.DatabaseValidationError, field, reason string) bool {
+	for i := range validationErrors {
+		if validationErrors[i].Field == field && validationErrors[i].Reason == reason {
+			return true
+		}
 	}
-	if len(value) > maxLength {
-		return appendDatabaseValidationError(
-			validationErrors,
-			field,
-			databaseValidationReasonInvalid,
-			fmt.Sprintf("%s must be at most %d characters", field, maxLength),
-		)
+	return false
+}
+
+func hasDatabaseValidationReason(validationErrors []storagev1alpha1.DatabaseValidationError, reason string) bool {
+	for i := range validationErrors {
+		if validationErrors[i].Reason == reason {
+			return true
+		}
 	}
-	return validationErrors
+	return false
 }
 
 func (r *DatabaseReconciler) mapDatabaseServerToDatabases(
@@ -551,7 +555,41 @@ func (r *DatabaseReconciler) mapDatabaseServerToDatabases(
 	requests := make([]ctrl.Request, 0)
 	for i := range list.Items {
 		database := list.Items[i]
-		if strings.TrimSpace(database.Spec.Server.Name) != obj.GetName() {
+		if databaseServerName(&database) != obj.GetName() && !databaseAwaitsPlacement(&database) &&
+			databaseMigrationTarget(&database) != obj.GetName() {
+			continue
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      database.Name,
+				Namespace: database.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// mapDatabaseToServerDatabases enqueues the other Databases on a Database's
+// server when its spec changes, since the connection limits it sets are part
+// of the effective limits their access Jobs apply.
+func (r *DatabaseReconciler) mapDatabaseToServerDatabases(
+	ctx context.Context,
+	obj client.Object,
+) []ctrl.Request {
+	changed, ok := obj.(*storagev1alpha1.Database)
+	if !ok {
+		return nil
+	}
+	var list storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &list, client.InNamespace(changed.Namespace)); err != nil {
+		return nil
+	}
+
+	serverName := databaseServerName(changed)
+	requests := make([]ctrl.Request, 0)
+	for i := range list.Items {
+		database := list.Items[i]
+		if database.Name == changed.Name || databaseServerName(&database) != serverName {
 			continue
 		}
 		requests = append(requests, ctrl.Request{
@@ -571,15 +609,17 @@ func (r *DatabaseReconciler) mapApplicationIdentityToDatabases(
 	identityName := obj.GetName()
 	identityNamespace := obj.GetNamespace()
 
+	// Databases in other namespaces may reference the identity too, so list
+	// them all.
 	var list storagev1alpha1.DatabaseList
-	if err := r.List(ctx, &list, client.InNamespace(identityNamespace)); err != nil {
+	if err := r.List(ctx, &list); err != nil {
 		return nil
 	}
 
 	requests := make([]ctrl.Request, 0)
 	for i := range list.Items {
 		database := list.Items[i]
-		if !databaseReferencesApplicationIdentity(&database, identityName) {
+		if !databaseReferencesApplicationIdentity(&database, identityNamespace, identityName) {
 			continue
 		}
 		requests = append(requests, ctrl.Request{
@@ -592,9 +632,11 @@ func (r *DatabaseReconciler) mapApplicationIdentityToDatabases(
 	return requests
 }
 
-func databaseReferencesApplicationIdentity(database *storagev1alpha1.Database, identityName string) bool {
+func databaseReferencesApplicationIdentity(database *storagev1alpha1.Database, identityNamespace, identityName string) bool {
 	for _, principal := range database.Spec.Access.Principals {
-		if principal.IdentityRef != nil && principal.IdentityRef.Name == identityName {
+		if principal.IdentityRef != nil &&
+			principal.IdentityRef.Name == identityName &&
+			databaseIdentityRefNamespace(database, principal.IdentityRef) == identityNamespace {
 			return true
 		}
 	}
@@ -602,13 +644,23 @@ func databaseReferencesApplicationIdentity(database *storagev1alpha1.Database, i
 }
 
 func (r *DatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &storagev1alpha1.Database{},
+		schemaMigrationsConfigMapIndexField, schemaMigrationsConfigMapIndex); err != nil {
+		return fmt.Errorf("index Databases by schema migrations ConfigMap: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&storagev1alpha1.Database{}).
 		Owns(&dbforpostgresqlv1.FlexibleServersDatabase{}).
 		Owns(&batchv1.Job{}).
+		Owns(&batchv1.CronJob{}).
 		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
 		Watches(&storagev1alpha1.DatabaseServer{}, handler.EnqueueRequestsFromMapFunc(r.mapDatabaseServerToDatabases)).
+		Watches(&storagev1alpha1.Database{}, handler.EnqueueRequestsFromMapFunc(r.mapDatabaseToServerDatabases),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Watches(&identityv1alpha1.ApplicationIdentity{}, handler.EnqueueRequestsFromMapFunc(r.mapApplicationIdentityToDatabases)).
+		Watches(&storagev1alpha1.DatabaseAccessGrant{}, handler.EnqueueRequestsFromMapFunc(r.mapDatabaseAccessGrantToDatabases)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToDatabases)).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: 1,
 		}).
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+)
+
+const (
+	// databaseAccessAuditInterval is how often the audit Job compares the
+	// access in a database with the access the operator manages.
+	databaseAccessAuditInterval = 6 * time.Hour
+
+	databaseAccessAuditComponentLabelValue = "database-access-audit"
+)
+
+// ensureDatabaseAccessAudit keeps status.accessAudit up to date once access
+// is provisioned. Every databaseAccessAuditInterval, or right away after the
+// mode changed, it runs the provisioning Job in audit mode with the current
+// access payload and records the drift the Job reports. The audit never
+// affects Ready. It returns when the next audit is due, or zero while the Job
+// runs (the Owns(batchv1.Job) watch re-triggers the reconcile).
+func (r *DatabaseReconciler) ensureDatabaseAccessAudit(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+	serverName string,
+	adminIdentity resolvedAdminIdentity,
+	accessPrincipals []dbUtil.AccessPrincipal,
+) (time.Duration, error) {
+	mode := databaseAccessAuditMode(database)
+
+	var lastObserved time.Time
+	if status := database.Status.AccessAudit; status != nil && status.ObservedTime != nil {
+		lastObserved = status.ObservedTime.Time
+		if remaining := time.Until(lastObserved.Add(databaseAccessAuditInterval)); remaining > 0 && status.Mode == mode {
+			return remaining, nil
+		}
+	}
+
+	jobName := databaseAccessAuditJobName(database, serverName, adminIdentity, accessPrincipals, mode, lastObserved)
+	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
+		Owner:              database,
+		JobName:            jobName,
+		Labels:             databaseAccessAuditJobLabels(serverName, database.Name),
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         serverName,
+		DatabaseHost:       database.Status.Host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		AccessPrincipals:   accessPrincipals,
+		Mode:               dbUtil.ProvisionModeAccessAudit,
+		AccessAuditEnforce: mode == storagev1alpha1.DatabaseAccessAuditModeEnforce,
+	}); err != nil {
+		return 0, err
+	}
+
+	message, found, err := readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), database.Namespace, jobName)
+	if err != nil || !found {
+		return 0, err
+	}
+	report, err := dbUtil.UnmarshalAccessAudit(message)
+	if err != nil {
+		logger.Error(err, "ignoring invalid access audit report", "jobName", jobName)
+		return 0, nil
+	}
+
+	previous := database.Status.AccessAudit
+	database.Status.AccessAudit = buildDatabaseAccessAuditStatus(report, mode, metav1.Now())
+	r.recordAccessAuditEvents(database, previous)
+	logger.Info("audited database access",
+		"jobName", jobName,
+		"mode", mode,
+		"drift", len(database.Status.AccessAudit.Drift),
+	)
+	return databaseAccessAuditInterval, nil
+}
+
+// databaseAccessAuditMode returns spec.accessAudit.mode, defaulting to Report.
+func databaseAccessAuditMode(database *storagev1alpha1.Database) storagev1alpha1.DatabaseAccessAuditMode {
+	if database.Spec.AccessAudit == nil || database.Spec.AccessAudit.Mode == "" {
+		return storagev1alpha1.DatabaseAccessAuditModeReport
+	}
+	return database.Spec.AccessAudit.Mode
+}
+
+// buildDatabaseAccessAuditStatus converts an audit report to the status.
+func buildDatabaseAccessAuditStatus(
+	report dbUtil.AccessAuditReport,
+	mode storagev1alpha1.DatabaseAccessAuditMode,
+	now metav1.Time,
+) *storagev1alpha1.DatabaseAccessAuditStatus {
+	status := &storagev1alpha1.DatabaseAccessAuditStatus{
+		ObservedTime: now.DeepCopy(),
+		Mode:         mode,
+		Truncated:    report.Truncated,
+	}
+	for _, drift := range report.Drift {
+		status.Drift = append(status.Drift, storagev1alpha1.DatabaseAccessDrift{
+			Kind:       string(drift.Kind),
+			Role:       drift.Role,
+			Grantee:    drift.Grantee,
+			Schema:     drift.Schema,
+			ObjectType: drift.ObjectType,
+			Owner:      drift.Owner,
+			Revoked:    drift.Revoked,
+		})
+	}
+	return status
+}
+
+// recordAccessAuditEvents records a Warning when the audit finds
+// drift that differs from the previous audit, so unchanged drift is reported
+// once, and a Normal Event for every audit that revoked grants.
+func (r *DatabaseReconciler) recordAccessAuditEvents(
+	database *storagev1alpha1.Database,
+	previous *storagev1alpha1.DatabaseAccessAuditStatus,
+) {
+	current := database.Status.AccessAudit
+	revoked := 0
+	for _, drift := range current.Drift {
+		if drift.Revoked {
+			revoked++
+		}
+	}
+	if revoked > 0 {
+		recordNormalEvent(r.Recorder, database, eventReasonAccessDriftRevoked, eventActionAuditAccess,
+			"Revoked %d unmanaged grants in database %q", revoked, database.Status.DatabaseName)
+	}
+	if len(current.Drift) == 0 || revoked == len(current.Drift) {
+		return
+	}
+	if previous != nil && apiequality.Semantic.DeepEqual(previous.Drift, current.Drift) {
+		return
+	}
+	kinds := map[string]struct{}{}
+	for _, drift := range current.Drift {
+		kinds[drift.Kind] = struct{}{}
+	}
+	recordWarningEvent(r.Recorder, database, eventReasonAccessDriftDetected, eventActionAuditAccess,
+		"Found %d access differences in database %q (%s)", len(current.Drift), database.Status.DatabaseName, strings.Join(slices.Sorted(maps.Keys(kinds)), ", "))
+}
+
+// databaseAccessAuditJobName embeds the previous audit time so each audit
+// gets a new Job, while retries within one audit reuse the same one.
+func databaseAccessAuditJobName(
+	database *storagev1alpha1.Database,
+	serverName string,
+	adminIdentity resolvedAdminIdentity,
+	accessPrincipals []dbUtil.AccessPrincipal,
+	mode storagev1alpha1.DatabaseAccessAuditMode,
+	lastObserved time.Time,
+) string {
+	accessPayload, err := dbUtil.MarshalAccessPrincipals(accessPrincipals)
+	if err != nil {
+		accessPayload = err.Error()
+	}
+	observed := ""
+	if !lastObserved.IsZero() {
+		observed = lastObserved.UTC().Format(time.RFC3339)
+	}
+	payload := strings.Join([]string{
+		"server=" + serverName,
+		"database=" + database.Status.DatabaseName,
+		"host=" + database.Status.Host,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"access=" + accessPayload,
+		"mode=" + string(mode),
+		"previous=" + observed,
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	return naming.WithRequiredSuffix(database.Name+"-access-audit", "-"+hash, 63, "ldb")
+}
+
+func databaseAccessAuditJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		componentLabelKey:          databaseAccessAuditComponentLabelValue,
+	}
+}
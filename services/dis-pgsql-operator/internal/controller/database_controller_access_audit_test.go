@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildDatabaseAccessAuditStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC))
+	report := dbUtil.AccessAuditReport{
+		Version: dbUtil.AccessAuditPayloadVersion,
+		Drift: []dbUtil.AccessDrift{
+			{Kind: dbUtil.AccessDriftUnmanagedMember, Role: "dispg-reader", Grantee: "intruder", Revoked: true},
+			{Kind: dbUtil.AccessDriftSchemaOwner, Role: "dispg-owner", Schema: "appdb", Owner: "someone"},
+		},
+		Truncated: true,
+	}
+
+	status := buildDatabaseAccessAuditStatus(report, storagev1alpha1.DatabaseAccessAuditModeEnforce, now)
+
+	if status.ObservedTime == nil || !status.ObservedTime.Equal(&now) {
+		t.Fatalf("expected observedTime %v, got %v", now, status.ObservedTime)
+	}
+	if status.Mode != storagev1alpha1.DatabaseAccessAuditModeEnforce || !status.Truncated {
+		t.Fatalf("expected mode and truncation to be carried over, got %#v", status)
+	}
+	want := []storagev1alpha1.DatabaseAccessDrift{
+		{Kind: "UnmanagedMember", Role: "dispg-reader", Grantee: "intruder", Revoked: true},
+		{Kind: "SchemaOwner", Role: "dispg-owner", Schema: "appdb", Owner: "someone"},
+	}
+	if len(status.Drift) != len(want) {
+		t.Fatalf("expected %d drift entries, got %#v", len(want), status.Drift)
+	}
+	for i := range want {
+		if status.Drift[i] != want[i] {
+			t.Fatalf("drift[%d] = %#v, want %#v", i, status.Drift[i], want[i])
+		}
+	}
+}
+
+func TestDatabaseAccessAuditJobName(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	database.Name = "appdb"
+	database.Status.DatabaseName = "appdb"
+	admin := testDebugAdminIdentity()
+	principals := []dbUtil.AccessPrincipal{{Role: dbUtil.AccessRoleOwner, Name: "app", PrincipalID: "id", PrincipalType: dbUtil.PrincipalTypeService}}
+	earlier := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	report := databaseAccessAuditJobName(database, "shared", admin, principals, storagev1alpha1.DatabaseAccessAuditModeReport, earlier)
+	if again := databaseAccessAuditJobName(database, "shared", admin, principals, storagev1alpha1.DatabaseAccessAuditModeReport, earlier); again != report {
+		t.Fatalf("expected a stable name within one audit, got %q and %q", report, again)
+	}
+	if enforce := databaseAccessAuditJobName(database, "shared", admin, principals, storagev1alpha1.DatabaseAccessAuditModeEnforce, earlier); enforce == report {
+		t.Fatalf("expected the mode to change the Job name")
+	}
+	if next := databaseAccessAuditJobName(database, "shared", admin, principals, storagev1alpha1.DatabaseAccessAuditModeReport, earlier.Add(databaseAccessAuditInterval)); next == report {
+		t.Fatalf("expected each audit to get a new Job")
+	}
+}
+
+func TestDatabaseAccessAuditMode(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	if got := databaseAccessAuditMode(database); got != storagev1alpha1.DatabaseAccessAuditModeReport {
+		t.Fatalf("expected Report by default, got %q", got)
+	}
+	database.Spec.AccessAudit = &storagev1alpha1.DatabaseAccessAuditSpec{Mode: storagev1alpha1.DatabaseAccessAuditModeEnforce}
+	if got := databaseAccessAuditMode(database); got != storagev1alpha1.DatabaseAccessAuditModeEnforce {
+		t.Fatalf("expected Enforce, got %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	identityv1alpha1 "github.com/Altinn/altinn-platform/services/dis-identity-operator/api/v1alpha1"
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
@@ -16,44 +17,57 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// ensureDatabaseAccess runs the access provisioning Job and, once access is
+// ready, the periodic access audit. Besides the AccessReady state it returns
+// when the next audit is due.
 func (r *DatabaseReconciler) ensureDatabaseAccess(
 	ctx context.Context,
 	logger logr.Logger,
 	database *storagev1alpha1.Database,
-) (bool, string, string, error) {
-	serverName := strings.TrimSpace(database.Spec.Server.Name)
+) (bool, string, string, time.Duration, error) {
+	serverName := databaseServerName(database)
 	var db storagev1alpha1.DatabaseServer
 	if err := r.Get(ctx, types.NamespacedName{
 		Name:      serverName,
 		Namespace: database.Namespace,
 	}, &db); err != nil {
 		if apierrors.IsNotFound(err) {
-			return false, databaseReasonProvisioning, "Referenced DatabaseServer is not available", nil
+			return false, databaseReasonProvisioning, "Referenced DatabaseServer is not available", 0, nil
 		}
-		return false, "", "", fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, serverName, err)
+		return false, "", "", 0, fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, serverName, err)
 	}
 
 	adminIdentity, requeue, err := r.resolveAdminIdentity(ctx, logger, &db)
 	if err != nil {
-		return false, "", "", err
+		return false, "", "", 0, err
 	}
 	if requeue {
-		return false, databaseReasonProvisioning, "Waiting for DatabaseServer admin identity", nil
+		return false, databaseReasonProvisioning, "Waiting for DatabaseServer admin identity", 0, nil
 	}
 
-	accessPrincipals, serviceConnections, requeue, message, err := r.resolveDatabaseAccessPrincipals(ctx, logger, database)
+	accessPrincipals, serviceConnections, ungranted, requeue, message, err := r.resolveDatabaseAccessPrincipals(ctx, logger, database)
 	if err != nil {
-		return false, "", "", err
+		return false, "", "", 0, err
 	}
 	if requeue {
-		return false, databaseReasonProvisioning, message, nil
+		return false, databaseReasonProvisioning, message, 0, nil
 	}
 
-	jobName := databaseAccessProvisionJobName(database, serverName, adminIdentity, accessPrincipals)
+	// Validation requires a principal, so none are left only when every
+	// DatabaseAccessGrant was withdrawn. The Job still runs to revoke their
+	// access and the connection details are removed before AccessGrantMissing
+	// is reported.
+	extensions, notEnabledExtensions := planDatabaseExtensions(database, &db)
+	jobName := databaseAccessProvisionJobName(database, serverName, adminIdentity, accessPrincipals, extensions)
+	mode := dbUtil.ProvisionModeAccess
+	if len(accessPrincipals) == 0 {
+		mode = dbUtil.ProvisionModeRevokeAllAccess
+	}
 	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
 		Owner:               database,
 		JobName:             jobName,
 		Labels:              databaseAccessJobLabels(serverName, database.Name),
+		Mode:                mode,
 		ServiceAccountName:  adminIdentity.ServiceAccountName,
 		AdminIdentityName:   adminIdentity.Name,
 		ServerName:          serverName,
@@ -63,59 +77,99 @@ func (r *DatabaseReconciler) ensureDatabaseAccess(
 		AccessPrincipals:    accessPrincipals,
 		RevokePublicConnect: true,
 		SearchPathScope:     searchPathScopeDatabase,
+		Extensions:          extensions,
 	}); err != nil {
-		return false, "", "", err
+		return false, "", "", 0, err
 	}
 
 	complete, err := r.databaseAccessJobComplete(ctx, database, jobName)
 	if err != nil {
-		return false, "", "", err
+		return false, "", "", 0, err
+	}
+
+	var extensionsReport *dbUtil.DatabaseExtensionsReport
+	if complete && len(extensions) > 0 {
+		extensionsReport, err = r.databaseExtensionsJobResult(ctx, logger, database.Namespace, jobName)
+		if err != nil {
+			return false, "", "", 0, err
+		}
 	}
+	database.Status.Extensions = buildDatabaseExtensionStatuses(database.Status.Extensions, extensions, notEnabledExtensions, extensionsReport)
+	setDatabaseExtensionsCondition(database)
 	if complete {
 		coords := make([]connection.Coordinates, 0, len(serviceConnections))
+		poolerPort := r.poolerPort(&db)
 		for _, sc := range serviceConnections {
 			coords = append(coords, connection.Coordinates{
-				Host:        database.Status.Host,
-				Port:        database.Status.Port,
-				DBName:      database.Status.DatabaseName,
-				User:        sc.ManagedIdentityName,
-				IdentityRef: sc.IdentityRef,
+				Host:         database.Status.Host,
+				ReadOnlyHost: readOnlyHost(&db),
+				Port:         database.Status.Port,
+				PoolerPort:   poolerPort,
+				DBName:       database.Status.DatabaseName,
+				User:         sc.ManagedIdentityName,
+				IdentityRef:  sc.IdentityRef,
+				Namespace:    sc.Namespace,
 			})
 		}
 		if err := r.reconcileConnectionConfigMaps(ctx, database, coords); err != nil {
-			return false, "", "", err
+			return false, "", "", 0, err
 		}
-		return true, databaseReasonReady, "Database access is ready", nil
+		if err := r.reconcileServiceBindingSecrets(ctx, database, coords); err != nil {
+			return false, "", "", 0, err
+		}
+		if len(ungranted) > 0 {
+			return false, databaseReasonAccessGrantMissing, databaseAccessGrantMissingMessage(ungranted), 0, nil
+		}
+		auditAfter, err := r.ensureDatabaseAccessAudit(ctx, logger, database, serverName, adminIdentity, accessPrincipals)
+		if err != nil {
+			return false, "", "", 0, err
+		}
+		return true, databaseReasonReady, "Database access is ready", auditAfter, nil
 	}
 
-	return false, databaseReasonProvisioning, "Database access provisioning job is running", nil
+	return false, databaseReasonProvisioning, "Database access provisioning job is running", 0, nil
 }
 
 // resolvedServiceConnection pairs a service principal's spec identityRef.name
 // (known at authoring time; drives the connection ConfigMap name and principal
 // label) with the resolved managed-identity name the app authenticates as.
+// Namespace is only set for identities in another namespace.
 type resolvedServiceConnection struct {
 	IdentityRef         string
+	Namespace           string
 	ManagedIdentityName string
 }
 
+// resolveDatabaseAccessPrincipals resolves the spec principals into the
+// payload of the access Job. Identities in another namespace that no
+// DatabaseAccessGrant allows are left out, so withdrawing a grant revokes the
+// role, and are returned as "namespace/name" in ungranted.
 func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 	ctx context.Context,
 	logger logr.Logger,
 	database *storagev1alpha1.Database,
-) ([]dbUtil.AccessPrincipal, []resolvedServiceConnection, bool, string, error) {
+) ([]dbUtil.AccessPrincipal, []resolvedServiceConnection, []string, bool, string, error) {
 	accessPrincipals := make([]dbUtil.AccessPrincipal, 0, len(database.Spec.Access.Principals))
 	serviceConnections := make([]resolvedServiceConnection, 0, len(database.Spec.Access.Principals))
+	var ungranted []string
 	seen := map[string]struct{}{}
+	connectionLimits, err := r.serverPrincipalConnectionLimits(ctx, database)
+	if err != nil {
+		return nil, nil, nil, false, "", err
+	}
 
 	for _, principal := range database.Spec.Access.Principals {
 		role := databaseAccessPayloadRole(principal.Role)
+		scope := databaseAccessPayloadScope(principal.Scope, database.Status.DatabaseName)
+		settings := databaseAccessPayloadSettings(principal, connectionLimits[databasePrincipalKey(database, principal)])
 		if principal.Group != nil {
 			accessPrincipal := dbUtil.AccessPrincipal{
 				Role:          role,
 				Name:          strings.TrimSpace(principal.Group.Name),
 				PrincipalID:   strings.TrimSpace(principal.Group.PrincipalId),
 				PrincipalType: dbUtil.PrincipalTypeGroup,
+				Scope:         scope,
+				Settings:      settings,
 			}
 			key := string(accessPrincipal.PrincipalType) + ":" + strings.ToLower(accessPrincipal.PrincipalID)
 			if _, ok := seen[key]; ok {
@@ -132,6 +186,8 @@ func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 				Name:          strings.TrimSpace(principal.ServicePrincipal.Name),
 				PrincipalID:   strings.TrimSpace(principal.ServicePrincipal.PrincipalId),
 				PrincipalType: dbUtil.PrincipalTypeService,
+				Scope:         scope,
+				Settings:      settings,
 			}
 			key := string(accessPrincipal.PrincipalType) + ":" + strings.ToLower(accessPrincipal.PrincipalID)
 			if _, ok := seen[key]; ok {
@@ -150,19 +206,33 @@ func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 		}
 
 		refName := strings.TrimSpace(principal.IdentityRef.Name)
+		refNamespace := databaseIdentityRefNamespace(database, principal.IdentityRef)
+		foreign := refNamespace != database.Namespace
+		if foreign {
+			granted, err := r.databaseAccessGranted(ctx, database, refNamespace, refName)
+			if err != nil {
+				return nil, nil, nil, false, "", err
+			}
+			if !granted {
+				logger.Info("No DatabaseAccessGrant allows the ApplicationIdentity", "namespace", refNamespace, "name", refName)
+				ungranted = append(ungranted, refNamespace+"/"+refName)
+				continue
+			}
+		}
+
 		var appIdentity identityv1alpha1.ApplicationIdentity
-		if err := r.Get(ctx, types.NamespacedName{Name: refName, Namespace: database.Namespace}, &appIdentity); err != nil {
+		if err := r.Get(ctx, types.NamespacedName{Name: refName, Namespace: refNamespace}, &appIdentity); err != nil {
 			if apierrors.IsNotFound(err) {
-				logger.Info("ApplicationIdentity for Database access not found yet", "name", refName)
-				return nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q", refName), nil
+				logger.Info("ApplicationIdentity for Database access not found yet", "namespace", refNamespace, "name", refName)
+				return nil, nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q", refName), nil
 			}
-			return nil, nil, false, "", fmt.Errorf("get ApplicationIdentity %s/%s: %w", database.Namespace, refName, err)
+			return nil, nil, nil, false, "", fmt.Errorf("get ApplicationIdentity %s/%s: %w", refNamespace, refName, err)
 		}
 
 		ready, readyFound := applicationIdentityReady(&appIdentity)
 		if readyFound && !ready {
-			logger.Info("ApplicationIdentity for Database access not ready yet", "name", refName)
-			return nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q to be ready", refName), nil
+			logger.Info("ApplicationIdentity for Database access not ready yet", "namespace", refNamespace, "name", refName)
+			return nil, nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q to be ready", refName), nil
 		}
 
 		var managedIdentityName string
@@ -174,8 +244,8 @@ func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 			principalID = strings.TrimSpace(*appIdentity.Status.PrincipalID)
 		}
 		if managedIdentityName == "" || principalID == "" {
-			logger.Info("ApplicationIdentity for Database access status not populated yet", "name", refName)
-			return nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q status", refName), nil
+			logger.Info("ApplicationIdentity for Database access status not populated yet", "namespace", refNamespace, "name", refName)
+			return nil, nil, nil, true, fmt.Sprintf("Waiting for ApplicationIdentity %q status", refName), nil
 		}
 
 		accessPrincipal := dbUtil.AccessPrincipal{
@@ -183,6 +253,8 @@ func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 			Name:          managedIdentityName,
 			PrincipalID:   principalID,
 			PrincipalType: dbUtil.PrincipalTypeService,
+			Scope:         scope,
+			Settings:      settings,
 		}
 		key := string(accessPrincipal.PrincipalType) + ":" + strings.ToLower(accessPrincipal.PrincipalID)
 		if _, ok := seen[key]; ok {
@@ -190,16 +262,20 @@ func (r *DatabaseReconciler) resolveDatabaseAccessPrincipals(
 		}
 		seen[key] = struct{}{}
 		accessPrincipals = append(accessPrincipals, accessPrincipal)
-		serviceConnections = append(serviceConnections, resolvedServiceConnection{
+		serviceConnection := resolvedServiceConnection{
 			IdentityRef:         refName,
 			ManagedIdentityName: managedIdentityName,
-		})
+		}
+		if foreign {
+			serviceConnection.Namespace = refNamespace
+		}
+		serviceConnections = append(serviceConnections, serviceConnection)
 	}
 
-	if len(accessPrincipals) == 0 {
-		return nil, nil, false, "", fmt.Errorf("database access principal resolution produced no principals")
+	if len(accessPrincipals) == 0 && len(ungranted) == 0 {
+		return nil, nil, nil, false, "", fmt.Errorf("database access principal resolution produced no principals")
 	}
-	return accessPrincipals, serviceConnections, false, "", nil
+	return accessPrincipals, serviceConnections, ungranted, false, "", nil
 }
 
 func databaseAccessPayloadRole(role storagev1alpha1.DatabaseAccessRole) dbUtil.AccessRole {
@@ -215,6 +291,49 @@ func databaseAccessPayloadRole(role storagev1alpha1.DatabaseAccessRole) dbUtil.A
 	}
 }
 
+// databaseAccessPayloadScope converts a principal scope to the access payload.
+// Tables without a schema are in the database's own schema, which is named
+// after the database.
+func databaseAccessPayloadScope(scope *storagev1alpha1.DatabaseAccessScope, schemaName string) *dbUtil.AccessScope {
+	if scope == nil {
+		return nil
+	}
+	payload := &dbUtil.AccessScope{
+		Schemas:          append([]string(nil), scope.Schemas...),
+		ExecuteFunctions: scope.ExecuteFunctions,
+	}
+	for _, table := range scope.Tables {
+		tableSchema := strings.TrimSpace(table.Schema)
+		if tableSchema == "" {
+			tableSchema = schemaName
+		}
+		payload.Tables = append(payload.Tables, dbUtil.AccessTable{Schema: tableSchema, Name: strings.TrimSpace(table.Name)})
+	}
+	return payload
+}
+
+// databaseAccessPayloadSettings converts the timeouts of a principal and its
+// effective connection limit on the server, zero when no Database there sets
+// one, to the access payload, or nil when there are none.
+func databaseAccessPayloadSettings(principal storagev1alpha1.DatabaseAccessPrincipalSpec, connectionLimit int32) *dbUtil.RoleSettings {
+	if connectionLimit == 0 && principal.StatementTimeout == nil && principal.IdleInTransactionSessionTimeout == nil {
+		return nil
+	}
+	settings := &dbUtil.RoleSettings{}
+	if connectionLimit > 0 {
+		settings.ConnectionLimit = &connectionLimit
+	}
+	if principal.StatementTimeout != nil {
+		milliseconds := principal.StatementTimeout.Milliseconds()
+		settings.StatementTimeoutMilliseconds = &milliseconds
+	}
+	if principal.IdleInTransactionSessionTimeout != nil {
+		milliseconds := principal.IdleInTransactionSessionTimeout.Milliseconds()
+		settings.IdleInTransactionSessionTimeoutMilliseconds = &milliseconds
+	}
+	return settings
+}
+
 func (r *DatabaseReconciler) databaseAccessJobComplete(
 	ctx context.Context,
 	database *storagev1alpha1.Database,
@@ -239,12 +358,13 @@ func databaseAccessProvisionJobName(
 	serverName string,
 	adminIdentity resolvedAdminIdentity,
 	accessPrincipals []dbUtil.AccessPrincipal,
+	extensions []dbUtil.DatabaseExtension,
 ) string {
 	accessPayload, err := dbUtil.MarshalAccessPrincipals(accessPrincipals)
 	if err != nil {
 		accessPayload = err.Error()
 	}
-	payload := strings.Join([]string{
+	fields := []string{
 		"server=" + serverName,
 		"database=" + database.Status.DatabaseName,
 		"host=" + database.Status.Host,
@@ -254,7 +374,16 @@ func databaseAccessProvisionJobName(
 		"schema=" + database.Status.DatabaseName,
 		"revokePublicConnect=true",
 		"searchPathScope=database",
-	}, ";")
+	}
+	// Only hashed when set, so Databases without extensions keep their Job.
+	if len(extensions) > 0 {
+		extensionsPayload, err := dbUtil.MarshalDatabaseExtensions(extensions)
+		if err != nil {
+			extensionsPayload = err.Error()
+		}
+		fields = append(fields, "extensions="+extensionsPayload)
+	}
+	payload := strings.Join(fields, ";")
 	hash := naming.StableSHA256Hex(payload)[:8]
 	base := fmt.Sprintf("%s-access-provision", database.Name)
 	return naming.WithRequiredSuffix(base, "-"+hash, 63, "ldb")
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// databaseConnectionFinalizer holds a Database that references identities
+	// in other namespaces until the connection details published there are
+	// deleted. Owner references cannot cross namespaces, so garbage collection
+	// does not remove them.
+	databaseConnectionFinalizer = "storage.dis.altinn.cloud/database-connection-finalizer"
+
+	// databaseReasonAccessGrantMissing is set on AccessReady while an
+	// identityRef in another namespace is not allowed by a DatabaseAccessGrant.
+	databaseReasonAccessGrantMissing = "AccessGrantMissing"
+)
+
+// databaseIdentityRefNamespace returns the namespace of the ApplicationIdentity
+// ref points to, defaulting to the Database's namespace.
+func databaseIdentityRefNamespace(database *storagev1alpha1.Database, ref *storagev1alpha1.DatabaseIdentityRef) string {
+	if namespace := strings.TrimSpace(ref.Namespace); namespace != "" {
+		return namespace
+	}
+	return database.Namespace
+}
+
+// hasForeignIdentityRefs reports whether any principal of database references
+// an ApplicationIdentity in another namespace.
+func hasForeignIdentityRefs(database *storagev1alpha1.Database) bool {
+	for _, principal := range database.Spec.Access.Principals {
+		if principal.IdentityRef != nil && databaseIdentityRefNamespace(database, principal.IdentityRef) != database.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// databaseAccessGrantPermits reports whether grant allows the Database
+// databaseNamespace/databaseName to reference the ApplicationIdentity
+// identityName in the grant's namespace. Empty names match every Database or
+// identity.
+func databaseAccessGrantPermits(
+	grant *storagev1alpha1.DatabaseAccessGrant,
+	databaseNamespace, databaseName, identityName string,
+) bool {
+	fromMatches := false
+	for _, from := range grant.Spec.From {
+		if from.Namespace == databaseNamespace && (from.Name == "" || from.Name == databaseName) {
+			fromMatches = true
+			break
+		}
+	}
+	if !fromMatches {
+		return false
+	}
+	for _, to := range grant.Spec.To {
+		if to.Name == "" || to.Name == identityName {
+			return true
+		}
+	}
+	return false
+}
+
+// databaseAccessGranted reports whether a DatabaseAccessGrant in
+// identityNamespace allows database to reference identityName.
+func (r *DatabaseReconciler) databaseAccessGranted(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	identityNamespace, identityName string,
+) (bool, error) {
+	var grants storagev1alpha1.DatabaseAccessGrantList
+	if err := r.List(ctx, &grants, client.InNamespace(identityNamespace)); err != nil {
+		return false, fmt.Errorf("list DatabaseAccessGrants in %s: %w", identityNamespace, err)
+	}
+	for i := range grants.Items {
+		if databaseAccessGrantPermits(&grants.Items[i], database.Namespace, database.Name, identityName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func databaseAccessGrantMissingMessage(ungranted []string) string {
+	return fmt.Sprintf("Waiting for a DatabaseAccessGrant for ApplicationIdentity %s", strings.Join(ungranted, ", "))
+}
+
+// mapDatabaseAccessGrantToDatabases enqueues the Databases a grant may apply
+// to, so creating or withdrawing a grant is picked up without waiting for a
+// resync.
+func (r *DatabaseReconciler) mapDatabaseAccessGrantToDatabases(
+	ctx context.Context,
+	obj client.Object,
+) []ctrl.Request {
+	grant, ok := obj.(*storagev1alpha1.DatabaseAccessGrant)
+	if !ok {
+		return nil
+	}
+
+	var requests []ctrl.Request
+	seen := map[types.NamespacedName]struct{}{}
+	for _, from := range grant.Spec.From {
+		var databases storagev1alpha1.DatabaseList
+		if err := r.List(ctx, &databases, client.InNamespace(from.Namespace)); err != nil {
+			return nil
+		}
+		for i := range databases.Items {
+			database := &databases.Items[i]
+			if from.Name != "" && from.Name != database.Name {
+				continue
+			}
+			key := types.NamespacedName{Namespace: database.Namespace, Name: database.Name}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			requests = append(requests, ctrl.Request{NamespacedName: key})
+		}
+	}
+	return requests
+}
+
+// upsertForeignConnectionObject creates or updates a ConfigMap or Secret that
+// is published for database in another namespace. It has no owner reference,
+// so an existing object is only taken over when its labels show that it was
+// published for the same Database.
+func (r *DatabaseReconciler) upsertForeignConnectionObject(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	current client.Object,
+	mutate func(),
+) error {
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, current, func() error {
+		created := current.GetCreationTimestamp()
+		if !created.IsZero() && !publishedForDatabase(current, database) {
+			return fmt.Errorf(
+				"%s/%s already exists and was not published for Database %s/%s",
+				current.GetNamespace(), current.GetName(), database.Namespace, database.Name,
+			)
+		}
+		mutate()
+		return nil
+	})
+	return err
+}
+
+func (r *DatabaseReconciler) upsertForeignConnectionConfigMap(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	desired *corev1.ConfigMap,
+) error {
+	current := &corev1.ConfigMap{}
+	current.SetName(desired.GetName())
+	current.SetNamespace(desired.GetNamespace())
+	return r.upsertForeignConnectionObject(ctx, database, current, func() {
+		current.Labels = desired.Labels
+		current.Data = desired.Data
+		current.BinaryData = nil
+	})
+}
+
+func (r *DatabaseReconciler) upsertForeignServiceBindingSecret(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	desired *corev1.Secret,
+) error {
+	current := &corev1.Secret{}
+	current.SetName(desired.GetName())
+	current.SetNamespace(desired.GetNamespace())
+	return r.upsertForeignConnectionObject(ctx, database, current, func() {
+		current.Labels = desired.Labels
+		if current.CreationTimestamp.IsZero() {
+			current.Type = desired.Type
+		}
+		current.Data = desired.Data
+		current.StringData = nil
+	})
+}
+
+// cleanupStaleForeignConnectionObjects deletes the objects of list's kind
+// that were published for database in other namespaces and are not in
+// desired. With an empty desired it deletes all of them.
+func (r *DatabaseReconciler) cleanupStaleForeignConnectionObjects(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	list client.ObjectList,
+	desired map[types.NamespacedName]struct{},
+) error {
+	if err := r.List(ctx, list, client.MatchingLabels{
+		connection.LabelComponent:         connection.ComponentValue,
+		connection.LabelDatabaseNamespace: database.Namespace,
+	}); err != nil {
+		return err
+	}
+	return meta.EachListItem(list, func(item runtime.Object) error {
+		current, ok := item.(client.Object)
+		if !ok || !publishedForDatabase(current, database) {
+			return nil
+		}
+		if _, ok := desired[client.ObjectKeyFromObject(current)]; ok {
+			return nil
+		}
+		return client.IgnoreNotFound(r.Delete(ctx, current))
+	})
+}
+
+// publishedForDatabase reports whether obj carries the labels of connection
+// details published for database in another namespace.
+func publishedForDatabase(obj client.Object, database *storagev1alpha1.Database) bool {
+	labels := obj.GetLabels()
+	return obj.GetNamespace() != database.Namespace &&
+		labels[connection.LabelComponent] == connection.ComponentValue &&
+		labels[connection.LabelDatabaseNamespace] == database.Namespace &&
+		labels[connection.LabelDatabase] == database.Name
+}
+
+// releaseDatabaseConnectionFinalizer deletes the connection details published
+// for a deleted Database in other namespaces and removes
+// databaseConnectionFinalizer.
+func (r *DatabaseReconciler) releaseDatabaseConnectionFinalizer(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+) (ctrl.Result, error) {
+	for _, list := range []client.ObjectList{&corev1.ConfigMapList{}, &corev1.SecretList{}} {
+		if err := r.cleanupStaleForeignConnectionObjects(ctx, database, list, nil); err != nil {
+			return ctrl.Result{}, fmt.Errorf("delete connection details of Database %s/%s: %w", database.Namespace, database.Name, err)
+		}
+	}
+	controllerutil.RemoveFinalizer(database, databaseConnectionFinalizer)
+	if err := r.Update(ctx, database); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("remove connection finalizer from Database %s/%s: %w", database.Namespace, database.Name, err)
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+)
+
+func TestDatabaseAccessGrantPermits(t *testing.T) {
+	grant := &storagev1alpha1.DatabaseAccessGrant{
+		Spec: storagev1alpha1.DatabaseAccessGrantSpec{
+			From: []storagev1alpha1.DatabaseAccessGrantFrom{
+				{Namespace: "billing", Name: "invoices"},
+				{Namespace: "shared"},
+			},
+			To: []storagev1alpha1.DatabaseAccessGrantTo{{Name: "reporting"}},
+		},
+	}
+	tests := map[string]struct {
+		namespace, database, identity string
+		want                          bool
+	}{
+		"named database":            {namespace: "billing", database: "invoices", identity: "reporting", want: true},
+		"other database":            {namespace: "billing", database: "payments", identity: "reporting"},
+		"any database in namespace": {namespace: "shared", database: "anything", identity: "reporting", want: true},
+		"other namespace":           {namespace: "team-a", database: "invoices", identity: "reporting"},
+		"other identity":            {namespace: "billing", database: "invoices", identity: "batch"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := databaseAccessGrantPermits(grant, tt.namespace, tt.database, tt.identity); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	grant.Spec.To = []storagev1alpha1.DatabaseAccessGrantTo{{}}
+	if !databaseAccessGrantPermits(grant, "billing", "invoices", "batch") {
+		t.Fatalf("expected an empty to name to allow every identity")
+	}
+}
+
+func TestDatabaseReferencesApplicationIdentity(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	database.Namespace = "billing"
+	database.Spec.Access.Principals = []storagev1alpha1.DatabaseAccessPrincipalSpec{
+		{Role: storagev1alpha1.DatabaseAccessRoleOwner, IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "invoices"}},
+		{Role: storagev1alpha1.DatabaseAccessRoleReader, IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "reporting", Namespace: "reporting"}},
+	}
+
+	if !databaseReferencesApplicationIdentity(database, "billing", "invoices") {
+		t.Fatalf("expected the identity in the Database's namespace to be referenced")
+	}
+	if !databaseReferencesApplicationIdentity(database, "reporting", "reporting") {
+		t.Fatalf("expected the identity in another namespace to be referenced")
+	}
+	if databaseReferencesApplicationIdentity(database, "billing", "reporting") {
+		t.Fatalf("expected a same-named identity in the Database's namespace not to be referenced")
+	}
+}
+
+func TestPublishedForDatabase(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	database.Namespace = "billing"
+	database.Name = "invoices"
+
+	configMap := &corev1.ConfigMap{}
+	configMap.Namespace = "reporting"
+	configMap.Labels = map[string]string{
+		connection.LabelComponent:         connection.ComponentValue,
+		connection.LabelDatabaseNamespace: "billing",
+		connection.LabelDatabase:          "invoices",
+	}
+	if !publishedForDatabase(configMap, database) {
+		t.Fatalf("expected the ConfigMap to belong to the Database")
+	}
+
+	configMap.Labels[connection.LabelDatabase] = "payments"
+	if publishedForDatabase(configMap, database) {
+		t.Fatalf("expected a ConfigMap of another Database not to belong to it")
+	}
+
+	configMap.Labels[connection.LabelDatabase] = "invoices"
+	configMap.Namespace = "billing"
+	if publishedForDatabase(configMap, database) {
+		t.Fatalf("expected a ConfigMap in the Database's namespace not to be foreign")
+	}
+}
+
+func TestBuildUserProvisionJobRevokeAllAccess(t *testing.T) {
+	spec := userProvisionJobSpec{
+		JobName:            "app-db-access-abcd1234",
+		ServiceAccountName: "pg-admin",
+		AdminIdentityName:  "pg-admin",
+		ServerName:         "shared",
+		DatabaseHost:       "shared.postgres.database.azure.com",
+		DatabaseName:       "app",
+		SchemaName:         "app",
+		Mode:               dbUtil.ProvisionModeAccess,
+	}
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected an access run without principals to be rejected")
+	}
+
+	spec.Mode = dbUtil.ProvisionModeRevokeAllAccess
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+	env := envToMap(userProvisionJobEnv(spec))
+	if env[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeRevokeAllAccess) {
+		t.Fatalf("expected %s=%s, got %v", dbUtil.ProvisionModeEnv, dbUtil.ProvisionModeRevokeAllAccess, env)
+	}
+
+	spec.AccessPrincipals = []dbUtil.AccessPrincipal{{
+		Role:          dbUtil.AccessRoleReader,
+		Name:          "reader",
+		PrincipalID:   "11111111-1111-1111-1111-111111111111",
+		PrincipalType: dbUtil.PrincipalTypeService,
+	}}
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected revoking all access to reject principals")
+	}
+}
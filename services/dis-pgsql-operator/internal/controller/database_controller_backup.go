@@ -0,0 +1,353 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+)
+
+const (
+	databaseBackupComponentLabelValue = "database-backup"
+
+	// databaseBackupSpecHashAnnotation holds the hash of the CronJob spec the
+	// operator last wrote, so the defaulted spec is not rewritten on every
+	// reconcile.
+	databaseBackupSpecHashAnnotation = "dis.altinn.cloud/backup-spec-hash"
+
+	// backupCredentialsContainerName and backupDumpContainerName are the init
+	// containers of the backup Job. The provisioning container uploads the
+	// dump once they have run.
+	backupCredentialsContainerName = "credentials"
+	backupDumpContainerName        = "dump"
+
+	backupWorkVolumeName = "backup-work"
+	backupWorkDir        = "/backup"
+
+	// cronJobNameMaxLength leaves room for the suffix the CronJob controller
+	// appends to its Jobs.
+	cronJobNameMaxLength = 52
+)
+
+// reconcileDatabaseBackup keeps the backup CronJob of spec.schedule in
+// place, and records the outcome of its Jobs in status.backup. The CronJob
+// is removed when spec.schedule is.
+func (r *DatabaseReconciler) reconcileDatabaseBackup(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) error {
+	name := databaseBackupCronJobName(database)
+	schedule := database.Spec.Schedule
+	if schedule == nil {
+		cronJob := &batchv1.CronJob{}
+		cronJob.Name = name
+		cronJob.Namespace = database.Namespace
+		policy := metav1.DeletePropagationBackground
+		if err := r.Delete(ctx, cronJob, &client.DeleteOptions{PropagationPolicy: &policy}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete backup CronJob %s/%s: %w", database.Namespace, name, err)
+		}
+		return nil
+	}
+
+	serverName := databaseServerName(database)
+	var server storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{Name: serverName, Namespace: database.Namespace}, &server); err != nil {
+		return fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, serverName, err)
+	}
+	adminIdentity, requeue, err := r.resolveAdminIdentity(ctx, logger, &server)
+	if err != nil || requeue {
+		return err
+	}
+	var retentionDays int32
+	if schedule.RetentionDays != nil {
+		retentionDays = *schedule.RetentionDays
+	}
+	// An unknown profile is reported on the DatabaseServer's Ready condition.
+	var storageGB int32
+	if profile, err := resolveServerProfile(r.Config, &server); err == nil {
+		var requestedStorageGB *int32
+		if server.Spec.Storage != nil {
+			requestedStorageGB = server.Spec.Storage.SizeGB
+		}
+		storageGB = dbUtil.ResolveStorageGB(profile, requestedStorageGB)
+	}
+
+	spec := userProvisionJobSpec{
+		Owner:              database,
+		Mode:               dbUtil.ProvisionModeBackup,
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         serverName,
+		DatabaseHost:       database.Status.Host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		Backup: &userProvisionBackup{
+			ContainerURL:  schedule.ContainerURL,
+			Prefix:        databaseBackupPrefix(database),
+			RetentionDays: retentionDays,
+			Image:         r.migrationImage(),
+			StorageGB:     storageGB,
+		},
+	}
+	if err := validateUserProvisionJobSpec(spec, r.Config.UseAzFakes); err != nil {
+		return err
+	}
+	desired := buildDatabaseBackupCronJob(database, name, r.Config.UserProvisionImage, spec, r.Config.UseAzFakes)
+	if err := r.upsertDatabaseBackupCronJob(ctx, logger, database, desired); err != nil {
+		return err
+	}
+	return r.observeDatabaseBackups(ctx, logger, database)
+}
+
+func (r *DatabaseReconciler) upsertDatabaseBackupCronJob(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+	desired *batchv1.CronJob,
+) error {
+	current := &batchv1.CronJob{}
+	current.SetName(desired.GetName())
+	current.SetNamespace(desired.GetNamespace())
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, current, func() error {
+		current.Labels = desired.Labels
+		if current.Annotations[databaseBackupSpecHashAnnotation] != desired.Annotations[databaseBackupSpecHashAnnotation] {
+			current.Annotations = desired.Annotations
+			current.Spec = desired.Spec
+		}
+		return ctrl.SetControllerReference(database, current, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("reconcile backup CronJob %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("reconciled backup CronJob", "name", desired.Name, "operation", op)
+	}
+	return nil
+}
+
+// observeDatabaseBackups records the newest succeeded backup Job's report
+// and the newest failure in status.backup. The CronJob keeps one Job of
+// each, so only the Jobs that finished since the last reconcile are read.
+func (r *DatabaseReconciler) observeDatabaseBackups(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) error {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(database.Namespace), client.MatchingLabels{
+		databaseNameLabelKey: database.Name,
+		componentLabelKey:    databaseBackupComponentLabelValue,
+	}); err != nil {
+		return fmt.Errorf("list backup Jobs for %s/%s: %w", database.Namespace, database.Name, err)
+	}
+
+	status := database.Status.Backup
+	if status == nil {
+		status = &storagev1alpha1.DatabaseBackupStatus{}
+	}
+	changed := false
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		result, finishedAt, finished := provisionJobOutcome(job)
+		if !finished {
+			continue
+		}
+		if result == provisionJobResultFailed {
+			if status.LastFailureTime == nil || finishedAt.After(status.LastFailureTime.Time) {
+				status.LastFailureTime = &metav1.Time{Time: finishedAt}
+				changed = true
+				recordWarningEvent(r.Recorder, database, eventReasonBackupFailed, eventActionBackupDatabase,
+					"Backup Job %s failed: %s", job.Name, jobFailureMessage(job))
+			}
+			continue
+		}
+		if status.LastBackupTime != nil && !finishedAt.After(status.LastBackupTime.Time) {
+			continue
+		}
+		content, found, err := readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), job.Namespace, job.Name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		report, err := dbUtil.UnmarshalBackupReport(content)
+		if err != nil {
+			logger.Info("backup Job wrote an invalid report", "jobName", job.Name, "error", err.Error())
+			continue
+		}
+		status.LastBackupTime = &metav1.Time{Time: finishedAt}
+		status.LastBackupName = report.Name
+		status.LastBackupSizeBytes = report.SizeBytes
+		status.PrunedBackups = report.Pruned
+		changed = true
+	}
+	if changed {
+		database.Status.Backup = status
+	}
+	return nil
+}
+
+// buildDatabaseBackupCronJob wraps the backup Job in a CronJob. Runs do not
+// overlap, and one succeeded and one failed Job are kept for their reports.
+func buildDatabaseBackupCronJob(
+	database *storagev1alpha1.Database,
+	name string,
+	image string,
+	spec userProvisionJobSpec,
+	useAzFakes bool,
+) *batchv1.CronJob {
+	labels := userProvisionJobLabels(databaseBackupJobLabels(spec.ServerName, database.Name))
+	job := buildUserProvisionJob(database.Namespace, name, image, labels, spec, 1, 1, 0)
+	// The CronJob's history limits remove finished Jobs instead.
+	job.Spec.TTLSecondsAfterFinished = nil
+	if useAzFakes {
+		applyUserProvisionFakes(&job.Spec.Template.Spec)
+	}
+
+	timeZone := "Etc/UTC"
+	suspend := database.Spec.Schedule.Suspend
+	historyLimit := int32(1)
+	cronJobSpec := batchv1.CronJobSpec{
+		Schedule:                   database.Spec.Schedule.Cron,
+		TimeZone:                   &timeZone,
+		ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+		Suspend:                    &suspend,
+		SuccessfulJobsHistoryLimit: &historyLimit,
+		FailedJobsHistoryLimit:     &historyLimit,
+		JobTemplate: batchv1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec:       job.Spec,
+		},
+	}
+	// Unreachable error: the spec only holds plain Kubernetes types.
+	content, _ := json.Marshal(cronJobSpec)
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				databaseBackupSpecHashAnnotation: naming.StableSHA256Hex(string(content))[:16],
+			},
+		},
+		Spec: cronJobSpec,
+	}
+}
+
+// addBackupContainers turns a provisioning Pod into a backup Pod. The
+// credentials container connects with the admin identity and hands its
+// credentials to the dump container, which writes a pg_dump archive of the
+// database to the shared work directory; the provisioning container then
+// uploads it to spec.Backup.ContainerURL and prunes old backups.
+func addBackupContainers(podSpec *corev1.PodSpec, image string, spec userProvisionJobSpec) {
+	mount := corev1.VolumeMount{Name: backupWorkVolumeName, MountPath: backupWorkDir}
+	runAsOperatorImageUser(podSpec)
+	workVolume := &corev1.EmptyDirVolumeSource{}
+	if spec.Backup.StorageGB > 0 {
+		// A dump larger than the server's storage means something is wrong;
+		// evict the Pod rather than fill the node's disk.
+		sizeLimit := resource.MustParse(fmt.Sprintf("%dGi", spec.Backup.StorageGB))
+		workVolume.SizeLimit = &sizeLimit
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         backupWorkVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: workVolume},
+	})
+
+	credentialsEnv := append(userProvisionJobEnv(spec),
+		corev1.EnvVar{Name: dbUtil.BackupPhaseEnv, Value: dbUtil.BackupPhaseCredentials},
+		corev1.EnvVar{Name: dbUtil.BackupWorkDirEnv, Value: backupWorkDir},
+	)
+	podSpec.InitContainers = append(podSpec.InitContainers,
+		corev1.Container{
+			Name:         backupCredentialsContainerName,
+			Image:        image,
+			Args:         []string{"--provision-user"},
+			Env:          credentialsEnv,
+			VolumeMounts: []corev1.VolumeMount{mount},
+		},
+		corev1.Container{
+			Name:    backupDumpContainerName,
+			Image:   spec.Backup.Image,
+			Command: []string{"sh", "-c", backupDumpScript()},
+			Env: []corev1.EnvVar{
+				{Name: dbUtil.BackupWorkDirEnv, Value: backupWorkDir},
+				{Name: dbUtil.DBHostEnv, Value: spec.DatabaseHost},
+				{Name: dbUtil.DBNameEnv, Value: spec.DatabaseName},
+				{Name: "PGPORT", Value: "5432"},
+				{Name: "PGSSLMODE", Value: "require"},
+			},
+			VolumeMounts: []corev1.VolumeMount{mount},
+		},
+	)
+
+	upload := &podSpec.Containers[0]
+	upload.Env = append(upload.Env,
+		corev1.EnvVar{Name: dbUtil.BackupPhaseEnv, Value: dbUtil.BackupPhaseUpload},
+		corev1.EnvVar{Name: dbUtil.BackupWorkDirEnv, Value: backupWorkDir},
+		corev1.EnvVar{Name: dbUtil.BackupContainerURLEnv, Value: spec.Backup.ContainerURL},
+		corev1.EnvVar{Name: dbUtil.BackupPrefixEnv, Value: spec.Backup.Prefix},
+	)
+	if spec.Backup.RetentionDays > 0 {
+		upload.Env = append(upload.Env, corev1.EnvVar{
+			Name:  dbUtil.BackupRetentionDaysEnv,
+			Value: strconv.Itoa(int(spec.Backup.RetentionDays)),
+		})
+	}
+	upload.VolumeMounts = append(upload.VolumeMounts, mount)
+}
+
+// backupDumpScript writes a custom-format archive without owners or
+// privileges, so it restores into any database as its Owner role.
+func backupDumpScript() string {
+	return fmt.Sprintf(`set -eu
+dir="$%[1]s"
+export PGUSER="$(cat "$dir/%[2]s")"
+export PGPASSWORD="$(cat "$dir/%[3]s")"
+pg_dump --format=custom --no-owner --no-privileges --host="$%[4]s" --dbname="$%[5]s" --file="$dir/%[6]s"
+`,
+		dbUtil.BackupWorkDirEnv,
+		dbUtil.MigrationUserFile,
+		dbUtil.MigrationPasswordFile,
+		dbUtil.DBHostEnv,
+		dbUtil.DBNameEnv,
+		dbUtil.BackupDumpFile,
+	)
+}
+
+// databaseBackupPrefix keeps the backups of each Database apart in a shared
+// container.
+func databaseBackupPrefix(database *storagev1alpha1.Database) string {
+	return database.Namespace + "/" + database.Name + "/"
+}
+
+func databaseBackupCronJobName(database *storagev1alpha1.Database) string {
+	return naming.WithRequiredSuffix(database.Name, "-backup", cronJobNameMaxLength, "ldb")
+}
+
+func databaseBackupJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		componentLabelKey:          databaseBackupComponentLabelValue,
+	}
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+func testBackupDatabase() (*storagev1alpha1.Database, userProvisionJobSpec) {
+	database := testDropDatabase()
+	database.Spec.Schedule = &storagev1alpha1.DatabaseBackupScheduleSpec{
+		Cron:         "0 2 * * *",
+		ContainerURL: "https://acct.blob.core.windows.net/backups",
+	}
+	spec := userProvisionJobSpec{
+		Owner:              database,
+		ServiceAccountName: "pg-admin",
+		AdminIdentityName:  "pg-admin",
+		ServerName:         "shared",
+		DatabaseHost:       "shared.postgres.database.azure.com",
+		DatabaseName:       "app",
+		SchemaName:         "app",
+		Mode:               dbUtil.ProvisionModeBackup,
+		Backup: &userProvisionBackup{
+			ContainerURL:  database.Spec.Schedule.ContainerURL,
+			Prefix:        databaseBackupPrefix(database),
+			RetentionDays: 14,
+			Image:         "postgres:17",
+			StorageGB:     64,
+		},
+	}
+	return database, spec
+}
+
+func TestBuildDatabaseBackupCronJob(t *testing.T) {
+	database, spec := testBackupDatabase()
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+
+	cronJob := buildDatabaseBackupCronJob(database, databaseBackupCronJobName(database), "operator:latest", spec, false)
+	if cronJob.Spec.Schedule != "0 2 * * *" || *cronJob.Spec.TimeZone != "Etc/UTC" ||
+		cronJob.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Fatalf("unexpected CronJob spec %#v", cronJob.Spec)
+	}
+	if cronJob.Spec.JobTemplate.Spec.TTLSecondsAfterFinished != nil {
+		t.Fatalf("expected the history limits to remove finished Jobs, not a TTL")
+	}
+	if provisionJobPhase(cronJob.Spec.JobTemplate.Labels) != provisionJobPhaseBackup ||
+		cronJob.Spec.JobTemplate.Labels[userProvisionLabelKey] != labelValueTrue {
+		t.Fatalf("unexpected Job labels %v", cronJob.Spec.JobTemplate.Labels)
+	}
+
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 2 ||
+		podSpec.InitContainers[0].Name != backupCredentialsContainerName ||
+		podSpec.InitContainers[1].Name != backupDumpContainerName {
+		t.Fatalf("expected credentials and dump init containers, got %v", podSpec.InitContainers)
+	}
+	if podSpec.ServiceAccountName != "pg-admin" {
+		t.Fatalf("expected the admin ServiceAccount, got %q", podSpec.ServiceAccountName)
+	}
+	credentials := envToMap(podSpec.InitContainers[0].Env)
+	if credentials[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeBackup) ||
+		credentials[dbUtil.BackupPhaseEnv] != dbUtil.BackupPhaseCredentials || credentials[dbUtil.DBNameEnv] != "app" {
+		t.Fatalf("unexpected credentials env %v", credentials)
+	}
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].EmptyDir == nil ||
+		podSpec.Volumes[0].EmptyDir.SizeLimit == nil || podSpec.Volumes[0].EmptyDir.SizeLimit.String() != "64Gi" {
+		t.Fatalf("expected the work volume to be bounded by the server's storage, got %v", podSpec.Volumes)
+	}
+	dump := podSpec.InitContainers[1]
+	if dump.Image != "postgres:17" || !strings.Contains(dump.Command[2], "pg_dump --format=custom") {
+		t.Fatalf("unexpected dump container %#v", dump)
+	}
+	upload := envToMap(podSpec.Containers[0].Env)
+	if upload[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeBackup) ||
+		upload[dbUtil.BackupPhaseEnv] != dbUtil.BackupPhaseUpload ||
+		upload[dbUtil.BackupContainerURLEnv] != "https://acct.blob.core.windows.net/backups" ||
+		upload[dbUtil.BackupPrefixEnv] != testDbgNamespace+"/app-db/" ||
+		upload[dbUtil.BackupRetentionDaysEnv] != "14" {
+		t.Fatalf("unexpected upload env %v", upload)
+	}
+
+	again := buildDatabaseBackupCronJob(database, cronJob.Name, "operator:latest", spec, false)
+	if again.Annotations[databaseBackupSpecHashAnnotation] != cronJob.Annotations[databaseBackupSpecHashAnnotation] {
+		t.Fatalf("expected a stable spec hash")
+	}
+	database.Spec.Schedule.Suspend = true
+	suspended := buildDatabaseBackupCronJob(database, cronJob.Name, "operator:latest", spec, false)
+	if suspended.Annotations[databaseBackupSpecHashAnnotation] == cronJob.Annotations[databaseBackupSpecHashAnnotation] {
+		t.Fatalf("expected the spec hash to change with the spec")
+	}
+}
+
+func TestBuildDatabaseBackupCronJobWithFakes(t *testing.T) {
+	database, spec := testBackupDatabase()
+	cronJob := buildDatabaseBackupCronJob(database, databaseBackupCronJobName(database), "operator:latest", spec, true)
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 3 || podSpec.InitContainers[0].Name != "wait-for-postgres" {
+		t.Fatalf("expected to wait for postgres first, got %v", podSpec.InitContainers)
+	}
+	if envToMap(podSpec.InitContainers[1].Env)[dbUtil.DisableAADEnv] != "1" {
+		t.Fatalf("expected the credentials container to disable AAD")
+	}
+	if envToMap(podSpec.InitContainers[2].Env)["PGSSLMODE"] != "disable" {
+		t.Fatalf("expected the dump container to disable TLS")
+	}
+}
+
+func TestValidateUserProvisionJobSpecBackup(t *testing.T) {
+	_, spec := testBackupDatabase()
+	spec.Backup = nil
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected backup mode to be rejected without a destination")
+	}
+
+	_, spec = testBackupDatabase()
+	spec.DatabaseHost = ""
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected backup to require the database host")
+	}
+}
+
+func TestDatabaseBackupCronJobName(t *testing.T) {
+	database := testDropDatabase()
+	database.Name = strings.Repeat("a", 63)
+	if name := databaseBackupCronJobName(database); len(name) > cronJobNameMaxLength || !strings.HasSuffix(name, "-backup") {
+		t.Fatalf("unexpected CronJob name %q", name)
+	}
+}
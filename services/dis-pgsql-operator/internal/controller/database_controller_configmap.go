@@ -5,8 +5,10 @@ import (
 
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -15,24 +17,41 @@ import (
 // reconcileConnectionConfigMaps upserts one connection ConfigMap per service
 // principal and deletes any stale ConfigMaps owned by this Database. It is
 // called only once the Database is ready, so the published coordinates are
-// always complete. A returned error blocks Ready and triggers a requeue.
+// always complete. A returned error blocks Ready and triggers a requeue. When
+// the ConfigMap format is not selected, every owned ConfigMap is stale.
+// ConfigMaps for identities in other namespaces are published there and
+// cleaned up by label instead of owner reference.
 func (r *DatabaseReconciler) reconcileConnectionConfigMaps(
 	ctx context.Context,
 	database *storagev1alpha1.Database,
 	coords []connection.Coordinates,
 ) error {
+	if !connection.PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatConfigMap) {
+		coords = nil
+	}
 	desiredNames := make(map[string]struct{}, len(coords))
+	desiredForeign := map[types.NamespacedName]struct{}{}
 	for i := range coords {
 		desired, err := connection.BuildConnectionConfigMap(database, coords[i])
 		if err != nil {
 			return err
 		}
+		if connection.IsForeign(database, coords[i]) {
+			desiredForeign[client.ObjectKeyFromObject(desired)] = struct{}{}
+			if err := r.upsertForeignConnectionConfigMap(ctx, database, desired); err != nil {
+				return err
+			}
+			continue
+		}
 		desiredNames[desired.Name] = struct{}{}
 		if err := r.upsertConnectionConfigMap(ctx, database, desired); err != nil {
 			return err
 		}
 	}
 
+	if err := r.cleanupStaleForeignConnectionObjects(ctx, database, &corev1.ConfigMapList{}, desiredForeign); err != nil {
+		return err
+	}
 	return r.cleanupStaleConnectionConfigMaps(ctx, database, desiredNames)
 }
 
@@ -89,3 +108,17 @@ func (r *DatabaseReconciler) cleanupStaleConnectionConfigMaps(
 
 	return nil
 }
+
+// poolerPort returns the PgBouncer port to publish for a server, or zero when
+// the server does not run PgBouncer.
+func (r *DatabaseReconciler) poolerPort(server *storagev1alpha1.DatabaseServer) int32 {
+	profile, err := resolveServerProfile(r.Config, server)
+	if err != nil {
+		return 0
+	}
+	pooling, err := dbUtil.ResolveConnectionPooling(profile, server.Spec.ConnectionPooling)
+	if err != nil || !pooling.Enabled {
+		return 0
+	}
+	return dbUtil.PgBouncerPort
+}
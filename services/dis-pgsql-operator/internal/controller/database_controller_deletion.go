@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+)
+
+const (
+	// databaseFinalizer holds a Database with deletionPolicy Delete until its
+	// PostgreSQL database and managed roles have been dropped.
+	databaseFinalizer = "storage.dis.altinn.cloud/database-finalizer"
+
+	// databaseConditionPendingDeletion is True while a deleted Database waits
+	// for its grace period to expire and while the drop Job runs.
+	databaseConditionPendingDeletion = "PendingDeletion"
+
+	databaseReasonGracePeriod = "GracePeriod"
+	databaseReasonDropping    = "Dropping"
+
+	databaseDropLabelKey = "dis.altinn.cloud/database-drop"
+
+	// defaultDatabaseDeletionGracePeriod applies when deletionPolicy is Delete
+	// and spec.deletionGracePeriod is not set.
+	defaultDatabaseDeletionGracePeriod = storagev1alpha1.DefaultDatabaseDeletionGracePeriod
+)
+
+// syncDatabaseFinalizer adds the finalizer to Databases with deletionPolicy
+// Delete and removes it from all others, so Retain keeps the plain garbage
+// collection behaviour. It returns whether the finalizers changed.
+//
+// Databases that reference identities in other namespaces also get
+// databaseConnectionFinalizer. It is kept until deletion, even when those
+// principals are removed, since releasing it is cheap.
+func syncDatabaseFinalizer(database *storagev1alpha1.Database) bool {
+	changed := false
+	if hasForeignIdentityRefs(database) {
+		changed = controllerutil.AddFinalizer(database, databaseConnectionFinalizer)
+	}
+	if database.Spec.DeletionPolicy == storagev1alpha1.DatabaseDeletionPolicyDelete {
+		return controllerutil.AddFinalizer(database, databaseFinalizer) || changed
+	}
+	return controllerutil.RemoveFinalizer(database, databaseFinalizer) || changed
+}
+
+// databaseDeletionGracePeriod resolves spec.deletionGracePeriod, falling back
+// to defaultDatabaseDeletionGracePeriod.
+func databaseDeletionGracePeriod(database *storagev1alpha1.Database) time.Duration {
+	if database.Spec.DeletionGracePeriod == nil {
+		return defaultDatabaseDeletionGracePeriod
+	}
+	return database.Spec.DeletionGracePeriod.Duration
+}
+
+// reconcileDatabaseDeletion implements the approved-deletion flow for a
+// Database that is being deleted. The finalizer is released straight away
+// when the policy is (or was set back to) Retain, or when no database was
+// ever created. Otherwise the Database reports PendingDeletion until the grace
+// period has passed, after which a provisioning Job drops the database and its
+// managed roles before the finalizer is removed.
+func (r *DatabaseReconciler) reconcileDatabaseDeletion(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (ctrl.Result, error) {
+	// The connection details in other namespaces go first; releasing the
+	// finalizer triggers another reconcile for the drop.
+	if controllerutil.ContainsFinalizer(database, databaseConnectionFinalizer) {
+		return r.releaseDatabaseConnectionFinalizer(ctx, database)
+	}
+	if !controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if database.Spec.DeletionPolicy != storagev1alpha1.DatabaseDeletionPolicyDelete ||
+		database.Status.DatabaseName == "" {
+		return ctrl.Result{}, r.removeDatabaseFinalizer(ctx, database)
+	}
+
+	original := database.DeepCopy()
+	deadline := database.DeletionTimestamp.Add(databaseDeletionGracePeriod(database))
+	if remaining := time.Until(deadline); remaining > 0 {
+		setDatabaseCondition(
+			database,
+			databaseConditionPendingDeletion,
+			metav1.ConditionTrue,
+			databaseReasonGracePeriod,
+			fmt.Sprintf(
+				"Database %q will be dropped after %s; set spec.deletionPolicy to Retain to keep it",
+				database.Status.DatabaseName,
+				deadline.UTC().Format(time.RFC3339),
+			),
+		)
+		if err := r.updateDatabaseDeletionStatus(ctx, original, database); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	allDropped := true
+	message := ""
+	for _, serverName := range databaseServersToDrop(database) {
+		dropped, dropMessage, err := r.ensureDatabaseDropped(ctx, logger, database, serverName)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !dropped {
+			allDropped = false
+			if message == "" {
+				message = dropMessage
+			}
+		}
+	}
+	if allDropped {
+		logger.Info("dropped database for deleted Database", "databaseName", database.Status.DatabaseName)
+		return ctrl.Result{}, r.removeDatabaseFinalizer(ctx, database)
+	}
+
+	setDatabaseCondition(
+		database,
+		databaseConditionPendingDeletion,
+		metav1.ConditionTrue,
+		databaseReasonDropping,
+		message,
+	)
+	if err := r.updateDatabaseDeletionStatus(ctx, original, database); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: databaseRequeueDelay}, nil
+}
+
+// databaseServersToDrop returns the servers holding a copy of the database:
+// its server, plus the target of an unfinished move or the source of a
+// completed move that was not cleaned up yet.
+func databaseServersToDrop(database *storagev1alpha1.Database) []string {
+	servers := []string{databaseServerName(database)}
+	migration := database.Status.Migration
+	if migration == nil {
+		return servers
+	}
+	switch {
+	case migration.Phase != storagev1alpha1.DatabaseMigrationPhaseCompleted:
+		if migration.TargetServer != servers[0] {
+			servers = append(servers, migration.TargetServer)
+		}
+	case migration.SourceCleanup == storagev1alpha1.DatabaseMigrationSourceCleanupPending:
+		if migration.SourceServer != servers[0] {
+			servers = append(servers, migration.SourceServer)
+		}
+	}
+	return servers
+}
+
+// ensureDatabaseDropped drives the drop of the PostgreSQL database on
+// serverName. The FlexibleServersDatabase is removed first (it is
+// detach-on-delete, so Azure is untouched) to stop ASO from recreating the
+// database after the Job has dropped it. It returns dropped=true once the drop
+// Job has completed, or when the server is gone and the database went with it.
+func (r *DatabaseReconciler) ensureDatabaseDropped(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+	serverName string,
+) (bool, string, error) {
+	var db storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      serverName,
+		Namespace: database.Namespace,
+	}, &db); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("DatabaseServer for deleted Database is gone; nothing to drop", "server", serverName)
+			return true, "", nil
+		}
+		return false, "", fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, serverName, err)
+	}
+	if !db.DeletionTimestamp.IsZero() {
+		logger.Info("DatabaseServer for deleted Database is being deleted; nothing to drop", "server", serverName)
+		return true, "", nil
+	}
+
+	detached, err := r.detachFlexibleServersDatabase(ctx, database, serverName)
+	if err != nil {
+		return false, "", err
+	}
+	if !detached {
+		return false, "Waiting for the FlexibleServersDatabase to be detached", nil
+	}
+
+	adminIdentity, requeue, err := r.resolveAdminIdentity(ctx, logger, &db)
+	if err != nil {
+		return false, "", err
+	}
+	if requeue {
+		return false, "Waiting for DatabaseServer admin identity", nil
+	}
+
+	// status.host belongs to the Database's server; other servers of a move
+	// use their own host.
+	host := db.Status.Host
+	if serverName == databaseServerName(database) && database.Status.Host != "" {
+		host = database.Status.Host
+	}
+	if host == "" {
+		return false, "Waiting for the DatabaseServer host", nil
+	}
+
+	jobName := databaseDropJobName(database, serverName, host, adminIdentity)
+	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
+		Owner:              database,
+		JobName:            jobName,
+		Labels:             databaseDropJobLabels(serverName, database.Name),
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         serverName,
+		DatabaseHost:       host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		Mode:               dbUtil.ProvisionModeDropDatabase,
+	}); err != nil {
+		return false, "", err
+	}
+
+	complete, err := r.databaseAccessJobComplete(ctx, database, jobName)
+	if err != nil {
+		return false, "", err
+	}
+	if !complete {
+		return false, fmt.Sprintf("Dropping database %q", database.Status.DatabaseName), nil
+	}
+	return true, "", nil
+}
+
+// detachFlexibleServersDatabase deletes the Database's FlexibleServersDatabase
+// and reports whether it is gone.
+func (r *DatabaseReconciler) detachFlexibleServersDatabase(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	serverName string,
+) (bool, error) {
+	resourceName := databaseASOResourceName(serverName, database.Status.DatabaseName)
+	var existing dbforpostgresqlv1.FlexibleServersDatabase
+	if err := r.Get(ctx, types.NamespacedName{Name: resourceName, Namespace: database.Namespace}, &existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("get FlexibleServersDatabase %s/%s: %w", database.Namespace, resourceName, err)
+	}
+	if !metav1.IsControlledBy(&existing, database) {
+		return true, nil
+	}
+	if existing.DeletionTimestamp.IsZero() {
+		if err := r.Delete(ctx, &existing); err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("delete FlexibleServersDatabase %s/%s: %w", database.Namespace, resourceName, err)
+		}
+	}
+	return false, nil
+}
+
+func (r *DatabaseReconciler) removeDatabaseFinalizer(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+) error {
+	controllerutil.RemoveFinalizer(database, databaseFinalizer)
+	if err := r.Update(ctx, database); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("remove finalizer from Database %s/%s: %w", database.Namespace, database.Name, err)
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) updateDatabaseDeletionStatus(
+	ctx context.Context,
+	original *storagev1alpha1.Database,
+	database *storagev1alpha1.Database,
+) error {
+	if apiequality.Semantic.DeepEqual(original.Status, database.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, database); err != nil {
+		return fmt.Errorf("update Database %s/%s deletion status: %w", database.Namespace, database.Name, err)
+	}
+	return nil
+}
+
+func databaseDropJobName(
+	database *storagev1alpha1.Database,
+	serverName string,
+	host string,
+	adminIdentity resolvedAdminIdentity,
+) string {
+	payload := strings.Join([]string{
+		"server=" + serverName,
+		"database=" + database.Status.DatabaseName,
+		"host=" + host,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"drop=true",
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	base := fmt.Sprintf("%s-drop", database.Name)
+	return naming.WithRequiredSuffix(base, "-"+hash, 63, "ldb")
+}
+
+func databaseDropJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		databaseDropLabelKey:       labelValueTrue,
+	}
+}
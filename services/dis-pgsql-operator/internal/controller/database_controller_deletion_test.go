@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func testDropDatabase() *storagev1alpha1.Database {
+	database := &storagev1alpha1.Database{}
+	database.Name = "app-db"
+	database.Namespace = testDbgNamespace
+	database.Spec.DeletionPolicy = storagev1alpha1.DatabaseDeletionPolicyDelete
+	database.Status.DatabaseName = "app"
+	return database
+}
+
+func TestSyncDatabaseFinalizer(t *testing.T) {
+	t.Run("adds finalizer for Delete", func(t *testing.T) {
+		database := testDropDatabase()
+		if !syncDatabaseFinalizer(database) {
+			t.Fatalf("expected finalizer to be added")
+		}
+		if !controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+			t.Fatalf("expected finalizer %q, got %v", databaseFinalizer, database.Finalizers)
+		}
+		if syncDatabaseFinalizer(database) {
+			t.Fatalf("expected no change on second sync")
+		}
+	})
+
+	t.Run("removes finalizer when rescued with Retain", func(t *testing.T) {
+		database := testDropDatabase()
+		database.Finalizers = []string{databaseFinalizer}
+		database.Spec.DeletionPolicy = storagev1alpha1.DatabaseDeletionPolicyRetain
+		if !syncDatabaseFinalizer(database) {
+			t.Fatalf("expected finalizer to be removed")
+		}
+		if controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+			t.Fatalf("expected no finalizer, got %v", database.Finalizers)
+		}
+	})
+
+	t.Run("leaves default policy without finalizer", func(t *testing.T) {
+		database := testDropDatabase()
+		database.Spec.DeletionPolicy = ""
+		if syncDatabaseFinalizer(database) {
+			t.Fatalf("expected no change")
+		}
+	})
+
+	t.Run("adds connection finalizer for identities in other namespaces", func(t *testing.T) {
+		database := testDropDatabase()
+		database.Spec.DeletionPolicy = storagev1alpha1.DatabaseDeletionPolicyRetain
+		database.Spec.Access.Principals = []storagev1alpha1.DatabaseAccessPrincipalSpec{{
+			Role:        storagev1alpha1.DatabaseAccessRoleReader,
+			IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "reporting", Namespace: "reporting"},
+		}}
+		if !syncDatabaseFinalizer(database) {
+			t.Fatalf("expected connection finalizer to be added")
+		}
+		if !controllerutil.ContainsFinalizer(database, databaseConnectionFinalizer) ||
+			controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+			t.Fatalf("expected only %q, got %v", databaseConnectionFinalizer, database.Finalizers)
+		}
+	})
+}
+
+func TestDatabaseDeletionGracePeriod(t *testing.T) {
+	database := testDropDatabase()
+	if got := databaseDeletionGracePeriod(database); got != defaultDatabaseDeletionGracePeriod {
+		t.Fatalf("expected default grace period, got %s", got)
+	}
+
+	database.Spec.DeletionGracePeriod = &metav1.Duration{Duration: 0}
+	if got := databaseDeletionGracePeriod(database); got != 0 {
+		t.Fatalf("expected explicit zero grace period, got %s", got)
+	}
+
+	database.Spec.DeletionGracePeriod = &metav1.Duration{Duration: 2 * time.Hour}
+	if got := databaseDeletionGracePeriod(database); got != 2*time.Hour {
+		t.Fatalf("expected 2h grace period, got %s", got)
+	}
+}
+
+func TestDatabaseDropJobNameDistinctFromAccessJob(t *testing.T) {
+	database := testDropDatabase()
+	admin := testDebugAdminIdentity()
+
+	name := databaseDropJobName(database, testDbgServerName, testDebugJobHost, admin)
+	if again := databaseDropJobName(database, testDbgServerName, testDebugJobHost, admin); again != name {
+		t.Fatalf("expected deterministic job name, got %q vs %q", name, again)
+	}
+	if len(name) > 63 || !strings.Contains(name, "-drop-") {
+		t.Fatalf("expected bounded drop job name, got %q", name)
+	}
+
+	accessName := databaseAccessProvisionJobName(database, testDbgServerName, admin, testDebugPrincipals(), nil)
+	if accessName == name {
+		t.Fatalf("expected drop and access job names to differ, got %q", name)
+	}
+	if _, ok := databaseAccessJobLabels(testDbgServerName, database.Name)[databaseDropLabelKey]; ok {
+		t.Fatalf("access job labels must not select drop jobs")
+	}
+}
+
+func TestUserProvisionJobEnvDropDatabase(t *testing.T) {
+	spec := userProvisionJobSpec{
+		ServiceAccountName: "admin-sa",
+		AdminIdentityName:  testDebugAdminMIName,
+		ServerName:         testDbgServerName,
+		DatabaseHost:       testDebugJobHost,
+		DatabaseName:       "app",
+		SchemaName:         "app",
+		Mode:               dbUtil.ProvisionModeDropDatabase,
+	}
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("expected drop spec without principals to be valid, got %v", err)
+	}
+
+	env := map[string]string{}
+	for _, e := range userProvisionJobEnv(spec) {
+		env[e.Name] = e.Value
+	}
+	if env[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeDropDatabase) {
+		t.Fatalf("expected %s=%s, got %q", dbUtil.ProvisionModeEnv, dbUtil.ProvisionModeDropDatabase, env[dbUtil.ProvisionModeEnv])
+	}
+	if env[dbUtil.DBNameEnv] != "app" {
+		t.Fatalf("expected %s=app, got %q", dbUtil.DBNameEnv, env[dbUtil.DBNameEnv])
+	}
+
+	spec.DatabaseName = ""
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected drop spec without database name to be rejected")
+	}
+}
@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+)
+
+const (
+	databaseConditionExtensionsReady = "ExtensionsReady"
+
+	databaseReasonExtensionsNotReady = "ExtensionsNotReady"
+
+	databaseExtensionReasonInstalled  = "Installed"
+	databaseExtensionReasonPending    = "Pending"
+	databaseExtensionReasonNotEnabled = "NotEnabled"
+	databaseExtensionReasonDropping   = "Dropping"
+	databaseExtensionReasonFailed     = "Failed"
+)
+
+// planDatabaseExtensions returns the extension payload for the access Job and
+// the status of the extensions left out of it. Extensions in spec.extensions
+// that the server has enabled are installed; the others are reported as
+// NotEnabled. Extensions removed from spec.extensions that status still shows
+// as installed are dropped.
+func planDatabaseExtensions(
+	database *storagev1alpha1.Database,
+	server *storagev1alpha1.DatabaseServer,
+) ([]dbUtil.DatabaseExtension, []storagev1alpha1.DatabaseExtensionStatus) {
+	enabled := make(map[storagev1alpha1.DatabaseServerExtension]struct{}, len(server.Spec.EnableExtensions))
+	for _, extension := range server.Spec.EnableExtensions {
+		enabled[extension] = struct{}{}
+	}
+
+	previous := make(map[storagev1alpha1.DatabaseServerExtension]storagev1alpha1.DatabaseExtensionStatus, len(database.Status.Extensions))
+	for _, status := range database.Status.Extensions {
+		previous[status.Name] = status
+	}
+
+	var planned []dbUtil.DatabaseExtension
+	var notEnabled []storagev1alpha1.DatabaseExtensionStatus
+	desired := make(map[storagev1alpha1.DatabaseServerExtension]struct{}, len(database.Spec.Extensions))
+	for _, extension := range database.Spec.Extensions {
+		desired[extension.Name] = struct{}{}
+		if _, ok := enabled[extension.Name]; !ok {
+			// Keep what is installed, so the extension is still dropped if it
+			// is later removed from spec.extensions.
+			notEnabled = append(notEnabled, storagev1alpha1.DatabaseExtensionStatus{
+				Name:    extension.Name,
+				Version: previous[extension.Name].Version,
+				Schema:  previous[extension.Name].Schema,
+				Reason:  databaseExtensionReasonNotEnabled,
+				Message: fmt.Sprintf("extension is not in spec.enableExtensions of DatabaseServer %q", server.Name),
+			})
+			continue
+		}
+		planned = append(planned, dbUtil.DatabaseExtension{
+			Name:    string(extension.Name),
+			Version: extension.Version,
+			State:   dbUtil.DatabaseExtensionPresent,
+		})
+	}
+	for _, extension := range database.Status.Extensions {
+		if _, ok := desired[extension.Name]; ok || extension.Version == "" {
+			continue
+		}
+		planned = append(planned, dbUtil.DatabaseExtension{
+			Name:  string(extension.Name),
+			State: dbUtil.DatabaseExtensionAbsent,
+		})
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].Name < planned[j].Name
+	})
+	return planned, notEnabled
+}
+
+// buildDatabaseExtensionStatuses merges the planned extensions with the
+// access Job's report. Without a report, while the Job runs, extensions keep
+// their previous status unless the requested version changed, so a rerun of
+// the Job does not flap installed extensions. Dropped extensions leave the
+// status once the report confirms they are gone.
+func buildDatabaseExtensionStatuses(
+	previous []storagev1alpha1.DatabaseExtensionStatus,
+	planned []dbUtil.DatabaseExtension,
+	notEnabled []storagev1alpha1.DatabaseExtensionStatus,
+	report *dbUtil.DatabaseExtensionsReport,
+) []storagev1alpha1.DatabaseExtensionStatus {
+	previousByName := make(map[string]storagev1alpha1.DatabaseExtensionStatus, len(previous))
+	for _, status := range previous {
+		previousByName[string(status.Name)] = status
+	}
+	var results map[string]dbUtil.DatabaseExtensionResult
+	if report != nil {
+		results = make(map[string]dbUtil.DatabaseExtensionResult, len(report.Extensions))
+		for _, result := range report.Extensions {
+			results[result.Name] = result
+		}
+	}
+
+	statuses := append([]storagev1alpha1.DatabaseExtensionStatus(nil), notEnabled...)
+	for _, extension := range planned {
+		status := storagev1alpha1.DatabaseExtensionStatus{
+			Name: storagev1alpha1.DatabaseServerExtension(extension.Name),
+		}
+		result, reported := results[extension.Name]
+		switch {
+		case !reported && extension.State == dbUtil.DatabaseExtensionAbsent:
+			if prev, ok := previousByName[extension.Name]; ok {
+				status.Version = prev.Version
+				status.Schema = prev.Schema
+			}
+			status.Reason = databaseExtensionReasonDropping
+			status.Message = "Waiting for the access provisioning Job to drop the extension"
+		case !reported:
+			prev, ok := previousByName[extension.Name]
+			settled := prev.Reason == databaseExtensionReasonInstalled || prev.Reason == databaseExtensionReasonFailed
+			if ok && settled && (extension.Version == "" || extension.Version == prev.Version) {
+				status = prev
+				break
+			}
+			status.Reason = databaseExtensionReasonPending
+			status.Message = "Waiting for the access provisioning Job to install the extension"
+		case extension.State == dbUtil.DatabaseExtensionAbsent:
+			if result.Version == "" {
+				continue
+			}
+			status.Version = result.Version
+			status.Schema = result.Schema
+			status.Reason = databaseExtensionReasonFailed
+			status.Message = extensionFailureMessage("drop", result.Error)
+		default:
+			status.Version = result.Version
+			status.Schema = result.Schema
+			if result.Error != "" || result.Version == "" {
+				status.Reason = databaseExtensionReasonFailed
+				status.Message = extensionFailureMessage("install", result.Error)
+				break
+			}
+			status.Ready = true
+			status.Reason = databaseExtensionReasonInstalled
+			status.Message = "Extension is installed"
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func extensionFailureMessage(action, err string) string {
+	if err == "" {
+		return fmt.Sprintf("failed to %s the extension", action)
+	}
+	return fmt.Sprintf("failed to %s the extension: %s", action, err)
+}
+
+// setDatabaseExtensionsCondition summarizes status.extensions in the
+// ExtensionsReady condition, which is only present while the Database manages
+// extensions.
+func setDatabaseExtensionsCondition(database *storagev1alpha1.Database) {
+	if len(database.Status.Extensions) == 0 {
+		meta.RemoveStatusCondition(&database.Status.Conditions, databaseConditionExtensionsReady)
+		return
+	}
+	for _, status := range database.Status.Extensions {
+		if status.Ready {
+			continue
+		}
+		setDatabaseCondition(
+			database,
+			databaseConditionExtensionsReady,
+			metav1.ConditionFalse,
+			status.Reason,
+			fmt.Sprintf("Extension %q: %s", status.Name, status.Message),
+		)
+		return
+	}
+	setDatabaseCondition(
+		database,
+		databaseConditionExtensionsReady,
+		metav1.ConditionTrue,
+		databaseReasonReady,
+		"Database extensions are ready",
+	)
+}
+
+// databaseExtensionsReady reports whether every managed extension is ready.
+// Databases without extensions have no ExtensionsReady condition.
+func databaseExtensionsReady(database *storagev1alpha1.Database) bool {
+	condition := meta.FindStatusCondition(database.Status.Conditions, databaseConditionExtensionsReady)
+	return condition == nil || condition.Status == metav1.ConditionTrue
+}
+
+// databaseExtensionsJobResult returns the extension report of a completed
+// access Job. An unreadable report is logged and treated as not found, so
+// the extensions keep their previous status.
+func (r *DatabaseReconciler) databaseExtensionsJobResult(
+	ctx context.Context,
+	logger logr.Logger,
+	namespace string,
+	jobName string,
+) (*dbUtil.DatabaseExtensionsReport, error) {
+	message, found, err := readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), namespace, jobName)
+	if err != nil || !found {
+		return nil, err
+	}
+	report, err := dbUtil.UnmarshalDatabaseExtensionsReport(message)
+	if err != nil {
+		logger.Error(err, "ignoring invalid database extensions report", "jobName", jobName)
+		return nil, nil
+	}
+	return &report, nil
+}
+
+// apiReader returns the uncached reader, so reading Job Pods does not
+// start a cluster-wide Pod informer.
+func (r *DatabaseReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
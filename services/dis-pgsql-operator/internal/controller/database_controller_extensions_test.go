@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+)
+
+func TestPlanDatabaseExtensions(t *testing.T) {
+	server := &storagev1alpha1.DatabaseServer{}
+	server.Name = "shared"
+	server.Spec.EnableExtensions = []storagev1alpha1.DatabaseServerExtension{
+		storagev1alpha1.DatabaseServerExtensionHstore,
+		storagev1alpha1.DatabaseServerExtensionUUIDOSSP,
+	}
+
+	database := &storagev1alpha1.Database{}
+	database.Spec.Extensions = []storagev1alpha1.DatabaseExtensionSpec{
+		{Name: storagev1alpha1.DatabaseServerExtensionUUIDOSSP},
+		{Name: storagev1alpha1.DatabaseServerExtensionHstore, Version: "1.8"},
+		{Name: storagev1alpha1.DatabaseServerExtensionPgCron},
+	}
+	database.Status.Extensions = []storagev1alpha1.DatabaseExtensionStatus{
+		{Name: storagev1alpha1.DatabaseServerExtensionPgAudit, Version: "1.7", Reason: databaseExtensionReasonInstalled},
+		{Name: storagev1alpha1.DatabaseServerExtensionPgStatStatements, Reason: databaseExtensionReasonNotEnabled},
+	}
+
+	planned, notEnabled := planDatabaseExtensions(database, server)
+	want := []dbUtil.DatabaseExtension{
+		{Name: "hstore", Version: "1.8", State: dbUtil.DatabaseExtensionPresent},
+		{Name: "pgaudit", State: dbUtil.DatabaseExtensionAbsent},
+		{Name: "uuid-ossp", State: dbUtil.DatabaseExtensionPresent},
+	}
+	if len(planned) != len(want) {
+		t.Fatalf("expected %d planned extensions, got %#v", len(want), planned)
+	}
+	for i := range want {
+		if planned[i] != want[i] {
+			t.Fatalf("planned %d: expected %#v, got %#v", i, want[i], planned[i])
+		}
+	}
+	if len(notEnabled) != 1 || notEnabled[0].Name != storagev1alpha1.DatabaseServerExtensionPgCron ||
+		notEnabled[0].Reason != databaseExtensionReasonNotEnabled || notEnabled[0].Ready {
+		t.Fatalf("expected pg_cron to be reported as not enabled, got %#v", notEnabled)
+	}
+}
+
+func TestBuildDatabaseExtensionStatuses(t *testing.T) {
+	planned := []dbUtil.DatabaseExtension{
+		{Name: "hstore", State: dbUtil.DatabaseExtensionPresent},
+		{Name: "pgaudit", State: dbUtil.DatabaseExtensionAbsent},
+		{Name: "uuid-ossp", Version: "1.1", State: dbUtil.DatabaseExtensionPresent},
+	}
+	previous := []storagev1alpha1.DatabaseExtensionStatus{
+		{Name: storagev1alpha1.DatabaseServerExtensionHstore, Ready: true, Version: "1.8", Schema: "app", Reason: databaseExtensionReasonInstalled},
+		{Name: storagev1alpha1.DatabaseServerExtensionPgAudit, Ready: true, Version: "1.7", Schema: "app", Reason: databaseExtensionReasonInstalled},
+	}
+
+	t.Run("keeps installed extensions while the Job runs", func(t *testing.T) {
+		statuses := buildDatabaseExtensionStatuses(previous, planned, nil, nil)
+		if len(statuses) != 3 {
+			t.Fatalf("expected 3 statuses, got %#v", statuses)
+		}
+		if statuses[0] != previous[0] {
+			t.Fatalf("expected hstore to keep its status, got %#v", statuses[0])
+		}
+		if statuses[1].Reason != databaseExtensionReasonDropping || statuses[1].Version != "1.7" || statuses[1].Ready {
+			t.Fatalf("expected pgaudit to be dropping, got %#v", statuses[1])
+		}
+		if statuses[2].Reason != databaseExtensionReasonPending || statuses[2].Ready {
+			t.Fatalf("expected uuid-ossp to be pending, got %#v", statuses[2])
+		}
+	})
+
+	t.Run("applies the Job report", func(t *testing.T) {
+		statuses := buildDatabaseExtensionStatuses(previous, planned, nil, &dbUtil.DatabaseExtensionsReport{
+			Extensions: []dbUtil.DatabaseExtensionResult{
+				{Name: "hstore", Version: "1.8", Schema: "app"},
+				{Name: "pgaudit"},
+				{Name: "uuid-ossp", Error: "extension \"uuid-ossp\" has no installation script nor update path for version \"1.1\""},
+			},
+		})
+		if len(statuses) != 2 {
+			t.Fatalf("expected the dropped extension to leave status, got %#v", statuses)
+		}
+		if !statuses[0].Ready || statuses[0].Reason != databaseExtensionReasonInstalled {
+			t.Fatalf("expected hstore installed, got %#v", statuses[0])
+		}
+		if statuses[1].Ready || statuses[1].Reason != databaseExtensionReasonFailed {
+			t.Fatalf("expected uuid-ossp failed, got %#v", statuses[1])
+		}
+	})
+
+	t.Run("reports a failed drop", func(t *testing.T) {
+		statuses := buildDatabaseExtensionStatuses(previous, planned[1:2], nil, &dbUtil.DatabaseExtensionsReport{
+			Extensions: []dbUtil.DatabaseExtensionResult{
+				{Name: "pgaudit", Version: "1.7", Schema: "app", Error: "cannot drop extension pgaudit because other objects depend on it"},
+			},
+		})
+		if len(statuses) != 1 || statuses[0].Reason != databaseExtensionReasonFailed || statuses[0].Version != "1.7" {
+			t.Fatalf("expected pgaudit drop failure, got %#v", statuses)
+		}
+	})
+}
+
+func TestSetDatabaseExtensionsCondition(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	database.Status.Extensions = []storagev1alpha1.DatabaseExtensionStatus{
+		{Name: storagev1alpha1.DatabaseServerExtensionHstore, Ready: true, Reason: databaseExtensionReasonInstalled},
+		{Name: storagev1alpha1.DatabaseServerExtensionPgCron, Reason: databaseExtensionReasonNotEnabled, Message: "not enabled"},
+	}
+	setDatabaseExtensionsCondition(database)
+	condition := meta.FindStatusCondition(database.Status.Conditions, databaseConditionExtensionsReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != databaseExtensionReasonNotEnabled {
+		t.Fatalf("expected ExtensionsReady=False with NotEnabled, got %#v", condition)
+	}
+	if databaseExtensionsReady(database) {
+		t.Fatalf("expected extensions not to be ready")
+	}
+
+	database.Status.Extensions = nil
+	setDatabaseExtensionsCondition(database)
+	if meta.FindStatusCondition(database.Status.Conditions, databaseConditionExtensionsReady) != nil {
+		t.Fatalf("expected ExtensionsReady to be removed without extensions")
+	}
+	if !databaseExtensionsReady(database) {
+		t.Fatalf("expected a Database without extensions to be ready")
+	}
+}
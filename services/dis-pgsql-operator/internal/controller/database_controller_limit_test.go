@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDatabaseWithinServerLimit(t *testing.T) {
+	created := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	newDatabase := func(name, server string, age time.Duration) storagev1alpha1.Database {
+		return storagev1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created.Add(age)),
+			},
+			Spec: storagev1alpha1.DatabaseSpec{
+				Server: storagev1alpha1.DatabaseServerReference{Name: server},
+			},
+		}
+	}
+	databases := []storagev1alpha1.Database{
+		newDatabase("third", "shared", 2*time.Hour),
+		newDatabase("first", "shared", 0),
+		newDatabase("other", "dedicated", 0),
+		newDatabase("second-b", "shared", time.Hour),
+		newDatabase("second-a", "shared", time.Hour),
+	}
+
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{name: "first", want: true},
+		{name: "second-a", want: true},
+		{name: "second-b", want: false},
+		{name: "third", want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			database := newDatabase(tc.name, "shared", 0)
+			if got := databaseWithinServerLimit(&database, databases, 2); got != tc.want {
+				t.Fatalf("databaseWithinServerLimit(%q) = %t, want %t", tc.name, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("databases on other servers do not count", func(t *testing.T) {
+		database := newDatabase("other", "dedicated", 0)
+		if !databaseWithinServerLimit(&database, databases, 1) {
+			t.Fatalf("expected the only Database on its server to be within the limit")
+		}
+	})
+}
+
+func TestDatabaseServerConnectionLimits(t *testing.T) {
+	created := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	newDatabase := func(name, server string, age time.Duration, limits map[string]int32) storagev1alpha1.Database {
+		database := storagev1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created.Add(age)),
+			},
+			Spec: storagev1alpha1.DatabaseSpec{
+				Server: storagev1alpha1.DatabaseServerReference{Name: server},
+			},
+		}
+		database.Spec.Access.Principals = append(database.Spec.Access.Principals, storagev1alpha1.DatabaseAccessPrincipalSpec{})
+		for principalID, limit := range limits {
+			database.Spec.Access.Principals = append(database.Spec.Access.Principals,
+				storagev1alpha1.DatabaseAccessPrincipalSpec{
+					ServicePrincipal: &storagev1alpha1.DatabaseServicePrincipalSpec{Name: principalID, PrincipalId: principalID},
+					ConnectionLimit:  &limit,
+				})
+		}
+		return database
+	}
+	databases := []storagev1alpha1.Database{
+		newDatabase("first", "shared", 0, map[string]int32{"a": 10, "b": 20}),
+		newDatabase("second", "shared", time.Hour, map[string]int32{"c": 50}),
+		newDatabase("third", "shared", 2*time.Hour, map[string]int32{"d": 40}),
+		// The login role of a principal on several Databases counts once, with
+		// the lowest limit.
+		newDatabase("fourth", "shared", 3*time.Hour, map[string]int32{"b": 5}),
+		newDatabase("other", "dedicated", 0, map[string]int32{"e": 500}),
+	}
+
+	cases := map[string]int{
+		"first":  30,
+		"second": 80,
+		"third":  120,
+		"fourth": 105,
+	}
+	for name, want := range cases {
+		t.Run(name, func(t *testing.T) {
+			for i := range databases {
+				if databases[i].Name != name {
+					continue
+				}
+				if got := databaseServerConnectionLimits(&databases[i], databases); got != want {
+					t.Fatalf("databaseServerConnectionLimits(%q) = %d, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDatabaseAccessPayloadSettings(t *testing.T) {
+	if settings := databaseAccessPayloadSettings(storagev1alpha1.DatabaseAccessPrincipalSpec{}, 0); settings != nil {
+		t.Fatalf("expected no settings for a principal without limits, got %#v", settings)
+	}
+
+	limit := int32(25)
+	settings := databaseAccessPayloadSettings(storagev1alpha1.DatabaseAccessPrincipalSpec{
+		ConnectionLimit:  &limit,
+		StatementTimeout: &metav1.Duration{Duration: 90 * time.Second},
+	}, 10)
+	if settings == nil || *settings.ConnectionLimit != 10 || *settings.StatementTimeoutMilliseconds != 90000 ||
+		settings.IdleInTransactionSessionTimeoutMilliseconds != nil {
+		t.Fatalf("unexpected settings %#v", settings)
+	}
+
+	// Another Database's limit applies to a principal that sets none here.
+	settings = databaseAccessPayloadSettings(storagev1alpha1.DatabaseAccessPrincipalSpec{}, 10)
+	if settings == nil || *settings.ConnectionLimit != 10 {
+		t.Fatalf("expected the server's limit, got %#v", settings)
+	}
+}
+
+func TestPrincipalConnectionLimits(t *testing.T) {
+	limit := func(value int32) *int32 { return &value }
+	newDatabase := func(namespace string, principals ...storagev1alpha1.DatabaseAccessPrincipalSpec) storagev1alpha1.Database {
+		database := storagev1alpha1.Database{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: namespace}}
+		database.Spec.Access.Principals = principals
+		return database
+	}
+	databases := []storagev1alpha1.Database{
+		newDatabase("team",
+			storagev1alpha1.DatabaseAccessPrincipalSpec{
+				IdentityRef:     &storagev1alpha1.DatabaseIdentityRef{Name: "app"},
+				ConnectionLimit: limit(40),
+			},
+			storagev1alpha1.DatabaseAccessPrincipalSpec{
+				Group:           &storagev1alpha1.DatabaseGroupPrincipalSpec{Name: "ops", PrincipalId: "AAAA"},
+				ConnectionLimit: limit(5),
+			},
+		),
+		newDatabase("team",
+			storagev1alpha1.DatabaseAccessPrincipalSpec{
+				IdentityRef:     &storagev1alpha1.DatabaseIdentityRef{Name: "app", Namespace: "team"},
+				ConnectionLimit: limit(20),
+			},
+			storagev1alpha1.DatabaseAccessPrincipalSpec{
+				Group: &storagev1alpha1.DatabaseGroupPrincipalSpec{Name: "ops", PrincipalId: "aaaa"},
+			},
+			storagev1alpha1.DatabaseAccessPrincipalSpec{
+				IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "worker"},
+			},
+		),
+	}
+
+	limits := principalConnectionLimits(databases)
+	app := databasePrincipalKey(&databases[0], databases[0].Spec.Access.Principals[0])
+	ops := databasePrincipalKey(&databases[1], databases[1].Spec.Access.Principals[1])
+	worker := databasePrincipalKey(&databases[1], databases[1].Spec.Access.Principals[2])
+	if len(limits) != 2 || limits[app] != 20 || limits[ops] != 5 {
+		t.Fatalf("expected the lowest limit per principal, got %v", limits)
+	}
+	if _, ok := limits[worker]; ok {
+		t.Fatalf("expected no limit for a principal no Database limits, got %v", limits)
+	}
+}
@@ -0,0 +1,725 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+)
+
+const (
+	databaseMigrationComponentLabelValue = "database-migration"
+
+	// databaseMigrationReleaseComponentLabelValue labels the Jobs that lift
+	// the read-only fence from the source of a failed or cancelled move. They
+	// are kept apart from the migration Jobs, which a cancel deletes.
+	databaseMigrationReleaseComponentLabelValue = "database-migration-release"
+
+	// migrationPrepareContainerName and migrationCopyContainerName are the init
+	// containers of the migration Job. The provisioning container verifies the
+	// copy once they have run.
+	migrationPrepareContainerName = "prepare-target"
+	migrationCopyContainerName    = "copy"
+
+	migrationWorkVolumeName = "migration-work"
+	migrationWorkDir        = "/migration"
+
+	// operatorImageUser is the user of the operator image. Pods that hand
+	// credential files from the operator image to a client image run every
+	// container as this user, since the files are only readable by it.
+	operatorImageUser int64 = 65532
+)
+
+// databaseMigrationTarget returns the DatabaseServer the database is moved to:
+// spec.server.name when it names another server than the one hosting the
+// database. It is empty when the database stays where it is, or when no
+// database has been created yet, since there is nothing to move then.
+func databaseMigrationTarget(database *storagev1alpha1.Database) string {
+	target := strings.TrimSpace(database.Spec.Server.Name)
+	if target == "" || database.Status.DatabaseName == "" || database.Status.Server == "" {
+		return ""
+	}
+	if target == database.Status.Server {
+		return ""
+	}
+	return target
+}
+
+// reconcileDatabaseMigration moves a Ready database to the server named in
+// spec.server.name, cleans up the source of a completed move and cancels a
+// move whose target is no longer wanted. It returns when to check again.
+//
+// Moving back to the source of a completed move before its cleanup skips the
+// cleanup, since the source is the new target.
+func (r *DatabaseReconciler) reconcileDatabaseMigration(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (time.Duration, error) {
+	target := databaseMigrationTarget(database)
+	migration := database.Status.Migration
+
+	if migration != nil && migration.Phase != storagev1alpha1.DatabaseMigrationPhaseCompleted && migration.TargetServer != target {
+		return r.cancelDatabaseMigration(ctx, logger, database)
+	}
+	if migration != nil && migration.Phase == storagev1alpha1.DatabaseMigrationPhaseCompleted &&
+		migration.SourceCleanup == storagev1alpha1.DatabaseMigrationSourceCleanupPending &&
+		target != migration.SourceServer {
+		return r.cleanupDatabaseMigrationSource(ctx, logger, database)
+	}
+	if target == "" {
+		return 0, nil
+	}
+
+	if migration == nil || migration.Phase == storagev1alpha1.DatabaseMigrationPhaseCompleted {
+		database.Status.Migration = &storagev1alpha1.DatabaseMigrationStatus{
+			SourceServer: database.Status.Server,
+			TargetServer: target,
+			Phase:        storagev1alpha1.DatabaseMigrationPhasePending,
+			StartTime:    &metav1.Time{Time: time.Now()},
+		}
+		recordNormalEvent(r.Recorder, database, eventReasonMigrationStarted, eventActionMigrateDatabase,
+			"Moving database %q from DatabaseServer %q to %q", database.Status.DatabaseName, database.Status.Server, target)
+	}
+	return r.runDatabaseMigration(ctx, logger, database)
+}
+
+// runDatabaseMigration drives the copy to the target server: it waits for
+// the target to take the database, creates it there, runs the migration Job
+// and switches status.server to the target once the copy is verified. The
+// connection details follow on the next reconciles, when the access Job has
+// run against the target.
+func (r *DatabaseReconciler) runDatabaseMigration(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (time.Duration, error) {
+	migration := database.Status.Migration
+	source := migration.SourceServer
+	target := migration.TargetServer
+
+	var targetServer storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{Name: target, Namespace: database.Namespace}, &targetServer); err != nil {
+		if apierrors.IsNotFound(err) {
+			setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending,
+				fmt.Sprintf("DatabaseServer %q was not found in namespace %q", target, database.Namespace))
+			return databaseRequeueDelay, nil
+		}
+		return 0, fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, target, err)
+	}
+	if !targetServer.DeletionTimestamp.IsZero() || !meta.IsStatusConditionTrue(targetServer.Status.Conditions, databaseServerConditionReady) {
+		setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending,
+			fmt.Sprintf("Waiting for DatabaseServer %q to be Ready", target))
+		return databaseRequeueDelay, nil
+	}
+	if message, err := r.databaseServerFull(ctx, database, &targetServer); err != nil || message != "" {
+		if err != nil {
+			return 0, err
+		}
+		setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending, message)
+		return databaseLimitRequeueDelay, nil
+	}
+
+	var sourceServer storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{Name: source, Namespace: database.Namespace}, &sourceServer); err != nil {
+		if apierrors.IsNotFound(err) {
+			setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending,
+				fmt.Sprintf("DatabaseServer %q was not found in namespace %q", source, database.Namespace))
+			return databaseRequeueDelay, nil
+		}
+		return 0, fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, source, err)
+	}
+	sourceAdmin, sourceRequeue, err := r.resolveAdminIdentity(ctx, logger, &sourceServer)
+	if err != nil {
+		return 0, err
+	}
+	targetAdmin, targetRequeue, err := r.resolveAdminIdentity(ctx, logger, &targetServer)
+	if err != nil {
+		return 0, err
+	}
+	if sourceRequeue || targetRequeue {
+		setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending, "Waiting for DatabaseServer admin identity")
+		return databaseRequeueDelay, nil
+	}
+	// The Job Pod has one workload identity, so it can only reach both
+	// servers when they share their admin.
+	if sourceAdmin.Name != targetAdmin.Name || sourceAdmin.ServiceAccountName != targetAdmin.ServiceAccountName {
+		r.failDatabaseMigration(database, fmt.Sprintf(
+			"DatabaseServers %q and %q have different admin identities; the migration Job authenticates as one identity, so both servers need the same admin",
+			source, target,
+		))
+		return 0, nil
+	}
+
+	if err := r.ensureFlexibleServersDatabase(ctx, database, target, database.Status.DatabaseName); err != nil {
+		var conflictErr *databaseASOResourceConflictError
+		if !errors.As(err, &conflictErr) {
+			return 0, err
+		}
+		r.failDatabaseMigration(database, fmt.Sprintf("Database %q on server %q is already managed by %s",
+			database.Status.DatabaseName, target, conflictErr.ownerDescription()))
+		return 0, nil
+	}
+	ready, targetHost, err := r.databaseReady(ctx, logger, database, target)
+	if err != nil {
+		return 0, err
+	}
+	if !ready {
+		setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending,
+			fmt.Sprintf("Waiting for the database on DatabaseServer %q", target))
+		return databaseRequeueDelay, nil
+	}
+
+	accessPrincipals, _, _, requeue, message, err := r.resolveDatabaseAccessPrincipals(ctx, logger, database)
+	if err != nil {
+		return 0, err
+	}
+	if requeue || len(accessPrincipals) == 0 {
+		if message == "" {
+			message = "Waiting for the Database's access principals"
+		}
+		setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhasePending, message)
+		return databaseRequeueDelay, nil
+	}
+	extensions, _ := planDatabaseExtensions(database, &targetServer)
+
+	jobMigration := &userProvisionMigration{
+		SourceHost: database.Status.Host,
+		Image:      r.migrationImage(),
+	}
+	jobName := databaseMigrationJobName(database, target, targetHost, targetAdmin, accessPrincipals, extensions, jobMigration)
+
+	failedJob, err := r.failedDatabaseMigrationJob(ctx, database.Namespace, jobName)
+	if err != nil {
+		return 0, err
+	}
+	if failedJob != nil {
+		r.failDatabaseMigration(database, fmt.Sprintf("Migration Job %s failed; see the logs of its Pod. The copy is retried", jobName))
+		// The failed Job may have left the source read-only; it is released
+		// before the copy is retried.
+		released, err := r.releaseDatabaseMigrationSource(ctx, logger, database, "job="+string(failedJob.UID))
+		if err != nil {
+			return 0, err
+		}
+		if !released {
+			return databaseRequeueDelay, nil
+		}
+	}
+
+	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
+		Owner:               database,
+		JobName:             jobName,
+		Labels:              databaseMigrationJobLabels(target, database.Name),
+		Mode:                dbUtil.ProvisionModeMigration,
+		ServiceAccountName:  targetAdmin.ServiceAccountName,
+		AdminIdentityName:   targetAdmin.Name,
+		ServerName:          target,
+		DatabaseHost:        targetHost,
+		DatabaseName:        database.Status.DatabaseName,
+		SchemaName:          database.Status.DatabaseName,
+		AccessPrincipals:    accessPrincipals,
+		RevokePublicConnect: true,
+		SearchPathScope:     searchPathScopeDatabase,
+		Extensions:          extensions,
+		Migration:           jobMigration,
+	}); err != nil {
+		return 0, err
+	}
+	if failedJob != nil {
+		return databaseRequeueDelay, nil
+	}
+
+	content, found, err := readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), database.Namespace, jobName)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		if migration.Phase != storagev1alpha1.DatabaseMigrationPhaseFailed {
+			setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhaseCopying,
+				fmt.Sprintf("Copying the database from DatabaseServer %q to %q", source, target))
+		}
+		return databaseRequeueDelay, nil
+	}
+	report, err := dbUtil.UnmarshalMigrationReport(content)
+	if err != nil {
+		r.failDatabaseMigration(database, fmt.Sprintf("Migration Job %s wrote an invalid report: %v", jobName, err))
+		return databaseRequeueDelay, nil
+	}
+
+	migration.VerifiedTables = report.Tables
+	migration.VerifiedRows = report.Rows
+	if !report.Verified {
+		r.failDatabaseMigration(database, databaseMigrationMismatchMessage(report))
+		return databaseRequeueDelay, nil
+	}
+
+	database.Status.Server = target
+	database.Status.Host = targetHost
+	migration.Phase = storagev1alpha1.DatabaseMigrationPhaseCompleted
+	migration.Message = fmt.Sprintf("Database moved to DatabaseServer %q; %d tables and %d rows verified", target, report.Tables, report.Rows)
+	migration.CompletionTime = &metav1.Time{Time: time.Now()}
+	migration.SourceCleanup = storagev1alpha1.DatabaseMigrationSourceCleanupPending
+	recordNormalEvent(r.Recorder, database, eventReasonMigrationCompleted, eventActionMigrateDatabase,
+		"Moved database %q from DatabaseServer %q to %q; %d tables and %d rows verified",
+		database.Status.DatabaseName, source, target, report.Tables, report.Rows)
+	return databaseRequeueDelay, nil
+}
+
+// cleanupDatabaseMigrationSource handles the database left on the source of a
+// completed move by spec.deletionPolicy. Retain detaches it, leaving the
+// database in place. Delete drops it once spec.deletionGracePeriod has passed
+// since the move, so moving back in the meantime keeps it.
+func (r *DatabaseReconciler) cleanupDatabaseMigrationSource(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (time.Duration, error) {
+	migration := database.Status.Migration
+	source := migration.SourceServer
+
+	if database.Spec.DeletionPolicy != storagev1alpha1.DatabaseDeletionPolicyDelete {
+		detached, err := r.detachFlexibleServersDatabase(ctx, database, source)
+		if err != nil {
+			return 0, err
+		}
+		if !detached {
+			return databaseRequeueDelay, nil
+		}
+		migration.SourceCleanup = storagev1alpha1.DatabaseMigrationSourceCleanupRetained
+		return 0, nil
+	}
+
+	completed := time.Now()
+	if migration.CompletionTime != nil {
+		completed = migration.CompletionTime.Time
+	}
+	deadline := completed.Add(databaseDeletionGracePeriod(database))
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining, nil
+	}
+
+	dropped, _, err := r.ensureDatabaseDropped(ctx, logger, database, source)
+	if err != nil {
+		return 0, err
+	}
+	if !dropped {
+		return databaseRequeueDelay, nil
+	}
+	migration.SourceCleanup = storagev1alpha1.DatabaseMigrationSourceCleanupDropped
+	recordNormalEvent(r.Recorder, database, eventReasonMigrationSourceDropped, eventActionMigrateDatabase,
+		"Dropped database %q from DatabaseServer %q after it moved to %q", database.Status.DatabaseName, source, migration.TargetServer)
+	return 0, nil
+}
+
+// cancelDatabaseMigration stops an unfinished move whose target is no longer
+// spec.server.name. Once the migration Pods are gone, the source is released
+// from its read-only fence. The database on the target is a partial or
+// outdated copy, so it is dropped with deletionPolicy Delete and detached
+// otherwise. The migration status is cleared once the target is cleaned up.
+func (r *DatabaseReconciler) cancelDatabaseMigration(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (time.Duration, error) {
+	migration := database.Status.Migration
+	if err := r.deleteDatabaseMigrationJobs(ctx, database); err != nil {
+		return 0, err
+	}
+	running, err := r.databaseMigrationPodsRunning(ctx, database)
+	if err != nil {
+		return 0, err
+	}
+	if running {
+		return databaseRequeueDelay, nil
+	}
+	startTime := ""
+	if migration.StartTime != nil {
+		startTime = migration.StartTime.UTC().Format(time.RFC3339)
+	}
+	released, err := r.releaseDatabaseMigrationSource(ctx, logger, database, "cancel="+migration.TargetServer+"@"+startTime)
+	if err != nil {
+		return 0, err
+	}
+	if !released {
+		return databaseRequeueDelay, nil
+	}
+
+	var cleaned bool
+	if database.Spec.DeletionPolicy == storagev1alpha1.DatabaseDeletionPolicyDelete {
+		cleaned, _, err = r.ensureDatabaseDropped(ctx, logger, database, migration.TargetServer)
+	} else {
+		cleaned, err = r.detachFlexibleServersDatabase(ctx, database, migration.TargetServer)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !cleaned {
+		return databaseRequeueDelay, nil
+	}
+
+	recordNormalEvent(r.Recorder, database, eventReasonMigrationCancelled, eventActionMigrateDatabase,
+		"Cancelled moving database %q to DatabaseServer %q", database.Status.DatabaseName, migration.TargetServer)
+	database.Status.Migration = nil
+	return 0, nil
+}
+
+// databaseServerFull reports, as a message, when the server's profile allows
+// no more Databases than it already has.
+func (r *DatabaseReconciler) databaseServerFull(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	server *storagev1alpha1.DatabaseServer,
+) (string, error) {
+	// An unknown profile is reported on the DatabaseServer's Ready condition.
+	profile, err := resolveServerProfile(r.Config, server)
+	if err != nil || profile.MaxDatabases == 0 {
+		return "", nil
+	}
+	var databases storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &databases, client.InNamespace(database.Namespace)); err != nil {
+		return "", fmt.Errorf("list Databases in namespace %s: %w", database.Namespace, err)
+	}
+	count := 0
+	for i := range databases.Items {
+		if databases.Items[i].Name != database.Name && databaseServerName(&databases.Items[i]) == server.Name {
+			count++
+		}
+	}
+	if count < profile.MaxDatabases {
+		return "", nil
+	}
+	return fmt.Sprintf("DatabaseServer %q already has the maximum of %d databases allowed by its profile", server.Name, profile.MaxDatabases), nil
+}
+
+// releaseDatabaseMigrationSource runs a Job that lifts the read-only fence
+// from the database on the source of a move, and reports whether it has
+// completed. reason tells the releases of one move apart, so each failure
+// gets a Job of its own. A source that is gone needs no release.
+func (r *DatabaseReconciler) releaseDatabaseMigrationSource(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+	reason string,
+) (bool, error) {
+	source := database.Status.Migration.SourceServer
+	var server storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{Name: source, Namespace: database.Namespace}, &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("get DatabaseServer %s/%s: %w", database.Namespace, source, err)
+	}
+	if !server.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+	adminIdentity, requeue, err := r.resolveAdminIdentity(ctx, logger, &server)
+	if err != nil {
+		return false, err
+	}
+	if requeue {
+		return false, nil
+	}
+
+	// status.host stays on the source until the move completes.
+	host := database.Status.Host
+	jobName := databaseMigrationReleaseJobName(database, source, host, adminIdentity, reason)
+	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
+		Owner:              database,
+		JobName:            jobName,
+		Labels:             databaseMigrationReleaseJobLabels(source, database.Name),
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         source,
+		DatabaseHost:       host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		Mode:               dbUtil.ProvisionModeMigrationRelease,
+	}); err != nil {
+		return false, err
+	}
+	return r.databaseAccessJobComplete(ctx, database, jobName)
+}
+
+// failedDatabaseMigrationJob returns the migration Job when it has failed.
+func (r *DatabaseReconciler) failedDatabaseMigrationJob(ctx context.Context, namespace, jobName string) (*batchv1.Job, error) {
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: namespace}, &job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get migration Job %s/%s: %w", namespace, jobName, err)
+	}
+	if !jobConditionTrue(&job, batchv1.JobFailed) {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// databaseMigrationPodsRunning reports whether Pods of deleted migration Jobs
+// are still around, which could fence the source after it is released.
+func (r *DatabaseReconciler) databaseMigrationPodsRunning(ctx context.Context, database *storagev1alpha1.Database) (bool, error) {
+	var pods corev1.PodList
+	if err := r.apiReader().List(ctx, &pods, client.InNamespace(database.Namespace), client.MatchingLabels{
+		databaseNameLabelKey: database.Name,
+		componentLabelKey:    databaseMigrationComponentLabelValue,
+	}); err != nil {
+		return false, fmt.Errorf("list migration Pods for %s/%s: %w", database.Namespace, database.Name, err)
+	}
+	return len(pods.Items) > 0, nil
+}
+
+func (r *DatabaseReconciler) deleteDatabaseMigrationJobs(ctx context.Context, database *storagev1alpha1.Database) error {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(database.Namespace), client.MatchingLabels{
+		databaseNameLabelKey: database.Name,
+		componentLabelKey:    databaseMigrationComponentLabelValue,
+	}); err != nil {
+		return fmt.Errorf("list migration Jobs for %s/%s: %w", database.Namespace, database.Name, err)
+	}
+	policy := metav1.DeletePropagationBackground
+	for i := range jobs.Items {
+		if err := r.Delete(ctx, &jobs.Items[i], &client.DeleteOptions{PropagationPolicy: &policy}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete migration Job %s/%s: %w", database.Namespace, jobs.Items[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *DatabaseReconciler) migrationImage() string {
+	if image := strings.TrimSpace(r.Config.MigrationImage); image != "" {
+		return image
+	}
+	return config.DefaultMigrationImage
+}
+
+// failDatabaseMigration reports the migration as Failed, with a Warning Event
+// when the failure is new.
+func (r *DatabaseReconciler) failDatabaseMigration(database *storagev1alpha1.Database, message string) {
+	if setDatabaseMigrationPhase(database, storagev1alpha1.DatabaseMigrationPhaseFailed, message) {
+		recordWarningEvent(r.Recorder, database, eventReasonMigrationFailed, eventActionMigrateDatabase, "%s", message)
+	}
+}
+
+// setDatabaseMigrationPhase sets the phase and message of the migration and
+// reports whether either changed.
+func setDatabaseMigrationPhase(
+	database *storagev1alpha1.Database,
+	phase storagev1alpha1.DatabaseMigrationPhase,
+	message string,
+) bool {
+	migration := database.Status.Migration
+	if migration.Phase == phase && migration.Message == message {
+		return false
+	}
+	migration.Phase = phase
+	migration.Message = message
+	return true
+}
+
+// databaseMigrationMismatchMessage describes a copy that did not verify by
+// its first differing table.
+func databaseMigrationMismatchMessage(report dbUtil.MigrationReport) string {
+	if len(report.Mismatches) == 0 {
+		return "The copy could not be verified; it is retried"
+	}
+	first := report.Mismatches[0]
+	rows := func(count int64) string {
+		if count < 0 {
+			return "missing"
+		}
+		return fmt.Sprintf("%d rows", count)
+	}
+	count := fmt.Sprintf("%d tables differ", len(report.Mismatches))
+	if report.Truncated {
+		count = fmt.Sprintf("more than %d tables differ", len(report.Mismatches))
+	} else if len(report.Mismatches) == 1 {
+		count = "1 table differs"
+	}
+	return fmt.Sprintf(
+		"The copy did not verify: %s, such as %s.%s (source %s, target %s). Stop writes to the database; the copy is retried",
+		count, first.Schema, first.Name, rows(first.SourceRows), rows(first.TargetRows),
+	)
+}
+
+func databaseMigrationJobName(
+	database *storagev1alpha1.Database,
+	targetServer string,
+	targetHost string,
+	adminIdentity resolvedAdminIdentity,
+	accessPrincipals []dbUtil.AccessPrincipal,
+	extensions []dbUtil.DatabaseExtension,
+	migration *userProvisionMigration,
+) string {
+	accessPayload, err := dbUtil.MarshalAccessPrincipals(accessPrincipals)
+	if err != nil {
+		accessPayload = err.Error()
+	}
+	extensionsPayload, err := dbUtil.MarshalDatabaseExtensions(extensions)
+	if err != nil {
+		extensionsPayload = err.Error()
+	}
+	payload := strings.Join([]string{
+		"source=" + database.Status.Server,
+		"sourceHost=" + migration.SourceHost,
+		"server=" + targetServer,
+		"host=" + targetHost,
+		"database=" + database.Status.DatabaseName,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"access=" + accessPayload,
+		"extensions=" + extensionsPayload,
+		"image=" + migration.Image,
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	base := fmt.Sprintf("%s-migrate", database.Name)
+	return naming.WithRequiredSuffix(base, "-"+hash, 63, "ldb")
+}
+
+func databaseMigrationReleaseJobName(
+	database *storagev1alpha1.Database,
+	sourceServer string,
+	sourceHost string,
+	adminIdentity resolvedAdminIdentity,
+	reason string,
+) string {
+	payload := strings.Join([]string{
+		"server=" + sourceServer,
+		"host=" + sourceHost,
+		"database=" + database.Status.DatabaseName,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"reason=" + reason,
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	base := fmt.Sprintf("%s-release", database.Name)
+	return naming.WithRequiredSuffix(base, "-"+hash, 63, "ldb")
+}
+
+func databaseMigrationReleaseJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		componentLabelKey:          databaseMigrationReleaseComponentLabelValue,
+	}
+}
+
+func databaseMigrationJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		componentLabelKey:          databaseMigrationComponentLabelValue,
+	}
+}
+
+// addMigrationContainers turns a provisioning Pod into a migration Pod. The
+// prepare container provisions access on the target, writes the admin
+// credentials to the shared work directory and makes the source read-only;
+// the copy container restores a dump of the source into the target as the
+// database's Owner role; the provisioning container then verifies the copy.
+func addMigrationContainers(podSpec *corev1.PodSpec, image string, spec userProvisionJobSpec) {
+	mount := corev1.VolumeMount{Name: migrationWorkVolumeName, MountPath: migrationWorkDir}
+	runAsOperatorImageUser(podSpec)
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         migrationWorkVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	prepareEnv := append(userProvisionJobEnv(spec),
+		corev1.EnvVar{Name: dbUtil.MigrationPhaseEnv, Value: dbUtil.MigrationPhasePrepare},
+		corev1.EnvVar{Name: dbUtil.MigrationWorkDirEnv, Value: migrationWorkDir},
+		corev1.EnvVar{Name: dbUtil.MigrationSourceHostEnv, Value: spec.Migration.SourceHost},
+	)
+	podSpec.InitContainers = append(podSpec.InitContainers,
+		corev1.Container{
+			Name:         migrationPrepareContainerName,
+			Image:        image,
+			Args:         []string{"--provision-user"},
+			Env:          prepareEnv,
+			VolumeMounts: []corev1.VolumeMount{mount},
+		},
+		corev1.Container{
+			Name:    migrationCopyContainerName,
+			Image:   spec.Migration.Image,
+			Command: []string{"sh", "-c", migrationCopyScript()},
+			Env: []corev1.EnvVar{
+				{Name: dbUtil.MigrationWorkDirEnv, Value: migrationWorkDir},
+				{Name: dbUtil.MigrationSourceHostEnv, Value: spec.Migration.SourceHost},
+				{Name: dbUtil.DBHostEnv, Value: spec.DatabaseHost},
+				{Name: dbUtil.DBNameEnv, Value: spec.DatabaseName},
+				{Name: "PGPORT", Value: "5432"},
+				{Name: "PGSSLMODE", Value: "require"},
+			},
+			VolumeMounts: []corev1.VolumeMount{mount},
+		},
+	)
+
+	verify := &podSpec.Containers[0]
+	verify.Env = append(verify.Env,
+		corev1.EnvVar{Name: dbUtil.MigrationPhaseEnv, Value: dbUtil.MigrationPhaseVerify},
+		corev1.EnvVar{Name: dbUtil.MigrationSourceHostEnv, Value: spec.Migration.SourceHost},
+	)
+}
+
+// migrationCopyScript dumps the source and restores it into the target in one
+// transaction, so a failed restore leaves nothing behind. Extensions and the
+// public schema are left out: the prepare container created the extensions,
+// and the Owner role cannot replace the public schema. Restoring over an
+// earlier attempt replaces the objects it created.
+func migrationCopyScript() string {
+	return fmt.Sprintf(`set -eu
+dir="$%[1]s"
+export PGUSER="$(cat "$dir/%[2]s")"
+export PGPASSWORD="$(cat "$dir/%[3]s")"
+owner="$(cat "$dir/%[4]s")"
+pg_dump --format=custom --no-owner --no-privileges --host="$%[5]s" --dbname="$%[6]s" --file="$dir/dump"
+pg_restore --list "$dir/dump" | grep -v -E ' (EXTENSION|SCHEMA - public|COMMENT - SCHEMA public) ' > "$dir/restore.list"
+pg_restore --clean --if-exists --no-owner --no-privileges --single-transaction --exit-on-error \
+  --role="$owner" --host="$%[7]s" --dbname="$%[6]s" --use-list="$dir/restore.list" "$dir/dump"
+rm -f "$dir/dump"
+`,
+		dbUtil.MigrationWorkDirEnv,
+		dbUtil.MigrationUserFile,
+		dbUtil.MigrationPasswordFile,
+		dbUtil.MigrationOwnerRoleFile,
+		dbUtil.MigrationSourceHostEnv,
+		dbUtil.DBNameEnv,
+		dbUtil.DBHostEnv,
+	)
+}
+
+// runAsOperatorImageUser runs every container of the Pod as the operator
+// image's user, so a client image can read the credential files the
+// operator image wrote.
+func runAsOperatorImageUser(podSpec *corev1.PodSpec) {
+	user := operatorImageUser
+	podSpec.SecurityContext = &corev1.PodSecurityContext{
+		RunAsUser:  &user,
+		RunAsGroup: &user,
+	}
+}
+
+// setContainerEnv sets name to value in the container's environment.
+func setContainerEnv(container *corev1.Container, name, value string) {
+	for i := range container.Env {
+		if container.Env[i].Name == name {
+			container.Env[i].Value = value
+			return
+		}
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+}
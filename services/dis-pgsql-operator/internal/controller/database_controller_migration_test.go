@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func testMigrationDatabase() *storagev1alpha1.Database {
+	database := testDropDatabase()
+	database.Spec.Server.Name = "server-b"
+	database.Status.Server = "server-a"
+	database.Status.Host = "server-a.postgres.database.azure.com"
+	return database
+}
+
+func TestDatabaseMigrationTarget(t *testing.T) {
+	database := testMigrationDatabase()
+	if got := databaseMigrationTarget(database); got != "server-b" {
+		t.Fatalf("expected target server-b, got %q", got)
+	}
+	if got := databaseServerName(database); got != "server-a" {
+		t.Fatalf("expected the database to stay on server-a until moved, got %q", got)
+	}
+
+	database.Spec.Server.Name = " server-a "
+	if got := databaseMigrationTarget(database); got != "" {
+		t.Fatalf("expected no target for the current server, got %q", got)
+	}
+
+	database = testMigrationDatabase()
+	database.Status.DatabaseName = ""
+	if got := databaseMigrationTarget(database); got != "" {
+		t.Fatalf("expected no target before the database exists, got %q", got)
+	}
+	if got := databaseServerName(database); got != "server-b" {
+		t.Fatalf("expected spec.server.name before the database exists, got %q", got)
+	}
+}
+
+func TestDatabaseServersToDrop(t *testing.T) {
+	database := testMigrationDatabase()
+	if got := databaseServersToDrop(database); strings.Join(got, ",") != "server-a" {
+		t.Fatalf("expected only the current server, got %v", got)
+	}
+
+	database.Status.Migration = &storagev1alpha1.DatabaseMigrationStatus{
+		SourceServer: "server-a",
+		TargetServer: "server-b",
+		Phase:        storagev1alpha1.DatabaseMigrationPhaseCopying,
+	}
+	if got := databaseServersToDrop(database); strings.Join(got, ",") != "server-a,server-b" {
+		t.Fatalf("expected the target of an unfinished move, got %v", got)
+	}
+
+	database.Status.Server = "server-b"
+	database.Status.Migration.Phase = storagev1alpha1.DatabaseMigrationPhaseCompleted
+	database.Status.Migration.SourceCleanup = storagev1alpha1.DatabaseMigrationSourceCleanupPending
+	if got := databaseServersToDrop(database); strings.Join(got, ",") != "server-b,server-a" {
+		t.Fatalf("expected the source of a completed move, got %v", got)
+	}
+
+	database.Status.Migration.SourceCleanup = storagev1alpha1.DatabaseMigrationSourceCleanupDropped
+	if got := databaseServersToDrop(database); strings.Join(got, ",") != "server-b" {
+		t.Fatalf("expected only the current server after cleanup, got %v", got)
+	}
+}
+
+func TestBuildUserProvisionJobMigration(t *testing.T) {
+	spec := userProvisionJobSpec{
+		JobName:            "app-db-migrate-abcd1234",
+		ServiceAccountName: "pg-admin",
+		AdminIdentityName:  "pg-admin",
+		ServerName:         "server-b",
+		DatabaseHost:       "server-b.postgres.database.azure.com",
+		DatabaseName:       "app",
+		SchemaName:         "app",
+		Mode:               dbUtil.ProvisionModeMigration,
+		AccessPrincipals: []dbUtil.AccessPrincipal{{
+			Role: dbUtil.AccessRoleOwner, Name: "app", PrincipalID: "00000000-0000-0000-0000-000000000001", PrincipalType: dbUtil.PrincipalTypeService,
+		}},
+		Migration: &userProvisionMigration{
+			SourceHost: "server-a.postgres.database.azure.com",
+			Image:      "postgres:17",
+		},
+	}
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+
+	job := buildUserProvisionJob(testDbgNamespace, spec.JobName, "operator:latest", nil, spec, 1, 1, 300)
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 2 ||
+		podSpec.InitContainers[0].Name != migrationPrepareContainerName ||
+		podSpec.InitContainers[1].Name != migrationCopyContainerName {
+		t.Fatalf("expected prepare and copy init containers, got %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].EmptyDir == nil {
+		t.Fatalf("expected an emptyDir work volume, got %v", podSpec.Volumes)
+	}
+
+	if podSpec.SecurityContext == nil || podSpec.SecurityContext.RunAsUser == nil || *podSpec.SecurityContext.RunAsUser != operatorImageUser {
+		t.Fatalf("expected the Pod to run as the operator image's user, got %#v", podSpec.SecurityContext)
+	}
+
+	prepare := podSpec.InitContainers[0]
+	if prepare.Image != "operator:latest" ||
+		envToMap(prepare.Env)[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeMigration) ||
+		envToMap(prepare.Env)[dbUtil.MigrationPhaseEnv] != dbUtil.MigrationPhasePrepare ||
+		envToMap(prepare.Env)[dbUtil.MigrationSourceHostEnv] != "server-a.postgres.database.azure.com" {
+		t.Fatalf("unexpected prepare container %#v", prepare)
+	}
+	copyContainer := podSpec.InitContainers[1]
+	if copyContainer.Image != "postgres:17" ||
+		envToMap(copyContainer.Env)[dbUtil.MigrationSourceHostEnv] != "server-a.postgres.database.azure.com" ||
+		envToMap(copyContainer.Env)[dbUtil.DBHostEnv] != "server-b.postgres.database.azure.com" {
+		t.Fatalf("unexpected copy container %#v", copyContainer)
+	}
+	if envToMap(copyContainer.Env)[dbUtil.AccessPrincipalsEnv] != "" {
+		t.Fatalf("expected the copy container to not get the access payload")
+	}
+	verify := podSpec.Containers[0]
+	if envToMap(verify.Env)[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeMigration) ||
+		envToMap(verify.Env)[dbUtil.MigrationPhaseEnv] != dbUtil.MigrationPhaseVerify ||
+		envToMap(verify.Env)[dbUtil.MigrationSourceHostEnv] != "server-a.postgres.database.azure.com" {
+		t.Fatalf("unexpected verify container env %v", verify.Env)
+	}
+
+	spec.Migration = nil
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected migration mode to be rejected without a source")
+	}
+}
+
+func TestBuildUserProvisionJobMigrationRelease(t *testing.T) {
+	database := testMigrationDatabase()
+	database.Status.Migration = &storagev1alpha1.DatabaseMigrationStatus{SourceServer: "server-a", TargetServer: "server-b"}
+	admin := testDebugAdminIdentity()
+	spec := userProvisionJobSpec{
+		JobName:            databaseMigrationReleaseJobName(database, "server-a", database.Status.Host, admin, "cancel=server-b"),
+		ServiceAccountName: admin.ServiceAccountName,
+		AdminIdentityName:  admin.Name,
+		ServerName:         "server-a",
+		DatabaseHost:       database.Status.Host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		Mode:               dbUtil.ProvisionModeMigrationRelease,
+	}
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+	if other := databaseMigrationReleaseJobName(database, "server-a", database.Status.Host, admin, "job=uid-1"); other == spec.JobName {
+		t.Fatalf("expected each release to get a Job of its own, got %s twice", other)
+	}
+
+	job := buildUserProvisionJob(testDbgNamespace, spec.JobName, "operator:latest", nil, spec, 1, 1, 300)
+	env := envToMap(job.Spec.Template.Spec.Containers[0].Env)
+	if env[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeMigrationRelease) || env[dbUtil.DBHostEnv] != database.Status.Host {
+		t.Fatalf("unexpected release container env %v", env)
+	}
+	if len(job.Spec.Template.Spec.InitContainers) != 0 {
+		t.Fatalf("expected the release to run in the provisioning container only")
+	}
+
+	spec.DatabaseHost = ""
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected the release to be rejected without the source host")
+	}
+}
+
+func TestDatabaseMigrationMismatchMessage(t *testing.T) {
+	message := databaseMigrationMismatchMessage(dbUtil.MigrationReport{
+		Mismatches: []dbUtil.MigrationTable{{Schema: "app", Name: "orders", SourceRows: 10, TargetRows: -1}},
+	})
+	if !strings.Contains(message, "1 table differs") || !strings.Contains(message, "app.orders (source 10 rows, target missing)") {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
+
+func TestSetContainerEnv(t *testing.T) {
+	container := corev1.Container{Env: []corev1.EnvVar{{Name: "PGSSLMODE", Value: "require"}}}
+	setContainerEnv(&container, "PGSSLMODE", "disable")
+	setContainerEnv(&container, "PGPORT", "5432")
+	if len(container.Env) != 2 || envToMap(container.Env)["PGSSLMODE"] != "disable" || envToMap(container.Env)["PGPORT"] != "5432" {
+		t.Fatalf("unexpected env %v", container.Env)
+	}
+}
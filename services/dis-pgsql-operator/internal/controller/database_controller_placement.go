@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/validation"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+)
+
+const databaseValidationFieldServerSelector = validation.FieldServerSelector
+
+// databaseServerName returns the DatabaseServer hosting database: the server
+// named in spec.server, or the one chosen with spec.serverSelector. It is
+// empty while a selected Database has not been placed yet.
+func databaseServerName(database *storagev1alpha1.Database) string {
+	// A created database stays on status.server until a move to another
+	// spec.server.name completes.
+	status := strings.TrimSpace(database.Status.Server)
+	if status != "" && database.Status.DatabaseName != "" {
+		return status
+	}
+	if name := strings.TrimSpace(database.Spec.Server.Name); name != "" {
+		return name
+	}
+	return status
+}
+
+// databaseAwaitsPlacement reports whether database selects its server and no
+// server has been chosen yet.
+func databaseAwaitsPlacement(database *storagev1alpha1.Database) bool {
+	return database.Spec.ServerSelector != nil && databaseServerName(database) == ""
+}
+
+// serverPlacementCandidate is the remaining capacity of a DatabaseServer
+// matching a Database's serverSelector.
+type serverPlacementCandidate struct {
+	Name           string
+	Databases      int
+	MaxDatabases   int
+	MaxConnections int
+	StorageGB      int
+}
+
+func (c serverPlacementCandidate) full() bool {
+	return c.MaxDatabases > 0 && c.Databases >= c.MaxDatabases
+}
+
+// freeDatabaseSlots is how many more Databases the server's profile allows.
+func (c serverPlacementCandidate) freeDatabaseSlots() int {
+	if c.MaxDatabases == 0 {
+		return math.MaxInt
+	}
+	return c.MaxDatabases - c.Databases
+}
+
+// connectionsPerDatabase and storagePerDatabase are each Database's share of
+// the server's max_connections and storage once one more is placed on it.
+func (c serverPlacementCandidate) connectionsPerDatabase() int {
+	return c.MaxConnections / (c.Databases + 1)
+}
+
+func (c serverPlacementCandidate) storagePerDatabase() int {
+	return c.StorageGB / (c.Databases + 1)
+}
+
+// chooseDatabaseServer returns the candidate with the most remaining
+// capacity: the most free database slots, then the largest share of
+// max_connections and then of storage. Ties go to the first name, so the
+// choice is stable. It returns false when every candidate is full.
+func chooseDatabaseServer(candidates []serverPlacementCandidate) (serverPlacementCandidate, bool) {
+	available := make([]serverPlacementCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !candidate.full() {
+			available = append(available, candidate)
+		}
+	}
+	if len(available) == 0 {
+		return serverPlacementCandidate{}, false
+	}
+
+	sort.Slice(available, func(i, j int) bool {
+		a, b := available[i], available[j]
+		if a.freeDatabaseSlots() != b.freeDatabaseSlots() {
+			return a.freeDatabaseSlots() > b.freeDatabaseSlots()
+		}
+		if a.connectionsPerDatabase() != b.connectionsPerDatabase() {
+			return a.connectionsPerDatabase() > b.connectionsPerDatabase()
+		}
+		if a.storagePerDatabase() != b.storagePerDatabase() {
+			return a.storagePerDatabase() > b.storagePerDatabase()
+		}
+		return a.Name < b.Name
+	})
+	return available[0], true
+}
+
+// databaseServerMatchesSelector reports whether db can host Databases
+// selecting it: it is a shared server matching the selector's labels and
+// server type, is Ready and is not being deleted. Dedicated servers hold one
+// Database and are only chosen by name.
+func databaseServerMatchesSelector(db *storagev1alpha1.DatabaseServer, selector *storagev1alpha1.DatabaseServerSelector) bool {
+	if !db.DeletionTimestamp.IsZero() || databaseServerMode(db) != storagev1alpha1.DatabaseServerModeShared {
+		return false
+	}
+	if !labels.SelectorFromSet(selector.MatchLabels).Matches(labels.Set(db.Labels)) {
+		return false
+	}
+	if serverType := strings.TrimSpace(selector.ServerType); serverType != "" && serverType != db.Spec.ServerType {
+		return false
+	}
+	return meta.IsStatusConditionTrue(db.Status.Conditions, databaseServerConditionReady)
+}
+
+// placeDatabase chooses the DatabaseServer for a Database with a
+// serverSelector. A Database whose status.server was lost is given back the
+// server its FlexibleServersDatabase was created on, so placement stays
+// sticky. When no server can take the Database, it returns the validation
+// error to report instead.
+func (r *DatabaseReconciler) placeDatabase(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+) (string, *storagev1alpha1.DatabaseValidationError, error) {
+	var owned dbforpostgresqlv1.FlexibleServersDatabaseList
+	if err := r.List(ctx, &owned,
+		client.InNamespace(database.Namespace),
+		client.MatchingLabels{databaseNameLabelKey: database.Name},
+	); err != nil {
+		return "", nil, fmt.Errorf("list FlexibleServersDatabases in namespace %s: %w", database.Namespace, err)
+	}
+	for i := range owned.Items {
+		if server := owned.Items[i].Labels[databaseServerNameLabelKey]; server != "" && metav1.IsControlledBy(&owned.Items[i], database) {
+			return server, nil, nil
+		}
+	}
+
+	var servers storagev1alpha1.DatabaseServerList
+	if err := r.List(ctx, &servers, client.InNamespace(database.Namespace)); err != nil {
+		return "", nil, fmt.Errorf("list DatabaseServers in namespace %s: %w", database.Namespace, err)
+	}
+	var databases storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &databases, client.InNamespace(database.Namespace)); err != nil {
+		return "", nil, fmt.Errorf("list Databases in namespace %s: %w", database.Namespace, err)
+	}
+
+	counts := make(map[string]int, len(servers.Items))
+	for i := range databases.Items {
+		if databases.Items[i].Name != database.Name {
+			counts[databaseServerName(&databases.Items[i])]++
+		}
+	}
+
+	candidates := make([]serverPlacementCandidate, 0, len(servers.Items))
+	for i := range servers.Items {
+		server := &servers.Items[i]
+		if !databaseServerMatchesSelector(server, database.Spec.ServerSelector) {
+			continue
+		}
+		// An unknown profile is reported on the DatabaseServer's Ready
+		// condition, so such a server is not Ready and never gets here.
+		profile, err := resolveServerProfile(r.Config, server)
+		if err != nil {
+			continue
+		}
+		maxConnections, err := dbUtil.ResolveMaxConnections(profile)
+		if err != nil {
+			continue
+		}
+		var requestedStorageGB *int32
+		if server.Spec.Storage != nil {
+			requestedStorageGB = server.Spec.Storage.SizeGB
+		}
+		candidates = append(candidates, serverPlacementCandidate{
+			Name:           server.Name,
+			Databases:      counts[server.Name],
+			MaxDatabases:   profile.MaxDatabases,
+			MaxConnections: maxConnections,
+			StorageGB:      int(dbUtil.ResolveStorageGB(profile, requestedStorageGB)),
+		})
+	}
+
+	if len(candidates) == 0 {
+		return "", &storagev1alpha1.DatabaseValidationError{
+			Field:   databaseValidationFieldServerSelector,
+			Reason:  databaseValidationReasonNotFound,
+			Message: fmt.Sprintf("no Ready DatabaseServer in namespace %q matches spec.serverSelector", database.Namespace),
+		}, nil
+	}
+
+	chosen, ok := chooseDatabaseServer(candidates)
+	if !ok {
+		full := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			full = append(full, fmt.Sprintf("%s (%d/%d databases)", candidate.Name, candidate.Databases, candidate.MaxDatabases))
+		}
+		sort.Strings(full)
+		return "", &storagev1alpha1.DatabaseValidationError{
+			Field:   databaseValidationFieldServerSelector,
+			Reason:  databaseValidationReasonLimitExceeded,
+			Message: fmt.Sprintf("every DatabaseServer matching spec.serverSelector is full: %s", strings.Join(full, ", ")),
+		}, nil
+	}
+	return chosen.Name, nil, nil
+}
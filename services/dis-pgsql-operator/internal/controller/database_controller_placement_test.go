@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChooseDatabaseServer(t *testing.T) {
+	t.Run("prefers the most free database slots", func(t *testing.T) {
+		chosen, ok := chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "a", Databases: 8, MaxDatabases: 10, MaxConnections: 1718, StorageGB: 512},
+			{Name: "b", Databases: 2, MaxDatabases: 10, MaxConnections: 859, StorageGB: 128},
+		})
+		if !ok || chosen.Name != "b" {
+			t.Fatalf("expected b, got %q (%v)", chosen.Name, ok)
+		}
+	})
+
+	t.Run("breaks ties on connections, then storage, then name", func(t *testing.T) {
+		chosen, _ := chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "a", Databases: 1, MaxDatabases: 10, MaxConnections: 859, StorageGB: 512},
+			{Name: "b", Databases: 1, MaxDatabases: 10, MaxConnections: 1718, StorageGB: 128},
+		})
+		if chosen.Name != "b" {
+			t.Fatalf("expected the larger connection budget, got %q", chosen.Name)
+		}
+
+		chosen, _ = chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "a", Databases: 1, MaxDatabases: 10, MaxConnections: 859, StorageGB: 128},
+			{Name: "b", Databases: 1, MaxDatabases: 10, MaxConnections: 859, StorageGB: 512},
+		})
+		if chosen.Name != "b" {
+			t.Fatalf("expected the larger storage, got %q", chosen.Name)
+		}
+
+		chosen, _ = chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "b", Databases: 1, MaxDatabases: 10, MaxConnections: 859, StorageGB: 128},
+			{Name: "a", Databases: 1, MaxDatabases: 10, MaxConnections: 859, StorageGB: 128},
+		})
+		if chosen.Name != "a" {
+			t.Fatalf("expected the first name, got %q", chosen.Name)
+		}
+	})
+
+	t.Run("skips full servers", func(t *testing.T) {
+		chosen, ok := chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "a", Databases: 10, MaxDatabases: 10, MaxConnections: 1718, StorageGB: 512},
+			{Name: "b", Databases: 40, MaxConnections: 50, StorageGB: 32},
+		})
+		if !ok || chosen.Name != "b" {
+			t.Fatalf("expected the server without a limit, got %q (%v)", chosen.Name, ok)
+		}
+	})
+
+	t.Run("reports when every server is full", func(t *testing.T) {
+		if _, ok := chooseDatabaseServer([]serverPlacementCandidate{
+			{Name: "a", Databases: 10, MaxDatabases: 10},
+			{Name: "b", Databases: 5, MaxDatabases: 5},
+		}); ok {
+			t.Fatalf("expected no server")
+		}
+	})
+}
+
+func TestDatabaseServerMatchesSelector(t *testing.T) {
+	server := &storagev1alpha1.DatabaseServer{}
+	server.Labels = map[string]string{"dis.altinn.cloud/pool": "shared"}
+	server.Spec.Mode = storagev1alpha1.DatabaseServerModeShared
+	server.Spec.ServerType = "prod"
+	server.Status.Conditions = []metav1.Condition{{Type: databaseServerConditionReady, Status: metav1.ConditionTrue}}
+
+	selector := &storagev1alpha1.DatabaseServerSelector{
+		MatchLabels: map[string]string{"dis.altinn.cloud/pool": "shared"},
+		ServerType:  "prod",
+	}
+	if !databaseServerMatchesSelector(server, selector) {
+		t.Fatalf("expected the server to match")
+	}
+
+	dev := &storagev1alpha1.DatabaseServerSelector{MatchLabels: selector.MatchLabels, ServerType: "dev"}
+	if databaseServerMatchesSelector(server, dev) {
+		t.Fatalf("expected another server type to not match")
+	}
+
+	other := &storagev1alpha1.DatabaseServerSelector{MatchLabels: map[string]string{"dis.altinn.cloud/pool": "other"}}
+	if databaseServerMatchesSelector(server, other) {
+		t.Fatalf("expected other labels to not match")
+	}
+
+	dedicated := server.DeepCopy()
+	dedicated.Spec.Mode = storagev1alpha1.DatabaseServerModeDedicated
+	if databaseServerMatchesSelector(dedicated, selector) {
+		t.Fatalf("expected a dedicated server to not match")
+	}
+
+	server.Status.Conditions[0].Status = metav1.ConditionFalse
+	if databaseServerMatchesSelector(server, selector) {
+		t.Fatalf("expected a server that is not Ready to not match")
+	}
+}
+
+func TestDatabaseServerName(t *testing.T) {
+	database := &storagev1alpha1.Database{}
+	database.Spec.ServerSelector = &storagev1alpha1.DatabaseServerSelector{ServerType: "prod"}
+	if !databaseAwaitsPlacement(database) {
+		t.Fatalf("expected an unplaced Database to await placement")
+	}
+
+	database.Status.Server = "shared-1"
+	if got := databaseServerName(database); got != "shared-1" || databaseAwaitsPlacement(database) {
+		t.Fatalf("expected the placed server, got %q", got)
+	}
+
+	database.Spec.Server.Name = "named"
+	if got := databaseServerName(database); got != "named" {
+		t.Fatalf("expected spec.server.name to win, got %q", got)
+	}
+}
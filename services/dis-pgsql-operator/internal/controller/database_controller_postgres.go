@@ -42,6 +42,11 @@ const (
 	databaseAccessProvisionLabelKey = "dis.altinn.cloud/access-provision"
 	userProvisionLabelKey           = "dis.altinn.cloud/user-provision"
 
+	// componentLabelKey names the feature an operator-created Job,
+	// RoleAssignment or CronJob belongs to, such as debug access or backups,
+	// so each feature lists and prunes only its own objects.
+	componentLabelKey = "dis.altinn.cloud/component"
+
 	// kindProvisionAdminUser is the non-superuser Postgres role the provisioning
 	// Job connects as when running against Kind (useAzFakes). It mirrors Azure's
 	// non-superuser Entra admin; see the useAzFakes branch in
@@ -50,13 +55,15 @@ const (
 	kindProvisionAdminUser = "dispg_admin"
 )
 
+// ensureFlexibleServersDatabase creates the database on serverName. It is
+// the Database's server, or the server the database is being moved to.
 func (r *DatabaseReconciler) ensureFlexibleServersDatabase(
 	ctx context.Context,
 	database *storagev1alpha1.Database,
+	serverName string,
 	databaseName string,
 ) error {
 	ns := database.Namespace
-	serverName := strings.TrimSpace(database.Spec.Server.Name)
 	resourceName := databaseASOResourceName(serverName, databaseName)
 
 	desiredSpec := dbforpostgresqlv1.FlexibleServersDatabase_Spec{
@@ -130,13 +137,15 @@ func (r *DatabaseReconciler) ensureFlexibleServersDatabase(
 	return nil
 }
 
+// databaseReady reports whether the database on serverName is ready, with
+// the server's host.
 func (r *DatabaseReconciler) databaseReady(
 	ctx context.Context,
 	logger logr.Logger,
 	database *storagev1alpha1.Database,
+	serverName string,
 ) (bool, string, error) {
 	ns := database.Namespace
-	serverName := strings.TrimSpace(database.Spec.Server.Name)
 	resourceName := databaseASOResourceName(serverName, database.Status.DatabaseName)
 
 	var flexibleServersDatabase dbforpostgresqlv1.FlexibleServersDatabase
@@ -0,0 +1,419 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	identityv1alpha1 "github.com/Altinn/altinn-platform/services/dis-identity-operator/api/v1alpha1"
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+)
+
+const (
+	// databaseSchemaMigrationsComponentLabelValue labels the schema migration
+	// Jobs, and is the connection.LabelComponent value a ConfigMap holding
+	// schema migrations must carry: only labelled ConfigMaps are cached.
+	databaseSchemaMigrationsComponentLabelValue = "schema-migrations"
+
+	// schemaMigrationsConfigMapIndexField indexes Databases by the ConfigMap
+	// holding their schema migrations.
+	schemaMigrationsConfigMapIndexField = "spec.schemaMigrations.configMap.name"
+
+	databaseConditionSchemaMigrationsReady = "SchemaMigrationsReady"
+
+	databaseReasonSchemaMigrationsNotReady = "SchemaMigrationsNotReady"
+
+	schemaMigrationsReasonApplied        = "Applied"
+	schemaMigrationsReasonRunning        = "Running"
+	schemaMigrationsReasonFailed         = "Failed"
+	schemaMigrationsReasonSourceNotFound = "SourceNotFound"
+
+	// schemaMigrationsFetchContainerName copies the migrations out of the
+	// source image before the provisioning container applies them.
+	schemaMigrationsFetchContainerName = "fetch-migrations"
+
+	schemaMigrationsVolumeName = "schema-migrations"
+
+	// workloadIdentitySkipContainersAnnotation lists the containers of a Pod
+	// the workload identity webhook does not inject a token into.
+	workloadIdentitySkipContainersAnnotation = "azure.workload.identity/skip-containers"
+
+	// schemaMigrationsDir is where the migrations are mounted. It differs
+	// from the default path in source images, which the fetch container
+	// would otherwise hide.
+	schemaMigrationsDir = "/dispg/schema-migrations"
+
+	defaultSchemaMigrationsPath = "/migrations"
+)
+
+// reconcileDatabaseSchemaMigrations applies spec.schemaMigrations with a
+// schema migration Job and summarizes the outcome in status.schemaMigrations
+// and the SchemaMigrationsReady condition, which is only present while the
+// Database has schema migrations.
+//
+// The Job runs again whenever the source or the server changes. A run that
+// applied everything is not repeated; a failed run is retried once its Job
+// has expired, and until then the failure is reported.
+func (r *DatabaseReconciler) reconcileDatabaseSchemaMigrations(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) error {
+	spec := database.Spec.SchemaMigrations
+	if spec == nil {
+		meta.RemoveStatusCondition(&database.Status.Conditions, databaseConditionSchemaMigrationsReady)
+		database.Status.SchemaMigrations = nil
+		return nil
+	}
+	status := database.Status.SchemaMigrations
+	if status == nil {
+		status = &storagev1alpha1.DatabaseSchemaMigrationsStatus{}
+		database.Status.SchemaMigrations = status
+	}
+
+	source, sourceKey, found, err := r.resolveSchemaMigrationsSource(ctx, database)
+	if err != nil {
+		return err
+	}
+	if !found {
+		setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+			schemaMigrationsReasonSourceNotFound,
+			fmt.Sprintf("ConfigMap %q with the schema migrations and the label %s=%s was not found",
+				source.ConfigMapName, connection.LabelComponent, databaseSchemaMigrationsComponentLabelValue))
+		return nil
+	}
+
+	identity, waiting, err := r.resolveSchemaMigrationsIdentity(ctx, logger, database)
+	if err != nil {
+		return err
+	}
+	if waiting != "" {
+		setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+			schemaMigrationsReasonRunning, waiting)
+		return nil
+	}
+
+	serverName := databaseServerName(database)
+	jobName := databaseSchemaMigrationsJobName(database, serverName, identity, sourceKey)
+	if status.JobName == jobName && status.FailedVersion == "" && status.Message == "" {
+		setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionTrue,
+			schemaMigrationsReasonApplied, schemaMigrationsAppliedMessage(status.Version))
+		return nil
+	}
+
+	// A failed Job is deleted and run again below, so its outcome is read
+	// first.
+	report, finishedAt, found, err := r.databaseSchemaMigrationsJobResult(ctx, logger, database.Namespace, jobName)
+	if err != nil {
+		return err
+	}
+	if err := r.ensureUserProvisionJobForTarget(ctx, logger, userProvisionJobSpec{
+		Owner:              database,
+		JobName:            jobName,
+		Labels:             databaseSchemaMigrationsJobLabels(serverName, database.Name),
+		Mode:               dbUtil.ProvisionModeSchemaMigrations,
+		ServiceAccountName: identity.ServiceAccountName,
+		AdminIdentityName:  identity.Name,
+		ServerName:         serverName,
+		DatabaseHost:       database.Status.Host,
+		DatabaseName:       database.Status.DatabaseName,
+		SchemaName:         database.Status.DatabaseName,
+		SchemaMigrations:   source,
+	}); err != nil {
+		return err
+	}
+
+	if !found {
+		// A retried Job keeps reporting the failure of the previous run.
+		if status.JobName != jobName {
+			setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+				schemaMigrationsReasonRunning, "Schema migration Job is running")
+		}
+		return nil
+	}
+
+	reported := status.JobName == jobName && status.FailedVersion == report.Failed && status.Message == report.Error
+	status.JobName = jobName
+	if report.Failed == "" && report.Error != "" {
+		// The run stopped before the migrations: the Job failed, e.g. it
+		// could not connect, or refused its login role. The applied version
+		// is unknown and kept.
+		status.FailedVersion = ""
+		status.Message = report.Error
+		message := fmt.Sprintf("Schema migration Job failed: %s", report.Error)
+		setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+			schemaMigrationsReasonFailed, message)
+		if !reported {
+			recordWarningEvent(r.Recorder, database, eventReasonSchemaMigrationFailed, eventActionMigrateSchema, "%s", message)
+		}
+		return nil
+	}
+	status.Version = report.Latest
+	if report.Applied > 0 {
+		status.AppliedTime = &finishedAt
+	}
+	if report.Failed != "" {
+		status.FailedVersion = report.Failed
+		status.Message = report.Error
+		message := fmt.Sprintf("Schema migration %q failed: %s", report.Failed, report.Error)
+		setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+			schemaMigrationsReasonFailed, message)
+		if !reported {
+			recordWarningEvent(r.Recorder, database, eventReasonSchemaMigrationFailed, eventActionMigrateSchema, "%s", message)
+		}
+		return nil
+	}
+	status.FailedVersion = ""
+	status.Message = ""
+	setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionTrue,
+		schemaMigrationsReasonApplied, schemaMigrationsAppliedMessage(status.Version))
+	if report.Applied > 0 {
+		recordNormalEvent(r.Recorder, database, eventReasonSchemaMigrated, eventActionMigrateSchema,
+			"Applied %d schema migrations; the database is at version %q", report.Applied, report.Latest)
+	}
+	return nil
+}
+
+// resolveSchemaMigrationsIdentity resolves spec.schemaMigrations.identityRef
+// into the login role the Job connects as and the ServiceAccount it runs
+// under, which the ApplicationIdentity creates with its own name. While the
+// identity is not ready, waiting says what the Job waits for.
+func (r *DatabaseReconciler) resolveSchemaMigrationsIdentity(
+	ctx context.Context,
+	logger logr.Logger,
+	database *storagev1alpha1.Database,
+) (resolvedAdminIdentity, string, error) {
+	refName := strings.TrimSpace(database.Spec.SchemaMigrations.IdentityRef.Name)
+	var appIdentity identityv1alpha1.ApplicationIdentity
+	if err := r.Get(ctx, types.NamespacedName{Name: refName, Namespace: database.Namespace}, &appIdentity); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("ApplicationIdentity for schema migrations not found yet", "name", refName)
+			return resolvedAdminIdentity{}, fmt.Sprintf("Waiting for ApplicationIdentity %q", refName), nil
+		}
+		return resolvedAdminIdentity{}, "", fmt.Errorf("get ApplicationIdentity %s/%s: %w", database.Namespace, refName, err)
+	}
+	if ready, readyFound := applicationIdentityReady(&appIdentity); readyFound && !ready {
+		return resolvedAdminIdentity{}, fmt.Sprintf("Waiting for ApplicationIdentity %q to be ready", refName), nil
+	}
+	var managedIdentityName, principalID string
+	if appIdentity.Status.ManagedIdentityName != nil {
+		managedIdentityName = strings.TrimSpace(*appIdentity.Status.ManagedIdentityName)
+	}
+	if appIdentity.Status.PrincipalID != nil {
+		principalID = strings.TrimSpace(*appIdentity.Status.PrincipalID)
+	}
+	if managedIdentityName == "" || principalID == "" {
+		return resolvedAdminIdentity{}, fmt.Sprintf("Waiting for ApplicationIdentity %q status", refName), nil
+	}
+	return resolvedAdminIdentity{
+		resolvedIdentity:   resolvedIdentity{Name: managedIdentityName, PrincipalID: principalID},
+		ServiceAccountName: refName,
+	}, "", nil
+}
+
+// resolveSchemaMigrationsSource returns the source of the Job and a key that
+// changes with it: the image reference and path, or the ConfigMap's content.
+// found is false when the ConfigMap does not exist.
+func (r *DatabaseReconciler) resolveSchemaMigrationsSource(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+) (*userProvisionSchemaMigrations, string, bool, error) {
+	spec := database.Spec.SchemaMigrations
+	if spec.ConfigMap == nil {
+		path := strings.TrimSpace(spec.Path)
+		if path == "" {
+			path = defaultSchemaMigrationsPath
+		}
+		source := &userProvisionSchemaMigrations{Image: strings.TrimSpace(spec.Image), Path: path}
+		return source, "image=" + source.Image + ";path=" + source.Path, true, nil
+	}
+
+	source := &userProvisionSchemaMigrations{ConfigMapName: spec.ConfigMap.Name}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, types.NamespacedName{Name: source.ConfigMapName, Namespace: database.Namespace}, &configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return source, "", false, nil
+		}
+		return nil, "", false, fmt.Errorf("get schema migrations ConfigMap %s/%s: %w", database.Namespace, source.ConfigMapName, err)
+	}
+	fields := make([]string, 0, len(configMap.Data)+len(configMap.BinaryData))
+	for key, value := range configMap.Data {
+		fields = append(fields, key+"="+naming.StableSHA256Hex(value))
+	}
+	for key, value := range configMap.BinaryData {
+		fields = append(fields, key+"="+naming.StableSHA256Hex(string(value)))
+	}
+	slices.Sort(fields)
+	return source, "configMap=" + source.ConfigMapName + ";" + strings.Join(fields, ";"), true, nil
+}
+
+// databaseSchemaMigrationsJobResult returns the report of a completed schema
+// migration Job and when the Job finished. A failed Job is returned as a
+// report with only Error set, from the Job's failure condition. An
+// unreadable report is logged and treated as not found.
+func (r *DatabaseReconciler) databaseSchemaMigrationsJobResult(
+	ctx context.Context,
+	logger logr.Logger,
+	namespace string,
+	jobName string,
+) (dbUtil.SchemaMigrationsReport, metav1.Time, bool, error) {
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: jobName}, &job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return dbUtil.SchemaMigrationsReport{}, metav1.Time{}, false, nil
+		}
+		return dbUtil.SchemaMigrationsReport{}, metav1.Time{}, false, fmt.Errorf("get schema migration Job %s/%s: %w", namespace, jobName, err)
+	}
+	result, finishedAt, finished := provisionJobOutcome(&job)
+	if !finished {
+		return dbUtil.SchemaMigrationsReport{}, metav1.Time{}, false, nil
+	}
+	if result != provisionJobResultSucceeded {
+		report := dbUtil.SchemaMigrationsReport{Error: jobFailureMessage(&job)}
+		if report.Error == "" {
+			report.Error = "the Job failed"
+		}
+		return report, metav1.Time{Time: finishedAt}, true, nil
+	}
+	message, found, err := readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), namespace, jobName)
+	if err != nil || !found {
+		return dbUtil.SchemaMigrationsReport{}, metav1.Time{}, false, err
+	}
+	report, err := dbUtil.UnmarshalSchemaMigrationsReport(message)
+	if err != nil {
+		logger.Error(err, "ignoring invalid schema migrations report", "jobName", jobName)
+		return dbUtil.SchemaMigrationsReport{}, metav1.Time{}, false, nil
+	}
+	return report, metav1.Time{Time: finishedAt}, true, nil
+}
+
+// databaseSchemaMigrationsReady reports whether every schema migration is
+// applied. Databases without schema migrations have no SchemaMigrationsReady
+// condition.
+func databaseSchemaMigrationsReady(database *storagev1alpha1.Database) bool {
+	condition := meta.FindStatusCondition(database.Status.Conditions, databaseConditionSchemaMigrationsReady)
+	return condition == nil || condition.Status == metav1.ConditionTrue
+}
+
+func schemaMigrationsAppliedMessage(version string) string {
+	if version == "" {
+		return "No schema migrations to apply"
+	}
+	return fmt.Sprintf("Schema migrations are applied up to version %q", version)
+}
+
+func databaseSchemaMigrationsJobName(
+	database *storagev1alpha1.Database,
+	serverName string,
+	identity resolvedAdminIdentity,
+	sourceKey string,
+) string {
+	payload := strings.Join([]string{
+		"server=" + serverName,
+		"database=" + database.Status.DatabaseName,
+		"host=" + database.Status.Host,
+		"sa=" + identity.ServiceAccountName,
+		"identity=" + identity.Name,
+		"source=" + sourceKey,
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	return naming.WithRequiredSuffix(database.Name+"-schema", "-"+hash, 63, "ldb")
+}
+
+func databaseSchemaMigrationsJobLabels(serverName, databaseName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		databaseNameLabelKey:       databaseName,
+		componentLabelKey:          databaseSchemaMigrationsComponentLabelValue,
+	}
+}
+
+// addSchemaMigrationsSource mounts the migrations into the provisioning
+// container. A ConfigMap is mounted directly; an image is copied into a
+// shared volume by an init container running the image. The init container
+// gets no workload identity token: the image only has to provide files.
+func addSchemaMigrationsSource(template *corev1.PodTemplateSpec, source *userProvisionSchemaMigrations) {
+	podSpec := &template.Spec
+	volume := corev1.Volume{Name: schemaMigrationsVolumeName}
+	if source.ConfigMapName != "" {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: source.ConfigMapName},
+		}
+	} else {
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+			Name:         schemaMigrationsFetchContainerName,
+			Image:        source.Image,
+			Command:      []string{"cp", "-R", strings.TrimSuffix(source.Path, "/") + "/.", schemaMigrationsDir},
+			VolumeMounts: []corev1.VolumeMount{{Name: schemaMigrationsVolumeName, MountPath: schemaMigrationsDir}},
+		})
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[workloadIdentitySkipContainersAnnotation] = schemaMigrationsFetchContainerName
+	}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+
+	container := &podSpec.Containers[0]
+	container.Env = append(container.Env, corev1.EnvVar{Name: dbUtil.SchemaMigrationsDirEnv, Value: schemaMigrationsDir})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      schemaMigrationsVolumeName,
+		MountPath: schemaMigrationsDir,
+		ReadOnly:  true,
+	})
+}
+
+// schemaMigrationsConfigMapIndex is the schemaMigrationsConfigMapIndexField
+// index of a Database.
+func schemaMigrationsConfigMapIndex(obj client.Object) []string {
+	database, ok := obj.(*storagev1alpha1.Database)
+	if !ok || database.Spec.SchemaMigrations == nil || database.Spec.SchemaMigrations.ConfigMap == nil {
+		return nil
+	}
+	return []string{database.Spec.SchemaMigrations.ConfigMap.Name}
+}
+
+// mapConfigMapToDatabases enqueues the Databases whose schema migrations
+// come from the ConfigMap, so a new migration is applied without waiting
+// for another change.
+func (r *DatabaseReconciler) mapConfigMapToDatabases(
+	ctx context.Context,
+	obj client.Object,
+) []ctrl.Request {
+	if obj.GetLabels()[connection.LabelComponent] != databaseSchemaMigrationsComponentLabelValue {
+		return nil
+	}
+	var list storagev1alpha1.DatabaseList
+	if err := r.List(ctx, &list,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{schemaMigrationsConfigMapIndexField: obj.GetName()},
+	); err != nil {
+		return nil
+	}
+
+	requests := make([]ctrl.Request, 0, len(list.Items))
+	for i := range list.Items {
+		database := list.Items[i]
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      database.Name,
+				Namespace: database.Namespace,
+			},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testSchemaMigrationsJobSpec(source *userProvisionSchemaMigrations) userProvisionJobSpec {
+	return userProvisionJobSpec{
+		JobName:            "app-db-schema-abcd1234",
+		ServiceAccountName: "app",
+		AdminIdentityName:  "app-identity",
+		ServerName:         "shared",
+		DatabaseHost:       "shared.postgres.database.azure.com",
+		DatabaseName:       "app",
+		SchemaName:         "app",
+		Mode:               dbUtil.ProvisionModeSchemaMigrations,
+		SchemaMigrations:   source,
+	}
+}
+
+func TestBuildUserProvisionJobSchemaMigrationsFromConfigMap(t *testing.T) {
+	spec := testSchemaMigrationsJobSpec(&userProvisionSchemaMigrations{ConfigMapName: "app-migrations"})
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+
+	job := buildUserProvisionJob(testDbgNamespace, spec.JobName, "operator:latest", nil, spec, 1, 1, 300)
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 0 {
+		t.Fatalf("expected no init containers, got %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].ConfigMap == nil || podSpec.Volumes[0].ConfigMap.Name != "app-migrations" {
+		t.Fatalf("expected the ConfigMap volume, got %v", podSpec.Volumes)
+	}
+	container := podSpec.Containers[0]
+	if envToMap(container.Env)[dbUtil.SchemaMigrationsDirEnv] != schemaMigrationsDir {
+		t.Fatalf("unexpected env %v", container.Env)
+	}
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].MountPath != schemaMigrationsDir || !container.VolumeMounts[0].ReadOnly {
+		t.Fatalf("unexpected volume mounts %v", container.VolumeMounts)
+	}
+}
+
+func TestBuildUserProvisionJobSchemaMigrationsFromImage(t *testing.T) {
+	spec := testSchemaMigrationsJobSpec(&userProvisionSchemaMigrations{Image: "ghcr.io/altinn/app-migrations:1.2.0", Path: "/migrations/"})
+	if err := validateUserProvisionJobSpec(spec, false); err != nil {
+		t.Fatalf("validateUserProvisionJobSpec: %v", err)
+	}
+
+	job := buildUserProvisionJob(testDbgNamespace, spec.JobName, "operator:latest", nil, spec, 1, 1, 300)
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].EmptyDir == nil {
+		t.Fatalf("expected an emptyDir volume, got %v", podSpec.Volumes)
+	}
+	if len(podSpec.InitContainers) != 1 {
+		t.Fatalf("expected the fetch container, got %v", podSpec.InitContainers)
+	}
+	fetch := podSpec.InitContainers[0]
+	if fetch.Name != schemaMigrationsFetchContainerName || fetch.Image != "ghcr.io/altinn/app-migrations:1.2.0" ||
+		len(fetch.Command) != 4 || fetch.Command[2] != "/migrations/." || fetch.Command[3] != schemaMigrationsDir {
+		t.Fatalf("unexpected fetch container %#v", fetch)
+	}
+	if job.Spec.Template.Annotations[workloadIdentitySkipContainersAnnotation] != schemaMigrationsFetchContainerName {
+		t.Fatalf("expected no workload identity token in the fetch container, got %v", job.Spec.Template.Annotations)
+	}
+
+	spec = testSchemaMigrationsJobSpec(nil)
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected schema migrations to be rejected without a source")
+	}
+	spec = testSchemaMigrationsJobSpec(&userProvisionSchemaMigrations{Image: "ghcr.io/altinn/app-migrations:1.2.0"})
+	if err := validateUserProvisionJobSpec(spec, false); err == nil {
+		t.Fatalf("expected schema migrations from an image to require a path")
+	}
+	spec = testSchemaMigrationsJobSpec(&userProvisionSchemaMigrations{ConfigMapName: "app-migrations"})
+	spec.AdminIdentityName = ""
+	if err := validateUserProvisionJobSpec(spec, true); err == nil {
+		t.Fatalf("expected schema migrations to require the Owner principal")
+	}
+}
+
+func TestDatabaseSchemaMigrationsJobName(t *testing.T) {
+	database := testDropDatabase()
+	admin := testDebugAdminIdentity()
+	first := databaseSchemaMigrationsJobName(database, "shared", admin, "configMap=app-migrations;0001.sql=a")
+	if first != databaseSchemaMigrationsJobName(database, "shared", admin, "configMap=app-migrations;0001.sql=a") {
+		t.Fatalf("expected a stable Job name")
+	}
+	if first == databaseSchemaMigrationsJobName(database, "shared", admin, "configMap=app-migrations;0001.sql=a;0002.sql=b") {
+		t.Fatalf("expected a new Job for new migrations")
+	}
+	if first == databaseSchemaMigrationsJobName(database, "other", admin, "configMap=app-migrations;0001.sql=a") {
+		t.Fatalf("expected a new Job on another server")
+	}
+	if len(first) > 63 {
+		t.Fatalf("Job name %q is too long", first)
+	}
+}
+
+func TestDatabaseSchemaMigrationsReady(t *testing.T) {
+	database := testDropDatabase()
+	if !databaseSchemaMigrationsReady(database) {
+		t.Fatalf("expected a Database without schema migrations to be ready")
+	}
+	setDatabaseCondition(database, databaseConditionSchemaMigrationsReady, metav1.ConditionFalse,
+		schemaMigrationsReasonFailed, "Schema migration \"0002\" failed")
+	if databaseSchemaMigrationsReady(database) {
+		t.Fatalf("expected a failed schema migration to block Ready")
+	}
+
+	database.Spec.SchemaMigrations = nil
+	database.Status.SchemaMigrations = &storagev1alpha1.DatabaseSchemaMigrationsStatus{Version: "0001"}
+	r := &DatabaseReconciler{}
+	if err := r.reconcileDatabaseSchemaMigrations(t.Context(), logr.Discard(), database); err != nil {
+		t.Fatalf("reconcileDatabaseSchemaMigrations: %v", err)
+	}
+	if database.Status.SchemaMigrations != nil || !databaseSchemaMigrationsReady(database) {
+		t.Fatalf("expected removing spec.schemaMigrations to clear the status")
+	}
+}
+
+func TestSchemaMigrationsConfigMapIndex(t *testing.T) {
+	database := testDropDatabase()
+	if got := schemaMigrationsConfigMapIndex(database); len(got) != 0 {
+		t.Fatalf("expected no index without schema migrations, got %v", got)
+	}
+	database.Spec.SchemaMigrations = &storagev1alpha1.DatabaseSchemaMigrationsSpec{Image: "ghcr.io/altinn/app-migrations:1.2.0"}
+	if got := schemaMigrationsConfigMapIndex(database); len(got) != 0 {
+		t.Fatalf("expected no index for an image, got %v", got)
+	}
+	database.Spec.SchemaMigrations = &storagev1alpha1.DatabaseSchemaMigrationsSpec{
+		ConfigMap: &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"},
+	}
+	if got := schemaMigrationsConfigMapIndex(database); len(got) != 1 || got[0] != "app-migrations" {
+		t.Fatalf("expected the ConfigMap name, got %v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/connection"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// reconcileServiceBindingSecrets upserts one servicebinding.io Secret per
+// service principal when the Database selects that format, and deletes any
+// stale binding Secrets owned by this Database. Like the ConfigMaps, it runs
+// only once the Database is ready.
+func (r *DatabaseReconciler) reconcileServiceBindingSecrets(
+	ctx context.Context,
+	database *storagev1alpha1.Database,
+	coords []connection.Coordinates,
+) error {
+	if !connection.PublishesFormat(database, storagev1alpha1.DatabaseConnectionFormatServiceBindingSecret) {
+		coords = nil
+	}
+	desiredNames := make(map[string]struct{}, len(coords))
+	desiredForeign := map[types.NamespacedName]struct{}{}
+	for i := range coords {
+		desired, err := connection.BuildServiceBindingSecret(database, coords[i])
+		if err != nil {
+			return err
+		}
+		if connection.IsForeign(database, coords[i]) {
+			desiredForeign[client.ObjectKeyFromObject(desired)] = struct{}{}
+			if err := r.upsertForeignServiceBindingSecret(ctx, database, desired); err != nil {
+				return err
+			}
+			continue
+		}
+		desiredNames[desired.Name] = struct{}{}
+		if err := r.upsertServiceBindingSecret(ctx, database, desired); err != nil {
+			return err
+		}
+	}
+
+	if err := r.cleanupStaleForeignConnectionObjects(ctx, database, &corev1.SecretList{}, desiredForeign); err != nil {
+		return err
+	}
+	return r.cleanupStaleServiceBindingSecrets(ctx, database, desiredNames)
+}
+
+func (r *DatabaseReconciler) upsertServiceBindingSecret(
+	ctx context.Context,
+	owner *storagev1alpha1.Database,
+	desired *corev1.Secret,
+) error {
+	current := &corev1.Secret{}
+	current.SetName(desired.GetName())
+	current.SetNamespace(desired.GetNamespace())
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, current, func() error {
+		current.Labels = desired.Labels
+		// The Secret type is immutable; it only takes effect on create.
+		if current.CreationTimestamp.IsZero() {
+			current.Type = desired.Type
+		}
+		current.Data = desired.Data
+		current.StringData = nil
+		return ctrl.SetControllerReference(owner, current, r.Scheme)
+	})
+	return err
+}
+
+// cleanupStaleServiceBindingSecrets deletes binding Secrets that this
+// Database owns but no longer desires, selected the same way as
+// cleanupStaleConnectionConfigMaps.
+func (r *DatabaseReconciler) cleanupStaleServiceBindingSecrets(
+	ctx context.Context,
+	owner *storagev1alpha1.Database,
+	desiredNames map[string]struct{},
+) error {
+	var secrets corev1.SecretList
+	if err := r.List(
+		ctx,
+		&secrets,
+		client.InNamespace(owner.Namespace),
+		client.MatchingLabels{connection.LabelComponent: connection.ComponentValue},
+	); err != nil {
+		return err
+	}
+
+	for i := range secrets.Items {
+		current := &secrets.Items[i]
+		if _, ok := desiredNames[current.Name]; ok {
+			continue
+		}
+		if !metav1.IsControlledBy(current, owner) {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, current)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -148,7 +148,7 @@ var _ = Describe("DatabaseServer controller", func() {
 					Principals: []storagev1alpha1.DatabaseAccessPrincipalSpec{
 						{
 							Role: storagev1alpha1.DatabaseAccessRoleWriter,
-							IdentityRef: &storagev1alpha1.ApplicationIdentityRef{
+							IdentityRef: &storagev1alpha1.DatabaseIdentityRef{
 								Name: databaseAppIdentityRef,
 							},
 						},
@@ -196,8 +196,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &jobs,
 				client.InNamespace(namespace),
 				client.MatchingLabels(map[string]string{
-					databaseNameLabelKey:  databaseName,
-					userProvisionLabelKey: labelValueTrue,
+					databaseNameLabelKey:            databaseName,
+					databaseAccessProvisionLabelKey: labelValueTrue,
 				}),
 			)).To(Succeed())
 			if len(jobs.Items) != 1 {
@@ -372,12 +372,16 @@ var _ = Describe("DatabaseServer controller", func() {
 		deleteAll(&storagev1alpha1.DatabaseServer{})
 
 		// envtest has no garbage collector, so owner-referenced connection
-		// ConfigMaps are not reclaimed when their Database is deleted. Remove
-		// them by label so they do not leak across specs.
+		// ConfigMaps and binding Secrets are not reclaimed when their Database
+		// is deleted. Remove them by label so they do not leak across specs.
 		Expect(k8sClient.DeleteAllOf(ctx, &corev1.ConfigMap{},
 			client.InNamespace(namespace),
 			client.MatchingLabels{connection.LabelComponent: connection.ComponentValue},
 		)).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Secret{},
+			client.InNamespace(namespace),
+			client.MatchingLabels{connection.LabelComponent: connection.ComponentValue},
+		)).To(Succeed())
 
 		deleteAll(&batchv1.Job{})
 		deleteAll(&dbforpostgresqlv1.FlexibleServersDatabase{})
@@ -1650,7 +1654,9 @@ var _ = Describe("DatabaseServer controller", func() {
 		}
 		Expect(k8sClient.Create(ctx, db)).To(Succeed())
 
-		maxConnections, err := dbUtil.ResolveMaxConnections(dbUtil.GetProfile(serverTypeProd))
+		profile, err := dbUtil.DefaultProfiles().Resolve("", serverTypeProd)
+		Expect(err).NotTo(HaveOccurred())
+		maxConnections, err := dbUtil.ResolveMaxConnections(profile)
 		Expect(err).NotTo(HaveOccurred())
 
 		expectedValues := map[string]string{
@@ -2039,12 +2045,13 @@ var _ = Describe("DatabaseServer controller", func() {
 			Should(BeEmpty())
 	})
 
-	It("updates the FlexibleServer when database server storage spec changes", func() {
+	It("applies storage changes right away and holds SKU changes", func() {
 		initialSize := int32(32)
 		initialTier := "P10"
 		updatedSize := int32(64)
 		updatedTier := skuP15
 
+		// Keep the maintenance window away from now, so the SKU change is held.
 		db := &storagev1alpha1.DatabaseServer{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "my-app-db-psql-update",
@@ -2064,11 +2071,19 @@ var _ = Describe("DatabaseServer controller", func() {
 					SizeGB: &initialSize,
 					Tier:   &initialTier,
 				},
+				MaintenanceWindow: &storagev1alpha1.DatabaseServerMaintenanceWindow{
+					DayOfWeek: (int(time.Now().UTC().Weekday()) + 3) % 7,
+					StartHour: 3,
+				},
 			},
 		}
 		Expect(k8sClient.Create(ctx, db)).To(Succeed())
 
-		Eventually(func(g Gomega) int {
+		type storageState struct {
+			size int
+			tier string
+		}
+		flexibleServerStorage := func(g Gomega) storageState {
 			var s dbforpostgresqlv1.FlexibleServer
 			g.Expect(k8sClient.Get(ctx, types.NamespacedName{
 				Name:      db.Name,
@@ -2077,7 +2092,14 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(s.Spec.Storage).NotTo(BeNil())
 			g.Expect(s.Spec.Storage.StorageSizeGB).NotTo(BeNil())
 			g.Expect(s.Spec.Storage.Tier).NotTo(BeNil())
-			return *s.Spec.Storage.StorageSizeGB
+			return storageState{
+				size: *s.Spec.Storage.StorageSizeGB,
+				tier: string(*s.Spec.Storage.Tier),
+			}
+		}
+
+		Eventually(func(g Gomega) int {
+			return flexibleServerStorage(g).size
 		}).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
 			Should(Equal(int(initialSize)))
 
@@ -2089,33 +2111,54 @@ var _ = Describe("DatabaseServer controller", func() {
 		}
 		Expect(k8sClient.Update(ctx, &updated)).To(Succeed())
 
-		Eventually(func(g Gomega) struct {
-			size int
-			tier string
-		} {
+		// Storage size and tier changes are online and applied right away.
+		Eventually(flexibleServerStorage).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
+			Should(Equal(storageState{size: int(updatedSize), tier: updatedTier}))
+
+		flexibleServerSku := func(g Gomega) string {
 			var s dbforpostgresqlv1.FlexibleServer
 			g.Expect(k8sClient.Get(ctx, types.NamespacedName{
 				Name:      db.Name,
 				Namespace: db.Namespace,
 			}, &s)).To(Succeed())
-			g.Expect(s.Spec.Storage).NotTo(BeNil())
-			g.Expect(s.Spec.Storage.StorageSizeGB).NotTo(BeNil())
-			g.Expect(s.Spec.Storage.Tier).NotTo(BeNil())
-			return struct {
-				size int
-				tier string
-			}{
-				size: *s.Spec.Storage.StorageSizeGB,
-				tier: string(*s.Spec.Storage.Tier),
-			}
+			g.Expect(s.Spec.Sku).NotTo(BeNil())
+			g.Expect(s.Spec.Sku.Name).NotTo(BeNil())
+			return *s.Spec.Sku.Name
+		}
+		devProfile, err := dbUtil.DefaultProfiles().Resolve("", serverTypeDev)
+		Expect(err).NotTo(HaveOccurred())
+		prodProfile, err := dbUtil.DefaultProfiles().Resolve("", serverTypeProd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &updated)).To(Succeed())
+		updated.Spec.ServerType = serverTypeProd
+		Expect(k8sClient.Update(ctx, &updated)).To(Succeed())
+
+		Eventually(func(g Gomega) bool {
+			var current storagev1alpha1.DatabaseServer
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &current)).To(Succeed())
+			return meta.IsStatusConditionTrue(current.Status.Conditions, databaseServerConditionPendingChanges)
 		}).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
-			Should(Equal(struct {
-				size int
-				tier string
-			}{
-				size: int(updatedSize),
-				tier: updatedTier,
-			}))
+			Should(BeTrue())
+		Consistently(flexibleServerSku).WithTimeout(2 * time.Second).WithPolling(500 * time.Millisecond).
+			Should(Equal(devProfile.SkuName))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &updated)).To(Succeed())
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[applyNowAnnotation] = "true"
+		Expect(k8sClient.Update(ctx, &updated)).To(Succeed())
+
+		Eventually(flexibleServerSku).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
+			Should(Equal(prodProfile.SkuName))
+		Eventually(func(g Gomega) {
+			var current storagev1alpha1.DatabaseServer
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &current)).To(Succeed())
+			g.Expect(current.Annotations).NotTo(HaveKey(applyNowAnnotation))
+			g.Expect(meta.FindStatusCondition(current.Status.Conditions, databaseServerConditionPendingChanges)).To(BeNil())
+		}).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
+			Should(Succeed())
 	})
 
 	It("clamps storage tier to the max supported for the requested size", func() {
@@ -2306,8 +2349,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &list,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
 				},
 			)).To(Succeed())
 			return list.Items
@@ -2385,8 +2428,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &list,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
 				},
 			)).To(Succeed())
 			ids := make([]string, 0, len(list.Items))
@@ -2477,8 +2520,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &list,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
 				},
 			)).To(Succeed())
 			return list.Items
@@ -2563,8 +2606,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &list,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
 				},
 			)).To(Succeed())
 			return len(list.Items)
@@ -2672,9 +2715,9 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &jobs,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels(map[string]string{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
-					userProvisionLabelKey:        labelValueTrue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
+					userProvisionLabelKey:      labelValueTrue,
 				}),
 			)).To(Succeed())
 			g.Expect(jobs.Items).To(HaveLen(1))
@@ -2687,7 +2730,7 @@ var _ = Describe("DatabaseServer controller", func() {
 			}, &fetched)).To(Succeed())
 			g.Expect(metav1.IsControlledBy(&job, &fetched)).To(BeTrue())
 			g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
-				corev1.EnvVar{Name: dbUtil.ServerDebugAccessEnv, Value: "1"},
+				corev1.EnvVar{Name: dbUtil.ProvisionModeEnv, Value: string(dbUtil.ProvisionModeServerDebugAccess)},
 			))
 		}).WithTimeout(30 * time.Second).WithPolling(500 * time.Millisecond).
 			Should(Succeed())
@@ -2747,9 +2790,9 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &jobs,
 				client.InNamespace(db.Namespace),
 				client.MatchingLabels(map[string]string{
-					databaseServerNameLabelKey:   db.Name,
-					debugAccessComponentLabelKey: debugAccessComponentLabelValue,
-					userProvisionLabelKey:        labelValueTrue,
+					databaseServerNameLabelKey: db.Name,
+					componentLabelKey:          debugAccessComponentLabelValue,
+					userProvisionLabelKey:      labelValueTrue,
 				}),
 			)).To(Succeed())
 			g.Expect(jobs.Items).To(HaveLen(1))
@@ -3476,19 +3519,19 @@ var _ = Describe("DatabaseServer controller", func() {
 		database.Spec.Access.Principals = []storagev1alpha1.DatabaseAccessPrincipalSpec{
 			{
 				Role: storagev1alpha1.DatabaseAccessRoleReader,
-				IdentityRef: &storagev1alpha1.ApplicationIdentityRef{
+				IdentityRef: &storagev1alpha1.DatabaseIdentityRef{
 					Name: " app-ref ",
 				},
 			},
 			{
 				Role: storagev1alpha1.DatabaseAccessRoleWriter,
-				IdentityRef: &storagev1alpha1.ApplicationIdentityRef{
+				IdentityRef: &storagev1alpha1.DatabaseIdentityRef{
 					Name: "same-ref",
 				},
 			},
 			{
 				Role: storagev1alpha1.DatabaseAccessRoleOwner,
-				IdentityRef: &storagev1alpha1.ApplicationIdentityRef{
+				IdentityRef: &storagev1alpha1.DatabaseIdentityRef{
 					Name: "same-ref",
 				},
 			},
@@ -3910,6 +3953,47 @@ var _ = Describe("DatabaseServer controller", func() {
 		Expect(cms.Items).To(HaveLen(1))
 	})
 
+	It("publishes a service binding Secret instead of the ConfigMap when selected", func() {
+		db := newSharedDatabaseServer("shared-db-conn-binding")
+		Expect(k8sClient.Create(ctx, db)).To(Succeed())
+		createApplicationIdentity(ctx, databaseAppIdentityRef, databaseAppManagedIdentity, databaseAppPrincipalID)
+
+		database := newDatabase("router-conn-binding", db.Name)
+		database.Spec.Connection = &storagev1alpha1.DatabaseConnectionSpec{
+			Formats:   []storagev1alpha1.DatabaseConnectionFormat{storagev1alpha1.DatabaseConnectionFormatServiceBindingSecret},
+			ExtraKeys: []storagev1alpha1.DatabaseConnectionKey{storagev1alpha1.DatabaseConnectionKeyDotNetConnectionString},
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+
+		Eventually(func(g Gomega) int {
+			return len(listDatabaseASOChildren(g, database.Name))
+		}).WithTimeout(10 * time.Second).WithPolling(250 * time.Millisecond).
+			Should(Equal(1))
+
+		markDatabaseASOReady(ctx, database)
+		completeDatabaseAccessJob(ctx, waitForDatabaseAccessJob(ctx, database.Name, database.Namespace))
+
+		name := connection.DeterministicConfigMapName(database.Name, databaseAppIdentityRef)
+		var secret corev1.Secret
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, &secret)
+		}).WithTimeout(10 * time.Second).WithPolling(250 * time.Millisecond).
+			Should(Succeed())
+
+		expectedHost := fmt.Sprintf("%s.postgres.database.azure.com", db.Name)
+		Expect(secret.Type).To(Equal(connection.ServiceBindingSecretType))
+		Expect(string(secret.Data[connection.BindingKeyType])).To(Equal(connection.BindingType))
+		Expect(string(secret.Data[connection.BindingKeyHost])).To(Equal(expectedHost))
+		Expect(string(secret.Data[connection.BindingKeyDatabase])).To(Equal(database.Spec.Name))
+		Expect(string(secret.Data[connection.BindingKeyUsername])).To(Equal(databaseAppManagedIdentity))
+		Expect(secret.Data).To(HaveKey(connection.DataKeyDotNetConnectionString))
+		Expect(metav1.IsControlledBy(&secret, database)).To(BeTrue())
+
+		var cm corev1.ConfigMap
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, &cm)).
+			NotTo(Succeed())
+	})
+
 	It("grants access to a servicePrincipal principal without publishing a ConfigMap", func() {
 		db := newSharedDatabaseServer("shared-db-sp-only")
 		Expect(k8sClient.Create(ctx, db)).To(Succeed())
@@ -4234,8 +4318,8 @@ var _ = Describe("DatabaseServer controller", func() {
 			g.Expect(k8sClient.List(ctx, &jobs,
 				client.InNamespace(database.Namespace),
 				client.MatchingLabels(map[string]string{
-					databaseNameLabelKey:  database.Name,
-					userProvisionLabelKey: labelValueTrue,
+					databaseNameLabelKey:            database.Name,
+					databaseAccessProvisionLabelKey: labelValueTrue,
 				}),
 			)).To(Succeed())
 			if len(jobs.Items) != 1 {
@@ -11,6 +11,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -40,21 +41,77 @@ type userProvisionJobSpec struct {
 	DatabaseName string
 	SchemaName   string
 
+	// Mode selects what the Job does; see dbUtil.ProvisionMode.
+	Mode dbUtil.ProvisionMode
+
 	AccessPrincipals []dbUtil.AccessPrincipal
 
 	RevokePublicConnect bool
 	SearchPathScope     string
 
-	// ServerDebugAccess selects server-wide debug access provisioning instead of
-	// per-database schema access. In this mode SchemaName/SearchPathScope and the
-	// per-principal Role field are unused; principals are granted membership in a
-	// single managed role holding DebugBuiltinRoles plus CONNECT on all databases.
-	ServerDebugAccess bool
-
 	// DebugBuiltinRoles are the built-in PostgreSQL roles (e.g. pg_monitor,
-	// pg_read_all_data) granted to the managed debug role. Only used when
-	// ServerDebugAccess is true.
+	// pg_read_all_data) granted to the managed debug role in the server debug
+	// access mode. SchemaName, SearchPathScope and the per-principal Role
+	// field are unused in that mode.
 	DebugBuiltinRoles []string
+
+	// Extensions are created, updated or dropped in DatabaseName after access
+	// is provisioned; the Job reports the outcome in its termination message.
+	// Only used by the modes that provision per-database access.
+	Extensions []dbUtil.DatabaseExtension
+
+	// AccessAuditEnforce makes the access audit revoke the unmanaged role
+	// memberships and default privileges it finds.
+	AccessAuditEnforce bool
+
+	// Migration is the source of the migration mode: the Job copies
+	// DatabaseName from Migration.SourceHost to DatabaseHost. An init
+	// container provisions access on the target and hands the admin
+	// credentials to a second one, which dumps the source and restores the
+	// target with Migration.Image. The provisioning container then provisions
+	// access again and reports the verification in its termination message.
+	Migration *userProvisionMigration
+
+	// Backup is the destination of the backup mode, run by the backup
+	// CronJob: an init container connects as the admin and hands its
+	// credentials to a second one, which dumps DatabaseName with
+	// Backup.Image. The provisioning container then uploads the dump to
+	// Backup.ContainerURL, prunes old backups and reports the upload in its
+	// termination message.
+	Backup *userProvisionBackup
+
+	// SchemaMigrations is the source of the schema migration mode: the Job
+	// applies the pending migrations to DatabaseName as the database's Owner
+	// role and reports the outcome in its termination message.
+	// ServiceAccountName and AdminIdentityName are an Owner principal of the
+	// database, not the admin: the Job connects as that principal.
+	SchemaMigrations *userProvisionSchemaMigrations
+}
+
+// userProvisionMigration is the source and the client image of a migration
+// Job.
+type userProvisionMigration struct {
+	SourceHost string
+	Image      string
+}
+
+// userProvisionBackup is the destination, retention and client image of a
+// backup Job. StorageGB is the server's storage size, which bounds the dump
+// and so the size of the Job's work volume; zero leaves it unbounded.
+type userProvisionBackup struct {
+	ContainerURL  string
+	Prefix        string
+	RetentionDays int32
+	Image         string
+	StorageGB     int32
+}
+
+// userProvisionSchemaMigrations is the source of a schema migration Job:
+// the directory Path in Image, or the ConfigMap named ConfigMapName.
+type userProvisionSchemaMigrations struct {
+	Image         string
+	Path          string
+	ConfigMapName string
 }
 
 type userProvisionJobReconciler interface {
@@ -65,6 +122,7 @@ type userProvisionJobReconciler interface {
 	userProvisionJobScheme() *runtime.Scheme
 	userProvisionJobImage() string
 	userProvisionJobUseAzFakes() bool
+	userProvisionJobRecorder() events.EventRecorder
 }
 
 func (r *DatabaseReconciler) userProvisionJobScheme() *runtime.Scheme {
@@ -79,6 +137,10 @@ func (r *DatabaseReconciler) userProvisionJobUseAzFakes() bool {
 	return r.Config.UseAzFakes
 }
 
+func (r *DatabaseReconciler) userProvisionJobRecorder() events.EventRecorder {
+	return r.Recorder
+}
+
 func ensureUserProvisionJobForReconciler(
 	ctx context.Context,
 	logger logr.Logger,
@@ -144,36 +206,8 @@ func ensureUserProvisionJobForReconciler(
 
 	job := buildUserProvisionJob(ns, jobName, image, labels, spec, parallelism, completions, ttlSeconds)
 
-	// If we're using AzFakes, we need to disable AAD authentication in the provisioner
-	// since we're running on Kind. We also connect as a dedicated NON-superuser admin
-	// instead of the bootstrap "postgres" superuser. Azure PostgreSQL's Entra admin is
-	// a non-superuser CREATEROLE role, which (unlike a superuser) is auto-granted
-	// membership in every role it creates. Connecting as a superuser hides that, so the
-	// Kind environment must mirror the non-superuser admin to exercise that code path.
 	if useAzFakes {
-		job.Spec.Template.Spec.Containers[0].Env = append(
-			job.Spec.Template.Spec.Containers[0].Env,
-			corev1.EnvVar{
-				Name:  dbUtil.DisableAADEnv,
-				Value: "1",
-			},
-			corev1.EnvVar{
-				Name:  dbUtil.DBAdminUserEnv,
-				Value: kindProvisionAdminUser,
-			},
-		)
-		job.Spec.Template.Spec.InitContainers = append(
-			job.Spec.Template.Spec.InitContainers,
-			corev1.Container{
-				Name:  "wait-for-postgres",
-				Image: "postgres:16",
-				Command: []string{
-					"sh",
-					"-c",
-					"until pg_isready -h postgres.default.svc -p 5432; do sleep 2; done",
-				},
-			},
-		)
+		applyUserProvisionFakes(&job.Spec.Template.Spec)
 	}
 
 	if err := controllerutil.SetControllerReference(spec.Owner, job, r.userProvisionJobScheme()); err != nil {
@@ -193,10 +227,56 @@ func ensureUserProvisionJobForReconciler(
 		}
 		return fmt.Errorf("create user provisioning Job %s/%s: %w", ns, jobName, err)
 	}
+	recordEvent(r.userProvisionJobRecorder(), spec.Owner, job, corev1.EventTypeNormal,
+		eventReasonProvisionJobCreated, eventActionProvision,
+		"Created %s provisioning Job %s", provisionJobPhase(job.Labels), jobName)
 
 	return nil
 }
 
+// applyUserProvisionFakes points a provisioning Pod at the in-cluster
+// PostgreSQL of a Kind environment. AAD authentication is disabled in the
+// provisioner, and it connects as a dedicated NON-superuser admin instead of
+// the bootstrap "postgres" superuser. Azure PostgreSQL's Entra admin is a
+// non-superuser CREATEROLE role, which (unlike a superuser) is auto-granted
+// membership in every role it creates. Connecting as a superuser hides that,
+// so the Kind environment must mirror the non-superuser admin to exercise
+// that code path.
+func applyUserProvisionFakes(podSpec *corev1.PodSpec) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			switch containers[i].Name {
+			case userProvisionContainerName, migrationPrepareContainerName, backupCredentialsContainerName:
+				containers[i].Env = append(
+					containers[i].Env,
+					corev1.EnvVar{
+						Name:  dbUtil.DisableAADEnv,
+						Value: "1",
+					},
+					corev1.EnvVar{
+						Name:  dbUtil.DBAdminUserEnv,
+						Value: kindProvisionAdminUser,
+					},
+				)
+			case migrationCopyContainerName, backupDumpContainerName:
+				setContainerEnv(&containers[i], "PGSSLMODE", "disable")
+			}
+		}
+	}
+	podSpec.InitContainers = append(
+		[]corev1.Container{{
+			Name:  "wait-for-postgres",
+			Image: "postgres:16",
+			Command: []string{
+				"sh",
+				"-c",
+				"until pg_isready -h postgres.default.svc -p 5432; do sleep 2; done",
+			},
+		}},
+		podSpec.InitContainers...,
+	)
+}
+
 func validateUserProvisionJobSpec(spec userProvisionJobSpec, useAzFakes bool) error {
 	if spec.ServiceAccountName == "" {
 		return fmt.Errorf("serviceAccountName must be set for user provisioning")
@@ -207,17 +287,73 @@ func validateUserProvisionJobSpec(spec userProvisionJobSpec, useAzFakes bool) er
 	if spec.ServerName == "" {
 		return fmt.Errorf("server name must be set for user provisioning")
 	}
-	// Server debug access is server-wide: it has no schema and does not use the
-	// per-principal Role field, so those checks are skipped for it below.
-	if !spec.ServerDebugAccess && spec.SchemaName == "" {
-		return fmt.Errorf("schema name must be set for user provisioning")
+	// Every mode but server debug access, the catalog and the upgrade
+	// pre-check works on one database; those three are server-wide.
+	switch spec.Mode {
+	case dbUtil.ProvisionModeServerDebugAccess, dbUtil.ProvisionModeDatabaseCatalog, dbUtil.ProvisionModeUpgradePreCheck:
+	default:
+		if spec.SchemaName == "" {
+			return fmt.Errorf("schema name must be set for user provisioning")
+		}
 	}
-	if spec.ServerDebugAccess && len(spec.DebugBuiltinRoles) == 0 {
-		return fmt.Errorf("at least one built-in role must be set for server debug access provisioning")
+
+	// needsPrincipals marks the modes that read the access payload and need
+	// at least one principal. Server debug access reads it but allows an
+	// empty set: the revocation Job runs with zero principals so the
+	// membership reconcile revokes everyone.
+	needsPrincipals := false
+	switch spec.Mode {
+	case dbUtil.ProvisionModeAccess, dbUtil.ProvisionModeAccessAudit:
+		needsPrincipals = true
+	case dbUtil.ProvisionModeRevokeAllAccess:
+		if len(spec.AccessPrincipals) > 0 {
+			return fmt.Errorf("access principals must be empty when revoking all access")
+		}
+	case dbUtil.ProvisionModeServerDebugAccess:
+		if len(spec.DebugBuiltinRoles) == 0 {
+			return fmt.Errorf("at least one built-in role must be set for server debug access provisioning")
+		}
+	case dbUtil.ProvisionModeDropDatabase:
+		if spec.DatabaseName == "" {
+			return fmt.Errorf("database name must be set for drop database provisioning")
+		}
+	case dbUtil.ProvisionModeDatabaseCatalog, dbUtil.ProvisionModeUpgradePreCheck:
+	case dbUtil.ProvisionModeMigration:
+		needsPrincipals = true
+		if spec.Migration == nil || spec.DatabaseName == "" || spec.DatabaseHost == "" || spec.Migration.SourceHost == "" {
+			return fmt.Errorf("database name, target host and source host must be set for migration")
+		}
+		if strings.TrimSpace(spec.Migration.Image) == "" {
+			return fmt.Errorf("migration image must be set for migration")
+		}
+	case dbUtil.ProvisionModeMigrationRelease:
+		if spec.DatabaseName == "" || spec.DatabaseHost == "" {
+			return fmt.Errorf("database name and host must be set for migration release")
+		}
+	case dbUtil.ProvisionModeBackup:
+		if spec.DatabaseName == "" || spec.DatabaseHost == "" {
+			return fmt.Errorf("database name and host must be set for backup")
+		}
+		if spec.Backup == nil || spec.Backup.ContainerURL == "" || spec.Backup.Prefix == "" || strings.TrimSpace(spec.Backup.Image) == "" {
+			return fmt.Errorf("container URL, prefix and image must be set for backup")
+		}
+	case dbUtil.ProvisionModeSchemaMigrations:
+		if spec.DatabaseName == "" {
+			return fmt.Errorf("database name must be set for schema migrations")
+		}
+		if spec.AdminIdentityName == "" {
+			return fmt.Errorf("the Owner principal must be set for schema migrations")
+		}
+		if spec.SchemaMigrations == nil || (spec.SchemaMigrations.Image == "") == (spec.SchemaMigrations.ConfigMapName == "") {
+			return fmt.Errorf("exactly one of image and ConfigMap must be set for schema migrations")
+		}
+		if spec.SchemaMigrations.Image != "" && spec.SchemaMigrations.Path == "" {
+			return fmt.Errorf("path must be set for schema migrations from an image")
+		}
+	default:
+		return fmt.Errorf("unknown user provisioning mode %q", spec.Mode)
 	}
-	// Server debug access allows an empty principal set: the revocation Job runs
-	// with zero principals so the membership reconcile revokes everyone.
-	if len(spec.AccessPrincipals) == 0 && !spec.ServerDebugAccess {
+	if needsPrincipals && len(spec.AccessPrincipals) == 0 {
 		return fmt.Errorf("at least one access principal must be set for user provisioning")
 	}
 	for i, principal := range spec.AccessPrincipals {
@@ -232,7 +368,7 @@ func validateUserProvisionJobSpec(spec userProvisionJobSpec, useAzFakes bool) er
 		default:
 			return fmt.Errorf("access principal %d principal type must be service or group", i)
 		}
-		if spec.ServerDebugAccess {
+		if spec.Mode == dbUtil.ProvisionModeServerDebugAccess {
 			continue
 		}
 		switch principal.Role {
@@ -244,6 +380,9 @@ func validateUserProvisionJobSpec(spec userProvisionJobSpec, useAzFakes bool) er
 	if _, err := dbUtil.MarshalAccessPrincipals(spec.AccessPrincipals); err != nil {
 		return err
 	}
+	if _, err := dbUtil.MarshalDatabaseExtensions(spec.Extensions); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -271,7 +410,7 @@ func buildUserProvisionJob(
 	}
 	maps.Copy(podLabels, labels)
 
-	return &batchv1.Job{
+	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      jobName,
 			Namespace: namespace,
@@ -290,7 +429,7 @@ func buildUserProvisionJob(
 					RestartPolicy:      corev1.RestartPolicyOnFailure,
 					Containers: []corev1.Container{
 						{
-							Name:  "provision-user",
+							Name:  userProvisionContainerName,
 							Image: image,
 							Args:  []string{"--provision-user"},
 							Env:   userProvisionJobEnv(spec),
@@ -300,6 +439,15 @@ func buildUserProvisionJob(
 			},
 		},
 	}
+	switch spec.Mode {
+	case dbUtil.ProvisionModeMigration:
+		addMigrationContainers(&job.Spec.Template.Spec, image, spec)
+	case dbUtil.ProvisionModeBackup:
+		addBackupContainers(&job.Spec.Template.Spec, image, spec)
+	case dbUtil.ProvisionModeSchemaMigrations:
+		addSchemaMigrationsSource(&job.Spec.Template, spec.SchemaMigrations)
+	}
+	return job
 }
 
 func userProvisionJobEnv(spec userProvisionJobSpec) []corev1.EnvVar {
@@ -311,6 +459,7 @@ func userProvisionJobEnv(spec userProvisionJobSpec) []corev1.EnvVar {
 	}
 
 	env := []corev1.EnvVar{
+		{Name: dbUtil.ProvisionModeEnv, Value: string(spec.Mode)},
 		{Name: dbUtil.AdminAppIdentityEnv, Value: spec.AdminIdentityName},
 		{Name: dbUtil.DatabaseServerNameEnv, Value: spec.ServerName},
 		{Name: dbUtil.DBSchemaEnv, Value: spec.SchemaName},
@@ -328,11 +477,16 @@ func userProvisionJobEnv(spec userProvisionJobSpec) []corev1.EnvVar {
 	if spec.SearchPathScope != "" {
 		env = append(env, corev1.EnvVar{Name: dbUtil.DBSearchPathScopeEnv, Value: spec.SearchPathScope})
 	}
-	if spec.ServerDebugAccess {
-		env = append(env,
-			corev1.EnvVar{Name: dbUtil.ServerDebugAccessEnv, Value: "1"},
-			corev1.EnvVar{Name: dbUtil.DebugBuiltinRolesEnv, Value: strings.Join(spec.DebugBuiltinRoles, ",")},
-		)
+	if len(spec.DebugBuiltinRoles) > 0 {
+		env = append(env, corev1.EnvVar{Name: dbUtil.DebugBuiltinRolesEnv, Value: strings.Join(spec.DebugBuiltinRoles, ",")})
+	}
+	if spec.AccessAuditEnforce {
+		env = append(env, corev1.EnvVar{Name: dbUtil.AccessAuditEnforceEnv, Value: "1"})
+	}
+	if len(spec.Extensions) > 0 {
+		// Validated by validateUserProvisionJobSpec, like the access payload.
+		extensions, _ := dbUtil.MarshalDatabaseExtensions(spec.Extensions)
+		env = append(env, corev1.EnvVar{Name: dbUtil.DatabaseExtensionsEnv, Value: extensions})
 	}
 	return env
 }
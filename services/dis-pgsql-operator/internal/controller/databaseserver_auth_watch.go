@@ -49,7 +49,7 @@ func (r *DatabaseServerReconciler) mapDatabaseToDatabaseServer(
 	if !ok {
 		return nil
 	}
-	serverName := database.Spec.Server.Name
+	serverName := databaseServerName(database)
 	if serverName == "" {
 		return nil
 	}
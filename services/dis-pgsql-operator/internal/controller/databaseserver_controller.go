@@ -17,10 +17,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	authorizationv1 "github.com/Azure/azure-service-operator/v2/api/authorization/v1api20220401"
 	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
 	networkv1 "github.com/Azure/azure-service-operator/v2/api/network/v1api20240601"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -30,6 +32,8 @@ import (
 
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/keyvault"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/network"
 )
 
@@ -57,6 +61,27 @@ const (
 	// blocked state the author must resolve by choosing a unique DatabaseServer name.
 	databaseServerReasonServerNameConflict = "ServerNameConflict"
 
+	// databaseServerReasonInvalidProfile marks a DatabaseServer whose spec.profile
+	// is not configured on the operator.
+	databaseServerReasonInvalidProfile = "InvalidProfile"
+
+	// databaseServerReasonInvalidConnectionPooling marks a spec.connectionPooling
+	// the selected profile cannot run, such as PgBouncer on the Burstable tier.
+	databaseServerReasonInvalidConnectionPooling = "InvalidConnectionPooling"
+
+	// databaseServerReasonInvalidGeoRedundantBackup marks a
+	// spec.geoRedundantBackup the selected profile does not allow.
+	databaseServerReasonInvalidGeoRedundantBackup = "InvalidGeoRedundantBackup"
+
+	// databaseServerReasonInvalidEncryption marks a spec.encryption combined
+	// with spec.geoRedundantBackup, whose backups the operator cannot encrypt.
+	databaseServerReasonInvalidEncryption = "InvalidEncryption"
+
+	// databaseServerReasonInvalidServerParameters marks spec.serverParams
+	// entries that are not in the server parameter catalog for spec.version,
+	// or whose value is out of range.
+	databaseServerReasonInvalidServerParameters = "InvalidServerParameters"
+
 	// azureReasonServerNameAlreadyExists is the reason ASO sets on the FlexibleServer's
 	// Ready condition when Azure rejects the create because the name is already in use.
 	azureReasonServerNameAlreadyExists = "ServerNameAlreadyExists"
@@ -82,6 +107,18 @@ type DatabaseServerReconciler struct {
 	// into the reconciler.
 	SubnetCatalog *network.SubnetCatalog
 
+	// APIReader reads objects the manager does not cache, such as the Pods of
+	// the database catalog Job. The cached client is used when it is unset.
+	APIReader client.Reader
+
+	// Recorder records lifecycle Events on DatabaseServers. No Events are
+	// recorded when it is unset.
+	Recorder events.EventRecorder
+
+	// KeyClient creates and reads customer-managed keys in dis-vault Vaults.
+	// Servers with spec.encryption wait for it when it is unset.
+	KeyClient keyvault.KeyClient
+
 	Config config.OperatorConfig
 }
 
@@ -119,6 +156,15 @@ type DatabaseServerReconciler struct {
 // ApplicationIdentity (dis-application)
 // +kubebuilder:rbac:groups=application.dis.altinn.cloud,resources=applicationidentities,verbs=get;list;watch
 
+// Vault (dis-vault), for customer-managed keys
+// +kubebuilder:rbac:groups=vault.dis.altinn.cloud,resources=vaults,verbs=get;list;watch
+
+// Database catalog and upgrade pre-check Job Pods (termination message)
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+
+// Lifecycle Events
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
 func (r *DatabaseServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("databaseServer", req.NamespacedName)
 
@@ -145,6 +191,20 @@ func (r *DatabaseServerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, nil
 	}
 
+	// An invalid spec is fixed by a spec change, or by a profile change that
+	// restarts the operator, and both re-trigger reconciliation.
+	if reason, message := r.validateDatabaseServer(&db); reason != "" {
+		logger.Info("DatabaseServer spec is invalid", "reason", reason, "message", message)
+		if reason == databaseServerReasonInvalidServerParameters &&
+			conditionChanged(db.Status.Conditions, databaseServerConditionReady, reason, message) {
+			recordWarningEvent(r.Recorder, &db, eventReasonServerParameterRejected, eventActionApplyParameters, "%s", message)
+		}
+		if err := r.setDatabaseServerReadyCondition(ctx, &db, metav1.ConditionFalse, reason, message); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	if databaseServerMode(&db) == storagev1alpha1.DatabaseServerModeShared {
 		return r.reconcileSharedDatabaseServer(ctx, logger, &db)
 	}
@@ -194,15 +254,26 @@ func (r *DatabaseServerReconciler) deleteDedicatedNetworkChildren(
 	db *storagev1alpha1.DatabaseServer,
 ) (pending bool, err error) {
 	// First wave: the resources that block the private DNS zone deletion in Azure.
-	firstWaveGone := true
-	for _, child := range []struct {
+	// Read replicas share the primary's private DNS zone, so they go with it.
+	type ownedChild struct {
 		name string
 		obj  client.Object
-	}{
+	}
+	firstWave := []ownedChild{
 		{db.Name, &dbforpostgresqlv1.FlexibleServer{}},
 		{dbVNetLinkNameForDatabaseServer(db), &networkv1.PrivateDnsZonesVirtualNetworkLink{}},
 		{aksVNetLinkNameForDatabaseServer(db), &networkv1.PrivateDnsZonesVirtualNetworkLink{}},
-	} {
+	}
+	replicas, err := r.listReadReplicas(ctx, db)
+	if err != nil {
+		return false, err
+	}
+	for i := range replicas {
+		firstWave = append(firstWave, ownedChild{replicas[i].Name, &dbforpostgresqlv1.FlexibleServer{}})
+	}
+
+	firstWaveGone := true
+	for _, child := range firstWave {
 		gone, err := r.ensureChildDeleted(ctx, logger, db.Namespace, child.name, child.obj)
 		if err != nil {
 			return false, err
@@ -265,6 +336,36 @@ func (r *DatabaseServerReconciler) ensureChildDeleted(
 	return false, nil
 }
 
+// validateDatabaseServer checks the parts of the spec that depend on the
+// operator configuration, the server parameter catalog or the running
+// version, which the CRD schema cannot see. It returns the Ready reason and
+// message for an invalid spec, or an empty reason.
+func (r *DatabaseServerReconciler) validateDatabaseServer(db *storagev1alpha1.DatabaseServer) (string, string) {
+	profile, err := resolveServerProfile(r.Config, db)
+	if err != nil {
+		return databaseServerReasonInvalidProfile, err.Error()
+	}
+	if _, err := dbUtil.ResolveConnectionPooling(profile, db.Spec.ConnectionPooling); err != nil {
+		return databaseServerReasonInvalidConnectionPooling, err.Error()
+	}
+	if _, err := dbUtil.ResolveGeoRedundantBackup(profile, db.Spec.GeoRedundantBackup); err != nil {
+		return databaseServerReasonInvalidGeoRedundantBackup, err.Error()
+	}
+	if db.Spec.Encryption != nil && db.Spec.GeoRedundantBackup != nil && *db.Spec.GeoRedundantBackup {
+		return databaseServerReasonInvalidEncryption, "spec.encryption cannot be combined with spec.geoRedundantBackup"
+	}
+	if errs := dbUtil.ValidateServerParameters(db.Spec.Version, db.Spec.ServerParams, field.NewPath("spec", "serverParams")); len(errs) > 0 {
+		return databaseServerReasonInvalidServerParameters, errs.ToAggregate().Error()
+	}
+	if db.Status.Version > 0 && db.Spec.Version < db.Status.Version {
+		return databaseServerReasonVersionDowngrade, fmt.Sprintf(
+			"spec.version %d is below the running version %d; PostgreSQL cannot be downgraded",
+			db.Spec.Version, db.Status.Version,
+		)
+	}
+	return "", ""
+}
+
 func databaseServerMode(db *storagev1alpha1.DatabaseServer) storagev1alpha1.DatabaseServerMode {
 	if db.Spec.Mode == storagev1alpha1.DatabaseServerModeShared {
 		return storagev1alpha1.DatabaseServerModeShared
@@ -368,12 +469,30 @@ func (r *DatabaseServerReconciler) reconcileDedicatedDatabaseServer(
 		return ctrl.Result{}, err
 	}
 
-	if err := r.ensurePostgresServer(ctx, logger, db, networkConfig); err != nil {
+	restore, blocked, err := r.resolvePostgresServerRestore(ctx, logger, db)
+	if err != nil {
+		logger.Error(err, "failed to resolve point-in-time restore for database server")
+		return ctrl.Result{}, err
+	}
+	if blocked {
+		return ctrl.Result{RequeueAfter: restoreBlockedRequeueInterval}, nil
+	}
+
+	encryption, blocked, encryptionRequeue, err := r.ensureServerEncryption(ctx, logger, db)
+	if err != nil {
+		logger.Error(err, "failed to resolve customer-managed key for database server")
+		return ctrl.Result{}, err
+	}
+	if blocked {
+		return ctrl.Result{RequeueAfter: encryptionRequeue}, nil
+	}
+
+	if err := r.ensurePostgresServer(ctx, logger, db, networkConfig, restore, encryption); err != nil {
 		logger.Error(err, "failed to ensure PostgreSQLFlexibleServer for database server")
 		return ctrl.Result{}, err
 	}
 
-	return r.reconcileCommonDatabaseServerResources(ctx, logger, db)
+	return r.reconcileCommonDatabaseServerResources(ctx, logger, db, encryptionRequeue)
 }
 
 func (r *DatabaseServerReconciler) reconcileSharedDatabaseServer(
@@ -387,18 +506,37 @@ func (r *DatabaseServerReconciler) reconcileSharedDatabaseServer(
 		return ctrl.Result{}, err
 	}
 
-	if err := r.ensurePostgresServer(ctx, logger, db, networkConfig); err != nil {
+	restore, blocked, err := r.resolvePostgresServerRestore(ctx, logger, db)
+	if err != nil {
+		logger.Error(err, "failed to resolve point-in-time restore for shared database server")
+		return ctrl.Result{}, err
+	}
+	if blocked {
+		return ctrl.Result{RequeueAfter: restoreBlockedRequeueInterval}, nil
+	}
+
+	encryption, blocked, encryptionRequeue, err := r.ensureServerEncryption(ctx, logger, db)
+	if err != nil {
+		logger.Error(err, "failed to resolve customer-managed key for shared database server")
+		return ctrl.Result{}, err
+	}
+	if blocked {
+		return ctrl.Result{RequeueAfter: encryptionRequeue}, nil
+	}
+
+	if err := r.ensurePostgresServer(ctx, logger, db, networkConfig, restore, encryption); err != nil {
 		logger.Error(err, "failed to ensure PostgreSQLFlexibleServer for shared database server")
 		return ctrl.Result{}, err
 	}
 
-	return r.reconcileCommonDatabaseServerResources(ctx, logger, db)
+	return r.reconcileCommonDatabaseServerResources(ctx, logger, db, encryptionRequeue)
 }
 
 func (r *DatabaseServerReconciler) reconcileCommonDatabaseServerResources(
 	ctx context.Context,
 	logger logr.Logger,
 	db *storagev1alpha1.DatabaseServer,
+	encryptionRequeue time.Duration,
 ) (ctrl.Result, error) {
 	// Surface a blocked/failed FlexibleServer (e.g. a globally-taken server name) on the
 	// DatabaseServer before reconciling the server's child resources. The owned
@@ -458,6 +596,20 @@ func (r *DatabaseServerReconciler) reconcileCommonDatabaseServerResources(
 		}
 	}
 
+	// Read replicas are auxiliary to the primary and never block Ready.
+	replicaRequeue, err := r.ensureReadReplicas(ctx, logger, db)
+	if err != nil {
+		logger.Error(err, "failed to ensure read replicas for database server")
+		return ctrl.Result{}, err
+	}
+
+	// Held restarting changes were released for the primary and its replicas
+	// alike, so the apply-now annotation is only removed once both are updated.
+	if err := r.consumeApplyNowAnnotation(ctx, logger, db); err != nil {
+		logger.Error(err, "failed to remove apply-now annotation from database server")
+		return ctrl.Result{}, err
+	}
+
 	if !r.Config.UseAzFakes {
 		ready, err := r.asoResourcesReady(ctx, logger, db)
 		if err != nil {
@@ -479,7 +631,31 @@ func (r *DatabaseServerReconciler) reconcileCommonDatabaseServerResources(
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// The orphaned-database catalog is informational and runs once the server is ready.
+	catalogRequeue, err := r.ensureDatabaseCatalog(ctx, logger, db, adminIdentity)
+	if err != nil {
+		logger.Error(err, "failed to ensure database catalog for database server")
+		return ctrl.Result{}, err
+	}
+
+	upgradeRequeue, err := r.ensureMajorVersionUpgrade(ctx, logger, db, adminIdentity)
+	if err != nil {
+		logger.Error(err, "failed to ensure major version upgrade for database server")
+		return ctrl.Result{}, err
+	}
+
+	// Held restarting changes are applied once the maintenance window opens,
+	// and debug access is revoked when its grant expires. The customer-managed
+	// key is read again to notice rotations and lost access.
+	now := time.Now()
+	return ctrl.Result{RequeueAfter: earliestRequeue(
+		catalogRequeue,
+		upgradeRequeue,
+		pendingChangesRequeue(db, now),
+		replicaRequeue,
+		debugAccessExpiryRequeue(db, now),
+		encryptionRequeue,
+	)}, nil
 }
 
 func (r *DatabaseServerReconciler) setDatabaseServerReadyCondition(
@@ -502,7 +678,11 @@ func (r *DatabaseServerReconciler) setDatabaseServerReadyCondition(
 		return nil
 	}
 
-	return r.Status().Update(ctx, db)
+	if err := r.Status().Update(ctx, db); err != nil {
+		return err
+	}
+	observeTimeToReady("DatabaseServer", previousStatus.Conditions, db.Status.Conditions, db.CreationTimestamp, time.Now())
+	return nil
 }
 
 func (r *DatabaseServerReconciler) allocateSubnetForDatabaseServer(
@@ -537,6 +717,8 @@ func (r *DatabaseServerReconciler) allocateSubnetForDatabaseServer(
 	if err := r.Status().Update(ctx, db); err != nil {
 		return fmt.Errorf("update database server status with SubnetCIDR: %w", err)
 	}
+	recordNormalEvent(r.Recorder, db, eventReasonSubnetAllocated, eventActionAllocateSubnet,
+		"Allocated subnet %s", free.CIDR)
 
 	return nil
 }
@@ -572,6 +754,13 @@ func (r *DatabaseServerReconciler) asoResourcesReady(
 		}
 	}
 
+	if server.Status.Version != nil {
+		if version := parseMajorVersion(string(*server.Status.Version)); version > 0 && version != db.Status.Version {
+			db.Status.Version = version
+			azureIdentityChanged = true
+		}
+	}
+
 	if server.Status.Id != nil {
 		if resourceID := strings.TrimSpace(*server.Status.Id); resourceID != "" && resourceID != db.Status.ResourceID {
 			db.Status.ResourceID = resourceID
@@ -579,7 +768,9 @@ func (r *DatabaseServerReconciler) asoResourcesReady(
 		}
 	}
 
-	if azureIdentityChanged {
+	restoreChanged := restoreStatusFromFlexibleServer(db, &server)
+
+	if azureIdentityChanged || restoreChanged {
 		if err := r.Status().Update(ctx, db); err != nil {
 			return false, fmt.Errorf("update database server status with Azure server identity: %w", err)
 		}
@@ -735,7 +926,15 @@ func (r *DatabaseServerReconciler) setDatabaseServerBlockedCondition(
 		return nil
 	}
 
-	return r.Status().Update(ctx, db)
+	if err := r.Status().Update(ctx, db); err != nil {
+		return err
+	}
+	// Only a new or changed block is recorded; the requeue while it persists
+	// leaves the status unchanged.
+	if conditionChanged(previousStatus.Conditions, databaseServerConditionReady, reason, message) {
+		recordWarningEvent(r.Recorder, db, eventReasonFlexibleServerBlocked, eventActionReconcile, "%s: %s", reason, message)
+	}
+	return nil
 }
 
 func (r *DatabaseServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+)
+
+const (
+	// databaseCatalogInterval is how often the catalog Job re-collects the
+	// databases on a shared server.
+	databaseCatalogInterval = 6 * time.Hour
+
+	databaseCatalogComponentLabelValue = "database-catalog"
+
+	// jobNameLabelKey is set by the Job controller on the Pods of a Job.
+	jobNameLabelKey = "batch.kubernetes.io/job-name"
+
+	// userProvisionContainerName is the container of the user provisioning Job
+	// (see buildUserProvisionJob).
+	userProvisionContainerName = "provision-user"
+)
+
+// ensureDatabaseCatalog keeps status.databaseCatalog on a shared server up to
+// date. Every databaseCatalogInterval it runs the provisioning Job in catalog
+// mode, reads the report from the Job's Pod termination message, and records
+// the databases that no Database resource targets, e.g. databases retained
+// after their Database was deleted. The catalog is informational and never
+// affects Ready. It returns when the next catalog is due, or zero while the
+// Job runs (the Owns(batchv1.Job) watch re-triggers the reconcile).
+func (r *DatabaseServerReconciler) ensureDatabaseCatalog(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	adminIdentity resolvedAdminIdentity,
+) (time.Duration, error) {
+	if databaseServerMode(db) != storagev1alpha1.DatabaseServerModeShared {
+		if db.Status.DatabaseCatalog == nil {
+			return 0, nil
+		}
+		db.Status.DatabaseCatalog = nil
+		return 0, r.Status().Update(ctx, db)
+	}
+
+	// Mirrors ensureDebugAccessProvisioning: under az fakes the provisioner
+	// falls back to the in-cluster Postgres and the host is never published.
+	if !r.Config.UseAzFakes && strings.TrimSpace(db.Status.Host) == "" {
+		logger.Info("DatabaseServer status host not populated yet; deferring database catalog")
+		return 0, nil
+	}
+
+	var lastObserved time.Time
+	if db.Status.DatabaseCatalog != nil && db.Status.DatabaseCatalog.ObservedTime != nil {
+		lastObserved = db.Status.DatabaseCatalog.ObservedTime.Time
+		if remaining := time.Until(lastObserved.Add(databaseCatalogInterval)); remaining > 0 {
+			return remaining, nil
+		}
+	}
+
+	jobName := databaseCatalogJobName(db, adminIdentity, lastObserved)
+	if err := ensureUserProvisionJobForReconciler(ctx, logger, r, userProvisionJobSpec{
+		Owner:              db,
+		JobName:            jobName,
+		Labels:             databaseCatalogJobLabels(db.Name),
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         db.Name,
+		DatabaseHost:       db.Status.Host,
+		DatabaseName:       debugAccessProvisionMaintenanceDatabase,
+		Mode:               dbUtil.ProvisionModeDatabaseCatalog,
+	}); err != nil {
+		return 0, err
+	}
+
+	payload, found, err := r.databaseCatalogJobResult(ctx, logger, db.Namespace, jobName)
+	if err != nil || !found {
+		return 0, err
+	}
+
+	databaseNames, err := r.serverDatabaseNames(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	previousStatus := db.Status.DeepCopy()
+	db.Status.DatabaseCatalog = buildDatabaseCatalog(db.Status.DatabaseCatalog, payload, databaseNames, metav1.Now())
+	if !apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		if err := r.Status().Update(ctx, db); err != nil {
+			return 0, fmt.Errorf("update database catalog status: %w", err)
+		}
+	}
+	logger.Info("collected database catalog",
+		"jobName", jobName,
+		"orphanedDatabases", len(db.Status.DatabaseCatalog.OrphanedDatabases),
+	)
+	return databaseCatalogInterval, nil
+}
+
+// databaseCatalogJobResult returns the report of a completed catalog Job. A
+// Job that has not completed, or whose Pods are already gone, reports
+// found=false; an unreadable report is logged and treated the same way so the
+// next Job replaces it.
+func (r *DatabaseServerReconciler) databaseCatalogJobResult(
+	ctx context.Context,
+	logger logr.Logger,
+	namespace string,
+	jobName string,
+) (dbUtil.DatabaseCatalogPayload, bool, error) {
+	message, found, err := r.jobTerminationReport(ctx, logger, namespace, jobName)
+	if err != nil || !found {
+		return dbUtil.DatabaseCatalogPayload{}, false, err
+	}
+	payload, err := dbUtil.UnmarshalDatabaseCatalog(message)
+	if err != nil {
+		logger.Error(err, "ignoring invalid database catalog report", "jobName", jobName)
+		return dbUtil.DatabaseCatalogPayload{}, false, nil
+	}
+	return payload, true, nil
+}
+
+func (r *DatabaseServerReconciler) jobTerminationReport(
+	ctx context.Context,
+	logger logr.Logger,
+	namespace string,
+	jobName string,
+) (string, bool, error) {
+	return readJobTerminationReport(ctx, logger, r.Client, r.apiReader(), namespace, jobName)
+}
+
+// readJobTerminationReport returns the termination message a completed
+// provisioning Job wrote as its report. A Job that has not completed, or
+// whose Pods are already gone, reports found=false. Pods are listed through
+// podReader, normally the uncached API reader.
+func readJobTerminationReport(
+	ctx context.Context,
+	logger logr.Logger,
+	jobReader client.Reader,
+	podReader client.Reader,
+	namespace string,
+	jobName string,
+) (string, bool, error) {
+	var job batchv1.Job
+	if err := jobReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: jobName}, &job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("get Job %s/%s: %w", namespace, jobName, err)
+	}
+	if !jobConditionTrue(&job, batchv1.JobComplete) {
+		return "", false, nil
+	}
+
+	var pods corev1.PodList
+	if err := podReader.List(ctx, &pods,
+		client.InNamespace(namespace),
+		client.MatchingLabels{jobNameLabelKey: jobName},
+	); err != nil {
+		return "", false, fmt.Errorf("list Pods of Job %s/%s: %w", namespace, jobName, err)
+	}
+
+	message, ok := jobTerminationMessage(pods.Items)
+	if !ok {
+		logger.Info("Job completed without a readable report", "jobName", jobName)
+		return "", false, nil
+	}
+	return message, true, nil
+}
+
+// apiReader returns the uncached reader, so reading Job Pods does not
+// start a cluster-wide Pod informer.
+func (r *DatabaseServerReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// jobTerminationMessage returns the termination message of the provisioning
+// container of a succeeded Job Pod.
+func jobTerminationMessage(pods []corev1.Pod) (string, bool) {
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != userProvisionContainerName || status.State.Terminated == nil {
+				continue
+			}
+			terminated := status.State.Terminated
+			if terminated.ExitCode == 0 && strings.TrimSpace(terminated.Message) != "" {
+				return terminated.Message, true
+			}
+		}
+	}
+	return "", false
+}
+
+// buildDatabaseCatalog turns a catalog report into status, keeping only the
+// databases not in managed. A database counts as connected at now when it has
+// open connections or its session counter grew since the previous catalog;
+// otherwise the previous lastConnectionTime is carried over.
+func buildDatabaseCatalog(
+	previous *storagev1alpha1.DatabaseServerDatabaseCatalog,
+	payload dbUtil.DatabaseCatalogPayload,
+	managed []string,
+	now metav1.Time,
+) *storagev1alpha1.DatabaseServerDatabaseCatalog {
+	managedSet := make(map[string]struct{}, len(managed))
+	for _, name := range managed {
+		managedSet[name] = struct{}{}
+	}
+	previousByName := map[string]storagev1alpha1.DatabaseServerOrphanedDatabase{}
+	if previous != nil {
+		for _, orphan := range previous.OrphanedDatabases {
+			previousByName[orphan.Name] = orphan
+		}
+	}
+
+	orphans := make([]storagev1alpha1.DatabaseServerOrphanedDatabase, 0, len(payload.Databases))
+	for _, database := range payload.Databases {
+		if _, ok := managedSet[database.Name]; ok {
+			continue
+		}
+		orphan := storagev1alpha1.DatabaseServerOrphanedDatabase{
+			Name:      database.Name,
+			SizeBytes: database.SizeBytes,
+			Sessions:  database.Sessions,
+		}
+		prev, seen := previousByName[database.Name]
+		if seen {
+			orphan.LastConnectionTime = prev.LastConnectionTime
+		}
+		if database.ActiveConnections > 0 || (seen && database.Sessions > prev.Sessions) {
+			orphan.LastConnectionTime = now.DeepCopy()
+		}
+		orphans = append(orphans, orphan)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Name < orphans[j].Name
+	})
+
+	return &storagev1alpha1.DatabaseServerDatabaseCatalog{
+		ObservedTime:      now.DeepCopy(),
+		Truncated:         payload.Truncated,
+		OrphanedDatabases: orphans,
+	}
+}
+
+// databaseCatalogJobName embeds the previous catalog time so each catalog
+// cycle gets a new Job, while retries within a cycle reuse the same one.
+func databaseCatalogJobName(
+	db *storagev1alpha1.DatabaseServer,
+	adminIdentity resolvedAdminIdentity,
+	lastObserved time.Time,
+) string {
+	observed := ""
+	if !lastObserved.IsZero() {
+		observed = lastObserved.UTC().Format(time.RFC3339)
+	}
+	payload := strings.Join([]string{
+		"server=" + db.Name,
+		"host=" + db.Status.Host,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"previous=" + observed,
+		"mode=catalog",
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	base := naming.EnsureLowerAlphaPrefix(naming.SanitizeLowerHyphen(db.Name), "db")
+	return naming.WithRequiredSuffix(base+"-catalog", "-"+hash, 63, "db")
+}
+
+func databaseCatalogJobLabels(serverName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		componentLabelKey:          databaseCatalogComponentLabelValue,
+	}
+}
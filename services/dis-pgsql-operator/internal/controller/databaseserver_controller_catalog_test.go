@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildDatabaseCatalog(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC))
+	previous := &storagev1alpha1.DatabaseServerDatabaseCatalog{
+		OrphanedDatabases: []storagev1alpha1.DatabaseServerOrphanedDatabase{
+			{Name: "idle", Sessions: 5, LastConnectionTime: &earlier},
+			{Name: "reused", Sessions: 5},
+		},
+	}
+	payload := dbUtil.DatabaseCatalogPayload{
+		Version: dbUtil.DatabaseCatalogPayloadVersion,
+		Databases: []dbUtil.CatalogDatabase{
+			{Name: "managed", SizeBytes: 100, ActiveConnections: 3},
+			{Name: "reused", SizeBytes: 20, Sessions: 6},
+			{Name: "idle", SizeBytes: 30, Sessions: 5},
+			{Name: "connected", SizeBytes: 40, ActiveConnections: 1},
+			{Name: "new", SizeBytes: 50, Sessions: 9},
+		},
+		Truncated: true,
+	}
+
+	catalog := buildDatabaseCatalog(previous, payload, []string{"managed"}, now)
+
+	if catalog.ObservedTime == nil || !catalog.ObservedTime.Equal(&now) {
+		t.Fatalf("expected observedTime %v, got %v", now, catalog.ObservedTime)
+	}
+	if !catalog.Truncated {
+		t.Fatalf("expected truncated flag to be carried over")
+	}
+
+	byName := map[string]storagev1alpha1.DatabaseServerOrphanedDatabase{}
+	names := make([]string, 0, len(catalog.OrphanedDatabases))
+	for _, orphan := range catalog.OrphanedDatabases {
+		byName[orphan.Name] = orphan
+		names = append(names, orphan.Name)
+	}
+	if got := strings.Join(names, ","); got != "connected,idle,new,reused" {
+		t.Fatalf("expected sorted orphans without managed databases, got %q", got)
+	}
+
+	cases := []struct {
+		name string
+		want *metav1.Time
+	}{
+		{name: "connected", want: &now},
+		{name: "reused", want: &now},
+		{name: "idle", want: &earlier},
+		{name: "new", want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := byName[tc.name].LastConnectionTime
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("expected no lastConnectionTime, got %v", got)
+				}
+				return
+			}
+			if got == nil || !got.Equal(tc.want) {
+				t.Fatalf("expected lastConnectionTime %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDatabaseCatalogTerminationMessage(t *testing.T) {
+	terminated := func(phase corev1.PodPhase, container string, exitCode int32, message string) corev1.Pod {
+		return corev1.Pod{Status: corev1.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: container,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: exitCode,
+					Message:  message,
+				}},
+			}},
+		}}
+	}
+
+	message, ok := jobTerminationMessage([]corev1.Pod{
+		terminated(corev1.PodFailed, userProvisionContainerName, 1, "boom"),
+		terminated(corev1.PodSucceeded, "wait-for-postgres", 0, "ignored"),
+		terminated(corev1.PodSucceeded, userProvisionContainerName, 0, `{"version":1}`),
+	})
+	if !ok || message != `{"version":1}` {
+		t.Fatalf("expected report from the succeeded provisioning container, got %q (ok=%t)", message, ok)
+	}
+
+	if _, ok := jobTerminationMessage([]corev1.Pod{
+		terminated(corev1.PodSucceeded, userProvisionContainerName, 0, ""),
+	}); ok {
+		t.Fatalf("expected no report for an empty termination message")
+	}
+}
+
+func TestDatabaseCatalogJobNamePerCycle(t *testing.T) {
+	db := testDebugJobServer()
+	admin := testDebugAdminIdentity()
+	observed := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	first := databaseCatalogJobName(db, admin, time.Time{})
+	if again := databaseCatalogJobName(db, admin, time.Time{}); again != first {
+		t.Fatalf("expected deterministic job name, got %q vs %q", first, again)
+	}
+	if len(first) > 63 || !strings.Contains(first, "-catalog-") {
+		t.Fatalf("expected bounded catalog job name, got %q", first)
+	}
+	if next := databaseCatalogJobName(db, admin, observed); next == first {
+		t.Fatalf("expected a new job name for the next catalog cycle, got %q", next)
+	}
+}
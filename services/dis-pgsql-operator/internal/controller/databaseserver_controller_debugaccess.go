@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"maps"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -24,10 +25,9 @@ import (
 )
 
 const (
-	// debugAccessComponentLabelKey marks RoleAssignments created for debug access
-	// so they can be listed and pruned independently of any other RoleAssignments
-	// the operator may own.
-	debugAccessComponentLabelKey   = "dis.altinn.cloud/component"
+	// debugAccessComponentLabelValue marks RoleAssignments and Jobs created for
+	// debug access so they can be listed and pruned independently of any other
+	// RoleAssignments the operator may own.
 	debugAccessComponentLabelValue = "debug-access"
 
 	// debugAccessReaderRole is the built-in Azure role granted to debug principals
@@ -53,20 +53,26 @@ type resolvedDebugAccessPrincipal struct {
 // that grant read-only debug access to this server's Flexible Server. Debug
 // access is only supported on dedicated servers; shared servers (and servers with
 // debugAccess unset or empty) converge to zero role assignments as the prune
-// removes any previously-created ones.
+// removes any previously-created ones. Principals whose grant in
+// status.debugAccessGrants has expired are left out of the desired set, so
+// their role assignment is pruned as well.
 func (r *DatabaseServerReconciler) ensureDebugAccessRoleAssignments(
 	ctx context.Context,
 	logger logr.Logger,
 	db *storagev1alpha1.DatabaseServer,
 ) error {
+	previousStatus := db.Status.DeepCopy()
+
 	// Debug-access role assignments are scoped to a dedicated server's Flexible
 	// Server. Shared servers must not own any, so leave the desired set empty for
 	// them and let the prune below converge any stale assignments to zero.
 	desired := map[string]*authorizationv1.RoleAssignment{}
 	var pendingIdentities []string
 	debugAccessConfigured := databaseServerMode(db) != storagev1alpha1.DatabaseServerModeShared && db.Spec.DebugAccess != nil
+	db.Status.DebugAccessGrants = nil
 	if debugAccessConfigured {
-		for _, principal := range db.Spec.DebugAccess.Principals {
+		db.Status.DebugAccessGrants = buildDebugAccessGrants(db.Spec.DebugAccess.Principals, previousStatus.DebugAccessGrants, time.Now())
+		for _, principal := range activeDebugAccessPrincipals(db) {
 			resolved, ok, err := r.resolveDebugAccessPrincipal(ctx, logger, db, principal)
 			if err != nil {
 				return err
@@ -99,24 +105,50 @@ func (r *DatabaseServerReconciler) ensureDebugAccessRoleAssignments(
 		return err
 	}
 
-	return r.setDebugAccessReadyCondition(ctx, db, debugAccessConfigured, pendingIdentities)
+	if err := r.setDebugAccessReadyCondition(ctx, db, previousStatus, debugAccessConfigured, pendingIdentities); err != nil {
+		return err
+	}
+
+	granted, revoked := debugAccessGrantChanges(previousStatus.DebugAccessGrants, db.Status.DebugAccessGrants)
+	for _, grant := range granted {
+		if grant.ExpiresAt != nil {
+			recordNormalEvent(r.Recorder, db, eventReasonDebugAccessGranted, eventActionGrantDebugAccess,
+				"Granted debug access to %s until %s", grant.Principal, grant.ExpiresAt.UTC().Format(time.RFC3339))
+			continue
+		}
+		recordNormalEvent(r.Recorder, db, eventReasonDebugAccessGranted, eventActionGrantDebugAccess,
+			"Granted debug access to %s", grant.Principal)
+	}
+	for _, grant := range revoked {
+		recordNormalEvent(r.Recorder, db, eventReasonDebugAccessRevoked, eventActionRevokeAccess,
+			"Revoked debug access for %s", grant.Principal)
+	}
+	return nil
 }
 
 // setDebugAccessReadyCondition records the DebugAccessReady condition on the
-// DatabaseServer status and persists it. It never touches the core Ready
-// condition. When debug access is not configured (unset or shared mode) the
-// condition is removed so no stale state lingers.
+// DatabaseServer status and persists it together with any other change since
+// previousStatus. It never touches the core Ready condition. When debug access
+// is not configured (unset or shared mode) the condition is removed so no
+// stale state lingers.
 func (r *DatabaseServerReconciler) setDebugAccessReadyCondition(
 	ctx context.Context,
 	db *storagev1alpha1.DatabaseServer,
+	previousStatus *storagev1alpha1.DatabaseServerStatus,
 	debugAccessConfigured bool,
 	pendingIdentities []string,
 ) error {
-	previousStatus := db.Status.DeepCopy()
-
 	switch {
 	case !debugAccessConfigured:
 		meta.RemoveStatusCondition(&db.Status.Conditions, databaseServerConditionDebugAccessReady)
+	case len(db.Status.DebugAccessGrants) > 0 && len(activeDebugAccessPrincipals(db)) == 0:
+		meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+			Type:               databaseServerConditionDebugAccessReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             databaseServerReasonDebugAccessExpired,
+			Message:            "All debug access grants have expired and are revoked",
+			ObservedGeneration: db.Generation,
+		})
 	case len(pendingIdentities) > 0:
 		meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
 			Type:    databaseServerConditionDebugAccessReady,
@@ -275,8 +307,8 @@ func (r *DatabaseServerReconciler) pruneDebugAccessRoleAssignments(
 		&list,
 		client.InNamespace(db.Namespace),
 		client.MatchingLabels{
-			databaseServerNameLabelKey:   db.Name,
-			debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+			databaseServerNameLabelKey: db.Name,
+			componentLabelKey:          debugAccessComponentLabelValue,
 		},
 	); err != nil {
 		return fmt.Errorf("list debug access RoleAssignments: %w", err)
@@ -334,8 +366,8 @@ func buildDebugAccessRoleAssignment(
 			Name:      debugAccessRoleAssignmentName(db.Name, principalID),
 			Namespace: db.Namespace,
 			Labels: map[string]string{
-				databaseServerNameLabelKey:   db.Name,
-				debugAccessComponentLabelKey: debugAccessComponentLabelValue,
+				databaseServerNameLabelKey: db.Name,
+				componentLabelKey:          debugAccessComponentLabelValue,
 			},
 		},
 		Spec: authorizationv1.RoleAssignment_Spec{
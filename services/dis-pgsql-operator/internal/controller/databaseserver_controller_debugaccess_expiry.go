@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+// databaseServerReasonDebugAccessExpired marks that every debug access grant
+// has expired and been revoked.
+const databaseServerReasonDebugAccessExpired = "Expired"
+
+// debugAccessPrincipalKey identifies a spec principal in
+// status.debugAccessGrants. Groups and service principals are keyed by object
+// id, so renaming them keeps their grant.
+func debugAccessPrincipalKey(principal storagev1alpha1.DebugAccessPrincipalSpec) string {
+	switch {
+	case principal.IdentityRef != nil:
+		return "identityRef/" + strings.TrimSpace(principal.IdentityRef.Name)
+	case principal.Group != nil:
+		return "group/" + strings.ToLower(strings.TrimSpace(principal.Group.PrincipalId))
+	case principal.ServicePrincipal != nil:
+		return "servicePrincipal/" + strings.ToLower(strings.TrimSpace(principal.ServicePrincipal.PrincipalId))
+	default:
+		return ""
+	}
+}
+
+// buildDebugAccessGrants returns the grant of every spec principal at now.
+// A grant keeps its grantedAt while its justification is unchanged, so a
+// duration counts from the first grant and reconciles do not extend it;
+// a new justification starts a new grant.
+func buildDebugAccessGrants(
+	principals []storagev1alpha1.DebugAccessPrincipalSpec,
+	previous []storagev1alpha1.DatabaseServerDebugAccessGrant,
+	now time.Time,
+) []storagev1alpha1.DatabaseServerDebugAccessGrant {
+	previousByPrincipal := make(map[string]storagev1alpha1.DatabaseServerDebugAccessGrant, len(previous))
+	for _, grant := range previous {
+		previousByPrincipal[grant.Principal] = grant
+	}
+
+	grants := make([]storagev1alpha1.DatabaseServerDebugAccessGrant, 0, len(principals))
+	seen := make(map[string]struct{}, len(principals))
+	for _, principal := range principals {
+		key := debugAccessPrincipalKey(principal)
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		grant := storagev1alpha1.DatabaseServerDebugAccessGrant{
+			Principal:     key,
+			GrantedAt:     metav1.NewTime(now),
+			Justification: principal.Justification,
+		}
+		if prev, ok := previousByPrincipal[key]; ok && prev.Justification == principal.Justification {
+			grant.GrantedAt = prev.GrantedAt
+		}
+
+		switch {
+		case principal.ExpiresAt != nil:
+			grant.ExpiresAt = principal.ExpiresAt.DeepCopy()
+		case principal.Duration != nil:
+			expiresAt := metav1.NewTime(grant.GrantedAt.Add(principal.Duration.Duration))
+			grant.ExpiresAt = &expiresAt
+		}
+		grant.Expired = grant.ExpiresAt != nil && !now.Before(grant.ExpiresAt.Time)
+		grants = append(grants, grant)
+	}
+	return grants
+}
+
+// activeDebugAccessPrincipals returns the spec principals whose grant in
+// status.debugAccessGrants has not expired. Principals without a grant yet
+// are active.
+func activeDebugAccessPrincipals(db *storagev1alpha1.DatabaseServer) []storagev1alpha1.DebugAccessPrincipalSpec {
+	if db.Spec.DebugAccess == nil {
+		return nil
+	}
+	expired := make(map[string]struct{}, len(db.Status.DebugAccessGrants))
+	for _, grant := range db.Status.DebugAccessGrants {
+		if grant.Expired {
+			expired[grant.Principal] = struct{}{}
+		}
+	}
+
+	active := make([]storagev1alpha1.DebugAccessPrincipalSpec, 0, len(db.Spec.DebugAccess.Principals))
+	for _, principal := range db.Spec.DebugAccess.Principals {
+		if _, ok := expired[debugAccessPrincipalKey(principal)]; ok {
+			continue
+		}
+		active = append(active, principal)
+	}
+	return active
+}
+
+// debugAccessExpiryRequeue returns the time until the next active grant
+// expires, or zero when no grant expires.
+func debugAccessExpiryRequeue(db *storagev1alpha1.DatabaseServer, now time.Time) time.Duration {
+	var next time.Duration
+	for _, grant := range db.Status.DebugAccessGrants {
+		if grant.Expired || grant.ExpiresAt == nil {
+			continue
+		}
+		remaining := grant.ExpiresAt.Sub(now)
+		if remaining <= 0 {
+			remaining = time.Second
+		}
+		if next == 0 || remaining < next {
+			next = remaining
+		}
+	}
+	return next
+}
+
+// debugAccessGrantChanges returns the grants that started and the grants that
+// ended between previous and current. A grant starts when its principal is
+// added or a new justification restarts it, and ends when it expires or its
+// principal is removed.
+func debugAccessGrantChanges(
+	previous, current []storagev1alpha1.DatabaseServerDebugAccessGrant,
+) (granted, revoked []storagev1alpha1.DatabaseServerDebugAccessGrant) {
+	previousByPrincipal := make(map[string]storagev1alpha1.DatabaseServerDebugAccessGrant, len(previous))
+	for _, grant := range previous {
+		previousByPrincipal[grant.Principal] = grant
+	}
+
+	currentPrincipals := make(map[string]struct{}, len(current))
+	for _, grant := range current {
+		currentPrincipals[grant.Principal] = struct{}{}
+		prev, ok := previousByPrincipal[grant.Principal]
+		switch {
+		case grant.Expired:
+			if ok && !prev.Expired {
+				revoked = append(revoked, grant)
+			}
+		case !ok || !prev.GrantedAt.Equal(&grant.GrantedAt):
+			granted = append(granted, grant)
+		}
+	}
+	for _, grant := range previous {
+		if _, ok := currentPrincipals[grant.Principal]; !ok && !grant.Expired {
+			revoked = append(revoked, grant)
+		}
+	}
+	return granted, revoked
+}
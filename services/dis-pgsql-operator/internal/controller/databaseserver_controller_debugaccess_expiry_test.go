@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestBuildDebugAccessGrants(t *testing.T) {
+	now := time.Date(2026, 5, 20, 10, 0, 0, 0, time.UTC)
+	group := func(id string) *storagev1alpha1.DatabaseGroupPrincipalSpec {
+		return &storagev1alpha1.DatabaseGroupPrincipalSpec{Name: "dba", PrincipalId: id}
+	}
+	earlier := metav1.NewTime(now.Add(-90 * time.Minute))
+	expiresAt := metav1.NewTime(now.Add(30 * time.Minute))
+
+	principals := []storagev1alpha1.DebugAccessPrincipalSpec{
+		{Group: group("AAAAAAAA-0000-0000-0000-000000000001"), Duration: &metav1.Duration{Duration: time.Hour}, Justification: "INC-1"},
+		{Group: group("aaaaaaaa-0000-0000-0000-000000000002"), Duration: &metav1.Duration{Duration: time.Hour}, Justification: "INC-2"},
+		{IdentityRef: &storagev1alpha1.ApplicationIdentityRef{Name: "oncall"}, ExpiresAt: &expiresAt},
+		{ServicePrincipal: &storagev1alpha1.DatabaseServicePrincipalSpec{Name: "ops", PrincipalId: "aaaaaaaa-0000-0000-0000-000000000003"}},
+	}
+	previous := []storagev1alpha1.DatabaseServerDebugAccessGrant{
+		{Principal: "group/aaaaaaaa-0000-0000-0000-000000000001", GrantedAt: earlier, Justification: "INC-1"},
+		{Principal: "group/aaaaaaaa-0000-0000-0000-000000000002", GrantedAt: earlier, Justification: "INC-0"},
+	}
+
+	grants := buildDebugAccessGrants(principals, previous, now)
+	if len(grants) != 4 {
+		t.Fatalf("expected 4 grants, got %#v", grants)
+	}
+
+	if !grants[0].GrantedAt.Equal(&earlier) || !grants[0].Expired || !grants[0].ExpiresAt.Time.Equal(now.Add(-30*time.Minute)) {
+		t.Fatalf("expected the first grant to keep grantedAt and be expired, got %#v", grants[0])
+	}
+	if !grants[1].GrantedAt.Time.Equal(now) || grants[1].Expired || !grants[1].ExpiresAt.Time.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected a new justification to start a new grant, got %#v", grants[1])
+	}
+	if grants[2].Principal != "identityRef/oncall" || grants[2].Expired || !grants[2].ExpiresAt.Equal(&expiresAt) {
+		t.Fatalf("expected the identityRef grant to expire at expiresAt, got %#v", grants[2])
+	}
+	if grants[3].ExpiresAt != nil || grants[3].Expired {
+		t.Fatalf("expected standing access without expiry, got %#v", grants[3])
+	}
+
+	db := newDebugAccessDatabaseServer()
+	db.Spec.DebugAccess = &storagev1alpha1.DatabaseServerDebugAccessSpec{Principals: principals}
+	db.Status.DebugAccessGrants = grants
+	if active := activeDebugAccessPrincipals(db); len(active) != 3 || active[0].Group.PrincipalId != principals[1].Group.PrincipalId {
+		t.Fatalf("expected the expired principal to be left out, got %#v", active)
+	}
+	if requeue := debugAccessExpiryRequeue(db, now); requeue != 30*time.Minute {
+		t.Fatalf("expected a requeue at the next expiry, got %s", requeue)
+	}
+}
+
+func TestDebugAccessExpiryRequeueWithoutExpiry(t *testing.T) {
+	db := newDebugAccessDatabaseServer()
+	db.Status.DebugAccessGrants = []storagev1alpha1.DatabaseServerDebugAccessGrant{{Principal: "group/x"}}
+	if requeue := debugAccessExpiryRequeue(db, time.Now()); requeue != 0 {
+		t.Fatalf("expected no requeue for standing access, got %s", requeue)
+	}
+}
+
+func TestDebugAccessGrantChanges(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2026, 5, 20, 9, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(earlier.Add(time.Hour))
+	previous := []storagev1alpha1.DatabaseServerDebugAccessGrant{
+		{Principal: "group/kept", GrantedAt: earlier},
+		{Principal: "group/expiring", GrantedAt: earlier},
+		{Principal: "group/removed", GrantedAt: earlier},
+		{Principal: "group/restarted", GrantedAt: earlier, Expired: true},
+		{Principal: "group/long-expired", GrantedAt: earlier, Expired: true},
+	}
+	current := []storagev1alpha1.DatabaseServerDebugAccessGrant{
+		{Principal: "group/kept", GrantedAt: earlier},
+		{Principal: "group/expiring", GrantedAt: earlier, Expired: true},
+		{Principal: "group/restarted", GrantedAt: now},
+		{Principal: "group/long-expired", GrantedAt: earlier, Expired: true},
+		{Principal: "group/added", GrantedAt: now},
+	}
+
+	granted, revoked := debugAccessGrantChanges(previous, current)
+	if len(granted) != 2 || granted[0].Principal != "group/restarted" || granted[1].Principal != "group/added" {
+		t.Fatalf("expected the restarted and added grants to start, got %#v", granted)
+	}
+	if len(revoked) != 2 || revoked[0].Principal != "group/expiring" || revoked[1].Principal != "group/removed" {
+		t.Fatalf("expected the expired and removed grants to end, got %#v", revoked)
+	}
+}
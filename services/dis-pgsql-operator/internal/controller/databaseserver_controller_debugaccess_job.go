@@ -10,6 +10,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
@@ -55,7 +56,8 @@ const debugAccessProvisionMaintenanceDatabase = "postgres"
 // revocation Job runs with an empty principal set (the membership reconcile
 // then revokes every member) and the marker is cleared once that Job
 // completes. The managed debug role itself is kept: it is inert without
-// members.
+// members. Expired grants count as removed: when every principal has
+// expired, the revocation Job runs as if spec.debugAccess were unset.
 func (r *DatabaseServerReconciler) ensureDebugAccessProvisioning(
 	ctx context.Context,
 	logger logr.Logger,
@@ -72,7 +74,7 @@ func (r *DatabaseServerReconciler) ensureDebugAccessProvisioning(
 		return nil
 	}
 
-	if db.Spec.DebugAccess == nil || len(db.Spec.DebugAccess.Principals) == 0 {
+	if len(activeDebugAccessPrincipals(db)) == 0 {
 		if db.Status.DebugAccessProvisionedHash == "" {
 			// Never provisioned: nothing to revoke.
 			return nil
@@ -110,7 +112,7 @@ func (r *DatabaseServerReconciler) ensureDebugAccessProvisioning(
 		DatabaseHost:       db.Status.Host,
 		DatabaseName:       debugAccessProvisionMaintenanceDatabase,
 		AccessPrincipals:   accessPrincipals,
-		ServerDebugAccess:  true,
+		Mode:               dbUtil.ProvisionModeServerDebugAccess,
 		DebugBuiltinRoles:  builtinRoles,
 	}); err != nil {
 		return err
@@ -152,7 +154,7 @@ func (r *DatabaseServerReconciler) ensureDebugAccessRevocation(
 		DatabaseHost:       db.Status.Host,
 		DatabaseName:       debugAccessProvisionMaintenanceDatabase,
 		AccessPrincipals:   nil,
-		ServerDebugAccess:  true,
+		Mode:               dbUtil.ProvisionModeServerDebugAccess,
 		DebugBuiltinRoles:  builtinRoles,
 	})
 }
@@ -186,7 +188,7 @@ func (r *DatabaseServerReconciler) serverDatabaseNames(
 	names := make([]string, 0, len(databases.Items))
 	for i := range databases.Items {
 		item := databases.Items[i]
-		if item.Spec.Server.Name != db.Name {
+		if databaseServerName(&item) != db.Name {
 			continue
 		}
 		name := strings.TrimSpace(item.Spec.Name)
@@ -215,6 +217,10 @@ func (r *DatabaseServerReconciler) userProvisionJobUseAzFakes() bool {
 	return r.Config.UseAzFakes
 }
 
+func (r *DatabaseServerReconciler) userProvisionJobRecorder() events.EventRecorder {
+	return r.Recorder
+}
+
 // resolveDebugAccessDataPlanePrincipals resolves each debug principal to the
 // PostgreSQL principal fields the provisioner needs. It reuses the control-plane
 // resolveDebugAccessPrincipal and skips not-ready identityRefs (and any resolved
@@ -226,10 +232,11 @@ func (r *DatabaseServerReconciler) resolveDebugAccessDataPlanePrincipals(
 	logger logr.Logger,
 	db *storagev1alpha1.DatabaseServer,
 ) ([]dbUtil.AccessPrincipal, error) {
-	accessPrincipals := make([]dbUtil.AccessPrincipal, 0, len(db.Spec.DebugAccess.Principals))
+	principals := activeDebugAccessPrincipals(db)
+	accessPrincipals := make([]dbUtil.AccessPrincipal, 0, len(principals))
 	seen := map[string]struct{}{}
 
-	for _, principal := range db.Spec.DebugAccess.Principals {
+	for _, principal := range principals {
 		resolved, ok, err := r.resolveDebugAccessPrincipal(ctx, logger, db, principal)
 		if err != nil {
 			return nil, err
@@ -322,6 +329,6 @@ func debugAccessProvisionJobLabels(serverName string) map[string]string {
 	return map[string]string{
 		databaseServerNameLabelKey:      serverName,
 		databaseAccessProvisionLabelKey: labelValueTrue,
-		debugAccessComponentLabelKey:    debugAccessComponentLabelValue,
+		componentLabelKey:               debugAccessComponentLabelValue,
 	}
 }
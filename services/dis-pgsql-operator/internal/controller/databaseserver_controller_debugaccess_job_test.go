@@ -106,7 +106,7 @@ func TestDebugAccessProvisionJobLabels(t *testing.T) {
 	if labels[databaseServerNameLabelKey] != testDbgServerName {
 		t.Fatalf("expected server-name label, got %#v", labels)
 	}
-	if labels[debugAccessComponentLabelKey] != debugAccessComponentLabelValue {
+	if labels[componentLabelKey] != debugAccessComponentLabelValue {
 		t.Fatalf("expected debug-access component label, got %#v", labels)
 	}
 	if labels[databaseAccessProvisionLabelKey] != labelValueTrue {
@@ -133,13 +133,13 @@ func TestUserProvisionJobEnvServerDebugMode(t *testing.T) {
 		DatabaseHost:      testDebugJobHost,
 		DatabaseName:      "postgres",
 		AccessPrincipals:  testDebugPrincipals(),
-		ServerDebugAccess: true,
+		Mode:              dbUtil.ProvisionModeServerDebugAccess,
 		DebugBuiltinRoles: []string{debugBuiltinRolePgMonitor, debugBuiltinRolePgReadAllData},
 	})
 
 	values := envToMap(env)
-	if values[dbUtil.ServerDebugAccessEnv] != "1" {
-		t.Fatalf("expected %s=1, got %q", dbUtil.ServerDebugAccessEnv, values[dbUtil.ServerDebugAccessEnv])
+	if values[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeServerDebugAccess) {
+		t.Fatalf("expected %s=%s, got %q", dbUtil.ProvisionModeEnv, dbUtil.ProvisionModeServerDebugAccess, values[dbUtil.ProvisionModeEnv])
 	}
 	if values[dbUtil.DebugBuiltinRolesEnv] != debugBuiltinRolePgMonitor+","+debugBuiltinRolePgReadAllData {
 		t.Fatalf("expected built-in roles env, got %q", values[dbUtil.DebugBuiltinRolesEnv])
@@ -160,14 +160,15 @@ func TestUserProvisionJobEnvNonDebugOmitsDebugVars(t *testing.T) {
 		AdminIdentityName: testDebugAdminMIName,
 		ServerName:        testDbgServerName,
 		SchemaName:        "app",
+		Mode:              dbUtil.ProvisionModeAccess,
 		AccessPrincipals: []dbUtil.AccessPrincipal{
 			{Role: dbUtil.AccessRoleReader, Name: "svc", PrincipalID: "oid", PrincipalType: dbUtil.PrincipalTypeService},
 		},
 	})
 
 	values := envToMap(env)
-	if _, ok := values[dbUtil.ServerDebugAccessEnv]; ok {
-		t.Fatalf("did not expect debug env in non-debug mode")
+	if values[dbUtil.ProvisionModeEnv] != string(dbUtil.ProvisionModeAccess) {
+		t.Fatalf("expected %s=%s, got %q", dbUtil.ProvisionModeEnv, dbUtil.ProvisionModeAccess, values[dbUtil.ProvisionModeEnv])
 	}
 	if _, ok := values[dbUtil.DebugBuiltinRolesEnv]; ok {
 		t.Fatalf("did not expect built-in roles env in non-debug mode")
@@ -180,7 +181,7 @@ func TestValidateUserProvisionJobSpecServerDebug(t *testing.T) {
 		AdminIdentityName:  testDebugAdminMIName,
 		ServerName:         testDbgServerName,
 		AccessPrincipals:   testDebugPrincipals(),
-		ServerDebugAccess:  true,
+		Mode:               dbUtil.ProvisionModeServerDebugAccess,
 		DebugBuiltinRoles:  []string{debugBuiltinRolePgMonitor},
 	}
 	if err := validateUserProvisionJobSpec(valid, false); err != nil {
@@ -214,7 +215,7 @@ func TestValidateUserProvisionJobSpecServerDebug(t *testing.T) {
 
 	// Non-debug provisioning still requires at least one principal.
 	nonDebug := valid
-	nonDebug.ServerDebugAccess = false
+	nonDebug.Mode = dbUtil.ProvisionModeAccess
 	nonDebug.DebugBuiltinRoles = nil
 	nonDebug.SchemaName = "app"
 	nonDebug.AccessPrincipals = nil
@@ -53,8 +53,8 @@ func TestBuildDebugAccessRoleAssignmentReaderRole(t *testing.T) {
 	if ra.Labels[databaseServerNameLabelKey] != testDbgServerName {
 		t.Fatalf("expected owner label %q=%q", databaseServerNameLabelKey, testDbgServerName)
 	}
-	if ra.Labels[debugAccessComponentLabelKey] != debugAccessComponentLabelValue {
-		t.Fatalf("expected component label %q=%q", debugAccessComponentLabelKey, debugAccessComponentLabelValue)
+	if ra.Labels[componentLabelKey] != debugAccessComponentLabelValue {
+		t.Fatalf("expected component label %q=%q", componentLabelKey, debugAccessComponentLabelValue)
 	}
 }
 
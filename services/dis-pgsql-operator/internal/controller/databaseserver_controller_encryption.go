@@ -0,0 +1,384 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	identityv1alpha1 "github.com/Altinn/altinn-platform/services/dis-identity-operator/api/v1alpha1"
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/keyvault"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	authorizationv1 "github.com/Azure/azure-service-operator/v2/api/authorization/v1api20220401"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
+)
+
+const (
+	// databaseServerConditionEncryptionKeyReady reports whether the server can
+	// reach its customer-managed key. It is only set when spec.encryption is.
+	databaseServerConditionEncryptionKeyReady = "EncryptionKeyReady"
+
+	databaseServerReasonKeyReady      = "KeyReady"
+	databaseServerReasonKeyRotated    = "KeyRotated"
+	databaseServerReasonKeyAccessLost = "KeyAccessLost"
+	databaseServerReasonWaitingForKey = "WaitingForKey"
+
+	// encryptionKeyCheckInterval is how often the key is read from Azure to
+	// notice rotations and keys that were disabled or expired.
+	encryptionKeyCheckInterval = time.Hour
+
+	// encryptionBlockedRequeueInterval re-checks a server waiting for its
+	// Vault, identity or key. The Vault is not watched, since its CRD may not
+	// be installed.
+	encryptionBlockedRequeueInterval = time.Minute
+
+	// keyVaultCryptoServiceEncryptionUserRole lets the server identity wrap
+	// and unwrap its data encryption key with the customer-managed key.
+	keyVaultCryptoServiceEncryptionUserRole = "Key Vault Crypto Service Encryption User"
+
+	encryptionComponentLabelValue = "encryption"
+)
+
+// vaultGVK is the dis-vault Vault. It is read as unstructured so the operator
+// does not depend on the dis-vault-operator module, and works without its CRD
+// until a server asks for encryption.
+var vaultGVK = schema.GroupVersionKind{Group: "vault.dis.altinn.cloud", Version: "v1alpha1", Kind: "Vault"}
+
+// serverEncryption is the resolved customer-managed key configuration applied
+// to the FlexibleServer.
+type serverEncryption struct {
+	IdentityResourceID string
+	KeyURI             string
+}
+
+// encryptionBlocked is a reason spec.encryption cannot be applied yet.
+type encryptionBlocked struct {
+	reason  string
+	message string
+}
+
+// ensureServerEncryption resolves spec.encryption: it reads the identity and
+// the Vault, grants the identity access to the key, and creates or reads the
+// key. The result is recorded in the EncryptionKeyReady condition and
+// status.encryption. While a server that does not exist yet cannot be
+// encrypted, blocked is true and the server is not created. An existing
+// server keeps the encryption it was created with, so a failure only changes
+// the condition. It returns when the key should be checked again.
+func (r *DatabaseServerReconciler) ensureServerEncryption(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+) (*serverEncryption, bool, time.Duration, error) {
+	previousStatus := db.Status.DeepCopy()
+	if db.Spec.Encryption == nil {
+		meta.RemoveStatusCondition(&db.Status.Conditions, databaseServerConditionEncryptionKeyReady)
+		db.Status.Encryption = nil
+		return nil, false, 0, r.updateEncryptionStatus(ctx, db, previousStatus)
+	}
+
+	var existing dbforpostgresqlv1.FlexibleServer
+	serverExists := true
+	if err := r.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, false, 0, fmt.Errorf("get FlexibleServer %s/%s: %w", db.Namespace, db.Name, err)
+		}
+		serverExists = false
+	}
+
+	encryption, blocked, err := r.resolveServerEncryption(ctx, logger, db, serverExists)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	if blocked != nil {
+		logger.Info("customer-managed key is not usable", "reason", blocked.reason, "message", blocked.message)
+		reason := blocked.reason
+		if serverExists && reason == databaseServerReasonWaitingForKey {
+			reason = databaseServerReasonKeyAccessLost
+		}
+		if reason == databaseServerReasonKeyAccessLost &&
+			conditionChanged(db.Status.Conditions, databaseServerConditionEncryptionKeyReady, reason, blocked.message) {
+			recordWarningEvent(r.Recorder, db, eventReasonEncryptionKeyAccessLost, eventActionCheckEncryptionKey, "%s", blocked.message)
+		}
+		meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+			Type:               databaseServerConditionEncryptionKeyReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            blocked.message,
+			ObservedGeneration: db.Generation,
+		})
+		if !serverExists {
+			meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+				Type:               databaseServerConditionReady,
+				Status:             metav1.ConditionFalse,
+				Reason:             reason,
+				Message:            blocked.message,
+				ObservedGeneration: db.Generation,
+			})
+		}
+		if err := r.updateEncryptionStatus(ctx, db, previousStatus); err != nil {
+			return nil, false, 0, err
+		}
+		return nil, !serverExists, encryptionBlockedRequeueInterval, nil
+	}
+
+	reason := databaseServerReasonKeyReady
+	message := fmt.Sprintf("Key %q version %s is in use", db.Spec.Encryption.KeyName, db.Status.Encryption.KeyVersion)
+	if rotated := db.Status.Encryption.RotationTime; rotated != nil {
+		reason = databaseServerReasonKeyRotated
+		message = fmt.Sprintf("Key %q was rotated to version %s at %s", db.Spec.Encryption.KeyName, db.Status.Encryption.KeyVersion, rotated.UTC().Format(time.RFC3339))
+	}
+	meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+		Type:               databaseServerConditionEncryptionKeyReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: db.Generation,
+	})
+	if err := r.updateEncryptionStatus(ctx, db, previousStatus); err != nil {
+		return nil, false, 0, err
+	}
+
+	return encryption, false, time.Until(db.Status.Encryption.CheckedTime.Add(encryptionKeyCheckInterval)), nil
+}
+
+// resolveServerEncryption returns the encryption to apply, or why it cannot
+// be applied yet. The key is only read from Azure when status.encryption was
+// not checked within encryptionKeyCheckInterval.
+func (r *DatabaseServerReconciler) resolveServerEncryption(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	serverExists bool,
+) (*serverEncryption, *encryptionBlocked, error) {
+	spec := db.Spec.Encryption
+
+	identityName := strings.TrimSpace(spec.IdentityRef.Name)
+	var identity identityv1alpha1.ApplicationIdentity
+	if err := r.Get(ctx, types.NamespacedName{Name: identityName, Namespace: db.Namespace}, &identity); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Encryption ApplicationIdentity %q not found", identityName)}, nil
+		}
+		return nil, nil, fmt.Errorf("get ApplicationIdentity %s/%s: %w", db.Namespace, identityName, err)
+	}
+	var identityResourceID, principalID string
+	if identity.Status.ResourceID != nil {
+		identityResourceID = strings.TrimSpace(*identity.Status.ResourceID)
+	}
+	if identity.Status.PrincipalID != nil {
+		principalID = strings.TrimSpace(*identity.Status.PrincipalID)
+	}
+	if ready, found := applicationIdentityReady(&identity); (found && !ready) || identityResourceID == "" || principalID == "" {
+		return nil, &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Waiting for encryption ApplicationIdentity %q to be ready", identityName)}, nil
+	}
+
+	vaultName := strings.TrimSpace(spec.VaultRef.Name)
+	vaultResourceID, blocked, err := r.readVault(ctx, db.Namespace, vaultName)
+	if err != nil || blocked != nil {
+		return nil, blocked, err
+	}
+	keyResourceID := vaultResourceID + "/keys/" + spec.KeyName
+
+	roleAssignment := buildEncryptionRoleAssignment(db, keyResourceID, principalID)
+	ready, message, err := r.ensureEncryptionRoleAssignment(ctx, logger, db, roleAssignment)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ready {
+		return nil, &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Waiting for the %s role assignment on key %q: %s", keyVaultCryptoServiceEncryptionUserRole, spec.KeyName, message)}, nil
+	}
+
+	status := db.Status.Encryption
+	if status == nil || status.CheckedTime == nil || status.KeyURI == "" ||
+		time.Since(status.CheckedTime.Time) >= encryptionKeyCheckInterval ||
+		!strings.HasSuffix(status.KeyURI, "/keys/"+spec.KeyName) {
+		if r.KeyClient == nil {
+			return nil, &encryptionBlocked{databaseServerReasonWaitingForKey, "Customer-managed keys are not available: the operator has no Key Vault client"}, nil
+		}
+		key, err := r.KeyClient.EnsureKey(ctx, vaultResourceID, spec.KeyName)
+		if err != nil {
+			return nil, &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Key %q in Vault %q is not usable: %v", spec.KeyName, vaultName, err)}, nil
+		}
+		r.recordEncryptionKey(db, key, serverExists)
+	}
+
+	return &serverEncryption{
+		IdentityResourceID: identityResourceID,
+		KeyURI:             db.Status.Encryption.KeyURI,
+	}, nil, nil
+}
+
+// recordEncryptionKey stores the key read from Azure in status.encryption.
+// A new version of a key already in use is a rotation.
+func (r *DatabaseServerReconciler) recordEncryptionKey(db *storagev1alpha1.DatabaseServer, key keyvault.Key, serverExists bool) {
+	now := metav1.Now()
+	previous := db.Status.Encryption
+	status := &storagev1alpha1.DatabaseServerEncryptionStatus{
+		KeyURI:      key.URI,
+		KeyVersion:  key.Version,
+		CheckedTime: &now,
+	}
+	if previous != nil && previous.KeyURI == key.URI {
+		status.RotationTime = previous.RotationTime
+		if serverExists && previous.KeyVersion != "" && previous.KeyVersion != key.Version {
+			status.RotationTime = &now
+			recordNormalEvent(r.Recorder, db, eventReasonEncryptionKeyRotated, eventActionCheckEncryptionKey,
+				"Key %q was rotated from version %s to %s", db.Spec.Encryption.KeyName, previous.KeyVersion, key.Version)
+		}
+	}
+	db.Status.Encryption = status
+}
+
+// readVault returns the ARM ID of a Vault's Key Vault once the Vault is
+// Ready.
+func (r *DatabaseServerReconciler) readVault(ctx context.Context, namespace, name string) (string, *encryptionBlocked, error) {
+	vault := &unstructured.Unstructured{}
+	vault.SetGroupVersionKind(vaultGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, vault); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Vault %q not found", name)}, nil
+		}
+		return "", nil, fmt.Errorf("get Vault %s/%s: %w", namespace, name, err)
+	}
+
+	resourceID, _, _ := unstructured.NestedString(vault.Object, "status", "resourceId")
+	if !vaultReady(vault) || strings.TrimSpace(resourceID) == "" {
+		return "", &encryptionBlocked{databaseServerReasonWaitingForKey, fmt.Sprintf("Waiting for Vault %q to be ready", name)}, nil
+	}
+	return strings.TrimSpace(resourceID), nil, nil
+}
+
+func vaultReady(vault *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(vault.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, ok := item.(map[string]any)
+		if ok && condition["type"] == "Ready" {
+			return condition["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+// ensureEncryptionRoleAssignment creates or updates the key role assignment
+// and reports whether ASO has made it in Azure.
+func (r *DatabaseServerReconciler) ensureEncryptionRoleAssignment(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	desired *authorizationv1.RoleAssignment,
+) (bool, string, error) {
+	current := &authorizationv1.RoleAssignment{}
+	current.SetName(desired.GetName())
+	current.SetNamespace(desired.GetNamespace())
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, current, func() error {
+		current.Labels = mergeDebugAccessLabels(current.Labels, desired.Labels)
+		current.Spec = desired.Spec
+		return controllerutil.SetControllerReference(db, current, r.Scheme)
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("reconcile encryption RoleAssignment %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("reconciled encryption RoleAssignment", "name", desired.Name, "operation", op)
+	}
+	if r.Config.UseAzFakes {
+		return true, "", nil
+	}
+
+	cond, found := findReadyCondition(current.Status.Conditions)
+	if !found {
+		return false, "not reconciled yet", nil
+	}
+	return cond.Status == metav1.ConditionTrue, cond.Message, nil
+}
+
+// buildEncryptionRoleAssignment grants the server identity the Key Vault
+// Crypto Service Encryption User role on the key only, not the whole vault.
+func buildEncryptionRoleAssignment(
+	db *storagev1alpha1.DatabaseServer,
+	keyResourceID string,
+	principalID string,
+) *authorizationv1.RoleAssignment {
+	principalType := authorizationv1.RoleAssignmentProperties_PrincipalType_ServicePrincipal
+	base := naming.EnsureLowerAlphaPrefix(naming.SanitizeLowerHyphen(db.Name), "db")
+	seed := strings.Join([]string{db.Namespace, db.Name, principalID, keyResourceID, keyVaultCryptoServiceEncryptionUserRole}, "/")
+
+	return &authorizationv1.RoleAssignment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      naming.WithRequiredSuffix(base, "-cmk-ra", roleAssignmentMaxNameLen, "db"),
+			Namespace: db.Namespace,
+			Labels: map[string]string{
+				databaseServerNameLabelKey: db.Name,
+				componentLabelKey:          encryptionComponentLabelValue,
+			},
+		},
+		Spec: authorizationv1.RoleAssignment_Spec{
+			AzureName:     uuid.NewSHA1(uuid.NameSpaceURL, []byte(seed)).String(),
+			Owner:         &genruntime.ArbitraryOwnerReference{ARMID: keyResourceID},
+			PrincipalId:   &principalID,
+			PrincipalType: &principalType,
+			RoleDefinitionReference: &genruntime.WellKnownResourceReference{
+				WellKnownName: keyVaultCryptoServiceEncryptionUserRole,
+			},
+		},
+	}
+}
+
+// applyFlexibleServerEncryption sets the server identity and customer-managed
+// key on the desired FlexibleServer spec. Without a resolved encryption an
+// existing server keeps the settings it has, so a Vault that is briefly
+// unavailable never changes the server.
+func applyFlexibleServerEncryption(
+	spec *dbforpostgresqlv1.FlexibleServer_Spec,
+	encryption *serverEncryption,
+	existing *dbforpostgresqlv1.FlexibleServer,
+) {
+	if encryption == nil {
+		if existing != nil {
+			spec.Identity = existing.Spec.Identity
+			spec.DataEncryption = existing.Spec.DataEncryption
+		}
+		return
+	}
+
+	identity := genruntime.ResourceReference{ARMID: encryption.IdentityResourceID}
+	spec.Identity = &dbforpostgresqlv1.UserAssignedIdentity{
+		Type: to.Ptr(dbforpostgresqlv1.UserAssignedIdentity_Type_UserAssigned),
+		UserAssignedIdentities: []dbforpostgresqlv1.UserAssignedIdentityDetails{
+			{Reference: identity},
+		},
+	}
+	spec.DataEncryption = &dbforpostgresqlv1.DataEncryption{
+		Type:                                 to.Ptr(dbforpostgresqlv1.DataEncryption_Type_AzureKeyVault),
+		PrimaryKeyURI:                        to.Ptr(encryption.KeyURI),
+		PrimaryUserAssignedIdentityReference: &identity,
+	}
+}
+
+func (r *DatabaseServerReconciler) updateEncryptionStatus(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+	previousStatus *storagev1alpha1.DatabaseServerStatus,
+) error {
+	if apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, db); err != nil {
+		return fmt.Errorf("update database server encryption status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/keyvault"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testIdentityResourceID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/pg"
+
+func testEncryptedDatabaseServer() *storagev1alpha1.DatabaseServer {
+	db := &storagev1alpha1.DatabaseServer{}
+	db.Name = "appdb"
+	db.Namespace = "team"
+	db.Spec.Encryption = &storagev1alpha1.DatabaseServerEncryptionSpec{
+		VaultRef:    storagev1alpha1.VaultReference{Name: "app-vault"},
+		KeyName:     "pg-cmk",
+		IdentityRef: storagev1alpha1.ApplicationIdentityRef{Name: "pg-identity"},
+	}
+	return db
+}
+
+func TestValidateDatabaseServerRejectsEncryptionWithGeoRedundantBackup(t *testing.T) {
+	r := &DatabaseServerReconciler{}
+	db := testEncryptedDatabaseServer()
+	db.Spec.ServerType = "prod"
+	db.Spec.GeoRedundantBackup = to.Ptr(true)
+
+	if reason, _ := r.validateDatabaseServer(db); reason != databaseServerReasonInvalidEncryption {
+		t.Fatalf("expected reason %q, got %q", databaseServerReasonInvalidEncryption, reason)
+	}
+
+	db.Spec.GeoRedundantBackup = nil
+	if reason, message := r.validateDatabaseServer(db); reason != "" {
+		t.Fatalf("expected encryption without geo-redundant backup to be valid, got %s: %s", reason, message)
+	}
+}
+
+func TestApplyFlexibleServerEncryption(t *testing.T) {
+	var spec dbforpostgresqlv1.FlexibleServer_Spec
+	applyFlexibleServerEncryption(&spec, &serverEncryption{
+		IdentityResourceID: testIdentityResourceID,
+		KeyURI:             "https://app-kv.vault.azure.net/keys/pg-cmk",
+	}, nil)
+
+	if spec.Identity == nil || spec.Identity.Type == nil || *spec.Identity.Type != dbforpostgresqlv1.UserAssignedIdentity_Type_UserAssigned {
+		t.Fatalf("expected a user-assigned identity, got %#v", spec.Identity)
+	}
+	if len(spec.Identity.UserAssignedIdentities) != 1 || spec.Identity.UserAssignedIdentities[0].Reference.ARMID != testIdentityResourceID {
+		t.Fatalf("expected the encryption identity, got %#v", spec.Identity.UserAssignedIdentities)
+	}
+	encryption := spec.DataEncryption
+	if encryption == nil || encryption.Type == nil || *encryption.Type != dbforpostgresqlv1.DataEncryption_Type_AzureKeyVault {
+		t.Fatalf("expected Key Vault data encryption, got %#v", encryption)
+	}
+	if encryption.PrimaryKeyURI == nil || *encryption.PrimaryKeyURI != "https://app-kv.vault.azure.net/keys/pg-cmk" {
+		t.Fatalf("expected the versionless key URI, got %v", encryption.PrimaryKeyURI)
+	}
+	if encryption.PrimaryUserAssignedIdentityReference == nil || encryption.PrimaryUserAssignedIdentityReference.ARMID != testIdentityResourceID {
+		t.Fatalf("expected the key to be used through the encryption identity, got %#v", encryption.PrimaryUserAssignedIdentityReference)
+	}
+}
+
+func TestApplyFlexibleServerEncryptionKeepsExistingSettings(t *testing.T) {
+	existing := &dbforpostgresqlv1.FlexibleServer{}
+	applyFlexibleServerEncryption(&existing.Spec, &serverEncryption{
+		IdentityResourceID: testIdentityResourceID,
+		KeyURI:             "https://app-kv.vault.azure.net/keys/pg-cmk",
+	}, nil)
+
+	var spec dbforpostgresqlv1.FlexibleServer_Spec
+	applyFlexibleServerEncryption(&spec, nil, existing)
+	if spec.DataEncryption != existing.Spec.DataEncryption || spec.Identity != existing.Spec.Identity {
+		t.Fatalf("expected an unresolved key to keep the server's encryption")
+	}
+
+	var unencrypted dbforpostgresqlv1.FlexibleServer_Spec
+	applyFlexibleServerEncryption(&unencrypted, nil, nil)
+	if unencrypted.DataEncryption != nil || unencrypted.Identity != nil {
+		t.Fatalf("expected a new server without a key to stay unencrypted")
+	}
+}
+
+func TestBuildEncryptionRoleAssignment(t *testing.T) {
+	db := testEncryptedDatabaseServer()
+	keyID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/app-kv/keys/pg-cmk"
+
+	ra := buildEncryptionRoleAssignment(db, keyID, "principal-id")
+
+	if ra.Name != "appdb-cmk-ra" || ra.Namespace != "team" {
+		t.Fatalf("unexpected name %s/%s", ra.Namespace, ra.Name)
+	}
+	if ra.Spec.Owner == nil || ra.Spec.Owner.ARMID != keyID {
+		t.Fatalf("expected the role assignment to be scoped to the key, got %#v", ra.Spec.Owner)
+	}
+	if ra.Spec.RoleDefinitionReference == nil || ra.Spec.RoleDefinitionReference.WellKnownName != keyVaultCryptoServiceEncryptionUserRole {
+		t.Fatalf("unexpected role %#v", ra.Spec.RoleDefinitionReference)
+	}
+	if ra.Spec.PrincipalId == nil || *ra.Spec.PrincipalId != "principal-id" {
+		t.Fatalf("unexpected principal %v", ra.Spec.PrincipalId)
+	}
+	if ra.Labels[componentLabelKey] != encryptionComponentLabelValue {
+		t.Fatalf("expected the encryption component label, got %v", ra.Labels)
+	}
+	if again := buildEncryptionRoleAssignment(db, keyID, "principal-id"); again.Spec.AzureName != ra.Spec.AzureName {
+		t.Fatalf("expected a deterministic Azure name")
+	}
+	if other := buildEncryptionRoleAssignment(db, keyID, "other-principal"); other.Spec.AzureName == ra.Spec.AzureName {
+		t.Fatalf("expected a new Azure name for a new principal")
+	}
+}
+
+func TestRecordEncryptionKey(t *testing.T) {
+	r := &DatabaseServerReconciler{}
+	db := testEncryptedDatabaseServer()
+	uri := "https://app-kv.vault.azure.net/keys/pg-cmk"
+
+	r.recordEncryptionKey(db, keyvault.Key{URI: uri, Version: "v1"}, false)
+	if db.Status.Encryption == nil || db.Status.Encryption.KeyVersion != "v1" || db.Status.Encryption.CheckedTime == nil {
+		t.Fatalf("expected the key to be recorded, got %#v", db.Status.Encryption)
+	}
+	if db.Status.Encryption.RotationTime != nil {
+		t.Fatalf("expected no rotation for the first key")
+	}
+
+	r.recordEncryptionKey(db, keyvault.Key{URI: uri, Version: "v1"}, true)
+	if db.Status.Encryption.RotationTime != nil {
+		t.Fatalf("expected no rotation while the version is unchanged")
+	}
+
+	r.recordEncryptionKey(db, keyvault.Key{URI: uri, Version: "v2"}, true)
+	rotated := db.Status.Encryption.RotationTime
+	if rotated == nil || db.Status.Encryption.KeyVersion != "v2" {
+		t.Fatalf("expected a rotation to v2, got %#v", db.Status.Encryption)
+	}
+
+	db.Status.Encryption.CheckedTime = &metav1.Time{Time: time.Now().Add(-2 * encryptionKeyCheckInterval)}
+	r.recordEncryptionKey(db, keyvault.Key{URI: uri, Version: "v2"}, true)
+	if db.Status.Encryption.RotationTime == nil || !db.Status.Encryption.RotationTime.Equal(rotated) {
+		t.Fatalf("expected the last rotation time to be kept")
+	}
+}
+
+func TestVaultReady(t *testing.T) {
+	vault := &unstructured.Unstructured{Object: map[string]any{}}
+	if vaultReady(vault) {
+		t.Fatalf("expected a Vault without status to not be ready")
+	}
+
+	for status, want := range map[string]bool{"True": true, "False": false} {
+		vault.Object["status"] = map[string]any{"conditions": []any{
+			map[string]any{"type": "Ready", "status": status},
+		}}
+		if got := vaultReady(vault); got != want {
+			t.Fatalf("Ready=%s: expected %v, got %v", status, want, got)
+		}
+	}
+}
@@ -98,7 +98,11 @@ func (r *DatabaseServerReconciler) ensurePostgresServerParameters(
 	logger logr.Logger,
 	db *storagev1alpha1.DatabaseServer,
 ) error {
-	serverParameters, err := dbUtil.ResolveServerParameters(db.Spec.ServerType, db.Spec.ServerParams)
+	profile, err := resolveServerProfile(r.Config, db)
+	if err != nil {
+		return err
+	}
+	serverParameters, err := dbUtil.ResolveServerParameters(profile, db.Spec.ConnectionPooling, db.Spec.ServerParams)
 	if err != nil {
 		return fmt.Errorf("resolve server parameters: %w", err)
 	}
@@ -242,7 +246,7 @@ func (r *DatabaseServerReconciler) updateServerParameterStatusFromASO(
 			Type:    serverParametersReadyConditionType,
 			Status:  metav1.ConditionTrue,
 			Reason:  databaseConditionReady,
-			Message: "All server parameter configurations are ready.",
+			Message: serverParametersReadyMessage(db),
 		})
 	}
 
@@ -251,6 +255,10 @@ func (r *DatabaseServerReconciler) updateServerParameterStatusFromASO(
 			return fmt.Errorf("update database server status with server parameter results: %w", err)
 		}
 	}
+	for _, parameterError := range newServerParameterErrors(previousStatus.ServerParameterErrors, serverParameterErrors) {
+		recordWarningEvent(r.Recorder, db, eventReasonServerParameterRejected, eventActionApplyParameters,
+			"Server parameter %s was rejected: %s", parameterError.Name, parameterError.Message)
+	}
 
 	if len(serverParameterErrors) > 0 {
 		return fmt.Errorf("one or more server parameters failed to apply; see status.serverParameterErrors")
@@ -259,6 +267,33 @@ func (r *DatabaseServerReconciler) updateServerParameterStatusFromASO(
 	return nil
 }
 
+// newServerParameterErrors returns the errors in current that are not in
+// previous with the same reason and message.
+func newServerParameterErrors(previous, current []storagev1alpha1.DatabaseServerParameterError) []storagev1alpha1.DatabaseServerParameterError {
+	seen := make(map[storagev1alpha1.DatabaseServerParameterError]struct{}, len(previous))
+	for _, parameterError := range previous {
+		seen[parameterError] = struct{}{}
+	}
+	var added []storagev1alpha1.DatabaseServerParameterError
+	for _, parameterError := range current {
+		if _, ok := seen[parameterError]; !ok {
+			added = append(added, parameterError)
+		}
+	}
+	return added
+}
+
+// serverParametersReadyMessage notes the static parameters in the spec, since
+// Azure stores them right away but only applies them when the server restarts.
+func serverParametersReadyMessage(db *storagev1alpha1.DatabaseServer) string {
+	const message = "All server parameter configurations are ready."
+	restart := dbUtil.RestartRequiredServerParameters(db.Spec.Version, db.Spec.ServerParams)
+	if len(restart) == 0 {
+		return message
+	}
+	return fmt.Sprintf("%s %s take effect after the next server restart.", message, strings.Join(restart, ", "))
+}
+
 func summarizeServerParameterErrors(errors []storagev1alpha1.DatabaseServerParameterError) string {
 	// Keep the condition message compact; full details remain in status.serverParameterErrors.
 	const maxErrorsInSummary = 3
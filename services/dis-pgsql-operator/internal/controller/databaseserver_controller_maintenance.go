@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+const (
+	// databaseServerConditionPendingChanges lists changes that restart the
+	// server and are held until the maintenance window. It is only present
+	// while changes are waiting and never affects Ready.
+	databaseServerConditionPendingChanges = "PendingChanges"
+
+	// databaseServerReasonWaitingForMaintenanceWindow marks held changes.
+	databaseServerReasonWaitingForMaintenanceWindow = "WaitingForMaintenanceWindow"
+
+	// applyNowAnnotation applies held changes on the next reconcile instead of
+	// waiting for the maintenance window. The operator removes it once the
+	// changes are applied, so it has to be set again for later changes.
+	applyNowAnnotation = "storage.dis.altinn.cloud/apply-now"
+
+	// maintenanceWindowDuration is how long held changes may be applied after
+	// the window starts.
+	maintenanceWindowDuration = time.Hour
+
+	// minPendingChangesRequeue keeps the requeue towards the window from
+	// spinning when the window start is only moments away.
+	minPendingChangesRequeue = time.Minute
+)
+
+// maintenanceWindow is the resolved weekly window of a server, in UTC.
+type maintenanceWindow struct {
+	DayOfWeek   int
+	StartHour   int
+	StartMinute int
+}
+
+func resolveMaintenanceWindow(db *storagev1alpha1.DatabaseServer) maintenanceWindow {
+	if db.Spec.MaintenanceWindow == nil {
+		return maintenanceWindow{
+			DayOfWeek:   defaultMaintenanceDayOfWeek,
+			StartHour:   defaultMaintenanceStartHour,
+			StartMinute: defaultMaintenanceStartMinute,
+		}
+	}
+	return maintenanceWindow{
+		DayOfWeek:   db.Spec.MaintenanceWindow.DayOfWeek,
+		StartHour:   db.Spec.MaintenanceWindow.StartHour,
+		StartMinute: db.Spec.MaintenanceWindow.StartMinute,
+	}
+}
+
+// lastStart returns the most recent window start at or before now.
+func (w maintenanceWindow) lastStart(now time.Time) time.Time {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), w.StartHour, w.StartMinute, 0, 0, time.UTC)
+	start = start.AddDate(0, 0, w.DayOfWeek-int(now.Weekday()))
+	if start.After(now) {
+		start = start.AddDate(0, 0, -7)
+	}
+	return start
+}
+
+// active reports whether now is inside the window.
+func (w maintenanceWindow) active(now time.Time) bool {
+	return now.UTC().Before(w.lastStart(now).Add(maintenanceWindowDuration))
+}
+
+// nextStart returns the first window start after now.
+func (w maintenanceWindow) nextStart(now time.Time) time.Time {
+	return w.lastStart(now).AddDate(0, 0, 7)
+}
+
+// pendingServerChange is one restarting change held back from the
+// FlexibleServer.
+type pendingServerChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+func (c pendingServerChange) String() string {
+	return fmt.Sprintf("%s %s -> %s", c.Field, c.From, c.To)
+}
+
+// holdRestartingChanges keeps the current SKU and major version in desired when
+// they differ from the existing server, since changing either restarts the
+// server. Storage size and performance tier changes are applied online and are
+// not held. It returns the changes it held back.
+func holdRestartingChanges(
+	existing *dbforpostgresqlv1.FlexibleServer_Spec,
+	desired *dbforpostgresqlv1.FlexibleServer_Spec,
+) []pendingServerChange {
+	var pending []pendingServerChange
+
+	if existing.Sku != nil && desired.Sku != nil {
+		if current, wanted := stringPtrValue(existing.Sku.Name), stringPtrValue(desired.Sku.Name); current != "" && current != wanted {
+			pending = append(pending, pendingServerChange{Field: "sku", From: current, To: wanted})
+		}
+		if current, wanted := skuTierValue(existing.Sku.Tier), skuTierValue(desired.Sku.Tier); current != "" && current != wanted {
+			pending = append(pending, pendingServerChange{Field: "sku tier", From: current, To: wanted})
+		}
+		if len(pending) > 0 {
+			held := *existing.Sku
+			desired.Sku = &held
+		}
+	}
+
+	if current, wanted := postgresVersionValue(existing.Version), postgresVersionValue(desired.Version); current != "" && current != wanted {
+		pending = append(pending, pendingServerChange{Field: "version", From: current, To: wanted})
+		desired.Version = existing.Version
+	}
+
+	return pending
+}
+
+// applyRestartingChangesNow reports whether restarting changes may be applied
+// on this reconcile.
+func applyRestartingChangesNow(db *storagev1alpha1.DatabaseServer, now time.Time) bool {
+	if _, ok := db.Annotations[applyNowAnnotation]; ok {
+		return true
+	}
+	return resolveMaintenanceWindow(db).active(now)
+}
+
+// consumeApplyNowAnnotation removes the apply-now annotation once the held
+// changes it released have been applied.
+func (r *DatabaseServerReconciler) consumeApplyNowAnnotation(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+) error {
+	if _, ok := db.Annotations[applyNowAnnotation]; !ok {
+		return nil
+	}
+	delete(db.Annotations, applyNowAnnotation)
+	if err := r.Update(ctx, db); err != nil {
+		return fmt.Errorf("remove %s annotation from DatabaseServer %s/%s: %w", applyNowAnnotation, db.Namespace, db.Name, err)
+	}
+	logger.Info("applied held server changes on request", "annotation", applyNowAnnotation)
+	return nil
+}
+
+// setPendingChangesCondition records the held changes, or removes the
+// condition when nothing is waiting.
+func (r *DatabaseServerReconciler) setPendingChangesCondition(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+	pending []pendingServerChange,
+	now time.Time,
+) error {
+	previousStatus := db.Status.DeepCopy()
+
+	if len(pending) == 0 {
+		meta.RemoveStatusCondition(&db.Status.Conditions, databaseServerConditionPendingChanges)
+	} else {
+		meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+			Type:               databaseServerConditionPendingChanges,
+			Status:             metav1.ConditionTrue,
+			Reason:             databaseServerReasonWaitingForMaintenanceWindow,
+			Message:            pendingChangesMessage(pending, resolveMaintenanceWindow(db).nextStart(now)),
+			ObservedGeneration: db.Generation,
+		})
+	}
+
+	if apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, db)
+}
+
+func pendingChangesMessage(pending []pendingServerChange, nextStart time.Time) string {
+	changes := make([]string, 0, len(pending))
+	for _, change := range pending {
+		changes = append(changes, change.String())
+	}
+	return fmt.Sprintf(
+		"Waiting for the maintenance window at %s, or the %s annotation: %s",
+		nextStart.UTC().Format(time.RFC3339),
+		applyNowAnnotation,
+		strings.Join(changes, "; "),
+	)
+}
+
+// pendingChangesRequeue returns when held changes are due, or zero when no
+// changes are waiting.
+func pendingChangesRequeue(db *storagev1alpha1.DatabaseServer, now time.Time) time.Duration {
+	if !meta.IsStatusConditionTrue(db.Status.Conditions, databaseServerConditionPendingChanges) {
+		return 0
+	}
+	return maintenanceWindowRequeue(db, now)
+}
+
+// maintenanceWindowRequeue returns the interval until the next maintenance
+// window starts.
+func maintenanceWindowRequeue(db *storagev1alpha1.DatabaseServer, now time.Time) time.Duration {
+	return max(resolveMaintenanceWindow(db).nextStart(now).Sub(now), minPendingChangesRequeue)
+}
+
+// earliestRequeue returns the shortest non-zero requeue interval.
+func earliestRequeue(intervals ...time.Duration) time.Duration {
+	var earliest time.Duration
+	for _, interval := range intervals {
+		if interval > 0 && (earliest == 0 || interval < earliest) {
+			earliest = interval
+		}
+	}
+	return earliest
+}
+
+func stringPtrValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func skuTierValue(value *dbforpostgresqlv1.SkuTier) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+func postgresVersionValue(value *dbforpostgresqlv1.PostgresMajorVersion) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	// Wednesday 02:00 UTC, 2026-05-20.
+	window := maintenanceWindow{DayOfWeek: int(time.Wednesday), StartHour: 2}
+
+	cases := []struct {
+		name       string
+		now        time.Time
+		wantActive bool
+		wantNext   time.Time
+	}{
+		{
+			name:       "inside the window",
+			now:        time.Date(2026, 5, 20, 2, 30, 0, 0, time.UTC),
+			wantActive: true,
+			wantNext:   time.Date(2026, 5, 27, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "after the window on the same day",
+			now:      time.Date(2026, 5, 20, 3, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2026, 5, 27, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "earlier in the week",
+			now:      time.Date(2026, 5, 18, 12, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2026, 5, 20, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "just before the window",
+			now:      time.Date(2026, 5, 20, 1, 59, 0, 0, time.UTC),
+			wantNext: time.Date(2026, 5, 20, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "other time zone",
+			now:      time.Date(2026, 5, 20, 7, 0, 0, 0, time.FixedZone("UTC+4", 4*60*60)),
+			wantNext: time.Date(2026, 5, 27, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := window.active(tc.now); got != tc.wantActive {
+				t.Fatalf("active(%v) = %t, want %t", tc.now, got, tc.wantActive)
+			}
+			if got := window.nextStart(tc.now); !got.Equal(tc.wantNext) {
+				t.Fatalf("nextStart(%v) = %v, want %v", tc.now, got, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestResolveMaintenanceWindowDefaults(t *testing.T) {
+	db := &storagev1alpha1.DatabaseServer{}
+	want := maintenanceWindow{
+		DayOfWeek:   defaultMaintenanceDayOfWeek,
+		StartHour:   defaultMaintenanceStartHour,
+		StartMinute: defaultMaintenanceStartMinute,
+	}
+	if got := resolveMaintenanceWindow(db); got != want {
+		t.Fatalf("expected default window %#v, got %#v", want, got)
+	}
+
+	db.Spec.MaintenanceWindow = &storagev1alpha1.DatabaseServerMaintenanceWindow{DayOfWeek: 3, StartHour: 22, StartMinute: 30}
+	spec := desiredMaintenanceWindow(db)
+	if *spec.DayOfWeek != 3 || *spec.StartHour != 22 || *spec.StartMinute != 30 {
+		t.Fatalf("expected configured window on the FlexibleServer, got %#v", spec)
+	}
+}
+
+func TestHoldRestartingChanges(t *testing.T) {
+	newSpec := func(sku string, tier dbforpostgresqlv1.SkuTier, storageTier dbforpostgresqlv1.AzureManagedDiskPerformanceTier, sizeGB int) dbforpostgresqlv1.FlexibleServer_Spec {
+		return dbforpostgresqlv1.FlexibleServer_Spec{
+			Sku: &dbforpostgresqlv1.Sku{Name: to.Ptr(sku), Tier: to.Ptr(tier)},
+			Storage: &dbforpostgresqlv1.Storage{
+				StorageSizeGB: to.Ptr(sizeGB),
+				Tier:          to.Ptr(storageTier),
+			},
+		}
+	}
+
+	existing := newSpec("Standard_B1ms", dbforpostgresqlv1.SkuTier_Burstable, dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P4, 32)
+
+	t.Run("holds SKU but not storage size or tier", func(t *testing.T) {
+		desired := newSpec("Standard_D4s_v3", dbforpostgresqlv1.SkuTier_GeneralPurpose, dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P30, 128)
+
+		pending := holdRestartingChanges(&existing, &desired)
+
+		if len(pending) != 2 {
+			t.Fatalf("expected sku and sku tier to be held, got %#v", pending)
+		}
+		if *desired.Sku.Name != "Standard_B1ms" || *desired.Sku.Tier != dbforpostgresqlv1.SkuTier_Burstable {
+			t.Fatalf("expected current SKU to be kept, got %#v", desired.Sku)
+		}
+		if *desired.Storage.Tier != dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P30 {
+			t.Fatalf("expected storage tier change to be applied, got %q", *desired.Storage.Tier)
+		}
+		if *desired.Storage.StorageSizeGB != 128 {
+			t.Fatalf("expected storage growth to be applied, got %d", *desired.Storage.StorageSizeGB)
+		}
+
+		message := pendingChangesMessage(pending, time.Date(2026, 5, 24, 3, 0, 0, 0, time.UTC))
+		if !strings.Contains(message, "sku Standard_B1ms -> Standard_D4s_v3") ||
+			!strings.Contains(message, "sku tier Burstable -> GeneralPurpose") ||
+			!strings.Contains(message, "2026-05-24T03:00:00Z") {
+			t.Fatalf("unexpected pending changes message %q", message)
+		}
+	})
+
+	t.Run("holds a major version upgrade", func(t *testing.T) {
+		current := newSpec("Standard_B1ms", dbforpostgresqlv1.SkuTier_Burstable, dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P4, 32)
+		current.Version = to.Ptr(dbforpostgresqlv1.PostgresMajorVersion("16"))
+		desired := newSpec("Standard_B1ms", dbforpostgresqlv1.SkuTier_Burstable, dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P4, 32)
+		desired.Version = to.Ptr(dbforpostgresqlv1.PostgresMajorVersion("17"))
+
+		pending := holdRestartingChanges(&current, &desired)
+
+		if len(pending) != 1 || pending[0].String() != "version 16 -> 17" {
+			t.Fatalf("expected the version to be held, got %#v", pending)
+		}
+		if *desired.Version != "16" {
+			t.Fatalf("expected current version to be kept, got %q", *desired.Version)
+		}
+	})
+
+	t.Run("holds nothing without restarting changes", func(t *testing.T) {
+		desired := newSpec("Standard_B1ms", dbforpostgresqlv1.SkuTier_Burstable, dbforpostgresqlv1.AzureManagedDiskPerformanceTier_P4, 64)
+		if pending := holdRestartingChanges(&existing, &desired); len(pending) != 0 {
+			t.Fatalf("expected no held changes, got %#v", pending)
+		}
+	})
+}
+
+func TestApplyRestartingChangesNow(t *testing.T) {
+	outsideWindow := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	db := &storagev1alpha1.DatabaseServer{}
+
+	if applyRestartingChangesNow(db, outsideWindow) {
+		t.Fatalf("expected changes to be held outside the maintenance window")
+	}
+
+	db.Annotations = map[string]string{applyNowAnnotation: "true"}
+	if !applyRestartingChangesNow(db, outsideWindow) {
+		t.Fatalf("expected the apply-now annotation to release held changes")
+	}
+}
+
+func TestPendingChangesRequeue(t *testing.T) {
+	now := time.Date(2026, 5, 23, 3, 0, 0, 0, time.UTC)
+	db := &storagev1alpha1.DatabaseServer{}
+
+	if got := pendingChangesRequeue(db, now); got != 0 {
+		t.Fatalf("expected no requeue without pending changes, got %v", got)
+	}
+
+	db.Status.Conditions = []metav1.Condition{{
+		Type:   databaseServerConditionPendingChanges,
+		Status: metav1.ConditionTrue,
+	}}
+	if got := pendingChangesRequeue(db, now); got != 24*time.Hour {
+		t.Fatalf("expected requeue at the next window, got %v", got)
+	}
+
+	if got := earliestRequeue(0, 6*time.Hour, 24*time.Hour); got != 6*time.Hour {
+		t.Fatalf("earliestRequeue = %v, want %v", got, 6*time.Hour)
+	}
+}
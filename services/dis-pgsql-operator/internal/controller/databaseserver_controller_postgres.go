@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,9 +14,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
 	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
 	k8sutil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/k8s"
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/validation"
 	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
 	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
@@ -24,17 +26,20 @@ import (
 
 // TODO: at the moment location is hardcoded here, but maybe
 // in the future we want to derive it from the database server spec?
-// The defaults for storage size and tier are also hardcoded
-// until we release the beta version.
+// The default storage size comes from the server profile.
 const (
-	defaultStorageGB               int32 = 32
-	loc                                  = "norwayeast"
-	defaultAvailabilityZone              = "1"
-	defaultHAStandbyZone                 = "2"
-	defaultMaintenanceDayOfWeek          = 0
-	defaultMaintenanceStartHour          = 3
-	defaultMaintenanceStartMinute        = 0
-	maintenanceCustomWindowEnabled       = "Enabled"
+	loc                            = "norwayeast"
+	defaultAvailabilityZone        = "1"
+	defaultHAStandbyZone           = "2"
+	defaultMaintenanceDayOfWeek    = storagev1alpha1.DefaultMaintenanceDayOfWeek
+	defaultMaintenanceStartHour    = storagev1alpha1.DefaultMaintenanceStartHour
+	defaultMaintenanceStartMinute  = storagev1alpha1.DefaultMaintenanceStartMinute
+	maintenanceCustomWindowEnabled = "Enabled"
+
+	// pairedLocation is the Azure paired region of loc. Geo-redundant backups
+	// are stored there and geo-restored servers are created there. It has no
+	// availability zones.
+	pairedLocation = "norwaywest"
 
 	// flexibleServerNameMaxLen is Azure's upper bound on a PostgreSQL Flexible
 	// Server name (3-63 chars, lowercase letters/digits/hyphens, starts with a
@@ -51,28 +56,27 @@ type postgresNetworkConfig struct {
 	Network *dbforpostgresqlv1.Network
 }
 
-const (
-	azureSubnetResourceType         = "Microsoft.Network/virtualNetworks/subnets"
-	azurePrivateDNSZoneResourceType = "Microsoft.Network/privateDnsZones"
-)
+// resolveServerProfile returns the profile selected by spec.profile, or by
+// serverType when no profile is set.
+func resolveServerProfile(cfg config.OperatorConfig, db *storagev1alpha1.DatabaseServer) (dbUtil.Profile, error) {
+	return cfg.Profiles.Resolve(db.Spec.Profile, db.Spec.ServerType)
+}
 
-// Reuse the defaults for now
-func desiredStorage(db *storagev1alpha1.DatabaseServer) *dbforpostgresqlv1.Storage {
-	sizeGB := defaultStorageGB
+func desiredStorage(db *storagev1alpha1.DatabaseServer, profile dbUtil.Profile) *dbforpostgresqlv1.Storage {
 	autoGrow := dbforpostgresqlv1.StorageAutoGrow_Enabled
 	storageType := dbforpostgresqlv1.StorageType_Premium_LRS
 
+	var requestedSizeGB *int32
 	var requestedTier *string
 
 	if db.Spec.Storage != nil {
-		if db.Spec.Storage.SizeGB != nil && *db.Spec.Storage.SizeGB > 0 {
-			sizeGB = *db.Spec.Storage.SizeGB
-		}
+		requestedSizeGB = db.Spec.Storage.SizeGB
 		if db.Spec.Storage.Tier != nil && *db.Spec.Storage.Tier != "" {
 			requestedTier = db.Spec.Storage.Tier
 		}
 	}
 
+	sizeGB := dbUtil.ResolveStorageGB(profile, requestedSizeGB)
 	asoTier := dbUtil.ResolveStorageTier(sizeGB, requestedTier)
 
 	return &dbforpostgresqlv1.Storage{
@@ -83,16 +87,21 @@ func desiredStorage(db *storagev1alpha1.DatabaseServer) *dbforpostgresqlv1.Stora
 	}
 }
 
-func desiredBackup(db *storagev1alpha1.DatabaseServer) *dbforpostgresqlv1.Backup {
+// desiredBackup sets retention and geo-redundancy. validateDatabaseServer
+// has already rejected geo-redundant backup on a profile that does not allow it.
+func desiredBackup(db *storagev1alpha1.DatabaseServer, profile dbUtil.Profile) *dbforpostgresqlv1.Backup {
 	geoRedundantBackup := dbforpostgresqlv1.Backup_GeoRedundantBackup_Disabled
+	if enabled, err := dbUtil.ResolveGeoRedundantBackup(profile, db.Spec.GeoRedundantBackup); err == nil && enabled {
+		geoRedundantBackup = dbforpostgresqlv1.Backup_GeoRedundantBackup_Enabled
+	}
 	return &dbforpostgresqlv1.Backup{
-		BackupRetentionDays: to.Ptr(dbUtil.ResolveBackupRetentionDays(db.Spec.ServerType, db.Spec.BackupRetentionDays)),
+		BackupRetentionDays: to.Ptr(dbUtil.ResolveBackupRetentionDays(profile, db.Spec.BackupRetentionDays)),
 		GeoRedundantBackup:  &geoRedundantBackup,
 	}
 }
 
-func desiredHighAvailability(db *storagev1alpha1.DatabaseServer) *dbforpostgresqlv1.HighAvailability {
-	mode := dbUtil.ResolveHighAvailabilityMode(db.Spec.ServerType, db.Spec.HighAvailabilityEnabled)
+func desiredHighAvailability(db *storagev1alpha1.DatabaseServer, profile dbUtil.Profile) *dbforpostgresqlv1.HighAvailability {
+	mode := dbUtil.ResolveHighAvailabilityMode(profile, db.Spec.HighAvailabilityEnabled)
 	highAvailability := &dbforpostgresqlv1.HighAvailability{
 		Mode: &mode,
 	}
@@ -104,12 +113,13 @@ func desiredHighAvailability(db *storagev1alpha1.DatabaseServer) *dbforpostgresq
 	return highAvailability
 }
 
-func desiredMaintenanceWindow() *dbforpostgresqlv1.MaintenanceWindow {
+func desiredMaintenanceWindow(db *storagev1alpha1.DatabaseServer) *dbforpostgresqlv1.MaintenanceWindow {
+	window := resolveMaintenanceWindow(db)
 	return &dbforpostgresqlv1.MaintenanceWindow{
 		CustomWindow: to.Ptr(maintenanceCustomWindowEnabled),
-		DayOfWeek:    to.Ptr(defaultMaintenanceDayOfWeek),
-		StartHour:    to.Ptr(defaultMaintenanceStartHour),
-		StartMinute:  to.Ptr(defaultMaintenanceStartMinute),
+		DayOfWeek:    to.Ptr(window.DayOfWeek),
+		StartHour:    to.Ptr(window.StartHour),
+		StartMinute:  to.Ptr(window.StartMinute),
 	}
 }
 
@@ -160,19 +170,21 @@ func (r *DatabaseServerReconciler) sharedPostgresNetworkConfig(db *storagev1alph
 		return postgresNetworkConfig{}, fmt.Errorf("spec.network must be set when mode is Shared")
 	}
 
-	subnetResourceID, err := r.validateSharedNetworkResourceID(
+	subnetResourceID, err := validation.SharedNetworkResourceID(
 		"spec.network.delegatedSubnetResourceId",
 		db.Spec.Network.DelegatedSubnetResourceID,
-		azureSubnetResourceType,
+		validation.SubnetResourceType,
+		r.Config.SubscriptionId,
 	)
 	if err != nil {
 		return postgresNetworkConfig{}, err
 	}
 
-	zoneResourceID, err := r.validateSharedNetworkResourceID(
+	zoneResourceID, err := validation.SharedNetworkResourceID(
 		"spec.network.privateDnsZoneResourceId",
 		db.Spec.Network.PrivateDNSZoneResourceID,
-		azurePrivateDNSZoneResourceType,
+		validation.PrivateDNSZoneResourceType,
+		r.Config.SubscriptionId,
 	)
 	if err != nil {
 		return postgresNetworkConfig{}, err
@@ -192,33 +204,6 @@ func (r *DatabaseServerReconciler) sharedPostgresNetworkConfig(db *storagev1alph
 	}, nil
 }
 
-func (r *DatabaseServerReconciler) validateSharedNetworkResourceID(fieldPath, resourceID, expectedResourceType string) (string, error) {
-	resourceID = strings.TrimSpace(resourceID)
-	if resourceID == "" {
-		return "", fmt.Errorf("%s must be set when mode is Shared", fieldPath)
-	}
-
-	parsed, err := arm.ParseResourceID(resourceID)
-	if err != nil {
-		return "", fmt.Errorf("%s must be a valid ARM resource ID: %w", fieldPath, err)
-	}
-
-	subscriptionID := strings.TrimSpace(r.Config.SubscriptionId)
-	if subscriptionID == "" {
-		return "", fmt.Errorf("operator subscription id is not configured; cannot validate %s", fieldPath)
-	}
-	if !strings.EqualFold(parsed.SubscriptionID, subscriptionID) {
-		return "", fmt.Errorf("%s must be in subscription %q", fieldPath, subscriptionID)
-	}
-
-	actualResourceType := parsed.ResourceType.String()
-	if !strings.EqualFold(actualResourceType, expectedResourceType) {
-		return "", fmt.Errorf("%s must reference %s, got %s", fieldPath, expectedResourceType, actualResourceType)
-	}
-
-	return resourceID, nil
-}
-
 func resourceReferenceLogValue(ref *genruntime.ResourceReference) string {
 	if ref == nil {
 		return ""
@@ -264,6 +249,8 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 	logger logr.Logger,
 	db *storagev1alpha1.DatabaseServer,
 	networkConfig postgresNetworkConfig,
+	restore *flexibleServerRestore,
+	encryption *serverEncryption,
 ) error {
 	ns := db.Namespace
 
@@ -296,14 +283,16 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 	serverAzureName := r.flexibleServerAzureName(db, existingPtr)
 	db.Status.ServerName = serverAzureName
 
-	// define if dev/prod profile
-	profile := dbUtil.GetProfile(db.Spec.ServerType)
+	profile, err := resolveServerProfile(r.Config, db)
+	if err != nil {
+		return err
+	}
 
 	// define storage size and tier
-	storage := desiredStorage(db)
-	backup := desiredBackup(db)
-	highAvailability := desiredHighAvailability(db)
-	maintenanceWindow := desiredMaintenanceWindow()
+	storage := desiredStorage(db, profile)
+	backup := desiredBackup(db, profile)
+	highAvailability := desiredHighAvailability(db, profile)
+	maintenanceWindow := desiredMaintenanceWindow(db)
 
 	versionStr := fmt.Sprintf("%d", db.Spec.Version)
 	version := dbforpostgresqlv1.PostgresMajorVersion(versionStr)
@@ -347,6 +336,9 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 
 		AuthConfig: authConfig,
 	}
+	applyFlexibleServerRestore(&desiredSpec, restore, existingPtr)
+	applyFlexibleServerPlacement(&desiredSpec)
+	applyFlexibleServerEncryption(&desiredSpec, encryption, existingPtr)
 
 	desiredLabels := map[string]string{
 		databaseServerNameLabelKey: db.Name,
@@ -371,12 +363,13 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 			"k8sName", k8sName,
 			"azureName", serverAzureName,
 			"namespace", ns,
-			"location", loc,
+			"location", *desiredSpec.Location,
 			"version", versionStr,
 			"subnetID", resourceReferenceLogValue(networkConfig.Network.DelegatedSubnetResourceReference),
 			"zoneID", resourceReferenceLogValue(networkConfig.Network.PrivateDnsZoneArmResourceReference),
 			"skuName", profile.SkuName,
 			"storageGB", storage.StorageSizeGB,
+			"restoreSource", resourceReferenceLogValue(desiredSpec.SourceServerResourceReference),
 		)
 
 		if err := r.Create(ctx, server); err != nil {
@@ -388,6 +381,22 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 		return nil
 	}
 
+	// A major version upgrade only reaches the FlexibleServer once its
+	// pre-checks have passed, and a downgrade never does.
+	currentVersion := postgresMajorVersion(existing.Spec.Version)
+	if version := desiredServerVersion(db, currentVersion); version != db.Spec.Version {
+		desiredSpec.Version = to.Ptr(dbforpostgresqlv1.PostgresMajorVersion(fmt.Sprintf("%d", version)))
+	}
+
+	// SKU and major version changes restart the server, so they wait for the
+	// maintenance window unless the apply-now annotation releases them.
+	now := time.Now()
+	applyNow := applyRestartingChangesNow(db, now)
+	var pending []pendingServerChange
+	if !applyNow {
+		pending = holdRestartingChanges(&existing.Spec, &desiredSpec)
+	}
+
 	var updated bool
 	existing.Labels, updated = k8sutil.SyncSpecAndLabels(&existing.Spec, desiredSpec, existing.Labels, desiredLabels)
 	if updated {
@@ -401,5 +410,14 @@ func (r *DatabaseServerReconciler) ensurePostgresServer(
 		}
 	}
 
-	return nil
+	// The apply-now annotation is removed once the read replicas have caught
+	// up as well, see reconcileCommonDatabaseServerResources.
+	if len(pending) > 0 {
+		logger.Info("holding restarting FlexibleServer changes until the maintenance window",
+			"pendingChanges", len(pending),
+			"nextWindow", resolveMaintenanceWindow(db).nextStart(now),
+		)
+	}
+
+	return r.setPendingChangesCondition(ctx, db, pending, now)
 }
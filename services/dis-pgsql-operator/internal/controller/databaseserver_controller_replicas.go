@@ -0,0 +1,382 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	k8sutil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/k8s"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
+)
+
+const (
+	// databaseServerConditionReplicasReady reports the state of the read replicas
+	// declared in spec.replicas. Like DebugAccessReady it never affects the core
+	// Ready condition: the primary is usable while replicas are provisioning.
+	databaseServerConditionReplicasReady = "ReplicasReady"
+
+	databaseServerReasonReplicasProvisioned = "Provisioned"
+
+	// databaseServerReasonReplicasUnsupported marks replicas requested on a
+	// server type whose compute tier cannot host read replicas (Burstable).
+	databaseServerReasonReplicasUnsupported = "Unsupported"
+
+	// replicaNameLabelKey marks a FlexibleServer as a read replica and carries
+	// its spec.replicas name, so replicas can be listed and pruned.
+	replicaNameLabelKey = "dis.altinn.cloud/replica-name"
+)
+
+// readReplicaResourceName is the Kubernetes name of the FlexibleServer backing
+// one read replica.
+func readReplicaResourceName(serverName, replicaName string) string {
+	return fmt.Sprintf("%s-replica-%s", serverName, replicaName)
+}
+
+// ensureReadReplicas reconciles one read-replica FlexibleServer per
+// spec.replicas entry, prunes replicas no longer declared, and records their
+// hosts in status.replicas. Replicas copy the primary's size, storage and
+// network from its FlexibleServer spec, so they are only created once the
+// primary has been provisioned in Azure. Restarting changes to an existing
+// replica wait for the maintenance window like the primary's; the returned
+// interval requeues towards the window while any are held.
+func (r *DatabaseServerReconciler) ensureReadReplicas(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+) (time.Duration, error) {
+	if len(db.Spec.Replicas) == 0 {
+		if err := r.pruneReadReplicas(ctx, logger, db, nil); err != nil {
+			return 0, err
+		}
+		return 0, r.setReadReplicaStatus(ctx, db, nil, metav1.ConditionUnknown, "", "")
+	}
+
+	// Leave existing replicas alone when the server type no longer supports
+	// them: deleting Azure servers because of a profile change is too
+	// destructive to do implicitly.
+	profile, err := resolveServerProfile(r.Config, db)
+	if err != nil {
+		return 0, err
+	}
+	if !profile.SupportsReadReplicas() {
+		return 0, r.setReadReplicaStatus(ctx, db, db.Status.Replicas, metav1.ConditionFalse,
+			databaseServerReasonReplicasUnsupported,
+			fmt.Sprintf("Read replicas are not supported on the %s tier of the server profile", profile.SkuTier),
+		)
+	}
+
+	var primary dbforpostgresqlv1.FlexibleServer
+	if err := r.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &primary); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return 0, fmt.Errorf("get FlexibleServer %s/%s: %w", db.Namespace, db.Name, err)
+		}
+	}
+	if primary.Name == "" || strings.TrimSpace(db.Status.ResourceID) == "" {
+		return 0, r.setReadReplicaStatus(ctx, db, db.Status.Replicas, metav1.ConditionFalse,
+			databaseServerReasonWaiting,
+			"Waiting for the primary server to be provisioned",
+		)
+	}
+
+	statuses := make([]storagev1alpha1.DatabaseServerReplicaStatus, 0, len(db.Spec.Replicas))
+	desired := make(map[string]struct{}, len(db.Spec.Replicas))
+	var pending []string
+	var held bool
+	now := time.Now()
+	for _, replica := range db.Spec.Replicas {
+		status, replicaHeld, err := r.ensureReadReplica(ctx, logger, db, &primary, replica, now)
+		if err != nil {
+			return 0, err
+		}
+		held = held || replicaHeld
+		desired[readReplicaResourceName(db.Name, replica.Name)] = struct{}{}
+		statuses = append(statuses, status)
+		if !status.Ready {
+			pending = append(pending, replica.Name)
+		}
+	}
+
+	if err := r.pruneReadReplicas(ctx, logger, db, desired); err != nil {
+		return 0, err
+	}
+
+	var requeue time.Duration
+	if held {
+		requeue = maintenanceWindowRequeue(db, now)
+	}
+	if len(pending) > 0 {
+		return requeue, r.setReadReplicaStatus(ctx, db, statuses, metav1.ConditionFalse,
+			databaseServerReasonWaiting,
+			fmt.Sprintf("Waiting for read replica(s) to be ready: %s", strings.Join(pending, ", ")),
+		)
+	}
+	return requeue, r.setReadReplicaStatus(ctx, db, statuses, metav1.ConditionTrue,
+		databaseServerReasonReplicasProvisioned,
+		"Read replicas are provisioned",
+	)
+}
+
+// ensureReadReplica creates or updates the FlexibleServer for one replica and
+// returns its observed status, and whether restarting changes to it were held
+// until the maintenance window.
+func (r *DatabaseServerReconciler) ensureReadReplica(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	primary *dbforpostgresqlv1.FlexibleServer,
+	replica storagev1alpha1.DatabaseServerReplicaSpec,
+	now time.Time,
+) (storagev1alpha1.DatabaseServerReplicaStatus, bool, error) {
+	name := readReplicaResourceName(db.Name, replica.Name)
+	key := types.NamespacedName{Name: name, Namespace: db.Namespace}
+
+	var existing dbforpostgresqlv1.FlexibleServer
+	found := true
+	if err := r.Get(ctx, key, &existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return storagev1alpha1.DatabaseServerReplicaStatus{}, false, fmt.Errorf("get read replica FlexibleServer %s/%s: %w", db.Namespace, name, err)
+		}
+		found = false
+	}
+	if found && !metav1.IsControlledBy(&existing, db) {
+		return storagev1alpha1.DatabaseServerReplicaStatus{}, false, fmt.Errorf("FlexibleServer %s/%s exists but is not owned by DatabaseServer %s", db.Namespace, name, db.Name)
+	}
+
+	var existingPtr *dbforpostgresqlv1.FlexibleServer
+	if found {
+		existingPtr = &existing
+	}
+	desiredSpec := desiredReadReplicaSpec(db, primary, replica, existingPtr)
+	desiredLabels := map[string]string{
+		databaseServerNameLabelKey: db.Name,
+		replicaNameLabelKey:        replica.Name,
+	}
+
+	if !found {
+		server := &dbforpostgresqlv1.FlexibleServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: db.Namespace,
+				Labels:    desiredLabels,
+			},
+			Spec: desiredSpec,
+		}
+		if err := controllerutil.SetControllerReference(db, server, r.Scheme); err != nil {
+			return storagev1alpha1.DatabaseServerReplicaStatus{}, false, fmt.Errorf("set controller reference on read replica FlexibleServer: %w", err)
+		}
+
+		logger.Info("creating PostgreSQL read replica FlexibleServer",
+			"k8sName", name,
+			"azureName", desiredSpec.AzureName,
+			"namespace", db.Namespace,
+		)
+		if err := r.Create(ctx, server); err != nil && !apierrors.IsAlreadyExists(err) {
+			return storagev1alpha1.DatabaseServerReplicaStatus{}, false, fmt.Errorf("create read replica FlexibleServer %s/%s: %w", db.Namespace, name, err)
+		}
+		return storagev1alpha1.DatabaseServerReplicaStatus{
+			Name:       replica.Name,
+			ServerName: desiredSpec.AzureName,
+		}, false, nil
+	}
+
+	// The primary's spec only carries restarting changes once they were
+	// released for it, but a replica that missed that reconcile must not
+	// restart outside the window when it catches up.
+	var held []pendingServerChange
+	if !applyRestartingChangesNow(db, now) {
+		held = holdRestartingChanges(&existing.Spec, &desiredSpec)
+	}
+	if len(held) > 0 {
+		logger.Info("holding restarting read replica changes until the maintenance window",
+			"k8sName", name,
+			"pendingChanges", len(held),
+			"nextWindow", resolveMaintenanceWindow(db).nextStart(now),
+		)
+	}
+
+	var updated bool
+	existing.Labels, updated = k8sutil.SyncSpecAndLabels(&existing.Spec, desiredSpec, existing.Labels, desiredLabels)
+	if updated {
+		logger.Info("updating PostgreSQL read replica FlexibleServer", "k8sName", name, "namespace", db.Namespace)
+		if err := r.Update(ctx, &existing); err != nil {
+			return storagev1alpha1.DatabaseServerReplicaStatus{}, false, fmt.Errorf("update read replica FlexibleServer %s/%s: %w", db.Namespace, name, err)
+		}
+	}
+
+	return readReplicaStatusFromFlexibleServer(replica.Name, &existing), len(held) > 0, nil
+}
+
+// desiredReadReplicaSpec builds the FlexibleServer spec of a read replica from
+// the primary's spec. The replica is sourced from the primary by Kubernetes
+// reference so ASO resolves the ARM ID, and is encrypted with the primary's
+// customer-managed key. High availability is not supported on replicas and is
+// always disabled.
+func desiredReadReplicaSpec(
+	db *storagev1alpha1.DatabaseServer,
+	primary *dbforpostgresqlv1.FlexibleServer,
+	replica storagev1alpha1.DatabaseServerReplicaSpec,
+	existing *dbforpostgresqlv1.FlexibleServer,
+) dbforpostgresqlv1.FlexibleServer_Spec {
+	azureName := ""
+	if existing != nil {
+		azureName = strings.TrimSpace(existing.Spec.AzureName)
+	}
+	if azureName == "" {
+		primaryAzureName := strings.TrimSpace(primary.Spec.AzureName)
+		if primaryAzureName == "" {
+			primaryAzureName = db.Name
+		}
+		azureName = naming.WithRequiredSuffix(primaryAzureName, "-"+replica.Name, flexibleServerNameMaxLen, flexibleServerNameFallback)
+	}
+
+	haDisabled := dbforpostgresqlv1.HighAvailability_Mode_Disabled
+	return dbforpostgresqlv1.FlexibleServer_Spec{
+		AzureName:  azureName,
+		Location:   primary.Spec.Location,
+		Owner:      primary.Spec.Owner,
+		CreateMode: to.Ptr(dbforpostgresqlv1.CreateMode_Replica),
+		SourceServerResourceReference: &genruntime.ResourceReference{
+			Group: dbforpostgresqlv1.GroupVersion.Group,
+			Kind:  "FlexibleServer",
+			Name:  primary.Name,
+		},
+		Version:          primary.Spec.Version,
+		Network:          primary.Spec.Network,
+		Storage:          primary.Spec.Storage,
+		Sku:              primary.Spec.Sku,
+		AuthConfig:       primary.Spec.AuthConfig,
+		Identity:         primary.Spec.Identity,
+		DataEncryption:   primary.Spec.DataEncryption,
+		HighAvailability: &dbforpostgresqlv1.HighAvailability{Mode: &haDisabled},
+		Tags:             primary.Spec.Tags,
+	}
+}
+
+func readReplicaStatusFromFlexibleServer(
+	replicaName string,
+	server *dbforpostgresqlv1.FlexibleServer,
+) storagev1alpha1.DatabaseServerReplicaStatus {
+	status := storagev1alpha1.DatabaseServerReplicaStatus{
+		Name:       replicaName,
+		ServerName: server.Spec.AzureName,
+	}
+	if server.Status.FullyQualifiedDomainName != nil {
+		status.Host = strings.TrimSpace(*server.Status.FullyQualifiedDomainName)
+	}
+	cond, ok := findReadyCondition(server.Status.Conditions)
+	status.Ready = ok && cond.Status == metav1.ConditionTrue && status.Host != ""
+	return status
+}
+
+// listReadReplicas returns the read-replica FlexibleServers owned by this
+// DatabaseServer.
+func (r *DatabaseServerReconciler) listReadReplicas(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+) ([]dbforpostgresqlv1.FlexibleServer, error) {
+	var list dbforpostgresqlv1.FlexibleServerList
+	if err := r.List(
+		ctx,
+		&list,
+		client.InNamespace(db.Namespace),
+		client.MatchingLabels{databaseServerNameLabelKey: db.Name},
+		client.HasLabels{replicaNameLabelKey},
+	); err != nil {
+		return nil, fmt.Errorf("list read replica FlexibleServers: %w", err)
+	}
+
+	replicas := make([]dbforpostgresqlv1.FlexibleServer, 0, len(list.Items))
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], db) {
+			replicas = append(replicas, list.Items[i])
+		}
+	}
+	return replicas, nil
+}
+
+// pruneReadReplicas deletes read-replica FlexibleServers owned by this
+// DatabaseServer that are no longer declared in spec.replicas.
+func (r *DatabaseServerReconciler) pruneReadReplicas(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	desired map[string]struct{},
+) error {
+	replicas, err := r.listReadReplicas(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i := range replicas {
+		item := replicas[i]
+		if _, keep := desired[item.Name]; keep {
+			continue
+		}
+		if !item.DeletionTimestamp.IsZero() {
+			continue
+		}
+		logger.Info("pruning read replica FlexibleServer", "name", item.Name, "namespace", item.Namespace)
+		if err := client.IgnoreNotFound(r.Delete(ctx, &item)); err != nil {
+			return fmt.Errorf("delete read replica FlexibleServer %s/%s: %w", item.Namespace, item.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// setReadReplicaStatus records status.replicas and the ReplicasReady condition
+// and persists them. An empty reason removes the condition, used when no
+// replicas are declared.
+func (r *DatabaseServerReconciler) setReadReplicaStatus(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+	replicas []storagev1alpha1.DatabaseServerReplicaStatus,
+	status metav1.ConditionStatus,
+	reason, message string,
+) error {
+	previousStatus := db.Status.DeepCopy()
+
+	db.Status.Replicas = replicas
+	if reason == "" {
+		meta.RemoveStatusCondition(&db.Status.Conditions, databaseServerConditionReplicasReady)
+	} else {
+		meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+			Type:               databaseServerConditionReplicasReady,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: db.Generation,
+		})
+	}
+
+	if apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, db)
+}
+
+// readOnlyHost returns the host apps should use for read-only traffic: the
+// first ready read replica, or "" when the server has none.
+func readOnlyHost(db *storagev1alpha1.DatabaseServer) string {
+	for _, replica := range db.Status.Replicas {
+		if replica.Ready && replica.Host != "" {
+			return replica.Host
+		}
+	}
+	return ""
+}
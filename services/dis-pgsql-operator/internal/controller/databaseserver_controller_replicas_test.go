@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
+	asoconditions "github.com/Azure/azure-service-operator/v2/pkg/genruntime/conditions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testReplicaPrimary() *dbforpostgresqlv1.FlexibleServer {
+	primary := &dbforpostgresqlv1.FlexibleServer{}
+	primary.Name = "my-app-db"
+	primary.Spec = dbforpostgresqlv1.FlexibleServer_Spec{
+		AzureName: "my-app-db-envtest",
+		Location:  to.Ptr(loc),
+		Owner:     &genruntime.KnownResourceReference{ARMID: "/subscriptions/sub/resourceGroups/rg"},
+		Version:   to.Ptr(dbforpostgresqlv1.PostgresMajorVersion("17")),
+		Sku: &dbforpostgresqlv1.Sku{
+			Name: to.Ptr("Standard_D4s_v3"),
+			Tier: to.Ptr(dbforpostgresqlv1.SkuTier_GeneralPurpose),
+		},
+		HighAvailability: &dbforpostgresqlv1.HighAvailability{
+			Mode: to.Ptr(dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant),
+		},
+	}
+	return primary
+}
+
+func TestDesiredReadReplicaSpec(t *testing.T) {
+	db := &storagev1alpha1.DatabaseServer{}
+	db.Name = "my-app-db"
+	primary := testReplicaPrimary()
+
+	spec := desiredReadReplicaSpec(db, primary, storagev1alpha1.DatabaseServerReplicaSpec{Name: "reporting"}, nil)
+
+	if spec.AzureName != "my-app-db-envtest-reporting" {
+		t.Fatalf("expected replica AzureName derived from primary, got %q", spec.AzureName)
+	}
+	if spec.CreateMode == nil || *spec.CreateMode != dbforpostgresqlv1.CreateMode_Replica {
+		t.Fatalf("expected CreateMode=Replica, got %#v", spec.CreateMode)
+	}
+	if spec.SourceServerResourceReference == nil ||
+		spec.SourceServerResourceReference.Name != primary.Name ||
+		spec.SourceServerResourceReference.Kind != "FlexibleServer" {
+		t.Fatalf("expected source reference to the primary FlexibleServer, got %#v", spec.SourceServerResourceReference)
+	}
+	if spec.Sku == nil || *spec.Sku.Name != "Standard_D4s_v3" {
+		t.Fatalf("expected replica to copy primary SKU, got %#v", spec.Sku)
+	}
+	if spec.HighAvailability == nil || *spec.HighAvailability.Mode != dbforpostgresqlv1.HighAvailability_Mode_Disabled {
+		t.Fatalf("expected high availability disabled on replica, got %#v", spec.HighAvailability)
+	}
+}
+
+func TestDesiredReadReplicaSpecKeepsExistingAzureName(t *testing.T) {
+	db := &storagev1alpha1.DatabaseServer{}
+	db.Name = "my-app-db"
+	existing := &dbforpostgresqlv1.FlexibleServer{}
+	existing.Spec.AzureName = "legacy-replica-name"
+
+	spec := desiredReadReplicaSpec(db, testReplicaPrimary(), storagev1alpha1.DatabaseServerReplicaSpec{Name: "reporting"}, existing)
+	if spec.AzureName != "legacy-replica-name" {
+		t.Fatalf("expected existing AzureName to be kept, got %q", spec.AzureName)
+	}
+}
+
+func TestReadReplicaStatusFromFlexibleServer(t *testing.T) {
+	server := &dbforpostgresqlv1.FlexibleServer{}
+	server.Spec.AzureName = "my-app-db-envtest-reporting"
+	server.Status.FullyQualifiedDomainName = to.Ptr("my-app-db-envtest-reporting.postgres.database.azure.com")
+
+	status := readReplicaStatusFromFlexibleServer("reporting", server)
+	if status.Ready {
+		t.Fatalf("expected replica without Ready condition to be not ready")
+	}
+
+	server.Status.Conditions = []asoconditions.Condition{{
+		Type:   asoconditions.ConditionTypeReady,
+		Status: metav1.ConditionTrue,
+	}}
+	status = readReplicaStatusFromFlexibleServer("reporting", server)
+	if !status.Ready || status.Host != "my-app-db-envtest-reporting.postgres.database.azure.com" {
+		t.Fatalf("expected ready replica with host, got %#v", status)
+	}
+}
+
+func TestReadOnlyHost(t *testing.T) {
+	db := &storagev1alpha1.DatabaseServer{}
+	if got := readOnlyHost(db); got != "" {
+		t.Fatalf("expected no read-only host without replicas, got %q", got)
+	}
+
+	db.Status.Replicas = []storagev1alpha1.DatabaseServerReplicaStatus{
+		{Name: "a", Host: "a.postgres.database.azure.com"},
+		{Name: "b", Host: "b.postgres.database.azure.com", Ready: true},
+	}
+	if got := readOnlyHost(db); got != "b.postgres.database.azure.com" {
+		t.Fatalf("expected first ready replica host, got %q", got)
+	}
+}
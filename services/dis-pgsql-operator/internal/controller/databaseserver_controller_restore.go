@@ -0,0 +1,330 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
+)
+
+const (
+	// databaseServerReasonRestoreSourceNotReady marks a restore that is waiting for
+	// its source DatabaseServer to exist and have a provisioned Flexible Server.
+	databaseServerReasonRestoreSourceNotReady = "RestoreSourceNotReady"
+
+	// databaseServerReasonInvalidRestore marks a spec.restore that cannot be
+	// honoured, e.g. a point in time outside the source's backup retention.
+	databaseServerReasonInvalidRestore = "InvalidRestore"
+
+	// restoreBlockedRequeueInterval re-checks a blocked restore. Both the source
+	// becoming ready and a future timestamp becoming valid happen without an
+	// event on this DatabaseServer.
+	restoreBlockedRequeueInterval = time.Minute
+)
+
+// flexibleServerRestore is the resolved restore applied when the
+// FlexibleServer is first created. PointInTime is unset for a geo-restore;
+// SubnetResourceID is only set for one, since the server must be in the
+// region of its delegated subnet.
+type flexibleServerRestore struct {
+	Type             storagev1alpha1.DatabaseServerRestoreType
+	SourceResourceID string
+	PointInTime      time.Time
+	Location         string
+	SubnetResourceID string
+}
+
+// resolvePostgresServerRestore resolves spec.restore into the source server ARM
+// ID, timestamp and location used to create the FlexibleServer. Restore
+// settings are creation-only, so nothing is resolved once the FlexibleServer
+// exists. When the restore cannot proceed yet (or at all) it records the
+// reason on the DatabaseServer status and returns blocked=true.
+func (r *DatabaseServerReconciler) resolvePostgresServerRestore(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+) (*flexibleServerRestore, bool, error) {
+	if db.Spec.Restore == nil {
+		return nil, false, nil
+	}
+
+	var existing dbforpostgresqlv1.FlexibleServer
+	if err := r.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &existing); err == nil {
+		return nil, false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, false, fmt.Errorf("get FlexibleServer %s/%s: %w", db.Namespace, db.Name, err)
+	}
+
+	restoreType := databaseServerRestoreType(db.Spec.Restore)
+	sourceName := strings.TrimSpace(db.Spec.Restore.SourceServer.Name)
+	if sourceName == "" || sourceName == db.Name {
+		return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+			storagev1alpha1.DatabaseServerRestorePhaseFailed,
+			databaseServerReasonInvalidRestore,
+			"spec.restore.sourceServer.name must reference another DatabaseServer in the same namespace",
+		)
+	}
+
+	var source storagev1alpha1.DatabaseServer
+	if err := r.Get(ctx, types.NamespacedName{Name: sourceName, Namespace: db.Namespace}, &source); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("restore source DatabaseServer not found", "source", sourceName)
+			return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+				storagev1alpha1.DatabaseServerRestorePhasePending,
+				databaseServerReasonRestoreSourceNotReady,
+				fmt.Sprintf("Restore source DatabaseServer %q not found", sourceName),
+			)
+		}
+		return nil, false, fmt.Errorf("get restore source DatabaseServer %s/%s: %w", db.Namespace, sourceName, err)
+	}
+
+	if source.Spec.Version != db.Spec.Version {
+		return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+			storagev1alpha1.DatabaseServerRestorePhaseFailed,
+			databaseServerReasonInvalidRestore,
+			fmt.Sprintf("spec.version %d must match the restore source version %d", db.Spec.Version, source.Spec.Version),
+		)
+	}
+
+	sourceResourceID := strings.TrimSpace(source.Status.ResourceID)
+	if sourceResourceID == "" {
+		logger.Info("waiting for restore source FlexibleServer to be provisioned", "source", sourceName)
+		return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+			storagev1alpha1.DatabaseServerRestorePhasePending,
+			databaseServerReasonRestoreSourceNotReady,
+			fmt.Sprintf("Waiting for restore source DatabaseServer %q to be provisioned", sourceName),
+		)
+	}
+
+	sourceProfile, err := resolveServerProfile(r.Config, &source)
+	if err != nil {
+		return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+			storagev1alpha1.DatabaseServerRestorePhasePending,
+			databaseServerReasonRestoreSourceNotReady,
+			fmt.Sprintf("Restore source DatabaseServer %q has an invalid profile: %v", sourceName, err),
+		)
+	}
+
+	restore := &flexibleServerRestore{
+		Type:             restoreType,
+		SourceResourceID: sourceResourceID,
+		Location:         loc,
+	}
+	var message string
+	switch restoreType {
+	case storagev1alpha1.DatabaseServerRestoreTypeGeoRestore:
+		// A geo-restore reads the copy of the backups Azure keeps in the paired
+		// region, which only exists when the source was created with it.
+		if enabled, err := dbUtil.ResolveGeoRedundantBackup(sourceProfile, source.Spec.GeoRedundantBackup); err != nil || !enabled {
+			return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+				storagev1alpha1.DatabaseServerRestorePhaseFailed,
+				databaseServerReasonInvalidRestore,
+				fmt.Sprintf("Restore source DatabaseServer %q does not have spec.geoRedundantBackup enabled", sourceName),
+			)
+		}
+		subnetID, err := pairedRegionSubnet(r.Config, db)
+		if err != nil {
+			return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+				storagev1alpha1.DatabaseServerRestorePhaseFailed,
+				databaseServerReasonInvalidRestore,
+				err.Error(),
+			)
+		}
+		restore.Location = pairedLocation
+		restore.SubnetResourceID = subnetID
+		message = fmt.Sprintf("Geo-restoring from DatabaseServer %q to %s", sourceName, pairedLocation)
+	default:
+		if db.Spec.Restore.PointInTime == nil {
+			return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+				storagev1alpha1.DatabaseServerRestorePhaseFailed,
+				databaseServerReasonInvalidRestore,
+				"spec.restore.pointInTime is required for a PointInTime restore",
+			)
+		}
+		pointInTime := db.Spec.Restore.PointInTime.Time
+		retentionDays := dbUtil.ResolveBackupRetentionDays(sourceProfile, source.Spec.BackupRetentionDays)
+		if err := dbUtil.ValidateRestorePointInTime(pointInTime, source.CreationTimestamp.Time, time.Now(), retentionDays); err != nil {
+			return nil, true, r.setDatabaseServerRestoreBlocked(ctx, db,
+				storagev1alpha1.DatabaseServerRestorePhaseFailed,
+				databaseServerReasonInvalidRestore,
+				fmt.Sprintf("spec.restore.pointInTime is invalid: %v", err),
+			)
+		}
+		restore.PointInTime = pointInTime
+		message = fmt.Sprintf("Restoring from DatabaseServer %q", sourceName)
+	}
+
+	previousStatus := db.Status.DeepCopy()
+	db.Status.Restore = &storagev1alpha1.DatabaseServerRestoreStatus{
+		Phase:            storagev1alpha1.DatabaseServerRestorePhaseRestoring,
+		Type:             restoreType,
+		SourceServerName: sourceName,
+		SourceResourceID: sourceResourceID,
+		PointInTime:      db.Spec.Restore.PointInTime.DeepCopy(),
+		Location:         restore.Location,
+		Message:          message,
+	}
+	if !apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		if err := r.Status().Update(ctx, db); err != nil {
+			return nil, false, fmt.Errorf("update database server restore status: %w", err)
+		}
+	}
+
+	return restore, false, nil
+}
+
+// pairedRegionSubnet returns the delegated subnet a geo-restored server is
+// created in: spec.network's subnet, which must be one of the operator's
+// paired-region subnets. The operator's own subnets are in the primary
+// region, where Azure would reject the server.
+func pairedRegionSubnet(cfg config.OperatorConfig, db *storagev1alpha1.DatabaseServer) (string, error) {
+	if len(cfg.PairedRegionSubnets) == 0 {
+		return "", fmt.Errorf("no delegated subnet in %s is configured (--paired-region-subnet-ids), so geo-restore is unavailable", pairedLocation)
+	}
+	subnetID := ""
+	if db.Spec.Network != nil {
+		subnetID = strings.TrimSpace(db.Spec.Network.DelegatedSubnetResourceID)
+	}
+	for _, candidate := range cfg.PairedRegionSubnets {
+		if subnetID != "" && strings.EqualFold(subnetID, candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("spec.network.delegatedSubnetResourceId %q is not a delegated subnet in %s; a geo-restore must use one of %s",
+		subnetID, pairedLocation, strings.Join(cfg.PairedRegionSubnets, ", "))
+}
+
+// databaseServerRestoreType returns spec.restore.type, defaulting to
+// PointInTime.
+func databaseServerRestoreType(restore *storagev1alpha1.DatabaseServerRestoreSpec) storagev1alpha1.DatabaseServerRestoreType {
+	if restore.Type == "" {
+		return storagev1alpha1.DatabaseServerRestoreTypePointInTime
+	}
+	return restore.Type
+}
+
+// setDatabaseServerRestoreBlocked records why a restore cannot proceed on both
+// status.restore and the Ready condition.
+func (r *DatabaseServerReconciler) setDatabaseServerRestoreBlocked(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+	phase storagev1alpha1.DatabaseServerRestorePhase,
+	reason, message string,
+) error {
+	previousStatus := db.Status.DeepCopy()
+
+	db.Status.Restore = &storagev1alpha1.DatabaseServerRestoreStatus{
+		Phase:            phase,
+		Type:             databaseServerRestoreType(db.Spec.Restore),
+		SourceServerName: strings.TrimSpace(db.Spec.Restore.SourceServer.Name),
+		PointInTime:      db.Spec.Restore.PointInTime.DeepCopy(),
+		Message:          message,
+	}
+	meta.SetStatusCondition(&db.Status.Conditions, metav1.Condition{
+		Type:               databaseServerConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: db.Generation,
+	})
+
+	if apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, db)
+}
+
+// applyFlexibleServerRestore sets the creation-only restore fields and the
+// location on the desired FlexibleServer spec. For an existing server the
+// fields it was created with are carried over so later reconciles never drift
+// them.
+func applyFlexibleServerRestore(
+	spec *dbforpostgresqlv1.FlexibleServer_Spec,
+	restore *flexibleServerRestore,
+	existing *dbforpostgresqlv1.FlexibleServer,
+) {
+	if existing != nil {
+		spec.CreateMode = existing.Spec.CreateMode
+		spec.SourceServerResourceReference = existing.Spec.SourceServerResourceReference
+		spec.PointInTimeUTC = existing.Spec.PointInTimeUTC
+		if existing.Spec.Location != nil {
+			spec.Location = existing.Spec.Location
+		}
+		return
+	}
+	if restore == nil {
+		return
+	}
+
+	spec.SourceServerResourceReference = &genruntime.ResourceReference{
+		ARMID: restore.SourceResourceID,
+	}
+	if restore.Location != "" {
+		spec.Location = to.Ptr(restore.Location)
+	}
+	if restore.Type == storagev1alpha1.DatabaseServerRestoreTypeGeoRestore {
+		spec.CreateMode = to.Ptr(dbforpostgresqlv1.CreateMode_GeoRestore)
+		if spec.Network != nil && restore.SubnetResourceID != "" {
+			network := *spec.Network
+			network.DelegatedSubnetResourceReference = &genruntime.ResourceReference{ARMID: restore.SubnetResourceID}
+			spec.Network = &network
+		}
+		return
+	}
+	spec.CreateMode = to.Ptr(dbforpostgresqlv1.CreateMode_PointInTimeRestore)
+	spec.PointInTimeUTC = to.Ptr(restore.PointInTime.UTC().Format(time.RFC3339))
+}
+
+// applyFlexibleServerPlacement adapts zone placement to the server location.
+// The paired region has no availability zones, so a server there gets no
+// zone and runs zone-redundant high availability as same-zone instead.
+func applyFlexibleServerPlacement(spec *dbforpostgresqlv1.FlexibleServer_Spec) {
+	if spec.Location == nil || *spec.Location != pairedLocation {
+		return
+	}
+	spec.AvailabilityZone = nil
+	if ha := spec.HighAvailability; ha != nil && ha.Mode != nil && *ha.Mode == dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant {
+		ha.Mode = to.Ptr(dbforpostgresqlv1.HighAvailability_Mode_SameZone)
+		ha.StandbyAvailabilityZone = nil
+	}
+}
+
+// restoreStatusFromFlexibleServer advances status.restore from Restoring to
+// Completed once the restored FlexibleServer reports Ready. It returns whether
+// the status changed.
+func restoreStatusFromFlexibleServer(
+	db *storagev1alpha1.DatabaseServer,
+	server *dbforpostgresqlv1.FlexibleServer,
+) bool {
+	if db.Status.Restore == nil || db.Status.Restore.Phase != storagev1alpha1.DatabaseServerRestorePhaseRestoring {
+		return false
+	}
+
+	cond, ok := findReadyCondition(server.Status.Conditions)
+	if !ok || cond.Status != metav1.ConditionTrue {
+		return false
+	}
+
+	db.Status.Restore.Phase = storagev1alpha1.DatabaseServerRestorePhaseCompleted
+	if db.Status.Restore.Type == storagev1alpha1.DatabaseServerRestoreTypeGeoRestore {
+		db.Status.Restore.Message = fmt.Sprintf("Geo-restored from DatabaseServer %q to %s", db.Status.Restore.SourceServerName, db.Status.Restore.Location)
+	} else {
+		db.Status.Restore.Message = fmt.Sprintf("Restored from DatabaseServer %q", db.Status.Restore.SourceServerName)
+	}
+	return true
+}
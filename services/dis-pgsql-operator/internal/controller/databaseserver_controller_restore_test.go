@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	to "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	genruntime "github.com/Azure/azure-service-operator/v2/pkg/genruntime"
+	asoconditions "github.com/Azure/azure-service-operator/v2/pkg/genruntime/conditions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testRestoreSourceID  = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.DBforPostgreSQL/flexibleServers/source-db"
+	testPrimarySubnetID  = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/pg-norwayeast/subnets/pg"
+	testPairedSubnetID   = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/pg-norwaywest/subnets/pg"
+	testPrivateDNSZoneID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/pg.postgres.database.azure.com"
+)
+
+func TestApplyFlexibleServerRestoreOnCreate(t *testing.T) {
+	pointInTime := time.Date(2026, 5, 20, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	spec := dbforpostgresqlv1.FlexibleServer_Spec{}
+
+	applyFlexibleServerRestore(&spec, &flexibleServerRestore{
+		SourceResourceID: testRestoreSourceID,
+		PointInTime:      pointInTime,
+	}, nil)
+
+	if spec.CreateMode == nil || *spec.CreateMode != dbforpostgresqlv1.CreateMode_PointInTimeRestore {
+		t.Fatalf("expected CreateMode=PointInTimeRestore, got %#v", spec.CreateMode)
+	}
+	if spec.SourceServerResourceReference == nil || spec.SourceServerResourceReference.ARMID != testRestoreSourceID {
+		t.Fatalf("expected source ARM ID %q, got %#v", testRestoreSourceID, spec.SourceServerResourceReference)
+	}
+	if spec.PointInTimeUTC == nil || *spec.PointInTimeUTC != "2026-05-20T08:30:00Z" {
+		t.Fatalf("expected UTC point in time, got %#v", spec.PointInTimeUTC)
+	}
+}
+
+func TestApplyFlexibleServerRestoreWithoutRestore(t *testing.T) {
+	spec := dbforpostgresqlv1.FlexibleServer_Spec{}
+	applyFlexibleServerRestore(&spec, nil, nil)
+
+	if spec.CreateMode != nil || spec.SourceServerResourceReference != nil || spec.PointInTimeUTC != nil {
+		t.Fatalf("expected no restore fields, got %#v", spec)
+	}
+}
+
+func TestApplyFlexibleServerRestoreCarriesOverExistingFields(t *testing.T) {
+	existing := &dbforpostgresqlv1.FlexibleServer{}
+	existing.Spec.CreateMode = to.Ptr(dbforpostgresqlv1.CreateMode_PointInTimeRestore)
+	existing.Spec.SourceServerResourceReference = &genruntime.ResourceReference{ARMID: testRestoreSourceID}
+	existing.Spec.PointInTimeUTC = to.Ptr("2026-05-20T08:30:00Z")
+	existing.Spec.Location = to.Ptr(pairedLocation)
+
+	// A later reconcile resolves no restore (the server already exists); the
+	// creation-only fields must not be dropped from the desired spec.
+	spec := dbforpostgresqlv1.FlexibleServer_Spec{Location: to.Ptr(loc)}
+	applyFlexibleServerRestore(&spec, nil, existing)
+
+	if spec.CreateMode == nil || *spec.CreateMode != dbforpostgresqlv1.CreateMode_PointInTimeRestore {
+		t.Fatalf("expected CreateMode carried over, got %#v", spec.CreateMode)
+	}
+	if spec.SourceServerResourceReference == nil || spec.SourceServerResourceReference.ARMID != testRestoreSourceID {
+		t.Fatalf("expected source reference carried over, got %#v", spec.SourceServerResourceReference)
+	}
+	if spec.PointInTimeUTC == nil || *spec.PointInTimeUTC != "2026-05-20T08:30:00Z" {
+		t.Fatalf("expected point in time carried over, got %#v", spec.PointInTimeUTC)
+	}
+	if spec.Location == nil || *spec.Location != pairedLocation {
+		t.Fatalf("expected location carried over, got %#v", spec.Location)
+	}
+}
+
+func TestApplyFlexibleServerGeoRestoreOnCreate(t *testing.T) {
+	spec := dbforpostgresqlv1.FlexibleServer_Spec{
+		Location:         to.Ptr(loc),
+		AvailabilityZone: to.Ptr(defaultAvailabilityZone),
+		HighAvailability: &dbforpostgresqlv1.HighAvailability{
+			Mode:                    to.Ptr(dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant),
+			StandbyAvailabilityZone: to.Ptr(defaultHAStandbyZone),
+		},
+		Network: &dbforpostgresqlv1.Network{
+			DelegatedSubnetResourceReference:   &genruntime.ResourceReference{ARMID: testPrimarySubnetID},
+			PrivateDnsZoneArmResourceReference: &genruntime.ResourceReference{ARMID: testPrivateDNSZoneID},
+		},
+	}
+
+	applyFlexibleServerRestore(&spec, &flexibleServerRestore{
+		Type:             storagev1alpha1.DatabaseServerRestoreTypeGeoRestore,
+		SourceResourceID: testRestoreSourceID,
+		Location:         pairedLocation,
+		SubnetResourceID: testPairedSubnetID,
+	}, nil)
+	applyFlexibleServerPlacement(&spec)
+
+	if spec.CreateMode == nil || *spec.CreateMode != dbforpostgresqlv1.CreateMode_GeoRestore {
+		t.Fatalf("expected CreateMode=GeoRestore, got %#v", spec.CreateMode)
+	}
+	if spec.SourceServerResourceReference == nil || spec.SourceServerResourceReference.ARMID != testRestoreSourceID {
+		t.Fatalf("expected source ARM ID %q, got %#v", testRestoreSourceID, spec.SourceServerResourceReference)
+	}
+	if spec.PointInTimeUTC != nil {
+		t.Fatalf("expected no point in time for a geo-restore, got %q", *spec.PointInTimeUTC)
+	}
+	if spec.Location == nil || *spec.Location != pairedLocation {
+		t.Fatalf("expected location %q, got %#v", pairedLocation, spec.Location)
+	}
+	if spec.Network.DelegatedSubnetResourceReference == nil || spec.Network.DelegatedSubnetResourceReference.ARMID != testPairedSubnetID {
+		t.Fatalf("expected the paired-region subnet %q, got %#v", testPairedSubnetID, spec.Network.DelegatedSubnetResourceReference)
+	}
+	if spec.Network.PrivateDnsZoneArmResourceReference == nil || spec.Network.PrivateDnsZoneArmResourceReference.ARMID != testPrivateDNSZoneID {
+		t.Fatalf("expected the private DNS zone to be kept, got %#v", spec.Network.PrivateDnsZoneArmResourceReference)
+	}
+	if spec.AvailabilityZone != nil {
+		t.Fatalf("expected no availability zone in the paired region, got %q", *spec.AvailabilityZone)
+	}
+	if *spec.HighAvailability.Mode != dbforpostgresqlv1.HighAvailability_Mode_SameZone || spec.HighAvailability.StandbyAvailabilityZone != nil {
+		t.Fatalf("expected same-zone high availability without a standby zone, got %#v", spec.HighAvailability)
+	}
+}
+
+func TestApplyFlexibleServerPlacementKeepsPrimaryRegionZones(t *testing.T) {
+	spec := dbforpostgresqlv1.FlexibleServer_Spec{
+		Location:         to.Ptr(loc),
+		AvailabilityZone: to.Ptr(defaultAvailabilityZone),
+		HighAvailability: &dbforpostgresqlv1.HighAvailability{
+			Mode:                    to.Ptr(dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant),
+			StandbyAvailabilityZone: to.Ptr(defaultHAStandbyZone),
+		},
+	}
+
+	applyFlexibleServerPlacement(&spec)
+
+	if spec.AvailabilityZone == nil || *spec.HighAvailability.Mode != dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant {
+		t.Fatalf("expected zone placement to be kept in %s, got %#v", loc, spec)
+	}
+}
+
+func TestDesiredBackupGeoRedundant(t *testing.T) {
+	enabled := true
+	db := &storagev1alpha1.DatabaseServer{}
+
+	if got := desiredBackup(db, dbUtil.DefaultProfiles()[dbUtil.ProdProfileName]).GeoRedundantBackup; *got != dbforpostgresqlv1.Backup_GeoRedundantBackup_Disabled {
+		t.Fatalf("expected geo-redundant backup disabled by default, got %q", *got)
+	}
+	db.Spec.GeoRedundantBackup = &enabled
+	if got := desiredBackup(db, dbUtil.DefaultProfiles()[dbUtil.ProdProfileName]).GeoRedundantBackup; *got != dbforpostgresqlv1.Backup_GeoRedundantBackup_Enabled {
+		t.Fatalf("expected geo-redundant backup enabled on prod, got %q", *got)
+	}
+	if got := desiredBackup(db, dbUtil.DefaultProfiles()[dbUtil.DevProfileName]).GeoRedundantBackup; *got != dbforpostgresqlv1.Backup_GeoRedundantBackup_Disabled {
+		t.Fatalf("expected geo-redundant backup disabled on a profile that does not allow it, got %q", *got)
+	}
+}
+
+func TestRestoreStatusFromFlexibleServer(t *testing.T) {
+	newServer := func(status metav1.ConditionStatus) *dbforpostgresqlv1.FlexibleServer {
+		server := &dbforpostgresqlv1.FlexibleServer{}
+		server.Status.Conditions = []asoconditions.Condition{{
+			Type:   asoconditions.ConditionTypeReady,
+			Status: status,
+		}}
+		return server
+	}
+	newDB := func(phase storagev1alpha1.DatabaseServerRestorePhase) *storagev1alpha1.DatabaseServer {
+		db := &storagev1alpha1.DatabaseServer{}
+		db.Status.Restore = &storagev1alpha1.DatabaseServerRestoreStatus{
+			Phase:            phase,
+			SourceServerName: "source-db",
+		}
+		return db
+	}
+
+	t.Run("completes restore when server is ready", func(t *testing.T) {
+		db := newDB(storagev1alpha1.DatabaseServerRestorePhaseRestoring)
+		if !restoreStatusFromFlexibleServer(db, newServer(metav1.ConditionTrue)) {
+			t.Fatalf("expected status change")
+		}
+		if db.Status.Restore.Phase != storagev1alpha1.DatabaseServerRestorePhaseCompleted {
+			t.Fatalf("expected Completed phase, got %q", db.Status.Restore.Phase)
+		}
+	})
+
+	t.Run("reports the region of a completed geo-restore", func(t *testing.T) {
+		db := newDB(storagev1alpha1.DatabaseServerRestorePhaseRestoring)
+		db.Status.Restore.Type = storagev1alpha1.DatabaseServerRestoreTypeGeoRestore
+		db.Status.Restore.Location = pairedLocation
+		if !restoreStatusFromFlexibleServer(db, newServer(metav1.ConditionTrue)) {
+			t.Fatalf("expected status change")
+		}
+		if !strings.Contains(db.Status.Restore.Message, pairedLocation) {
+			t.Fatalf("expected the message to name %s, got %q", pairedLocation, db.Status.Restore.Message)
+		}
+	})
+
+	t.Run("keeps restoring while server is not ready", func(t *testing.T) {
+		db := newDB(storagev1alpha1.DatabaseServerRestorePhaseRestoring)
+		if restoreStatusFromFlexibleServer(db, newServer(metav1.ConditionFalse)) {
+			t.Fatalf("did not expect status change")
+		}
+	})
+
+	t.Run("ignores servers without restore status", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{}
+		if restoreStatusFromFlexibleServer(db, newServer(metav1.ConditionTrue)) {
+			t.Fatalf("did not expect status change")
+		}
+		if db.Status.Restore != nil {
+			t.Fatalf("expected restore status to stay nil, got %#v", db.Status.Restore)
+		}
+	})
+}
+
+func TestPairedRegionSubnet(t *testing.T) {
+	newServer := func(subnetID string) *storagev1alpha1.DatabaseServer {
+		return &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+			Mode: storagev1alpha1.DatabaseServerModeShared,
+			Network: &storagev1alpha1.DatabaseServerNetworkSpec{
+				DelegatedSubnetResourceID: subnetID,
+				PrivateDNSZoneResourceID:  testPrivateDNSZoneID,
+			},
+		}}
+	}
+	cfg := config.OperatorConfig{PairedRegionSubnets: []string{testPairedSubnetID}}
+
+	if subnetID, err := pairedRegionSubnet(cfg, newServer(strings.ToUpper(testPairedSubnetID))); err != nil || subnetID != testPairedSubnetID {
+		t.Fatalf("expected the configured paired-region subnet, got %q (%v)", subnetID, err)
+	}
+	if _, err := pairedRegionSubnet(cfg, newServer(testPrimarySubnetID)); err == nil || !strings.Contains(err.Error(), pairedLocation) {
+		t.Fatalf("expected a primary-region subnet to be rejected, got %v", err)
+	}
+	if _, err := pairedRegionSubnet(config.OperatorConfig{}, newServer(testPairedSubnetID)); err == nil || !strings.Contains(err.Error(), "--paired-region-subnet-ids") {
+		t.Fatalf("expected geo-restore to be unavailable without paired-region subnets, got %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+)
+
+const (
+	// databaseServerReasonVersionDowngrade marks a spec.version below the
+	// version the server already runs. Azure cannot downgrade PostgreSQL.
+	databaseServerReasonVersionDowngrade = "VersionDowngrade"
+
+	upgradePreCheckComponentLabelValue = "upgrade-precheck"
+
+	// upgradePreCheckRetryInterval is how long failed pre-checks wait before
+	// they run again, so fixing the problems resumes the upgrade without
+	// another spec change.
+	upgradePreCheckRetryInterval = 30 * time.Minute
+)
+
+// desiredServerVersion returns the major version to request from ASO for a
+// server that currently has current. A newer spec.version is only passed on
+// once its pre-checks have passed (see ensureMajorVersionUpgrade); until then,
+// and for a downgrade, the server keeps its current version.
+func desiredServerVersion(db *storagev1alpha1.DatabaseServer, current int) int {
+	if current == 0 || db.Spec.Version <= current {
+		return max(db.Spec.Version, current)
+	}
+	if upgradePreChecksPassed(db, current) {
+		return db.Spec.Version
+	}
+	return current
+}
+
+// upgradePreChecksPassed reports whether the pre-checks for upgrading from
+// current to spec.version have passed.
+func upgradePreChecksPassed(db *storagev1alpha1.DatabaseServer, current int) bool {
+	upgrade := db.Status.Upgrade
+	return upgrade != nil &&
+		upgrade.FromVersion == current &&
+		upgrade.ToVersion == db.Spec.Version &&
+		(upgrade.Phase == storagev1alpha1.DatabaseServerUpgradePhasePending ||
+			upgrade.Phase == storagev1alpha1.DatabaseServerUpgradePhaseUpgrading)
+}
+
+// ensureMajorVersionUpgrade drives an in-place major version upgrade through
+// status.upgrade. When spec.version is above the FlexibleServer's version it
+// runs the provisioning Job in upgrade pre-check mode and records whether the
+// server's extensions and storage allow the upgrade. Once they do, the
+// upgrade is Pending and ensurePostgresServer applies the new version in the
+// maintenance window; the phase then moves to Upgrading and Completed as
+// Azure reports the new version. Failed pre-checks are re-run every
+// upgradePreCheckRetryInterval. It returns when it needs to run again, or zero
+// when a watched change will re-trigger the reconcile.
+func (r *DatabaseServerReconciler) ensureMajorVersionUpgrade(
+	ctx context.Context,
+	logger logr.Logger,
+	db *storagev1alpha1.DatabaseServer,
+	adminIdentity resolvedAdminIdentity,
+) (time.Duration, error) {
+	var server dbforpostgresqlv1.FlexibleServer
+	if err := r.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("get FlexibleServer %s/%s: %w", db.Namespace, db.Name, err)
+	}
+
+	current := postgresMajorVersion(server.Spec.Version)
+	target := db.Spec.Version
+	if current == 0 || target <= current {
+		return 0, r.setUpgradeStatus(ctx, db, trackAppliedUpgrade(db.Status.Upgrade, current, r.flexibleServerRunsVersion(&server, current)))
+	}
+
+	upgrade := db.Status.Upgrade
+	if upgrade != nil && (upgrade.FromVersion != current || upgrade.ToVersion != target) {
+		upgrade = nil
+	}
+	if upgradePreChecksPassed(db, current) {
+		return 0, nil
+	}
+
+	var lastPreCheck time.Time
+	if upgrade != nil && upgrade.PreCheckTime != nil {
+		lastPreCheck = upgrade.PreCheckTime.Time
+		if upgrade.Phase == storagev1alpha1.DatabaseServerUpgradePhaseFailed {
+			if remaining := time.Until(lastPreCheck.Add(upgradePreCheckRetryInterval)); remaining > 0 {
+				return remaining, nil
+			}
+		}
+	}
+
+	// Azure cannot upgrade a server that has read replicas, and the Job
+	// cannot tell, so this is checked here.
+	if len(db.Spec.Replicas) > 0 {
+		return 0, r.setUpgradeStatus(ctx, db, &storagev1alpha1.DatabaseServerUpgradeStatus{
+			Phase:        storagev1alpha1.DatabaseServerUpgradePhaseFailed,
+			FromVersion:  current,
+			ToVersion:    target,
+			PreCheckTime: &metav1.Time{Time: time.Now()},
+			Message:      "Pre-checks failed: read replicas must be removed from spec.replicas before a major version upgrade",
+		})
+	}
+
+	// Mirrors ensureDatabaseCatalog: under az fakes the provisioner falls back
+	// to the in-cluster Postgres and the host is never published.
+	if !r.Config.UseAzFakes && strings.TrimSpace(db.Status.Host) == "" {
+		logger.Info("DatabaseServer status host not populated yet; deferring upgrade pre-checks")
+		return 0, nil
+	}
+
+	jobName := upgradePreCheckJobName(db, adminIdentity, current, target, lastPreCheck)
+	if err := ensureUserProvisionJobForReconciler(ctx, logger, r, userProvisionJobSpec{
+		Owner:              db,
+		JobName:            jobName,
+		Labels:             upgradePreCheckJobLabels(db.Name),
+		ServiceAccountName: adminIdentity.ServiceAccountName,
+		AdminIdentityName:  adminIdentity.Name,
+		ServerName:         db.Name,
+		DatabaseHost:       db.Status.Host,
+		DatabaseName:       debugAccessProvisionMaintenanceDatabase,
+		Mode:               dbUtil.ProvisionModeUpgradePreCheck,
+	}); err != nil {
+		return 0, err
+	}
+
+	payload, found, err := r.upgradePreCheckJobResult(ctx, logger, db.Namespace, jobName)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		preChecking := &storagev1alpha1.DatabaseServerUpgradeStatus{
+			Phase:       storagev1alpha1.DatabaseServerUpgradePhasePreChecking,
+			FromVersion: current,
+			ToVersion:   target,
+			Message:     fmt.Sprintf("Running pre-checks for the upgrade from PostgreSQL %d to %d", current, target),
+		}
+		if upgrade != nil {
+			preChecking.PreCheckTime = upgrade.PreCheckTime
+		}
+		return 0, r.setUpgradeStatus(ctx, db, preChecking)
+	}
+
+	storageGB := 0
+	if server.Spec.Storage != nil && server.Spec.Storage.StorageSizeGB != nil {
+		storageGB = *server.Spec.Storage.StorageSizeGB
+	}
+	result := upgradePreCheckResult(current, target, dbUtil.UpgradePreCheckProblems(payload, storageGB), metav1.Now())
+	logger.Info("upgrade pre-checks finished",
+		"jobName", jobName,
+		"fromVersion", current,
+		"toVersion", target,
+		"phase", result.Phase,
+	)
+	return 0, r.setUpgradeStatus(ctx, db, result)
+}
+
+// upgradePreCheckResult turns the pre-check problems into status: Pending when
+// there are none, Failed otherwise.
+func upgradePreCheckResult(current, target int, problems []string, now metav1.Time) *storagev1alpha1.DatabaseServerUpgradeStatus {
+	status := &storagev1alpha1.DatabaseServerUpgradeStatus{
+		Phase:        storagev1alpha1.DatabaseServerUpgradePhasePending,
+		FromVersion:  current,
+		ToVersion:    target,
+		PreCheckTime: now.DeepCopy(),
+		Message: fmt.Sprintf(
+			"Pre-checks passed; upgrading from PostgreSQL %d to %d in the maintenance window, or on the %s annotation",
+			current, target, applyNowAnnotation,
+		),
+	}
+	if len(problems) > 0 {
+		status.Phase = storagev1alpha1.DatabaseServerUpgradePhaseFailed
+		status.Message = "Pre-checks failed: " + strings.Join(problems, "; ")
+	}
+	return status
+}
+
+// trackAppliedUpgrade advances an upgrade whose version has been applied to
+// the FlexibleServer: Upgrading until Azure runs the new version, then
+// Completed. An upgrade that was never applied, because spec.version was
+// lowered again, is dropped.
+func trackAppliedUpgrade(
+	upgrade *storagev1alpha1.DatabaseServerUpgradeStatus,
+	current int,
+	running bool,
+) *storagev1alpha1.DatabaseServerUpgradeStatus {
+	if upgrade == nil || upgrade.Phase == storagev1alpha1.DatabaseServerUpgradePhaseCompleted {
+		return upgrade
+	}
+	if upgrade.ToVersion != current {
+		return nil
+	}
+
+	tracked := upgrade.DeepCopy()
+	if running {
+		tracked.Phase = storagev1alpha1.DatabaseServerUpgradePhaseCompleted
+		tracked.Message = fmt.Sprintf("Upgraded from PostgreSQL %d to %d", upgrade.FromVersion, upgrade.ToVersion)
+	} else {
+		tracked.Phase = storagev1alpha1.DatabaseServerUpgradePhaseUpgrading
+		tracked.Message = fmt.Sprintf("Azure is upgrading the server from PostgreSQL %d to %d", upgrade.FromVersion, upgrade.ToVersion)
+	}
+	return tracked
+}
+
+// flexibleServerRunsVersion reports whether Azure runs version on the server
+// and the server is ready again. Under az fakes nothing reports a version, so
+// an applied version counts as running.
+func (r *DatabaseServerReconciler) flexibleServerRunsVersion(server *dbforpostgresqlv1.FlexibleServer, version int) bool {
+	if r.Config.UseAzFakes {
+		return true
+	}
+	if server.Status.Version == nil || parseMajorVersion(string(*server.Status.Version)) != version {
+		return false
+	}
+	cond, ok := findReadyCondition(server.Status.Conditions)
+	return ok && cond.Status == metav1.ConditionTrue
+}
+
+func (r *DatabaseServerReconciler) setUpgradeStatus(
+	ctx context.Context,
+	db *storagev1alpha1.DatabaseServer,
+	upgrade *storagev1alpha1.DatabaseServerUpgradeStatus,
+) error {
+	previousStatus := db.Status.DeepCopy()
+	db.Status.Upgrade = upgrade
+	if apiequality.Semantic.DeepEqual(previousStatus, &db.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, db); err != nil {
+		return fmt.Errorf("update database server upgrade status: %w", err)
+	}
+	return nil
+}
+
+// upgradePreCheckJobResult returns the report of a completed pre-check Job.
+// An unreadable report is logged and treated as missing so the next pre-check
+// replaces it.
+func (r *DatabaseServerReconciler) upgradePreCheckJobResult(
+	ctx context.Context,
+	logger logr.Logger,
+	namespace string,
+	jobName string,
+) (dbUtil.UpgradePreCheckPayload, bool, error) {
+	message, found, err := r.jobTerminationReport(ctx, logger, namespace, jobName)
+	if err != nil || !found {
+		return dbUtil.UpgradePreCheckPayload{}, false, err
+	}
+	payload, err := dbUtil.UnmarshalUpgradePreCheck(message)
+	if err != nil {
+		logger.Error(err, "ignoring invalid upgrade pre-check report", "jobName", jobName)
+		return dbUtil.UpgradePreCheckPayload{}, false, nil
+	}
+	return payload, true, nil
+}
+
+// upgradePreCheckJobName embeds the versions and the previous pre-check time,
+// so each retry of a failed pre-check gets a new Job while reconciles within
+// one pre-check reuse the same one.
+func upgradePreCheckJobName(
+	db *storagev1alpha1.DatabaseServer,
+	adminIdentity resolvedAdminIdentity,
+	current, target int,
+	lastPreCheck time.Time,
+) string {
+	previous := ""
+	if !lastPreCheck.IsZero() {
+		previous = lastPreCheck.UTC().Format(time.RFC3339)
+	}
+	payload := strings.Join([]string{
+		"server=" + db.Name,
+		"host=" + db.Status.Host,
+		"adminSA=" + adminIdentity.ServiceAccountName,
+		"admin=" + adminIdentity.Name,
+		"from=" + strconv.Itoa(current),
+		"to=" + strconv.Itoa(target),
+		"previous=" + previous,
+		"mode=upgrade-precheck",
+	}, ";")
+	hash := naming.StableSHA256Hex(payload)[:8]
+	base := naming.EnsureLowerAlphaPrefix(naming.SanitizeLowerHyphen(db.Name), "db")
+	return naming.WithRequiredSuffix(base+"-upgrade", "-"+hash, 63, "db")
+}
+
+func upgradePreCheckJobLabels(serverName string) map[string]string {
+	return map[string]string{
+		databaseServerNameLabelKey: serverName,
+		componentLabelKey:          upgradePreCheckComponentLabelValue,
+	}
+}
+
+// postgresMajorVersion returns the major version set on a FlexibleServer
+// spec, or zero when it is unset.
+func postgresMajorVersion(version *dbforpostgresqlv1.PostgresMajorVersion) int {
+	if version == nil {
+		return 0
+	}
+	return parseMajorVersion(string(*version))
+}
+
+// parseMajorVersion parses a major version such as "17", or "17.4" as Azure
+// may report it, returning zero when it is not a number.
+func parseMajorVersion(version string) int {
+	major, _, _ := strings.Cut(strings.TrimSpace(version), ".")
+	parsed, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestDesiredServerVersion(t *testing.T) {
+	upgrade := func(phase storagev1alpha1.DatabaseServerUpgradePhase, from, to int) *storagev1alpha1.DatabaseServerUpgradeStatus {
+		return &storagev1alpha1.DatabaseServerUpgradeStatus{Phase: phase, FromVersion: from, ToVersion: to}
+	}
+
+	cases := []struct {
+		name    string
+		spec    int
+		current int
+		upgrade *storagev1alpha1.DatabaseServerUpgradeStatus
+		want    int
+	}{
+		{name: "new server", spec: 17, current: 0, want: 17},
+		{name: "unchanged version", spec: 16, current: 16, want: 16},
+		{name: "downgrade keeps the current version", spec: 15, current: 16, want: 16},
+		{name: "upgrade without pre-checks", spec: 17, current: 16, want: 16},
+		{name: "upgrade while pre-checking", spec: 17, current: 16, upgrade: upgrade(storagev1alpha1.DatabaseServerUpgradePhasePreChecking, 16, 17), want: 16},
+		{name: "upgrade with failed pre-checks", spec: 17, current: 16, upgrade: upgrade(storagev1alpha1.DatabaseServerUpgradePhaseFailed, 16, 17), want: 16},
+		{name: "upgrade with passed pre-checks", spec: 17, current: 16, upgrade: upgrade(storagev1alpha1.DatabaseServerUpgradePhasePending, 16, 17), want: 17},
+		{name: "pre-checks for another version", spec: 18, current: 16, upgrade: upgrade(storagev1alpha1.DatabaseServerUpgradePhasePending, 16, 17), want: 16},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &storagev1alpha1.DatabaseServer{}
+			db.Spec.Version = tc.spec
+			db.Status.Upgrade = tc.upgrade
+			if got := desiredServerVersion(db, tc.current); got != tc.want {
+				t.Fatalf("desiredServerVersion = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpgradePreCheckResult(t *testing.T) {
+	now := metav1.NewTime(time.Date(2026, 5, 20, 10, 0, 0, 0, time.UTC))
+
+	passed := upgradePreCheckResult(16, 17, nil, now)
+	if passed.Phase != storagev1alpha1.DatabaseServerUpgradePhasePending || !passed.PreCheckTime.Equal(&now) {
+		t.Fatalf("expected Pending with the pre-check time, got %#v", passed)
+	}
+
+	failed := upgradePreCheckResult(16, 17, []string{"extension pgaudit is installed in app", "not enough storage"}, now)
+	if failed.Phase != storagev1alpha1.DatabaseServerUpgradePhaseFailed {
+		t.Fatalf("expected Failed, got %q", failed.Phase)
+	}
+	if !strings.Contains(failed.Message, "pgaudit") || !strings.Contains(failed.Message, "not enough storage") {
+		t.Fatalf("expected every problem in the message, got %q", failed.Message)
+	}
+}
+
+func TestTrackAppliedUpgrade(t *testing.T) {
+	pending := &storagev1alpha1.DatabaseServerUpgradeStatus{
+		Phase:       storagev1alpha1.DatabaseServerUpgradePhasePending,
+		FromVersion: 16,
+		ToVersion:   17,
+	}
+
+	if got := trackAppliedUpgrade(pending, 17, false); got.Phase != storagev1alpha1.DatabaseServerUpgradePhaseUpgrading {
+		t.Fatalf("expected Upgrading while Azure upgrades, got %#v", got)
+	}
+	completed := trackAppliedUpgrade(pending, 17, true)
+	if completed.Phase != storagev1alpha1.DatabaseServerUpgradePhaseCompleted || completed.Message != "Upgraded from PostgreSQL 16 to 17" {
+		t.Fatalf("expected Completed, got %#v", completed)
+	}
+	if pending.Phase != storagev1alpha1.DatabaseServerUpgradePhasePending {
+		t.Fatalf("expected the input status to be left alone, got %q", pending.Phase)
+	}
+	if got := trackAppliedUpgrade(completed, 17, true); got != completed {
+		t.Fatalf("expected a completed upgrade to be kept")
+	}
+
+	failed := &storagev1alpha1.DatabaseServerUpgradeStatus{
+		Phase:       storagev1alpha1.DatabaseServerUpgradePhaseFailed,
+		FromVersion: 16,
+		ToVersion:   17,
+	}
+	if got := trackAppliedUpgrade(failed, 16, true); got != nil {
+		t.Fatalf("expected an abandoned upgrade to be dropped, got %#v", got)
+	}
+}
+
+func TestUpgradePreCheckJobNamePerAttempt(t *testing.T) {
+	db := testDebugJobServer()
+	admin := testDebugAdminIdentity()
+
+	first := upgradePreCheckJobName(db, admin, 16, 17, time.Time{})
+	if again := upgradePreCheckJobName(db, admin, 16, 17, time.Time{}); again != first {
+		t.Fatalf("expected deterministic job name, got %q vs %q", first, again)
+	}
+	if len(first) > 63 || !strings.Contains(first, "-upgrade-") {
+		t.Fatalf("expected bounded upgrade job name, got %q", first)
+	}
+	if other := upgradePreCheckJobName(db, admin, 16, 18, time.Time{}); other == first {
+		t.Fatalf("expected a new job name for another target version")
+	}
+	if retry := upgradePreCheckJobName(db, admin, 16, 17, time.Date(2026, 5, 20, 10, 0, 0, 0, time.UTC)); retry == first {
+		t.Fatalf("expected a new job name for a retry")
+	}
+}
+
+func TestParseMajorVersion(t *testing.T) {
+	for input, want := range map[string]int{"17": 17, "16.4": 16, " 15 ": 15, "": 0, "latest": 0} {
+		if got := parseMajorVersion(input); got != want {
+			t.Fatalf("parseMajorVersion(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+)
+
+// Event reasons recorded on Database and DatabaseServer resources. Conditions
+// only show the current state; Events keep the recent history visible in
+// kubectl describe without access to the operator logs.
+const (
+	eventReasonSubnetAllocated         = "SubnetAllocated"
+	eventReasonFlexibleServerBlocked   = "FlexibleServerBlocked"
+	eventReasonProvisionJobCreated     = "ProvisionJobCreated"
+	eventReasonProvisionJobSucceeded   = "ProvisionJobSucceeded"
+	eventReasonProvisionJobFailed      = "ProvisionJobFailed"
+	eventReasonASOResourceConflict     = "ASOResourceConflict"
+	eventReasonDebugAccessGranted      = "DebugAccessGranted"
+	eventReasonDebugAccessRevoked      = "DebugAccessRevoked"
+	eventReasonServerParameterRejected = "ServerParameterRejected"
+	eventReasonAccessDriftDetected     = "AccessDriftDetected"
+	eventReasonAccessDriftRevoked      = "AccessDriftRevoked"
+	eventReasonEncryptionKeyRotated    = "EncryptionKeyRotated"
+	eventReasonEncryptionKeyAccessLost = "EncryptionKeyAccessLost"
+	eventReasonDatabasePlaced          = "DatabasePlaced"
+	eventReasonServerPoolFull          = "ServerPoolFull"
+	eventReasonMigrationStarted        = "MigrationStarted"
+	eventReasonMigrationCompleted      = "MigrationCompleted"
+	eventReasonMigrationFailed         = "MigrationFailed"
+	eventReasonMigrationCancelled      = "MigrationCancelled"
+	eventReasonMigrationSourceDropped  = "MigrationSourceDropped"
+	eventReasonBackupFailed            = "BackupFailed"
+	eventReasonSchemaMigrated          = "SchemaMigrated"
+	eventReasonSchemaMigrationFailed   = "SchemaMigrationFailed"
+)
+
+// Event actions name what the operator did, or tried to do, when the Event
+// was recorded.
+const (
+	eventActionAllocateSubnet     = "AllocateSubnet"
+	eventActionReconcile          = "Reconcile"
+	eventActionProvision          = "Provision"
+	eventActionGrantDebugAccess   = "GrantDebugAccess"
+	eventActionRevokeAccess       = "RevokeDebugAccess"
+	eventActionApplyParameters    = "ApplyServerParameters"
+	eventActionAuditAccess        = "AuditAccess"
+	eventActionCheckEncryptionKey = "CheckEncryptionKey"
+	eventActionPlaceDatabase      = "PlaceDatabase"
+	eventActionMigrateDatabase    = "MigrateDatabase"
+	eventActionBackupDatabase     = "BackupDatabase"
+	eventActionMigrateSchema      = "MigrateSchema"
+)
+
+// recordEvent records an Event regarding obj, with related as the secondary
+// object when set. It is a no-op without a recorder, so reconcilers built
+// without one (as in unit tests) keep working.
+func recordEvent(
+	recorder events.EventRecorder,
+	regarding runtime.Object,
+	related runtime.Object,
+	eventType, reason, action, note string,
+	args ...any,
+) {
+	if recorder == nil || regarding == nil {
+		return
+	}
+	recorder.Eventf(regarding, related, eventType, reason, action, note, args...)
+}
+
+// recordWarningEvent records a Warning Event regarding obj.
+func recordWarningEvent(recorder events.EventRecorder, regarding runtime.Object, reason, action, note string, args ...any) {
+	recordEvent(recorder, regarding, nil, corev1.EventTypeWarning, reason, action, note, args...)
+}
+
+// recordNormalEvent records a Normal Event regarding obj.
+func recordNormalEvent(recorder events.EventRecorder, regarding runtime.Object, reason, action, note string, args ...any) {
+	recordEvent(recorder, regarding, nil, corev1.EventTypeNormal, reason, action, note, args...)
+}
+
+// conditionChanged reports whether setting conditionType to reason and
+// message would change it, so an Event is recorded once per transition rather
+// than on every reconcile.
+func conditionChanged(conditions []metav1.Condition, conditionType, reason, message string) bool {
+	previous := meta.FindStatusCondition(conditions, conditionType)
+	return previous == nil || previous.Reason != reason || previous.Message != message
+}
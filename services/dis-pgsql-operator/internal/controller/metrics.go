@@ -0,0 +1,332 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/network"
+)
+
+const (
+	metricsNamespace = "dispg"
+
+	// inventoryCollectTimeout bounds the cache reads of one scrape.
+	inventoryCollectTimeout = 10 * time.Second
+
+	// Provisioning Job phases, derived from the Job labels by
+	// provisionJobPhase.
+	provisionJobPhaseUser            = "user"
+	provisionJobPhaseAccess          = "access"
+	provisionJobPhaseDebug           = "debug"
+	provisionJobPhaseDrop            = "drop"
+	provisionJobPhaseCatalog         = "catalog"
+	provisionJobPhaseUpgradePreCheck = "upgrade-precheck"
+	provisionJobPhaseAccessAudit     = "access-audit"
+	provisionJobPhaseMigration       = "migration"
+	provisionJobPhaseBackup          = "backup"
+	provisionJobPhaseSchemaMigration = "schema-migration"
+
+	provisionJobResultSucceeded = "succeeded"
+	provisionJobResultFailed    = "failed"
+)
+
+var (
+	provisionJobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "provision_job_duration_seconds",
+		Help:      "Duration of finished user provisioning Jobs, from start to completion or failure.",
+		Buckets:   []float64{5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"phase", "result"})
+
+	provisionJobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provision_job_failures_total",
+		Help:      "User provisioning Jobs that failed.",
+	}, []string{"phase"})
+
+	timeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "time_to_ready_seconds",
+		Help:      "Time a resource spent not Ready before it became Ready, from creation for new resources.",
+		Buckets:   []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200},
+	}, []string{"kind"})
+
+	databasesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "databases"),
+		"Database resources per DatabaseServer.",
+		[]string{"namespace", "server", "mode"}, nil,
+	)
+
+	subnetCatalogSubnetsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "subnet_catalog_subnets"),
+		"Subnets in the subnet catalog by state (free or used).",
+		[]string{"state"}, nil,
+	)
+
+	serverParameterErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "server_parameter_errors"),
+		"Entries in status.serverParameterErrors per DatabaseServer.",
+		[]string{"namespace", "server"}, nil,
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(provisionJobDuration, provisionJobFailures, timeToReady)
+}
+
+// InventoryCollector reports gauges computed from the cached DatabaseServers
+// and Databases at scrape time, so deleted resources leave no stale series.
+type InventoryCollector struct {
+	reader        client.Reader
+	subnetCatalog *network.SubnetCatalog
+}
+
+// NewInventoryCollector returns a collector reading through reader. The
+// subnet catalog gauges are skipped when subnetCatalog is nil.
+func NewInventoryCollector(reader client.Reader, subnetCatalog *network.SubnetCatalog) *InventoryCollector {
+	return &InventoryCollector{reader: reader, subnetCatalog: subnetCatalog}
+}
+
+// Describe implements prometheus.Collector.
+func (c *InventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- databasesDesc
+	ch <- subnetCatalogSubnetsDesc
+	ch <- serverParameterErrorsDesc
+}
+
+// Collect implements prometheus.Collector. A failed read drops the affected
+// metrics from the scrape rather than reporting wrong values.
+func (c *InventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryCollectTimeout)
+	defer cancel()
+
+	var servers storagev1alpha1.DatabaseServerList
+	if err := c.reader.List(ctx, &servers); err != nil {
+		ch <- prometheus.NewInvalidMetric(databasesDesc, fmt.Errorf("list DatabaseServers: %w", err))
+		return
+	}
+	var databases storagev1alpha1.DatabaseList
+	if err := c.reader.List(ctx, &databases); err != nil {
+		ch <- prometheus.NewInvalidMetric(databasesDesc, fmt.Errorf("list Databases: %w", err))
+		return
+	}
+
+	for _, count := range countDatabasesPerServer(servers.Items, databases.Items) {
+		ch <- prometheus.MustNewConstMetric(databasesDesc, prometheus.GaugeValue, float64(count.Databases),
+			count.Namespace, count.Server, string(count.Mode))
+	}
+
+	usedCIDRs := make([]string, 0, len(servers.Items))
+	for i := range servers.Items {
+		server := &servers.Items[i]
+		usedCIDRs = append(usedCIDRs, server.Status.SubnetCIDR)
+		ch <- prometheus.MustNewConstMetric(serverParameterErrorsDesc, prometheus.GaugeValue,
+			float64(len(server.Status.ServerParameterErrors)), server.Namespace, server.Name)
+	}
+
+	if c.subnetCatalog != nil {
+		free, used := c.subnetCatalog.Usage(usedCIDRs)
+		ch <- prometheus.MustNewConstMetric(subnetCatalogSubnetsDesc, prometheus.GaugeValue, float64(free), "free")
+		ch <- prometheus.MustNewConstMetric(subnetCatalogSubnetsDesc, prometheus.GaugeValue, float64(used), "used")
+	}
+}
+
+// serverDatabaseCount is the number of Databases targeting one server.
+type serverDatabaseCount struct {
+	Namespace string
+	Server    string
+	Mode      storagev1alpha1.DatabaseServerMode
+	Databases int
+}
+
+// countDatabasesPerServer counts the Databases of every server, including
+// servers without any. Databases referencing a missing server are not
+// counted.
+func countDatabasesPerServer(
+	servers []storagev1alpha1.DatabaseServer,
+	databases []storagev1alpha1.Database,
+) []serverDatabaseCount {
+	counts := make([]serverDatabaseCount, 0, len(servers))
+	index := make(map[string]int, len(servers))
+	for i := range servers {
+		index[servers[i].Namespace+"/"+servers[i].Name] = len(counts)
+		counts = append(counts, serverDatabaseCount{
+			Namespace: servers[i].Namespace,
+			Server:    servers[i].Name,
+			Mode:      databaseServerMode(&servers[i]),
+		})
+	}
+	for i := range databases {
+		key := databases[i].Namespace + "/" + databaseServerName(&databases[i])
+		if j, ok := index[key]; ok {
+			counts[j].Databases++
+		}
+	}
+	return counts
+}
+
+// SetupProvisionJobObserver observes user provisioning Jobs as they finish,
+// recording their metrics and an Event on the Database or DatabaseServer that
+// owns them. It hooks into the manager's Job informer, which both reconcilers
+// already watch, and only acts on transitions to finished, so Jobs that had
+// already finished before the operator started are not counted again.
+func SetupProvisionJobObserver(ctx context.Context, mgr ctrl.Manager, recorder events.EventRecorder) error {
+	informer, err := mgr.GetCache().GetInformer(ctx, &batchv1.Job{})
+	if err != nil {
+		return fmt.Errorf("get Job informer: %w", err)
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj any) {
+			oldJob, ok := oldObj.(*batchv1.Job)
+			if !ok {
+				return
+			}
+			newJob, ok := newObj.(*batchv1.Job)
+			if !ok {
+				return
+			}
+			observeProvisionJobTransition(recorder, oldJob, newJob)
+		},
+	}); err != nil {
+		return fmt.Errorf("add Job observer handler: %w", err)
+	}
+	return nil
+}
+
+// observeProvisionJobTransition records the duration, and a failure, of a
+// user provisioning Job that has just finished, and an Event on its owner.
+func observeProvisionJobTransition(recorder events.EventRecorder, oldJob, newJob *batchv1.Job) {
+	if newJob.Labels[userProvisionLabelKey] != labelValueTrue {
+		return
+	}
+	_, _, wasFinished := provisionJobOutcome(oldJob)
+	result, finishedAt, finished := provisionJobOutcome(newJob)
+	if wasFinished || !finished {
+		return
+	}
+
+	phase := provisionJobPhase(newJob.Labels)
+	if result == provisionJobResultFailed {
+		provisionJobFailures.WithLabelValues(phase).Inc()
+	}
+	if newJob.Status.StartTime != nil && !finishedAt.IsZero() {
+		provisionJobDuration.WithLabelValues(phase, result).Observe(finishedAt.Sub(newJob.Status.StartTime.Time).Seconds())
+	}
+
+	owner := metav1.GetControllerOf(newJob)
+	if owner == nil {
+		return
+	}
+	regarding := &corev1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  newJob.Namespace,
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+	if result == provisionJobResultFailed {
+		recordEvent(recorder, regarding, newJob, corev1.EventTypeWarning, eventReasonProvisionJobFailed, eventActionProvision,
+			"%s provisioning Job %s failed: %s", phase, newJob.Name, jobFailureMessage(newJob))
+		return
+	}
+	recordEvent(recorder, regarding, newJob, corev1.EventTypeNormal, eventReasonProvisionJobSucceeded, eventActionProvision,
+		"%s provisioning Job %s succeeded", phase, newJob.Name)
+}
+
+// jobFailureMessage returns the reason and message of a failed Job's Failed
+// condition.
+func jobFailureMessage(job *batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type != batchv1.JobFailed || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.Message == "" {
+			return condition.Reason
+		}
+		return fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+	}
+	return ""
+}
+
+// provisionJobOutcome reports whether a Job has finished, how, and when.
+func provisionJobOutcome(job *batchv1.Job) (string, time.Time, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			finishedAt := condition.LastTransitionTime.Time
+			if job.Status.CompletionTime != nil {
+				finishedAt = job.Status.CompletionTime.Time
+			}
+			return provisionJobResultSucceeded, finishedAt, true
+		case batchv1.JobFailed:
+			return provisionJobResultFailed, condition.LastTransitionTime.Time, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// provisionJobPhase maps the labels set on each kind of provisioning Job to
+// the phase label of the Job metrics.
+func provisionJobPhase(labels map[string]string) string {
+	switch labels[componentLabelKey] {
+	case debugAccessComponentLabelValue:
+		return provisionJobPhaseDebug
+	case databaseCatalogComponentLabelValue:
+		return provisionJobPhaseCatalog
+	case upgradePreCheckComponentLabelValue:
+		return provisionJobPhaseUpgradePreCheck
+	case databaseAccessAuditComponentLabelValue:
+		return provisionJobPhaseAccessAudit
+	case databaseMigrationComponentLabelValue:
+		return provisionJobPhaseMigration
+	case databaseBackupComponentLabelValue:
+		return provisionJobPhaseBackup
+	case databaseSchemaMigrationsComponentLabelValue:
+		return provisionJobPhaseSchemaMigration
+	}
+	switch {
+	case labels[databaseDropLabelKey] == labelValueTrue:
+		return provisionJobPhaseDrop
+	case labels[databaseAccessProvisionLabelKey] == labelValueTrue:
+		return provisionJobPhaseAccess
+	default:
+		return provisionJobPhaseUser
+	}
+}
+
+// observeTimeToReady records how long a resource was not Ready once its Ready
+// condition turns True. The wait is counted from the previous Ready
+// transition, or from creation when the resource was never Ready before.
+func observeTimeToReady(kind string, previous, current []metav1.Condition, createdAt metav1.Time, now time.Time) {
+	currentReady := meta.FindStatusCondition(current, "Ready")
+	if currentReady == nil || currentReady.Status != metav1.ConditionTrue {
+		return
+	}
+	since := createdAt.Time
+	if previousReady := meta.FindStatusCondition(previous, "Ready"); previousReady != nil {
+		if previousReady.Status == metav1.ConditionTrue {
+			return
+		}
+		since = previousReady.LastTransitionTime.Time
+	}
+	if since.IsZero() || now.Before(since) {
+		return
+	}
+	timeToReady.WithLabelValues(kind).Observe(now.Sub(since).Seconds())
+}
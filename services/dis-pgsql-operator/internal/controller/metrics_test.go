@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestProvisionJobPhase(t *testing.T) {
+	tests := map[string]struct {
+		labels map[string]string
+		want   string
+	}{
+		"user":             {labels: map[string]string{userProvisionLabelKey: labelValueTrue}, want: provisionJobPhaseUser},
+		"access":           {labels: map[string]string{databaseAccessProvisionLabelKey: labelValueTrue}, want: provisionJobPhaseAccess},
+		"drop":             {labels: map[string]string{databaseDropLabelKey: labelValueTrue}, want: provisionJobPhaseDrop},
+		"debug":            {labels: map[string]string{componentLabelKey: debugAccessComponentLabelValue}, want: provisionJobPhaseDebug},
+		"catalog":          {labels: map[string]string{componentLabelKey: databaseCatalogComponentLabelValue}, want: provisionJobPhaseCatalog},
+		"upgrade precheck": {labels: map[string]string{componentLabelKey: upgradePreCheckComponentLabelValue}, want: provisionJobPhaseUpgradePreCheck},
+		"access audit":     {labels: map[string]string{componentLabelKey: databaseAccessAuditComponentLabelValue}, want: provisionJobPhaseAccessAudit},
+		"migration":        {labels: map[string]string{componentLabelKey: databaseMigrationComponentLabelValue}, want: provisionJobPhaseMigration},
+		"backup":           {labels: map[string]string{componentLabelKey: databaseBackupComponentLabelValue}, want: provisionJobPhaseBackup},
+		"schema migration": {labels: map[string]string{componentLabelKey: databaseSchemaMigrationsComponentLabelValue}, want: provisionJobPhaseSchemaMigration},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := provisionJobPhase(tt.labels); got != tt.want {
+				t.Fatalf("expected phase %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestObserveProvisionJobTransition(t *testing.T) {
+	start := metav1.NewTime(time.Date(2026, 5, 20, 10, 0, 0, 0, time.UTC))
+	running := &batchv1.Job{}
+	controller := true
+	running.Name = "drop-appdb"
+	running.Labels = map[string]string{userProvisionLabelKey: labelValueTrue, databaseDropLabelKey: labelValueTrue}
+	running.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: storagev1alpha1.GroupVersion.String(),
+		Kind:       "Database",
+		Name:       "appdb",
+		Controller: &controller,
+	}}
+	running.Status.StartTime = &start
+
+	failed := running.DeepCopy()
+	failed.Status.Conditions = []batchv1.JobCondition{{
+		Type:               batchv1.JobFailed,
+		Status:             corev1.ConditionTrue,
+		Reason:             "BackoffLimitExceeded",
+		LastTransitionTime: metav1.NewTime(start.Add(45 * time.Second)),
+	}}
+
+	recorder := events.NewFakeRecorder(4)
+	failures := testutil.ToFloat64(provisionJobFailures.WithLabelValues(provisionJobPhaseDrop))
+	observeProvisionJobTransition(recorder, running, failed)
+	// A resync of the finished Job must not count it again.
+	observeProvisionJobTransition(recorder, failed, failed)
+
+	if got := testutil.ToFloat64(provisionJobFailures.WithLabelValues(provisionJobPhaseDrop)) - failures; got != 1 {
+		t.Fatalf("expected one failure to be recorded, got %v", got)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one Event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; event != "Warning ProvisionJobFailed drop provisioning Job drop-appdb failed: BackoffLimitExceeded" {
+		t.Fatalf("unexpected Event %q", event)
+	}
+
+	unlabeled := failed.DeepCopy()
+	unlabeled.Labels = nil
+	observeProvisionJobTransition(nil, running, unlabeled)
+	if got := testutil.ToFloat64(provisionJobFailures.WithLabelValues(provisionJobPhaseDrop)) - failures; got != 1 {
+		t.Fatalf("expected Jobs of other components to be ignored, got %v", got)
+	}
+}
+
+func TestCountDatabasesPerServer(t *testing.T) {
+	server := func(namespace, name string, mode storagev1alpha1.DatabaseServerMode) storagev1alpha1.DatabaseServer {
+		s := storagev1alpha1.DatabaseServer{}
+		s.Namespace = namespace
+		s.Name = name
+		s.Spec.Mode = mode
+		return s
+	}
+	database := func(namespace, serverName string) storagev1alpha1.Database {
+		d := storagev1alpha1.Database{}
+		d.Namespace = namespace
+		d.Spec.Server.Name = serverName
+		return d
+	}
+
+	counts := countDatabasesPerServer(
+		[]storagev1alpha1.DatabaseServer{
+			server("team-a", "shared", storagev1alpha1.DatabaseServerModeShared),
+			server("team-b", "shared", ""),
+		},
+		[]storagev1alpha1.Database{
+			database("team-a", "shared"),
+			database("team-a", "shared"),
+			database("team-a", "missing"),
+			database("team-c", "shared"),
+		},
+	)
+	if len(counts) != 2 {
+		t.Fatalf("expected a count per server, got %#v", counts)
+	}
+	if counts[0].Databases != 2 || counts[0].Mode != storagev1alpha1.DatabaseServerModeShared {
+		t.Fatalf("expected two databases on team-a/shared, got %#v", counts[0])
+	}
+	if counts[1].Databases != 0 || counts[1].Mode != databaseServerMode(&storagev1alpha1.DatabaseServer{}) {
+		t.Fatalf("expected an empty team-b/shared with the default mode, got %#v", counts[1])
+	}
+}
+
+func TestObserveTimeToReady(t *testing.T) {
+	now := time.Date(2026, 5, 20, 10, 0, 0, 0, time.UTC)
+	created := metav1.NewTime(now.Add(-10 * time.Minute))
+	ready := []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}
+	notReady := []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Minute))}}
+
+	before := testutil.CollectAndCount(timeToReady)
+	observeTimeToReady("StillReady", ready, ready, created, now)
+	observeTimeToReady("NotReady", notReady, notReady, created, now)
+	if got := testutil.CollectAndCount(timeToReady) - before; got != 0 {
+		t.Fatalf("expected no observation without a transition to Ready, got %d new series", got)
+	}
+
+	observeTimeToReady("Created", nil, ready, created, now)
+	observeTimeToReady("Recovered", notReady, ready, created, now)
+	if got := testutil.CollectAndCount(timeToReady) - before; got != 2 {
+		t.Fatalf("expected an observation per transition to Ready, got %d new series", got)
+	}
+}
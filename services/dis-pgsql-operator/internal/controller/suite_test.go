@@ -103,6 +103,7 @@ var _ = BeforeSuite(func() {
 
 	k8sManager, err = ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme,
+		Cache:  CacheOptions(),
 	})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sManager).NotTo(BeNil())
@@ -128,13 +129,15 @@ var _ = BeforeSuite(func() {
 		Client:        k8sManager.GetClient(),
 		Scheme:        k8sManager.GetScheme(),
 		SubnetCatalog: testCatalog,
+		Recorder:      k8sManager.GetEventRecorder("databaseserver-controller"),
 		Config:        config,
 	}).SetupWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
 	err = (&DatabaseReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
-		Config: config,
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorder("database-controller"),
+		Config:   config,
 	}).SetupWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -0,0 +1,410 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// AccessAuditPayloadVersion versions the report the audit Job writes.
+	AccessAuditPayloadVersion = 1
+
+	// AccessAuditOutputPath is where the audit Job writes its report, read by
+	// the operator from the Pod termination message.
+	AccessAuditOutputPath = DatabaseCatalogOutputPath
+
+	// AccessAuditMaxBytes is the Kubernetes limit for a termination message.
+	// Reports that do not fit are truncated.
+	AccessAuditMaxBytes = DatabaseCatalogMaxBytes
+
+	// publicGrantee is the grantee of privileges granted to PUBLIC.
+	publicGrantee = "PUBLIC"
+)
+
+// AccessDriftKind classifies a difference between the access in a database
+// and the access payload.
+type AccessDriftKind string
+
+const (
+	// AccessDriftUnmanagedMember is a member of a managed role that no
+	// principal accounts for.
+	AccessDriftUnmanagedMember AccessDriftKind = "UnmanagedMember"
+	// AccessDriftMissingMember is a principal that is not a member of its
+	// managed role.
+	AccessDriftMissingMember AccessDriftKind = "MissingMember"
+	// AccessDriftMissingSchema is a managed schema that does not exist.
+	AccessDriftMissingSchema AccessDriftKind = "MissingSchema"
+	// AccessDriftSchemaOwner is a managed schema owned by another role than
+	// the database's Owner role.
+	AccessDriftSchemaOwner AccessDriftKind = "SchemaOwner"
+	// AccessDriftUnmanagedDefaultPrivilege is a default privilege in a
+	// managed schema that the operator did not grant.
+	AccessDriftUnmanagedDefaultPrivilege AccessDriftKind = "UnmanagedDefaultPrivilege"
+	// AccessDriftMissingDefaultPrivilege is a default privilege the operator
+	// grants that is missing.
+	AccessDriftMissingDefaultPrivilege AccessDriftKind = "MissingDefaultPrivilege"
+)
+
+// AccessDrift is one difference found by the audit. Role is the managed role
+// of a membership, the expected owner of a schema, or the creating role of a
+// default privilege; Grantee is the member or the grantee of the default
+// privilege.
+type AccessDrift struct {
+	Kind       AccessDriftKind `json:"kind"`
+	Role       string          `json:"role,omitempty"`
+	Grantee    string          `json:"grantee,omitempty"`
+	Schema     string          `json:"schema,omitempty"`
+	ObjectType string          `json:"objectType,omitempty"`
+	Owner      string          `json:"owner,omitempty"`
+	Revoked    bool            `json:"revoked,omitempty"`
+}
+
+// AccessAuditReport is the report written by the audit Job.
+type AccessAuditReport struct {
+	Version   int           `json:"version"`
+	Drift     []AccessDrift `json:"drift,omitempty"`
+	Truncated bool          `json:"truncated,omitempty"`
+}
+
+// MarshalAccessAudit serializes the drift, dropping the last entries until
+// the report fits AccessAuditMaxBytes.
+func MarshalAccessAudit(drift []AccessDrift) (string, error) {
+	report := AccessAuditReport{
+		Version: AccessAuditPayloadVersion,
+		Drift:   drift,
+	}
+	for {
+		content, err := json.Marshal(report)
+		if err != nil {
+			return "", fmt.Errorf("marshal access audit report: %w", err)
+		}
+		if len(content) <= AccessAuditMaxBytes || len(report.Drift) == 0 {
+			return string(content), nil
+		}
+		report.Drift = report.Drift[:len(report.Drift)-1]
+		report.Truncated = true
+	}
+}
+
+// UnmarshalAccessAudit parses a report written by the audit Job.
+func UnmarshalAccessAudit(value string) (AccessAuditReport, error) {
+	var report AccessAuditReport
+	if err := json.Unmarshal([]byte(value), &report); err != nil {
+		return AccessAuditReport{}, fmt.Errorf("parse access audit report: %w", err)
+	}
+	if report.Version != AccessAuditPayloadVersion {
+		return AccessAuditReport{}, fmt.Errorf("unsupported access audit report version %d", report.Version)
+	}
+	return report, nil
+}
+
+// defaultPrivilege is one grantee of the default privileges a creating role
+// has in a schema, for one object type.
+type defaultPrivilege struct {
+	Creator    string
+	Schema     string
+	ObjectType string
+	Grantee    string
+}
+
+// accessState is the access the audit compares: the members of the managed
+// roles, the owners of the managed schemas and the grantees of the default
+// privileges in them.
+type accessState struct {
+	members           map[string]map[string]struct{}
+	schemaOwners      map[string]string
+	defaultPrivileges map[defaultPrivilege]struct{}
+}
+
+// desiredAccessState derives from opts the access that ensureAccess
+// provisions.
+func desiredAccessState(opts accessOptions) (*accessState, error) {
+	roles := managedAccessRolesFor(opts.DatabaseName, opts.SchemaName)
+	memberships, err := baseAccessMemberships(roles, opts.Principals)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planScopedAccess(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &accessState{
+		members:           memberships.members,
+		schemaOwners:      map[string]string{opts.SchemaName: roles.Owner},
+		defaultPrivileges: map[defaultPrivilege]struct{}{},
+	}
+	maps.Copy(state.members, plan.memberships.members)
+
+	for _, creator := range accessCreators(roles, opts.Principals) {
+		state.addDefaultPrivileges(creator, opts.SchemaName, roles)
+		for schema, schemaRoles := range plan.schemas {
+			state.schemaOwners[schema] = roles.Owner
+			state.addDefaultPrivileges(creator, schema, schemaRoles)
+		}
+		for schema, roleName := range plan.execute {
+			state.defaultPrivileges[defaultPrivilege{Creator: creator, Schema: schema, ObjectType: "Functions", Grantee: roleName}] = struct{}{}
+		}
+	}
+	return state, nil
+}
+
+// addDefaultPrivileges adds the default privileges grantDefaultPrivilegesForCreator
+// grants.
+func (state *accessState) addDefaultPrivileges(creator, schema string, roles managedAccessRoles) {
+	for _, objectType := range []string{"Tables", "Sequences"} {
+		for _, grantee := range []string{roles.Reader, roles.Writer} {
+			state.defaultPrivileges[defaultPrivilege{Creator: creator, Schema: schema, ObjectType: objectType, Grantee: grantee}] = struct{}{}
+		}
+	}
+}
+
+// collectAccessState reads the actual access for the roles and schemas of
+// desired, and for the scoped roles of the database that are no longer
+// planned.
+func collectAccessState(ctx context.Context, conn pgxConn, databaseName string, desired *accessState) (*accessState, error) {
+	existing, err := scopedRoles(ctx, conn, databaseName)
+	if err != nil {
+		return nil, err
+	}
+	roles := slices.Sorted(maps.Keys(desired.members))
+	for _, roleName := range existing {
+		if _, ok := desired.members[roleName]; !ok {
+			roles = append(roles, roleName)
+		}
+	}
+
+	actual := &accessState{
+		members:           map[string]map[string]struct{}{},
+		schemaOwners:      map[string]string{},
+		defaultPrivileges: map[defaultPrivilege]struct{}{},
+	}
+	for _, roleName := range roles {
+		members, err := managedRoleMembers(ctx, conn, roleName)
+		if err != nil {
+			return nil, err
+		}
+		actual.members[roleName] = members
+	}
+
+	schemas := slices.Sorted(maps.Keys(desired.schemaOwners))
+	if err := scanRows(ctx, conn, schemaOwnersSQL(), schemas, "schema owners", func(rows pgx.Rows) error {
+		var schema, owner string
+		if err := rows.Scan(&schema, &owner); err != nil {
+			return err
+		}
+		actual.schemaOwners[schema] = owner
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := scanRows(ctx, conn, defaultPrivilegesSQL(), schemas, "default privileges", func(rows pgx.Rows) error {
+		var privilege defaultPrivilege
+		var objectType string
+		if err := rows.Scan(&privilege.Creator, &privilege.Schema, &objectType, &privilege.Grantee); err != nil {
+			return err
+		}
+		privilege.ObjectType = defaultPrivilegeObjectType(objectType)
+		actual.defaultPrivileges[privilege] = struct{}{}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return actual, nil
+}
+
+func scanRows(ctx context.Context, conn pgxConn, sql string, schemas []string, what string, scan func(pgx.Rows) error) error {
+	rows, err := conn.Query(ctx, sql, schemas)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", what, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("scan %s: %w", what, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s: %w", what, err)
+	}
+	return nil
+}
+
+// diffAccessState returns the drift between desired and actual, sorted.
+func diffAccessState(desired, actual *accessState) []AccessDrift {
+	var drift []AccessDrift
+
+	roles := map[string]struct{}{}
+	for roleName := range desired.members {
+		roles[roleName] = struct{}{}
+	}
+	for roleName := range actual.members {
+		roles[roleName] = struct{}{}
+	}
+	for roleName := range roles {
+		for member := range actual.members[roleName] {
+			if _, ok := desired.members[roleName][member]; !ok {
+				drift = append(drift, AccessDrift{Kind: AccessDriftUnmanagedMember, Role: roleName, Grantee: member})
+			}
+		}
+		for member := range desired.members[roleName] {
+			if _, ok := actual.members[roleName][member]; !ok {
+				drift = append(drift, AccessDrift{Kind: AccessDriftMissingMember, Role: roleName, Grantee: member})
+			}
+		}
+	}
+
+	for schema, owner := range desired.schemaOwners {
+		actualOwner, ok := actual.schemaOwners[schema]
+		switch {
+		case !ok:
+			drift = append(drift, AccessDrift{Kind: AccessDriftMissingSchema, Role: owner, Schema: schema})
+		case actualOwner != owner:
+			drift = append(drift, AccessDrift{Kind: AccessDriftSchemaOwner, Role: owner, Schema: schema, Owner: actualOwner})
+		}
+	}
+
+	for privilege := range actual.defaultPrivileges {
+		if _, ok := desired.defaultPrivileges[privilege]; !ok {
+			drift = append(drift, privilege.drift(AccessDriftUnmanagedDefaultPrivilege))
+		}
+	}
+	for privilege := range desired.defaultPrivileges {
+		// Default privileges of a missing schema are already covered by the
+		// MissingSchema entry.
+		if _, ok := actual.schemaOwners[privilege.Schema]; !ok {
+			continue
+		}
+		if _, ok := actual.defaultPrivileges[privilege]; !ok {
+			drift = append(drift, privilege.drift(AccessDriftMissingDefaultPrivilege))
+		}
+	}
+
+	slices.SortFunc(drift, func(a, b AccessDrift) int {
+		return strings.Compare(
+			strings.Join([]string{string(a.Kind), a.Role, a.Grantee, a.Schema, a.ObjectType}, "\x00"),
+			strings.Join([]string{string(b.Kind), b.Role, b.Grantee, b.Schema, b.ObjectType}, "\x00"),
+		)
+	})
+	return drift
+}
+
+func (privilege defaultPrivilege) drift(kind AccessDriftKind) AccessDrift {
+	return AccessDrift{
+		Kind:       kind,
+		Role:       privilege.Creator,
+		Grantee:    privilege.Grantee,
+		Schema:     privilege.Schema,
+		ObjectType: privilege.ObjectType,
+	}
+}
+
+// revokeAccessDrift revokes the unmanaged memberships and default privileges
+// in drift and marks them revoked. Missing grants and schema owners are left
+// to the access provisioning Job.
+func revokeAccessDrift(ctx context.Context, conn pgxConn, drift []AccessDrift) error {
+	for i := range drift {
+		entry := &drift[i]
+		switch entry.Kind {
+		case AccessDriftUnmanagedMember:
+			if _, err := conn.Exec(ctx, revokeRoleSQL(entry.Role, entry.Grantee)); err != nil {
+				return fmt.Errorf("revoke unmanaged member %s of %s: %w", entry.Grantee, entry.Role, err)
+			}
+		case AccessDriftUnmanagedDefaultPrivilege:
+			if _, err := conn.Exec(ctx, revokeDefaultPrivilegesSQL(entry.Role, entry.Schema, entry.ObjectType, entry.Grantee)); err != nil {
+				return fmt.Errorf("revoke unmanaged default privileges of %s on %s in schema %s: %w", entry.Grantee, entry.ObjectType, entry.Schema, err)
+			}
+		default:
+			continue
+		}
+		entry.Revoked = true
+	}
+	return nil
+}
+
+// writeAccessAudit compares the access in the database with opts, revokes
+// unmanaged grants when enforce is set, and writes the drift to path.
+func writeAccessAudit(ctx context.Context, conn pgxConn, opts accessOptions, enforce bool, path string) error {
+	desired, err := desiredAccessState(opts)
+	if err != nil {
+		return err
+	}
+	actual, err := collectAccessState(ctx, conn, opts.DatabaseName, desired)
+	if err != nil {
+		return err
+	}
+	drift := diffAccessState(desired, actual)
+	if enforce {
+		if err := revokeAccessDrift(ctx, conn, drift); err != nil {
+			return err
+		}
+	}
+	content, err := MarshalAccessAudit(drift)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write access audit to %s: %w", path, err)
+	}
+	return nil
+}
+
+// defaultPrivilegeObjectType names a pg_default_acl.defaclobjtype.
+func defaultPrivilegeObjectType(objectType string) string {
+	switch objectType {
+	case "r":
+		return "Tables"
+	case "S":
+		return "Sequences"
+	case "f":
+		return "Functions"
+	case "T":
+		return "Types"
+	case "n":
+		return "Schemas"
+	default:
+		return objectType
+	}
+}
+
+func schemaOwnersSQL() string {
+	return `SELECT nspname, pg_get_userbyid(nspowner)
+FROM pg_namespace
+WHERE nspname = ANY($1)`
+}
+
+// defaultPrivilegesSQL lists one row per creator, schema, object type and
+// grantee of the default privileges in the given schemas. Grantee 0 is PUBLIC.
+func defaultPrivilegesSQL() string {
+	return `SELECT DISTINCT creator.rolname,
+  namespace.nspname,
+  acl.defaclobjtype::text,
+  COALESCE(grantee.rolname, 'PUBLIC')
+FROM pg_default_acl acl
+JOIN pg_roles creator ON creator.oid = acl.defaclrole
+JOIN pg_namespace namespace ON namespace.oid = acl.defaclnamespace
+CROSS JOIN LATERAL aclexplode(acl.defaclacl) privilege
+LEFT JOIN pg_roles grantee ON grantee.oid = privilege.grantee
+WHERE namespace.nspname = ANY($1)
+  AND privilege.grantee <> acl.defaclrole`
+}
+
+func revokeDefaultPrivilegesSQL(creator, schemaName, objectType, grantee string) string {
+	granteeSQL := publicGrantee
+	if grantee != publicGrantee {
+		granteeSQL = pgx.Identifier{grantee}.Sanitize()
+	}
+	return fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s REVOKE ALL ON %s FROM %s;",
+		pgx.Identifier{creator}.Sanitize(),
+		pgx.Identifier{schemaName}.Sanitize(),
+		strings.ToUpper(objectType),
+		granteeSQL,
+	)
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func auditTestOptions() accessOptions {
+	return accessOptions{
+		DatabaseName: "appdb",
+		SchemaName:   "appdb",
+		Principals: []AccessPrincipal{
+			{Role: AccessRoleOwner, Name: "app", PrincipalType: PrincipalTypeService},
+			{Role: AccessRoleReader, Name: "reports", PrincipalType: PrincipalTypeService},
+		},
+	}
+}
+
+func TestDiffAccessState(t *testing.T) {
+	opts := auditTestOptions()
+	roles := managedAccessRolesFor("appdb", "appdb")
+	desired, err := desiredAccessState(opts)
+	if err != nil {
+		t.Fatalf("desiredAccessState: %v", err)
+	}
+
+	actual := &accessState{
+		members: map[string]map[string]struct{}{
+			roles.Reader: {roles.Writer: {}, "reports": {}, "intruder": {}},
+			roles.Writer: {roles.Owner: {}},
+			roles.Owner:  {},
+		},
+		schemaOwners:      map[string]string{"appdb": "someone"},
+		defaultPrivileges: map[defaultPrivilege]struct{}{},
+	}
+	for privilege := range desired.defaultPrivileges {
+		actual.defaultPrivileges[privilege] = struct{}{}
+	}
+	missing := defaultPrivilege{Creator: "app", Schema: "appdb", ObjectType: "Sequences", Grantee: roles.Writer}
+	delete(actual.defaultPrivileges, missing)
+	unmanaged := defaultPrivilege{Creator: roles.Owner, Schema: "appdb", ObjectType: "Tables", Grantee: publicGrantee}
+	actual.defaultPrivileges[unmanaged] = struct{}{}
+
+	drift := diffAccessState(desired, actual)
+
+	want := []AccessDrift{
+		{Kind: AccessDriftMissingDefaultPrivilege, Role: "app", Grantee: roles.Writer, Schema: "appdb", ObjectType: "Sequences"},
+		{Kind: AccessDriftMissingMember, Role: roles.Owner, Grantee: "app"},
+		{Kind: AccessDriftSchemaOwner, Role: roles.Owner, Schema: "appdb", Owner: "someone"},
+		{Kind: AccessDriftUnmanagedDefaultPrivilege, Role: roles.Owner, Grantee: publicGrantee, Schema: "appdb", ObjectType: "Tables"},
+		{Kind: AccessDriftUnmanagedMember, Role: roles.Reader, Grantee: "intruder"},
+	}
+	if !slices.Equal(drift, want) {
+		t.Fatalf("unexpected drift:\n got %#v\nwant %#v", drift, want)
+	}
+}
+
+func TestDesiredAccessStateScopes(t *testing.T) {
+	opts := auditTestOptions()
+	opts.Principals[0].Scope = &AccessScope{Schemas: []string{"audit"}, ExecuteFunctions: true}
+	opts.Principals[1].Scope = &AccessScope{Tables: []AccessTable{{Schema: "appdb", Name: "orders"}}}
+
+	desired, err := desiredAccessState(opts)
+	if err != nil {
+		t.Fatalf("desiredAccessState: %v", err)
+	}
+	roles := managedAccessRolesFor("appdb", "appdb")
+	auditRoles := managedAccessRolesFor("appdb", "audit")
+
+	if _, ok := desired.members[roles.Reader]["reports"]; ok {
+		t.Fatalf("expected a table-scoped reader to stay out of the Reader role")
+	}
+	tableRole := tableReaderRoleName("appdb", []AccessTable{{Schema: "appdb", Name: "orders"}})
+	if _, ok := desired.members[tableRole]["reports"]; !ok {
+		t.Fatalf("expected the table reader role to have reports, got %#v", desired.members[tableRole])
+	}
+	if _, ok := desired.members[auditRoles.Owner]["app"]; !ok {
+		t.Fatalf("expected app in the Owner role of schema audit")
+	}
+	if desired.schemaOwners["audit"] != roles.Owner {
+		t.Fatalf("expected schema audit to be owned by %s, got %q", roles.Owner, desired.schemaOwners["audit"])
+	}
+	executeRole := managedRoleName("appdb", "audit", accessRoleExecute)
+	if _, ok := desired.defaultPrivileges[defaultPrivilege{Creator: "app", Schema: "audit", ObjectType: "Functions", Grantee: executeRole}]; !ok {
+		t.Fatalf("expected default execute privileges on schema audit")
+	}
+}
+
+func TestRevokeAccessDrift(t *testing.T) {
+	drift := []AccessDrift{
+		{Kind: AccessDriftMissingMember, Role: "dispg-reader", Grantee: "app"},
+		{Kind: AccessDriftUnmanagedMember, Role: "dispg-reader", Grantee: "intruder"},
+		{Kind: AccessDriftUnmanagedDefaultPrivilege, Role: "dispg-owner", Grantee: publicGrantee, Schema: "appdb", ObjectType: "Tables"},
+		{Kind: AccessDriftSchemaOwner, Role: "dispg-owner", Schema: "appdb", Owner: "someone"},
+	}
+	conn := &recordingConn{}
+
+	if err := revokeAccessDrift(context.Background(), conn, drift); err != nil {
+		t.Fatalf("revokeAccessDrift: %v", err)
+	}
+
+	requireExec(t, conn, revokeRoleSQL("dispg-reader", "intruder"))
+	requireExec(t, conn, `ALTER DEFAULT PRIVILEGES FOR ROLE "dispg-owner" IN SCHEMA "appdb" REVOKE ALL ON TABLES FROM PUBLIC;`)
+	if len(conn.execs) != 2 {
+		t.Fatalf("expected only unmanaged grants to be revoked, got %#v", conn.execs)
+	}
+	for _, entry := range drift {
+		wantRevoked := strings.HasPrefix(string(entry.Kind), "Unmanaged")
+		if entry.Revoked != wantRevoked {
+			t.Fatalf("expected %s revoked=%v, got %#v", entry.Kind, wantRevoked, entry)
+		}
+	}
+}
+
+func TestMarshalAccessAuditTruncatesToLimit(t *testing.T) {
+	drift := make([]AccessDrift, 0, 100)
+	for range 100 {
+		drift = append(drift, AccessDrift{Kind: AccessDriftUnmanagedMember, Role: strings.Repeat("r", 63), Grantee: strings.Repeat("g", 63)})
+	}
+
+	content, err := MarshalAccessAudit(drift)
+	if err != nil {
+		t.Fatalf("MarshalAccessAudit: %v", err)
+	}
+	if len(content) > AccessAuditMaxBytes {
+		t.Fatalf("expected report within %d bytes, got %d", AccessAuditMaxBytes, len(content))
+	}
+	report, err := UnmarshalAccessAudit(content)
+	if err != nil {
+		t.Fatalf("UnmarshalAccessAudit: %v", err)
+	}
+	if !report.Truncated || len(report.Drift) == 0 {
+		t.Fatalf("expected a truncated report with drift, got %d entries", len(report.Drift))
+	}
+
+	if _, err := UnmarshalAccessAudit(`{"version":2}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+}
+
+func TestWriteAccessAuditEnforce(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	opts := auditTestOptions()
+	opts.Principals = opts.Principals[:1]
+	roles := managedAccessRolesFor("appdb", "appdb")
+	memberRows := func(members ...string) *pgxmock.Rows {
+		rows := pgxmock.NewRows([]string{"rolname"})
+		for _, member := range members {
+			rows.AddRow(member)
+		}
+		return rows
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(scopedRolesSQL())).
+		WithArgs(scopedRoleComment("appdb")).
+		WillReturnRows(memberRows())
+	members := map[string][]string{
+		roles.Reader: {roles.Writer},
+		roles.Writer: {roles.Owner},
+		roles.Owner:  {"app", "intruder"},
+	}
+	for _, roleName := range slices.Sorted(slices.Values(roles.all())) {
+		mock.ExpectQuery(regexp.QuoteMeta(roleMembersSQL())).
+			WithArgs(roleName).
+			WillReturnRows(memberRows(members[roleName]...))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(schemaOwnersSQL())).
+		WithArgs([]string{"appdb"}).
+		WillReturnRows(pgxmock.NewRows([]string{"nspname", "owner"}).AddRow("appdb", roles.Owner))
+	defaults := pgxmock.NewRows([]string{"creator", "schema", "objtype", "grantee"})
+	for _, creator := range []string{roles.Owner, "app"} {
+		for _, objectType := range []string{"r", "S"} {
+			defaults.AddRow(creator, "appdb", objectType, roles.Reader)
+			defaults.AddRow(creator, "appdb", objectType, roles.Writer)
+		}
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(defaultPrivilegesSQL())).
+		WithArgs([]string{"appdb"}).
+		WillReturnRows(defaults)
+	mock.ExpectExec(regexp.QuoteMeta(revokeRoleSQL(roles.Owner, "intruder"))).
+		WillReturnResult(pgxmock.NewResult("REVOKE", 0))
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	if err := writeAccessAudit(context.Background(), mock, opts, true, path); err != nil {
+		t.Fatalf("writeAccessAudit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	report, err := UnmarshalAccessAudit(string(content))
+	if err != nil {
+		t.Fatalf("UnmarshalAccessAudit: %v", err)
+	}
+	want := []AccessDrift{{Kind: AccessDriftUnmanagedMember, Role: roles.Owner, Grantee: "intruder", Revoked: true}}
+	if !slices.Equal(report.Drift, want) {
+		t.Fatalf("unexpected report %#v", report.Drift)
+	}
+}
@@ -30,18 +30,150 @@ const (
 	RevokePublicConnectEnv = "DISPG_REVOKE_PUBLIC_CONNECT"
 	DBSearchPathScopeEnv   = "DISPG_DB_SEARCH_PATH_SCOPE"
 
-	// ServerDebugAccessEnv toggles the server-wide debug-access provisioning mode.
-	// In this mode the Job grants each principal read-only visibility across every
-	// database on the server (one managed NOLOGIN role holding the built-in
-	// monitoring roles plus CONNECT on all databases) instead of per-database
-	// schema access. The per-principal Role field in the access payload is unused.
-	ServerDebugAccessEnv = "DISPG_SERVER_DEBUG_ACCESS"
+	// ProvisionModeEnv selects what the Job does, as a ProvisionMode. Every
+	// provisioning container of a Job carries the same mode.
+	ProvisionModeEnv = "DISPG_PROVISION_MODE"
 
 	// DebugBuiltinRolesEnv carries the comma-separated set of built-in PostgreSQL
 	// roles granted to the managed debug role (e.g. "pg_monitor,pg_read_all_data").
 	DebugBuiltinRolesEnv = "DISPG_DEBUG_BUILTIN_ROLES"
+
+	// DatabaseExtensionsEnv carries the serialized extension payload of an
+	// access run. After access is provisioned the Job creates, updates or
+	// drops each listed extension in the database and writes the outcome to
+	// DatabaseExtensionsOutputPath. Unset when the Database manages no
+	// extensions.
+	DatabaseExtensionsEnv = "DISPG_DATABASE_EXTENSIONS"
+
+	// AccessAuditEnforceEnv makes the access audit revoke the unmanaged role
+	// memberships and default privileges it finds.
+	AccessAuditEnforceEnv = "DISPG_ACCESS_AUDIT_ENFORCE"
+
+	// MigrationPhaseEnv selects a phase of the migration Job that moves a
+	// database to another server: MigrationPhasePrepare or
+	// MigrationPhaseVerify. Both provision access on the target server (the
+	// DBHostEnv) like a regular access run first.
+	MigrationPhaseEnv = "DISPG_MIGRATION_PHASE"
+
+	// MigrationWorkDirEnv is the directory shared by the containers of the
+	// migration Job, where the prepare phase writes the credentials the dump
+	// and restore use.
+	MigrationWorkDirEnv = "DISPG_MIGRATION_WORK_DIR"
+
+	// MigrationSourceHostEnv is the host of the server the database is moved
+	// from. The prepare phase makes it read-only and the verify phase
+	// compares the target with it.
+	MigrationSourceHostEnv = "DISPG_MIGRATION_SOURCE_HOST"
+
+	// BackupPhaseEnv selects a phase of the scheduled backup Job:
+	// BackupPhaseCredentials connects to the database and writes the admin
+	// credentials to BackupWorkDirEnv for the pg_dump container, and
+	// BackupPhaseUpload uploads the dump, prunes old backups and writes the
+	// report to BackupOutputPath. The access payload is not read.
+	BackupPhaseEnv = "DISPG_BACKUP_PHASE"
+
+	// BackupWorkDirEnv is the directory shared by the containers of the
+	// backup Job, holding the credentials and the dump.
+	BackupWorkDirEnv = "DISPG_BACKUP_WORK_DIR"
+
+	// BackupContainerURLEnv is the Blob Storage container, or file://
+	// directory, the upload phase writes to.
+	BackupContainerURLEnv = "DISPG_BACKUP_CONTAINER_URL"
+
+	// BackupPrefixEnv is the name prefix of the database's backups.
+	BackupPrefixEnv = "DISPG_BACKUP_PREFIX"
+
+	// BackupRetentionDaysEnv makes the upload phase prune the database's
+	// backups older than this many days. Unset keeps all backups.
+	BackupRetentionDaysEnv = "DISPG_BACKUP_RETENTION_DAYS"
+
+	// SchemaMigrationsDirEnv names the directory holding the migrations of a
+	// ProvisionModeSchemaMigrations run.
+	SchemaMigrationsDirEnv = "DISPG_SCHEMA_MIGRATIONS_DIR"
 )
 
+// ProvisionMode selects what a provisioning Job does. The operator sets it
+// in ProvisionModeEnv and RunUserProvisioner runs the mode's entry point.
+type ProvisionMode string
+
+const (
+	// ProvisionModeAccess provisions the database's managed roles and schema,
+	// grants the principals in the access payload and reconciles the
+	// extensions in DatabaseExtensionsEnv.
+	ProvisionModeAccess ProvisionMode = "access"
+
+	// ProvisionModeRevokeAllAccess is an access run without principals,
+	// because every principal's access was withdrawn: the Job provisions the
+	// managed roles like any access run, but revokes all their members and
+	// drops the scoped roles. The access payload is not read.
+	ProvisionModeRevokeAllAccess ProvisionMode = "revoke-all-access"
+
+	// ProvisionModeServerDebugAccess grants each principal read-only
+	// visibility across every database on the server (one managed NOLOGIN
+	// role holding DebugBuiltinRolesEnv plus CONNECT on all databases)
+	// instead of per-database schema access. The per-principal Role field in
+	// the access payload is unused, and an empty payload revokes everyone.
+	ProvisionModeServerDebugAccess ProvisionMode = "server-debug-access"
+
+	// ProvisionModeDropDatabase is the approved deletion: the Job connects to
+	// the maintenance database, revokes all memberships of the database's
+	// managed access roles, drops the database (DBNameEnv) and then drops the
+	// managed roles. The access payload is not read.
+	ProvisionModeDropDatabase ProvisionMode = "drop-database"
+
+	// ProvisionModeDatabaseCatalog lists every user database on the server
+	// with its size and connection statistics and writes the report to
+	// DatabaseCatalogOutputPath. The access payload is not read.
+	ProvisionModeDatabaseCatalog ProvisionMode = "database-catalog"
+
+	// ProvisionModeUpgradePreCheck connects to every database on the server,
+	// collects their total size and installed extensions, and writes the
+	// report to UpgradePreCheckOutputPath. The access payload is not read.
+	ProvisionModeUpgradePreCheck ProvisionMode = "upgrade-precheck"
+
+	// ProvisionModeAccessAudit compares the access payload with the role
+	// memberships, schema owners and default privileges in the database and
+	// writes the drift to AccessAuditOutputPath. Nothing is granted unless
+	// AccessAuditEnforceEnv is set.
+	ProvisionModeAccessAudit ProvisionMode = "access-audit"
+
+	// ProvisionModeMigration runs a phase of the migration Job, selected by
+	// MigrationPhaseEnv.
+	ProvisionModeMigration ProvisionMode = "migration"
+
+	// ProvisionModeMigrationRelease lifts the read-only fence the migration
+	// prepare phase set on the source, when the move failed or was
+	// cancelled. It connects to the maintenance database of DBHostEnv, which
+	// is the source then. The access payload is not read.
+	ProvisionModeMigrationRelease ProvisionMode = "migration-release"
+
+	// ProvisionModeBackup runs a phase of the scheduled backup Job, selected
+	// by BackupPhaseEnv.
+	ProvisionModeBackup ProvisionMode = "backup"
+
+	// ProvisionModeSchemaMigrations connects as the Owner principal in
+	// AdminAppIdentityEnv rather than the admin, applies the pending .sql
+	// files in SchemaMigrationsDirEnv as the database's Owner role and writes
+	// the outcome to SchemaMigrationsOutputPath. The access payload is not
+	// read.
+	ProvisionModeSchemaMigrations ProvisionMode = "schema-migrations"
+)
+
+// ProvisionModes lists every ProvisionMode.
+var ProvisionModes = []ProvisionMode{
+	ProvisionModeAccess,
+	ProvisionModeRevokeAllAccess,
+	ProvisionModeServerDebugAccess,
+	ProvisionModeDropDatabase,
+	ProvisionModeDatabaseCatalog,
+	ProvisionModeUpgradePreCheck,
+	ProvisionModeAccessAudit,
+	ProvisionModeMigration,
+	ProvisionModeMigrationRelease,
+	ProvisionModeBackup,
+	ProvisionModeSchemaMigrations,
+}
+
 type AccessRole string
 
 const (
@@ -62,6 +194,32 @@ type AccessPrincipal struct {
 	Name          string        `json:"name"`
 	PrincipalID   string        `json:"principalId,omitempty"`
 	PrincipalType PrincipalType `json:"principalType"`
+	Scope         *AccessScope  `json:"scope,omitempty"`
+	Settings      *RoleSettings `json:"settings,omitempty"`
+}
+
+// AccessScope widens or narrows what a principal's role applies to. Each
+// scope is granted through extra managed roles next to the Reader, Writer and
+// Owner roles of the database's schema. A nil scope leaves the payload, and so
+// the Job name, unchanged for principals without one.
+type AccessScope struct {
+	// Schemas are additional schemas the role applies to.
+	Schemas []string `json:"schemas,omitempty"`
+	// Tables limits a Reader to SELECT on these tables instead of the
+	// database's schema.
+	Tables []AccessTable `json:"tables,omitempty"`
+	// ExecuteFunctions grants EXECUTE on the functions of the role's schemas.
+	ExecuteFunctions bool `json:"executeFunctions,omitempty"`
+}
+
+// AccessTable is a schema-qualified table or view.
+type AccessTable struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+}
+
+func (table AccessTable) String() string {
+	return table.Schema + "." + table.Name
 }
 
 type AccessPrincipalsPayload struct {
@@ -115,6 +273,8 @@ func normalizedAccessPrincipals(principals []AccessPrincipal) []AccessPrincipal
 		principal.Name = strings.TrimSpace(principal.Name)
 		principal.PrincipalID = strings.TrimSpace(principal.PrincipalID)
 		principal.PrincipalType = PrincipalType(strings.TrimSpace(string(principal.PrincipalType)))
+		principal.Scope = normalizedAccessScope(principal.Scope)
+		principal.Settings = normalizedRoleSettings(principal.Settings)
 		normalized = append(normalized, principal)
 	}
 
@@ -135,6 +295,37 @@ func normalizedAccessPrincipals(principals []AccessPrincipal) []AccessPrincipal
 	return normalized
 }
 
+// normalizedAccessScope returns a sorted, de-duplicated copy of scope, or nil
+// when it grants nothing extra.
+func normalizedAccessScope(scope *AccessScope) *AccessScope {
+	if scope == nil {
+		return nil
+	}
+	normalized := &AccessScope{ExecuteFunctions: scope.ExecuteFunctions}
+	for _, schema := range scope.Schemas {
+		if schema = strings.TrimSpace(schema); schema != "" {
+			normalized.Schemas = append(normalized.Schemas, schema)
+		}
+	}
+	slices.Sort(normalized.Schemas)
+	normalized.Schemas = slices.Compact(normalized.Schemas)
+	for _, table := range scope.Tables {
+		table.Schema = strings.TrimSpace(table.Schema)
+		table.Name = strings.TrimSpace(table.Name)
+		if table.Name != "" {
+			normalized.Tables = append(normalized.Tables, table)
+		}
+	}
+	slices.SortFunc(normalized.Tables, func(a, b AccessTable) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	normalized.Tables = slices.Compact(normalized.Tables)
+	if len(normalized.Schemas) == 0 && len(normalized.Tables) == 0 && !normalized.ExecuteFunctions {
+		return nil
+	}
+	return normalized
+}
+
 // NormalizeBuiltinRoles parses a comma-separated built-in role list into a
 // trimmed, de-duplicated, sorted slice. It is deterministic so the same set
 // always yields the same order, keeping the derived managed-role name and Job
@@ -0,0 +1,318 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Internal roles of the scoped managed roles. They only feed managedRoleName,
+// so they never appear in the access payload.
+const (
+	accessRoleExecute     AccessRole = "Execute"
+	accessRoleTableReader AccessRole = "TableReader"
+)
+
+// scopedAccessPlan holds the managed roles that principal scopes need on top
+// of the Reader, Writer and Owner roles of the database's schema, and the
+// principals that should be members of them.
+type scopedAccessPlan struct {
+	// schemas maps each additional schema to its Reader, Writer and Owner roles.
+	schemas map[string]managedAccessRoles
+	// execute maps a schema to the role holding EXECUTE on its functions.
+	execute map[string]string
+	// tables maps a table reader role to the tables it may read.
+	tables      map[string][]AccessTable
+	memberships *managedRoleMemberships
+}
+
+// planScopedAccess derives the scoped managed roles from the principals in
+// opts. Role names are pure functions of the database and the scope, so the
+// same scope on several principals shares one role.
+func planScopedAccess(opts accessOptions) (*scopedAccessPlan, error) {
+	plan := &scopedAccessPlan{
+		schemas: map[string]managedAccessRoles{},
+		execute: map[string]string{},
+		tables:  map[string][]AccessTable{},
+		memberships: &managedRoleMemberships{
+			members: map[string]map[string]struct{}{},
+		},
+	}
+
+	for _, principal := range opts.Principals {
+		scope := normalizedAccessScope(principal.Scope)
+		if scope == nil {
+			continue
+		}
+		name := strings.TrimSpace(principal.Name)
+
+		if len(scope.Tables) > 0 {
+			if principal.Role != AccessRoleReader {
+				return nil, fmt.Errorf("principal %s: table scope is only supported for the Reader role", name)
+			}
+			tables := make([]AccessTable, 0, len(scope.Tables))
+			for _, table := range scope.Tables {
+				if table.Schema == "" {
+					table.Schema = opts.SchemaName
+				}
+				tables = append(tables, table)
+			}
+			// Sorted again now that every table has a schema, so the role
+			// name does not depend on whether the schema was spelled out.
+			slices.SortFunc(tables, func(a, b AccessTable) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			tables = slices.Compact(tables)
+			roleName := tableReaderRoleName(opts.DatabaseName, tables)
+			plan.tables[roleName] = tables
+			plan.memberships.add(roleName, name)
+		}
+
+		for _, schema := range scope.Schemas {
+			if schema == opts.SchemaName {
+				continue
+			}
+			roles := managedAccessRolesFor(opts.DatabaseName, schema)
+			roleName, err := roles.roleFor(principal.Role)
+			if err != nil {
+				return nil, err
+			}
+			plan.schemas[schema] = roles
+			plan.memberships.add(roleName, name)
+		}
+
+		if scope.ExecuteFunctions {
+			for _, schema := range append([]string{opts.SchemaName}, scope.Schemas...) {
+				roleName := managedRoleName(opts.DatabaseName, schema, accessRoleExecute)
+				plan.execute[schema] = roleName
+				plan.memberships.add(roleName, name)
+			}
+		}
+	}
+
+	for _, roles := range plan.schemas {
+		// Every schema role is planned, even one without members of its own.
+		if plan.memberships.members[roles.Owner] == nil {
+			plan.memberships.members[roles.Owner] = map[string]struct{}{}
+		}
+		plan.memberships.add(roles.Reader, roles.Writer)
+		plan.memberships.add(roles.Writer, roles.Owner)
+	}
+	plan.memberships.roles = slices.Sorted(maps.Keys(plan.memberships.members))
+	return plan, nil
+}
+
+// roles returns every role of the plan, sorted.
+func (plan *scopedAccessPlan) roles() []string {
+	return plan.memberships.roles
+}
+
+// tableReaderRoleName returns the managed role reading exactly tables.
+func tableReaderRoleName(databaseName string, tables []AccessTable) string {
+	refs := make([]string, 0, len(tables))
+	for _, table := range tables {
+		refs = append(refs, table.String())
+	}
+	return managedRoleName(databaseName, strings.Join(refs, ","), accessRoleTableReader)
+}
+
+// ensureScopedAccess creates and grants the scoped managed roles of plan,
+// reconciles their members, and drops the scoped roles of the database that
+// are no longer planned. Scoped roles are marked with a role comment, which is
+// how roles of removed scopes are found again. Missing additional schemas are
+// created owned by the database's Owner role, so dropping a scoped role never
+// drops a schema.
+func ensureScopedAccess(
+	ctx context.Context,
+	conn pgxConn,
+	opts accessOptions,
+	roles managedAccessRoles,
+	creators []string,
+	plan *scopedAccessPlan,
+) error {
+	comment := scopedRoleComment(opts.DatabaseName)
+	for _, roleName := range plan.roles() {
+		if err := ensureManagedRole(ctx, conn, roleName); err != nil {
+			return err
+		}
+		if _, err := conn.Exec(ctx, commentOnRoleSQL(roleName, comment)); err != nil {
+			return fmt.Errorf("mark scoped role %s: %w", roleName, err)
+		}
+	}
+
+	for _, schema := range slices.Sorted(maps.Keys(plan.schemas)) {
+		schemaRoles := plan.schemas[schema]
+		if _, err := conn.Exec(ctx, createSchemaSQL(schema, roles.Owner)); err != nil {
+			return fmt.Errorf("create schema %s: %w", schema, err)
+		}
+		schemaOpts := opts
+		schemaOpts.SchemaName = schema
+		if err := ensureManagedRoleGrants(ctx, conn, schemaOpts, schemaRoles); err != nil {
+			return err
+		}
+		for _, creator := range creators {
+			if err := grantDefaultPrivilegesForCreator(ctx, conn, creator, schema, schemaRoles); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, schema := range slices.Sorted(maps.Keys(plan.execute)) {
+		roleName := plan.execute[schema]
+		statements := []struct {
+			name string
+			sql  string
+		}{
+			{name: "grant execute connect", sql: grantConnectSQL(opts.DatabaseName, roleName)},
+			{name: "grant execute schema usage", sql: grantSchemaUsageSQL(schema, roleName)},
+			{name: "grant execute functions", sql: grantAllFunctionsExecuteSQL(schema, roleName)},
+		}
+		for _, statement := range statements {
+			if _, err := conn.Exec(ctx, statement.sql); err != nil {
+				return fmt.Errorf("%s on %s: %w", statement.name, schema, err)
+			}
+		}
+		for _, creator := range creators {
+			if _, err := conn.Exec(ctx, alterDefaultFunctionExecutePrivilegesSQL(creator, schema, roleName)); err != nil {
+				return fmt.Errorf("grant default execute functions on %s: %w", schema, err)
+			}
+		}
+	}
+
+	for _, roleName := range slices.Sorted(maps.Keys(plan.tables)) {
+		if _, err := conn.Exec(ctx, grantConnectSQL(opts.DatabaseName, roleName)); err != nil {
+			return fmt.Errorf("grant table reader connect: %w", err)
+		}
+		usage := map[string]struct{}{}
+		for _, table := range plan.tables[roleName] {
+			if _, ok := usage[table.Schema]; !ok {
+				usage[table.Schema] = struct{}{}
+				if _, err := conn.Exec(ctx, grantSchemaUsageSQL(table.Schema, roleName)); err != nil {
+					return fmt.Errorf("grant table reader schema usage on %s: %w", table.Schema, err)
+				}
+			}
+			if _, err := conn.Exec(ctx, grantTableReadSQL(table, roleName)); err != nil {
+				return fmt.Errorf("grant table reader select on %s: %w", table, err)
+			}
+		}
+	}
+
+	if err := reconcileManagedRoleMemberships(ctx, conn, plan.memberships); err != nil {
+		return err
+	}
+
+	existing, err := scopedRoles(ctx, conn, opts.DatabaseName)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for _, roleName := range existing {
+		if _, ok := plan.memberships.members[roleName]; !ok {
+			stale = append(stale, roleName)
+		}
+	}
+	return dropScopedRoles(ctx, conn, stale, true)
+}
+
+// dropScopedRoles revokes every membership of roles and drops them. With
+// dropOwned the privileges of the roles in the connected database are dropped
+// first; the drop of a whole database does without, since its privileges go
+// with the database.
+func dropScopedRoles(ctx context.Context, conn pgxConn, roles []string, dropOwned bool) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	revokeAll := &managedRoleMemberships{
+		roles:   roles,
+		members: map[string]map[string]struct{}{},
+	}
+	if err := reconcileManagedRoleMemberships(ctx, conn, revokeAll); err != nil {
+		return err
+	}
+	for _, roleName := range roles {
+		if dropOwned {
+			if _, err := conn.Exec(ctx, dropOwnedBySQL(roleName)); err != nil {
+				return fmt.Errorf("drop privileges of scoped role %s: %w", roleName, err)
+			}
+		}
+		if _, err := conn.Exec(ctx, dropRoleSQL(roleName)); err != nil {
+			return fmt.Errorf("drop scoped role %s: %w", roleName, err)
+		}
+	}
+	return nil
+}
+
+// scopedRoles lists the scoped managed roles of a database, sorted.
+func scopedRoles(ctx context.Context, conn pgxConn, databaseName string) ([]string, error) {
+	rows, err := conn.Query(ctx, scopedRolesSQL(), scopedRoleComment(databaseName))
+	if err != nil {
+		return nil, fmt.Errorf("list scoped roles of database %s: %w", databaseName, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var roleName string
+		if err := rows.Scan(&roleName); err != nil {
+			return nil, fmt.Errorf("scan scoped role of database %s: %w", databaseName, err)
+		}
+		roles = append(roles, roleName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate scoped roles of database %s: %w", databaseName, err)
+	}
+	slices.Sort(roles)
+	return roles, nil
+}
+
+// scopedRoleComment is the role comment that marks the scoped managed roles
+// of a database.
+func scopedRoleComment(databaseName string) string {
+	return "dis-pgsql scoped access role of database " + databaseName
+}
+
+func scopedRolesSQL() string {
+	return `SELECT role.rolname
+FROM pg_roles role
+JOIN pg_shdescription description
+  ON description.objoid = role.oid
+ AND description.classoid = 'pg_authid'::regclass
+WHERE description.description = $1`
+}
+
+func commentOnRoleSQL(role, comment string) string {
+	return fmt.Sprintf("COMMENT ON ROLE %s IS '%s';",
+		pgx.Identifier{role}.Sanitize(),
+		strings.ReplaceAll(comment, "'", "''"),
+	)
+}
+
+func dropOwnedBySQL(role string) string {
+	return fmt.Sprintf("DROP OWNED BY %s;", pgx.Identifier{role}.Sanitize())
+}
+
+func grantTableReadSQL(table AccessTable, role string) string {
+	return fmt.Sprintf("GRANT SELECT ON TABLE %s TO %s;",
+		pgx.Identifier{table.Schema, table.Name}.Sanitize(),
+		pgx.Identifier{role}.Sanitize(),
+	)
+}
+
+func grantAllFunctionsExecuteSQL(schemaName, role string) string {
+	return fmt.Sprintf("GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA %s TO %s;",
+		pgx.Identifier{schemaName}.Sanitize(),
+		pgx.Identifier{role}.Sanitize(),
+	)
+}
+
+func alterDefaultFunctionExecutePrivilegesSQL(creator, schemaName, role string) string {
+	return fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT EXECUTE ON FUNCTIONS TO %s;",
+		pgx.Identifier{creator}.Sanitize(),
+		pgx.Identifier{schemaName}.Sanitize(),
+		pgx.Identifier{role}.Sanitize(),
+	)
+}
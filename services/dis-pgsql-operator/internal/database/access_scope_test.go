@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPlanScopedAccess(t *testing.T) {
+	plan, err := planScopedAccess(accessOptions{
+		DatabaseName: appDBName,
+		SchemaName:   appDBName,
+		Principals: []AccessPrincipal{
+			{
+				Role:          AccessRoleWriter,
+				Name:          "app-user",
+				PrincipalType: PrincipalTypeService,
+				Scope:         &AccessScope{Schemas: []string{"audit", "audit"}, ExecuteFunctions: true},
+			},
+			{
+				Role:          AccessRoleReader,
+				Name:          "report-user",
+				PrincipalType: PrincipalTypeService,
+				Scope:         &AccessScope{Tables: []AccessTable{{Name: "orders"}, {Schema: "audit", Name: "events"}}},
+			},
+			{
+				Role:          AccessRoleOwner,
+				Name:          "plain-owner",
+				PrincipalType: PrincipalTypeGroup,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("planScopedAccess: %v", err)
+	}
+
+	audit := managedAccessRolesFor(appDBName, "audit")
+	if plan.schemas["audit"] != audit || len(plan.schemas) != 1 {
+		t.Fatalf("expected the audit schema roles, got %#v", plan.schemas)
+	}
+	for _, schema := range []string{appDBName, "audit"} {
+		if plan.execute[schema] != managedRoleName(appDBName, schema, accessRoleExecute) {
+			t.Fatalf("expected an execute role for schema %s, got %#v", schema, plan.execute)
+		}
+	}
+	tableRole := tableReaderRoleName(appDBName, []AccessTable{{Schema: appDBName, Name: "orders"}, {Schema: "audit", Name: "events"}})
+	if tables := plan.tables[tableRole]; len(tables) != 2 || tables[0].Schema != appDBName {
+		t.Fatalf("expected the table reader role with defaulted schemas, got %#v", plan.tables)
+	}
+
+	if _, ok := plan.memberships.members[audit.Writer]["app-user"]; !ok {
+		t.Fatalf("expected app-user in the audit writer role")
+	}
+	if _, ok := plan.memberships.members[audit.Reader][audit.Writer]; !ok {
+		t.Fatalf("expected the audit role hierarchy")
+	}
+	if _, ok := plan.memberships.members[tableRole]["report-user"]; !ok {
+		t.Fatalf("expected report-user in the table reader role")
+	}
+	if len(plan.roles()) != 6 {
+		t.Fatalf("expected three schema roles, two execute roles and one table role, got %v", plan.roles())
+	}
+}
+
+func TestPlanScopedAccessRejectsTablesForWriters(t *testing.T) {
+	_, err := planScopedAccess(accessOptions{
+		DatabaseName: appDBName,
+		SchemaName:   appDBName,
+		Principals: []AccessPrincipal{{
+			Role:          AccessRoleWriter,
+			Name:          "app-user",
+			PrincipalType: PrincipalTypeService,
+			Scope:         &AccessScope{Tables: []AccessTable{{Name: "orders"}}},
+		}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a table scope on a Writer")
+	}
+}
+
+func TestEnsureAccessGrantsScopedRoles(t *testing.T) {
+	conn := &recordingConn{}
+	tables := []AccessTable{{Schema: appDBName, Name: "orders"}}
+
+	if err := ensureAccess(context.Background(), conn, &recordingConn{}, accessOptions{
+		DatabaseName: appDBName,
+		SchemaName:   appDBName,
+		Principals: []AccessPrincipal{
+			{
+				Role:          AccessRoleOwner,
+				Name:          "app-owner",
+				PrincipalType: PrincipalTypeService,
+				Scope:         &AccessScope{Schemas: []string{"audit"}, ExecuteFunctions: true},
+			},
+			{
+				Role:          AccessRoleReader,
+				Name:          "report-user",
+				PrincipalType: PrincipalTypeService,
+				Scope:         &AccessScope{Tables: tables},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("ensureAccess: %v", err)
+	}
+
+	roles := managedAccessRolesFor(appDBName, appDBName)
+	audit := managedAccessRolesFor(appDBName, "audit")
+	execute := managedRoleName(appDBName, appDBName, accessRoleExecute)
+	tableRole := tableReaderRoleName(appDBName, tables)
+
+	requireExec(t, conn, createNoLoginRoleSQL(audit.Owner))
+	requireExec(t, conn, commentOnRoleSQL(audit.Owner, scopedRoleComment(appDBName)))
+	requireExec(t, conn, createSchemaSQL("audit", roles.Owner))
+	requireNoExec(t, conn, alterSchemaOwnerSQL("audit", audit.Owner))
+	requireExec(t, conn, grantSchemaCreateSQL("audit", audit.Owner))
+	requireExec(t, conn, alterDefaultTableReadPrivilegesSQL("app-owner", "audit", audit.Reader))
+	requireExec(t, conn, grantRoleSQL(audit.Owner, "app-owner"))
+	requireExec(t, conn, grantRoleSQL(roles.Owner, "app-owner"))
+	requireExec(t, conn, grantAllFunctionsExecuteSQL(appDBName, execute))
+	requireExec(t, conn, alterDefaultFunctionExecutePrivilegesSQL("app-owner", appDBName, execute))
+	requireExec(t, conn, grantRoleSQL(execute, "app-owner"))
+	requireExec(t, conn, grantTableReadSQL(tables[0], tableRole))
+	requireExec(t, conn, grantRoleSQL(tableRole, "report-user"))
+	requireNoExec(t, conn, grantRoleSQL(roles.Reader, "report-user"))
+}
+
+func TestEnsureAccessDropsRemovedScopedRoles(t *testing.T) {
+	stale := managedRoleName(appDBName, "audit", accessRoleExecute)
+	conn := &recordingConn{
+		members: map[string][]string{
+			scopedRoleComment(appDBName): {stale},
+			stale:                        {"app-user"},
+		},
+	}
+
+	if err := ensureAccess(context.Background(), conn, &recordingConn{}, accessOptions{
+		DatabaseName: appDBName,
+		SchemaName:   appDBName,
+		Principals: []AccessPrincipal{{
+			Role:          AccessRoleWriter,
+			Name:          "app-user",
+			PrincipalType: PrincipalTypeService,
+		}},
+	}); err != nil {
+		t.Fatalf("ensureAccess: %v", err)
+	}
+
+	requireExec(t, conn, revokeRoleSQL(stale, "app-user"))
+	requireExec(t, conn, dropOwnedBySQL(stale))
+	requireExec(t, conn, dropRoleSQL(stale))
+	if execIndex(t, conn, dropOwnedBySQL(stale)) > execIndex(t, conn, dropRoleSQL(stale)) {
+		t.Fatalf("expected the privileges of %s to be dropped before the role", stale)
+	}
+}
+
+func TestScopedAccessSQL(t *testing.T) {
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "comment on role",
+			got:  commentOnRoleSQL("managed-role", "it's scoped"),
+			want: `COMMENT ON ROLE "managed-role" IS 'it''s scoped';`,
+		},
+		{
+			name: "drop owned",
+			got:  dropOwnedBySQL("managed-role"),
+			want: `DROP OWNED BY "managed-role";`,
+		},
+		{
+			name: "grant table read",
+			got:  grantTableReadSQL(AccessTable{Schema: "app-db", Name: "orders"}, "managed-role"),
+			want: `GRANT SELECT ON TABLE "app-db"."orders" TO "managed-role";`,
+		},
+		{
+			name: "grant function execute",
+			got:  grantAllFunctionsExecuteSQL("app-db", "managed-role"),
+			want: `GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA "app-db" TO "managed-role";`,
+		},
+		{
+			name: "default function execute",
+			got:  alterDefaultFunctionExecutePrivilegesSQL("owner", "app-db", "managed-role"),
+			want: `ALTER DEFAULT PRIVILEGES FOR ROLE "owner" IN SCHEMA "app-db" GRANT EXECUTE ON FUNCTIONS TO "managed-role";`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf("got %q, want %q", tc.got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+// PgBouncerPort is the port the built-in PgBouncer listens on.
+const PgBouncerPort = 6432
+
+// ConnectionPooling is the resolved PgBouncer configuration of a server.
+type ConnectionPooling struct {
+	Enabled  bool
+	PoolMode string
+}
+
+// ResolveConnectionPooling combines spec.connectionPooling with the profile.
+// Without a connectionPooling block, PgBouncer follows the profile. Enabling
+// it explicitly on a Burstable profile is an error, since Azure rejects it.
+func ResolveConnectionPooling(
+	profile Profile,
+	requested *storagev1alpha1.DatabaseServerConnectionPoolingSpec,
+) (ConnectionPooling, error) {
+	pooling := ConnectionPooling{
+		Enabled:  profile.PgBouncerEnabled && profile.SupportsPgBouncer(),
+		PoolMode: defaultPgBouncerPoolMode,
+	}
+	if requested == nil {
+		return pooling, nil
+	}
+
+	pooling.Enabled = requested.Enabled == nil || *requested.Enabled
+	if requested.PoolMode != "" {
+		pooling.PoolMode = strings.ToLower(string(requested.PoolMode))
+	}
+	if pooling.Enabled && !profile.SupportsPgBouncer() {
+		return ConnectionPooling{}, fmt.Errorf(
+			"spec.connectionPooling is not supported on the %s tier (SKU %s); select a GeneralPurpose or MemoryOptimized profile",
+			profile.SkuTier, profile.SkuName,
+		)
+	}
+	return pooling, nil
+}
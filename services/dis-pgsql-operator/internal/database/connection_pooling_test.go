@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestResolveConnectionPooling(t *testing.T) {
+	disabled := false
+
+	cases := []struct {
+		name        string
+		profile     Profile
+		requested   *storagev1alpha1.DatabaseServerConnectionPoolingSpec
+		wantEnabled bool
+		wantMode    string
+		wantErr     bool
+	}{
+		{name: "follows prod profile", profile: prodProfile, wantEnabled: true, wantMode: "transaction"},
+		{name: "follows dev profile", profile: devProfile, wantEnabled: false, wantMode: "transaction"},
+		{
+			name:        "enables with pool mode",
+			profile:     Profile{SkuName: "Standard_D2s_v3", SkuTier: prodProfile.SkuTier, MemoryGB: 8},
+			requested:   &storagev1alpha1.DatabaseServerConnectionPoolingSpec{PoolMode: storagev1alpha1.ConnectionPoolModeStatement},
+			wantEnabled: true,
+			wantMode:    "statement",
+		},
+		{
+			name:      "disables on prod profile",
+			profile:   prodProfile,
+			requested: &storagev1alpha1.DatabaseServerConnectionPoolingSpec{Enabled: &disabled},
+			wantMode:  "transaction",
+		},
+		{
+			name:      "disabled block is accepted on Burstable",
+			profile:   devProfile,
+			requested: &storagev1alpha1.DatabaseServerConnectionPoolingSpec{Enabled: &disabled},
+			wantMode:  "transaction",
+		},
+		{
+			name:      "rejects Burstable",
+			profile:   devProfile,
+			requested: &storagev1alpha1.DatabaseServerConnectionPoolingSpec{},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveConnectionPooling(tc.profile, tc.requested)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveConnectionPooling returned error: %v", err)
+			}
+			if got.Enabled != tc.wantEnabled || got.PoolMode != tc.wantMode {
+				t.Fatalf("ResolveConnectionPooling = %#v, want enabled=%t mode=%q", got, tc.wantEnabled, tc.wantMode)
+			}
+		})
+	}
+}
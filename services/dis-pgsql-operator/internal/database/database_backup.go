@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+const (
+	// BackupPayloadVersion versions the report of the backup upload phase.
+	BackupPayloadVersion = 1
+
+	// BackupOutputPath is where the upload phase writes its report, read by
+	// the operator from the Pod termination message.
+	BackupOutputPath = DatabaseCatalogOutputPath
+
+	BackupPhaseCredentials = "credentials"
+	BackupPhaseUpload      = "upload"
+
+	// BackupDumpFile is the pg_dump archive in the work directory.
+	BackupDumpFile = "dump"
+
+	// backupSuffix ends every backup name, after the time it was taken in
+	// backupTimeLayout. Pruning only considers names of this form.
+	backupSuffix     = ".dump"
+	backupTimeLayout = "20060102T150405Z"
+
+	// blobStorageHostSuffix follows the account name in Blob Storage hosts.
+	blobStorageHostSuffix = ".blob.core.windows.net"
+
+	// backupBlockSize is the size of the blocks a backup is uploaded in. A
+	// blob holds at most 50,000 blocks, so backups up to 1.5 TiB fit.
+	backupBlockSize = 32 << 20
+)
+
+// BackupReport is the report written by the upload phase.
+type BackupReport struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"sizeBytes"`
+	Pruned    int32  `json:"pruned,omitempty"`
+}
+
+// MarshalBackupReport serializes the report.
+func MarshalBackupReport(report BackupReport) (string, error) {
+	report.Version = BackupPayloadVersion
+	content, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("marshal backup report: %w", err)
+	}
+	return string(content), nil
+}
+
+// UnmarshalBackupReport parses a report written by the upload phase.
+func UnmarshalBackupReport(value string) (BackupReport, error) {
+	var report BackupReport
+	if err := json.Unmarshal([]byte(value), &report); err != nil {
+		return BackupReport{}, fmt.Errorf("parse backup report: %w", err)
+	}
+	if report.Version != BackupPayloadVersion {
+		return BackupReport{}, fmt.Errorf("unsupported backup report version %d", report.Version)
+	}
+	return report, nil
+}
+
+// backupStore is where backups are written: a Blob Storage container, or a
+// directory for tests and local clusters.
+type backupStore interface {
+	Upload(ctx context.Context, name string, content io.Reader) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// runBackupUpload reads the upload settings from the environment, uploads
+// the dump and writes the report to BackupOutputPath.
+func runBackupUpload(ctx context.Context) error {
+	workDir := strings.TrimSpace(os.Getenv(BackupWorkDirEnv))
+	containerURL := strings.TrimSpace(os.Getenv(BackupContainerURLEnv))
+	prefix := strings.TrimSpace(os.Getenv(BackupPrefixEnv))
+	if workDir == "" || containerURL == "" || prefix == "" {
+		return fmt.Errorf("%s, %s and %s must be set in the backup upload phase", BackupWorkDirEnv, BackupContainerURLEnv, BackupPrefixEnv)
+	}
+	var retention time.Duration
+	if raw := strings.TrimSpace(os.Getenv(BackupRetentionDaysEnv)); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			return fmt.Errorf("%s must be a positive number of days", BackupRetentionDaysEnv)
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	// Local clusters run without Entra, and back up to Azurite or a
+	// directory.
+	store, err := newBackupStore(containerURL, parseBoolEnv(os.Getenv(DisableAADEnv)))
+	if err != nil {
+		return err
+	}
+	report, err := uploadBackup(ctx, store, filepath.Join(workDir, BackupDumpFile), prefix, retention, time.Now())
+	if err != nil {
+		return err
+	}
+	content, err := MarshalBackupReport(report)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(BackupOutputPath, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write backup report to %s: %w", BackupOutputPath, err)
+	}
+	return nil
+}
+
+// uploadBackup uploads the dump at dumpPath as <prefix><time>.dump and
+// then prunes the backups under prefix older than retention, keeping the
+// new one. A zero retention keeps every backup.
+func uploadBackup(
+	ctx context.Context,
+	store backupStore,
+	dumpPath string,
+	prefix string,
+	retention time.Duration,
+	now time.Time,
+) (BackupReport, error) {
+	dump, err := os.Open(dumpPath)
+	if err != nil {
+		return BackupReport{}, fmt.Errorf("open dump: %w", err)
+	}
+	defer func() { _ = dump.Close() }()
+	info, err := dump.Stat()
+	if err != nil {
+		return BackupReport{}, fmt.Errorf("stat dump: %w", err)
+	}
+
+	now = now.UTC()
+	name := prefix + now.Format(backupTimeLayout) + backupSuffix
+	if err := store.Upload(ctx, name, dump); err != nil {
+		return BackupReport{}, fmt.Errorf("upload backup %s: %w", name, err)
+	}
+	report := BackupReport{
+		Version:   BackupPayloadVersion,
+		Name:      name,
+		SizeBytes: info.Size(),
+	}
+	if retention > 0 {
+		pruned, err := pruneBackups(ctx, store, prefix, now.Add(-retention), name)
+		if err != nil {
+			return BackupReport{}, err
+		}
+		report.Pruned = pruned
+	}
+	return report, nil
+}
+
+// pruneBackups deletes the backups under prefix taken before cutoff, except
+// keep. Names that do not parse as a backup are left alone.
+func pruneBackups(ctx context.Context, store backupStore, prefix string, cutoff time.Time, keep string) (int32, error) {
+	names, err := store.List(ctx, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("list backups: %w", err)
+	}
+	var pruned int32
+	for _, name := range names {
+		if name == keep {
+			continue
+		}
+		taken, ok := backupTime(prefix, name)
+		if !ok || !taken.Before(cutoff) {
+			continue
+		}
+		if err := store.Delete(ctx, name); err != nil {
+			return pruned, fmt.Errorf("delete backup %s: %w", name, err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// backupTime parses the time a backup under prefix was taken from its name.
+func backupTime(prefix, name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, backupSuffix)
+	if !ok {
+		return time.Time{}, false
+	}
+	taken, err := time.Parse(backupTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return taken, true
+}
+
+// BlobStorageAccount returns the storage account of a Blob Storage URL,
+// https://<account>.blob.core.windows.net, and false for any other URL.
+func BlobStorageAccount(containerURL *url.URL) (string, bool) {
+	if containerURL.Scheme != "https" || containerURL.User != nil || containerURL.Port() != "" {
+		return "", false
+	}
+	account, found := strings.CutSuffix(strings.ToLower(containerURL.Hostname()), blobStorageHostSuffix)
+	if !found || account == "" || strings.Contains(account, ".") {
+		return "", false
+	}
+	return account, true
+}
+
+// newBackupStore returns the store for containerURL. Blob Storage is
+// accessed with the Pod's workload identity, the DatabaseServer's admin, so
+// only Blob Storage hosts get its token; the operator checks the account.
+// http:// (Azurite) and file:// URLs are only accepted with allowLocal.
+func newBackupStore(containerURL string, allowLocal bool) (backupStore, error) {
+	parsed, err := url.Parse(containerURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", BackupContainerURLEnv, err)
+	}
+	if _, blob := BlobStorageAccount(parsed); !blob && !allowLocal {
+		return nil, fmt.Errorf("%s must be an https://<account>%s URL", BackupContainerURLEnv, blobStorageHostSuffix)
+	}
+	switch parsed.Scheme {
+	case "file":
+		return fileBackupStore{dir: parsed.Path}, nil
+	case "https", "http":
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("azure credential error: %w", err)
+		}
+		return newBlobBackupStore(containerURL, cred, azcore.ClientOptions{
+			InsecureAllowCredentialWithHTTP: parsed.Scheme == "http",
+		})
+	default:
+		return nil, fmt.Errorf("%s must be an https://, http:// or file:// URL", BackupContainerURLEnv)
+	}
+}
+
+func newBlobBackupStore(containerURL string, cred azcore.TokenCredential, options azcore.ClientOptions) (*blobBackupStore, error) {
+	client, err := container.NewClient(containerURL, cred, &container.ClientOptions{ClientOptions: options})
+	if err != nil {
+		return nil, fmt.Errorf("create Blob Storage client for %s: %w", containerURL, err)
+	}
+	return &blobBackupStore{client: client}, nil
+}
+
+// fileBackupStore keeps backups in a directory, with the blob name as the
+// relative path.
+type fileBackupStore struct {
+	dir string
+}
+
+func (store fileBackupStore) Upload(_ context.Context, name string, content io.Reader) error {
+	target := filepath.Join(store.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (store fileBackupStore) List(_ context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(store.dir, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(store.dir, current)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	slices.Sort(names)
+	return names, err
+}
+
+func (store fileBackupStore) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(store.dir, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// blobBackupStore keeps backups in a Blob Storage container. Backups are
+// uploaded in blocks, so they are streamed from the dump instead of read into
+// memory at once.
+type blobBackupStore struct {
+	client *container.Client
+}
+
+func (store *blobBackupStore) Upload(ctx context.Context, name string, content io.Reader) error {
+	_, err := store.client.NewBlockBlobClient(name).UploadStream(ctx, content, &blockblob.UploadStreamOptions{
+		BlockSize: backupBlockSize,
+	})
+	return err
+}
+
+func (store *blobBackupStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	pager := store.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range page.Segment.BlobItems {
+			if blob.Name != nil {
+				names = append(names, *blob.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (store *blobBackupStore) Delete(ctx context.Context, name string) error {
+	_, err := store.client.NewBlobClient(name).Delete(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return err
+}
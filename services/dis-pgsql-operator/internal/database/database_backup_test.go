@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+func TestUploadBackupPrunesOldBackups(t *testing.T) {
+	workDir := t.TempDir()
+	dumpPath := filepath.Join(workDir, BackupDumpFile)
+	if err := os.WriteFile(dumpPath, []byte("PGDMP archive"), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+
+	store := fileBackupStore{dir: t.TempDir()}
+	ctx := context.Background()
+	for _, name := range []string{
+		"apps/app-db/20261001T020000Z.dump",
+		"apps/app-db/20261015T020000Z.dump",
+		"apps/app-db/notes.txt",
+		"apps/other/20261001T020000Z.dump",
+	} {
+		if err := store.Upload(ctx, name, strings.NewReader("old")); err != nil {
+			t.Fatalf("seed %s: %v", name, err)
+		}
+	}
+
+	now := time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)
+	report, err := uploadBackup(ctx, store, dumpPath, "apps/app-db/", 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("uploadBackup: %v", err)
+	}
+	if report.Name != "apps/app-db/20261018T020000Z.dump" || report.SizeBytes != int64(len("PGDMP archive")) || report.Pruned != 1 {
+		t.Fatalf("unexpected report %#v", report)
+	}
+
+	names, err := store.List(ctx, "apps/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := "apps/app-db/20261015T020000Z.dump,apps/app-db/20261018T020000Z.dump,apps/app-db/notes.txt,apps/other/20261001T020000Z.dump"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("expected backups %s, got %s", want, got)
+	}
+}
+
+func TestUploadBackupWithoutRetentionKeepsBackups(t *testing.T) {
+	workDir := t.TempDir()
+	dumpPath := filepath.Join(workDir, BackupDumpFile)
+	if err := os.WriteFile(dumpPath, nil, 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	store := fileBackupStore{dir: t.TempDir()}
+	ctx := context.Background()
+	if err := store.Upload(ctx, "ns/db/20200101T000000Z.dump", strings.NewReader("old")); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	report, err := uploadBackup(ctx, store, dumpPath, "ns/db/", 0, time.Now())
+	if err != nil {
+		t.Fatalf("uploadBackup: %v", err)
+	}
+	names, _ := store.List(ctx, "ns/db/")
+	if report.Pruned != 0 || len(names) != 2 {
+		t.Fatalf("expected no pruning, got %d pruned and %v", report.Pruned, names)
+	}
+}
+
+func TestBackupReportRoundTrip(t *testing.T) {
+	content, err := MarshalBackupReport(BackupReport{Name: "ns/db/20261018T020000Z.dump", SizeBytes: 42})
+	if err != nil {
+		t.Fatalf("MarshalBackupReport: %v", err)
+	}
+	report, err := UnmarshalBackupReport(content)
+	if err != nil || report.SizeBytes != 42 {
+		t.Fatalf("unexpected report %#v (%v)", report, err)
+	}
+	if _, err := UnmarshalBackupReport(`{"version":2}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+}
+
+// staticTokenCredential hands out a fixed bearer token.
+type staticTokenCredential string
+
+func (token staticTokenCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: string(token), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeBlobContainer is a minimal stand-in for the Blob Storage REST API.
+type fakeBlobContainer struct {
+	mu     sync.Mutex
+	blocks map[string][]byte
+	blobs  map[string][]byte
+}
+
+func (fake *fakeBlobContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("x-ms-version") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/backups/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		fake.blocks[name+"/"+query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var content []byte
+		for _, part := range strings.Split(string(body), "<Latest>")[1:] {
+			blockID := strings.SplitN(part, "</Latest>", 2)[0]
+			content = append(content, fake.blocks[name+"/"+blockID]...)
+		}
+		fake.blobs[name] = content
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "":
+		fake.blobs[name] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && query.Get("comp") == "list":
+		// One blob per page, to exercise the marker.
+		var names []string
+		for blob := range fake.blobs {
+			if strings.HasPrefix(blob, query.Get("prefix")) && blob > query.Get("marker") {
+				names = append(names, blob)
+			}
+		}
+		if len(names) == 0 {
+			_, _ = fmt.Fprint(w, `<EnumerationResults><Blobs/><NextMarker/></EnumerationResults>`)
+			return
+		}
+		first := names[0]
+		for _, blob := range names {
+			first = min(first, blob)
+		}
+		_, _ = fmt.Fprintf(w, `<EnumerationResults><Blobs><Blob><Name>%s</Name></Blob></Blobs><NextMarker>%s</NextMarker></EnumerationResults>`, first, first)
+	case r.Method == http.MethodDelete:
+		if _, ok := fake.blobs[name]; !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(fake.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestNewBackupStoreRejectsOtherHosts(t *testing.T) {
+	for _, containerURL := range []string{
+		"https://backups.example.com/backups",
+		"https://acct.blob.core.windows.net.example.com/backups",
+		"http://azurite:10000/devstoreaccount1/backups",
+		"file:///backups",
+	} {
+		if _, err := newBackupStore(containerURL, false); err == nil {
+			t.Fatalf("expected %s to be rejected", containerURL)
+		}
+	}
+	if _, err := newBackupStore("file:///backups", true); err != nil {
+		t.Fatalf("expected a directory to be accepted in a local cluster: %v", err)
+	}
+}
+
+func TestBlobBackupStore(t *testing.T) {
+	fake := &fakeBlobContainer{blocks: map[string][]byte{}, blobs: map[string][]byte{}}
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+
+	store, err := newBlobBackupStore(server.URL+"/backups", staticTokenCredential("token"), azcore.ClientOptions{
+		Transport: server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("newBlobBackupStore: %v", err)
+	}
+	ctx := context.Background()
+	content := strings.Repeat("x", backupBlockSize+10)
+	if err := store.Upload(ctx, "ns/db/20261018T020000Z.dump", strings.NewReader(content)); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if got := string(fake.blobs["ns/db/20261018T020000Z.dump"]); got != content {
+		t.Fatalf("expected the blob to hold the content in order, got %d bytes", len(got))
+	}
+	if err := store.Upload(ctx, "ns/db/20261001T020000Z.dump", strings.NewReader("old")); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	names, err := store.List(ctx, "ns/db/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if strings.Join(names, ",") != "ns/db/20261001T020000Z.dump,ns/db/20261018T020000Z.dump" {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	if err := store.Delete(ctx, "ns/db/20261001T020000Z.dump"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "ns/db/20261001T020000Z.dump"); err != nil {
+		t.Fatalf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
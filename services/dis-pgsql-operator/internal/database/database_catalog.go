@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const (
+	// DatabaseCatalogPayloadVersion versions the catalog the Job reports.
+	DatabaseCatalogPayloadVersion = 1
+
+	// DatabaseCatalogOutputPath is where the catalog Job writes its report. It
+	// is the container's termination message, which the operator reads from
+	// the Job's Pod status, so no extra RBAC is needed for the Job identity.
+	DatabaseCatalogOutputPath = "/dev/termination-log"
+
+	// DatabaseCatalogMaxBytes is the Kubernetes limit for a termination
+	// message. Reports that do not fit are truncated, keeping the largest
+	// databases.
+	DatabaseCatalogMaxBytes = 4096
+)
+
+// CatalogDatabase describes one database found on the server.
+type CatalogDatabase struct {
+	Name              string `json:"name"`
+	SizeBytes         int64  `json:"sizeBytes"`
+	ActiveConnections int64  `json:"activeConnections,omitempty"`
+	Sessions          int64  `json:"sessions,omitempty"`
+}
+
+// DatabaseCatalogPayload is the report written by the catalog Job.
+type DatabaseCatalogPayload struct {
+	Version   int               `json:"version"`
+	Databases []CatalogDatabase `json:"databases"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// MarshalDatabaseCatalog serializes the catalog largest database first and
+// drops the smallest databases until the report fits DatabaseCatalogMaxBytes.
+func MarshalDatabaseCatalog(databases []CatalogDatabase) (string, error) {
+	sorted := append([]CatalogDatabase(nil), databases...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SizeBytes != sorted[j].SizeBytes {
+			return sorted[i].SizeBytes > sorted[j].SizeBytes
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	payload := DatabaseCatalogPayload{
+		Version:   DatabaseCatalogPayloadVersion,
+		Databases: sorted,
+	}
+	for {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return "", fmt.Errorf("marshal database catalog payload: %w", err)
+		}
+		if len(content) <= DatabaseCatalogMaxBytes || len(payload.Databases) == 0 {
+			return string(content), nil
+		}
+		payload.Databases = payload.Databases[:len(payload.Databases)-1]
+		payload.Truncated = true
+	}
+}
+
+// UnmarshalDatabaseCatalog parses a report written by the catalog Job.
+func UnmarshalDatabaseCatalog(value string) (DatabaseCatalogPayload, error) {
+	var payload DatabaseCatalogPayload
+	if err := json.Unmarshal([]byte(value), &payload); err != nil {
+		return DatabaseCatalogPayload{}, fmt.Errorf("parse database catalog payload: %w", err)
+	}
+	if payload.Version != DatabaseCatalogPayloadVersion {
+		return DatabaseCatalogPayload{}, fmt.Errorf("unsupported database catalog payload version %d", payload.Version)
+	}
+	return payload, nil
+}
+
+// collectDatabaseCatalog lists the user databases on the server with their
+// size and connection statistics. Template databases, the maintenance
+// database and the Azure-internal databases are skipped.
+func collectDatabaseCatalog(ctx context.Context, conn pgxConn) ([]CatalogDatabase, error) {
+	rows, err := conn.Query(ctx, listDatabaseCatalogSQL())
+	if err != nil {
+		return nil, fmt.Errorf("list database catalog: %w", err)
+	}
+	defer rows.Close()
+
+	var databases []CatalogDatabase
+	for rows.Next() {
+		var database CatalogDatabase
+		if err := rows.Scan(&database.Name, &database.SizeBytes, &database.ActiveConnections, &database.Sessions); err != nil {
+			return nil, fmt.Errorf("scan database catalog row: %w", err)
+		}
+		databases = append(databases, database)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate database catalog: %w", err)
+	}
+	return databases, nil
+}
+
+// writeDatabaseCatalog collects the catalog and writes it to path.
+func writeDatabaseCatalog(ctx context.Context, conn pgxConn, path string) error {
+	databases, err := collectDatabaseCatalog(ctx, conn)
+	if err != nil {
+		return err
+	}
+	content, err := MarshalDatabaseCatalog(databases)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write database catalog to %s: %w", path, err)
+	}
+	return nil
+}
+
+// listDatabaseCatalogSQL reads pg_stat_database.sessions through to_jsonb so
+// the query also runs on PostgreSQL versions before 14, where the column does
+// not exist and the value falls back to 0.
+func listDatabaseCatalogSQL() string {
+	return `SELECT d.datname,
+  pg_database_size(d.oid),
+  COALESCE(s.numbackends, 0)::bigint,
+  COALESCE((to_jsonb(s) ->> 'sessions')::bigint, 0)
+FROM pg_database d
+LEFT JOIN pg_stat_database s ON s.datid = d.oid
+WHERE NOT d.datistemplate
+  AND d.datname NOT IN ('postgres', 'azure_maintenance', 'azure_sys')
+ORDER BY d.datname`
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func TestMarshalDatabaseCatalogRoundTrip(t *testing.T) {
+	content, err := MarshalDatabaseCatalog([]CatalogDatabase{
+		{Name: "small", SizeBytes: 10},
+		{Name: "large", SizeBytes: 1000, ActiveConnections: 2, Sessions: 42},
+	})
+	if err != nil {
+		t.Fatalf("MarshalDatabaseCatalog: %v", err)
+	}
+
+	payload, err := UnmarshalDatabaseCatalog(content)
+	if err != nil {
+		t.Fatalf("UnmarshalDatabaseCatalog: %v", err)
+	}
+	if payload.Truncated {
+		t.Fatalf("did not expect truncation")
+	}
+	if len(payload.Databases) != 2 || payload.Databases[0].Name != "large" || payload.Databases[1].Name != "small" {
+		t.Fatalf("expected databases ordered largest first, got %#v", payload.Databases)
+	}
+	if payload.Databases[0].Sessions != 42 || payload.Databases[0].ActiveConnections != 2 {
+		t.Fatalf("expected connection statistics to round-trip, got %#v", payload.Databases[0])
+	}
+}
+
+func TestMarshalDatabaseCatalogTruncatesToLimit(t *testing.T) {
+	databases := make([]CatalogDatabase, 0, 200)
+	for i := range 200 {
+		databases = append(databases, CatalogDatabase{
+			Name:      strings.Repeat("d", 40) + string(rune('a'+i%26)) + strings.Repeat("x", i%7),
+			SizeBytes: int64(i),
+		})
+	}
+
+	content, err := MarshalDatabaseCatalog(databases)
+	if err != nil {
+		t.Fatalf("MarshalDatabaseCatalog: %v", err)
+	}
+	if len(content) > DatabaseCatalogMaxBytes {
+		t.Fatalf("expected report within %d bytes, got %d", DatabaseCatalogMaxBytes, len(content))
+	}
+
+	payload, err := UnmarshalDatabaseCatalog(content)
+	if err != nil {
+		t.Fatalf("UnmarshalDatabaseCatalog: %v", err)
+	}
+	if !payload.Truncated {
+		t.Fatalf("expected truncated report")
+	}
+	if payload.Databases[0].SizeBytes != 199 {
+		t.Fatalf("expected the largest database to be kept, got %#v", payload.Databases[0])
+	}
+}
+
+func TestUnmarshalDatabaseCatalogRejectsUnknownVersion(t *testing.T) {
+	if _, err := UnmarshalDatabaseCatalog(`{"version":2,"databases":[]}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+	if _, err := UnmarshalDatabaseCatalog("not json"); err == nil {
+		t.Fatalf("expected error for invalid payload")
+	}
+}
+
+func TestWriteDatabaseCatalog(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listDatabaseCatalogSQL())).
+		WillReturnRows(pgxmock.NewRows([]string{"datname", "size", "numbackends", "sessions"}).
+			AddRow("app-db", int64(8192), int64(1), int64(7)).
+			AddRow("old-db", int64(4096), int64(0), int64(0)))
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	if err := writeDatabaseCatalog(context.Background(), mock, path); err != nil {
+		t.Fatalf("writeDatabaseCatalog: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	payload, err := UnmarshalDatabaseCatalog(string(content))
+	if err != nil {
+		t.Fatalf("UnmarshalDatabaseCatalog: %v", err)
+	}
+	if len(payload.Databases) != 2 || payload.Databases[0].Name != "app-db" || payload.Databases[0].Sessions != 7 {
+		t.Fatalf("unexpected catalog %#v", payload.Databases)
+	}
+}
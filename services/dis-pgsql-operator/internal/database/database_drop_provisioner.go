@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// dropDatabaseOptions configures the approved-deletion run for one database:
+// the managed access roles derived from DatabaseName/SchemaName lose their
+// members and are dropped together with the database itself.
+type dropDatabaseOptions struct {
+	DatabaseName string
+	SchemaName   string
+}
+
+// dropDatabase removes everything ensureAccess created for a database. It must
+// run on a connection to the maintenance database, since PostgreSQL cannot
+// drop the database a session is connected to. It is idempotent: memberships
+// are revoked first so principals cannot reconnect, remaining sessions are
+// terminated, the database is dropped (which also removes its schema, the
+// database-level grants and per-database role settings), and finally the
+// now-unreferenced managed roles, including the scoped ones, are dropped.
+func dropDatabase(ctx context.Context, conn pgxConn, opts dropDatabaseOptions) error {
+	if opts.DatabaseName == "" {
+		return fmt.Errorf("database name must be set to drop a database")
+	}
+	if strings.EqualFold(opts.DatabaseName, maintenanceDatabase) {
+		return fmt.Errorf("refusing to drop the %q maintenance database", maintenanceDatabase)
+	}
+	schemaName := opts.SchemaName
+	if schemaName == "" {
+		schemaName = opts.DatabaseName
+	}
+
+	accessRoles := managedAccessRolesFor(opts.DatabaseName, schemaName)
+	scoped, err := scopedRoles(ctx, conn, opts.DatabaseName)
+	if err != nil {
+		return err
+	}
+	revokeAll := &managedRoleMemberships{
+		roles:   append(accessRoles.all(), scoped...),
+		members: map[string]map[string]struct{}{},
+	}
+	if err := reconcileManagedRoleMemberships(ctx, conn, revokeAll); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, terminateDatabaseSessionsSQL(), opts.DatabaseName); err != nil {
+		return fmt.Errorf("terminate sessions on database %s: %w", opts.DatabaseName, err)
+	}
+	if _, err := conn.Exec(ctx, dropDatabaseSQL(opts.DatabaseName)); err != nil {
+		return fmt.Errorf("drop database %s: %w", opts.DatabaseName, err)
+	}
+
+	// Owner is a member of Writer and Writer of Reader; dropping from the top of
+	// the hierarchy down keeps each DROP ROLE free of remaining dependents.
+	roles := accessRoles.all()
+	slices.Reverse(roles)
+	for _, roleName := range roles {
+		if _, err := conn.Exec(ctx, dropRoleSQL(roleName)); err != nil {
+			return fmt.Errorf("drop managed role %s: %w", roleName, err)
+		}
+	}
+
+	// Scoped roles only held privileges in the dropped database.
+	return dropScopedRoles(ctx, conn, scoped, false)
+}
+
+// terminateDatabaseSessionsSQL ends every other session on the database so the
+// DROP DATABASE that follows is not blocked. DROP DATABASE ... WITH (FORCE)
+// would do the same but needs PostgreSQL 13.
+func terminateDatabaseSessionsSQL() string {
+	return "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()"
+}
+
+func dropDatabaseSQL(dbName string) string {
+	return fmt.Sprintf("DROP DATABASE IF EXISTS %s;", pgx.Identifier{dbName}.Sanitize())
+}
+
+func dropRoleSQL(role string) string {
+	return fmt.Sprintf("DROP ROLE IF EXISTS %s;", pgx.Identifier{role}.Sanitize())
+}
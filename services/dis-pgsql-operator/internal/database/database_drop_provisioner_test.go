@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDropDatabaseRevokesDropsDatabaseThenRoles(t *testing.T) {
+	roles := managedAccessRolesFor(appDBName, appDBName)
+	conn := &recordingConn{
+		members: map[string][]string{
+			roles.Reader: {"app-reader", roles.Writer},
+			roles.Writer: {roles.Owner},
+			roles.Owner:  {"app-owner"},
+		},
+	}
+
+	if err := dropDatabase(context.Background(), conn, dropDatabaseOptions{
+		DatabaseName: appDBName,
+		SchemaName:   appDBName,
+	}); err != nil {
+		t.Fatalf("dropDatabase: %v", err)
+	}
+
+	requireExec(t, conn, revokeRoleSQL(roles.Reader, "app-reader"))
+	requireExec(t, conn, revokeRoleSQL(roles.Reader, roles.Writer))
+	requireExec(t, conn, revokeRoleSQL(roles.Writer, roles.Owner))
+	requireExec(t, conn, revokeRoleSQL(roles.Owner, "app-owner"))
+	requireExec(t, conn, terminateDatabaseSessionsSQL(), appDBName)
+	requireExec(t, conn, `DROP DATABASE IF EXISTS "`+appDBName+`";`)
+	for _, role := range roles.all() {
+		requireExec(t, conn, dropRoleSQL(role))
+	}
+	requireNoExec(t, conn, grantRoleSQL(roles.Reader, roles.Writer))
+
+	dropDatabaseIndex := execIndex(t, conn, dropDatabaseSQL(appDBName))
+	for _, role := range roles.all() {
+		if execIndex(t, conn, dropRoleSQL(role)) < dropDatabaseIndex {
+			t.Fatalf("expected managed role %s to be dropped after the database", role)
+		}
+	}
+}
+
+func TestDropDatabaseDropsScopedRoles(t *testing.T) {
+	scoped := managedRoleName(appDBName, "audit", AccessRoleReader)
+	conn := &recordingConn{
+		members: map[string][]string{
+			scopedRoleComment(appDBName): {scoped},
+			scoped:                       {"app-reader"},
+		},
+	}
+
+	if err := dropDatabase(context.Background(), conn, dropDatabaseOptions{DatabaseName: appDBName}); err != nil {
+		t.Fatalf("dropDatabase: %v", err)
+	}
+
+	requireExec(t, conn, revokeRoleSQL(scoped, "app-reader"))
+	requireNoExec(t, conn, dropOwnedBySQL(scoped))
+	if execIndex(t, conn, dropRoleSQL(scoped)) < execIndex(t, conn, dropDatabaseSQL(appDBName)) {
+		t.Fatalf("expected scoped role %s to be dropped after the database", scoped)
+	}
+}
+
+func TestDropDatabaseDefaultsSchemaToDatabaseName(t *testing.T) {
+	conn := &recordingConn{}
+	if err := dropDatabase(context.Background(), conn, dropDatabaseOptions{DatabaseName: appDBName}); err != nil {
+		t.Fatalf("dropDatabase: %v", err)
+	}
+
+	requireExec(t, conn, dropRoleSQL(managedAccessRolesFor(appDBName, appDBName).Owner))
+}
+
+func TestDropDatabaseRejectsMaintenanceDatabase(t *testing.T) {
+	for _, name := range []string{"", maintenanceDatabase, "Postgres"} {
+		conn := &recordingConn{}
+		if err := dropDatabase(context.Background(), conn, dropDatabaseOptions{DatabaseName: name}); err == nil {
+			t.Fatalf("expected error for database name %q", name)
+		}
+		if len(conn.execs) != 0 {
+			t.Fatalf("expected no statements for database name %q, got %#v", name, conn.execs)
+		}
+	}
+}
+
+func execIndex(t *testing.T, conn *recordingConn, sql string) int {
+	t.Helper()
+
+	for i, exec := range conn.execs {
+		if exec.sql == sql {
+			return i
+		}
+	}
+	t.Fatalf("missing exec %q; got %#v", sql, conn.execs)
+	return -1
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DatabaseExtensionsPayloadVersion versions both the extension list the
+	// operator passes in DatabaseExtensionsEnv and the report the Job writes.
+	DatabaseExtensionsPayloadVersion = 1
+
+	// DatabaseExtensionsOutputPath is where the access Job writes its
+	// extension report, read by the operator from the Pod termination message.
+	DatabaseExtensionsOutputPath = DatabaseCatalogOutputPath
+
+	// databaseExtensionErrorMaxLength bounds each reported error so the report
+	// for every supported extension fits the termination message.
+	databaseExtensionErrorMaxLength = 512
+)
+
+// extensionFixedSchemas lists extensions whose control file pins the schema
+// they install into; CREATE EXTENSION ... WITH SCHEMA fails for any other.
+var extensionFixedSchemas = map[string]string{
+	string(storagev1alpha1.DatabaseServerExtensionPgCron): "cron",
+}
+
+var extensionVersionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// DatabaseExtensionState is whether an extension should be installed in, or
+// dropped from, the database.
+type DatabaseExtensionState string
+
+const (
+	DatabaseExtensionPresent DatabaseExtensionState = "present"
+	DatabaseExtensionAbsent  DatabaseExtensionState = "absent"
+)
+
+// DatabaseExtension is one entry of the extension payload. An empty Version
+// keeps a present extension at the default version the server ships.
+type DatabaseExtension struct {
+	Name    string                 `json:"name"`
+	Version string                 `json:"version,omitempty"`
+	State   DatabaseExtensionState `json:"state"`
+}
+
+// DatabaseExtensionsPayload is the serialized form of DatabaseExtensionsEnv.
+type DatabaseExtensionsPayload struct {
+	Version    int                 `json:"version"`
+	Extensions []DatabaseExtension `json:"extensions"`
+}
+
+// DatabaseExtensionResult is the outcome for one extension. Version and
+// Schema describe what is installed after the run; both are empty when the
+// extension is not installed. Error is set when the change failed.
+type DatabaseExtensionResult struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Schema  string `json:"schema,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DatabaseExtensionsReport is the report written by the access Job.
+type DatabaseExtensionsReport struct {
+	Version    int                       `json:"version"`
+	Extensions []DatabaseExtensionResult `json:"extensions"`
+}
+
+// MarshalDatabaseExtensions validates and serializes the extension payload,
+// sorted by name so equal inputs produce the same Job.
+func MarshalDatabaseExtensions(extensions []DatabaseExtension) (string, error) {
+	normalized, err := normalizedDatabaseExtensions(extensions)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(DatabaseExtensionsPayload{
+		Version:    DatabaseExtensionsPayloadVersion,
+		Extensions: normalized,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal database extensions payload: %w", err)
+	}
+	return string(content), nil
+}
+
+// parseDatabaseExtensionsPayload parses DatabaseExtensionsEnv. An empty value
+// means the Job manages no extensions.
+func parseDatabaseExtensionsPayload(raw string) ([]DatabaseExtension, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var payload DatabaseExtensionsPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", DatabaseExtensionsEnv, err)
+	}
+	if payload.Version != DatabaseExtensionsPayloadVersion {
+		return nil, fmt.Errorf("%s version %d is not supported", DatabaseExtensionsEnv, payload.Version)
+	}
+	return normalizedDatabaseExtensions(payload.Extensions)
+}
+
+func normalizedDatabaseExtensions(extensions []DatabaseExtension) ([]DatabaseExtension, error) {
+	normalized := make([]DatabaseExtension, 0, len(extensions))
+	seen := make(map[string]struct{}, len(extensions))
+	for i, extension := range extensions {
+		extension.Name = strings.TrimSpace(extension.Name)
+		extension.Version = strings.TrimSpace(extension.Version)
+		if _, ok := allowedDatabaseServerExtensions[storagev1alpha1.DatabaseServerExtension(extension.Name)]; !ok {
+			return nil, fmt.Errorf("database extension %d: unsupported extension %q", i, extension.Name)
+		}
+		if _, ok := seen[extension.Name]; ok {
+			return nil, fmt.Errorf("database extension %d: duplicate extension %q", i, extension.Name)
+		}
+		seen[extension.Name] = struct{}{}
+		switch extension.State {
+		case DatabaseExtensionPresent:
+		case DatabaseExtensionAbsent:
+			extension.Version = ""
+		default:
+			return nil, fmt.Errorf("database extension %d: state must be present or absent", i)
+		}
+		if extension.Version != "" && !extensionVersionPattern.MatchString(extension.Version) {
+			return nil, fmt.Errorf("database extension %d: invalid version %q", i, extension.Version)
+		}
+		normalized = append(normalized, extension)
+	}
+	slices.SortFunc(normalized, func(a, b DatabaseExtension) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return normalized, nil
+}
+
+// MarshalDatabaseExtensionsReport serializes the extension results.
+func MarshalDatabaseExtensionsReport(results []DatabaseExtensionResult) (string, error) {
+	content, err := json.Marshal(DatabaseExtensionsReport{
+		Version:    DatabaseExtensionsPayloadVersion,
+		Extensions: results,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal database extensions report: %w", err)
+	}
+	return string(content), nil
+}
+
+// UnmarshalDatabaseExtensionsReport parses a report written by the access Job.
+func UnmarshalDatabaseExtensionsReport(value string) (DatabaseExtensionsReport, error) {
+	var report DatabaseExtensionsReport
+	if err := json.Unmarshal([]byte(value), &report); err != nil {
+		return DatabaseExtensionsReport{}, fmt.Errorf("parse database extensions report: %w", err)
+	}
+	if report.Version != DatabaseExtensionsPayloadVersion {
+		return DatabaseExtensionsReport{}, fmt.Errorf("unsupported database extensions report version %d", report.Version)
+	}
+	return report, nil
+}
+
+// ensureDatabaseExtensions installs, updates or drops each extension in the
+// connected database. Each extension is handled on its own: a failure is
+// recorded in its result and the others still run, so one broken extension
+// does not hold back access provisioning. Only errors reading the catalog
+// abort the run.
+//
+// Present extensions are created in schemaName unless their control file
+// pins a schema. An extension that already exists is updated in place, to
+// Version or else to the default version, and left in whatever schema it was
+// installed into. Absent extensions are dropped without CASCADE, so objects
+// that depend on them make the drop fail instead of being removed.
+func ensureDatabaseExtensions(
+	ctx context.Context,
+	conn pgxConn,
+	schemaName string,
+	extensions []DatabaseExtension,
+) ([]DatabaseExtensionResult, error) {
+	results := make([]DatabaseExtensionResult, 0, len(extensions))
+	for _, extension := range extensions {
+		_, _, installed, err := installedExtension(ctx, conn, extension.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var changeErr error
+		switch {
+		case extension.State == DatabaseExtensionAbsent:
+			if installed {
+				_, changeErr = conn.Exec(ctx, dropExtensionSQL(extension.Name))
+			}
+		case installed:
+			_, changeErr = conn.Exec(ctx, updateExtensionSQL(extension.Name, extension.Version))
+		default:
+			_, changeErr = conn.Exec(ctx, createExtensionSQL(extension.Name, extensionSchema(extension.Name, schemaName), extension.Version))
+		}
+
+		version, schema, _, err := installedExtension(ctx, conn, extension.Name)
+		if err != nil {
+			return nil, err
+		}
+		result := DatabaseExtensionResult{
+			Name:    extension.Name,
+			Version: version,
+			Schema:  schema,
+		}
+		if changeErr != nil {
+			result.Error = truncateExtensionError(changeErr)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// installedExtension returns the version and schema of an installed
+// extension.
+func installedExtension(ctx context.Context, conn pgxConn, name string) (string, string, bool, error) {
+	var version, schema string
+	err := conn.QueryRow(ctx, installedExtensionSQL(), name).Scan(&version, &schema)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("read extension %q: %w", name, err)
+	}
+	return version, schema, true, nil
+}
+
+// writeDatabaseExtensions applies the extensions and writes the report to
+// path.
+func writeDatabaseExtensions(
+	ctx context.Context,
+	conn pgxConn,
+	schemaName string,
+	extensions []DatabaseExtension,
+	path string,
+) error {
+	results, err := ensureDatabaseExtensions(ctx, conn, schemaName, extensions)
+	if err != nil {
+		return err
+	}
+	content, err := MarshalDatabaseExtensionsReport(results)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write database extensions report to %s: %w", path, err)
+	}
+	return nil
+}
+
+func extensionSchema(name, schemaName string) string {
+	if schema, ok := extensionFixedSchemas[name]; ok {
+		return schema
+	}
+	return schemaName
+}
+
+func truncateExtensionError(err error) string {
+	message := err.Error()
+	if len(message) <= databaseExtensionErrorMaxLength {
+		return message
+	}
+	return message[:databaseExtensionErrorMaxLength]
+}
+
+func installedExtensionSQL() string {
+	return `SELECT e.extversion, n.nspname
+FROM pg_catalog.pg_extension e
+JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+WHERE e.extname = $1`
+}
+
+// createExtensionSQL quotes the version as an identifier, which CREATE
+// EXTENSION accepts in place of a string literal.
+func createExtensionSQL(name, schema, version string) string {
+	sql := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s",
+		pgx.Identifier{name}.Sanitize(),
+		pgx.Identifier{schema}.Sanitize(),
+	)
+	if version != "" {
+		sql += " VERSION " + pgx.Identifier{version}.Sanitize()
+	}
+	return sql + ";"
+}
+
+func updateExtensionSQL(name, version string) string {
+	sql := fmt.Sprintf("ALTER EXTENSION %s UPDATE", pgx.Identifier{name}.Sanitize())
+	if version != "" {
+		sql += " TO " + pgx.Identifier{version}.Sanitize()
+	}
+	return sql + ";"
+}
+
+func dropExtensionSQL(name string) string {
+	return fmt.Sprintf("DROP EXTENSION IF EXISTS %s;", pgx.Identifier{name}.Sanitize())
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func TestMarshalDatabaseExtensionsRoundTrip(t *testing.T) {
+	content, err := MarshalDatabaseExtensions([]DatabaseExtension{
+		{Name: "uuid-ossp", State: DatabaseExtensionPresent},
+		{Name: " hstore ", Version: "1.8", State: DatabaseExtensionPresent},
+		{Name: "pgaudit", Version: "1.7", State: DatabaseExtensionAbsent},
+	})
+	if err != nil {
+		t.Fatalf("MarshalDatabaseExtensions: %v", err)
+	}
+
+	extensions, err := parseDatabaseExtensionsPayload(content)
+	if err != nil {
+		t.Fatalf("parseDatabaseExtensionsPayload: %v", err)
+	}
+	want := []DatabaseExtension{
+		{Name: "hstore", Version: "1.8", State: DatabaseExtensionPresent},
+		{Name: "pgaudit", State: DatabaseExtensionAbsent},
+		{Name: "uuid-ossp", State: DatabaseExtensionPresent},
+	}
+	if len(extensions) != len(want) {
+		t.Fatalf("expected %d extensions, got %#v", len(want), extensions)
+	}
+	for i := range want {
+		if extensions[i] != want[i] {
+			t.Fatalf("extension %d: expected %#v, got %#v", i, want[i], extensions[i])
+		}
+	}
+}
+
+func TestMarshalDatabaseExtensionsRejectsInvalidEntries(t *testing.T) {
+	tests := map[string]DatabaseExtension{
+		"unsupported extension": {Name: "postgis", State: DatabaseExtensionPresent},
+		"unknown state":         {Name: "hstore", State: "installed"},
+		"invalid version":       {Name: "hstore", Version: "1.8; DROP", State: DatabaseExtensionPresent},
+	}
+	for name, extension := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := MarshalDatabaseExtensions([]DatabaseExtension{extension}); err == nil {
+				t.Fatalf("expected error for %#v", extension)
+			}
+		})
+	}
+
+	t.Run("duplicate extension", func(t *testing.T) {
+		_, err := MarshalDatabaseExtensions([]DatabaseExtension{
+			{Name: "hstore", State: DatabaseExtensionPresent},
+			{Name: "hstore", State: DatabaseExtensionAbsent},
+		})
+		if err == nil || !strings.Contains(err.Error(), "duplicate") {
+			t.Fatalf("expected duplicate error, got %v", err)
+		}
+	})
+}
+
+func TestParseDatabaseExtensionsPayloadEmpty(t *testing.T) {
+	extensions, err := parseDatabaseExtensionsPayload("  ")
+	if err != nil || extensions != nil {
+		t.Fatalf("expected no extensions, got %#v, %v", extensions, err)
+	}
+}
+
+func TestWriteDatabaseExtensions(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	columns := []string{"extversion", "nspname"}
+	installed := regexp.QuoteMeta(installedExtensionSQL())
+
+	// hstore is installed and updated to the pinned version.
+	mock.ExpectQuery(installed).WithArgs("hstore").WillReturnRows(pgxmock.NewRows(columns).AddRow("1.7", "public"))
+	mock.ExpectExec(regexp.QuoteMeta(updateExtensionSQL("hstore", "1.8"))).WillReturnResult(pgxmock.NewResult("ALTER EXTENSION", 0))
+	mock.ExpectQuery(installed).WithArgs("hstore").WillReturnRows(pgxmock.NewRows(columns).AddRow("1.8", "public"))
+
+	// pg_cron is created in its own schema.
+	mock.ExpectQuery(installed).WithArgs("pg_cron").WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE EXTENSION IF NOT EXISTS "pg_cron" WITH SCHEMA "cron";`)).
+		WillReturnError(errors.New("can only create extension in database postgres"))
+	mock.ExpectQuery(installed).WithArgs("pg_cron").WillReturnRows(pgxmock.NewRows(columns))
+
+	// pgaudit is dropped.
+	mock.ExpectQuery(installed).WithArgs("pgaudit").WillReturnRows(pgxmock.NewRows(columns).AddRow("1.7", "appdb"))
+	mock.ExpectExec(regexp.QuoteMeta(`DROP EXTENSION IF EXISTS "pgaudit";`)).WillReturnResult(pgxmock.NewResult("DROP EXTENSION", 0))
+	mock.ExpectQuery(installed).WithArgs("pgaudit").WillReturnRows(pgxmock.NewRows(columns))
+
+	// uuid-ossp is created in the database schema.
+	mock.ExpectQuery(installed).WithArgs("uuid-ossp").WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA "appdb";`)).
+		WillReturnResult(pgxmock.NewResult("CREATE EXTENSION", 0))
+	mock.ExpectQuery(installed).WithArgs("uuid-ossp").WillReturnRows(pgxmock.NewRows(columns).AddRow("1.1", "appdb"))
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	err = writeDatabaseExtensions(context.Background(), mock, "appdb", []DatabaseExtension{
+		{Name: "hstore", Version: "1.8", State: DatabaseExtensionPresent},
+		{Name: "pg_cron", State: DatabaseExtensionPresent},
+		{Name: "pgaudit", State: DatabaseExtensionAbsent},
+		{Name: "uuid-ossp", State: DatabaseExtensionPresent},
+	}, path)
+	if err != nil {
+		t.Fatalf("writeDatabaseExtensions: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	report, err := UnmarshalDatabaseExtensionsReport(string(content))
+	if err != nil {
+		t.Fatalf("UnmarshalDatabaseExtensionsReport: %v", err)
+	}
+	want := []DatabaseExtensionResult{
+		{Name: "hstore", Version: "1.8", Schema: "public"},
+		{Name: "pg_cron", Error: "can only create extension in database postgres"},
+		{Name: "pgaudit"},
+		{Name: "uuid-ossp", Version: "1.1", Schema: "appdb"},
+	}
+	if len(report.Extensions) != len(want) {
+		t.Fatalf("expected %d results, got %#v", len(want), report.Extensions)
+	}
+	for i := range want {
+		if report.Extensions[i] != want[i] {
+			t.Fatalf("result %d: expected %#v, got %#v", i, want[i], report.Extensions[i])
+		}
+	}
+}
+
+func TestExtensionSQL(t *testing.T) {
+	if got := createExtensionSQL("hstore", "app", "1.8"); got != `CREATE EXTENSION IF NOT EXISTS "hstore" WITH SCHEMA "app" VERSION "1.8";` {
+		t.Fatalf("unexpected create SQL %q", got)
+	}
+	if got := updateExtensionSQL("hstore", ""); got != `ALTER EXTENSION "hstore" UPDATE;` {
+		t.Fatalf("unexpected update SQL %q", got)
+	}
+}
@@ -0,0 +1,278 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// MigrationPayloadVersion versions the verification report of the
+	// migration Job.
+	MigrationPayloadVersion = 1
+
+	// MigrationOutputPath is where the verify phase writes its report, read by
+	// the operator from the Pod termination message.
+	MigrationOutputPath = DatabaseCatalogOutputPath
+
+	// MigrationMaxBytes is the Kubernetes limit for a termination message.
+	// Reports that do not fit drop their last mismatches.
+	MigrationMaxBytes = DatabaseCatalogMaxBytes
+
+	// MigrationPhasePrepare provisions the database's managed roles, schema and
+	// extensions on the target server and writes the credentials the dump and
+	// restore use to the work directory.
+	MigrationPhasePrepare = "prepare"
+
+	// MigrationPhaseVerify provisions access on the target again, since the
+	// restore recreates the schema, and compares the tables and their row
+	// counts with the source.
+	MigrationPhaseVerify = "verify"
+
+	// MigrationUserFile, MigrationPasswordFile and MigrationOwnerRoleFile are
+	// written to the work directory by the prepare phase: the admin identity,
+	// its token and the database's Owner managed role, which owns the
+	// restored objects.
+	MigrationUserFile      = "user"
+	MigrationPasswordFile  = "password"
+	MigrationOwnerRoleFile = "owner-role"
+
+	// defaultTransactionReadOnlyParam is the setting that fences the source
+	// of a move.
+	defaultTransactionReadOnlyParam = "default_transaction_read_only"
+)
+
+// MigrationTable is a table that differs between the source and the target.
+// A table missing on one side has -1 rows there.
+type MigrationTable struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	SourceRows int64  `json:"sourceRows"`
+	TargetRows int64  `json:"targetRows"`
+}
+
+// MigrationReport is the report written by the verify phase. The copy is
+// verified when every table has the same number of rows on both servers.
+type MigrationReport struct {
+	Version    int              `json:"version"`
+	Verified   bool             `json:"verified"`
+	Tables     int32            `json:"tables"`
+	Rows       int64            `json:"rows"`
+	Mismatches []MigrationTable `json:"mismatches,omitempty"`
+	Truncated  bool             `json:"truncated,omitempty"`
+}
+
+// MarshalMigrationReport serializes the report, dropping the last mismatches
+// until it fits MigrationMaxBytes.
+func MarshalMigrationReport(report MigrationReport) (string, error) {
+	report.Version = MigrationPayloadVersion
+	for {
+		content, err := json.Marshal(report)
+		if err != nil {
+			return "", fmt.Errorf("marshal migration report: %w", err)
+		}
+		if len(content) <= MigrationMaxBytes || len(report.Mismatches) == 0 {
+			return string(content), nil
+		}
+		report.Mismatches = report.Mismatches[:len(report.Mismatches)-1]
+		report.Truncated = true
+	}
+}
+
+// UnmarshalMigrationReport parses a report written by the verify phase.
+func UnmarshalMigrationReport(value string) (MigrationReport, error) {
+	var report MigrationReport
+	if err := json.Unmarshal([]byte(value), &report); err != nil {
+		return MigrationReport{}, fmt.Errorf("parse migration report: %w", err)
+	}
+	if report.Version != MigrationPayloadVersion {
+		return MigrationReport{}, fmt.Errorf("unsupported migration report version %d", report.Version)
+	}
+	return report, nil
+}
+
+// migrationTableKey is a schema-qualified table.
+type migrationTableKey struct {
+	Schema string
+	Name   string
+}
+
+// compareMigrationTables compares the row counts of the tables on the source
+// and the target. Tables and Rows count the source.
+func compareMigrationTables(source, target map[migrationTableKey]int64) MigrationReport {
+	report := MigrationReport{Version: MigrationPayloadVersion}
+	for table, rows := range source {
+		report.Tables++
+		report.Rows += rows
+		targetRows, ok := target[table]
+		if !ok {
+			targetRows = -1
+		}
+		if targetRows != rows {
+			report.Mismatches = append(report.Mismatches, MigrationTable{
+				Schema:     table.Schema,
+				Name:       table.Name,
+				SourceRows: rows,
+				TargetRows: targetRows,
+			})
+		}
+	}
+	for table, rows := range target {
+		if _, ok := source[table]; !ok {
+			report.Mismatches = append(report.Mismatches, MigrationTable{
+				Schema:     table.Schema,
+				Name:       table.Name,
+				SourceRows: -1,
+				TargetRows: rows,
+			})
+		}
+	}
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		if report.Mismatches[i].Schema != report.Mismatches[j].Schema {
+			return report.Mismatches[i].Schema < report.Mismatches[j].Schema
+		}
+		return report.Mismatches[i].Name < report.Mismatches[j].Name
+	})
+	report.Verified = len(report.Mismatches) == 0
+	return report
+}
+
+// migrationTableRowCounts counts the rows of every user table in the
+// database. Tables that belong to an extension are skipped, since the restore
+// does not copy extensions.
+func migrationTableRowCounts(ctx context.Context, conn pgxConn) (map[migrationTableKey]int64, error) {
+	rows, err := conn.Query(ctx, listMigrationTablesSQL())
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	var tables []migrationTableKey
+	for rows.Next() {
+		var table migrationTableKey
+		if err := rows.Scan(&table.Schema, &table.Name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan table row: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tables: %w", err)
+	}
+
+	counts := make(map[migrationTableKey]int64, len(tables))
+	for _, table := range tables {
+		var count int64
+		if err := conn.QueryRow(ctx, countTableRowsSQL(table.Schema, table.Name)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("count rows of %s.%s: %w", table.Schema, table.Name, err)
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// fenceMigrationSource makes the database on the source read-only before it
+// is dumped, so rows written during the move cannot be lost: new sessions
+// default to read-only transactions and the sessions already open are ended.
+// The fence is lifted again when ending the sessions fails.
+func fenceMigrationSource(ctx context.Context, conn pgxConn, dbName string) error {
+	if _, err := conn.Exec(ctx, fenceDatabaseSQL(dbName)); err != nil {
+		return fmt.Errorf("make database %s read-only: %w", dbName, err)
+	}
+	if _, err := conn.Exec(ctx, terminateDatabaseSessionsSQL(), dbName); err != nil {
+		if releaseErr := releaseMigrationFence(ctx, conn, dbName); releaseErr != nil {
+			return fmt.Errorf("terminate sessions on database %s: %w (and %w)", dbName, err, releaseErr)
+		}
+		return fmt.Errorf("terminate sessions on database %s: %w", dbName, err)
+	}
+	return nil
+}
+
+// releaseMigrationFence lifts the read-only fence of fenceMigrationSource. It
+// also clears a fence left on a target by an earlier move away from it. The
+// session must not be read-only itself.
+func releaseMigrationFence(ctx context.Context, conn pgxConn, dbName string) error {
+	if _, err := conn.Exec(ctx, releaseDatabaseFenceSQL(dbName)); err != nil {
+		return fmt.Errorf("lift the read-only fence of database %s: %w", dbName, err)
+	}
+	return nil
+}
+
+// writeMigrationVerification compares the tables on the source and the
+// target and writes the report to path. A mismatch is reported, not returned
+// as an error, so the operator can record what differs; the source is
+// released then, since the move does not go ahead.
+func writeMigrationVerification(ctx context.Context, source, target pgxConn, dbName, path string) error {
+	sourceCounts, err := migrationTableRowCounts(ctx, source)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	targetCounts, err := migrationTableRowCounts(ctx, target)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	report := compareMigrationTables(sourceCounts, targetCounts)
+	if !report.Verified {
+		if err := releaseMigrationFence(ctx, source, dbName); err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
+	}
+	content, err := MarshalMigrationReport(report)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write migration report to %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeMigrationCredentials writes the credentials of the admin identity and
+// the Owner role to workDir for the dump and restore container. The password
+// is the Entra token, which is valid for both servers. The files are only
+// readable by their owner; the Job's Pod runs every container as the
+// operator image's user.
+func writeMigrationCredentials(workDir, user, password, ownerRole string) error {
+	files := map[string]string{
+		MigrationUserFile:      user,
+		MigrationPasswordFile:  password,
+		MigrationOwnerRoleFile: ownerRole,
+	}
+	for name, content := range files {
+		path := filepath.Join(workDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("write migration %s to %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+func listMigrationTablesSQL() string {
+	return `SELECT n.nspname, c.relname
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'r'
+  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+  AND n.nspname NOT LIKE 'pg\_%'
+  AND NOT EXISTS (
+    SELECT 1 FROM pg_depend d
+    WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+  )
+ORDER BY n.nspname, c.relname`
+}
+
+func fenceDatabaseSQL(dbName string) string {
+	return fmt.Sprintf("ALTER DATABASE %s SET default_transaction_read_only = on", pgx.Identifier{dbName}.Sanitize())
+}
+
+func releaseDatabaseFenceSQL(dbName string) string {
+	return fmt.Sprintf("ALTER DATABASE %s RESET default_transaction_read_only", pgx.Identifier{dbName}.Sanitize())
+}
+
+func countTableRowsSQL(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT count(*) FROM %s", pgx.Identifier{schemaName, tableName}.Sanitize())
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func TestCompareMigrationTables(t *testing.T) {
+	source := map[migrationTableKey]int64{
+		{Schema: "app", Name: "orders"}: 10,
+		{Schema: "app", Name: "users"}:  3,
+		{Schema: "app", Name: "audit"}:  0,
+	}
+
+	report := compareMigrationTables(source, map[migrationTableKey]int64{
+		{Schema: "app", Name: "orders"}: 10,
+		{Schema: "app", Name: "users"}:  3,
+		{Schema: "app", Name: "audit"}:  0,
+	})
+	if !report.Verified || report.Tables != 3 || report.Rows != 13 || len(report.Mismatches) != 0 {
+		t.Fatalf("expected a verified copy, got %#v", report)
+	}
+
+	report = compareMigrationTables(source, map[migrationTableKey]int64{
+		{Schema: "app", Name: "orders"}: 9,
+		{Schema: "app", Name: "users"}:  3,
+		{Schema: "app", Name: "extra"}:  1,
+	})
+	if report.Verified {
+		t.Fatalf("expected the copy to not be verified")
+	}
+	want := []MigrationTable{
+		{Schema: "app", Name: "audit", SourceRows: 0, TargetRows: -1},
+		{Schema: "app", Name: "extra", SourceRows: -1, TargetRows: 1},
+		{Schema: "app", Name: "orders", SourceRows: 10, TargetRows: 9},
+	}
+	if fmt.Sprint(report.Mismatches) != fmt.Sprint(want) {
+		t.Fatalf("expected mismatches %v, got %v", want, report.Mismatches)
+	}
+}
+
+func TestMarshalMigrationReportTruncatesToLimit(t *testing.T) {
+	report := MigrationReport{Tables: 200}
+	for i := range 200 {
+		report.Mismatches = append(report.Mismatches, MigrationTable{
+			Schema: "app", Name: fmt.Sprintf("table_with_a_long_name_%03d", i), SourceRows: int64(i), TargetRows: 0,
+		})
+	}
+
+	content, err := MarshalMigrationReport(report)
+	if err != nil {
+		t.Fatalf("MarshalMigrationReport: %v", err)
+	}
+	if len(content) > MigrationMaxBytes {
+		t.Fatalf("expected report within %d bytes, got %d", MigrationMaxBytes, len(content))
+	}
+	parsed, err := UnmarshalMigrationReport(content)
+	if err != nil {
+		t.Fatalf("UnmarshalMigrationReport: %v", err)
+	}
+	if !parsed.Truncated || parsed.Tables != 200 || parsed.Mismatches[0].Name != "table_with_a_long_name_000" {
+		t.Fatalf("expected a truncated report keeping the first mismatches, got %d mismatches", len(parsed.Mismatches))
+	}
+}
+
+func TestUnmarshalMigrationReportRejectsUnknownVersion(t *testing.T) {
+	if _, err := UnmarshalMigrationReport(`{"version":2,"verified":true}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+	if _, err := UnmarshalMigrationReport("not json"); err == nil {
+		t.Fatalf("expected error for invalid payload")
+	}
+}
+
+func TestWriteMigrationVerification(t *testing.T) {
+	expectTables := func(mock pgxmock.PgxConnIface, orders int64) {
+		mock.ExpectQuery(regexp.QuoteMeta(listMigrationTablesSQL())).
+			WillReturnRows(pgxmock.NewRows([]string{"nspname", "relname"}).AddRow("app", "orders"))
+		mock.ExpectQuery(regexp.QuoteMeta(countTableRowsSQL("app", "orders"))).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(orders))
+	}
+
+	source, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	target, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	expectTables(source, 42)
+	expectTables(target, 42)
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	if err := writeMigrationVerification(context.Background(), source, target, "appdb", path); err != nil {
+		t.Fatalf("writeMigrationVerification: %v", err)
+	}
+	for _, mock := range []pgxmock.PgxConnIface{source, target} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("expectations: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	report, err := UnmarshalMigrationReport(string(content))
+	if err != nil {
+		t.Fatalf("UnmarshalMigrationReport: %v", err)
+	}
+	if !report.Verified || report.Tables != 1 || report.Rows != 42 {
+		t.Fatalf("unexpected report %#v", report)
+	}
+
+	// A copy that does not verify releases the source.
+	expectTables(source, 43)
+	expectTables(target, 42)
+	source.ExpectExec(regexp.QuoteMeta(releaseDatabaseFenceSQL("appdb"))).
+		WillReturnResult(pgxmock.NewResult("ALTER", 0))
+	if err := writeMigrationVerification(context.Background(), source, target, "appdb", path); err != nil {
+		t.Fatalf("writeMigrationVerification: %v", err)
+	}
+	for _, mock := range []pgxmock.PgxConnIface{source, target} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("expectations: %v", err)
+		}
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if report, err = UnmarshalMigrationReport(string(content)); err != nil || report.Verified {
+		t.Fatalf("expected an unverified report, got %#v (%v)", report, err)
+	}
+}
+
+func TestFenceMigrationSource(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER DATABASE "appdb" SET default_transaction_read_only = on`)).
+		WillReturnResult(pgxmock.NewResult("ALTER", 0))
+	mock.ExpectExec(regexp.QuoteMeta(terminateDatabaseSessionsSQL())).
+		WithArgs("appdb").
+		WillReturnResult(pgxmock.NewResult("SELECT", 2))
+	if err := fenceMigrationSource(context.Background(), mock, "appdb"); err != nil {
+		t.Fatalf("fenceMigrationSource: %v", err)
+	}
+
+	// The fence is lifted when the sessions cannot be ended.
+	mock.ExpectExec(regexp.QuoteMeta(fenceDatabaseSQL("appdb"))).
+		WillReturnResult(pgxmock.NewResult("ALTER", 0))
+	mock.ExpectExec(regexp.QuoteMeta(terminateDatabaseSessionsSQL())).
+		WithArgs("appdb").
+		WillReturnError(errors.New("permission denied"))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER DATABASE "appdb" RESET default_transaction_read_only`)).
+		WillReturnResult(pgxmock.NewResult("ALTER", 0))
+	if err := fenceMigrationSource(context.Background(), mock, "appdb"); err == nil {
+		t.Fatalf("expected an error when the sessions cannot be ended")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestCountTableRowsSQLQuotesIdentifiers(t *testing.T) {
+	if got := countTableRowsSQL("app", `we"ird`); got != `SELECT count(*) FROM "app"."we""ird"` {
+		t.Fatalf("unexpected SQL %s", got)
+	}
+}
+
+func TestWriteMigrationCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeMigrationCredentials(dir, "pg-admin", "token", "dispg-app-owner"); err != nil {
+		t.Fatalf("writeMigrationCredentials: %v", err)
+	}
+
+	password, err := os.ReadFile(filepath.Join(dir, MigrationPasswordFile))
+	if err != nil || string(password) != "token" {
+		t.Fatalf("unexpected password %q (%v)", password, err)
+	}
+	owner, err := os.ReadFile(filepath.Join(dir, MigrationOwnerRoleFile))
+	if err != nil || string(owner) != "dispg-app-owner" {
+		t.Fatalf("unexpected owner role %q (%v)", owner, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, MigrationPasswordFile))
+	if err != nil {
+		t.Fatalf("stat password: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected the password to be readable by its owner only, got %v", info.Mode().Perm())
+	}
+}
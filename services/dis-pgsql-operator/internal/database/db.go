@@ -3,44 +3,192 @@ package database
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 
 	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+	"sigs.k8s.io/yaml"
 )
 
 const (
-	defaultBackupRetentionDaysNonProd  = 14
-	defaultBackupRetentionDaysProd     = 30
-	defaultHighAvailabilityEnabledProd = true
+	defaultBackupRetentionDaysNonProd       = 14
+	defaultBackupRetentionDaysProd          = 30
+	defaultStorageGB                  int32 = 32
+
+	// DevProfileName and ProdProfileName are the built-in profiles. They can
+	// be overridden by operator configuration like any other profile.
+	DevProfileName  = "dev"
+	ProdProfileName = "prod"
 )
 
+// Profile is a named server size. Profiles are declared in operator
+// configuration and selected per server with spec.profile.
 type Profile struct {
-	SkuName  string
-	SkuTier  dbforpostgresqlv1.SkuTier
-	MemoryGB int
-	// TODO: Storage will come later in the beta version.
+	SkuName  string                    `json:"skuName"`
+	SkuTier  dbforpostgresqlv1.SkuTier `json:"skuTier"`
+	MemoryGB int                       `json:"memoryGB"`
+
+	// StorageGB is the storage size used when spec.storage.sizeGB is unset.
+	StorageGB int32 `json:"storageGB,omitempty"`
+
+	// HighAvailabilityEnabled and BackupRetentionDays are the defaults for
+	// spec.highAvailabilityEnabled and spec.backupRetentionDays.
+	HighAvailabilityEnabled bool `json:"highAvailabilityEnabled,omitempty"`
+	BackupRetentionDays     int  `json:"backupRetentionDays,omitempty"`
+
+	// GeoRedundantBackupAllowed lets servers on the profile set
+	// spec.geoRedundantBackup. Geo-redundant backups double the backup
+	// storage cost, so they are reserved for profiles that need a
+	// cross-region recovery path.
+	GeoRedundantBackupAllowed bool `json:"geoRedundantBackupAllowed,omitempty"`
+
+	// PgBouncerEnabled turns on the built-in PgBouncer. It is rejected on the
+	// Burstable tier.
+	PgBouncerEnabled bool `json:"pgBouncerEnabled,omitempty"`
+
+	// MaxDatabases caps the number of Database resources on one server.
+	// Zero means unlimited.
+	MaxDatabases int `json:"maxDatabases,omitempty"`
 }
 
-// TODO: these profiles need to be defined later
 // SKU memory sizes are from Azure Flexible Server compute options/limits:
 // https://learn.microsoft.com/en-us/azure/postgresql/flexible-server/concepts-compute
 // https://learn.microsoft.com/en-us/azure/postgresql/flexible-server/concepts-limits
 var devProfile = Profile{
-	SkuName:  "Standard_B1ms",
-	SkuTier:  dbforpostgresqlv1.SkuTier_Burstable,
-	MemoryGB: 2,
+	SkuName:             "Standard_B1ms",
+	SkuTier:             dbforpostgresqlv1.SkuTier_Burstable,
+	MemoryGB:            2,
+	StorageGB:           defaultStorageGB,
+	BackupRetentionDays: defaultBackupRetentionDaysNonProd,
 }
 
 var prodProfile = Profile{
-	SkuName:  "Standard_D4s_v3",
-	SkuTier:  dbforpostgresqlv1.SkuTier_GeneralPurpose,
-	MemoryGB: 16,
+	SkuName:                   "Standard_D4s_v3",
+	SkuTier:                   dbforpostgresqlv1.SkuTier_GeneralPurpose,
+	MemoryGB:                  16,
+	StorageGB:                 defaultStorageGB,
+	HighAvailabilityEnabled:   true,
+	BackupRetentionDays:       defaultBackupRetentionDaysProd,
+	GeoRedundantBackupAllowed: true,
+	PgBouncerEnabled:          true,
+}
+
+// Profiles maps profile names to profiles.
+type Profiles map[string]Profile
+
+// DefaultProfiles returns the built-in dev and prod profiles.
+func DefaultProfiles() Profiles {
+	return Profiles{
+		DevProfileName:  devProfile,
+		ProdProfileName: prodProfile,
+	}
 }
 
-func GetProfile(serverType string) Profile {
+// ParseProfiles parses a YAML or JSON object of named profiles and merges it
+// over DefaultProfiles. Unset storage and backup retention fall back to the
+// operator defaults. An empty input returns the built-in profiles.
+func ParseProfiles(raw []byte) (Profiles, error) {
+	profiles := DefaultProfiles()
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return profiles, nil
+	}
+
+	var configured map[string]Profile
+	if err := yaml.UnmarshalStrict(raw, &configured); err != nil {
+		return nil, fmt.Errorf("parse server profiles: %w", err)
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := configured[name]
+		if profile.StorageGB == 0 {
+			profile.StorageGB = defaultStorageGB
+		}
+		if profile.BackupRetentionDays == 0 {
+			profile.BackupRetentionDays = defaultBackupRetentionDaysNonProd
+		}
+		if err := validateProfile(name, profile); err != nil {
+			return nil, err
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+func validateProfile(name string, profile Profile) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("server profile name must not be empty")
+	}
+	if strings.TrimSpace(profile.SkuName) == "" {
+		return fmt.Errorf("server profile %q: skuName must not be empty", name)
+	}
+	switch profile.SkuTier {
+	case dbforpostgresqlv1.SkuTier_Burstable,
+		dbforpostgresqlv1.SkuTier_GeneralPurpose,
+		dbforpostgresqlv1.SkuTier_MemoryOptimized:
+	default:
+		return fmt.Errorf("server profile %q: unsupported skuTier %q", name, profile.SkuTier)
+	}
+	if _, err := ResolveMaxConnections(profile); err != nil {
+		return fmt.Errorf("server profile %q: %w", name, err)
+	}
+	if profile.StorageGB < 0 {
+		return fmt.Errorf("server profile %q: storageGB must not be negative", name)
+	}
+	if profile.BackupRetentionDays < 7 || profile.BackupRetentionDays > 35 {
+		return fmt.Errorf("server profile %q: backupRetentionDays must be between 7 and 35", name)
+	}
+	if profile.PgBouncerEnabled && !profile.SupportsPgBouncer() {
+		return fmt.Errorf("server profile %q: pgBouncerEnabled is not supported on the %s tier", name, profile.SkuTier)
+	}
+	if profile.MaxDatabases < 0 {
+		return fmt.Errorf("server profile %q: maxDatabases must not be negative", name)
+	}
+	return nil
+}
+
+// Resolve returns the profile for a server. An explicit profile name must
+// exist. Without one, a profile named after serverType is used, and otherwise
+// serverType is mapped to the built-in prod or dev profile as before profiles
+// were configurable. A nil Profiles resolves against DefaultProfiles.
+func (p Profiles) Resolve(name, serverType string) (Profile, error) {
+	if p == nil {
+		p = DefaultProfiles()
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		profile, ok := p[name]
+		if !ok {
+			return Profile{}, fmt.Errorf("server profile %q is not configured", name)
+		}
+		return profile, nil
+	}
+	if profile, ok := p[serverType]; ok {
+		return profile, nil
+	}
+	fallback := DevProfileName
 	if isProdServerType(serverType) {
-		return prodProfile
+		fallback = ProdProfileName
+	}
+	profile, ok := p[fallback]
+	if !ok {
+		return Profile{}, fmt.Errorf("server profile %q for serverType %q is not configured", fallback, serverType)
 	}
-	return devProfile
+	return profile, nil
+}
+
+// Names returns the configured profile names in sorted order.
+func (p Profiles) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // SupportsPgBouncer reports whether the profile's compute tier can run the
@@ -49,30 +197,56 @@ func (p Profile) SupportsPgBouncer() bool {
 	return p.SkuTier != dbforpostgresqlv1.SkuTier_Burstable
 }
 
-func ResolveBackupRetentionDays(serverType string, requested *int) int {
-	if requested != nil {
+// SupportsReadReplicas reports whether the profile's compute tier can host
+// read replicas. Azure does not support read replicas on the Burstable tier.
+func (p Profile) SupportsReadReplicas() bool {
+	return p.SkuTier != dbforpostgresqlv1.SkuTier_Burstable
+}
+
+// ResolveStorageGB returns the requested storage size, or the profile's
+// default when none is requested.
+func ResolveStorageGB(profile Profile, requested *int32) int32 {
+	if requested != nil && *requested > 0 {
 		return *requested
 	}
-
-	if isProdServerType(serverType) {
-		return defaultBackupRetentionDaysProd
+	if profile.StorageGB > 0 {
+		return profile.StorageGB
 	}
-	return defaultBackupRetentionDaysNonProd
+	return defaultStorageGB
 }
 
-func ResolveHighAvailabilityEnabled(serverType string, requested *bool) bool {
+func ResolveBackupRetentionDays(profile Profile, requested *int) int {
 	if requested != nil {
 		return *requested
 	}
+	if profile.BackupRetentionDays > 0 {
+		return profile.BackupRetentionDays
+	}
+	return defaultBackupRetentionDaysNonProd
+}
 
-	if isProdServerType(serverType) {
-		return defaultHighAvailabilityEnabledProd
+// ResolveGeoRedundantBackup returns whether the server stores geo-redundant
+// backups. It is off unless requested, and requesting it on a profile without
+// geoRedundantBackupAllowed is an error.
+func ResolveGeoRedundantBackup(profile Profile, requested *bool) (bool, error) {
+	if requested == nil || !*requested {
+		return false, nil
+	}
+	if !profile.GeoRedundantBackupAllowed {
+		return false, fmt.Errorf("spec.geoRedundantBackup is not allowed on profile with SKU %s; select a profile with geoRedundantBackupAllowed", profile.SkuName)
+	}
+	return true, nil
+}
+
+func ResolveHighAvailabilityEnabled(profile Profile, requested *bool) bool {
+	if requested != nil {
+		return *requested
 	}
-	return false
+	return profile.HighAvailabilityEnabled
 }
 
-func ResolveHighAvailabilityMode(serverType string, requested *bool) dbforpostgresqlv1.HighAvailability_Mode {
-	if ResolveHighAvailabilityEnabled(serverType, requested) {
+func ResolveHighAvailabilityMode(profile Profile, requested *bool) dbforpostgresqlv1.HighAvailability_Mode {
+	if ResolveHighAvailabilityEnabled(profile, requested) {
 		return dbforpostgresqlv1.HighAvailability_Mode_ZoneRedundant
 	}
 	return dbforpostgresqlv1.HighAvailability_Mode_Disabled
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"testing"
+
+	dbforpostgresqlv1 "github.com/Azure/azure-service-operator/v2/api/dbforpostgresql/v20250801"
+)
+
+func TestParseProfiles(t *testing.T) {
+	t.Run("returns built-in profiles for empty config", func(t *testing.T) {
+		profiles, err := ParseProfiles(nil)
+		if err != nil {
+			t.Fatalf("ParseProfiles(nil) returned error: %v", err)
+		}
+		if profiles[DevProfileName] != devProfile || profiles[ProdProfileName] != prodProfile {
+			t.Fatalf("expected built-in profiles, got %#v", profiles)
+		}
+	})
+
+	t.Run("adds and overrides profiles with defaults filled in", func(t *testing.T) {
+		profiles, err := ParseProfiles([]byte(`
+dev:
+  skuName: Standard_B2s
+  skuTier: Burstable
+  memoryGB: 4
+large:
+  skuName: Standard_E8ds_v5
+  skuTier: MemoryOptimized
+  memoryGB: 64
+  storageGB: 256
+  highAvailabilityEnabled: true
+  backupRetentionDays: 35
+  pgBouncerEnabled: true
+  maxDatabases: 20
+`))
+		if err != nil {
+			t.Fatalf("ParseProfiles returned error: %v", err)
+		}
+
+		dev := profiles[DevProfileName]
+		if dev.SkuName != "Standard_B2s" || dev.StorageGB != defaultStorageGB || dev.BackupRetentionDays != defaultBackupRetentionDaysNonProd {
+			t.Fatalf("unexpected dev profile %#v", dev)
+		}
+		if profiles[ProdProfileName] != prodProfile {
+			t.Fatalf("expected built-in prod profile to be kept, got %#v", profiles[ProdProfileName])
+		}
+		large := profiles["large"]
+		if large.SkuTier != dbforpostgresqlv1.SkuTier_MemoryOptimized || large.StorageGB != 256 || large.MaxDatabases != 20 || !large.PgBouncerEnabled {
+			t.Fatalf("unexpected large profile %#v", large)
+		}
+	})
+
+	cases := map[string]string{
+		"unknown field":          "dev: {skuName: x, skuTier: Burstable, memoryGB: 2, cpu: 1}",
+		"missing sku":            "dev: {skuTier: Burstable, memoryGB: 2}",
+		"unsupported tier":       "dev: {skuName: x, skuTier: Premium, memoryGB: 2}",
+		"invalid memory":         "dev: {skuName: x, skuTier: Burstable}",
+		"retention out of range": "dev: {skuName: x, skuTier: Burstable, memoryGB: 2, backupRetentionDays: 40}",
+		"pgbouncer on burstable": "dev: {skuName: x, skuTier: Burstable, memoryGB: 2, pgBouncerEnabled: true}",
+		"negative max databases": "dev: {skuName: x, skuTier: Burstable, memoryGB: 2, maxDatabases: -1}",
+	}
+	for name, raw := range cases {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := ParseProfiles([]byte(raw)); err == nil {
+				t.Fatalf("expected error for %s", name)
+			}
+		})
+	}
+}
+
+func TestProfilesResolve(t *testing.T) {
+	profiles := DefaultProfiles()
+	profiles["staging"] = Profile{SkuName: "Standard_D2s_v3", SkuTier: dbforpostgresqlv1.SkuTier_GeneralPurpose, MemoryGB: 8}
+
+	cases := []struct {
+		name       string
+		profile    string
+		serverType string
+		want       string
+		wantErr    bool
+	}{
+		{name: "explicit profile", profile: "staging", serverType: "dev", want: "Standard_D2s_v3"},
+		{name: "profile named after serverType", serverType: "staging", want: "Standard_D2s_v3"},
+		{name: "production maps to prod", serverType: "production", want: prodProfile.SkuName},
+		{name: "unknown serverType maps to dev", serverType: "test", want: devProfile.SkuName},
+		{name: "unknown explicit profile", profile: "missing", serverType: "prod", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := profiles.Resolve(tc.profile, tc.serverType)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve returned error: %v", err)
+			}
+			if got.SkuName != tc.want {
+				t.Fatalf("Resolve(%q, %q).SkuName = %q, want %q", tc.profile, tc.serverType, got.SkuName, tc.want)
+			}
+		})
+	}
+
+	t.Run("nil profiles resolve against built-ins", func(t *testing.T) {
+		got, err := Profiles(nil).Resolve("", "prod")
+		if err != nil || got != prodProfile {
+			t.Fatalf("expected built-in prod profile, got %#v (err=%v)", got, err)
+		}
+	})
+}
+
+func TestResolveProfileDefaults(t *testing.T) {
+	requestedDays := 7
+	requestedHA := false
+	var requestedStorage int32 = 128
+
+	if got := ResolveBackupRetentionDays(prodProfile, nil); got != defaultBackupRetentionDaysProd {
+		t.Fatalf("ResolveBackupRetentionDays(prod, nil) = %d, want %d", got, defaultBackupRetentionDaysProd)
+	}
+	if got := ResolveBackupRetentionDays(prodProfile, &requestedDays); got != requestedDays {
+		t.Fatalf("ResolveBackupRetentionDays(prod, 7) = %d, want %d", got, requestedDays)
+	}
+	if !ResolveHighAvailabilityEnabled(prodProfile, nil) || ResolveHighAvailabilityEnabled(devProfile, nil) {
+		t.Fatalf("expected HA default to follow the profile")
+	}
+	if ResolveHighAvailabilityEnabled(prodProfile, &requestedHA) {
+		t.Fatalf("expected requested HA to override the profile")
+	}
+	if got := ResolveStorageGB(devProfile, nil); got != defaultStorageGB {
+		t.Fatalf("ResolveStorageGB(dev, nil) = %d, want %d", got, defaultStorageGB)
+	}
+	if got := ResolveStorageGB(Profile{StorageGB: 64}, &requestedStorage); got != requestedStorage {
+		t.Fatalf("ResolveStorageGB(profile, 128) = %d, want %d", got, requestedStorage)
+	}
+}
+
+func TestResolveGeoRedundantBackup(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	if got, err := ResolveGeoRedundantBackup(devProfile, nil); err != nil || got {
+		t.Fatalf("expected geo-redundant backup off by default, got %v (err=%v)", got, err)
+	}
+	if got, err := ResolveGeoRedundantBackup(devProfile, &disabled); err != nil || got {
+		t.Fatalf("expected an explicit false to be accepted on any profile, got %v (err=%v)", got, err)
+	}
+	if got, err := ResolveGeoRedundantBackup(prodProfile, &enabled); err != nil || !got {
+		t.Fatalf("expected geo-redundant backup on the prod profile, got %v (err=%v)", got, err)
+	}
+	if _, err := ResolveGeoRedundantBackup(devProfile, &enabled); err == nil {
+		t.Fatalf("expected an error for a profile without geoRedundantBackupAllowed")
+	}
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValidateRestorePointInTime checks that a point-in-time restore target is
+// recoverable from the source server's backups: it must be in the past, no
+// earlier than the source server's creation, and inside its backup retention
+// window.
+func ValidateRestorePointInTime(pointInTime, sourceCreated, now time.Time, retentionDays int) error {
+	if pointInTime.IsZero() {
+		return fmt.Errorf("restore point in time must be set")
+	}
+	if !pointInTime.Before(now) {
+		return fmt.Errorf("restore point in time %s must be in the past", pointInTime.UTC().Format(time.RFC3339))
+	}
+	if retentionDays <= 0 {
+		return fmt.Errorf("source server has invalid backup retention %d days", retentionDays)
+	}
+
+	earliest := now.Add(-time.Duration(retentionDays) * 24 * time.Hour)
+	if pointInTime.Before(earliest) {
+		return fmt.Errorf(
+			"restore point in time %s is outside the source server's %d-day backup retention window (earliest %s)",
+			pointInTime.UTC().Format(time.RFC3339),
+			retentionDays,
+			earliest.UTC().Format(time.RFC3339),
+		)
+	}
+	if !sourceCreated.IsZero() && pointInTime.Before(sourceCreated) {
+		return fmt.Errorf(
+			"restore point in time %s is before the source server was created (%s)",
+			pointInTime.UTC().Format(time.RFC3339),
+			sourceCreated.UTC().Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
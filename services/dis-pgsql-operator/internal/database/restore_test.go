@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateRestorePointInTime(t *testing.T) {
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-90 * 24 * time.Hour)
+
+	t.Run("accepts timestamp inside the retention window", func(t *testing.T) {
+		if err := ValidateRestorePointInTime(now.Add(-2*time.Hour), created, now, 14); err != nil {
+			t.Fatalf("expected valid point in time, got %v", err)
+		}
+	})
+
+	t.Run("rejects zero timestamp", func(t *testing.T) {
+		if err := ValidateRestorePointInTime(time.Time{}, created, now, 14); err == nil {
+			t.Fatalf("expected error for zero point in time, got nil")
+		}
+	})
+
+	t.Run("rejects timestamp in the future", func(t *testing.T) {
+		if err := ValidateRestorePointInTime(now.Add(time.Minute), created, now, 14); err == nil {
+			t.Fatalf("expected error for future point in time, got nil")
+		}
+	})
+
+	t.Run("rejects timestamp older than retention", func(t *testing.T) {
+		if err := ValidateRestorePointInTime(now.Add(-15*24*time.Hour), created, now, 14); err == nil {
+			t.Fatalf("expected error for point in time outside retention, got nil")
+		}
+	})
+
+	t.Run("rejects timestamp before the source server existed", func(t *testing.T) {
+		recent := now.Add(-24 * time.Hour)
+		if err := ValidateRestorePointInTime(now.Add(-48*time.Hour), recent, now, 14); err == nil {
+			t.Fatalf("expected error for point in time before source creation, got nil")
+		}
+	})
+
+	t.Run("rejects invalid retention", func(t *testing.T) {
+		if err := ValidateRestorePointInTime(now.Add(-time.Hour), created, now, 0); err == nil {
+			t.Fatalf("expected error for invalid retention, got nil")
+		}
+	})
+}
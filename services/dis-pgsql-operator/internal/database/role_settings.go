@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	statementTimeoutSetting                = "statement_timeout"
+	idleInTransactionSessionTimeoutSetting = "idle_in_transaction_session_timeout"
+)
+
+// RoleSettings are the limits applied to a principal's login role. A nil
+// RoleSettings leaves the payload, and so the Job name, unchanged for
+// principals without any.
+type RoleSettings struct {
+	// ConnectionLimit caps the concurrent connections of the role on the
+	// server. The operator sends the lowest limit any Database on the server
+	// sets for the principal, so every access Job applies the same one.
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+	// StatementTimeoutMilliseconds sets statement_timeout for the role in
+	// the database.
+	StatementTimeoutMilliseconds *int64 `json:"statementTimeoutMilliseconds,omitempty"`
+	// IdleInTransactionSessionTimeoutMilliseconds sets
+	// idle_in_transaction_session_timeout for the role in the database.
+	IdleInTransactionSessionTimeoutMilliseconds *int64 `json:"idleInTransactionSessionTimeoutMilliseconds,omitempty"`
+}
+
+// normalizedRoleSettings returns settings, or nil when it sets nothing.
+func normalizedRoleSettings(settings *RoleSettings) *RoleSettings {
+	if settings == nil || *settings == (RoleSettings{}) {
+		return nil
+	}
+	return settings
+}
+
+// ensurePrincipalSettings applies the settings of a principal's login role.
+// Unset settings are reset, so removing one from the spec removes it from the
+// role: the timeouts fall back to the server's defaults, and the connection
+// limit, which is only unset when no Database on the server sets one, is
+// lifted. The timeouts are set for the database only, the connection limit
+// applies to the role on the whole server.
+func ensurePrincipalSettings(ctx context.Context, conn pgxConn, principal AccessPrincipal, dbName string) error {
+	settings := principal.Settings
+	if settings == nil {
+		settings = &RoleSettings{}
+	}
+
+	connectionLimit := int32(-1)
+	if settings.ConnectionLimit != nil {
+		connectionLimit = *settings.ConnectionLimit
+	}
+	if _, err := conn.Exec(ctx, alterRoleConnectionLimitSQL(principal.Name, connectionLimit)); err != nil {
+		return fmt.Errorf("set connection limit of %s: %w", principal.Name, err)
+	}
+
+	for _, setting := range []struct {
+		name         string
+		milliseconds *int64
+	}{
+		{name: statementTimeoutSetting, milliseconds: settings.StatementTimeoutMilliseconds},
+		{name: idleInTransactionSessionTimeoutSetting, milliseconds: settings.IdleInTransactionSessionTimeoutMilliseconds},
+	} {
+		statement := resetRoleSettingSQL(principal.Name, dbName, setting.name)
+		if setting.milliseconds != nil {
+			statement = setRoleTimeoutSQL(principal.Name, dbName, setting.name, *setting.milliseconds)
+		}
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("set %s of %s: %w", setting.name, principal.Name, err)
+		}
+	}
+	return nil
+}
+
+func alterRoleConnectionLimitSQL(role string, limit int32) string {
+	return fmt.Sprintf("ALTER ROLE %s CONNECTION LIMIT %d;", pgx.Identifier{role}.Sanitize(), limit)
+}
+
+// setRoleTimeoutSQL sets a timeout setting in milliseconds, its unit when
+// none is given.
+func setRoleTimeoutSQL(role, dbName, setting string, milliseconds int64) string {
+	return fmt.Sprintf("ALTER ROLE %s IN DATABASE %s SET %s = %d;",
+		pgx.Identifier{role}.Sanitize(),
+		pgx.Identifier{dbName}.Sanitize(),
+		setting,
+		milliseconds,
+	)
+}
+
+func resetRoleSettingSQL(role, dbName, setting string) string {
+	return fmt.Sprintf("ALTER ROLE %s IN DATABASE %s RESET %s;",
+		pgx.Identifier{role}.Sanitize(),
+		pgx.Identifier{dbName}.Sanitize(),
+		setting,
+	)
+}
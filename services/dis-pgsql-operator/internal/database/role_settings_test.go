@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEnsurePrincipalSettings(t *testing.T) {
+	limit := int32(20)
+	statementTimeout := int64(30000)
+	conn := &recordingConn{}
+	principal := AccessPrincipal{
+		Role:          AccessRoleWriter,
+		Name:          "app-user",
+		PrincipalType: PrincipalTypeService,
+		Settings: &RoleSettings{
+			ConnectionLimit:              &limit,
+			StatementTimeoutMilliseconds: &statementTimeout,
+		},
+	}
+	if err := ensurePrincipalSettings(context.Background(), conn, principal, appDBName); err != nil {
+		t.Fatalf("ensurePrincipalSettings: %v", err)
+	}
+	requireExec(t, conn, `ALTER ROLE "app-user" CONNECTION LIMIT 20;`)
+	requireExec(t, conn, `ALTER ROLE "app-user" IN DATABASE "`+appDBName+`" SET statement_timeout = 30000;`)
+	requireExec(t, conn, `ALTER ROLE "app-user" IN DATABASE "`+appDBName+`" RESET idle_in_transaction_session_timeout;`)
+
+	conn = &recordingConn{}
+	principal.Settings = nil
+	if err := ensurePrincipalSettings(context.Background(), conn, principal, appDBName); err != nil {
+		t.Fatalf("ensurePrincipalSettings: %v", err)
+	}
+	requireExec(t, conn, `ALTER ROLE "app-user" CONNECTION LIMIT -1;`)
+	requireExec(t, conn, resetRoleSettingSQL("app-user", appDBName, statementTimeoutSetting))
+	requireExec(t, conn, resetRoleSettingSQL("app-user", appDBName, idleInTransactionSessionTimeoutSetting))
+}
+
+func TestMarshalAccessPrincipalsOmitsEmptySettings(t *testing.T) {
+	payload, err := MarshalAccessPrincipals([]AccessPrincipal{{
+		Role:          AccessRoleReader,
+		Name:          "app-user",
+		PrincipalType: PrincipalTypeService,
+		Settings:      &RoleSettings{},
+	}})
+	if err != nil {
+		t.Fatalf("MarshalAccessPrincipals: %v", err)
+	}
+	if strings.Contains(payload, "settings") {
+		t.Fatalf("expected empty settings to be left out, got %s", payload)
+	}
+}
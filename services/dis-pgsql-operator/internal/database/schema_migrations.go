@@ -0,0 +1,360 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// SchemaMigrationsPayloadVersion versions the report the schema migration
+	// Job writes.
+	SchemaMigrationsPayloadVersion = 1
+
+	// SchemaMigrationsOutputPath is where the schema migration Job writes its
+	// report, read by the operator from the Pod termination message.
+	SchemaMigrationsOutputPath = DatabaseCatalogOutputPath
+
+	// schemaMigrationsTable records the applied migrations in the database's
+	// schema. It is owned by the Owner role, like the objects the migrations
+	// create.
+	schemaMigrationsTable = "dispg_schema_migrations"
+
+	schemaMigrationFileSuffix = ".sql"
+
+	// schemaMigrationErrorMaxLength bounds the reported error so the report
+	// fits the termination message.
+	schemaMigrationErrorMaxLength = 1024
+)
+
+// SchemaMigrationsReport is the report written by the schema migration Job.
+// A migration that fails is reported in Failed and Error rather than failing
+// the Job, since running it again would fail the same way.
+type SchemaMigrationsReport struct {
+	Version int `json:"version"`
+
+	// Applied is the number of migrations this run applied.
+	Applied int32 `json:"applied"`
+
+	// Latest is the latest migration applied to the database, by this run or
+	// an earlier one.
+	Latest string `json:"latest,omitempty"`
+
+	// Failed is the migration the run stopped at, and Error why.
+	Failed string `json:"failed,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MarshalSchemaMigrationsReport serializes a schema migration report.
+func MarshalSchemaMigrationsReport(report SchemaMigrationsReport) (string, error) {
+	report.Version = SchemaMigrationsPayloadVersion
+	content, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("marshal schema migrations report: %w", err)
+	}
+	return string(content), nil
+}
+
+// UnmarshalSchemaMigrationsReport parses a report written by the schema
+// migration Job.
+func UnmarshalSchemaMigrationsReport(value string) (SchemaMigrationsReport, error) {
+	var report SchemaMigrationsReport
+	if err := json.Unmarshal([]byte(value), &report); err != nil {
+		return SchemaMigrationsReport{}, fmt.Errorf("parse schema migrations report: %w", err)
+	}
+	if report.Version != SchemaMigrationsPayloadVersion {
+		return SchemaMigrationsReport{}, fmt.Errorf("unsupported schema migrations report version %d", report.Version)
+	}
+	return report, nil
+}
+
+// schemaMigration is one .sql file. Its version is the file name without
+// the suffix.
+type schemaMigration struct {
+	Version  string
+	SQL      string
+	Checksum string
+}
+
+// schemaMigrationConn is a connection that can run a migration in a
+// transaction.
+type schemaMigrationConn interface {
+	pgxConn
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// readSchemaMigrations returns the .sql files in dir ordered by name.
+// Hidden entries are skipped, which leaves out the bookkeeping entries of a
+// mounted ConfigMap.
+func readSchemaMigrations(dir string) ([]schemaMigration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read schema migrations in %s: %w", dir, err)
+	}
+	migrations := make([]schemaMigration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !strings.HasSuffix(name, schemaMigrationFileSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		// ConfigMap keys are symlinks, so stat the target.
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat schema migration %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read schema migration %s: %w", path, err)
+		}
+		migrations = append(migrations, schemaMigration{
+			Version:  strings.TrimSuffix(name, schemaMigrationFileSuffix),
+			SQL:      string(content),
+			Checksum: naming.StableSHA256Hex(string(content)),
+		})
+	}
+	return migrations, nil
+}
+
+// applySchemaMigrations applies the migrations that are not recorded in
+// the migrations table yet, in order. Each runs in its own transaction as
+// ownerRole with schemaName first in the search path, and is recorded in
+// the same transaction, so a failed migration leaves nothing behind and
+// stops the run. A recorded migration whose file has changed also stops the
+// run. Only errors reaching the database are returned.
+func applySchemaMigrations(
+	ctx context.Context,
+	conn schemaMigrationConn,
+	schemaName string,
+	ownerRole string,
+	migrations []schemaMigration,
+) (SchemaMigrationsReport, error) {
+	report := SchemaMigrationsReport{Version: SchemaMigrationsPayloadVersion}
+	if err := inOwnerTransaction(ctx, conn, schemaName, ownerRole, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, createSchemaMigrationsTableSQL(schemaName))
+		return err
+	}); err != nil {
+		return report, fmt.Errorf("create schema migrations table: %w", err)
+	}
+
+	applied, err := appliedSchemaMigrations(ctx, conn, schemaName)
+	if err != nil {
+		return report, err
+	}
+	for version := range applied {
+		report.Latest = max(report.Latest, version)
+	}
+
+	for _, migration := range migrations {
+		if checksum, ok := applied[migration.Version]; ok {
+			if checksum != migration.Checksum {
+				report.Failed = migration.Version
+				report.Error = "the migration changed after it was applied; add a new migration instead"
+				break
+			}
+			continue
+		}
+		if err := inOwnerTransaction(ctx, conn, schemaName, ownerRole, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, migration.SQL); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, recordSchemaMigrationSQL(schemaName), migration.Version, migration.Checksum)
+			return err
+		}); err != nil {
+			report.Failed = migration.Version
+			report.Error = truncateSchemaMigrationError(err)
+			break
+		}
+		report.Applied++
+		report.Latest = max(report.Latest, migration.Version)
+	}
+	return report, nil
+}
+
+// inOwnerTransaction runs statements in a transaction as ownerRole. The role
+// and search path are reset when the transaction ends.
+func inOwnerTransaction(
+	ctx context.Context,
+	conn schemaMigrationConn,
+	schemaName string,
+	ownerRole string,
+	statements func(pgx.Tx) error,
+) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	run := func() error {
+		if _, err := tx.Exec(ctx, setLocalRoleSQL(ownerRole)); err != nil {
+			return fmt.Errorf("set role %q: %w", ownerRole, err)
+		}
+		if _, err := tx.Exec(ctx, setLocalSearchPathSQL(schemaName)); err != nil {
+			return fmt.Errorf("set search_path: %w", err)
+		}
+		return statements(tx)
+	}
+	if err := run(); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// schemaMigrationsRoleRefusal says why the session's login role may not
+// apply migrations, or returns "" when it may. The migrations are tenant
+// SQL, which can RESET ROLE or end the transaction, so the login role must
+// hold no rights beyond ownerRole's: no administrative attributes and no
+// membership in any other role.
+func schemaMigrationsRoleRefusal(ctx context.Context, conn pgxConn, ownerRole string) (string, error) {
+	var privileged bool
+	if err := conn.QueryRow(ctx, sessionRolePrivilegedSQL()).Scan(&privileged); err != nil {
+		return "", fmt.Errorf("read the attributes of the session role: %w", err)
+	}
+	if privileged {
+		return "the login role of the schema migrations must not be a superuser or have CREATEROLE, CREATEDB, REPLICATION or BYPASSRLS", nil
+	}
+
+	rows, err := conn.Query(ctx, sessionRoleMembershipsSQL())
+	if err != nil {
+		return "", fmt.Errorf("read the memberships of the session role: %w", err)
+	}
+	defer rows.Close()
+	var others []string
+	owner := false
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return "", fmt.Errorf("scan the memberships of the session role: %w", err)
+		}
+		if role == ownerRole {
+			owner = true
+			continue
+		}
+		others = append(others, role)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("read the memberships of the session role: %w", err)
+	}
+	if len(others) > 0 {
+		return fmt.Sprintf("the login role of the schema migrations must only be a member of %q, but is also a member of %s",
+			ownerRole, strings.Join(others, ", ")), nil
+	}
+	if !owner {
+		return fmt.Sprintf("the login role of the schema migrations is not a member of %q", ownerRole), nil
+	}
+	return "", nil
+}
+
+// appliedSchemaMigrations returns the checksum of each recorded migration.
+func appliedSchemaMigrations(ctx context.Context, conn pgxConn, schemaName string) (map[string]string, error) {
+	rows, err := conn.Query(ctx, appliedSchemaMigrationsSQL(schemaName))
+	if err != nil {
+		return nil, fmt.Errorf("read applied schema migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := map[string]string{}
+	for rows.Next() {
+		var version, checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("scan applied schema migration: %w", err)
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read applied schema migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// writeSchemaMigrations applies the migrations in dir and writes the report
+// to path. A login role that may not apply migrations is reported instead.
+func writeSchemaMigrations(
+	ctx context.Context,
+	conn schemaMigrationConn,
+	dir string,
+	schemaName string,
+	ownerRole string,
+	path string,
+) error {
+	migrations, err := readSchemaMigrations(dir)
+	if err != nil {
+		return err
+	}
+	refusal, err := schemaMigrationsRoleRefusal(ctx, conn, ownerRole)
+	if err != nil {
+		return err
+	}
+	report := SchemaMigrationsReport{Error: refusal}
+	if refusal == "" {
+		report, err = applySchemaMigrations(ctx, conn, schemaName, ownerRole, migrations)
+		if err != nil {
+			return err
+		}
+	}
+	content, err := MarshalSchemaMigrationsReport(report)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write schema migrations report to %s: %w", path, err)
+	}
+	return nil
+}
+
+func truncateSchemaMigrationError(err error) string {
+	message := err.Error()
+	if len(message) <= schemaMigrationErrorMaxLength {
+		return message
+	}
+	return message[:schemaMigrationErrorMaxLength]
+}
+
+func createSchemaMigrationsTableSQL(schemaName string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  version text PRIMARY KEY,
+  checksum text NOT NULL,
+  applied_at timestamptz NOT NULL DEFAULT now()
+);`, pgx.Identifier{schemaName, schemaMigrationsTable}.Sanitize())
+}
+
+func appliedSchemaMigrationsSQL(schemaName string) string {
+	return fmt.Sprintf("SELECT version, checksum FROM %s", pgx.Identifier{schemaName, schemaMigrationsTable}.Sanitize())
+}
+
+func recordSchemaMigrationSQL(schemaName string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, checksum) VALUES ($1, $2)", pgx.Identifier{schemaName, schemaMigrationsTable}.Sanitize())
+}
+
+func sessionRolePrivilegedSQL() string {
+	return `SELECT rolsuper OR rolcreaterole OR rolcreatedb OR rolreplication OR rolbypassrls
+FROM pg_roles
+WHERE rolname = session_user`
+}
+
+// sessionRoleMembershipsSQL lists the roles the login role is a direct
+// member of.
+func sessionRoleMembershipsSQL() string {
+	return `SELECT g.rolname
+FROM pg_auth_members m
+JOIN pg_roles g ON g.oid = m.roleid
+JOIN pg_roles r ON r.oid = m.member
+WHERE r.rolname = session_user
+ORDER BY g.rolname`
+}
+
+func setLocalRoleSQL(role string) string {
+	return fmt.Sprintf("SET LOCAL ROLE %s;", pgx.Identifier{role}.Sanitize())
+}
+
+func setLocalSearchPathSQL(schemaName string) string {
+	return fmt.Sprintf("SET LOCAL search_path TO %s, public;", pgx.Identifier{schemaName}.Sanitize())
+}
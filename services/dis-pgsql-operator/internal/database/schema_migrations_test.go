@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func TestReadSchemaMigrations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"0002_add_index.sql":    "CREATE INDEX orders_created ON orders (created);",
+		"0001_create_table.sql": "CREATE TABLE orders (id bigint, created timestamptz);",
+		"README.md":             "not a migration",
+		".hidden.sql":           "SELECT 1;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "0003_dir.sql"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// A mounted ConfigMap exposes its keys as symlinks into a hidden directory.
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "..data", "0004_seed.sql"), []byte("INSERT INTO orders VALUES (1, now());"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "0004_seed.sql"), filepath.Join(dir, "0004_seed.sql")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	migrations, err := readSchemaMigrations(dir)
+	if err != nil {
+		t.Fatalf("readSchemaMigrations: %v", err)
+	}
+	versions := make([]string, 0, len(migrations))
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	if got := strings.Join(versions, ","); got != "0001_create_table,0002_add_index,0004_seed" {
+		t.Fatalf("unexpected migrations %s", got)
+	}
+	if migrations[0].Checksum != naming.StableSHA256Hex(files["0001_create_table.sql"]) {
+		t.Fatalf("unexpected checksum %q", migrations[0].Checksum)
+	}
+}
+
+func expectOwnerTransaction(mock pgxmock.PgxConnIface) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SET LOCAL ROLE "app-owner";`)).WillReturnResult(pgxmock.NewResult("SET", 0))
+	mock.ExpectExec(regexp.QuoteMeta(`SET LOCAL search_path TO "app", public;`)).WillReturnResult(pgxmock.NewResult("SET", 0))
+}
+
+func TestApplySchemaMigrations(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	migrations := []schemaMigration{
+		{Version: "0001_create_table", SQL: "CREATE TABLE orders (id bigint);", Checksum: "a"},
+		{Version: "0002_add_column", SQL: "ALTER TABLE orders ADD COLUMN total numeric;", Checksum: "b"},
+		{Version: "0003_bad", SQL: "ALTER TABLE missing ADD COLUMN x int;", Checksum: "c"},
+		{Version: "0004_never", SQL: "SELECT 1;", Checksum: "d"},
+	}
+
+	expectOwnerTransaction(mock)
+	mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrationsTableSQL("app"))).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(appliedSchemaMigrationsSQL("app"))).
+		WillReturnRows(pgxmock.NewRows([]string{"version", "checksum"}).AddRow("0001_create_table", "a"))
+
+	expectOwnerTransaction(mock)
+	mock.ExpectExec(regexp.QuoteMeta(migrations[1].SQL)).WillReturnResult(pgxmock.NewResult("ALTER TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta(recordSchemaMigrationSQL("app"))).
+		WithArgs("0002_add_column", "b").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	expectOwnerTransaction(mock)
+	mock.ExpectExec(regexp.QuoteMeta(migrations[2].SQL)).WillReturnError(errors.New(`relation "missing" does not exist`))
+	mock.ExpectRollback()
+
+	report, err := applySchemaMigrations(context.Background(), mock, "app", "app-owner", migrations)
+	if err != nil {
+		t.Fatalf("applySchemaMigrations: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+	want := SchemaMigrationsReport{
+		Version: SchemaMigrationsPayloadVersion,
+		Applied: 1,
+		Latest:  "0002_add_column",
+		Failed:  "0003_bad",
+		Error:   `relation "missing" does not exist`,
+	}
+	if report != want {
+		t.Fatalf("expected %#v, got %#v", want, report)
+	}
+}
+
+func TestApplySchemaMigrationsRejectsChangedMigration(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	expectOwnerTransaction(mock)
+	mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrationsTableSQL("app"))).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(appliedSchemaMigrationsSQL("app"))).
+		WillReturnRows(pgxmock.NewRows([]string{"version", "checksum"}).AddRow("0001_create_table", "old"))
+
+	report, err := applySchemaMigrations(context.Background(), mock, "app", "app-owner", []schemaMigration{
+		{Version: "0001_create_table", SQL: "CREATE TABLE orders (id bigint, total numeric);", Checksum: "new"},
+		{Version: "0002_add_column", SQL: "SELECT 1;", Checksum: "b"},
+	})
+	if err != nil {
+		t.Fatalf("applySchemaMigrations: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+	if report.Applied != 0 || report.Latest != "0001_create_table" || report.Failed != "0001_create_table" ||
+		!strings.Contains(report.Error, "changed after it was applied") {
+		t.Fatalf("unexpected report %#v", report)
+	}
+}
+
+func TestSchemaMigrationsRoleRefusal(t *testing.T) {
+	tests := map[string]struct {
+		privileged bool
+		members    []string
+		refused    string
+	}{
+		"owner only":     {members: []string{"app-owner"}},
+		"admin":          {privileged: true, refused: "must not be a superuser"},
+		"another role":   {members: []string{"app-owner", "azure_pg_admin"}, refused: "also a member of azure_pg_admin"},
+		"not the owner":  {members: []string{"app-writer"}, refused: "also a member of app-writer"},
+		"no memberships": {refused: "is not a member of \"app-owner\""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, err := pgxmock.NewConn()
+			if err != nil {
+				t.Fatalf("new mock: %v", err)
+			}
+			defer func() {
+				_ = mock.Close(context.Background())
+			}()
+
+			mock.ExpectQuery(regexp.QuoteMeta(sessionRolePrivilegedSQL())).
+				WillReturnRows(pgxmock.NewRows([]string{"privileged"}).AddRow(tt.privileged))
+			if !tt.privileged {
+				rows := pgxmock.NewRows([]string{"rolname"})
+				for _, member := range tt.members {
+					rows.AddRow(member)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(sessionRoleMembershipsSQL())).WillReturnRows(rows)
+			}
+
+			refusal, err := schemaMigrationsRoleRefusal(context.Background(), mock, "app-owner")
+			if err != nil {
+				t.Fatalf("schemaMigrationsRoleRefusal: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("expectations: %v", err)
+			}
+			if tt.refused == "" && refusal != "" || !strings.Contains(refusal, tt.refused) {
+				t.Fatalf("unexpected refusal %q, want %q", refusal, tt.refused)
+			}
+		})
+	}
+}
+
+func TestSchemaMigrationsReportRoundTrip(t *testing.T) {
+	content, err := MarshalSchemaMigrationsReport(SchemaMigrationsReport{Applied: 2, Latest: "0002_add_column"})
+	if err != nil {
+		t.Fatalf("MarshalSchemaMigrationsReport: %v", err)
+	}
+	report, err := UnmarshalSchemaMigrationsReport(content)
+	if err != nil || report.Applied != 2 || report.Latest != "0002_add_column" {
+		t.Fatalf("unexpected report %#v (%v)", report, err)
+	}
+	if _, err := UnmarshalSchemaMigrationsReport(`{"version":2}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+}
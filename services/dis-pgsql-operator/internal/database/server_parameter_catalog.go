@@ -0,0 +1,228 @@
+package database
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// ServerParameterType is the kind of value a catalogued server parameter takes.
+type ServerParameterType string
+
+const (
+	ServerParameterTypeBoolean ServerParameterType = "boolean"
+	ServerParameterTypeInteger ServerParameterType = "integer"
+	ServerParameterTypeReal    ServerParameterType = "real"
+	ServerParameterTypeEnum    ServerParameterType = "enum"
+	ServerParameterTypeString  ServerParameterType = "string"
+)
+
+// ServerParameterSpec describes a server parameter users may set. Min and Max
+// bound integer and real values, Values lists the accepted enum values, and
+// MinVersion/MaxVersion limit the PostgreSQL major versions that have it.
+type ServerParameterSpec struct {
+	Name            string              `json:"name"`
+	Type            ServerParameterType `json:"type"`
+	Min             *float64            `json:"min,omitempty"`
+	Max             *float64            `json:"max,omitempty"`
+	Values          []string            `json:"values,omitempty"`
+	RequiresRestart bool                `json:"restart,omitempty"`
+	MinVersion      int                 `json:"minVersion,omitempty"`
+	MaxVersion      int                 `json:"maxVersion,omitempty"`
+}
+
+type serverParameterCatalog struct {
+	MinVersion int                   `json:"minVersion"`
+	MaxVersion int                   `json:"maxVersion"`
+	Parameters []ServerParameterSpec `json:"parameters"`
+}
+
+//go:embed server_parameter_catalog.yaml
+var serverParameterCatalogYAML []byte
+
+var catalog = mustParseServerParameterCatalog(serverParameterCatalogYAML)
+
+func mustParseServerParameterCatalog(raw []byte) serverParameterCatalog {
+	parsed, err := parseServerParameterCatalog(raw)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded server parameter catalog: %v", err))
+	}
+	return parsed
+}
+
+func parseServerParameterCatalog(raw []byte) (serverParameterCatalog, error) {
+	var parsed serverParameterCatalog
+	if err := yaml.UnmarshalStrict(raw, &parsed); err != nil {
+		return serverParameterCatalog{}, err
+	}
+	if parsed.MinVersion <= 0 || parsed.MaxVersion < parsed.MinVersion {
+		return serverParameterCatalog{}, fmt.Errorf("invalid version range %d-%d", parsed.MinVersion, parsed.MaxVersion)
+	}
+
+	seen := make(map[string]struct{}, len(parsed.Parameters))
+	for _, spec := range parsed.Parameters {
+		if _, ok := seen[spec.Name]; ok {
+			return serverParameterCatalog{}, fmt.Errorf("parameter %q is listed twice", spec.Name)
+		}
+		seen[spec.Name] = struct{}{}
+
+		switch spec.Type {
+		case ServerParameterTypeBoolean, ServerParameterTypeString:
+		case ServerParameterTypeInteger, ServerParameterTypeReal:
+			if spec.Min == nil || spec.Max == nil || *spec.Min > *spec.Max {
+				return serverParameterCatalog{}, fmt.Errorf("parameter %q needs min <= max", spec.Name)
+			}
+		case ServerParameterTypeEnum:
+			if len(spec.Values) == 0 {
+				return serverParameterCatalog{}, fmt.Errorf("parameter %q needs values", spec.Name)
+			}
+		default:
+			return serverParameterCatalog{}, fmt.Errorf("parameter %q has unsupported type %q", spec.Name, spec.Type)
+		}
+	}
+	return parsed, nil
+}
+
+// ServerParameterCatalog returns the server parameters users may set on the
+// given PostgreSQL major version, keyed by name. It returns nil for versions
+// the catalog does not cover.
+func ServerParameterCatalog(version int) map[string]ServerParameterSpec {
+	if version < catalog.MinVersion || version > catalog.MaxVersion {
+		return nil
+	}
+	specs := make(map[string]ServerParameterSpec, len(catalog.Parameters))
+	for _, spec := range catalog.Parameters {
+		if spec.MinVersion != 0 && version < spec.MinVersion {
+			continue
+		}
+		if spec.MaxVersion != 0 && version > spec.MaxVersion {
+			continue
+		}
+		specs[spec.Name] = spec
+	}
+	return specs
+}
+
+// ValidateServerParameters checks spec.serverParams against the catalog for
+// the given PostgreSQL major version. Errors are reported per field so they
+// can be returned from admission as well as from the reconciler.
+func ValidateServerParameters(
+	version int,
+	requested []storagev1alpha1.DatabaseServerParameter,
+	path *field.Path,
+) field.ErrorList {
+	if len(requested) == 0 {
+		return nil
+	}
+
+	var errs field.ErrorList
+	specs := ServerParameterCatalog(version)
+	if specs == nil {
+		return append(errs, field.Forbidden(path, fmt.Sprintf(
+			"server parameters are not catalogued for PostgreSQL %d (supported: %d-%d)",
+			version, catalog.MinVersion, catalog.MaxVersion,
+		)))
+	}
+
+	seen := make(map[string]struct{}, len(requested))
+	for i := range requested {
+		itemPath := path.Index(i)
+		name := strings.TrimSpace(requested[i].Name)
+
+		if _, ok := seen[name]; ok {
+			errs = append(errs, field.Duplicate(itemPath.Child("name"), name))
+			continue
+		}
+		seen[name] = struct{}{}
+
+		if _, managed := extensionManagedServerParameters[name]; managed {
+			errs = append(errs, field.Forbidden(itemPath.Child("name"), fmt.Sprintf("%s is managed by enableExtensions", name)))
+			continue
+		}
+		if _, managed := nonOverridableServerParameters[name]; managed {
+			errs = append(errs, field.Forbidden(itemPath.Child("name"), fmt.Sprintf("%s is managed by the operator", name)))
+			continue
+		}
+
+		spec, ok := specs[name]
+		if !ok {
+			errs = append(errs, field.Invalid(itemPath.Child("name"), name,
+				fmt.Sprintf("not a supported server parameter for PostgreSQL %d", version)))
+			continue
+		}
+
+		value, err := normalizeServerParameterValue(requested[i].Value)
+		if err != nil {
+			errs = append(errs, field.Invalid(itemPath.Child("value"), requested[i].Value.String(), err.Error()))
+			continue
+		}
+		if detail := spec.validate(value); detail != "" {
+			errs = append(errs, field.Invalid(itemPath.Child("value"), value, detail))
+		}
+	}
+	return errs
+}
+
+// validate returns why value is not acceptable for the parameter, or an empty
+// string.
+func (s ServerParameterSpec) validate(value string) string {
+	switch s.Type {
+	case ServerParameterTypeBoolean:
+		switch strings.ToLower(value) {
+		case "on", "off", "true", "false":
+			return ""
+		}
+		return "must be on or off"
+	case ServerParameterTypeInteger:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "must be an integer in the parameter's base unit"
+		}
+		return s.validateRange(float64(parsed))
+	case ServerParameterTypeReal:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+			return "must be a number"
+		}
+		return s.validateRange(parsed)
+	case ServerParameterTypeEnum:
+		for _, allowed := range s.Values {
+			if strings.EqualFold(value, allowed) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(s.Values, ", "))
+	}
+	return ""
+}
+
+func (s ServerParameterSpec) validateRange(value float64) string {
+	if value < *s.Min || value > *s.Max {
+		return fmt.Sprintf(
+			"must be between %s and %s",
+			strconv.FormatFloat(*s.Min, 'f', -1, 64),
+			strconv.FormatFloat(*s.Max, 'f', -1, 64),
+		)
+	}
+	return ""
+}
+
+// RestartRequiredServerParameters returns the names of the requested
+// parameters that only take effect after a server restart, in request order.
+func RestartRequiredServerParameters(version int, requested []storagev1alpha1.DatabaseServerParameter) []string {
+	specs := ServerParameterCatalog(version)
+	var names []string
+	for i := range requested {
+		name := strings.TrimSpace(requested[i].Name)
+		if spec, ok := specs[name]; ok && spec.RequiresRestart {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestServerParameterCatalog(t *testing.T) {
+	t.Run("limits parameters to the versions that have them", func(t *testing.T) {
+		v13 := ServerParameterCatalog(13)
+		v17 := ServerParameterCatalog(17)
+		if _, ok := v13["idle_session_timeout"]; ok {
+			t.Fatalf("expected idle_session_timeout to be missing on PostgreSQL 13")
+		}
+		if _, ok := v17["idle_session_timeout"]; !ok {
+			t.Fatalf("expected idle_session_timeout on PostgreSQL 17")
+		}
+		if _, ok := v13["vacuum_cleanup_index_scale_factor"]; !ok {
+			t.Fatalf("expected vacuum_cleanup_index_scale_factor on PostgreSQL 13")
+		}
+		if _, ok := v17["vacuum_cleanup_index_scale_factor"]; ok {
+			t.Fatalf("expected vacuum_cleanup_index_scale_factor to be missing on PostgreSQL 17")
+		}
+	})
+
+	t.Run("does not cover versions outside its range", func(t *testing.T) {
+		if specs := ServerParameterCatalog(9); specs != nil {
+			t.Fatalf("expected no catalog for PostgreSQL 9, got %d parameters", len(specs))
+		}
+	})
+
+	t.Run("does not list operator-managed parameters", func(t *testing.T) {
+		specs := ServerParameterCatalog(17)
+		for name := range nonOverridableServerParameters {
+			if _, ok := specs[name]; ok {
+				t.Fatalf("expected %q to be left out of the catalog", name)
+			}
+		}
+		for name := range extensionManagedServerParameters {
+			if _, ok := specs[name]; ok {
+				t.Fatalf("expected %q to be left out of the catalog", name)
+			}
+		}
+	})
+
+	t.Run("rejects invalid catalogs", func(t *testing.T) {
+		cases := map[string]string{
+			"range without bounds": "minVersion: 11\nmaxVersion: 17\nparameters: [{name: a, type: integer}]",
+			"enum without values":  "minVersion: 11\nmaxVersion: 17\nparameters: [{name: a, type: enum}]",
+			"unknown type":         "minVersion: 11\nmaxVersion: 17\nparameters: [{name: a, type: list}]",
+			"duplicate parameter":  "minVersion: 11\nmaxVersion: 17\nparameters: [{name: a, type: boolean}, {name: a, type: boolean}]",
+			"missing versions":     "parameters: [{name: a, type: boolean}]",
+		}
+		for name, raw := range cases {
+			if _, err := parseServerParameterCatalog([]byte(raw)); err == nil {
+				t.Fatalf("expected error for %s", name)
+			}
+		}
+	})
+}
+
+func TestValidateServerParameters(t *testing.T) {
+	path := field.NewPath("spec", "serverParams")
+	param := func(name string, value intstr.IntOrString) storagev1alpha1.DatabaseServerParameter {
+		return storagev1alpha1.DatabaseServerParameter{Name: name, Value: value}
+	}
+
+	t.Run("accepts catalogued values", func(t *testing.T) {
+		errs := ValidateServerParameters(17, []storagev1alpha1.DatabaseServerParameter{
+			param("autovacuum_naptime", intstr.FromInt32(15)),
+			param("work_mem", intstr.FromString("4096")),
+			param("log_connections", intstr.FromString("on")),
+			param("checkpoint_completion_target", intstr.FromString("0.9")),
+			param("log_statement", intstr.FromString("DDL")),
+			param("timezone", intstr.FromString("Europe/Oslo")),
+		}, path)
+		if len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("accepts no parameters on any version", func(t *testing.T) {
+		if errs := ValidateServerParameters(9, nil, path); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	cases := []struct {
+		name      string
+		version   int
+		params    []storagev1alpha1.DatabaseServerParameter
+		wantField string
+		wantText  string
+	}{
+		{
+			name:      "unknown parameter",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("autovacum_naptime", intstr.FromInt32(15))},
+			wantField: "spec.serverParams[0].name",
+			wantText:  "not a supported server parameter for PostgreSQL 17",
+		},
+		{
+			name:      "parameter from a newer version",
+			version:   16,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("transaction_timeout", intstr.FromInt32(0))},
+			wantField: "spec.serverParams[0].name",
+			wantText:  "PostgreSQL 16",
+		},
+		{
+			name:      "operator-managed parameter",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param(ServerParameterMaxConnections, intstr.FromInt32(100))},
+			wantField: "spec.serverParams[0].name",
+			wantText:  "managed by the operator",
+		},
+		{
+			name:      "integer out of range",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("work_mem", intstr.FromInt32(1))},
+			wantField: "spec.serverParams[0].value",
+			wantText:  "must be between 64 and 2147483647",
+		},
+		{
+			name:      "integer with unit",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("work_mem", intstr.FromString("4MB"))},
+			wantField: "spec.serverParams[0].value",
+			wantText:  "base unit",
+		},
+		{
+			name:      "invalid boolean",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("log_connections", intstr.FromString("yes please"))},
+			wantField: "spec.serverParams[0].value",
+			wantText:  "must be on or off",
+		},
+		{
+			name:      "invalid enum",
+			version:   17,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("log_statement", intstr.FromString("some"))},
+			wantField: "spec.serverParams[0].value",
+			wantText:  "must be one of none, ddl, mod, all",
+		},
+		{
+			name:    "duplicate parameter",
+			version: 17,
+			params: []storagev1alpha1.DatabaseServerParameter{
+				param("autovacuum_naptime", intstr.FromInt32(15)),
+				param("autovacuum_naptime", intstr.FromInt32(30)),
+			},
+			wantField: "spec.serverParams[1].name",
+			wantText:  "Duplicate value",
+		},
+		{
+			name:      "version outside the catalog",
+			version:   9,
+			params:    []storagev1alpha1.DatabaseServerParameter{param("autovacuum_naptime", intstr.FromInt32(15))},
+			wantField: "spec.serverParams",
+			wantText:  "not catalogued for PostgreSQL 9",
+		},
+	}
+	for _, tc := range cases {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			errs := ValidateServerParameters(tc.version, tc.params, path)
+			if len(errs) != 1 {
+				t.Fatalf("expected one error, got %v", errs)
+			}
+			if errs[0].Field != tc.wantField {
+				t.Fatalf("error field = %q, want %q", errs[0].Field, tc.wantField)
+			}
+			if !strings.Contains(errs[0].Error(), tc.wantText) {
+				t.Fatalf("error %q does not mention %q", errs[0].Error(), tc.wantText)
+			}
+		})
+	}
+}
+
+func TestRestartRequiredServerParameters(t *testing.T) {
+	got := RestartRequiredServerParameters(17, []storagev1alpha1.DatabaseServerParameter{
+		{Name: "autovacuum_naptime", Value: intstr.FromInt32(15)},
+		{Name: "shared_buffers", Value: intstr.FromInt32(131072)},
+		{Name: "max_worker_processes", Value: intstr.FromInt32(16)},
+	})
+	if strings.Join(got, ",") != "shared_buffers,max_worker_processes" {
+		t.Fatalf("RestartRequiredServerParameters = %v", got)
+	}
+}
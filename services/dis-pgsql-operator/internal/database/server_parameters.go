@@ -20,7 +20,6 @@ const (
 )
 
 const (
-	defaultPgBouncerEnabled     = "true"
 	defaultPgBouncerMaxPrepared = "5000"
 	defaultPgBouncerPoolMode    = "transaction"
 )
@@ -43,25 +42,32 @@ type ServerParameter struct {
 }
 
 func ResolveServerParameters(
-	serverType string,
+	profile Profile,
+	pooling *storagev1alpha1.DatabaseServerConnectionPoolingSpec,
 	requested []storagev1alpha1.DatabaseServerParameter,
 ) ([]ServerParameter, error) {
-	profile := GetProfile(serverType)
 	maxConnections, err := ResolveMaxConnections(profile)
 	if err != nil {
 		return nil, err
 	}
+	connectionPooling, err := ResolveConnectionPooling(profile, pooling)
+	if err != nil {
+		return nil, err
+	}
 
 	resolved := map[string]string{
 		ServerParameterMaxConnections: strconv.Itoa(maxConnections),
 	}
 
 	// PgBouncer is only available on tiers that support it (Azure rejects it on
-	// the Burstable tier), so only seed its parameters when the profile allows it.
+	// the Burstable tier). Elsewhere pgbouncer.enabled is always set, so turning
+	// pooling off also reaches the server.
 	if profile.SupportsPgBouncer() {
-		resolved[ServerParameterPgBouncerEnabled] = defaultPgBouncerEnabled
-		resolved[ServerParameterPgBouncerMaxPrepared] = defaultPgBouncerMaxPrepared
-		resolved[ServerParameterPgBouncerPoolMode] = defaultPgBouncerPoolMode
+		resolved[ServerParameterPgBouncerEnabled] = strconv.FormatBool(connectionPooling.Enabled)
+		if connectionPooling.Enabled {
+			resolved[ServerParameterPgBouncerMaxPrepared] = defaultPgBouncerMaxPrepared
+			resolved[ServerParameterPgBouncerPoolMode] = connectionPooling.PoolMode
+		}
 	}
 
 	for i := range requested {
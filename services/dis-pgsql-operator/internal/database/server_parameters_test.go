@@ -57,7 +57,7 @@ func TestResolveServerParameters(t *testing.T) {
 	}
 
 	t.Run("omits pgbouncer parameters for dev profile on Burstable tier", func(t *testing.T) {
-		got, err := ResolveServerParameters("dev", nil)
+		got, err := ResolveServerParameters(devProfile, nil, nil)
 		if err != nil {
 			t.Fatalf("ResolveServerParameters(dev, nil) returned error: %v", err)
 		}
@@ -78,7 +78,7 @@ func TestResolveServerParameters(t *testing.T) {
 	})
 
 	t.Run("includes pgbouncer defaults and max_connections for prod profile", func(t *testing.T) {
-		got, err := ResolveServerParameters("prod", nil)
+		got, err := ResolveServerParameters(prodProfile, nil, nil)
 		if err != nil {
 			t.Fatalf("ResolveServerParameters(prod, nil) returned error: %v", err)
 		}
@@ -98,8 +98,54 @@ func TestResolveServerParameters(t *testing.T) {
 		}
 	})
 
+	t.Run("disables pgbouncer when the profile disables it", func(t *testing.T) {
+		profile := prodProfile
+		profile.PgBouncerEnabled = false
+		got, err := ResolveServerParameters(profile, nil, nil)
+		if err != nil {
+			t.Fatalf("ResolveServerParameters(profile, nil, nil) returned error: %v", err)
+		}
+
+		values := toMap(got)
+		if values[ServerParameterPgBouncerEnabled] != "false" {
+			t.Fatalf("pgbouncer.enabled = %q, want %q", values[ServerParameterPgBouncerEnabled], "false")
+		}
+		if _, ok := values[ServerParameterPgBouncerPoolMode]; ok {
+			t.Fatalf("expected %q to be omitted, got %q", ServerParameterPgBouncerPoolMode, values[ServerParameterPgBouncerPoolMode])
+		}
+		if values[ServerParameterMaxConnections] != "1718" {
+			t.Fatalf("max_connections = %q, want %q", values[ServerParameterMaxConnections], "1718")
+		}
+	})
+
+	t.Run("applies connectionPooling over the profile", func(t *testing.T) {
+		profile := prodProfile
+		profile.PgBouncerEnabled = false
+		got, err := ResolveServerParameters(profile, &storagev1alpha1.DatabaseServerConnectionPoolingSpec{
+			PoolMode: storagev1alpha1.ConnectionPoolModeSession,
+		}, nil)
+		if err != nil {
+			t.Fatalf("ResolveServerParameters(profile, pooling, nil) returned error: %v", err)
+		}
+
+		values := toMap(got)
+		if values[ServerParameterPgBouncerEnabled] != "true" {
+			t.Fatalf("pgbouncer.enabled = %q, want %q", values[ServerParameterPgBouncerEnabled], "true")
+		}
+		if values[ServerParameterPgBouncerPoolMode] != "session" {
+			t.Fatalf("pgbouncer.pool_mode = %q, want %q", values[ServerParameterPgBouncerPoolMode], "session")
+		}
+	})
+
+	t.Run("rejects connectionPooling on Burstable tier", func(t *testing.T) {
+		_, err := ResolveServerParameters(devProfile, &storagev1alpha1.DatabaseServerConnectionPoolingSpec{}, nil)
+		if err == nil {
+			t.Fatalf("expected error for connectionPooling on Burstable tier, got nil")
+		}
+	})
+
 	t.Run("merges user provided server parameters", func(t *testing.T) {
-		got, err := ResolveServerParameters("dev", []storagev1alpha1.DatabaseServerParameter{
+		got, err := ResolveServerParameters(devProfile, nil, []storagev1alpha1.DatabaseServerParameter{
 			{
 				Name:  "autovacuum_naptime",
 				Value: intstr.FromInt(15),
@@ -123,7 +169,7 @@ func TestResolveServerParameters(t *testing.T) {
 	})
 
 	t.Run("rejects extension managed parameters", func(t *testing.T) {
-		_, err := ResolveServerParameters("dev", []storagev1alpha1.DatabaseServerParameter{
+		_, err := ResolveServerParameters(devProfile, nil, []storagev1alpha1.DatabaseServerParameter{
 			{
 				Name:  ServerParameterAzureExtensions,
 				Value: intstr.FromString("hstore"),
@@ -135,7 +181,7 @@ func TestResolveServerParameters(t *testing.T) {
 	})
 
 	t.Run("rejects non-overridable parameters", func(t *testing.T) {
-		_, err := ResolveServerParameters("dev", []storagev1alpha1.DatabaseServerParameter{
+		_, err := ResolveServerParameters(devProfile, nil, []storagev1alpha1.DatabaseServerParameter{
 			{
 				Name:  ServerParameterMaxConnections,
 				Value: intstr.FromInt(100),
@@ -147,7 +193,7 @@ func TestResolveServerParameters(t *testing.T) {
 	})
 
 	t.Run("rejects empty names and values", func(t *testing.T) {
-		_, err := ResolveServerParameters("dev", []storagev1alpha1.DatabaseServerParameter{
+		_, err := ResolveServerParameters(devProfile, nil, []storagev1alpha1.DatabaseServerParameter{
 			{
 				Name:  " ",
 				Value: intstr.FromInt(1),
@@ -157,7 +203,7 @@ func TestResolveServerParameters(t *testing.T) {
 			t.Fatalf("expected error for empty parameter name, got nil")
 		}
 
-		_, err = ResolveServerParameters("dev", []storagev1alpha1.DatabaseServerParameter{
+		_, err = ResolveServerParameters(devProfile, nil, []storagev1alpha1.DatabaseServerParameter{
 			{
 				Name:  "autovacuum_naptime",
 				Value: intstr.FromString(" "),
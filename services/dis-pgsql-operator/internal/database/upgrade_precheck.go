@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// UpgradePreCheckPayloadVersion versions the report the upgrade pre-check
+	// Job writes.
+	UpgradePreCheckPayloadVersion = 1
+
+	// UpgradePreCheckOutputPath is where the pre-check Job writes its report,
+	// the container's termination message like the catalog report.
+	UpgradePreCheckOutputPath = DatabaseCatalogOutputPath
+
+	// UpgradePreCheckMaxBytes is the Kubernetes limit for a termination
+	// message. Reports that do not fit drop database names from the longest
+	// extension lists.
+	UpgradePreCheckMaxBytes = DatabaseCatalogMaxBytes
+
+	// UpgradeMinFreeStoragePercent is the share of the server's storage that
+	// must be free before a major version upgrade. pg_upgrade writes new
+	// system catalogs and Azure takes a backup before upgrading, and both need
+	// room next to the existing data.
+	UpgradeMinFreeStoragePercent = 20
+)
+
+// upgradeUnsupportedExtensions are the extensions Azure does not support in
+// an in-place major version upgrade:
+// https://learn.microsoft.com/en-us/azure/postgresql/flexible-server/concepts-major-version-upgrade
+var upgradeUnsupportedExtensions = []string{
+	"dblink",
+	"orafce",
+	"pg_partman",
+	"pgaudit",
+	"postgres_fdw",
+	"timescaledb",
+}
+
+// UpgradeExtension is an extension installed in one or more databases.
+type UpgradeExtension struct {
+	Name      string   `json:"name"`
+	Databases []string `json:"databases"`
+}
+
+// UpgradePreCheckPayload is the report written by the upgrade pre-check Job.
+type UpgradePreCheckPayload struct {
+	Version int `json:"version"`
+
+	// DatabaseSizeBytes is the total size of the databases on the server.
+	DatabaseSizeBytes int64 `json:"databaseSizeBytes"`
+
+	// Extensions lists the installed extensions other than plpgsql.
+	Extensions []UpgradeExtension `json:"extensions,omitempty"`
+
+	// Truncated is true when database names were dropped to fit the report.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// MarshalUpgradePreCheck serializes the report, dropping database names from
+// the longest extension lists until it fits UpgradePreCheckMaxBytes. Every
+// extension keeps at least one database, so none is hidden from the checks.
+func MarshalUpgradePreCheck(payload UpgradePreCheckPayload) (string, error) {
+	payload.Version = UpgradePreCheckPayloadVersion
+	extensions := make([]UpgradeExtension, 0, len(payload.Extensions))
+	for _, extension := range payload.Extensions {
+		extensions = append(extensions, UpgradeExtension{
+			Name:      extension.Name,
+			Databases: slices.Sorted(slices.Values(extension.Databases)),
+		})
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		return extensions[i].Name < extensions[j].Name
+	})
+	payload.Extensions = extensions
+
+	for {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return "", fmt.Errorf("marshal upgrade pre-check payload: %w", err)
+		}
+		if len(content) <= UpgradePreCheckMaxBytes {
+			return string(content), nil
+		}
+		longest := -1
+		for i, extension := range payload.Extensions {
+			if len(extension.Databases) > 1 && (longest < 0 || len(extension.Databases) > len(payload.Extensions[longest].Databases)) {
+				longest = i
+			}
+		}
+		if longest < 0 {
+			return string(content), nil
+		}
+		databases := payload.Extensions[longest].Databases
+		payload.Extensions[longest].Databases = databases[:len(databases)-1]
+		payload.Truncated = true
+	}
+}
+
+// UnmarshalUpgradePreCheck parses a report written by the pre-check Job.
+func UnmarshalUpgradePreCheck(value string) (UpgradePreCheckPayload, error) {
+	var payload UpgradePreCheckPayload
+	if err := json.Unmarshal([]byte(value), &payload); err != nil {
+		return UpgradePreCheckPayload{}, fmt.Errorf("parse upgrade pre-check payload: %w", err)
+	}
+	if payload.Version != UpgradePreCheckPayloadVersion {
+		return UpgradePreCheckPayload{}, fmt.Errorf("unsupported upgrade pre-check payload version %d", payload.Version)
+	}
+	return payload, nil
+}
+
+// UpgradePreCheckProblems returns the reasons a server with this report
+// cannot be upgraded in place, or nothing when the upgrade can go ahead.
+// storageGB is the server's provisioned storage.
+func UpgradePreCheckProblems(payload UpgradePreCheckPayload, storageGB int) []string {
+	var problems []string
+	for _, extension := range payload.Extensions {
+		if !slices.Contains(upgradeUnsupportedExtensions, extension.Name) {
+			continue
+		}
+		databases := strings.Join(extension.Databases, ", ")
+		if payload.Truncated {
+			databases += ", ..."
+		}
+		problems = append(problems, fmt.Sprintf(
+			"extension %s is installed in %s and is not supported by in-place major version upgrades; drop it first",
+			extension.Name, databases,
+		))
+	}
+
+	if storageGB > 0 {
+		storageBytes := int64(storageGB) << 30
+		if payload.DatabaseSizeBytes*100 > storageBytes*(100-UpgradeMinFreeStoragePercent) {
+			problems = append(problems, fmt.Sprintf(
+				"databases use %.1f GiB of %d GiB storage; at least %d%% must be free, so grow spec.storage.sizeGB first",
+				float64(payload.DatabaseSizeBytes)/(1<<30), storageGB, UpgradeMinFreeStoragePercent,
+			))
+		}
+	}
+	return problems
+}
+
+// databaseConnector opens a connection to another database on the same
+// server. The returned function closes it.
+type databaseConnector func(ctx context.Context, database string) (pgxConn, func(), error)
+
+// collectUpgradePreCheck sums the size of the databases on the server and
+// lists the extensions installed in each of them, connecting to every
+// database in turn since pg_extension is per database.
+func collectUpgradePreCheck(ctx context.Context, conn pgxConn, connect databaseConnector) (UpgradePreCheckPayload, error) {
+	rows, err := conn.Query(ctx, listUpgradePreCheckDatabasesSQL())
+	if err != nil {
+		return UpgradePreCheckPayload{}, fmt.Errorf("list databases for upgrade pre-check: %w", err)
+	}
+	var payload UpgradePreCheckPayload
+	var databases []string
+	for rows.Next() {
+		var name string
+		var sizeBytes int64
+		if err := rows.Scan(&name, &sizeBytes); err != nil {
+			rows.Close()
+			return UpgradePreCheckPayload{}, fmt.Errorf("scan database for upgrade pre-check: %w", err)
+		}
+		databases = append(databases, name)
+		payload.DatabaseSizeBytes += sizeBytes
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return UpgradePreCheckPayload{}, fmt.Errorf("iterate databases for upgrade pre-check: %w", err)
+	}
+
+	extensionDatabases := map[string][]string{}
+	for _, database := range databases {
+		extensions, err := installedExtensions(ctx, database, connect)
+		if err != nil {
+			return UpgradePreCheckPayload{}, err
+		}
+		for _, extension := range extensions {
+			extensionDatabases[extension] = append(extensionDatabases[extension], database)
+		}
+	}
+	for name, databases := range extensionDatabases {
+		payload.Extensions = append(payload.Extensions, UpgradeExtension{Name: name, Databases: databases})
+	}
+	return payload, nil
+}
+
+func installedExtensions(ctx context.Context, database string, connect databaseConnector) ([]string, error) {
+	conn, closeConn, err := connect(ctx, database)
+	if err != nil {
+		return nil, fmt.Errorf("connect database %q for upgrade pre-check: %w", database, err)
+	}
+	defer closeConn()
+
+	rows, err := conn.Query(ctx, listInstalledExtensionsSQL())
+	if err != nil {
+		return nil, fmt.Errorf("list extensions in database %q: %w", database, err)
+	}
+	defer rows.Close()
+
+	var extensions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan extension in database %q: %w", database, err)
+		}
+		extensions = append(extensions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate extensions in database %q: %w", database, err)
+	}
+	return extensions, nil
+}
+
+// writeUpgradePreCheck collects the pre-check report and writes it to path.
+func writeUpgradePreCheck(ctx context.Context, conn pgxConn, connect databaseConnector, path string) error {
+	payload, err := collectUpgradePreCheck(ctx, conn, connect)
+	if err != nil {
+		return err
+	}
+	content, err := MarshalUpgradePreCheck(payload)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write upgrade pre-check report to %s: %w", path, err)
+	}
+	return nil
+}
+
+// listUpgradePreCheckDatabasesSQL skips template databases and the
+// Azure-internal maintenance database, which cannot be connected to.
+func listUpgradePreCheckDatabasesSQL() string {
+	return `SELECT datname, pg_database_size(oid)
+FROM pg_database
+WHERE datallowconn
+  AND NOT datistemplate
+  AND datname <> 'azure_maintenance'
+ORDER BY datname`
+}
+
+func listInstalledExtensionsSQL() string {
+	return `SELECT extname FROM pg_extension WHERE extname <> 'plpgsql' ORDER BY extname`
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+)
+
+func TestMarshalUpgradePreCheckTruncatesToLimit(t *testing.T) {
+	databases := make([]string, 0, 300)
+	for i := range 300 {
+		databases = append(databases, strings.Repeat("d", 30)+string(rune('a'+i%26))+strings.Repeat("x", i%11))
+	}
+
+	content, err := MarshalUpgradePreCheck(UpgradePreCheckPayload{
+		DatabaseSizeBytes: 42,
+		Extensions: []UpgradeExtension{
+			{Name: "pgaudit", Databases: []string{"only-db"}},
+			{Name: "hstore", Databases: databases},
+		},
+	})
+	if err != nil {
+		t.Fatalf("MarshalUpgradePreCheck: %v", err)
+	}
+	if len(content) > UpgradePreCheckMaxBytes {
+		t.Fatalf("expected report within %d bytes, got %d", UpgradePreCheckMaxBytes, len(content))
+	}
+
+	payload, err := UnmarshalUpgradePreCheck(content)
+	if err != nil {
+		t.Fatalf("UnmarshalUpgradePreCheck: %v", err)
+	}
+	if !payload.Truncated || payload.DatabaseSizeBytes != 42 {
+		t.Fatalf("unexpected payload %#v", payload)
+	}
+	if len(payload.Extensions) != 2 || payload.Extensions[0].Name != "hstore" || payload.Extensions[1].Name != "pgaudit" {
+		t.Fatalf("expected both extensions sorted by name, got %#v", payload.Extensions)
+	}
+	if len(payload.Extensions[1].Databases) != 1 {
+		t.Fatalf("expected the short extension list to be kept, got %#v", payload.Extensions[1])
+	}
+}
+
+func TestUnmarshalUpgradePreCheckRejectsUnknownVersion(t *testing.T) {
+	if _, err := UnmarshalUpgradePreCheck(`{"version":2}`); err == nil {
+		t.Fatalf("expected error for unsupported version")
+	}
+	if _, err := UnmarshalUpgradePreCheck("not json"); err == nil {
+		t.Fatalf("expected error for invalid payload")
+	}
+}
+
+func TestUpgradePreCheckProblems(t *testing.T) {
+	t.Run("passes with supported extensions and free storage", func(t *testing.T) {
+		problems := UpgradePreCheckProblems(UpgradePreCheckPayload{
+			DatabaseSizeBytes: 10 << 30,
+			Extensions:        []UpgradeExtension{{Name: "pg_stat_statements", Databases: []string{"app"}}},
+		}, 32)
+		if len(problems) != 0 {
+			t.Fatalf("expected no problems, got %v", problems)
+		}
+	})
+
+	t.Run("reports unsupported extensions", func(t *testing.T) {
+		problems := UpgradePreCheckProblems(UpgradePreCheckPayload{
+			Extensions: []UpgradeExtension{{Name: "pgaudit", Databases: []string{"app", "reporting"}}},
+		}, 32)
+		if len(problems) != 1 || !strings.Contains(problems[0], "pgaudit is installed in app, reporting") {
+			t.Fatalf("expected pgaudit problem, got %v", problems)
+		}
+	})
+
+	t.Run("reports missing storage headroom", func(t *testing.T) {
+		problems := UpgradePreCheckProblems(UpgradePreCheckPayload{DatabaseSizeBytes: 28 << 30}, 32)
+		if len(problems) != 1 || !strings.Contains(problems[0], "28.0 GiB of 32 GiB") {
+			t.Fatalf("expected storage problem, got %v", problems)
+		}
+	})
+}
+
+func TestWriteUpgradePreCheck(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	defer func() {
+		_ = mock.Close(context.Background())
+	}()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listUpgradePreCheckDatabasesSQL())).
+		WillReturnRows(pgxmock.NewRows([]string{"datname", "size"}).
+			AddRow("app", int64(8192)).
+			AddRow("postgres", int64(4096)))
+	mock.ExpectQuery(regexp.QuoteMeta(listInstalledExtensionsSQL())).
+		WillReturnRows(pgxmock.NewRows([]string{"extname"}).AddRow("hstore").AddRow("pgaudit"))
+	mock.ExpectQuery(regexp.QuoteMeta(listInstalledExtensionsSQL())).
+		WillReturnRows(pgxmock.NewRows([]string{"extname"}).AddRow("pgaudit"))
+
+	var connected []string
+	connect := func(_ context.Context, database string) (pgxConn, func(), error) {
+		connected = append(connected, database)
+		return mock, func() {}, nil
+	}
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	if err := writeUpgradePreCheck(context.Background(), mock, connect, path); err != nil {
+		t.Fatalf("writeUpgradePreCheck: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+	if strings.Join(connected, ",") != "app,postgres" {
+		t.Fatalf("expected a connection per database, got %v", connected)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	payload, err := UnmarshalUpgradePreCheck(string(content))
+	if err != nil {
+		t.Fatalf("UnmarshalUpgradePreCheck: %v", err)
+	}
+	if payload.DatabaseSizeBytes != 12288 {
+		t.Fatalf("expected total size 12288, got %d", payload.DatabaseSizeBytes)
+	}
+	if len(payload.Extensions) != 2 || payload.Extensions[1].Name != "pgaudit" ||
+		strings.Join(payload.Extensions[1].Databases, ",") != "app,postgres" {
+		t.Fatalf("unexpected extensions %#v", payload.Extensions)
+	}
+}
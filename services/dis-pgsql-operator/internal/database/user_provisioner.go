@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/naming"
@@ -22,39 +23,85 @@ const (
 	maintenanceDatabase = "postgres"
 )
 
-// RunUserProvisioner connects to the database using workload identity and
-// ensures the normal user exists with appropriate permissions.
+// RunUserProvisioner runs the entry point of the ProvisionMode in
+// ProvisionModeEnv.
 func RunUserProvisioner(ctx context.Context) error {
+	mode := ProvisionMode(strings.TrimSpace(os.Getenv(ProvisionModeEnv)))
+	switch mode {
+	case ProvisionModeAccess:
+		return withProvisionSession(ctx, mode, provisionAccess)
+	case ProvisionModeRevokeAllAccess:
+		return withProvisionSession(ctx, mode, revokeAllAccess)
+	case ProvisionModeServerDebugAccess:
+		return runServerDebugAccess(ctx)
+	case ProvisionModeDropDatabase:
+		return withProvisionSession(ctx, mode, runDropDatabase)
+	case ProvisionModeDatabaseCatalog:
+		return withProvisionSession(ctx, mode, runDatabaseCatalog)
+	case ProvisionModeUpgradePreCheck:
+		return withProvisionSession(ctx, mode, runUpgradePreCheck)
+	case ProvisionModeAccessAudit:
+		return withProvisionSession(ctx, mode, runAccessAudit)
+	case ProvisionModeMigration:
+		return runMigration(ctx)
+	case ProvisionModeMigrationRelease:
+		return withProvisionSession(ctx, mode, runMigrationRelease)
+	case ProvisionModeBackup:
+		return runBackup(ctx)
+	case ProvisionModeSchemaMigrations:
+		return withProvisionSession(ctx, mode, runSchemaMigrations)
+	default:
+		return fmt.Errorf("%s must be one of %v, got %q", ProvisionModeEnv, ProvisionModes, mode)
+	}
+}
+
+// provisionSession is the connection a provisioning mode runs on, opened as
+// the admin identity, or as the Owner principal for schema migrations.
+type provisionSession struct {
+	serverName string
+	dbName     string
+	schemaName string
+	useAAD     bool
+	sslMode    string
+	cfg        *pgx.ConnConfig
+	conn       *pgx.Conn
+}
+
+// withProvisionSession opens the session for mode, runs run on it and closes
+// it again.
+func withProvisionSession(ctx context.Context, mode ProvisionMode, run func(context.Context, *provisionSession) error) error {
+	session, err := openProvisionSession(ctx, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := session.conn.Close(ctx); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "close postgres connection: %v\n", err)
+		}
+	}()
+	return run(ctx, session)
+}
+
+func openProvisionSession(ctx context.Context, mode ProvisionMode) (*provisionSession, error) {
 	serverName := strings.TrimSpace(os.Getenv(DatabaseServerNameEnv))
 	adminAppIdentity := strings.TrimSpace(os.Getenv(AdminAppIdentityEnv))
 	schemaName := strings.TrimSpace(os.Getenv(DBSchemaEnv))
 	disableAAD := parseBoolEnv(os.Getenv(DisableAADEnv))
-	revokePublicConnect := parseBoolEnv(os.Getenv(RevokePublicConnectEnv))
-	databaseScopedSearchPath := strings.EqualFold(strings.TrimSpace(os.Getenv(DBSearchPathScopeEnv)), "database")
-	serverDebugAccess := parseBoolEnv(os.Getenv(ServerDebugAccessEnv))
-	debugBuiltinRoles := NormalizeBuiltinRoles(os.Getenv(DebugBuiltinRolesEnv))
 	sslMode := strings.TrimSpace(os.Getenv("DISPG_DB_SSLMODE"))
+	schemaMigrations := mode == ProvisionModeSchemaMigrations
 
 	if serverName == "" {
-		return fmt.Errorf("%s must be set", DatabaseServerNameEnv)
-	}
-	if adminAppIdentity == "" && !disableAAD {
-		return fmt.Errorf("%s must be set", AdminAppIdentityEnv)
+		return nil, fmt.Errorf("%s must be set", DatabaseServerNameEnv)
 	}
-	if serverDebugAccess && len(debugBuiltinRoles) == 0 {
-		return fmt.Errorf("%s must contain at least one built-in role in server debug access mode", DebugBuiltinRolesEnv)
+	// The schema migration Job connects as the Owner principal in
+	// AdminAppIdentityEnv in either mode, never as the admin.
+	if adminAppIdentity == "" && (!disableAAD || schemaMigrations) {
+		return nil, fmt.Errorf("%s must be set", AdminAppIdentityEnv)
 	}
 	if schemaName == "" {
 		schemaName = serverName
 	}
 
-	// Server debug access allows an empty principal set: the revocation Job runs
-	// with zero principals so the membership reconcile revokes everyone.
-	accessPrincipals, err := accessPrincipalsFromEnv(disableAAD, serverDebugAccess)
-	if err != nil {
-		return err
-	}
-
 	host := strings.TrimSpace(os.Getenv(DBHostEnv))
 	if host == "" {
 		if disableAAD {
@@ -64,13 +111,26 @@ func RunUserProvisioner(ctx context.Context) error {
 			// from DatabaseServer.Status.Host (server.Status.FullyQualifiedDomainName).
 			// Deriving "<serverName>.postgres.database.azure.com" is no longer correct
 			// because AzureName now carries a stable uniqueness suffix.
-			return fmt.Errorf("%s must be set when AAD is enabled", DBHostEnv)
+			return nil, fmt.Errorf("%s must be set when AAD is enabled", DBHostEnv)
 		}
 	}
 	dbName := strings.TrimSpace(os.Getenv(DBNameEnv))
+	switch mode {
+	case ProvisionModeDropDatabase, ProvisionModeSchemaMigrations, ProvisionModeMigrationRelease:
+		if dbName == "" {
+			return nil, fmt.Errorf("%s must be set in %s mode", DBNameEnv, mode)
+		}
+	}
 	if dbName == "" {
 		dbName = maintenanceDatabase
 	}
+	// A database cannot be dropped from a session connected to it, so the drop
+	// run always connects to the maintenance database. The migration release
+	// does too, like the fence it lifts.
+	connDBName := dbName
+	if mode == ProvisionModeDropDatabase || mode == ProvisionModeMigrationRelease {
+		connDBName = maintenanceDatabase
+	}
 	if sslMode == "" {
 		if disableAAD {
 			sslMode = "disable"
@@ -81,31 +141,40 @@ func RunUserProvisioner(ctx context.Context) error {
 	connStr := fmt.Sprintf(
 		"host=%s port=5432 dbname=%s sslmode=%s",
 		host,
-		dbName,
+		connDBName,
 		sslMode,
 	)
 
 	cfg, err := pgx.ParseConfig(connStr)
 	if err != nil {
-		return fmt.Errorf("parse pgx config: %w", err)
+		return nil, fmt.Errorf("parse pgx config: %w", err)
+	}
+	// A migration's read-only fence is meant for the applications, not for the
+	// operator's own sessions. Schema migrations run as an application
+	// principal and stay fenced.
+	if !schemaMigrations {
+		cfg.RuntimeParams[defaultTransactionReadOnlyParam] = "off"
 	}
 	if disableAAD {
 		adminUser := strings.TrimSpace(os.Getenv(DBAdminUserEnv))
 		if adminUser == "" {
 			adminUser = "postgres"
 		}
+		if schemaMigrations {
+			adminUser = adminAppIdentity
+		}
 		cfg.User = adminUser
 		cfg.Password = strings.TrimSpace(os.Getenv("DISPG_DB_PASSWORD"))
 	} else {
 		cred, err := azidentity.NewDefaultAzureCredential(nil)
 		if err != nil {
-			return fmt.Errorf("azure credential error: %w", err)
+			return nil, fmt.Errorf("azure credential error: %w", err)
 		}
 		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{
 			Scopes: []string{userProvisionScope},
 		})
 		if err != nil {
-			return fmt.Errorf("get azure token: %w", err)
+			return nil, fmt.Errorf("get azure token: %w", err)
 		}
 		cfg.User = adminAppIdentity
 		cfg.Password = token.Token
@@ -113,54 +182,250 @@ func RunUserProvisioner(ctx context.Context) error {
 
 	conn, err := pgx.ConnectConfig(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("connect postgres: %w", err)
+		return nil, fmt.Errorf("connect postgres: %w", err)
+	}
+	return &provisionSession{
+		serverName: serverName,
+		dbName:     dbName,
+		schemaName: schemaName,
+		useAAD:     !disableAAD,
+		sslMode:    sslMode,
+		cfg:        cfg,
+		conn:       conn,
+	}, nil
+}
+
+// withPrincipalConn runs run on the connection principals are created on: the
+// maintenance database, whose pgaadauth_* helpers the session's database
+// lacks under AAD.
+func (s *provisionSession) withPrincipalConn(ctx context.Context, run func(pgxConn) error) error {
+	if !s.useAAD || strings.EqualFold(s.cfg.Database, maintenanceDatabase) {
+		return run(s.conn)
+	}
+	maintenanceCfg := s.cfg.Copy()
+	maintenanceCfg.Database = maintenanceDatabase
+	maintenanceConn, err := pgx.ConnectConfig(ctx, maintenanceCfg)
+	if err != nil {
+		return fmt.Errorf("connect maintenance database %q: %w", maintenanceDatabase, err)
 	}
 	defer func() {
-		if err := conn.Close(ctx); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "close postgres connection: %v\n", err)
+		if err := maintenanceConn.Close(ctx); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "close maintenance connection: %v\n", err)
 		}
 	}()
+	return run(maintenanceConn)
+}
+
+// provisionDatabaseAccess provisions per-database access for principals and
+// then reconciles extensions, as every access-provisioning mode does.
+func (s *provisionSession) provisionDatabaseAccess(ctx context.Context, principals []AccessPrincipal, extensions []DatabaseExtension) error {
+	if err := s.withPrincipalConn(ctx, func(principalConn pgxConn) error {
+		return ensureAccess(ctx, s.conn, principalConn, accessOptions{
+			DatabaseName:             s.dbName,
+			SchemaName:               s.schemaName,
+			Principals:               principals,
+			UseAAD:                   s.useAAD,
+			RevokePublicConnect:      parseBoolEnv(os.Getenv(RevokePublicConnectEnv)),
+			DatabaseScopedSearchPath: strings.EqualFold(strings.TrimSpace(os.Getenv(DBSearchPathScopeEnv)), "database"),
+		})
+	}); err != nil {
+		return err
+	}
+	if len(extensions) == 0 {
+		return nil
+	}
+	return writeDatabaseExtensions(ctx, s.conn, s.schemaName, extensions, DatabaseExtensionsOutputPath)
+}
+
+func provisionAccess(ctx context.Context, s *provisionSession) error {
+	principals, err := accessPrincipalsFromEnv(!s.useAAD, false)
+	if err != nil {
+		return err
+	}
+	extensions, err := parseDatabaseExtensionsPayload(os.Getenv(DatabaseExtensionsEnv))
+	if err != nil {
+		return err
+	}
+	return s.provisionDatabaseAccess(ctx, principals, extensions)
+}
+
+// revokeAllAccess provisions access without principals, which revokes every
+// member of the managed roles.
+func revokeAllAccess(ctx context.Context, s *provisionSession) error {
+	extensions, err := parseDatabaseExtensionsPayload(os.Getenv(DatabaseExtensionsEnv))
+	if err != nil {
+		return err
+	}
+	return s.provisionDatabaseAccess(ctx, nil, extensions)
+}
+
+func runServerDebugAccess(ctx context.Context) error {
+	builtinRoles := NormalizeBuiltinRoles(os.Getenv(DebugBuiltinRolesEnv))
+	if len(builtinRoles) == 0 {
+		return fmt.Errorf("%s must contain at least one built-in role in %s mode", DebugBuiltinRolesEnv, ProvisionModeServerDebugAccess)
+	}
+	return withProvisionSession(ctx, ProvisionModeServerDebugAccess, func(ctx context.Context, s *provisionSession) error {
+		// An empty principal set is the revocation run: the membership
+		// reconcile revokes everyone.
+		principals, err := accessPrincipalsFromEnv(!s.useAAD, true)
+		if err != nil {
+			return err
+		}
+		return s.withPrincipalConn(ctx, func(principalConn pgxConn) error {
+			return ensureServerDebugAccess(ctx, s.conn, principalConn, serverDebugOptions{
+				ServerName:   s.serverName,
+				BuiltinRoles: builtinRoles,
+				Principals:   principals,
+				UseAAD:       s.useAAD,
+			})
+		})
+	})
+}
+
+func runDropDatabase(ctx context.Context, s *provisionSession) error {
+	return dropDatabase(ctx, s.conn, dropDatabaseOptions{
+		DatabaseName: s.dbName,
+		SchemaName:   s.schemaName,
+	})
+}
 
-	principalConn := conn
-	if !disableAAD && !strings.EqualFold(dbName, maintenanceDatabase) {
-		maintenanceCfg := cfg.Copy()
-		maintenanceCfg.Database = maintenanceDatabase
-		maintenanceConn, connErr := pgx.ConnectConfig(ctx, maintenanceCfg)
-		if connErr != nil {
-			return fmt.Errorf("connect maintenance database %q: %w", maintenanceDatabase, connErr)
+func runDatabaseCatalog(ctx context.Context, s *provisionSession) error {
+	return writeDatabaseCatalog(ctx, s.conn, DatabaseCatalogOutputPath)
+}
+
+func runUpgradePreCheck(ctx context.Context, s *provisionSession) error {
+	connect := func(ctx context.Context, database string) (pgxConn, func(), error) {
+		databaseCfg := s.cfg.Copy()
+		databaseCfg.Database = database
+		databaseConn, err := pgx.ConnectConfig(ctx, databaseCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return databaseConn, func() {
+			if err := databaseConn.Close(ctx); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "close database %q connection: %v\n", database, err)
+			}
+		}, nil
+	}
+	return writeUpgradePreCheck(ctx, s.conn, connect, UpgradePreCheckOutputPath)
+}
+
+func runAccessAudit(ctx context.Context, s *provisionSession) error {
+	principals, err := accessPrincipalsFromEnv(!s.useAAD, false)
+	if err != nil {
+		return err
+	}
+	return writeAccessAudit(ctx, s.conn, accessOptions{
+		DatabaseName: s.dbName,
+		SchemaName:   s.schemaName,
+		Principals:   principals,
+	}, parseBoolEnv(os.Getenv(AccessAuditEnforceEnv)), AccessAuditOutputPath)
+}
+
+// runMigration runs the prepare or the verify phase of a migration Job. Both
+// provision access on the target first; the verify phase reports the
+// migration, not the extensions, which the prepare phase already created.
+func runMigration(ctx context.Context) error {
+	phase := strings.TrimSpace(os.Getenv(MigrationPhaseEnv))
+	if !slices.Contains([]string{MigrationPhasePrepare, MigrationPhaseVerify}, phase) {
+		return fmt.Errorf("%s must be %q or %q", MigrationPhaseEnv, MigrationPhasePrepare, MigrationPhaseVerify)
+	}
+	sourceHost := strings.TrimSpace(os.Getenv(MigrationSourceHostEnv))
+	if sourceHost == "" {
+		return fmt.Errorf("%s must be set in the migration %s phase", MigrationSourceHostEnv, phase)
+	}
+	workDir := strings.TrimSpace(os.Getenv(MigrationWorkDirEnv))
+	if phase == MigrationPhasePrepare && workDir == "" {
+		return fmt.Errorf("%s must be set in the migration prepare phase", MigrationWorkDirEnv)
+	}
+
+	return withProvisionSession(ctx, ProvisionModeMigration, func(ctx context.Context, s *provisionSession) error {
+		principals, err := accessPrincipalsFromEnv(!s.useAAD, false)
+		if err != nil {
+			return err
+		}
+		var extensions []DatabaseExtension
+		if phase == MigrationPhasePrepare {
+			extensions, err = parseDatabaseExtensionsPayload(os.Getenv(DatabaseExtensionsEnv))
+			if err != nil {
+				return err
+			}
+		}
+		if err := s.provisionDatabaseAccess(ctx, principals, extensions); err != nil {
+			return err
+		}
+
+		// The source is fenced from its maintenance database, since the fence
+		// ends every other session on the database itself.
+		sourceDBName := s.dbName
+		if phase == MigrationPhasePrepare {
+			sourceDBName = maintenanceDatabase
+		}
+		sourceCfg, err := pgx.ParseConfig(fmt.Sprintf("host=%s port=5432 dbname=%s sslmode=%s", sourceHost, sourceDBName, s.sslMode))
+		if err != nil {
+			return fmt.Errorf("parse source pgx config: %w", err)
+		}
+		sourceCfg.User = s.cfg.User
+		sourceCfg.Password = s.cfg.Password
+		sourceCfg.RuntimeParams[defaultTransactionReadOnlyParam] = "off"
+		sourceConn, err := pgx.ConnectConfig(ctx, sourceCfg)
+		if err != nil {
+			return fmt.Errorf("connect source postgres: %w", err)
 		}
 		defer func() {
-			if err := maintenanceConn.Close(ctx); err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "close maintenance connection: %v\n", err)
+			if err := sourceConn.Close(ctx); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "close source postgres connection: %v\n", err)
 			}
 		}()
-		principalConn = maintenanceConn
-	}
 
-	if serverDebugAccess {
-		if err := ensureServerDebugAccess(ctx, conn, principalConn, serverDebugOptions{
-			ServerName:   serverName,
-			BuiltinRoles: debugBuiltinRoles,
-			Principals:   accessPrincipals,
-			UseAAD:       !disableAAD,
-		}); err != nil {
+		if phase == MigrationPhaseVerify {
+			return writeMigrationVerification(ctx, sourceConn, s.conn, s.dbName, MigrationOutputPath)
+		}
+		// A move back to a former source restores into a fenced database.
+		if err := releaseMigrationFence(ctx, s.conn, s.dbName); err != nil {
+			return fmt.Errorf("target: %w", err)
+		}
+		if err := writeMigrationCredentials(workDir, s.cfg.User, s.cfg.Password, managedAccessRolesFor(s.dbName, s.schemaName).Owner); err != nil {
 			return err
 		}
+		if err := fenceMigrationSource(ctx, sourceConn, s.dbName); err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
 		return nil
-	}
+	})
+}
 
-	if err := ensureAccess(ctx, conn, principalConn, accessOptions{
-		DatabaseName:             dbName,
-		SchemaName:               schemaName,
-		Principals:               accessPrincipals,
-		UseAAD:                   !disableAAD,
-		RevokePublicConnect:      revokePublicConnect,
-		DatabaseScopedSearchPath: databaseScopedSearchPath,
-	}); err != nil {
-		return err
+func runMigrationRelease(ctx context.Context, s *provisionSession) error {
+	return releaseMigrationFence(ctx, s.conn, s.dbName)
+}
+
+// runBackup runs the credentials or the upload phase of a backup Job. The
+// upload only talks to Blob Storage.
+func runBackup(ctx context.Context) error {
+	switch phase := strings.TrimSpace(os.Getenv(BackupPhaseEnv)); phase {
+	case BackupPhaseUpload:
+		return runBackupUpload(ctx)
+	case BackupPhaseCredentials:
+	default:
+		return fmt.Errorf("%s must be %q or %q", BackupPhaseEnv, BackupPhaseCredentials, BackupPhaseUpload)
+	}
+	workDir := strings.TrimSpace(os.Getenv(BackupWorkDirEnv))
+	if workDir == "" {
+		return fmt.Errorf("%s must be set in the backup credentials phase", BackupWorkDirEnv)
 	}
+	return withProvisionSession(ctx, ProvisionModeBackup, func(ctx context.Context, s *provisionSession) error {
+		// pg_dump reads the same credential files as the migration's copy.
+		return writeMigrationCredentials(workDir, s.cfg.User, s.cfg.Password, managedAccessRolesFor(s.dbName, s.schemaName).Owner)
+	})
+}
 
-	return nil
+func runSchemaMigrations(ctx context.Context, s *provisionSession) error {
+	dir := strings.TrimSpace(os.Getenv(SchemaMigrationsDirEnv))
+	if dir == "" {
+		return fmt.Errorf("%s must be set in %s mode", SchemaMigrationsDirEnv, ProvisionModeSchemaMigrations)
+	}
+	return writeSchemaMigrations(ctx, s.conn, dir, s.schemaName,
+		managedAccessRolesFor(s.dbName, s.schemaName).Owner, SchemaMigrationsOutputPath)
 }
 
 type pgxConn interface {
@@ -204,7 +469,11 @@ func ensureAccess(ctx context.Context, conn pgxConn, principalConn pgxConn, opts
 		return err
 	}
 
-	desiredMembers := newManagedRoleMemberships(accessRoles)
+	scopedPlan, err := planScopedAccess(opts)
+	if err != nil {
+		return err
+	}
+
 	for _, principal := range opts.Principals {
 		principal = normalizeAccessPrincipal(principal)
 		if err := validateAccessPrincipal(principal, opts.UseAAD); err != nil {
@@ -213,20 +482,22 @@ func ensureAccess(ctx context.Context, conn pgxConn, principalConn pgxConn, opts
 		if err := ensurePrincipal(ctx, principalConn, principal.Name, principal.PrincipalID, string(principal.PrincipalType), opts.UseAAD); err != nil {
 			return err
 		}
-		roleName, err := accessRoles.roleFor(principal.Role)
-		if err != nil {
-			return err
-		}
-		desiredMembers.add(roleName, principal.Name)
 		if _, err := conn.Exec(ctx, setSearchPathSQL(principal.Name, opts.DatabaseName, opts.SchemaName, opts.DatabaseScopedSearchPath)); err != nil {
 			return fmt.Errorf("set principal role search_path: %w", err)
 		}
+		if err := ensurePrincipalSettings(ctx, conn, principal, opts.DatabaseName); err != nil {
+			return err
+		}
 		if principal.Role == AccessRoleOwner {
 			if err := grantDefaultPrivilegesForCreator(ctx, conn, principal.Name, opts.SchemaName, accessRoles); err != nil {
 				return err
 			}
 		}
 	}
+	desiredMembers, err := baseAccessMemberships(accessRoles, opts.Principals)
+	if err != nil {
+		return err
+	}
 
 	if err := grantDefaultPrivilegesForCreator(ctx, conn, accessRoles.Owner, opts.SchemaName, accessRoles); err != nil {
 		return err
@@ -236,7 +507,39 @@ func ensureAccess(ctx context.Context, conn pgxConn, principalConn pgxConn, opts
 		return err
 	}
 
-	return nil
+	return ensureScopedAccess(ctx, conn, opts, accessRoles, accessCreators(accessRoles, opts.Principals), scopedPlan)
+}
+
+// baseAccessMemberships returns the members of the Reader, Writer and Owner
+// roles of the database's schema. A table scope replaces the Reader role of
+// the database's schema.
+func baseAccessMemberships(roles managedAccessRoles, principals []AccessPrincipal) (*managedRoleMemberships, error) {
+	memberships := newManagedRoleMemberships(roles)
+	for _, principal := range principals {
+		principal = normalizeAccessPrincipal(principal)
+		roleName, err := roles.roleFor(principal.Role)
+		if err != nil {
+			return nil, err
+		}
+		if principal.Scope == nil || len(principal.Scope.Tables) == 0 {
+			memberships.add(roleName, principal.Name)
+		}
+	}
+	return memberships, nil
+}
+
+// accessCreators returns the roles that create objects in the managed
+// schemas and so carry the default privileges: the Owner role and every Owner
+// principal.
+func accessCreators(roles managedAccessRoles, principals []AccessPrincipal) []string {
+	creators := []string{roles.Owner}
+	for _, principal := range principals {
+		principal = normalizeAccessPrincipal(principal)
+		if principal.Role == AccessRoleOwner {
+			creators = append(creators, principal.Name)
+		}
+	}
+	return creators
 }
 
 func accessPrincipalsFromEnv(disableAAD, serverDebug bool) ([]AccessPrincipal, error) {
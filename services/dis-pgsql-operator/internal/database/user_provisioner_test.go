@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
@@ -502,3 +503,41 @@ func TestAccessPrincipalsFromEnvServerDebug(t *testing.T) {
 		t.Fatalf("expected empty payload to be rejected outside server debug mode")
 	}
 }
+
+func TestRunUserProvisionerRejectsInvalidModes(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "no mode", want: ProvisionModeEnv},
+		{name: "unknown mode", env: map[string]string{ProvisionModeEnv: "drop"}, want: ProvisionModeEnv},
+		{
+			name: "migration without phase",
+			env:  map[string]string{ProvisionModeEnv: string(ProvisionModeMigration)},
+			want: MigrationPhaseEnv,
+		},
+		{
+			name: "backup without phase",
+			env:  map[string]string{ProvisionModeEnv: string(ProvisionModeBackup)},
+			want: BackupPhaseEnv,
+		},
+		{
+			name: "server debug access without built-in roles",
+			env:  map[string]string{ProvisionModeEnv: string(ProvisionModeServerDebugAccess)},
+			want: DebugBuiltinRolesEnv,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(ProvisionModeEnv, "")
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			err := RunUserProvisioner(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected an error about %s, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package keyvault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	moduleName    = "dis-pgsql-operator/keyvault"
+	moduleVersion = "v0.1.0"
+
+	// keysAPIVersion is the Microsoft.KeyVault API version used for keys.
+	keysAPIVersion = "2023-07-01"
+
+	// keySize is the RSA key size created for server encryption. Azure
+	// Database for PostgreSQL accepts RSA keys of 2048, 3072 or 4096 bits.
+	keySize = 3072
+)
+
+// ErrKeyNotUsable is returned when a key exists but cannot encrypt data,
+// because it is disabled or expired.
+var ErrKeyNotUsable = errors.New("key is not usable")
+
+// Key is a Key Vault key as seen through Azure Resource Manager.
+type Key struct {
+	// URI is the versionless key URI. A server configured with it follows
+	// new versions of the key.
+	URI string
+	// Version is the current version of the key.
+	Version string
+}
+
+// KeyClient creates and reads Key Vault keys.
+type KeyClient interface {
+	// EnsureKey returns the key named keyName in the vault, creating an RSA
+	// key when the vault has none. It returns ErrKeyNotUsable when the key
+	// is disabled or expired.
+	EnsureKey(ctx context.Context, vaultResourceID, keyName string) (Key, error)
+}
+
+// ARMKeyClient manages keys through the Key Vault resource provider, so it
+// needs Microsoft.KeyVault/vaults/keys permissions on the vault rather than
+// data plane access.
+type ARMKeyClient struct {
+	client *arm.Client
+	now    func() time.Time
+}
+
+// NewARMKeyClient creates a KeyClient that authenticates with cred.
+func NewARMKeyClient(cred azcore.TokenCredential, options *arm.ClientOptions) (*ARMKeyClient, error) {
+	client, err := arm.NewClient(moduleName, moduleVersion, cred, options)
+	if err != nil {
+		return nil, fmt.Errorf("create key vault ARM client: %w", err)
+	}
+	return &ARMKeyClient{client: client, now: time.Now}, nil
+}
+
+type keyResource struct {
+	Properties keyProperties `json:"properties"`
+}
+
+type keyProperties struct {
+	Attributes        *keyAttributes `json:"attributes,omitempty"`
+	Kty               string         `json:"kty,omitempty"`
+	KeySize           int            `json:"keySize,omitempty"`
+	KeyOps            []string       `json:"keyOps,omitempty"`
+	KeyURI            string         `json:"keyUri,omitempty"`
+	KeyURIWithVersion string         `json:"keyUriWithVersion,omitempty"`
+}
+
+type keyAttributes struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Expires *int64 `json:"exp,omitempty"`
+}
+
+// EnsureKey implements KeyClient.
+func (c *ARMKeyClient) EnsureKey(ctx context.Context, vaultResourceID, keyName string) (Key, error) {
+	endpoint := runtime.JoinPaths(c.client.Endpoint(), strings.TrimSpace(vaultResourceID), "keys", keyName)
+
+	resource, found, err := c.do(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Key{}, fmt.Errorf("get key %q: %w", keyName, err)
+	}
+	if !found {
+		// The keys PUT only creates a key when none exists, so a concurrent
+		// create cannot replace a key that is already in use.
+		resource, _, err = c.do(ctx, http.MethodPut, endpoint, &keyResource{Properties: keyProperties{
+			Kty:     "RSA",
+			KeySize: keySize,
+			KeyOps:  []string{"wrapKey", "unwrapKey"},
+		}})
+		if err != nil {
+			return Key{}, fmt.Errorf("create key %q: %w", keyName, err)
+		}
+	}
+	return keyFromResource(keyName, resource, c.now())
+}
+
+func (c *ARMKeyClient) do(ctx context.Context, method, endpoint string, body any) (keyResource, bool, error) {
+	req, err := runtime.NewRequest(ctx, method, endpoint)
+	if err != nil {
+		return keyResource{}, false, err
+	}
+	query := req.Raw().URL.Query()
+	query.Set("api-version", keysAPIVersion)
+	req.Raw().URL.RawQuery = query.Encode()
+	req.Raw().Header.Set("Accept", "application/json")
+	if body != nil {
+		if err := runtime.MarshalAsJSON(req, body); err != nil {
+			return keyResource{}, false, err
+		}
+	}
+
+	resp, err := c.client.Pipeline().Do(req)
+	if err != nil {
+		return keyResource{}, false, err
+	}
+	if method == http.MethodGet && resp.StatusCode == http.StatusNotFound {
+		runtime.Drain(resp)
+		return keyResource{}, false, nil
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK, http.StatusCreated) {
+		return keyResource{}, false, runtime.NewResponseError(resp)
+	}
+
+	var resource keyResource
+	if err := runtime.UnmarshalAsJSON(resp, &resource); err != nil {
+		return keyResource{}, false, err
+	}
+	return resource, true, nil
+}
+
+func keyFromResource(keyName string, resource keyResource, now time.Time) (Key, error) {
+	props := resource.Properties
+	if attrs := props.Attributes; attrs != nil {
+		if attrs.Enabled != nil && !*attrs.Enabled {
+			return Key{}, fmt.Errorf("%w: key %q is disabled", ErrKeyNotUsable, keyName)
+		}
+		if attrs.Expires != nil && !now.Before(time.Unix(*attrs.Expires, 0)) {
+			return Key{}, fmt.Errorf("%w: key %q expired at %s", ErrKeyNotUsable, keyName, time.Unix(*attrs.Expires, 0).UTC().Format(time.RFC3339))
+		}
+	}
+
+	version := props.KeyURIWithVersion[strings.LastIndex(props.KeyURIWithVersion, "/")+1:]
+	if props.KeyURI == "" || version == "" {
+		return Key{}, fmt.Errorf("key %q has no key URI", keyName)
+	}
+	return Key{URI: props.KeyURI, Version: version}, nil
+}
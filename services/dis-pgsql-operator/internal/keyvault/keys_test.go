@@ -0,0 +1,148 @@
+package keyvault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const testVaultID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/app-kv"
+
+// fakeTransport answers ARM requests from a list of canned responses and
+// records the requests it saw.
+type fakeTransport struct {
+	responses []*http.Response
+	requests  []*http.Request
+	bodies    []string
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		raw, _ := io.ReadAll(req.Body)
+		body = string(raw)
+	}
+	f.requests = append(f.requests, req)
+	f.bodies = append(f.bodies, body)
+	if len(f.responses) == 0 {
+		return nil, errors.New("unexpected request")
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	resp.Request = req
+	return resp, nil
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func newTestClient(t *testing.T, transport *fakeTransport, now time.Time) *ARMKeyClient {
+	t.Helper()
+	client, err := NewARMKeyClient(&fake.TokenCredential{}, &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+		Transport: transport,
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}})
+	if err != nil {
+		t.Fatalf("NewARMKeyClient: %v", err)
+	}
+	client.now = func() time.Time { return now }
+	return client
+}
+
+func keyJSON(enabled bool, expires int64) string {
+	attributes := map[string]any{"enabled": enabled}
+	if expires > 0 {
+		attributes["exp"] = expires
+	}
+	raw, _ := json.Marshal(map[string]any{"properties": map[string]any{
+		"attributes":        attributes,
+		"keyUri":            "https://app-kv.vault.azure.net/keys/pg-cmk",
+		"keyUriWithVersion": "https://app-kv.vault.azure.net/keys/pg-cmk/0123abcd",
+	}})
+	return string(raw)
+}
+
+func TestEnsureKeyReturnsExistingKey(t *testing.T) {
+	transport := &fakeTransport{responses: []*http.Response{jsonResponse(http.StatusOK, keyJSON(true, 0))}}
+	client := newTestClient(t, transport, time.Now())
+
+	key, err := client.EnsureKey(context.Background(), testVaultID, "pg-cmk")
+	if err != nil {
+		t.Fatalf("EnsureKey: %v", err)
+	}
+	if key.URI != "https://app-kv.vault.azure.net/keys/pg-cmk" || key.Version != "0123abcd" {
+		t.Fatalf("unexpected key %#v", key)
+	}
+	if len(transport.requests) != 1 || transport.requests[0].Method != http.MethodGet {
+		t.Fatalf("expected a single GET, got %d requests", len(transport.requests))
+	}
+	req := transport.requests[0]
+	if req.URL.Path != testVaultID+"/keys/pg-cmk" || req.URL.Query().Get("api-version") != keysAPIVersion {
+		t.Fatalf("unexpected request URL %s", req.URL)
+	}
+}
+
+func TestEnsureKeyCreatesMissingKey(t *testing.T) {
+	transport := &fakeTransport{responses: []*http.Response{
+		jsonResponse(http.StatusNotFound, `{"error":{"code":"NotFound"}}`),
+		jsonResponse(http.StatusOK, keyJSON(true, 0)),
+	}}
+	client := newTestClient(t, transport, time.Now())
+
+	if _, err := client.EnsureKey(context.Background(), testVaultID, "pg-cmk"); err != nil {
+		t.Fatalf("EnsureKey: %v", err)
+	}
+	if len(transport.requests) != 2 || transport.requests[1].Method != http.MethodPut {
+		t.Fatalf("expected GET then PUT, got %d requests", len(transport.requests))
+	}
+	var body keyResource
+	if err := json.Unmarshal([]byte(transport.bodies[1]), &body); err != nil {
+		t.Fatalf("decode PUT body: %v", err)
+	}
+	if body.Properties.Kty != "RSA" || body.Properties.KeySize != keySize {
+		t.Fatalf("expected an RSA %d key, got %#v", keySize, body.Properties)
+	}
+}
+
+func TestEnsureKeyRejectsUnusableKeys(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	for name, body := range map[string]string{
+		"disabled": keyJSON(false, 0),
+		"expired":  keyJSON(true, now.Add(-time.Hour).Unix()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			transport := &fakeTransport{responses: []*http.Response{jsonResponse(http.StatusOK, body)}}
+			client := newTestClient(t, transport, now)
+
+			if _, err := client.EnsureKey(context.Background(), testVaultID, "pg-cmk"); !errors.Is(err, ErrKeyNotUsable) {
+				t.Fatalf("expected ErrKeyNotUsable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureKeyReportsAccessErrors(t *testing.T) {
+	transport := &fakeTransport{responses: []*http.Response{
+		jsonResponse(http.StatusForbidden, `{"error":{"code":"AuthorizationFailed","message":"denied"}}`),
+	}}
+	client := newTestClient(t, transport, time.Now())
+
+	_, err := client.EnsureKey(context.Background(), testVaultID, "pg-cmk")
+	if err == nil || !strings.Contains(err.Error(), "AuthorizationFailed") {
+		t.Fatalf("expected the ARM error code in the error, got %v", err)
+	}
+}
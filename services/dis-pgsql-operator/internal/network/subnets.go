@@ -89,6 +89,23 @@ func (c *SubnetCatalog) FirstFreeSubnet(used []string) (SubnetInfo, error) {
 	return SubnetInfo{}, ErrNoFreeSubnets
 }
 
+// Usage returns how many catalog subnets are free and how many are in used.
+// CIDRs in used that are not in the catalog are ignored.
+func (c *SubnetCatalog) Usage(used []string) (int, int) {
+	usedSet := make(map[string]struct{}, len(used))
+	for _, u := range used {
+		usedSet[u] = struct{}{}
+	}
+
+	inUse := 0
+	for _, s := range c.subnets {
+		if _, taken := usedSet[s.CIDR]; taken {
+			inUse++
+		}
+	}
+	return len(c.subnets) - inUse, inUse
+}
+
 // All returns a copy of all subnets in the catalog, in the catalog's order.
 func (c *SubnetCatalog) All() []SubnetInfo {
 	out := make([]SubnetInfo, len(c.subnets))
@@ -121,3 +121,21 @@ func TestFirstFreeSubnet_AllUsed(t *testing.T) {
 		t.Fatalf("expected error when all subnets are used, got nil")
 	}
 }
+
+func TestUsage_IgnoresUnknownCIDRs(t *testing.T) {
+	input := []SubnetInfo{
+		{Name: "s1", CIDR: cidr0},
+		{Name: "s2", CIDR: cidr16},
+		{Name: "s3", CIDR: cidr32},
+	}
+
+	catalog, err := NewSubnetCatalog(input)
+	if err != nil {
+		t.Fatalf("NewSubnetCatalog returned error: %v", err)
+	}
+
+	free, used := catalog.Usage([]string{cidr16, cidr16, "10.200.0.0/28", ""})
+	if free != 2 || used != 1 {
+		t.Fatalf("expected 2 free and 1 used, got %d free and %d used", free, used)
+	}
+}
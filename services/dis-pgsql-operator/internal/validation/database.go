@@ -0,0 +1,773 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+)
+
+// Reasons reported in Database status.validationErrors.
+const (
+	ReasonRequired    = "Required"
+	ReasonUnsupported = "Unsupported"
+	ReasonInvalid     = "Invalid"
+	ReasonConflict    = "Conflict"
+	ReasonImmutable   = "Immutable"
+)
+
+// Database fields reported in status.validationErrors.
+const (
+	FieldMetadataName        = "metadata.name"
+	FieldSpecName            = "spec.name"
+	FieldServerName          = "spec.server.name"
+	FieldServerSelector      = "spec.serverSelector"
+	FieldAccessPrincipals    = "spec.access.principals"
+	FieldDeletionPolicy      = "spec.deletionPolicy"
+	FieldDeletionGracePeriod = "spec.deletionGracePeriod"
+	FieldScheduleCron        = "spec.schedule.cron"
+	FieldScheduleContainer   = "spec.schedule.containerURL"
+	FieldScheduleRetention   = "spec.schedule.retentionDays"
+	FieldSchemaMigrations    = "spec.schemaMigrations"
+)
+
+const (
+	MaxDatabaseNameLength  = 63
+	MaxPrincipalNameLength = 63
+	MaxIdentifierLength    = 63
+)
+
+var entraPrincipalIDPattern = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// cronFieldPattern matches one field of a five-field cron schedule. The
+// CronJob API does the full check; this catches the common mistakes, such as
+// a seconds field or a time zone prefix, before the CronJob is written.
+var cronFieldPattern = regexp.MustCompile(`^[0-9A-Za-z*?/,-]+$`)
+
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// AppendDatabaseError adds a validation error unless field already has one,
+// so the first problem found for a field is the one reported.
+func AppendDatabaseError(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	field, reason, message string,
+) []storagev1alpha1.DatabaseValidationError {
+	for i := range validationErrors {
+		if validationErrors[i].Field == field {
+			return validationErrors
+		}
+	}
+
+	return append(validationErrors, storagev1alpha1.DatabaseValidationError{
+		Field:   field,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// Database checks the parts of a Database spec that need no other resources.
+// The admission webhook rejects these up front; the reconciler repeats them
+// and reports them in status.validationErrors when the webhook is disabled.
+func Database(database *storagev1alpha1.Database) []storagev1alpha1.DatabaseValidationError {
+	var validationErrors []storagev1alpha1.DatabaseValidationError
+	serverName := strings.TrimSpace(database.Spec.Server.Name)
+
+	addRequiredStringError := func(field, value string) {
+		if strings.TrimSpace(value) != "" {
+			return
+		}
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			field,
+			ReasonRequired,
+			fmt.Sprintf("%s must be set", field),
+		)
+	}
+
+	if database.Spec.ServerSelector == nil {
+		addRequiredStringError(FieldServerName, database.Spec.Server.Name)
+	} else {
+		validationErrors = databaseServerSelector(validationErrors, database)
+	}
+	addRequiredStringError(FieldMetadataName, database.Name)
+	addRequiredStringError(FieldSpecName, database.Spec.Name)
+	validationErrors = databaseAccess(validationErrors, database)
+
+	if database.Spec.Name != "" && strings.TrimSpace(database.Spec.Name) != database.Spec.Name {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldSpecName,
+			ReasonInvalid,
+			"spec.name must not have leading or trailing whitespace",
+		)
+	}
+
+	if len(database.Spec.Name) > MaxDatabaseNameLength {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldSpecName,
+			ReasonInvalid,
+			fmt.Sprintf("spec.name must be at most %d characters", MaxDatabaseNameLength),
+		)
+	}
+
+	if database.Spec.Server.Name != "" && serverName != database.Spec.Server.Name {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldServerName,
+			ReasonInvalid,
+			"spec.server.name must not have leading or trailing whitespace",
+		)
+	}
+
+	switch database.Spec.DeletionPolicy {
+	case "", storagev1alpha1.DatabaseDeletionPolicyRetain, storagev1alpha1.DatabaseDeletionPolicyDelete:
+	default:
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldDeletionPolicy,
+			ReasonUnsupported,
+			"spec.deletionPolicy must be Retain or Delete",
+		)
+	}
+
+	if database.Spec.DeletionGracePeriod != nil && database.Spec.DeletionGracePeriod.Duration < 0 {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldDeletionGracePeriod,
+			ReasonInvalid,
+			"spec.deletionGracePeriod must not be negative",
+		)
+	}
+
+	if database.Spec.Schedule != nil {
+		validationErrors = databaseSchedule(validationErrors, database.Spec.Schedule)
+	}
+
+	if database.Spec.SchemaMigrations != nil {
+		validationErrors = databaseSchemaMigrations(validationErrors, database)
+	}
+
+	return validationErrors
+}
+
+// databaseSchemaMigrations checks that the schema migrations have exactly
+// one source and run as an Owner principal of the Database.
+func databaseSchemaMigrations(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	database *storagev1alpha1.Database,
+) []storagev1alpha1.DatabaseValidationError {
+	migrations := database.Spec.SchemaMigrations
+	image := strings.TrimSpace(migrations.Image)
+	switch {
+	case (image == "") == (migrations.ConfigMap == nil):
+		return AppendDatabaseError(
+			validationErrors,
+			FieldSchemaMigrations,
+			ReasonInvalid,
+			"set exactly one of spec.schemaMigrations.image and spec.schemaMigrations.configMap",
+		)
+	case migrations.ConfigMap != nil && migrations.Path != "":
+		return AppendDatabaseError(
+			validationErrors,
+			FieldSchemaMigrations,
+			ReasonInvalid,
+			"spec.schemaMigrations.path is only used with spec.schemaMigrations.image",
+		)
+	case migrations.Path != "" && !strings.HasPrefix(migrations.Path, "/"):
+		return AppendDatabaseError(
+			validationErrors,
+			FieldSchemaMigrations,
+			ReasonInvalid,
+			fmt.Sprintf("spec.schemaMigrations.path %q must be an absolute path", migrations.Path),
+		)
+	case migrations.ConfigMap != nil && len(k8svalidation.IsDNS1123Subdomain(migrations.ConfigMap.Name)) > 0:
+		return AppendDatabaseError(
+			validationErrors,
+			FieldSchemaMigrations,
+			ReasonInvalid,
+			fmt.Sprintf("spec.schemaMigrations.configMap.name %q is not a valid ConfigMap name", migrations.ConfigMap.Name),
+		)
+	case !schemaMigrationsOwner(database, strings.TrimSpace(migrations.IdentityRef.Name)):
+		return AppendDatabaseError(
+			validationErrors,
+			FieldSchemaMigrations,
+			ReasonInvalid,
+			fmt.Sprintf("spec.schemaMigrations.identityRef.name %q must be the identityRef of an Owner principal in the Database's namespace", migrations.IdentityRef.Name),
+		)
+	}
+	return validationErrors
+}
+
+// schemaMigrationsOwner reports whether name is the identityRef of an Owner
+// principal in the Database's namespace.
+func schemaMigrationsOwner(database *storagev1alpha1.Database, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, principal := range database.Spec.Access.Principals {
+		ref := principal.IdentityRef
+		if principal.Role != storagev1alpha1.DatabaseAccessRoleOwner || ref == nil || strings.TrimSpace(ref.Name) != name {
+			continue
+		}
+		if namespace := strings.TrimSpace(ref.Namespace); namespace == "" || namespace == database.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// DatabaseScheduleStorage checks spec.schedule.containerURL against the
+// operator's configuration. The upload authenticates with the admin
+// identity, so the container must be in one of storageAccounts. http:// and
+// file:// URLs are only accepted with allowLocal, in local clusters. URLs
+// that Database already rejects are skipped.
+func DatabaseScheduleStorage(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	schedule *storagev1alpha1.DatabaseBackupScheduleSpec,
+	storageAccounts []string,
+	allowLocal bool,
+) []storagev1alpha1.DatabaseValidationError {
+	containerURL, err := url.Parse(strings.TrimSpace(schedule.ContainerURL))
+	if err != nil || containerURL.Path == "" || containerURL.Path == "/" || containerURL.RawQuery != "" {
+		return validationErrors
+	}
+	switch containerURL.Scheme {
+	case "http", "file":
+		if allowLocal {
+			return validationErrors
+		}
+		return AppendDatabaseError(
+			validationErrors,
+			FieldScheduleContainer,
+			ReasonUnsupported,
+			"spec.schedule.containerURL must be an https:// Blob Storage URL; http:// and file:// are only supported in local clusters",
+		)
+	case "https":
+		account, ok := dbUtil.BlobStorageAccount(containerURL)
+		if !ok {
+			return AppendDatabaseError(
+				validationErrors,
+				FieldScheduleContainer,
+				ReasonInvalid,
+				"spec.schedule.containerURL must be a Blob Storage container, such as https://account.blob.core.windows.net/backups",
+			)
+		}
+		if !slices.ContainsFunc(storageAccounts, func(allowed string) bool { return strings.EqualFold(allowed, account) }) {
+			return AppendDatabaseError(
+				validationErrors,
+				FieldScheduleContainer,
+				ReasonUnsupported,
+				fmt.Sprintf("storage account %q is not one the operator uploads backups to", account),
+			)
+		}
+	}
+	return validationErrors
+}
+
+// databaseSchedule checks the backup schedule.
+func databaseSchedule(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	schedule *storagev1alpha1.DatabaseBackupScheduleSpec,
+) []storagev1alpha1.DatabaseValidationError {
+	if !validCron(schedule.Cron) {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldScheduleCron,
+			ReasonInvalid,
+			fmt.Sprintf("spec.schedule.cron %q must be five cron fields, such as \"0 2 * * *\", or a macro such as @daily", schedule.Cron),
+		)
+	}
+
+	containerURL, err := url.Parse(strings.TrimSpace(schedule.ContainerURL))
+	switch {
+	case err != nil || containerURL.Path == "" || containerURL.Path == "/":
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldScheduleContainer,
+			ReasonInvalid,
+			"spec.schedule.containerURL must name a container, such as https://account.blob.core.windows.net/backups",
+		)
+	case containerURL.Scheme != "https" && containerURL.Scheme != "http" && containerURL.Scheme != "file":
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldScheduleContainer,
+			ReasonUnsupported,
+			"spec.schedule.containerURL must be an https://, http:// or file:// URL",
+		)
+	case containerURL.RawQuery != "":
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldScheduleContainer,
+			ReasonInvalid,
+			"spec.schedule.containerURL must not have a query; the admin identity authenticates, not a SAS token",
+		)
+	}
+
+	if schedule.RetentionDays != nil && *schedule.RetentionDays < 1 {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldScheduleRetention,
+			ReasonInvalid,
+			"spec.schedule.retentionDays must be at least 1",
+		)
+	}
+	return validationErrors
+}
+
+func validCron(cron string) bool {
+	fields := strings.Fields(cron)
+	if len(fields) == 1 {
+		return slices.Contains(cronMacros, fields[0])
+	}
+	if len(fields) != 5 {
+		return false
+	}
+	for _, cronField := range fields {
+		if !cronFieldPattern.MatchString(cronField) {
+			return false
+		}
+	}
+	return true
+}
+
+// databaseServerSelector checks that spec.serverSelector is the only way the
+// server is chosen and that its labels are valid.
+func databaseServerSelector(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	database *storagev1alpha1.Database,
+) []storagev1alpha1.DatabaseValidationError {
+	if database.Spec.Server.Name != "" {
+		return AppendDatabaseError(
+			validationErrors,
+			FieldServerSelector,
+			ReasonInvalid,
+			"set only one of spec.server.name and spec.serverSelector",
+		)
+	}
+
+	keys := make([]string, 0, len(database.Spec.ServerSelector.MatchLabels))
+	for key := range database.Spec.ServerSelector.MatchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		problems := k8svalidation.IsQualifiedName(key)
+		problems = append(problems, k8svalidation.IsValidLabelValue(database.Spec.ServerSelector.MatchLabels[key])...)
+		if len(problems) > 0 {
+			return AppendDatabaseError(
+				validationErrors,
+				FieldServerSelector,
+				ReasonInvalid,
+				fmt.Sprintf("spec.serverSelector.matchLabels[%s] is invalid: %s", key, strings.Join(problems, "; ")),
+			)
+		}
+	}
+	return validationErrors
+}
+
+// DatabaseUpdate checks the fields of a Database that cannot change once it
+// exists. spec.name is the PostgreSQL database, so renaming it would orphan
+// the existing database.
+func DatabaseUpdate(oldDatabase, newDatabase *storagev1alpha1.Database) []storagev1alpha1.DatabaseValidationError {
+	var validationErrors []storagev1alpha1.DatabaseValidationError
+	if oldDatabase.Spec.Name != "" && oldDatabase.Spec.Name != newDatabase.Spec.Name {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			FieldSpecName,
+			ReasonImmutable,
+			fmt.Sprintf("spec.name cannot change from %q to %q; recreate Database to use a new name", oldDatabase.Spec.Name, newDatabase.Spec.Name),
+		)
+	}
+	return validationErrors
+}
+
+// DatabaseFieldErrors converts Database validation errors to field errors for
+// an admission response.
+func DatabaseFieldErrors(validationErrors []storagev1alpha1.DatabaseValidationError) field.ErrorList {
+	errs := make(field.ErrorList, 0, len(validationErrors))
+	for _, validationError := range validationErrors {
+		errorType := field.ErrorTypeInvalid
+		switch validationError.Reason {
+		case ReasonRequired:
+			errorType = field.ErrorTypeRequired
+		case ReasonUnsupported:
+			errorType = field.ErrorTypeNotSupported
+		case ReasonConflict:
+			errorType = field.ErrorTypeDuplicate
+		case ReasonImmutable:
+			errorType = field.ErrorTypeForbidden
+		}
+		errs = append(errs, &field.Error{
+			Type:     errorType,
+			Field:    validationError.Field,
+			BadValue: field.OmitValueType{},
+			Detail:   validationError.Message,
+		})
+	}
+	return errs
+}
+
+func databaseAccess(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	database *storagev1alpha1.Database,
+) []storagev1alpha1.DatabaseValidationError {
+	if len(database.Spec.Access.Principals) == 0 {
+		return AppendDatabaseError(
+			validationErrors,
+			FieldAccessPrincipals,
+			ReasonRequired,
+			"spec.access.principals must contain at least one principal",
+		)
+	}
+
+	seen := map[string]string{}
+	for i, principal := range database.Spec.Access.Principals {
+		field := func(suffix string) string {
+			if suffix == "" {
+				return fmt.Sprintf("spec.access.principals[%d]", i)
+			}
+			return fmt.Sprintf("spec.access.principals[%d].%s", i, suffix)
+		}
+
+		roleField := field("role")
+		switch principal.Role {
+		case storagev1alpha1.DatabaseAccessRoleReader,
+			storagev1alpha1.DatabaseAccessRoleWriter,
+			storagev1alpha1.DatabaseAccessRoleOwner:
+		case "":
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				roleField,
+				ReasonRequired,
+				"role must be set",
+			)
+		default:
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				roleField,
+				ReasonInvalid,
+				"role must be one of Reader, Writer, or Owner",
+			)
+		}
+
+		hasIdentityRef := principal.IdentityRef != nil
+		hasGroup := principal.Group != nil
+		hasServicePrincipal := principal.ServicePrincipal != nil
+		sourceCount := 0
+		for _, set := range []bool{hasIdentityRef, hasGroup, hasServicePrincipal} {
+			if set {
+				sourceCount++
+			}
+		}
+		if sourceCount != 1 {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				field(""),
+				ReasonInvalid,
+				"exactly one principal source must be set: identityRef, group, or servicePrincipal",
+			)
+			continue
+		}
+
+		var principalKey string
+		if hasIdentityRef {
+			refName := principal.IdentityRef.Name
+			refField := field("identityRef.name")
+			validationErrors = accessName(validationErrors, refField, refName, MaxPrincipalNameLength)
+			if refName != "" {
+				for _, msg := range k8svalidation.IsDNS1123Subdomain(refName) {
+					validationErrors = AppendDatabaseError(
+						validationErrors,
+						refField,
+						ReasonInvalid,
+						fmt.Sprintf("identityRef.name must be a valid Kubernetes name: %s", msg),
+					)
+				}
+				principalKey = "identityRef:" + refName
+			}
+
+			refNamespace := principal.IdentityRef.Namespace
+			if refNamespace != "" && refNamespace != database.Namespace {
+				namespaceField := field("identityRef.namespace")
+				for _, msg := range k8svalidation.IsDNS1123Label(refNamespace) {
+					validationErrors = AppendDatabaseError(
+						validationErrors,
+						namespaceField,
+						ReasonInvalid,
+						fmt.Sprintf("identityRef.namespace must be a valid namespace name: %s", msg),
+					)
+				}
+				if principal.Role != storagev1alpha1.DatabaseAccessRoleReader {
+					validationErrors = AppendDatabaseError(
+						validationErrors,
+						namespaceField,
+						ReasonUnsupported,
+						"identities in another namespace can only get the Reader role",
+					)
+				}
+				if refName != "" {
+					principalKey = "identityRef:" + refNamespace + "/" + refName
+				}
+			}
+		}
+
+		if hasGroup {
+			groupName := principal.Group.Name
+			groupPrincipalID := principal.Group.PrincipalId
+			groupNameField := field("group.name")
+			groupPrincipalIDField := field("group.principalId")
+
+			validationErrors = accessName(validationErrors, groupNameField, groupName, MaxPrincipalNameLength)
+			if strings.TrimSpace(groupPrincipalID) == "" {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					groupPrincipalIDField,
+					ReasonRequired,
+					"group.principalId must be set",
+				)
+			} else if groupPrincipalID != strings.TrimSpace(groupPrincipalID) {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					groupPrincipalIDField,
+					ReasonInvalid,
+					"group.principalId must not have leading or trailing whitespace",
+				)
+			} else if !entraPrincipalIDPattern.MatchString(groupPrincipalID) {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					groupPrincipalIDField,
+					ReasonInvalid,
+					"group.principalId must be an Entra object ID GUID",
+				)
+			}
+			if groupPrincipalID != "" {
+				principalKey = "group:" + strings.ToLower(groupPrincipalID)
+			} else if groupName != "" {
+				principalKey = "group-name:" + groupName
+			}
+		}
+
+		if hasServicePrincipal {
+			servicePrincipalName := principal.ServicePrincipal.Name
+			servicePrincipalID := principal.ServicePrincipal.PrincipalId
+			servicePrincipalNameField := field("servicePrincipal.name")
+			servicePrincipalIDField := field("servicePrincipal.principalId")
+
+			validationErrors = accessName(validationErrors, servicePrincipalNameField, servicePrincipalName, MaxPrincipalNameLength)
+			if strings.TrimSpace(servicePrincipalID) == "" {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					servicePrincipalIDField,
+					ReasonRequired,
+					"servicePrincipal.principalId must be set",
+				)
+			} else if servicePrincipalID != strings.TrimSpace(servicePrincipalID) {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					servicePrincipalIDField,
+					ReasonInvalid,
+					"servicePrincipal.principalId must not have leading or trailing whitespace",
+				)
+			} else if !entraPrincipalIDPattern.MatchString(servicePrincipalID) {
+				validationErrors = AppendDatabaseError(
+					validationErrors,
+					servicePrincipalIDField,
+					ReasonInvalid,
+					"servicePrincipal.principalId must be an Entra object ID GUID",
+				)
+			}
+			if servicePrincipalID != "" {
+				principalKey = "servicePrincipal:" + strings.ToLower(servicePrincipalID)
+			} else if servicePrincipalName != "" {
+				principalKey = "servicePrincipal-name:" + servicePrincipalName
+			}
+		}
+
+		if principal.Scope != nil {
+			validationErrors = accessScope(validationErrors, field, principal.Role, principal.Scope, database.Spec.Name)
+		}
+		validationErrors = accessSettings(validationErrors, field, principal)
+
+		if principalKey == "" {
+			continue
+		}
+		if firstField, ok := seen[principalKey]; ok {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				field(""),
+				ReasonConflict,
+				fmt.Sprintf("principal duplicates %s", firstField),
+			)
+			continue
+		}
+		seen[principalKey] = field("")
+	}
+
+	return validationErrors
+}
+
+// accessScope checks the scope of one principal. schemaName is the database's
+// own schema, which every role already covers.
+func accessScope(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	field func(string) string,
+	role storagev1alpha1.DatabaseAccessRole,
+	scope *storagev1alpha1.DatabaseAccessScope,
+	schemaName string,
+) []storagev1alpha1.DatabaseValidationError {
+	if len(scope.Tables) > 0 {
+		if role != storagev1alpha1.DatabaseAccessRoleReader {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				field("scope.tables"),
+				ReasonUnsupported,
+				"scope.tables is only supported with the Reader role",
+			)
+		}
+		if len(scope.Schemas) > 0 || scope.ExecuteFunctions {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				field("scope"),
+				ReasonInvalid,
+				"scope.tables cannot be combined with scope.schemas or scope.executeFunctions",
+			)
+		}
+	}
+
+	seenSchemas := map[string]string{}
+	for j, schema := range scope.Schemas {
+		schemaField := field(fmt.Sprintf("scope.schemas[%d]", j))
+		validationErrors = accessName(validationErrors, schemaField, schema, MaxIdentifierLength)
+		switch {
+		case reservedSchema(schema):
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				schemaField,
+				ReasonInvalid,
+				fmt.Sprintf("schema %q is reserved by PostgreSQL", schema),
+			)
+		case schema == schemaName:
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				schemaField,
+				ReasonInvalid,
+				"the database's own schema is always covered and must not be listed",
+			)
+		}
+		if firstField, ok := seenSchemas[schema]; ok {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				schemaField,
+				ReasonConflict,
+				fmt.Sprintf("schema duplicates %s", firstField),
+			)
+			continue
+		}
+		seenSchemas[schema] = schemaField
+	}
+
+	for j, table := range scope.Tables {
+		tableField := field(fmt.Sprintf("scope.tables[%d]", j))
+		validationErrors = accessName(validationErrors, tableField+".name", table.Name, MaxIdentifierLength)
+		if table.Schema != "" {
+			validationErrors = accessName(validationErrors, tableField+".schema", table.Schema, MaxIdentifierLength)
+		}
+	}
+	return validationErrors
+}
+
+// accessSettings checks the connection limit and timeouts of one principal.
+// PostgreSQL takes the timeouts in whole milliseconds, as a 32-bit integer.
+func accessSettings(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	field func(string) string,
+	principal storagev1alpha1.DatabaseAccessPrincipalSpec,
+) []storagev1alpha1.DatabaseValidationError {
+	if principal.ConnectionLimit != nil && *principal.ConnectionLimit < 1 {
+		validationErrors = AppendDatabaseError(
+			validationErrors,
+			field("connectionLimit"),
+			ReasonInvalid,
+			"connectionLimit must be at least 1",
+		)
+	}
+	for _, timeout := range []struct {
+		name  string
+		value *metav1.Duration
+	}{
+		{name: "statementTimeout", value: principal.StatementTimeout},
+		{name: "idleInTransactionSessionTimeout", value: principal.IdleInTransactionSessionTimeout},
+	} {
+		if timeout.value == nil {
+			continue
+		}
+		if timeout.value.Duration < time.Millisecond || timeout.value.Milliseconds() > math.MaxInt32 {
+			validationErrors = AppendDatabaseError(
+				validationErrors,
+				field(timeout.name),
+				ReasonInvalid,
+				fmt.Sprintf("%s must be between 1ms and %s", timeout.name, time.Duration(math.MaxInt32)*time.Millisecond),
+			)
+		}
+	}
+	return validationErrors
+}
+
+// reservedSchema reports whether schema belongs to PostgreSQL itself.
+func reservedSchema(schema string) bool {
+	lower := strings.ToLower(schema)
+	return strings.HasPrefix(lower, "pg_") || lower == "information_schema"
+}
+
+func accessName(
+	validationErrors []storagev1alpha1.DatabaseValidationError,
+	field, value string,
+	maxLength int,
+) []storagev1alpha1.DatabaseValidationError {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return AppendDatabaseError(
+			validationErrors,
+			field,
+			ReasonRequired,
+			fmt.Sprintf("%s must be set", field),
+		)
+	}
+	if trimmed != value {
+		return AppendDatabaseError(
+			validationErrors,
+			field,
+			ReasonInvalid,
+			fmt.Sprintf("%s must not have leading or trailing whitespace", field),
+		)
+	}
+	if strings.ContainsRune(value, 0) {
+		return AppendDatabaseError(
+			validationErrors,
+			field,
+			ReasonInvalid,
+			fmt.Sprintf("%s must not contain NUL bytes", field),
+		)
+	}
+	if len(value) > maxLength {
+		return AppendDatabaseError(
+			validationErrors,
+			field,
+			ReasonInvalid,
+			fmt.Sprintf("%s must be at most %d characters", field, maxLength),
+		)
+	}
+	return validationErrors
+}
@@ -0,0 +1,429 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func testDatabase() *storagev1alpha1.Database {
+	database := &storagev1alpha1.Database{
+		Spec: storagev1alpha1.DatabaseSpec{
+			Name:   "appdb",
+			Server: storagev1alpha1.DatabaseServerReference{Name: "shared"},
+			Access: storagev1alpha1.DatabaseAccessSpec{
+				Principals: []storagev1alpha1.DatabaseAccessPrincipalSpec{
+					{Role: storagev1alpha1.DatabaseAccessRoleOwner, IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "app"}},
+				},
+			},
+		},
+	}
+	database.Name = "appdb"
+	return database
+}
+
+func TestDatabase(t *testing.T) {
+	t.Run("accepts a valid database", func(t *testing.T) {
+		if errs := Database(testDatabase()); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("rejects conflicting principal sources", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals[0].Group = &storagev1alpha1.DatabaseGroupPrincipalSpec{
+			Name:        "admins",
+			PrincipalId: "00000000-0000-0000-0000-000000000001",
+		}
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != "spec.access.principals[0]" || errs[0].Reason != ReasonInvalid {
+			t.Fatalf("expected a principal source error, got %v", errs)
+		}
+	})
+
+	t.Run("rejects duplicate principals", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals = append(database.Spec.Access.Principals, storagev1alpha1.DatabaseAccessPrincipalSpec{
+			Role:        storagev1alpha1.DatabaseAccessRoleReader,
+			IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "app"},
+		})
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != "spec.access.principals[1]" || errs[0].Reason != ReasonConflict {
+			t.Fatalf("expected a duplicate principal error, got %v", errs)
+		}
+	})
+
+	t.Run("accepts a reader in another namespace", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals = append(database.Spec.Access.Principals, storagev1alpha1.DatabaseAccessPrincipalSpec{
+			Role:        storagev1alpha1.DatabaseAccessRoleReader,
+			IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "app", Namespace: "reporting"},
+		})
+		if errs := Database(database); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("rejects writers in another namespace", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals[0].IdentityRef.Namespace = "reporting"
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != "spec.access.principals[0].identityRef.namespace" || errs[0].Reason != ReasonUnsupported {
+			t.Fatalf("expected a cross-namespace role error, got %v", errs)
+		}
+	})
+
+	t.Run("accepts scoped principals", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals[0].Scope = &storagev1alpha1.DatabaseAccessScope{
+			Schemas:          []string{"audit"},
+			ExecuteFunctions: true,
+		}
+		database.Spec.Access.Principals = append(database.Spec.Access.Principals, storagev1alpha1.DatabaseAccessPrincipalSpec{
+			Role:        storagev1alpha1.DatabaseAccessRoleReader,
+			IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "reports"},
+			Scope: &storagev1alpha1.DatabaseAccessScope{
+				Tables: []storagev1alpha1.DatabaseTableReference{{Name: "orders"}, {Schema: "audit", Name: "events"}},
+			},
+		})
+		if errs := Database(database); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("rejects invalid scopes", func(t *testing.T) {
+		tests := map[string]struct {
+			scope  storagev1alpha1.DatabaseAccessScope
+			field  string
+			reason string
+		}{
+			"tables for an Owner": {
+				scope:  storagev1alpha1.DatabaseAccessScope{Tables: []storagev1alpha1.DatabaseTableReference{{Name: "orders"}}},
+				field:  "spec.access.principals[0].scope.tables",
+				reason: ReasonUnsupported,
+			},
+			"reserved schema": {
+				scope:  storagev1alpha1.DatabaseAccessScope{Schemas: []string{"pg_catalog"}},
+				field:  "spec.access.principals[0].scope.schemas[0]",
+				reason: ReasonInvalid,
+			},
+			"own schema": {
+				scope:  storagev1alpha1.DatabaseAccessScope{Schemas: []string{"appdb"}},
+				field:  "spec.access.principals[0].scope.schemas[0]",
+				reason: ReasonInvalid,
+			},
+			"duplicate schema": {
+				scope:  storagev1alpha1.DatabaseAccessScope{Schemas: []string{"audit", "audit"}},
+				field:  "spec.access.principals[0].scope.schemas[1]",
+				reason: ReasonConflict,
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				database := testDatabase()
+				database.Spec.Access.Principals[0].Scope = &tt.scope
+				errs := Database(database)
+				if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Reason != tt.reason {
+					t.Fatalf("expected %s on %s, got %v", tt.reason, tt.field, errs)
+				}
+			})
+		}
+	})
+}
+
+func TestDatabaseSchedule(t *testing.T) {
+	retention := func(days int32) *int32 { return &days }
+	tests := map[string]struct {
+		schedule storagev1alpha1.DatabaseBackupScheduleSpec
+		field    string
+		reason   string
+	}{
+		"valid": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "0 2 * * *", ContainerURL: "https://acct.blob.core.windows.net/backups", RetentionDays: retention(30)},
+		},
+		"macro": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: "file:///backups"},
+		},
+		"seconds field": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "0 0 2 * * *", ContainerURL: "https://acct.blob.core.windows.net/backups"},
+			field:    FieldScheduleCron,
+			reason:   ReasonInvalid,
+		},
+		"time zone prefix": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "TZ=Europe/Oslo 0 2 * * *", ContainerURL: "https://acct.blob.core.windows.net/backups"},
+			field:    FieldScheduleCron,
+			reason:   ReasonInvalid,
+		},
+		"account without container": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: "https://acct.blob.core.windows.net"},
+			field:    FieldScheduleContainer,
+			reason:   ReasonInvalid,
+		},
+		"sas token": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: "https://acct.blob.core.windows.net/backups?sig=secret"},
+			field:    FieldScheduleContainer,
+			reason:   ReasonInvalid,
+		},
+		"unsupported scheme": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: "s3://bucket/backups"},
+			field:    FieldScheduleContainer,
+			reason:   ReasonUnsupported,
+		},
+		"zero retention": {
+			schedule: storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: "file:///backups", RetentionDays: retention(0)},
+			field:    FieldScheduleRetention,
+			reason:   ReasonInvalid,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			database := testDatabase()
+			database.Spec.Schedule = &tt.schedule
+			errs := Database(database)
+			if tt.field == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Reason != tt.reason {
+				t.Fatalf("expected %s on %s, got %v", tt.reason, tt.field, errs)
+			}
+		})
+	}
+}
+
+func TestDatabaseScheduleStorage(t *testing.T) {
+	accounts := []string{"appbackups"}
+	tests := map[string]struct {
+		containerURL string
+		allowLocal   bool
+		reason       string
+	}{
+		"allowed account":       {containerURL: "https://AppBackups.blob.core.windows.net/backups"},
+		"other account":         {containerURL: "https://other.blob.core.windows.net/backups", reason: ReasonUnsupported},
+		"other host":            {containerURL: "https://appbackups.example.com/backups", reason: ReasonInvalid},
+		"suffix in a subdomain": {containerURL: "https://appbackups.blob.core.windows.net.example.com/backups", reason: ReasonInvalid},
+		"port":                  {containerURL: "https://appbackups.blob.core.windows.net:8443/backups", reason: ReasonInvalid},
+		"user info":             {containerURL: "https://user@appbackups.blob.core.windows.net/backups", reason: ReasonInvalid},
+		"azurite":               {containerURL: "http://azurite:10000/devstoreaccount1/backups", reason: ReasonUnsupported},
+		"local azurite":         {containerURL: "http://azurite:10000/devstoreaccount1/backups", allowLocal: true},
+		"directory":             {containerURL: "file:///backups", reason: ReasonUnsupported},
+		"local directory":       {containerURL: "file:///backups", allowLocal: true},
+		"already rejected":      {containerURL: "https://other.blob.core.windows.net/backups?sig=secret"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			schedule := &storagev1alpha1.DatabaseBackupScheduleSpec{Cron: "@daily", ContainerURL: tt.containerURL}
+			errs := DatabaseScheduleStorage(nil, schedule, accounts, tt.allowLocal)
+			if tt.reason == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != FieldScheduleContainer || errs[0].Reason != tt.reason {
+				t.Fatalf("expected %s on %s, got %v", tt.reason, FieldScheduleContainer, errs)
+			}
+		})
+	}
+}
+
+func TestDatabaseServerSelector(t *testing.T) {
+	t.Run("accepts a selector instead of a server", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Server = storagev1alpha1.DatabaseServerReference{}
+		database.Spec.ServerSelector = &storagev1alpha1.DatabaseServerSelector{
+			MatchLabels: map[string]string{"dis.altinn.cloud/pool": "shared"},
+			ServerType:  "prod",
+		}
+		if errs := Database(database); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("rejects a selector with a server", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.ServerSelector = &storagev1alpha1.DatabaseServerSelector{ServerType: "prod"}
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != FieldServerSelector || errs[0].Reason != ReasonInvalid {
+			t.Fatalf("expected a serverSelector error, got %v", errs)
+		}
+	})
+
+	t.Run("rejects invalid labels", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Server = storagev1alpha1.DatabaseServerReference{}
+		database.Spec.ServerSelector = &storagev1alpha1.DatabaseServerSelector{
+			MatchLabels: map[string]string{"pool": "not a label value"},
+		}
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != FieldServerSelector || errs[0].Reason != ReasonInvalid {
+			t.Fatalf("expected a serverSelector error, got %v", errs)
+		}
+	})
+
+	t.Run("requires a server without a selector", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Server = storagev1alpha1.DatabaseServerReference{}
+		errs := Database(database)
+		if len(errs) != 1 || errs[0].Field != FieldServerName || errs[0].Reason != ReasonRequired {
+			t.Fatalf("expected a spec.server.name error, got %v", errs)
+		}
+	})
+}
+
+func TestDatabaseUpdate(t *testing.T) {
+	oldDatabase := testDatabase()
+	newDatabase := testDatabase()
+	newDatabase.Spec.Name = "renamed"
+
+	errs := DatabaseUpdate(oldDatabase, newDatabase)
+	if len(errs) != 1 || errs[0].Field != FieldSpecName || errs[0].Reason != ReasonImmutable {
+		t.Fatalf("expected spec.name to be immutable, got %v", errs)
+	}
+
+	if errs := DatabaseUpdate(oldDatabase, testDatabase()); len(errs) != 0 {
+		t.Fatalf("expected no errors for an unchanged name, got %v", errs)
+	}
+}
+
+func TestDatabaseFieldErrors(t *testing.T) {
+	errs := DatabaseFieldErrors([]storagev1alpha1.DatabaseValidationError{
+		{Field: FieldServerName, Reason: ReasonRequired, Message: "spec.server.name must be set"},
+		{Field: "spec.access.principals[1]", Reason: ReasonConflict, Message: "principal duplicates spec.access.principals[0]"},
+		{Field: FieldSpecName, Reason: ReasonImmutable, Message: "spec.name cannot change"},
+	})
+
+	want := []field.ErrorType{field.ErrorTypeRequired, field.ErrorTypeDuplicate, field.ErrorTypeForbidden}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d field errors, got %v", len(want), errs)
+	}
+	for i := range want {
+		if errs[i].Type != want[i] {
+			t.Fatalf("errs[%d].Type = %q, want %q", i, errs[i].Type, want[i])
+		}
+	}
+}
+
+func TestDatabaseSchemaMigrations(t *testing.T) {
+	owner := storagev1alpha1.DatabaseLocalIdentityRef{Name: "app"}
+	tests := map[string]struct {
+		migrations storagev1alpha1.DatabaseSchemaMigrationsSpec
+		invalid    bool
+	}{
+		"image": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{Image: "ghcr.io/altinn/app-migrations:1.2.0", Path: "/sql", IdentityRef: owner},
+		},
+		"configMap": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{ConfigMap: &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"}, IdentityRef: owner},
+		},
+		"no source": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{IdentityRef: owner},
+			invalid:    true,
+		},
+		"no identity": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{ConfigMap: &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"}},
+			invalid:    true,
+		},
+		"identity without the Owner role": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{
+				ConfigMap:   &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"},
+				IdentityRef: storagev1alpha1.DatabaseLocalIdentityRef{Name: "reader"},
+			},
+			invalid: true,
+		},
+		"both sources": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{
+				Image:       "ghcr.io/altinn/app-migrations:1.2.0",
+				ConfigMap:   &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"},
+				IdentityRef: owner,
+			},
+			invalid: true,
+		},
+		"path with configMap": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{Path: "/sql", ConfigMap: &storagev1alpha1.DatabaseConfigMapReference{Name: "app-migrations"}, IdentityRef: owner},
+			invalid:    true,
+		},
+		"relative path": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{Image: "ghcr.io/altinn/app-migrations:1.2.0", Path: "sql", IdentityRef: owner},
+			invalid:    true,
+		},
+		"invalid configMap name": {
+			migrations: storagev1alpha1.DatabaseSchemaMigrationsSpec{ConfigMap: &storagev1alpha1.DatabaseConfigMapReference{Name: "App_Migrations"}, IdentityRef: owner},
+			invalid:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			database := testDatabase()
+			database.Spec.Access.Principals = append(database.Spec.Access.Principals, storagev1alpha1.DatabaseAccessPrincipalSpec{
+				Role:        storagev1alpha1.DatabaseAccessRoleReader,
+				IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "reader"},
+			})
+			database.Spec.SchemaMigrations = &tt.migrations
+			errs := Database(database)
+			if !tt.invalid {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != FieldSchemaMigrations || errs[0].Reason != ReasonInvalid {
+				t.Fatalf("expected Invalid on %s, got %v", FieldSchemaMigrations, errs)
+			}
+		})
+	}
+}
+
+func TestDatabaseAccessSettings(t *testing.T) {
+	limit := func(value int32) *int32 { return &value }
+	timeout := func(value time.Duration) *metav1.Duration { return &metav1.Duration{Duration: value} }
+	tests := map[string]struct {
+		principal storagev1alpha1.DatabaseAccessPrincipalSpec
+		field     string
+	}{
+		"limits and timeouts": {
+			principal: storagev1alpha1.DatabaseAccessPrincipalSpec{
+				ConnectionLimit:                 limit(20),
+				StatementTimeout:                timeout(30 * time.Second),
+				IdleInTransactionSessionTimeout: timeout(time.Minute),
+			},
+		},
+		"zero connection limit": {
+			principal: storagev1alpha1.DatabaseAccessPrincipalSpec{ConnectionLimit: limit(0)},
+			field:     "spec.access.principals[0].connectionLimit",
+		},
+		"sub-millisecond statement timeout": {
+			principal: storagev1alpha1.DatabaseAccessPrincipalSpec{StatementTimeout: timeout(time.Microsecond)},
+			field:     "spec.access.principals[0].statementTimeout",
+		},
+		"idle timeout beyond PostgreSQL's range": {
+			principal: storagev1alpha1.DatabaseAccessPrincipalSpec{IdleInTransactionSessionTimeout: timeout(30 * 24 * time.Hour)},
+			field:     "spec.access.principals[0].idleInTransactionSessionTimeout",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			database := testDatabase()
+			principal := tt.principal
+			principal.Role = storagev1alpha1.DatabaseAccessRoleOwner
+			principal.IdentityRef = &storagev1alpha1.DatabaseIdentityRef{Name: "app"}
+			database.Spec.Access.Principals = []storagev1alpha1.DatabaseAccessPrincipalSpec{principal}
+			errs := Database(database)
+			if tt.field == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Reason != ReasonInvalid {
+				t.Fatalf("expected Invalid on %s, got %v", tt.field, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	dbUtil "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/database"
+)
+
+// Azure resource types a shared DatabaseServer's network IDs must reference.
+const (
+	SubnetResourceType         = "Microsoft.Network/virtualNetworks/subnets"
+	PrivateDNSZoneResourceType = "Microsoft.Network/privateDnsZones"
+)
+
+// SharedNetworkResourceID checks that resourceID is an ARM ID of the expected
+// type in the operator's subscription and returns it trimmed.
+func SharedNetworkResourceID(fieldPath, resourceID, expectedResourceType, subscriptionID string) (string, error) {
+	resourceID = strings.TrimSpace(resourceID)
+	if resourceID == "" {
+		return "", fmt.Errorf("%s must be set when mode is Shared", fieldPath)
+	}
+
+	parsed, err := arm.ParseResourceID(resourceID)
+	if err != nil {
+		return "", fmt.Errorf("%s must be a valid ARM resource ID: %w", fieldPath, err)
+	}
+
+	subscriptionID = strings.TrimSpace(subscriptionID)
+	if subscriptionID == "" {
+		return "", fmt.Errorf("operator subscription id is not configured; cannot validate %s", fieldPath)
+	}
+	if !strings.EqualFold(parsed.SubscriptionID, subscriptionID) {
+		return "", fmt.Errorf("%s must be in subscription %q", fieldPath, subscriptionID)
+	}
+
+	actualResourceType := parsed.ResourceType.String()
+	if !strings.EqualFold(actualResourceType, expectedResourceType) {
+		return "", fmt.Errorf("%s must reference %s, got %s", fieldPath, expectedResourceType, actualResourceType)
+	}
+
+	return resourceID, nil
+}
+
+// DatabaseServer checks the parts of a DatabaseServer spec that depend on the
+// operator configuration or the server parameter catalog, which the CRD
+// schema cannot see.
+func DatabaseServer(cfg config.OperatorConfig, db *storagev1alpha1.DatabaseServer) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	profile, err := cfg.Profiles.Resolve(db.Spec.Profile, db.Spec.ServerType)
+	if err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("profile"), db.Spec.Profile, err.Error()))
+	} else {
+		if _, err := dbUtil.ResolveConnectionPooling(profile, db.Spec.ConnectionPooling); err != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("connectionPooling"), err.Error()))
+		}
+		if _, err := dbUtil.ResolveGeoRedundantBackup(profile, db.Spec.GeoRedundantBackup); err != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("geoRedundantBackup"), err.Error()))
+		}
+	}
+
+	// The operator only sets the primary key, and Azure needs a separate
+	// key and identity in the paired region to encrypt geo-redundant backups.
+	if db.Spec.Encryption != nil && geoRedundantBackup(db) {
+		errs = append(errs, field.Forbidden(specPath.Child("encryption"), "encryption cannot be combined with geoRedundantBackup"))
+	}
+
+	errs = append(errs, dbUtil.ValidateServerParameters(db.Spec.Version, db.Spec.ServerParams, specPath.Child("serverParams"))...)
+
+	if db.Spec.DebugAccess != nil {
+		principalsPath := specPath.Child("debugAccess", "principals")
+		for i, principal := range db.Spec.DebugAccess.Principals {
+			principalPath := principalsPath.Index(i)
+			if principal.ExpiresAt != nil && principal.Duration != nil {
+				errs = append(errs, field.Invalid(principalPath, "", "set at most one of expiresAt and duration"))
+			}
+			if principal.Duration != nil && principal.Duration.Duration <= 0 {
+				errs = append(errs, field.Invalid(principalPath.Child("duration"), principal.Duration.Duration.String(), "duration must be positive"))
+			}
+		}
+	}
+
+	if db.Spec.Mode == storagev1alpha1.DatabaseServerModeShared && db.Spec.Network != nil {
+		networkPath := specPath.Child("network")
+		for _, networkID := range []struct {
+			path         *field.Path
+			value        string
+			resourceType string
+		}{
+			{networkPath.Child("delegatedSubnetResourceId"), db.Spec.Network.DelegatedSubnetResourceID, SubnetResourceType},
+			{networkPath.Child("privateDnsZoneResourceId"), db.Spec.Network.PrivateDNSZoneResourceID, PrivateDNSZoneResourceType},
+		} {
+			if _, err := SharedNetworkResourceID(networkID.path.String(), networkID.value, networkID.resourceType, cfg.SubscriptionId); err != nil {
+				errs = append(errs, field.Invalid(networkID.path, networkID.value, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+// DatabaseServerUpdate checks the fields of a DatabaseServer that cannot
+// change once the Flexible Server exists: Azure cannot downgrade PostgreSQL,
+// a server cannot move between dedicated and shared networking, and
+// geo-redundant backup and the customer-managed key are only set when the
+// server is created.
+func DatabaseServerUpdate(oldServer, newServer *storagev1alpha1.DatabaseServer) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	// A rejected upgrade leaves the server on status.version, so spec.version
+	// may go back to it.
+	runningVersion := oldServer.Spec.Version
+	if oldServer.Status.Version > 0 {
+		runningVersion = oldServer.Status.Version
+	}
+	if newServer.Spec.Version < runningVersion {
+		errs = append(errs, field.Forbidden(
+			specPath.Child("version"),
+			fmt.Sprintf("PostgreSQL cannot be downgraded from %d to %d", runningVersion, newServer.Spec.Version),
+		))
+	}
+
+	if databaseServerMode(oldServer) != databaseServerMode(newServer) {
+		errs = append(errs, field.Forbidden(specPath.Child("mode"), "mode is immutable"))
+	}
+
+	if geoRedundantBackup(oldServer) != geoRedundantBackup(newServer) {
+		errs = append(errs, field.Forbidden(specPath.Child("geoRedundantBackup"), "geoRedundantBackup is immutable"))
+	}
+
+	if encryption(oldServer) != encryption(newServer) {
+		errs = append(errs, field.Forbidden(specPath.Child("encryption"), "encryption is immutable"))
+	}
+
+	oldNetwork, newNetwork := network(oldServer), network(newServer)
+	networkPath := specPath.Child("network")
+	if oldNetwork.DelegatedSubnetResourceID != newNetwork.DelegatedSubnetResourceID {
+		errs = append(errs, field.Forbidden(networkPath.Child("delegatedSubnetResourceId"), "delegatedSubnetResourceId is immutable"))
+	}
+	if oldNetwork.PrivateDNSZoneResourceID != newNetwork.PrivateDNSZoneResourceID {
+		errs = append(errs, field.Forbidden(networkPath.Child("privateDnsZoneResourceId"), "privateDnsZoneResourceId is immutable"))
+	}
+
+	return errs
+}
+
+func databaseServerMode(db *storagev1alpha1.DatabaseServer) storagev1alpha1.DatabaseServerMode {
+	if db.Spec.Mode == storagev1alpha1.DatabaseServerModeShared {
+		return storagev1alpha1.DatabaseServerModeShared
+	}
+	return storagev1alpha1.DatabaseServerModeDedicated
+}
+
+func geoRedundantBackup(db *storagev1alpha1.DatabaseServer) bool {
+	return db.Spec.GeoRedundantBackup != nil && *db.Spec.GeoRedundantBackup
+}
+
+func network(db *storagev1alpha1.DatabaseServer) storagev1alpha1.DatabaseServerNetworkSpec {
+	if db.Spec.Network == nil {
+		return storagev1alpha1.DatabaseServerNetworkSpec{}
+	}
+	return *db.Spec.Network
+}
+
+func encryption(db *storagev1alpha1.DatabaseServer) storagev1alpha1.DatabaseServerEncryptionSpec {
+	if db.Spec.Encryption == nil {
+		return storagev1alpha1.DatabaseServerEncryptionSpec{}
+	}
+	return *db.Spec.Encryption
+}
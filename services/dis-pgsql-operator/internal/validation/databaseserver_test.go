@@ -0,0 +1,256 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+)
+
+const testSubscriptionID = "00000000-0000-0000-0000-000000000001"
+
+func testSharedNetwork(subscriptionID string) *storagev1alpha1.DatabaseServerNetworkSpec {
+	return &storagev1alpha1.DatabaseServerNetworkSpec{
+		DelegatedSubnetResourceID: "/subscriptions/" + subscriptionID + "/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/pg",
+		PrivateDNSZoneResourceID:  "/subscriptions/" + subscriptionID + "/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/pg.postgres.database.azure.com",
+	}
+}
+
+func TestDatabaseServer(t *testing.T) {
+	cfg := config.OperatorConfig{SubscriptionId: testSubscriptionID}
+	enabled := true
+
+	t.Run("accepts a valid server", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+			Version:    17,
+			ServerType: "prod",
+			Mode:       storagev1alpha1.DatabaseServerModeShared,
+			Network:    testSharedNetwork(testSubscriptionID),
+			ServerParams: []storagev1alpha1.DatabaseServerParameter{
+				{Name: "autovacuum_naptime", Value: intstr.FromInt32(15)},
+			},
+		}}
+		if errs := DatabaseServer(cfg, db); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+			Version:           17,
+			ServerType:        "dev",
+			Mode:              storagev1alpha1.DatabaseServerModeShared,
+			Network:           testSharedNetwork("00000000-0000-0000-0000-000000000002"),
+			ConnectionPooling: &storagev1alpha1.DatabaseServerConnectionPoolingSpec{Enabled: &enabled},
+			ServerParams: []storagev1alpha1.DatabaseServerParameter{
+				{Name: "work_mem", Value: intstr.FromInt32(1)},
+			},
+		}}
+		errs := DatabaseServer(cfg, db)
+		got := map[string]bool{}
+		for _, err := range errs {
+			got[err.Field] = true
+		}
+		for _, want := range []string{
+			"spec.connectionPooling",
+			"spec.serverParams[0].value",
+			"spec.network.delegatedSubnetResourceId",
+			"spec.network.privateDnsZoneResourceId",
+		} {
+			if !got[want] {
+				t.Fatalf("expected an error for %s, got %v", want, errs)
+			}
+		}
+	})
+
+	t.Run("rejects an unknown profile", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{Version: 17, ServerType: "dev", Profile: "missing"}}
+		errs := DatabaseServer(cfg, db)
+		if len(errs) != 1 || errs[0].Field != "spec.profile" {
+			t.Fatalf("expected a spec.profile error, got %v", errs)
+		}
+	})
+
+	t.Run("rejects geo-redundant backup on a profile that does not allow it", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{Version: 17, ServerType: "dev", GeoRedundantBackup: &enabled}}
+		errs := DatabaseServer(cfg, db)
+		if len(errs) != 1 || errs[0].Field != "spec.geoRedundantBackup" {
+			t.Fatalf("expected a spec.geoRedundantBackup error, got %v", errs)
+		}
+
+		db.Spec.ServerType = "prod"
+		if errs := DatabaseServer(cfg, db); len(errs) != 0 {
+			t.Fatalf("expected geo-redundant backup on prod to be accepted, got %v", errs)
+		}
+	})
+
+	t.Run("rejects encryption with geo-redundant backup", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+			Version:            17,
+			ServerType:         "prod",
+			GeoRedundantBackup: &enabled,
+			Encryption: &storagev1alpha1.DatabaseServerEncryptionSpec{
+				VaultRef:    storagev1alpha1.VaultReference{Name: "app-vault"},
+				KeyName:     "pg-cmk",
+				IdentityRef: storagev1alpha1.ApplicationIdentityRef{Name: "pg-identity"},
+			},
+		}}
+		errs := DatabaseServer(cfg, db)
+		if len(errs) != 1 || errs[0].Field != "spec.encryption" {
+			t.Fatalf("expected a spec.encryption error, got %v", errs)
+		}
+
+		db.Spec.GeoRedundantBackup = nil
+		if errs := DatabaseServer(cfg, db); len(errs) != 0 {
+			t.Fatalf("expected encryption without geo-redundant backup to be accepted, got %v", errs)
+		}
+	})
+
+	t.Run("rejects invalid debug access expiry", func(t *testing.T) {
+		db := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+			Version:    17,
+			ServerType: "dev",
+			DebugAccess: &storagev1alpha1.DatabaseServerDebugAccessSpec{
+				Principals: []storagev1alpha1.DebugAccessPrincipalSpec{
+					{
+						Group:     &storagev1alpha1.DatabaseGroupPrincipalSpec{Name: "dba", PrincipalId: testSubscriptionID},
+						ExpiresAt: &metav1.Time{Time: time.Now().Add(time.Hour)},
+						Duration:  &metav1.Duration{Duration: time.Hour},
+					},
+					{
+						Group:    &storagev1alpha1.DatabaseGroupPrincipalSpec{Name: "ops", PrincipalId: testSubscriptionID},
+						Duration: &metav1.Duration{Duration: -time.Minute},
+					},
+				},
+			},
+		}}
+		errs := DatabaseServer(cfg, db)
+		if len(errs) != 2 || errs[0].Field != "spec.debugAccess.principals[0]" || errs[1].Field != "spec.debugAccess.principals[1].duration" {
+			t.Fatalf("expected debug access expiry errors, got %v", errs)
+		}
+	})
+}
+
+func TestDatabaseServerUpdate(t *testing.T) {
+	oldServer := &storagev1alpha1.DatabaseServer{Spec: storagev1alpha1.DatabaseServerSpec{
+		Version: 16,
+		Mode:    storagev1alpha1.DatabaseServerModeShared,
+		Network: testSharedNetwork(testSubscriptionID),
+	}}
+
+	t.Run("allows a major version upgrade", func(t *testing.T) {
+		newServer := oldServer.DeepCopy()
+		newServer.Spec.Version = 17
+		if errs := DatabaseServerUpdate(oldServer, newServer); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("allows reverting a held upgrade to the running version", func(t *testing.T) {
+		upgrading := oldServer.DeepCopy()
+		upgrading.Spec.Version = 17
+		upgrading.Status.Version = 16
+		reverted := upgrading.DeepCopy()
+		reverted.Spec.Version = 16
+		if errs := DatabaseServerUpdate(upgrading, reverted); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+		reverted.Spec.Version = 15
+		if errs := DatabaseServerUpdate(upgrading, reverted); len(errs) != 1 || !strings.Contains(errs[0].Error(), "from 16 to 15") {
+			t.Fatalf("expected a downgrade below the running version to be rejected, got %v", errs)
+		}
+	})
+
+	cases := []struct {
+		name      string
+		mutate    func(*storagev1alpha1.DatabaseServer)
+		wantField string
+		wantText  string
+	}{
+		{
+			name:      "version downgrade",
+			mutate:    func(db *storagev1alpha1.DatabaseServer) { db.Spec.Version = 15 },
+			wantField: "spec.version",
+			wantText:  "cannot be downgraded from 16 to 15",
+		},
+		{
+			name: "mode switch",
+			mutate: func(db *storagev1alpha1.DatabaseServer) {
+				db.Spec.Mode = storagev1alpha1.DatabaseServerModeDedicated
+			},
+			wantField: "spec.mode",
+			wantText:  "immutable",
+		},
+		{
+			name: "subnet change",
+			mutate: func(db *storagev1alpha1.DatabaseServer) {
+				db.Spec.Network.DelegatedSubnetResourceID = strings.Replace(db.Spec.Network.DelegatedSubnetResourceID, "subnets/pg", "subnets/other", 1)
+			},
+			wantField: "spec.network.delegatedSubnetResourceId",
+			wantText:  "immutable",
+		},
+		{
+			name: "private DNS zone change",
+			mutate: func(db *storagev1alpha1.DatabaseServer) {
+				db.Spec.Network.PrivateDNSZoneResourceID += "-other"
+			},
+			wantField: "spec.network.privateDnsZoneResourceId",
+			wantText:  "immutable",
+		},
+		{
+			name: "geo-redundant backup added",
+			mutate: func(db *storagev1alpha1.DatabaseServer) {
+				enabled := true
+				db.Spec.GeoRedundantBackup = &enabled
+			},
+			wantField: "spec.geoRedundantBackup",
+			wantText:  "immutable",
+		},
+		{
+			name: "customer-managed key added",
+			mutate: func(db *storagev1alpha1.DatabaseServer) {
+				db.Spec.Encryption = &storagev1alpha1.DatabaseServerEncryptionSpec{
+					VaultRef:    storagev1alpha1.VaultReference{Name: "app-vault"},
+					KeyName:     "pg-cmk",
+					IdentityRef: storagev1alpha1.ApplicationIdentityRef{Name: "pg-identity"},
+				}
+			},
+			wantField: "spec.encryption",
+			wantText:  "immutable",
+		},
+	}
+	for _, tc := range cases {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			newServer := oldServer.DeepCopy()
+			tc.mutate(newServer)
+			errs := DatabaseServerUpdate(oldServer, newServer)
+			if len(errs) != 1 || errs[0].Field != tc.wantField || !strings.Contains(errs[0].Error(), tc.wantText) {
+				t.Fatalf("expected one %s error mentioning %q, got %v", tc.wantField, tc.wantText, errs)
+			}
+		})
+	}
+
+	withoutNetwork := oldServer.DeepCopy()
+	withoutNetwork.Spec.Network = nil
+	for _, tc := range []struct {
+		name       string
+		old, newer *storagev1alpha1.DatabaseServer
+	}{
+		{name: "network added", old: withoutNetwork, newer: oldServer},
+		{name: "network removed", old: oldServer, newer: withoutNetwork},
+	} {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			errs := DatabaseServerUpdate(tc.old, tc.newer)
+			if len(errs) != 2 ||
+				errs[0].Field != "spec.network.delegatedSubnetResourceId" ||
+				errs[1].Field != "spec.network.privateDnsZoneResourceId" {
+				t.Fatalf("expected both network IDs to be immutable, got %v", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/validation"
+)
+
+// databaselog is for logging in this package.
+var databaselog = logf.Log.WithName("database-resource")
+
+// SetupDatabaseWebhookWithManager registers the webhooks for Database in the manager.
+func SetupDatabaseWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &storagev1alpha1.Database{}).
+		WithDefaulter(&DatabaseCustomDefaulter{}).
+		WithValidator(&DatabaseCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-storage-dis-altinn-cloud-v1alpha1-database,mutating=true,failurePolicy=fail,sideEffects=None,groups=storage.dis.altinn.cloud,resources=databases,verbs=create,versions=v1alpha1,name=mdatabase-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DatabaseCustomDefaulter sets default values on Database resources when
+// they are created.
+type DatabaseCustomDefaulter struct{}
+
+// Default records the deletion grace period on Databases with deletionPolicy
+// Delete, so the delay before the database is dropped is visible in the spec.
+func (d *DatabaseCustomDefaulter) Default(_ context.Context, database *storagev1alpha1.Database) error {
+	if database.Spec.DeletionPolicy == storagev1alpha1.DatabaseDeletionPolicyDelete && database.Spec.DeletionGracePeriod == nil {
+		databaselog.V(1).Info("defaulting deletion grace period", "name", database.GetName())
+		database.Spec.DeletionGracePeriod = &metav1.Duration{Duration: storagev1alpha1.DefaultDatabaseDeletionGracePeriod}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-storage-dis-altinn-cloud-v1alpha1-database,mutating=false,failurePolicy=fail,sideEffects=None,groups=storage.dis.altinn.cloud,resources=databases,verbs=create;update,versions=v1alpha1,name=vdatabase-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DatabaseCustomValidator rejects Database specs the operator cannot
+// reconcile. Checks that need other resources, such as whether the
+// DatabaseServer exists, stay in the reconciler, since the server may be
+// applied after the Database.
+type DatabaseCustomValidator struct{}
+
+// ValidateCreate validates a new Database.
+func (v *DatabaseCustomValidator) ValidateCreate(_ context.Context, database *storagev1alpha1.Database) (admission.Warnings, error) {
+	return nil, databaseInvalid(database, validation.Database(database))
+}
+
+// ValidateUpdate validates a changed Database. Updates that leave the spec
+// alone, such as finalizer changes by the operator, are always allowed.
+func (v *DatabaseCustomValidator) ValidateUpdate(
+	_ context.Context,
+	oldDatabase, newDatabase *storagev1alpha1.Database,
+) (admission.Warnings, error) {
+	if !newDatabase.DeletionTimestamp.IsZero() || apiequality.Semantic.DeepEqual(oldDatabase.Spec, newDatabase.Spec) {
+		return nil, nil
+	}
+	validationErrors := validation.DatabaseUpdate(oldDatabase, newDatabase)
+	validationErrors = append(validationErrors, validation.Database(newDatabase)...)
+	return nil, databaseInvalid(newDatabase, validationErrors)
+}
+
+// ValidateDelete allows every delete.
+func (v *DatabaseCustomValidator) ValidateDelete(_ context.Context, _ *storagev1alpha1.Database) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func databaseInvalid(database *storagev1alpha1.Database, validationErrors []storagev1alpha1.DatabaseValidationError) error {
+	if len(validationErrors) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		storagev1alpha1.GroupVersion.WithKind("Database").GroupKind(),
+		database.Name,
+		validation.DatabaseFieldErrors(validationErrors),
+	)
+}
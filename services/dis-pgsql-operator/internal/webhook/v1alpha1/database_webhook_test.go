@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func testDatabase() *storagev1alpha1.Database {
+	return &storagev1alpha1.Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "appdb"},
+		Spec: storagev1alpha1.DatabaseSpec{
+			Name:   "appdb",
+			Server: storagev1alpha1.DatabaseServerReference{Name: "shared"},
+			Access: storagev1alpha1.DatabaseAccessSpec{
+				Principals: []storagev1alpha1.DatabaseAccessPrincipalSpec{
+					{Role: storagev1alpha1.DatabaseAccessRoleOwner, IdentityRef: &storagev1alpha1.DatabaseIdentityRef{Name: "app"}},
+				},
+			},
+		},
+	}
+}
+
+func TestDatabaseCustomDefaulter(t *testing.T) {
+	database := testDatabase()
+	if err := (&DatabaseCustomDefaulter{}).Default(context.Background(), database); err != nil {
+		t.Fatalf("Default returned error: %v", err)
+	}
+	if database.Spec.DeletionGracePeriod != nil {
+		t.Fatalf("expected no grace period for Retain, got %v", database.Spec.DeletionGracePeriod)
+	}
+
+	database.Spec.DeletionPolicy = storagev1alpha1.DatabaseDeletionPolicyDelete
+	if err := (&DatabaseCustomDefaulter{}).Default(context.Background(), database); err != nil {
+		t.Fatalf("Default returned error: %v", err)
+	}
+	if database.Spec.DeletionGracePeriod == nil || database.Spec.DeletionGracePeriod.Duration != storagev1alpha1.DefaultDatabaseDeletionGracePeriod {
+		t.Fatalf("expected the default grace period, got %v", database.Spec.DeletionGracePeriod)
+	}
+}
+
+func TestDatabaseCustomValidator(t *testing.T) {
+	validator := &DatabaseCustomValidator{}
+	ctx := context.Background()
+
+	t.Run("accepts a valid database", func(t *testing.T) {
+		if _, err := validator.ValidateCreate(ctx, testDatabase()); err != nil {
+			t.Fatalf("expected valid Database to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("rejects duplicate principals with a field path", func(t *testing.T) {
+		database := testDatabase()
+		database.Spec.Access.Principals = append(database.Spec.Access.Principals, database.Spec.Access.Principals[0])
+		_, err := validator.ValidateCreate(ctx, database)
+		if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.access.principals[1]") {
+			t.Fatalf("expected an Invalid error for spec.access.principals[1], got %v", err)
+		}
+	})
+
+	t.Run("rejects renaming the database", func(t *testing.T) {
+		newDatabase := testDatabase()
+		newDatabase.Spec.Name = "renamed"
+		_, err := validator.ValidateUpdate(ctx, testDatabase(), newDatabase)
+		if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.name") {
+			t.Fatalf("expected spec.name to be immutable, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/config"
+	"github.com/Altinn/altinn-platform/services/dis-pgsql-operator/internal/validation"
+)
+
+// databaseserverlog is for logging in this package.
+var databaseserverlog = logf.Log.WithName("databaseserver-resource")
+
+// SetupDatabaseServerWebhookWithManager registers the webhooks for DatabaseServer in the manager.
+func SetupDatabaseServerWebhookWithManager(mgr ctrl.Manager, cfg config.OperatorConfig) error {
+	return ctrl.NewWebhookManagedBy(mgr, &storagev1alpha1.DatabaseServer{}).
+		WithDefaulter(&DatabaseServerCustomDefaulter{}).
+		WithValidator(&DatabaseServerCustomValidator{Config: cfg}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-storage-dis-altinn-cloud-v1alpha1-databaseserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=storage.dis.altinn.cloud,resources=databaseservers,verbs=create,versions=v1alpha1,name=mdatabaseserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DatabaseServerCustomDefaulter sets default values on DatabaseServer
+// resources when they are created. It does not run on updates, so the
+// operator's own metadata updates never change the spec.
+type DatabaseServerCustomDefaulter struct{}
+
+// Default records the default maintenance window on servers that do not set
+// one, so the window restarting changes wait for is visible in the spec.
+func (d *DatabaseServerCustomDefaulter) Default(_ context.Context, db *storagev1alpha1.DatabaseServer) error {
+	if db.Spec.MaintenanceWindow == nil {
+		databaseserverlog.V(1).Info("defaulting maintenance window", "name", db.GetName())
+		db.Spec.MaintenanceWindow = &storagev1alpha1.DatabaseServerMaintenanceWindow{
+			DayOfWeek:   storagev1alpha1.DefaultMaintenanceDayOfWeek,
+			StartHour:   storagev1alpha1.DefaultMaintenanceStartHour,
+			StartMinute: storagev1alpha1.DefaultMaintenanceStartMinute,
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-storage-dis-altinn-cloud-v1alpha1-databaseserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=storage.dis.altinn.cloud,resources=databaseservers,verbs=create;update,versions=v1alpha1,name=vdatabaseserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DatabaseServerCustomValidator rejects DatabaseServer specs the operator
+// cannot reconcile. It needs the operator configuration for the server
+// profiles and the subscription shared network IDs must be in.
+type DatabaseServerCustomValidator struct {
+	Config config.OperatorConfig
+}
+
+// ValidateCreate validates a new DatabaseServer.
+func (v *DatabaseServerCustomValidator) ValidateCreate(_ context.Context, db *storagev1alpha1.DatabaseServer) (admission.Warnings, error) {
+	return nil, databaseServerInvalid(db, validation.DatabaseServer(v.Config, db))
+}
+
+// ValidateUpdate validates a changed DatabaseServer. Updates that leave the
+// spec alone, such as finalizer and annotation changes by the operator, are
+// always allowed so a server whose spec has become invalid can still be
+// deleted.
+func (v *DatabaseServerCustomValidator) ValidateUpdate(
+	_ context.Context,
+	oldDB, newDB *storagev1alpha1.DatabaseServer,
+) (admission.Warnings, error) {
+	if !newDB.DeletionTimestamp.IsZero() || apiequality.Semantic.DeepEqual(oldDB.Spec, newDB.Spec) {
+		return nil, nil
+	}
+	errs := validation.DatabaseServerUpdate(oldDB, newDB)
+	errs = append(errs, validation.DatabaseServer(v.Config, newDB)...)
+	return nil, databaseServerInvalid(newDB, errs)
+}
+
+// ValidateDelete allows every delete.
+func (v *DatabaseServerCustomValidator) ValidateDelete(_ context.Context, _ *storagev1alpha1.DatabaseServer) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func databaseServerInvalid(db *storagev1alpha1.DatabaseServer, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(storagev1alpha1.GroupVersion.WithKind("DatabaseServer").GroupKind(), db.Name, errs)
+}
@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storagev1alpha1 "github.com/Altinn/altinn-platform/services/dis-pgsql-operator/api/v1alpha1"
+)
+
+func TestDatabaseServerCustomDefaulter(t *testing.T) {
+	db := &storagev1alpha1.DatabaseServer{}
+	if err := (&DatabaseServerCustomDefaulter{}).Default(context.Background(), db); err != nil {
+		t.Fatalf("Default returned error: %v", err)
+	}
+	window := db.Spec.MaintenanceWindow
+	if window == nil || window.DayOfWeek != storagev1alpha1.DefaultMaintenanceDayOfWeek || window.StartHour != storagev1alpha1.DefaultMaintenanceStartHour {
+		t.Fatalf("expected the default maintenance window, got %#v", window)
+	}
+
+	configured := &storagev1alpha1.DatabaseServerMaintenanceWindow{DayOfWeek: 3, StartHour: 22}
+	db.Spec.MaintenanceWindow = configured
+	if err := (&DatabaseServerCustomDefaulter{}).Default(context.Background(), db); err != nil {
+		t.Fatalf("Default returned error: %v", err)
+	}
+	if db.Spec.MaintenanceWindow != configured {
+		t.Fatalf("expected the configured window to be kept, got %#v", db.Spec.MaintenanceWindow)
+	}
+}
+
+func TestDatabaseServerCustomValidator(t *testing.T) {
+	validator := &DatabaseServerCustomValidator{}
+	ctx := context.Background()
+	oldDB := &storagev1alpha1.DatabaseServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Spec:       storagev1alpha1.DatabaseServerSpec{Version: 17, ServerType: "dev"},
+	}
+
+	t.Run("rejects a downgrade", func(t *testing.T) {
+		newDB := oldDB.DeepCopy()
+		newDB.Spec.Version = 16
+		_, err := validator.ValidateUpdate(ctx, oldDB, newDB)
+		if !apierrors.IsInvalid(err) {
+			t.Fatalf("expected an Invalid error, got %v", err)
+		}
+	})
+
+	t.Run("allows metadata changes to an invalid server", func(t *testing.T) {
+		invalid := oldDB.DeepCopy()
+		invalid.Spec.Profile = "removed-from-config"
+		updated := invalid.DeepCopy()
+		updated.Finalizers = nil
+		updated.Annotations = map[string]string{"storage.dis.altinn.cloud/apply-now": "true"}
+		if _, err := validator.ValidateUpdate(ctx, invalid, updated); err != nil {
+			t.Fatalf("expected metadata-only update to be allowed, got %v", err)
+		}
+		if _, err := validator.ValidateCreate(ctx, invalid); !apierrors.IsInvalid(err) {
+			t.Fatalf("expected unknown profile to be rejected on create, got %v", err)
+		}
+	})
+}